@@ -5,15 +5,20 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"lio-ai/internal/audit"
 	"lio-ai/internal/auth"
 	"lio-ai/internal/config"
 	"lio-ai/internal/db"
 	"lio-ai/internal/handlers"
+	"lio-ai/internal/mail"
 	"lio-ai/internal/middleware"
+	"lio-ai/internal/models"
 	"lio-ai/internal/repositories"
 	"lio-ai/internal/services"
+	"lio-ai/internal/storage"
 )
 
 func main() {
@@ -48,6 +53,13 @@ func main() {
 	router.Use(middleware.ErrorRecoveryMiddleware())
 	router.Use(middleware.CORSMiddleware())
 	router.Use(middleware.LoggingMiddleware())
+	router.Use(middleware.ResponseVersioning())
+
+	// SECURITY: Authenticate personal API keys against their stored hashes,
+	// then JWTs for requests without one
+	apiKeyService := services.NewAPIKeyService(repositories.NewAPIKeyRepository(database.GetConnection()),
+		repositories.NewUserRepository(database.GetConnection()))
+	router.Use(middleware.APIKeyAuth(apiKeyService))
 
 	// SECURITY: Add JWT auth middleware
 	router.Use(middleware.NewAuthMiddleware(jwtManager))
@@ -55,37 +67,321 @@ func main() {
 	// SECURITY: Add CSRF protection middleware
 	router.Use(middleware.CSRFMiddleware())
 
-	// Rate limiting middleware
-	limiter := middleware.NewRateLimiter()
-	router.Use(middleware.RateLimitMiddleware(limiter))
+	// Track requests being served so admins can list and cancel them
+	requestRegistry := services.NewRequestRegistry()
+	router.Use(middleware.TrackInflight(requestRegistry))
 
 	// Initialize repositories
 	userRepo := repositories.NewUserRepository(database.GetConnection())
 	docRepo := repositories.NewDocumentRepository(database.GetConnection())
+	collectionRepo := repositories.NewCollectionRepository(database.GetConnection())
+	widgetRepo := repositories.NewWidgetRepository(database.GetConnection())
 	chatRepo := repositories.NewChatRepository(database.GetConnection())
 	usageRepo := repositories.NewUsageRepository(database.GetConnection())
 	providerKeyRepo := repositories.NewProviderKeyRepository(database.GetConnection())
-	
+	syncQueueRepo := repositories.NewSyncQueueRepository(database.GetConnection())
+	trialRepo := repositories.NewTrialRepository(database.GetConnection())
+	templateRepo := repositories.NewTemplateRepository(database.GetConnection())
+	personaRepo := repositories.NewPersonaRepository(database.GetConnection())
+	responsePluginRepo := repositories.NewResponsePluginRepository(database.GetConnection())
+	preferencesRepo := repositories.NewPreferencesRepository(database.GetConnection())
+	securityEventRepo := repositories.NewSecurityEventRepository(database.GetConnection())
+	scheduledRepo := repositories.NewScheduledMessageRepository(database.GetConnection())
+	idempotencyRepo := repositories.NewIdempotencyRepository(database.GetConnection())
+	invitationRepo := repositories.NewInvitationRepository(database.GetConnection())
+	userImportRepo := repositories.NewUserImportRepository(database.GetConnection())
+	docImportRepo := repositories.NewDocumentImportRepository(database.GetConnection())
+	modelAliasRepo := repositories.NewModelAliasRepository(database.GetConnection())
+	modelCatalogRepo := repositories.NewModelCatalogRepository(database.GetConnection())
+	notificationRepo := repositories.NewNotificationRepository(database.GetConnection())
+	storageRepo := repositories.NewStorageRepository(database.GetConnection())
+	gatewayConfigRepo := repositories.NewGatewayConfigRepository(database.GetConnection())
+	retentionRepo := repositories.NewRetentionRepository(database.GetConnection())
+	incidentRepo := repositories.NewIncidentRepository(database.GetConnection())
+	passkeyRepo := repositories.NewPasskeyRepository(database.GetConnection())
+	identityRepo := repositories.NewIdentityRepository(database.GetConnection(), providerKeyRepo)
+	exemptionRepo := repositories.NewExemptionRepository(database.GetConnection())
+
+	// Record chat, message and usage writes for the analytics change feed
+	var changeFeedService *services.ChangeFeedService
+	if cfg.Analytics.CDCToken != "" {
+		changeRepo := repositories.NewChangeRepository(database.GetConnection())
+		chatRepo.SetChangeFeed(changeRepo)
+		usageRepo.SetChangeFeed(changeRepo)
+		changeFeedService = services.NewChangeFeedService(changeRepo, cfg.Analytics.CDCRetention)
+	}
+
+	// Bind provider keys stored before encryption_version 2 to their owner
+	if n, err := providerKeyRepo.ReencryptLegacyKeys(); err != nil {
+		log.Printf("⚠️  Failed to re-encrypt provider keys: %v", err)
+	} else if n > 0 {
+		log.Printf("✅ Re-encrypted %d provider keys with owner-bound encryption", n)
+	}
+
+	// Python FastAPI backend location
+	backendURL := os.Getenv("BACKEND_URL")
+	if backendURL == "" {
+		backendURL = "http://localhost:8000"
+	}
+
+	// Probe the backend in the background; the gateway starts without it
+	// Record restarts, backend outages and degraded models for the status page
+	incidentService := services.NewIncidentService(incidentRepo)
+	incidentService.Start(30 * time.Second)
+
+	backendHealth := services.NewBackendHealth(backendURL)
+	backendHealth.SetIncidents(incidentService)
+	backendHealth.Start(cfg.Resilience.BackendHealthInterval)
+
 	// Initialize services
 	userService := services.NewUserService(userRepo, jwtManager)
-	docService := services.NewDocumentService(docRepo)
-	chatService := services.NewChatService(chatRepo)
+	// Reject tokens revoked on logout or by a password change
+	tokenRevocations := repositories.NewTokenRevocationRepository(database.GetConnection())
+	jwtManager.SetRevocationList(tokenRevocations)
+	userService.SetTokenRevocations(tokenRevocations)
+	userService.SetSessions(repositories.NewSessionRepository(database.GetConnection()))
+	storageService := services.NewStorageService(storageRepo, userRepo, cfg.Storage.PlanLimits)
+	storageService.SetDocumentLimits(cfg.Storage.DocumentLimits)
+	docService := services.NewDocumentService(docRepo, collectionRepo)
+	docImportService := services.NewDocumentImportService(docImportRepo, docService)
+	collectionService := services.NewCollectionService(collectionRepo)
+	docService.SetStorageService(storageService)
+	docAccessRepo := repositories.NewDocumentAccessRepository(database.GetConnection())
+	docService.SetAccessLog(docAccessRepo)
+	docService.SetVersionHistory(repositories.NewDocumentVersionRepository(database.GetConnection()))
+	docService.SetComments(repositories.NewDocumentCommentRepository(database.GetConnection()))
+	webhookService := services.NewWebhookService(repositories.NewWebhookRepository(database.GetConnection()),
+		cfg.Webhooks.Timeout, cfg.Webhooks.AllowPrivateNetworks)
+	docService.SetWebhooks(webhookService)
 	usageService := services.NewUsageService(usageRepo)
-	
+	usageService.SetQuotaResetLocation(cfg.Quota.ResetLocation)
+	usageService.SetStorageService(storageService)
+	usageService.SetPlatformKeyMarkup(cfg.PlatformKeys.Markup)
+	// Retry usage tracking that fails on transient database errors, keeping
+	// what still fails in usage_dead_letters
+	usageQueue := services.NewUsageQueue(usageService, 1000)
+	usageService.SetUsageQueue(usageQueue)
+	usageQueue.Start(2)
+	chatService := services.NewChatService(chatRepo, usageService)
+	chatService.SetAIServiceURL(cfg.Backend.AIServiceURL)
+	chatService.SetStorageService(storageService)
+	chatService.SetChatDocuments(repositories.NewChatDocumentRepository(database.GetConnection()), docRepo, docAccessRepo)
+	templateService := services.NewTemplateService(templateRepo)
+	chatService.SetTemplateService(templateService)
+	personaService := services.NewPersonaService(personaRepo)
+	chatService.SetPersonaService(personaService)
+	modelAliasService := services.NewModelAliasService(modelAliasRepo)
+	chatService.SetModelAliases(modelAliasService)
+
+	// Deprecated models warn, then map to their replacement after the sunset
+	notificationService := services.NewNotificationService(notificationRepo)
+	modelDeprecationService := services.NewModelDeprecationService(modelCatalogRepo, notificationService)
+	chatService.SetModelDeprecations(modelDeprecationService)
+	chatService.SetModelFallbacks(cfg.Routing.Fallbacks, cfg.Routing.AttemptTimeout)
+	chatService.SetPlatformKeys(cfg.PlatformKeys.Keys)
+	moderationService := services.NewModerationService(cfg.Backend.AIServiceURL, cfg.Moderation.Model, cfg.Moderation.FailOpen)
+	if cfg.Moderation.Enabled {
+		chatService.SetModerationService(moderationService)
+	}
+	securityService := services.NewSecurityService(securityEventRepo, cfg.Security.FlagWindow)
+	if cfg.Security.StrictModeration {
+		securityService.SetStrictModeration(moderationService)
+	}
+	chatService.SetSecurityService(securityService)
+	responsePluginService := services.NewResponsePluginService(responsePluginRepo)
+	chatService.SetResponsePlugins(responsePluginService)
+	preferencesService := services.NewPreferencesService(preferencesRepo)
+	chatService.SetPreferences(preferencesService)
+	modelHealth := services.NewModelHealth()
+	modelHealth.SetIncidents(incidentService)
+	chatService.SetLatencyRouting(modelHealth, cfg.Routing.Routes, cfg.Routing.RouteHysteresis)
+	var responseCache *services.ResponseCache
+	if cfg.Cache.ResponseTTL > 0 {
+		responseCache = services.NewResponseCache(cfg.Cache.ResponseTTL, cfg.Cache.ResponseMaxEntries)
+		chatService.SetResponseCache(responseCache)
+	}
+	attachmentsEnabled := false
+	var snapshotStore storage.Store
+	if attachmentStore, err := storage.NewStoreFromConfig(cfg.Attachments); err != nil {
+		log.Printf("⚠️  Message attachments and document uploads disabled: %v", err)
+	} else {
+		chatService.SetAttachmentStore(attachmentStore, cfg.App.MaxAttachmentBytes)
+		docService.SetFileStore(attachmentStore, cfg.App.MaxDocumentBytes)
+		snapshotStore = attachmentStore
+		attachmentsEnabled = true
+	}
+	// Embed documents as they are written for semantic search
+	var semanticSearchService *services.SemanticSearchService
+	if cfg.Search.SemanticEnabled {
+		chunkRepo := repositories.NewDocumentChunkRepository(database.GetConnection())
+		semanticSearchService = services.NewSemanticSearchService(chunkRepo, docRepo, chatService, cfg.Search.EmbeddingModel)
+		docService.SetSemanticIndex(semanticSearchService)
+		semanticSearchService.SetAccessLog(docAccessRepo)
+		semanticSearchService.Start()
+		chatService.SetContextSearch(semanticSearchService)
+	}
+	workspaceRepo := repositories.NewWorkspaceRepository(database.GetConnection())
+	workspaceService := services.NewWorkspaceService(workspaceRepo, snapshotStore, docService, chatService)
+	keySyncService := services.NewKeySyncService(providerKeyRepo, syncQueueRepo, backendURL)
+	trialService := services.NewTrialService(trialRepo, chatService, usageService, int(cfg.Trial.DailyCompletions), int(cfg.Trial.IPDailyCompletions), cfg.Trial.Model)
+	widgetService := services.NewWidgetService(widgetRepo, chatService, personaService, models.WidgetLimits{
+		RequestsPerMinute: cfg.Widget.RequestsPerMinute,
+		DailyRequests:     cfg.Widget.DailyRequests,
+		DailyTokens:       cfg.Widget.DailyTokens,
+		MaxTokens:         cfg.Widget.MaxTokens,
+		MaxCostUSD:        cfg.Widget.MaxCostUSD,
+	})
+	messageScheduler := services.NewMessageScheduler(scheduledRepo, chatService)
+	retentionService := services.NewRetentionService(retentionRepo, userRepo, chatService, cfg.Retention.MetadataTTL, cfg.Retention.ErrorBodyTTL)
+	retentionService.SetDocumentAccessTTL(cfg.Retention.DocumentAccessTTL)
+	cleanupService := services.NewCleanupService(repositories.NewCleanupRepository(database.GetConnection()), cfg.Cleanup.JobRetention, cfg.Cleanup.OrphanGrace)
+	cleanupService.SetGuestSessions(middleware.GuestIDPrefix, cfg.Guest.SessionTTL)
+	cleanupService.SetStore(snapshotStore)
+	mailer := mail.NewMailerFromConfig(cfg.Mail)
+	usageService.SetQuotaAlerts(repositories.NewQuotaAlertRepository(database.GetConnection()), webhookService, mailer, userRepo)
+	usageService.SetBudgets(repositories.NewBudgetRepository(database.GetConnection()))
+	orgRepo := repositories.NewOrganizationRepository(database.GetConnection())
+	usageService.SetOrganizations(orgRepo)
+	organizationService := services.NewOrganizationService(orgRepo, userRepo, usageService)
+	planRepo := repositories.NewPlanRepository(database.GetConnection())
+	usageService.SetPlans(planRepo)
+	planService := services.NewPlanService(planRepo, userRepo, usageService)
+	billingService := services.NewBillingService(repositories.NewBillingRepository(database.GetConnection()), userRepo)
+	gatewayConfigService := services.NewGatewayConfigService(gatewayConfigRepo, modelAliasRepo, environmentConfig(cfg, attachmentsEnabled))
+	provisioningService := services.NewProvisioningService(userRepo, invitationRepo, userImportRepo, mailer, cfg.Provisioning.InviteURL)
+	passkeyService := services.NewPasskeyService(passkeyRepo, userRepo, &auth.RelyingParty{
+		ID:      cfg.Passkeys.RPID,
+		Name:    cfg.Passkeys.RPName,
+		Origins: cfg.Passkeys.Origins,
+	}, cfg.Passkeys.ChallengeTTL)
+	identityService := services.NewIdentityService(identityRepo, userRepo)
+	if err := provisioningService.FailInterruptedImports(); err != nil {
+		log.Printf("⚠️  %v", err)
+	}
+	if err := docImportService.FailInterruptedImports(); err != nil {
+		log.Printf("⚠️  %v", err)
+	}
+	if err := workspaceService.FailInterrupted(); err != nil {
+		log.Printf("⚠️  %v", err)
+	}
+
+	// Retry provider key syncs queued while the backend was unreachable
+	keySyncService.Start(15 * time.Second)
+
+	// Send scheduled messages once they are due
+	messageScheduler.Start(10 * time.Second)
+
+	// Expire request logs past their retention period
+	retentionService.Start(cfg.Retention.Interval)
+
+	// Delete expired tokens, finished jobs and orphaned stored objects
+	cleanupService.Start(cfg.Cleanup.Interval)
+
+	// Start new quota days at midnight and months on the 1st
+	usageService.StartQuotaResets(cfg.Quota.ResetInterval)
+
+	// Roll usage up by hour and day for summaries and metrics
+	usageService.StartUsageRollups(5 * time.Minute)
+
+	// Close each month into billing statements once its usage is rolled up
+	billingService.Start(time.Hour)
+
+	// Take scheduled workspace snapshots once they are due
+	workspaceService.Start(5 * time.Minute)
+	if changeFeedService != nil {
+		changeFeedService.Start(cfg.Retention.Interval)
+	}
+
+	// Synthetic traffic on the exemption list skips rate limiting and usage tracking
+	exemptionService := services.NewExemptionService(exemptionRepo)
+	if err := exemptionService.Load(); err != nil {
+		log.Printf("⚠️  Failed to load rate limit exemptions: %v", err)
+	}
+	router.Use(middleware.RateLimitExemptions(exemptionService))
+
+	// Rate limiting middleware (throttles users as they approach their daily quota)
+	limiter := middleware.NewRateLimiter()
+	router.Use(middleware.DynamicRateLimitMiddleware(limiter, usageService))
+
+	// Audit events (auth, admin) for SIEM ingestion
+	auditLogger, err := audit.NewLoggerFromConfig(cfg.Audit)
+	if err != nil {
+		log.Fatalf("Failed to initialize audit logging: %v", err)
+	}
+	defer auditLogger.Close()
+
 	// Initialize handlers
 	authHandler := handlers.NewAuthHandler(userService)
+	authHandler.SetAuditLogger(auditLogger)
+	authHandler.SetPasskeyService(passkeyService)
+	if cfg.App.OnboardingSamples {
+		onboardingService, err := services.NewOnboardingService(userRepo, chatService, docService, personaService)
+		if err != nil {
+			log.Fatalf("Failed to load onboarding samples: %v", err)
+		}
+		authHandler.SetOnboarding(onboardingService)
+	}
 	docHandler := handlers.NewDocumentHandler(docService)
+	docHandler.SetRequireIfMatch(cfg.App.DocumentIfMatch)
+	docImportHandler := handlers.NewDocumentImportHandler(docImportService)
+	collectionHandler := handlers.NewCollectionHandler(collectionService)
 	chatHandler := handlers.NewChatHandler(chatService)
+	chatHandler.SetScheduler(messageScheduler)
 	usageHandler := handlers.NewUsageHandler(usageService)
 	systemHandler := handlers.NewSystemHandler(database.GetConnection())
-	providerKeyHandler := handlers.NewProviderKeyHandler(providerKeyRepo)
+	systemHandler.SetBackendHealth(backendHealth)
+	systemHandler.SetIncidents(incidentService)
+	systemHandler.SetMinAggregationUsers(cfg.Metrics.MinAggregationUsers)
+	systemHandler.SetUsageQueue(usageQueue)
+	if responseCache != nil {
+		systemHandler.SetResponseCache(responseCache)
+	}
+	providerKeyHandler := handlers.NewProviderKeyHandler(providerKeyRepo, usageService, keySyncService)
+	trialHandler := handlers.NewTrialHandler(trialService)
+	widgetHandler := handlers.NewWidgetHandler(widgetService)
+	apiKeyHandler := handlers.NewAPIKeyHandler(apiKeyService)
+	if cfg.Guest.Enabled {
+		chatHandler.SetGuestService(services.NewGuestService(usageService, cfg.Guest.DailyTokenLimit, cfg.Guest.DailyCostLimitUSD))
+	}
+	templateHandler := handlers.NewTemplateHandler(templateService)
+	personaHandler := handlers.NewPersonaHandler(personaService)
+	responsePluginHandler := handlers.NewResponsePluginHandler(responsePluginService, personaService)
+	preferencesHandler := handlers.NewPreferencesHandler(preferencesService)
+	workspaceHandler := handlers.NewWorkspaceHandler(workspaceService)
+	securityHandler := handlers.NewSecurityHandler(securityService)
+	retentionHandler := handlers.NewRetentionHandler(retentionService)
+	cleanupHandler := handlers.NewCleanupHandler(cleanupService)
+	inflightHandler := handlers.NewInflightHandler(requestRegistry)
+	identityHandler := handlers.NewIdentityHandler(identityService)
+	identityHandler.SetAuditLogger(auditLogger)
+	provisioningHandler := handlers.NewProvisioningHandler(provisioningService)
+	modelAliasHandler := handlers.NewModelAliasHandler(modelAliasService)
+	exemptionHandler := handlers.NewExemptionHandler(exemptionService)
+	modelDeprecationHandler := handlers.NewModelDeprecationHandler(modelDeprecationService)
+	notificationHandler := handlers.NewNotificationHandler(notificationService)
+	webhookHandler := handlers.NewWebhookHandler(webhookService)
+	organizationHandler := handlers.NewOrganizationHandler(organizationService, usageService)
+	planHandler := handlers.NewPlanHandler(planService)
+	billingHandler := handlers.NewBillingHandler(billingService)
+	storageHandler := handlers.NewStorageHandler(storageService)
+	gatewayConfigHandler := handlers.NewGatewayConfigHandler(gatewayConfigService)
+	costConfigService := services.NewCostConfigService(repositories.NewCostConfigRepository(database.GetConnection()), usageService)
+	costConfigService.SetModelAliases(modelAliasService)
+	if cfg.Pricing.SyncURL != "" {
+		// Keep prices current from the price feed
+		costConfigService.SetPriceFeed(cfg.Pricing.SyncURL, cfg.Pricing.SyncTimeout)
+		costConfigService.StartPriceSync(cfg.Pricing.SyncInterval)
+	}
+	costConfigHandler := handlers.NewCostConfigHandler(costConfigService)
 
 	// Initialize proxy handler for FastAPI backend
-	backendURL := os.Getenv("BACKEND_URL")
-	if backendURL == "" {
-		backendURL = "http://localhost:8000"
+	proxyHandler := handlers.NewProxyHandler(backendURL, backendHealth)
+	proxyPolicy, err := handlers.NewProxyPolicy(cfg.Proxy.Allow, cfg.Proxy.Deny, cfg.Proxy.DefaultDeny)
+	if err != nil {
+		log.Fatalf("Invalid proxy rules: %v", err)
 	}
-	proxyHandler := handlers.NewProxyHandler(backendURL)
+	proxyHandler.SetPolicy(proxyPolicy)
+	proxyHandler.SetAuditLogger(auditLogger)
 
 	// Root endpoint
 	router.GET("/", func(c *gin.Context) {
@@ -109,7 +405,22 @@ func main() {
 			auth.POST("/register", authHandler.Register)
 			auth.POST("/login", authHandler.Login)
 			auth.POST("/logout", middleware.RequireAuth(), authHandler.Logout)
+			auth.POST("/password", middleware.RequireAuth(), middleware.RequireLoginToken(), authHandler.ChangePassword)
+			auth.GET("/sessions", middleware.RequireAuth(), middleware.RequireLoginToken(), authHandler.ListSessions)
+			auth.DELETE("/sessions", middleware.RequireAuth(), middleware.RequireLoginToken(), authHandler.TerminateOtherSessions)
+			auth.DELETE("/sessions/:id", middleware.RequireAuth(), middleware.RequireLoginToken(), authHandler.TerminateSession)
 			auth.GET("/profile", middleware.RequireAuth(), authHandler.GetProfile)
+			auth.POST("/invitations/accept", provisioningHandler.AcceptInvitation)
+
+			// Passkeys: registered on a logged-in account, then usable
+			// instead of the password
+			auth.GET("/passkeys", middleware.RequireAuth(), authHandler.ListPasskeys)
+			auth.DELETE("/passkeys/:id", middleware.RequireAuth(), authHandler.DeletePasskey)
+			auth.POST("/passkeys/register/begin", middleware.RequireAuth(), authHandler.BeginPasskeyRegistration)
+			auth.POST("/passkeys/register/finish", middleware.RequireAuth(), authHandler.FinishPasskeyRegistration)
+			auth.POST("/passkeys/login/begin", authHandler.BeginPasskeyLogin)
+			auth.POST("/passkeys/login/finish", authHandler.FinishPasskeyLogin)
+			auth.POST("/link", middleware.RequireAuth(), identityHandler.LinkAccount)
 		}
 
 		// Document routes (JWT required)
@@ -117,10 +428,46 @@ func main() {
 		documents.Use(middleware.RequireAuth())
 		{
 			documents.POST("", docHandler.CreateDocument)
+			documents.POST("/upload", docHandler.UploadDocument)
+			documents.POST("/import", docImportHandler.ImportDocuments)
+			documents.GET("/import/:id", docImportHandler.GetImportJob)
 			documents.GET("", docHandler.GetDocuments)
 			documents.GET("/:id", docHandler.GetDocument)
 			documents.PUT("/:id", docHandler.UpdateDocument)
 			documents.DELETE("/:id", docHandler.DeleteDocument)
+			documents.PUT("/:id/collection", docHandler.MoveDocument)
+			documents.PATCH("/:id/favorite", docHandler.FavoriteDocument)
+			documents.GET("/:id/file", docHandler.GetDocumentFile)
+			documents.GET("/:id/export", docHandler.ExportDocument)
+			documents.GET("/:id/versions", docHandler.GetVersions)
+			documents.GET("/:id/diff", docHandler.DiffDocument)
+			documents.GET("/:id/comments", docHandler.ListComments)
+			documents.POST("/:id/comments", docHandler.CreateComment)
+			documents.PATCH("/:id/comments/:comment_id", docHandler.UpdateComment)
+			documents.DELETE("/:id/comments/:comment_id", docHandler.DeleteComment)
+			documents.GET("/:id/access-log", docHandler.GetAccessLog)
+		}
+
+		// Semantic search over document chunks (JWT required, SEMANTIC_SEARCH_ENABLED)
+		if semanticSearchService != nil {
+			semanticSearchHandler := handlers.NewSemanticSearchHandler(semanticSearchService)
+			search := api.Group("/search/semantic")
+			search.Use(middleware.RequireAuth())
+			{
+				search.GET("", semanticSearchHandler.Search)
+				search.POST("/reindex", semanticSearchHandler.Reindex)
+			}
+		}
+
+		// Document collection routes (JWT required)
+		collections := api.Group("/collections")
+		collections.Use(middleware.RequireAuth())
+		{
+			collections.POST("", collectionHandler.CreateCollection)
+			collections.GET("", collectionHandler.GetCollections)
+			collections.GET("/:id", collectionHandler.GetCollection)
+			collections.PUT("/:id", collectionHandler.UpdateCollection)
+			collections.DELETE("/:id", collectionHandler.DeleteCollection)
 		}
 
 		// Chat routes (JWT required)
@@ -132,19 +479,172 @@ func main() {
 			chats.GET("/:id", chatHandler.GetChat)
 			chats.PUT("/:id", chatHandler.UpdateChat)
 			chats.DELETE("/:id", chatHandler.DeleteChat)
-			chats.POST("/:id/messages", chatHandler.SendMessage)
+			chats.POST("/:id/duplicate", chatHandler.DuplicateChat)
+			chats.GET("/:id/summary", chatHandler.GetChatSummary)
+			chats.POST("/:id/summarize", chatHandler.SummarizeChat)
+			chats.PATCH("/:id/pin", chatHandler.PinChat)
+			chats.PATCH("/:id/unpin", chatHandler.UnpinChat)
+			chats.POST("/:id/messages", middleware.Idempotency(idempotencyRepo), chatHandler.SendMessage)
 			chats.GET("/:id/messages", chatHandler.GetMessages)
+			chats.GET("/:id/scheduled", chatHandler.GetScheduledMessages)
+			chats.DELETE("/:id/scheduled/:schedule_id", chatHandler.CancelScheduledMessage)
+			chats.PATCH("/:id/messages/:message_id/bookmark", chatHandler.BookmarkMessage)
+			chats.PATCH("/:id/messages/:message_id/unbookmark", chatHandler.UnbookmarkMessage)
+			chats.POST("/:id/messages/:message_id/feedback", chatHandler.SubmitFeedback)
+			chats.DELETE("/:id/messages/:message_id/feedback", chatHandler.DeleteFeedback)
+			chats.GET("/bookmarks", chatHandler.GetBookmarks)
+			chats.GET("/:id/stats", chatHandler.GetChatStats)
+			chats.GET("/:id/export", chatHandler.ExportChat)
+			chats.POST("/:id/stop", chatHandler.StopGeneration)
+			chats.POST("/:id/compare", chatHandler.CompareModels)
+			chats.POST("/:id/context/documents", chatHandler.AttachDocuments)
+			chats.GET("/:id/context/documents", chatHandler.ListDocuments)
+			chats.DELETE("/:id/context/documents/:document_id", chatHandler.DetachDocument)
+			chats.GET("/:id/attachments/:attachment_id", chatHandler.GetAttachment)
 			
 			// UUID-based routes
 			chats.GET("/uuid/:uuid", chatHandler.GetChatByUUID)
-			chats.POST("/uuid/:uuid/messages", chatHandler.SendMessageByUUID)
+			chats.POST("/uuid/:uuid/messages", middleware.Idempotency(idempotencyRepo), chatHandler.SendMessageByUUID)
 			chats.GET("/uuid/:uuid/messages", chatHandler.GetMessagesByUUID)
 		}
 
-		// Chat completion endpoint (JWT required)
-		api.POST("/chat/completions", middleware.RequireAuth(), chatHandler.ChatCompletion)
+		// Prompt template routes (JWT required)
+		templates := api.Group("/templates")
+		templates.Use(middleware.RequireAuth())
+		{
+			templates.POST("", templateHandler.CreateTemplate)
+			templates.GET("", templateHandler.GetTemplates)
+			templates.GET("/:id", templateHandler.GetTemplate)
+			templates.PUT("/:id", templateHandler.UpdateTemplate)
+			templates.DELETE("/:id", templateHandler.DeleteTemplate)
+			templates.POST("/:id/render", templateHandler.RenderTemplate)
+		}
+
+		// Assistant persona routes (JWT required)
+		personas := api.Group("/personas")
+		personas.Use(middleware.RequireAuth())
+		{
+			personas.POST("", personaHandler.CreatePersona)
+			personas.GET("", personaHandler.GetPersonas)
+			personas.GET("/:id", personaHandler.GetPersona)
+			personas.PUT("/:id", personaHandler.UpdatePersona)
+			personas.DELETE("/:id", personaHandler.DeletePersona)
+			personas.PUT("/:id/response-plugins", responsePluginHandler.SetPersona)
+			personas.DELETE("/:id/response-plugins", responsePluginHandler.DeletePersona)
+		}
+
+		// Post-processing of assistant output (JWT required)
+		responsePlugins := api.Group("/response-plugins")
+		responsePlugins.Use(middleware.RequireAuth())
+		{
+			responsePlugins.GET("", responsePluginHandler.ListPlugins)
+			responsePlugins.PUT("", responsePluginHandler.SetDefault)
+		}
+
+		// Account-wide settings, such as provider order for routed models (JWT required)
+		preferences := api.Group("/preferences")
+		preferences.Use(middleware.RequireAuth())
+		{
+			preferences.GET("", preferencesHandler.GetPreferences)
+			preferences.PUT("", preferencesHandler.UpdatePreferences)
+		}
+
+		// Snapshots and point-in-time restore of chats, documents and settings (JWT required)
+		workspace := api.Group("/workspace")
+		workspace.Use(middleware.RequireAuth())
+		{
+			workspace.POST("/snapshots", workspaceHandler.CreateSnapshot)
+			workspace.GET("/snapshots", workspaceHandler.ListSnapshots)
+			workspace.GET("/snapshots/:id", workspaceHandler.GetSnapshot)
+			workspace.DELETE("/snapshots/:id", workspaceHandler.DeleteSnapshot)
+			workspace.POST("/restore", workspaceHandler.Restore)
+			workspace.GET("/restores/:id", workspaceHandler.GetRestore)
+			workspace.GET("/snapshot-schedule", workspaceHandler.GetSchedule)
+			workspace.PUT("/snapshot-schedule", workspaceHandler.SetSchedule)
+		}
+
+		// Model aliases clients can name instead of a concrete model (JWT required)
+		api.GET("/model-aliases", middleware.RequireAuth(), modelAliasHandler.ListAliases)
+
+		// Notification center
+		notifications := api.Group("/notifications")
+		notifications.Use(middleware.RequireAuth())
+		{
+			notifications.GET("", notificationHandler.ListNotifications)
+			notifications.POST("/:id/read", notificationHandler.MarkRead)
+			notifications.POST("/read-all", notificationHandler.MarkAllRead)
+		}
+
+		// Webhooks receiving signed document events, with a console for
+		// test deliveries and retries
+		webhooks := api.Group("/webhooks")
+		webhooks.Use(middleware.RequireAuth())
+		{
+			webhooks.GET("", webhookHandler.ListWebhooks)
+			webhooks.POST("", webhookHandler.CreateWebhook)
+			webhooks.GET("/events", webhookHandler.ListEvents)
+			webhooks.DELETE("/:id", webhookHandler.DeleteWebhook)
+			webhooks.POST("/:id/test", webhookHandler.TestWebhook)
+			webhooks.GET("/:id/deliveries", webhookHandler.ListDeliveries)
+			webhooks.POST("/:id/deliveries/:delivery_id/retry", webhookHandler.RetryDelivery)
+		}
+
+		// Chat completion endpoint (JWT required). Guest mode lets unauthenticated clients chat under a signed
+		// guest session with reduced limits
+		completionAuth := middleware.RequireAuth()
+		if cfg.Guest.Enabled {
+			completionAuth = middleware.GuestSession(jwtManager, cfg.Guest.SessionTTL, limiter, cfg.Guest.RequestsPerMinute)
+		}
+		api.POST("/chat/completions", completionAuth, middleware.Idempotency(idempotencyRepo), chatHandler.ChatCompletion)
+
+		// Embeddings endpoint (JWT required), proxied to the model's provider with the user's key
+		api.POST("/embeddings", middleware.RequireAuth(), chatHandler.CreateEmbeddings)
+		api.POST("/tokens/count", middleware.RequireAuth(), chatHandler.CountTokens)
+
+		// Anonymous trial routes (NO JWT, per-device and per-IP daily quotas)
+		if cfg.Trial.Enabled {
+			trial := api.Group("/trial", middleware.TrialRateLimit(limiter, cfg.Trial.RequestsPerMinute))
+			{
+				trial.POST("/chat/completions", trialHandler.ChatCompletion)
+				trial.GET("/quota", trialHandler.GetQuota)
+			}
+		}
+
+		// Widget tokens (JWT required), and the public completions of the
+		// chat widgets embedded with them
+		widgetTokens := api.Group("/widget-tokens")
+		widgetTokens.Use(middleware.RequireAuth())
+		{
+			widgetTokens.POST("", widgetHandler.CreateToken)
+			widgetTokens.GET("", widgetHandler.ListTokens)
+			widgetTokens.DELETE("/:id", widgetHandler.RevokeToken)
+		}
+		api.POST("/widget/chat/completions", middleware.WidgetAuth(widgetService, limiter), widgetHandler.ChatCompletion)
 
 		// Usage routes (JWT required)
+		// Plan tiers and the limits they give
+		api.GET("/plans", middleware.RequireAuth(), planHandler.ListPlans)
+
+		billing := api.Group("/billing")
+		billing.Use(middleware.RequireAuth())
+		{
+			billing.GET("/statements", billingHandler.ListStatements)
+			billing.GET("/statements/:id", billingHandler.GetStatement)
+			billing.GET("/statements/:id/download", billingHandler.DownloadStatement)
+		}
+
+		// Organizations whose members share quotas and budgets
+		orgs := api.Group("/orgs")
+		orgs.Use(middleware.RequireAuth())
+		{
+			orgs.POST("", organizationHandler.CreateOrganization)
+			orgs.GET("/current", organizationHandler.GetCurrentOrganization)
+			orgs.GET("/:id", organizationHandler.GetOrganization)
+			orgs.DELETE("/:id", organizationHandler.DeleteOrganization)
+			orgs.POST("/:id/members", organizationHandler.AddMember)
+			orgs.DELETE("/:id/members/:user_id", organizationHandler.RemoveMember)
+		}
+
 		usage := api.Group("/usage")
 		usage.Use(middleware.RequireAuth())
 		{
@@ -153,8 +653,21 @@ func main() {
 			usage.POST("/track", usageHandler.TrackUsage)
 			usage.POST("/check-quota", usageHandler.CheckQuota)
 			usage.GET("/dashboard", usageHandler.GetDashboard)
+			usage.POST("/simulate", usageHandler.SimulateUsage)
+			usage.POST("/estimate", chatHandler.EstimateCost)
+			usage.GET("/export", usageHandler.ExportUsage)
+			usage.GET("/timeseries", usageHandler.GetUsageTimeseries)
+			usage.GET("/stream", usageHandler.StreamUsage)
+			usage.GET("/by-resource", usageHandler.GetUsageByResource)
+			usage.GET("/budget", usageHandler.GetBudget)
+			usage.GET("/alerts", usageHandler.ListQuotaAlerts)
+			usage.GET("/alerts/settings", usageHandler.GetQuotaAlertSettings)
+			usage.PUT("/alerts/settings", usageHandler.UpdateQuotaAlertSettings)
 		}
 
+		// Public status page JSON (NO JWT)
+		api.GET("/status", systemHandler.GetStatus)
+
 		// System routes (JWT required)
 		system := api.Group("/system")
 		system.Use(middleware.RequireAuth())
@@ -162,20 +675,115 @@ func main() {
 			system.GET("/metrics", systemHandler.GetMetrics)
 			system.GET("/info", systemHandler.GetInfo)
 			system.GET("/stats", systemHandler.GetStats)
+			system.GET("/changelog", systemHandler.GetChangelog)
+			system.GET("/incidents", systemHandler.GetIncidents)
 		}
 
 		// Provider API Key routes (JWT required)
+		// Personal API keys; managing them needs a login, not a key
+		personalKeys := api.Group("/api-keys-personal")
+		personalKeys.Use(middleware.RequireAuth(), middleware.RequireLoginToken())
+		{
+			personalKeys.GET("", apiKeyHandler.ListKeys)
+			personalKeys.POST("", apiKeyHandler.CreateKey)
+			personalKeys.GET("/:id", apiKeyHandler.GetKey)
+			personalKeys.PATCH("/:id", apiKeyHandler.UpdateKey)
+			personalKeys.DELETE("/:id", apiKeyHandler.RevokeKey)
+		}
+
+		// Provider keys are returned decrypted, so a leaked personal API key
+		// must not reach them; managing them needs a login
 		apiKeys := api.Group("/api-keys")
-		apiKeys.Use(middleware.RequireAuth())
+		apiKeys.Use(middleware.RequireAuth(), middleware.RequireLoginToken())
 		{
 			apiKeys.GET("", providerKeyHandler.GetAllKeys)
 			apiKeys.POST("", providerKeyHandler.CreateOrUpdateKey)
 			apiKeys.POST("/sync", providerKeyHandler.SyncAllKeys)
 			apiKeys.DELETE("/:provider", providerKeyHandler.DeleteKey)
 			apiKeys.GET("/:provider", providerKeyHandler.GetProviderKey)
+			apiKeys.GET("/:provider/usage", providerKeyHandler.GetKeyUsage)
+		}
+
+		// Admin routes (admin role required)
+		admin := api.Group("/admin")
+		admin.Use(middleware.AuditMiddleware(auditLogger, "admin.request"), middleware.RequireRole("admin"))
+		{
+			admin.GET("/sync-queue", providerKeyHandler.GetSyncQueue)
+			admin.POST("/users/import", provisioningHandler.ImportUsers)
+			admin.GET("/users/import/:id", provisioningHandler.GetImportJob)
+			admin.POST("/users/:id/purge", retentionHandler.PurgeUser)
+			admin.POST("/users/:id/merge", identityHandler.MergeUsers)
+			admin.PUT("/users/:id/budget", usageHandler.SetUserBudget)
+			admin.DELETE("/users/:id/budget", usageHandler.DeleteUserBudget)
+			admin.GET("/budgets", usageHandler.ListBudgets)
+			admin.PUT("/plans/:name", planHandler.SetPlan)
+			admin.PUT("/users/:id/plan", planHandler.AssignPlan)
+			admin.POST("/users/:id/billing/adjustments", billingHandler.AddAdjustment)
+			admin.POST("/billing/close", billingHandler.ClosePeriod)
+			admin.PUT("/orgs/:id/quota", organizationHandler.SetQuota)
+			admin.DELETE("/orgs/:id/quota", organizationHandler.DeleteQuota)
+			admin.PUT("/orgs/:id/budget", organizationHandler.SetBudget)
+			admin.DELETE("/orgs/:id/budget", organizationHandler.DeleteBudget)
+			admin.GET("/usage/summary", usageHandler.GetAdminUsageSummary)
+			admin.GET("/usage/top-users", usageHandler.GetTopUsers)
+			admin.GET("/usage/by-model", usageHandler.GetUsageByModel)
+			admin.GET("/usage/dead-letters", usageHandler.ListUsageDeadLetters)
+			admin.POST("/usage/dead-letters/replay", usageHandler.ReplayUsageDeadLetters)
+			admin.GET("/identity-merges", identityHandler.ListMerges)
+			admin.POST("/retention/run", retentionHandler.EnforceRetention)
+			admin.GET("/cleanup", cleanupHandler.GetStats)
+			admin.POST("/cleanup/run", cleanupHandler.RunCleanup)
+			admin.GET("/feedback/summary", chatHandler.GetFeedbackSummary)
+			admin.GET("/model-health", chatHandler.GetModelHealth)
+			admin.GET("/inflight", inflightHandler.ListInflight)
+			admin.POST("/inflight/:id/cancel", inflightHandler.CancelInflight)
+			admin.GET("/security-events", securityHandler.ListEvents)
+			admin.PUT("/model-aliases/:alias", modelAliasHandler.SetAlias)
+			admin.DELETE("/model-aliases/:alias", modelAliasHandler.DeleteAlias)
+			admin.GET("/model-deprecations", modelDeprecationHandler.ListDeprecations)
+			admin.PUT("/model-deprecations/*model", modelDeprecationHandler.Deprecate)
+			admin.DELETE("/model-deprecations/*model", modelDeprecationHandler.Undeprecate)
+			admin.GET("/storage/top", storageHandler.GetTopConsumers)
+			admin.GET("/exemptions", exemptionHandler.ListExemptions)
+			admin.POST("/exemptions", exemptionHandler.CreateExemption)
+			admin.DELETE("/exemptions/:id", exemptionHandler.DeleteExemption)
+			admin.GET("/response-plugins/stats", responsePluginHandler.GetStats)
+			admin.GET("/config/export", gatewayConfigHandler.ExportConfig)
+			admin.POST("/config/import", gatewayConfigHandler.ImportConfig)
+			admin.GET("/cost-config", costConfigHandler.ListCostConfigs)
+			admin.POST("/cost-config", costConfigHandler.CreateCostConfig)
+			admin.GET("/cost-config/effective", costConfigHandler.GetEffectivePrice)
+			admin.GET("/cost-config/history", costConfigHandler.ListPriceHistory)
+			admin.POST("/cost-config/sync", costConfigHandler.SyncPrices)
+			admin.PATCH("/cost-config/*model", costConfigHandler.UpdateCostConfig)
+			admin.DELETE("/cost-config/*model", costConfigHandler.DeactivateCostConfig)
+
+			// Runtime profiling (go tool pprof)
+			handlers.RegisterProfilingRoutes(admin)
 		}
 	}
 
+	// SCIM 2.0 provisioning for identity providers (SCIM_BEARER_TOKEN required)
+	if cfg.Provisioning.SCIMToken != "" {
+		scimHandler := handlers.NewSCIMHandler(provisioningService)
+		scim := router.Group("/scim/v2")
+		scim.Use(middleware.AuditMiddleware(auditLogger, "scim.request"), middleware.SCIMAuth(cfg.Provisioning.SCIMToken))
+		{
+			scim.GET("/Users", scimHandler.ListUsers)
+			scim.POST("/Users", scimHandler.CreateUser)
+			scim.GET("/Users/:id", scimHandler.GetUser)
+			scim.PUT("/Users/:id", scimHandler.ReplaceUser)
+			scim.PATCH("/Users/:id", scimHandler.PatchUser)
+			scim.DELETE("/Users/:id", scimHandler.DeleteUser)
+		}
+	}
+
+	// Change data feed for the analytics service (CDC_BEARER_TOKEN required)
+	if changeFeedService != nil {
+		changeFeedHandler := handlers.NewChangeFeedHandler(changeFeedService)
+		router.GET("/api/v1/cdc", middleware.ServiceTokenAuth(cfg.Analytics.CDCToken), changeFeedHandler.ListChanges)
+	}
+
 	// Proxy routes for code generation service (JWT required)
 	codeGen := router.Group("/api/v1/codegen")
 	codeGen.Use(middleware.RequireAuth())
@@ -236,3 +844,59 @@ func main() {
 		log.Fatalf("Server failed to start: %v", err)
 	}
 }
+
+// environmentConfig describes the settings read from environment variables,
+// for configuration export and comparison on import
+func environmentConfig(cfg *config.Config, attachmentsEnabled bool) *models.GatewayConfig {
+	plans := make(map[string]models.PlanSetting, len(cfg.Storage.PlanLimits))
+	for plan, limit := range cfg.Storage.PlanLimits {
+		plans[plan] = models.PlanSetting{StorageLimitBytes: limit}
+	}
+	fallbacks := cfg.Routing.Fallbacks
+	if fallbacks == nil {
+		fallbacks = map[string][]string{}
+	}
+
+	return &models.GatewayConfig{
+		Environment: cfg.App.Environment,
+		Plans:       plans,
+		Features: map[string]bool{
+			"trial":                       cfg.Trial.Enabled,
+			"guest_mode":                  cfg.Guest.Enabled,
+			"moderation":                  cfg.Moderation.Enabled,
+			"jailbreak_strict_moderation": cfg.Security.StrictModeration,
+			"attachments":                 attachmentsEnabled,
+			"document_uploads":            attachmentsEnabled,
+			"scim":                        cfg.Provisioning.SCIMToken != "",
+			"change_feed":                 cfg.Analytics.CDCToken != "",
+			"semantic_search":             cfg.Search.SemanticEnabled,
+			"workspace_snapshots":         attachmentsEnabled,
+			"proxy_default_deny":          cfg.Proxy.DefaultDeny,
+			"document_require_if_match":   cfg.App.DocumentIfMatch,
+			"onboarding_samples":          cfg.App.OnboardingSamples,
+		},
+		Routing: &models.RoutingSetting{
+			Fallbacks:             fallbacks,
+			AttemptTimeoutSeconds: int64(cfg.Routing.AttemptTimeout / time.Second),
+			Routes:                cfg.Routing.Routes,
+		},
+		Policies: map[string]int64{
+			"max_attachment_bytes":             cfg.App.MaxAttachmentBytes,
+			"max_document_upload_bytes":        cfg.App.MaxDocumentBytes,
+			"trial_daily_completions":          cfg.Trial.DailyCompletions,
+			"trial_ip_daily_completions":       cfg.Trial.IPDailyCompletions,
+			"trial_requests_per_minute":        int64(cfg.Trial.RequestsPerMinute),
+			"metrics_min_aggregation_users":    int64(cfg.Metrics.MinAggregationUsers),
+			"llm_cache_ttl_seconds":            int64(cfg.Cache.ResponseTTL / time.Second),
+			"guest_daily_tokens":               int64(cfg.Guest.DailyTokenLimit),
+			"guest_requests_per_minute":        int64(cfg.Guest.RequestsPerMinute),
+			"log_retention_metadata_seconds":   int64(cfg.Retention.MetadataTTL / time.Second),
+			"log_retention_error_body_seconds": int64(cfg.Retention.ErrorBodyTTL / time.Second),
+			"widget_requests_per_minute":       int64(cfg.Widget.RequestsPerMinute),
+			"widget_daily_requests":            int64(cfg.Widget.DailyRequests),
+			"widget_daily_tokens":              int64(cfg.Widget.DailyTokens),
+			"change_feed_retention_seconds":    int64(cfg.Analytics.CDCRetention / time.Second),
+			"doc_access_log_retention_seconds": int64(cfg.Retention.DocumentAccessTTL / time.Second),
+		},
+	}
+}
@@ -0,0 +1,121 @@
+package audit
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"lio-ai/internal/config"
+)
+
+// Outcomes recorded on audit events
+const (
+	OutcomeSuccess = "success"
+	OutcomeFailure = "failure"
+	OutcomeDenied  = "denied"
+)
+
+// Event is a security-relevant action (authentication, admin operations)
+type Event struct {
+	Time       time.Time              `json:"time"`
+	Type       string                 `json:"type"` // e.g. "auth.login", "admin.request"
+	Outcome    string                 `json:"outcome"`
+	ActorID    string                 `json:"actor_id,omitempty"`
+	ActorEmail string                 `json:"actor_email,omitempty"`
+	IP         string                 `json:"ip,omitempty"`
+	Method     string                 `json:"method,omitempty"`
+	Path       string                 `json:"path,omitempty"`
+	Status     int                    `json:"status,omitempty"`
+	Details    map[string]interface{} `json:"details,omitempty"`
+}
+
+// Sink receives audit events, e.g. to forward them to a SIEM
+type Sink interface {
+	Write(event Event) error
+	Close() error
+}
+
+// Logger fans audit events out to all configured sinks
+type Logger struct {
+	sinks []Sink
+	mu    sync.Mutex
+}
+
+// NewLogger creates an audit logger writing to sinks
+func NewLogger(sinks ...Sink) *Logger {
+	return &Logger{sinks: sinks}
+}
+
+// Log records an event. A nil logger discards events.
+func (l *Logger) Log(event Event) {
+	if l == nil || len(l.sinks) == 0 {
+		return
+	}
+	if event.Time.IsZero() {
+		event.Time = time.Now().UTC()
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, sink := range l.sinks {
+		if err := sink.Write(event); err != nil {
+			log.Printf("⚠️  Audit sink write failed: %v", err)
+		}
+	}
+}
+
+// Close flushes and closes all sinks
+func (l *Logger) Close() error {
+	if l == nil {
+		return nil
+	}
+	var firstErr error
+	for _, sink := range l.sinks {
+		if err := sink.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// NewLoggerFromConfig builds a logger writing to the sinks cfg lists.
+// With no sinks listed, audit events go to stdout.
+func NewLoggerFromConfig(cfg config.AuditConfig) (*Logger, error) {
+	names := cfg.Sinks
+	if len(names) == 0 {
+		names = []string{"stdout"}
+	}
+
+	var sinks []Sink
+	for _, name := range names {
+		var sink Sink
+		var err error
+
+		switch strings.TrimSpace(name) {
+		case "":
+			continue
+		case "none":
+			continue
+		case "stdout":
+			sink = NewStdoutSink()
+		case "file":
+			sink, err = NewRotatingFileSink(cfg.FilePath, cfg.FileMaxBytes, cfg.FileMaxBackups)
+		case "syslog":
+			sink, err = NewSyslogSink(cfg.SyslogNetwork, cfg.SyslogAddr, cfg.SyslogTag)
+		case "http":
+			sink, err = NewHTTPSink(cfg.HTTPURL, cfg.HTTPToken)
+		default:
+			err = fmt.Errorf("unknown audit sink: %s", name)
+		}
+
+		if err != nil {
+			NewLogger(sinks...).Close()
+			return nil, err
+		}
+		sinks = append(sinks, sink)
+	}
+
+	return NewLogger(sinks...), nil
+}
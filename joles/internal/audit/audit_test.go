@@ -0,0 +1,70 @@
+package audit
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"lio-ai/internal/config"
+)
+
+func TestNewLoggerFromConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit", "audit.log")
+	logger, err := NewLoggerFromConfig(config.AuditConfig{
+		Sinks:          []string{"file", "none"},
+		FilePath:       path,
+		FileMaxBytes:   1 << 20,
+		FileMaxBackups: 1,
+	})
+	if err != nil {
+		t.Fatalf("NewLoggerFromConfig failed: %v", err)
+	}
+	if len(logger.sinks) != 1 {
+		t.Fatalf("logger has %d sinks, want the file sink only", len(logger.sinks))
+	}
+	logger.Log(Event{Type: "auth.login", Outcome: OutcomeSuccess, ActorID: "1"})
+	if err := logger.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("audit file not written at the configured path: %v", err)
+	}
+	if !strings.Contains(string(data), `"type":"auth.login"`) {
+		t.Errorf("audit file = %q, want the logged event", data)
+	}
+}
+
+func TestNewLoggerFromConfigErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  config.AuditConfig
+		want string
+	}{
+		{"unknown sink", config.AuditConfig{Sinks: []string{"stdout", "kafka"}}, "unknown audit sink: kafka"},
+		{"http without url", config.AuditConfig{Sinks: []string{"http"}}, "AUDIT_HTTP_URL is required"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewLoggerFromConfig(tt.cfg)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("err = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewLoggerFromConfigDefaultsToStdout(t *testing.T) {
+	logger, err := NewLoggerFromConfig(config.AuditConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(logger.sinks) != 1 {
+		t.Fatalf("logger has %d sinks, want stdout", len(logger.sinks))
+	}
+	if _, ok := logger.sinks[0].(*JSONSink); !ok {
+		t.Errorf("sink is %T, want the stdout JSON sink", logger.sinks[0])
+	}
+}
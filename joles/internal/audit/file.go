@@ -0,0 +1,119 @@
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// JSONSink writes one JSON object per line to a writer
+type JSONSink struct {
+	w  io.Writer
+	mu sync.Mutex
+}
+
+// NewStdoutSink writes audit events as JSON lines to stdout
+func NewStdoutSink() *JSONSink {
+	return &JSONSink{w: os.Stdout}
+}
+
+// Write encodes an event as a JSON line
+func (s *JSONSink) Write(event Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return json.NewEncoder(s.w).Encode(event)
+}
+
+// Close is a no-op; stdout stays open
+func (s *JSONSink) Close() error {
+	return nil
+}
+
+// RotatingFileSink writes JSON lines to a file, rotating it once it reaches
+// maxBytes and keeping up to maxBackups old files (audit.log.1, .2, ...)
+type RotatingFileSink struct {
+	path       string
+	maxBytes   int64
+	maxBackups int
+
+	mu   sync.Mutex
+	file *os.File
+	size int64
+}
+
+// NewRotatingFileSink opens (or creates) the audit log file
+func NewRotatingFileSink(path string, maxBytes int64, maxBackups int) (*RotatingFileSink, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create audit log directory: %w", err)
+	}
+	s := &RotatingFileSink{path: path, maxBytes: maxBytes, maxBackups: maxBackups}
+	if err := s.open(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *RotatingFileSink) open() error {
+	f, err := os.OpenFile(s.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open audit log: %w", err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("failed to stat audit log: %w", err)
+	}
+	s.file = f
+	s.size = info.Size()
+	return nil
+}
+
+// rotate shifts audit.log -> audit.log.1 -> audit.log.2 ... and reopens
+func (s *RotatingFileSink) rotate() error {
+	if err := s.file.Close(); err != nil {
+		return err
+	}
+	os.Remove(fmt.Sprintf("%s.%d", s.path, s.maxBackups))
+	for i := s.maxBackups - 1; i >= 1; i-- {
+		os.Rename(fmt.Sprintf("%s.%d", s.path, i), fmt.Sprintf("%s.%d", s.path, i+1))
+	}
+	if s.maxBackups > 0 {
+		if err := os.Rename(s.path, s.path+".1"); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	} else {
+		os.Remove(s.path)
+	}
+	return s.open()
+}
+
+// Write appends an event, rotating first if the file is full
+func (s *RotatingFileSink) Write(event Event) error {
+	line, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.maxBytes > 0 && s.size+int64(len(line)) > s.maxBytes && s.size > 0 {
+		if err := s.rotate(); err != nil {
+			return fmt.Errorf("failed to rotate audit log: %w", err)
+		}
+	}
+	n, err := s.file.Write(line)
+	s.size += int64(n)
+	return err
+}
+
+// Close closes the audit log file
+func (s *RotatingFileSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.file.Close()
+}
@@ -0,0 +1,129 @@
+package audit
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	httpSinkBuffer    = 1000
+	httpSinkBatchSize = 100
+	httpSinkFlush     = 2 * time.Second
+)
+
+// HTTPSink batches events and POSTs them as a JSON array to a collector
+// (e.g. a SIEM HTTP event endpoint). Writes never block request handling;
+// events are dropped if the buffer is full.
+type HTTPSink struct {
+	url    string
+	token  string
+	client *http.Client
+	events chan Event
+	done   chan struct{}
+	once   sync.Once
+}
+
+// NewHTTPSink starts a forwarder posting to url with an optional bearer token
+func NewHTTPSink(url, token string) (*HTTPSink, error) {
+	if url == "" {
+		return nil, fmt.Errorf("AUDIT_HTTP_URL is required for the http audit sink")
+	}
+	s := &HTTPSink{
+		url:    url,
+		token:  token,
+		client: &http.Client{Timeout: 10 * time.Second},
+		events: make(chan Event, httpSinkBuffer),
+		done:   make(chan struct{}),
+	}
+	go s.run()
+	return s, nil
+}
+
+// Write queues an event for delivery
+func (s *HTTPSink) Write(event Event) error {
+	select {
+	case s.events <- event:
+		return nil
+	default:
+		return fmt.Errorf("audit http sink buffer full, event dropped")
+	}
+}
+
+// Close flushes queued events and stops the forwarder
+func (s *HTTPSink) Close() error {
+	s.once.Do(func() { close(s.events) })
+	<-s.done
+	return nil
+}
+
+func (s *HTTPSink) run() {
+	defer close(s.done)
+
+	ticker := time.NewTicker(httpSinkFlush)
+	defer ticker.Stop()
+
+	batch := make([]Event, 0, httpSinkBatchSize)
+	for {
+		select {
+		case event, ok := <-s.events:
+			if !ok {
+				s.flush(batch)
+				return
+			}
+			batch = append(batch, event)
+			if len(batch) >= httpSinkBatchSize {
+				s.flush(batch)
+				batch = batch[:0]
+			}
+		case <-ticker.C:
+			s.flush(batch)
+			batch = batch[:0]
+		}
+	}
+}
+
+// flush posts a batch, retrying once on failure
+func (s *HTTPSink) flush(batch []Event) {
+	if len(batch) == 0 {
+		return
+	}
+	body, err := json.Marshal(batch)
+	if err != nil {
+		log.Printf("⚠️  Audit http sink: failed to encode batch: %v", err)
+		return
+	}
+
+	for attempt := 1; attempt <= 2; attempt++ {
+		if err = s.post(body); err == nil {
+			return
+		}
+		time.Sleep(time.Second)
+	}
+	log.Printf("⚠️  Audit http sink: dropped %d events: %v", len(batch), err)
+}
+
+func (s *HTTPSink) post(body []byte) error {
+	req, err := http.NewRequest(http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.token != "" {
+		req.Header.Set("Authorization", "Bearer "+s.token)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("collector returned status %d", resp.StatusCode)
+	}
+	return nil
+}
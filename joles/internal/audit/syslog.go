@@ -0,0 +1,41 @@
+//go:build !windows && !plan9
+
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/syslog"
+)
+
+// SyslogSink forwards events as JSON messages to syslog
+type SyslogSink struct {
+	w *syslog.Writer
+}
+
+// NewSyslogSink connects to syslog. An empty network and addr use the
+// local syslog daemon; otherwise e.g. ("udp", "siem.internal:514").
+func NewSyslogSink(network, addr, tag string) (*SyslogSink, error) {
+	w, err := syslog.Dial(network, addr, syslog.LOG_AUTH|syslog.LOG_INFO, tag)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to syslog: %w", err)
+	}
+	return &SyslogSink{w: w}, nil
+}
+
+// Write sends an event; failures and denials are logged at warning level
+func (s *SyslogSink) Write(event Event) error {
+	msg, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	if event.Outcome == OutcomeSuccess {
+		return s.w.Info(string(msg))
+	}
+	return s.w.Warning(string(msg))
+}
+
+// Close closes the syslog connection
+func (s *SyslogSink) Close() error {
+	return s.w.Close()
+}
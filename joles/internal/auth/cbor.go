@@ -0,0 +1,146 @@
+package auth
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// errCBORTruncated is returned for CBOR that ends mid-item
+var errCBORTruncated = errors.New("cbor: unexpected end of data")
+
+// Nesting deeper than this is rejected; WebAuthn structures are shallow
+const cborMaxDepth = 16
+
+// decodeCBOR decodes the first CBOR item in data and returns it with the
+// bytes that follow it. It covers what WebAuthn authenticators emit:
+// integers, byte and text strings, arrays, maps and simple values, all of
+// definite length. Integers decode to int64, maps to
+// map[interface{}]interface{}.
+func decodeCBOR(data []byte) (interface{}, []byte, error) {
+	return decodeCBORItem(data, 0)
+}
+
+func decodeCBORItem(data []byte, depth int) (interface{}, []byte, error) {
+	if depth > cborMaxDepth {
+		return nil, nil, errors.New("cbor: nested too deeply")
+	}
+	if len(data) == 0 {
+		return nil, nil, errCBORTruncated
+	}
+	major, info := data[0]>>5, data[0]&0x1f
+	data = data[1:]
+
+	// Simple values and floats carry their value in the additional info
+	if major == 7 {
+		switch info {
+		case 20:
+			return false, data, nil
+		case 21:
+			return true, data, nil
+		case 22, 23:
+			return nil, data, nil
+		case 25, 26, 27:
+			// Floats are skipped; nothing WebAuthn verifies is one
+			size := 1 << (info - 24)
+			if len(data) < size {
+				return nil, nil, errCBORTruncated
+			}
+			return nil, data[size:], nil
+		}
+		return nil, nil, fmt.Errorf("cbor: unsupported simple value %d", info)
+	}
+
+	n, data, err := cborArgument(info, data)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	switch major {
+	case 0:
+		if n > 1<<63-1 {
+			return nil, nil, errors.New("cbor: integer overflows int64")
+		}
+		return int64(n), data, nil
+	case 1:
+		if n > 1<<63-1 {
+			return nil, nil, errors.New("cbor: integer overflows int64")
+		}
+		return -1 - int64(n), data, nil
+	case 2, 3:
+		if uint64(len(data)) < n {
+			return nil, nil, errCBORTruncated
+		}
+		if major == 3 {
+			return string(data[:n]), data[n:], nil
+		}
+		return data[:n:n], data[n:], nil
+	case 4:
+		if uint64(len(data)) < n {
+			return nil, nil, errCBORTruncated
+		}
+		items := make([]interface{}, 0, n)
+		for i := uint64(0); i < n; i++ {
+			var item interface{}
+			if item, data, err = decodeCBORItem(data, depth+1); err != nil {
+				return nil, nil, err
+			}
+			items = append(items, item)
+		}
+		return items, data, nil
+	case 5:
+		if uint64(len(data)) < n {
+			return nil, nil, errCBORTruncated
+		}
+		m := make(map[interface{}]interface{}, n)
+		for i := uint64(0); i < n; i++ {
+			var key, value interface{}
+			if key, data, err = decodeCBORItem(data, depth+1); err != nil {
+				return nil, nil, err
+			}
+			switch key.(type) {
+			case int64, string:
+			default:
+				return nil, nil, errors.New("cbor: map keys must be integers or strings")
+			}
+			if value, data, err = decodeCBORItem(data, depth+1); err != nil {
+				return nil, nil, err
+			}
+			m[key] = value
+		}
+		return m, data, nil
+	case 6:
+		// Tags are dropped, keeping the tagged item
+		return decodeCBORItem(data, depth+1)
+	}
+	return nil, nil, fmt.Errorf("cbor: unsupported major type %d", major)
+}
+
+// cborArgument reads the argument of an item header from its additional
+// info and the bytes following the initial byte
+func cborArgument(info byte, data []byte) (uint64, []byte, error) {
+	switch {
+	case info < 24:
+		return uint64(info), data, nil
+	case info <= 27:
+		size := 1 << (info - 24)
+		if len(data) < size {
+			return 0, nil, errCBORTruncated
+		}
+		var n uint64
+		switch size {
+		case 1:
+			n = uint64(data[0])
+		case 2:
+			n = uint64(binary.BigEndian.Uint16(data))
+		case 4:
+			n = uint64(binary.BigEndian.Uint32(data))
+		case 8:
+			n = binary.BigEndian.Uint64(data)
+		}
+		return n, data[size:], nil
+	case info == 31:
+		return 0, nil, errors.New("cbor: indefinite lengths are not supported")
+	}
+	return 0, nil, fmt.Errorf("cbor: invalid additional info %d", info)
+}
@@ -0,0 +1,94 @@
+package auth
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func mustHex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(strings.ReplaceAll(s, " ", ""))
+	if err != nil {
+		t.Fatalf("bad hex %q: %v", s, err)
+	}
+	return b
+}
+
+func TestDecodeCBOR(t *testing.T) {
+	tests := []struct {
+		name string
+		hex  string
+		want interface{}
+		rest string
+	}{
+		{"small int", "17", int64(23), ""},
+		{"one-byte int", "1818", int64(24), ""},
+		{"eight-byte int", "1b 7fffffffffffffff", int64(1<<63 - 1), ""},
+		{"negative int", "26", int64(-7), ""},
+		{"two-byte negative int", "390100", int64(-257), ""},
+		{"byte string", "43 010203", []byte{1, 2, 3}, ""},
+		{"text string", "64 6e6f6e65", "none", ""},
+		{"array", "83 01 02 03", []interface{}{int64(1), int64(2), int64(3)}, ""},
+		{"map", "a2 01 02 63 666d74 64 6e6f6e65", map[interface{}]interface{}{int64(1): int64(2), "fmt": "none"}, ""},
+		{"simple values", "83 f4 f5 f6", []interface{}{false, true, nil}, ""},
+		{"float is skipped", "fa 3fc00000", nil, ""},
+		{"tag keeps item", "c2 41 01", []byte{1}, ""},
+		{"trailing bytes are returned", "01 02 03", int64(1), "0203"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, rest, err := decodeCBOR(mustHex(t, tt.hex))
+			if err != nil {
+				t.Fatalf("decodeCBOR: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("decodeCBOR = %#v, want %#v", got, tt.want)
+			}
+			if !bytes.Equal(rest, mustHex(t, tt.rest)) {
+				t.Errorf("rest = %x, want %s", rest, tt.rest)
+			}
+		})
+	}
+}
+
+func TestDecodeCBORRejectsMalformedInput(t *testing.T) {
+	tests := []struct {
+		name      string
+		data      []byte
+		truncated bool
+	}{
+		{"empty", nil, true},
+		{"missing argument", []byte{0x19, 0x01}, true},
+		{"short byte string", []byte{0x45, 1, 2}, true},
+		{"short text string", []byte{0x63, 'a'}, true},
+		{"array missing items", []byte{0x83, 1, 2}, true},
+		{"map missing value", []byte{0xa1, 1}, true},
+		{"short float", []byte{0xfb, 0, 0}, true},
+		// Lengths far beyond the input fail before anything is allocated
+		{"oversized byte string", []byte{0x5b, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}, true},
+		{"oversized array", []byte{0x9b, 0x7f, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x01}, true},
+		{"oversized map", []byte{0xba, 0xff, 0xff, 0xff, 0xff, 0x01, 0x02}, true},
+		{"integer overflowing int64", []byte{0x1b, 0x80, 0, 0, 0, 0, 0, 0, 0}, false},
+		{"negative overflowing int64", []byte{0x3b, 0x80, 0, 0, 0, 0, 0, 0, 0}, false},
+		{"indefinite length", []byte{0x5f, 0x41, 1, 0xff}, false},
+		{"reserved additional info", []byte{0x1c}, false},
+		{"unsupported simple value", []byte{0xe0}, false},
+		{"array map key", []byte{0xa1, 0x80, 0x01}, false},
+		{"nested too deeply", bytes.Repeat([]byte{0x81}, cborMaxDepth+2), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := decodeCBOR(tt.data)
+			if err == nil {
+				t.Fatalf("decodeCBOR(%x) succeeded, want an error", tt.data)
+			}
+			if got := errors.Is(err, errCBORTruncated); got != tt.truncated {
+				t.Errorf("decodeCBOR(%x) error = %v, truncated %v, want %v", tt.data, err, got, tt.truncated)
+			}
+		})
+	}
+}
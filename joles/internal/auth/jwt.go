@@ -7,19 +7,32 @@ import (
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 )
 
+// ErrTokenRevoked is returned for tokens revoked before they expired
+var ErrTokenRevoked = errors.New("token has been revoked")
+
+// RevocationList reports whether a login token was revoked, by its jti or
+// by a revocation of all of its user's tokens issued before a time
+type RevocationList interface {
+	IsRevoked(tokenID, userID string, issuedAt time.Time) (bool, error)
+}
+
 // Claims represents JWT claims with user information
 type Claims struct {
 	UserID string   `json:"user_id"`
 	Email  string   `json:"email"`
 	Roles  []string `json:"roles"`
+	// Set on guest session tokens; these identify a guest, not an account
+	Guest bool `json:"guest,omitempty"`
 	jwt.RegisteredClaims
 }
 
 // JWTManager manages JWT token generation and validation
 type JWTManager struct {
-	secretKey string
+	secretKey   string
+	revocations RevocationList
 }
 
 // NewJWTManager creates a new JWT manager
@@ -36,13 +49,43 @@ func NewJWTManager() (*JWTManager, error) {
 	return &JWTManager{secretKey: secretKey}, nil
 }
 
-// GenerateToken creates a new JWT token
-func (jm *JWTManager) GenerateToken(userID, email string, roles []string, expiresIn time.Duration) (string, error) {
+// SetRevocationList rejects login tokens revoked in list
+func (jm *JWTManager) SetRevocationList(list RevocationList) {
+	jm.revocations = list
+}
+
+// GenerateToken creates a new JWT token with a unique jti, by which it can
+// be revoked, returning it with its claims
+func (jm *JWTManager) GenerateToken(userID, email string, roles []string, expiresIn time.Duration) (string, *Claims, error) {
 	now := time.Now()
 	claims := &Claims{
 		UserID: userID,
 		Email:  email,
 		Roles:  roles,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.New().String(),
+			ExpiresAt: jwt.NewNumericDate(now.Add(expiresIn)),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	tokenString, err := token.SignedString([]byte(jm.secretKey))
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to sign token: %w", err)
+	}
+
+	return tokenString, claims, nil
+}
+
+// GenerateGuestToken creates a token for a guest session. Guest tokens
+// are only accepted by the guest session middleware.
+func (jm *JWTManager) GenerateGuestToken(guestID string, expiresIn time.Duration) (string, error) {
+	now := time.Now()
+	claims := &Claims{
+		UserID: guestID,
+		Guest:  true,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(now.Add(expiresIn)),
 			IssuedAt:  jwt.NewNumericDate(now),
@@ -59,7 +102,8 @@ func (jm *JWTManager) GenerateToken(userID, email string, roles []string, expire
 	return tokenString, nil
 }
 
-// ValidateToken validates and parses a JWT token
+// ValidateToken validates and parses a JWT token. Login tokens in the
+// revocation list fail with ErrTokenRevoked.
 func (jm *JWTManager) ValidateToken(tokenString string) (*Claims, error) {
 	claims := &Claims{}
 	
@@ -83,6 +127,20 @@ func (jm *JWTManager) ValidateToken(tokenString string) (*Claims, error) {
 		return nil, errors.New("invalid token")
 	}
 
+	if jm.revocations != nil && !claims.Guest {
+		var issuedAt time.Time
+		if claims.IssuedAt != nil {
+			issuedAt = claims.IssuedAt.Time
+		}
+		revoked, err := jm.revocations.IsRevoked(claims.ID, claims.UserID, issuedAt)
+		if err != nil {
+			return nil, err
+		}
+		if revoked {
+			return nil, ErrTokenRevoked
+		}
+	}
+
 	return claims, nil
 }
 
@@ -0,0 +1,104 @@
+package auth
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// fakeRevocations revokes the jtis in tokens, and every token of a user
+// issued before the cutoff in before
+type fakeRevocations struct {
+	tokens map[string]bool
+	before map[string]time.Time
+	err    error
+	calls  int
+}
+
+func (f *fakeRevocations) IsRevoked(tokenID, userID string, issuedAt time.Time) (bool, error) {
+	f.calls++
+	if f.err != nil {
+		return false, f.err
+	}
+	return f.tokens[tokenID] || issuedAt.Before(f.before[userID]), nil
+}
+
+func newTestJWTManager(t *testing.T) *JWTManager {
+	t.Helper()
+	t.Setenv("JWT_SECRET_KEY", "test-secret-key-at-least-32-bytes!")
+	jm, err := NewJWTManager()
+	if err != nil {
+		t.Fatalf("NewJWTManager: %v", err)
+	}
+	return jm
+}
+
+func TestGenerateTokenSetsUniqueJTI(t *testing.T) {
+	jm := newTestJWTManager(t)
+
+	_, first, err := jm.GenerateToken("1", "a@example.com", []string{"user"}, time.Hour)
+	if err != nil {
+		t.Fatalf("GenerateToken: %v", err)
+	}
+	_, second, err := jm.GenerateToken("1", "a@example.com", []string{"user"}, time.Hour)
+	if err != nil {
+		t.Fatalf("GenerateToken: %v", err)
+	}
+	if first.ID == "" || first.ID == second.ID {
+		t.Fatalf("jti = %q and %q, want distinct non-empty IDs", first.ID, second.ID)
+	}
+}
+
+func TestValidateTokenChecksRevocations(t *testing.T) {
+	jm := newTestJWTManager(t)
+	list := &fakeRevocations{tokens: map[string]bool{}, before: map[string]time.Time{}}
+	jm.SetRevocationList(list)
+
+	token, claims, err := jm.GenerateToken("1", "a@example.com", []string{"user"}, time.Hour)
+	if err != nil {
+		t.Fatalf("GenerateToken: %v", err)
+	}
+	other, _, err := jm.GenerateToken("1", "a@example.com", []string{"user"}, time.Hour)
+	if err != nil {
+		t.Fatalf("GenerateToken: %v", err)
+	}
+	if _, err := jm.ValidateToken(token); err != nil {
+		t.Fatalf("ValidateToken before revocation: %v", err)
+	}
+
+	list.tokens[claims.ID] = true
+	if _, err := jm.ValidateToken(token); !errors.Is(err, ErrTokenRevoked) {
+		t.Errorf("revoked jti: error = %v, want ErrTokenRevoked", err)
+	}
+	if _, err := jm.ValidateToken(other); err != nil {
+		t.Errorf("other token of the user: %v", err)
+	}
+
+	list.before["1"] = time.Now().Add(time.Second)
+	if _, err := jm.ValidateToken(other); !errors.Is(err, ErrTokenRevoked) {
+		t.Errorf("token issued before the user's cutoff: error = %v, want ErrTokenRevoked", err)
+	}
+
+	list.err = errors.New("database is locked")
+	if _, err := jm.ValidateToken(other); err == nil || errors.Is(err, ErrTokenRevoked) {
+		t.Errorf("failing revocation list: error = %v, want its error", err)
+	}
+}
+
+func TestValidateTokenSkipsRevocationsForGuests(t *testing.T) {
+	jm := newTestJWTManager(t)
+	list := &fakeRevocations{err: errors.New("unused")}
+	jm.SetRevocationList(list)
+
+	token, err := jm.GenerateGuestToken("guest_1", time.Hour)
+	if err != nil {
+		t.Fatalf("GenerateGuestToken: %v", err)
+	}
+	claims, err := jm.ValidateToken(token)
+	if err != nil {
+		t.Fatalf("ValidateToken: %v", err)
+	}
+	if !claims.Guest || list.calls != 0 {
+		t.Errorf("guest = %v after %d revocation checks, want a guest token and none", claims.Guest, list.calls)
+	}
+}
@@ -0,0 +1,268 @@
+package auth
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"slices"
+)
+
+// ErrPasskeyInvalid is returned when a WebAuthn response fails verification
+var ErrPasskeyInvalid = errors.New("passkey verification failed")
+
+// COSE algorithms accepted for passkeys, in order of preference
+const (
+	COSEAlgES256 = -7
+	COSEAlgEdDSA = -8
+	COSEAlgRS256 = -257
+)
+
+// PasskeyAlgorithms are offered to authenticators at registration
+var PasskeyAlgorithms = []int{COSEAlgES256, COSEAlgEdDSA, COSEAlgRS256}
+
+// Authenticator data flags
+const (
+	flagUserPresent    = 0x01
+	flagUserVerified   = 0x04
+	flagBackupEligible = 0x08
+	flagAttestedData   = 0x40
+)
+
+// Bytes of authenticator data before the attested credential data
+const authDataHeaderLen = 37
+
+// RelyingParty verifies WebAuthn ceremonies for one site. Passkeys are
+// the only factor when used to log in, so user verification is required.
+//
+// Attestation is requested as "none": the statement is not checked, and
+// any authenticator the browser accepts can register.
+type RelyingParty struct {
+	ID      string   // Domain passkeys are scoped to, e.g. "example.com"
+	Name    string   // Shown by the browser during registration
+	Origins []string // Origins allowed to run ceremonies, e.g. "https://example.com"
+}
+
+// PasskeyCredential is a credential created at registration
+type PasskeyCredential struct {
+	ID             []byte
+	PublicKey      []byte // COSE_Key, as sent by the authenticator
+	SignCount      uint32
+	BackupEligible bool // Synced passkey rather than a device-bound key
+}
+
+// clientData is the JSON the browser signs over, collected by the client
+type clientData struct {
+	Type      string `json:"type"`
+	Challenge string `json:"challenge"`
+	Origin    string `json:"origin"`
+}
+
+// NewPasskeyChallenge returns a random challenge for a ceremony
+func NewPasskeyChallenge() ([]byte, error) {
+	challenge := make([]byte, 32)
+	if _, err := rand.Read(challenge); err != nil {
+		return nil, fmt.Errorf("failed to generate challenge: %w", err)
+	}
+	return challenge, nil
+}
+
+// VerifyRegistration checks an authenticator's response to a registration
+// ceremony started with challenge and returns the new credential
+func (rp *RelyingParty) VerifyRegistration(challenge, clientDataJSON, attestationObject []byte) (*PasskeyCredential, error) {
+	if err := rp.verifyClientData(clientDataJSON, "webauthn.create", challenge); err != nil {
+		return nil, err
+	}
+
+	decoded, _, err := decodeCBOR(attestationObject)
+	if err != nil {
+		return nil, fmt.Errorf("%w: attestation object: %v", ErrPasskeyInvalid, err)
+	}
+	attestation, ok := decoded.(map[interface{}]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%w: attestation object is not a map", ErrPasskeyInvalid)
+	}
+	authData, ok := attestation["authData"].([]byte)
+	if !ok {
+		return nil, fmt.Errorf("%w: attestation object has no authenticator data", ErrPasskeyInvalid)
+	}
+
+	flags, signCount, err := rp.verifyAuthData(authData)
+	if err != nil {
+		return nil, err
+	}
+	if flags&flagAttestedData == 0 {
+		return nil, fmt.Errorf("%w: no credential in authenticator data", ErrPasskeyInvalid)
+	}
+
+	// aaguid (16) | credential ID length (2) | credential ID | COSE_Key
+	rest := authData[authDataHeaderLen:]
+	if len(rest) < 18 {
+		return nil, fmt.Errorf("%w: truncated credential data", ErrPasskeyInvalid)
+	}
+	idLen := int(binary.BigEndian.Uint16(rest[16:18]))
+	rest = rest[18:]
+	if idLen == 0 || idLen > 1023 || len(rest) < idLen {
+		return nil, fmt.Errorf("%w: invalid credential ID", ErrPasskeyInvalid)
+	}
+	credentialID := rest[:idLen]
+	_, after, err := decodeCBOR(rest[idLen:])
+	if err != nil {
+		return nil, fmt.Errorf("%w: credential public key: %v", ErrPasskeyInvalid, err)
+	}
+	publicKey := rest[idLen : len(rest)-len(after)]
+	if _, _, err := parseCOSEKey(publicKey); err != nil {
+		return nil, err
+	}
+
+	return &PasskeyCredential{
+		ID:             bytes.Clone(credentialID),
+		PublicKey:      bytes.Clone(publicKey),
+		SignCount:      signCount,
+		BackupEligible: flags&flagBackupEligible != 0,
+	}, nil
+}
+
+// VerifyLogin checks an authenticator's assertion for a login ceremony
+// started with challenge, signed by the credential with publicKey, and
+// returns the authenticator's new signature counter
+func (rp *RelyingParty) VerifyLogin(challenge, publicKey, clientDataJSON, authData, signature []byte) (uint32, error) {
+	if err := rp.verifyClientData(clientDataJSON, "webauthn.get", challenge); err != nil {
+		return 0, err
+	}
+	_, signCount, err := rp.verifyAuthData(authData)
+	if err != nil {
+		return 0, err
+	}
+
+	key, alg, err := parseCOSEKey(publicKey)
+	if err != nil {
+		return 0, err
+	}
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	signed := append(bytes.Clone(authData), clientDataHash[:]...)
+	if !verifySignature(key, alg, signed, signature) {
+		return 0, fmt.Errorf("%w: invalid signature", ErrPasskeyInvalid)
+	}
+	return signCount, nil
+}
+
+// verifyClientData checks the ceremony type, challenge and origin the
+// browser recorded
+func (rp *RelyingParty) verifyClientData(clientDataJSON []byte, ceremony string, challenge []byte) error {
+	var data clientData
+	if err := json.Unmarshal(clientDataJSON, &data); err != nil {
+		return fmt.Errorf("%w: invalid client data", ErrPasskeyInvalid)
+	}
+	if data.Type != ceremony {
+		return fmt.Errorf("%w: client data is for %q, not %q", ErrPasskeyInvalid, data.Type, ceremony)
+	}
+	got, err := base64.RawURLEncoding.DecodeString(data.Challenge)
+	if err != nil || subtle.ConstantTimeCompare(got, challenge) != 1 {
+		return fmt.Errorf("%w: challenge does not match", ErrPasskeyInvalid)
+	}
+	if !slices.Contains(rp.Origins, data.Origin) {
+		return fmt.Errorf("%w: origin %q is not allowed", ErrPasskeyInvalid, data.Origin)
+	}
+	return nil
+}
+
+// verifyAuthData checks the relying party ID hash and the user presence
+// and verification flags, returning the flags and signature counter
+func (rp *RelyingParty) verifyAuthData(authData []byte) (byte, uint32, error) {
+	if len(authData) < authDataHeaderLen {
+		return 0, 0, fmt.Errorf("%w: truncated authenticator data", ErrPasskeyInvalid)
+	}
+	rpIDHash := sha256.Sum256([]byte(rp.ID))
+	if subtle.ConstantTimeCompare(authData[:32], rpIDHash[:]) != 1 {
+		return 0, 0, fmt.Errorf("%w: credential is for another relying party", ErrPasskeyInvalid)
+	}
+	flags := authData[32]
+	if flags&flagUserPresent == 0 {
+		return 0, 0, fmt.Errorf("%w: user not present", ErrPasskeyInvalid)
+	}
+	if flags&flagUserVerified == 0 {
+		return 0, 0, fmt.Errorf("%w: user not verified", ErrPasskeyInvalid)
+	}
+	return flags, binary.BigEndian.Uint32(authData[33:37]), nil
+}
+
+// parseCOSEKey decodes a COSE_Key for one of PasskeyAlgorithms
+func parseCOSEKey(data []byte) (crypto.PublicKey, int, error) {
+	decoded, _, err := decodeCBOR(data)
+	if err != nil {
+		return nil, 0, fmt.Errorf("%w: public key: %v", ErrPasskeyInvalid, err)
+	}
+	m, ok := decoded.(map[interface{}]interface{})
+	if !ok {
+		return nil, 0, fmt.Errorf("%w: public key is not a COSE key", ErrPasskeyInvalid)
+	}
+	kty, _ := m[int64(1)].(int64)
+	alg, _ := m[int64(3)].(int64)
+	param := func(label int64) []byte {
+		b, _ := m[label].([]byte)
+		return b
+	}
+
+	switch {
+	case kty == 2 && alg == COSEAlgES256:
+		// EC2 on P-256: crv (-1) = 1, x (-2), y (-3)
+		x, y := param(-2), param(-3)
+		if crv, _ := m[int64(-1)].(int64); crv != 1 || len(x) != 32 || len(y) != 32 {
+			return nil, 0, fmt.Errorf("%w: unsupported EC key", ErrPasskeyInvalid)
+		}
+		// ecdh rejects points not on the curve
+		point := append(append([]byte{4}, x...), y...)
+		if _, err := ecdh.P256().NewPublicKey(point); err != nil {
+			return nil, 0, fmt.Errorf("%w: invalid EC key", ErrPasskeyInvalid)
+		}
+		key := &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		return key, COSEAlgES256, nil
+	case kty == 1 && alg == COSEAlgEdDSA:
+		// OKP: crv (-1) = 6 (Ed25519), x (-2)
+		x := param(-2)
+		if crv, _ := m[int64(-1)].(int64); crv != 6 || len(x) != ed25519.PublicKeySize {
+			return nil, 0, fmt.Errorf("%w: unsupported OKP key", ErrPasskeyInvalid)
+		}
+		return ed25519.PublicKey(x), COSEAlgEdDSA, nil
+	case kty == 3 && alg == COSEAlgRS256:
+		// RSA: n (-1), e (-2)
+		n, e := param(-1), param(-2)
+		if len(n) < 256 || len(e) == 0 || len(e) > 4 {
+			return nil, 0, fmt.Errorf("%w: unsupported RSA key", ErrPasskeyInvalid)
+		}
+		exponent := 0
+		for _, b := range e {
+			exponent = exponent<<8 | int(b)
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: exponent}, COSEAlgRS256, nil
+	}
+	return nil, 0, fmt.Errorf("%w: unsupported key type %d with algorithm %d", ErrPasskeyInvalid, kty, alg)
+}
+
+// verifySignature checks signature over data with a key from parseCOSEKey
+func verifySignature(key crypto.PublicKey, alg int, data, signature []byte) bool {
+	switch alg {
+	case COSEAlgES256:
+		digest := sha256.Sum256(data)
+		return ecdsa.VerifyASN1(key.(*ecdsa.PublicKey), digest[:], signature)
+	case COSEAlgEdDSA:
+		return ed25519.Verify(key.(ed25519.PublicKey), data, signature)
+	case COSEAlgRS256:
+		digest := sha256.Sum256(data)
+		return rsa.VerifyPKCS1v15(key.(*rsa.PublicKey), crypto.SHA256, digest[:], signature) == nil
+	}
+	return false
+}
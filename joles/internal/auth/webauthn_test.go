@@ -0,0 +1,215 @@
+package auth
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+var testRP = &RelyingParty{ID: "example.com", Name: "Example", Origins: []string{"https://example.com"}}
+
+// cborHead encodes a CBOR item header
+func cborHead(major byte, n int) []byte {
+	switch {
+	case n < 24:
+		return []byte{major<<5 | byte(n)}
+	case n < 1<<8:
+		return []byte{major<<5 | 24, byte(n)}
+	default:
+		return []byte{major<<5 | 25, byte(n >> 8), byte(n)}
+	}
+}
+
+func cborByteString(b []byte) []byte {
+	return append(cborHead(2, len(b)), b...)
+}
+
+// testAuthenticator holds an ES256 credential and produces the responses
+// of a platform authenticator for testRP
+type testAuthenticator struct {
+	key          *ecdsa.PrivateKey
+	credentialID []byte
+}
+
+func newTestAuthenticator(t *testing.T) *testAuthenticator {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	return &testAuthenticator{key: key, credentialID: []byte("credential-1")}
+}
+
+// coseKey encodes the public key as {1: 2, 3: -7, -1: 1, -2: x, -3: y}
+func (a *testAuthenticator) coseKey() []byte {
+	key := []byte{0xa5, 0x01, 0x02, 0x03, 0x26, 0x20, 0x01, 0x21}
+	key = append(key, cborByteString(a.key.X.FillBytes(make([]byte, 32)))...)
+	key = append(key, 0x22)
+	return append(key, cborByteString(a.key.Y.FillBytes(make([]byte, 32)))...)
+}
+
+func authDataHeader(rpID string, flags byte, signCount uint32) []byte {
+	rpIDHash := sha256.Sum256([]byte(rpID))
+	data := append(rpIDHash[:], flags)
+	return binary.BigEndian.AppendUint32(data, signCount)
+}
+
+// attestationObject wraps authenticator data with the credential in a
+// "none" attestation
+func (a *testAuthenticator) attestationObject(authData []byte) []byte {
+	obj := []byte{0xa3, 0x63, 'f', 'm', 't', 0x64, 'n', 'o', 'n', 'e', 0x67, 'a', 't', 't', 'S', 't', 'm', 't', 0xa0, 0x68, 'a', 'u', 't', 'h', 'D', 'a', 't', 'a'}
+	return append(obj, cborByteString(authData)...)
+}
+
+func (a *testAuthenticator) registrationAuthData(flags byte) []byte {
+	data := authDataHeader(testRP.ID, flags|flagAttestedData, 0)
+	data = append(data, make([]byte, 16)...) // aaguid
+	data = binary.BigEndian.AppendUint16(data, uint16(len(a.credentialID)))
+	data = append(data, a.credentialID...)
+	return append(data, a.coseKey()...)
+}
+
+func (a *testAuthenticator) sign(t *testing.T, authData, clientDataJSON []byte) []byte {
+	t.Helper()
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	digest := sha256.Sum256(append(bytes.Clone(authData), clientDataHash[:]...))
+	signature, err := ecdsa.SignASN1(rand.Reader, a.key, digest[:])
+	if err != nil {
+		t.Fatalf("SignASN1: %v", err)
+	}
+	return signature
+}
+
+func testClientData(ceremony string, challenge []byte, origin string) []byte {
+	return []byte(fmt.Sprintf(`{"type":%q,"challenge":%q,"origin":%q,"crossOrigin":false}`,
+		ceremony, base64.RawURLEncoding.EncodeToString(challenge), origin))
+}
+
+// A login assertion for testRP by an ES256 credential, recorded once
+const (
+	vectorCOSEKey    = "a5010203262001215820d21e3da2c671fc5eaf7576e3c63a8a552e251983a008103a91fab9fa87c4bacd2258206f57ead9ff2ea533938eca12f93f738fdc37a048f36c2abdbcdb93c9ec4c55fc"
+	vectorAuthData   = "a379a6f6eeafb9a55e378c118034e2751e682fab9f2d30ab13d2125586ce19470500000007"
+	vectorClientData = `{"type":"webauthn.get","challenge":"Zml4ZWQtY2hhbGxlbmdlLWZvci1lczI1Ni12ZWN0b3I","origin":"https://example.com","crossOrigin":false}`
+	vectorChallenge  = "fixed-challenge-for-es256-vector"
+	vectorSignature  = "30460221008e0609b7f7013a96a083ed73e8265788572989a325e547133d9de0bce1ae683e022100a9dfe07991979033af7f56fa92710e4f343081d4b1ced9618ab25933f9714a3b"
+)
+
+func TestVerifyLoginES256Vector(t *testing.T) {
+	signCount, err := testRP.VerifyLogin([]byte(vectorChallenge), mustHex(t, vectorCOSEKey),
+		[]byte(vectorClientData), mustHex(t, vectorAuthData), mustHex(t, vectorSignature))
+	if err != nil {
+		t.Fatalf("VerifyLogin: %v", err)
+	}
+	if signCount != 7 {
+		t.Errorf("signCount = %d, want 7", signCount)
+	}
+
+	// One flipped bit in the signed data fails the signature
+	tampered := mustHex(t, vectorAuthData)
+	tampered[len(tampered)-1] ^= 1
+	if _, err := testRP.VerifyLogin([]byte(vectorChallenge), mustHex(t, vectorCOSEKey),
+		[]byte(vectorClientData), tampered, mustHex(t, vectorSignature)); !errors.Is(err, ErrPasskeyInvalid) {
+		t.Errorf("tampered authenticator data: error = %v, want ErrPasskeyInvalid", err)
+	}
+}
+
+func TestVerifyLogin(t *testing.T) {
+	authenticator := newTestAuthenticator(t)
+	challenge := []byte("login-challenge")
+
+	tests := []struct {
+		name       string
+		challenge  []byte
+		clientData []byte
+		authData   []byte
+		resign     bool // Sign the modified response, so only the change is caught
+		wantErr    bool
+	}{
+		{"valid", challenge, testClientData("webauthn.get", challenge, "https://example.com"), authDataHeader("example.com", flagUserPresent|flagUserVerified, 42), true, false},
+		{"wrong origin", challenge, testClientData("webauthn.get", challenge, "https://evil.example"), authDataHeader("example.com", flagUserPresent|flagUserVerified, 42), true, true},
+		{"origin on another port", challenge, testClientData("webauthn.get", challenge, "https://example.com:8443"), authDataHeader("example.com", flagUserPresent|flagUserVerified, 42), true, true},
+		{"registration client data", challenge, testClientData("webauthn.create", challenge, "https://example.com"), authDataHeader("example.com", flagUserPresent|flagUserVerified, 42), true, true},
+		{"other challenge", []byte("another-challenge"), testClientData("webauthn.get", challenge, "https://example.com"), authDataHeader("example.com", flagUserPresent|flagUserVerified, 42), true, true},
+		{"rpIdHash mismatch", challenge, testClientData("webauthn.get", challenge, "https://example.com"), authDataHeader("evil.example", flagUserPresent|flagUserVerified, 42), true, true},
+		{"user not present", challenge, testClientData("webauthn.get", challenge, "https://example.com"), authDataHeader("example.com", flagUserVerified, 42), true, true},
+		{"user not verified", challenge, testClientData("webauthn.get", challenge, "https://example.com"), authDataHeader("example.com", flagUserPresent, 42), true, true},
+		{"truncated authenticator data", challenge, testClientData("webauthn.get", challenge, "https://example.com"), authDataHeader("example.com", flagUserPresent|flagUserVerified, 42)[:36], true, true},
+		{"invalid client data", challenge, []byte("not json"), authDataHeader("example.com", flagUserPresent|flagUserVerified, 42), true, true},
+		{"unsigned change", challenge, testClientData("webauthn.get", challenge, "https://example.com"), authDataHeader("example.com", flagUserPresent|flagUserVerified, 42), false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			signed := tt.authData
+			if !tt.resign {
+				signed = authDataHeader("example.com", flagUserPresent|flagUserVerified, 41)
+			}
+			signature := authenticator.sign(t, signed, tt.clientData)
+
+			signCount, err := testRP.VerifyLogin(tt.challenge, authenticator.coseKey(), tt.clientData, tt.authData, signature)
+			if tt.wantErr {
+				if !errors.Is(err, ErrPasskeyInvalid) {
+					t.Fatalf("VerifyLogin error = %v, want ErrPasskeyInvalid", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("VerifyLogin: %v", err)
+			}
+			if signCount != 42 {
+				t.Errorf("signCount = %d, want 42", signCount)
+			}
+		})
+	}
+}
+
+func TestVerifyRegistration(t *testing.T) {
+	authenticator := newTestAuthenticator(t)
+	challenge := []byte("registration-challenge")
+	clientData := testClientData("webauthn.create", challenge, "https://example.com")
+
+	credential, err := testRP.VerifyRegistration(challenge, clientData,
+		authenticator.attestationObject(authenticator.registrationAuthData(flagUserPresent|flagUserVerified|flagBackupEligible)))
+	if err != nil {
+		t.Fatalf("VerifyRegistration: %v", err)
+	}
+	if !bytes.Equal(credential.ID, authenticator.credentialID) || !bytes.Equal(credential.PublicKey, authenticator.coseKey()) {
+		t.Errorf("credential = %x with key %x, want %x with key %x", credential.ID, credential.PublicKey, authenticator.credentialID, authenticator.coseKey())
+	}
+	if !credential.BackupEligible {
+		t.Errorf("BackupEligible = false, want true")
+	}
+
+	valid := authenticator.registrationAuthData(flagUserPresent | flagUserVerified)
+	oversizedID := bytes.Clone(valid)
+	binary.BigEndian.PutUint16(oversizedID[authDataHeaderLen+16:], 1024)
+	tests := []struct {
+		name              string
+		clientData        []byte
+		attestationObject []byte
+	}{
+		{"login client data", testClientData("webauthn.get", challenge, "https://example.com"), authenticator.attestationObject(valid)},
+		{"wrong origin", testClientData("webauthn.create", challenge, "http://example.com"), authenticator.attestationObject(valid)},
+		{"rpIdHash mismatch", clientData, authenticator.attestationObject(append(authDataHeader("evil.example", flagUserPresent|flagUserVerified|flagAttestedData, 0), valid[authDataHeaderLen:]...))},
+		{"user not verified", clientData, authenticator.attestationObject(authenticator.registrationAuthData(flagUserPresent))},
+		{"no attested credential", clientData, authenticator.attestationObject(authDataHeader("example.com", flagUserPresent|flagUserVerified, 0))},
+		{"truncated credential data", clientData, authenticator.attestationObject(valid[:authDataHeaderLen+10])},
+		{"oversized credential ID", clientData, authenticator.attestationObject(oversizedID)},
+		{"truncated public key", clientData, authenticator.attestationObject(valid[:len(valid)-5])},
+		{"truncated attestation object", clientData, authenticator.attestationObject(valid)[:20]},
+		{"attestation object not a map", clientData, cborByteString(valid)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := testRP.VerifyRegistration(challenge, tt.clientData, tt.attestationObject); !errors.Is(err, ErrPasskeyInvalid) {
+				t.Errorf("VerifyRegistration error = %v, want ErrPasskeyInvalid", err)
+			}
+		})
+	}
+}
@@ -4,16 +4,43 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
 
 // Config holds the application configuration
 type Config struct {
-	Server   ServerConfig
-	Database DatabaseConfig
-	Backend  BackendConfig
-	App      AppConfig
+	Server       ServerConfig
+	Database     DatabaseConfig
+	Backend      BackendConfig
+	App          AppConfig
+	Resilience   ResilienceConfig
+	Trial        TrialConfig
+	Guest        GuestConfig
+	Provisioning ProvisioningConfig
+	Routing      RoutingConfig
+	Storage      StorageConfig
+	Attachments  AttachmentStoreConfig
+	Metrics      MetricsConfig
+	Cache        CacheConfig
+	Moderation   ModerationConfig
+	Security     SecurityConfig
+	Retention    RetentionConfig
+	Cleanup      CleanupConfig
+	Webhooks     WebhookConfig
+	Proxy        ProxyConfig
+	Passkeys     PasskeyConfig
+	Widget       WidgetConfig
+	Analytics    AnalyticsConfig
+	Search       SearchConfig
+	Quota        QuotaConfig
+	Pricing      PricingConfig
+	Audit        AuditConfig
+	Mail         MailConfig
+	PlatformKeys PlatformKeysConfig
 }
 
 // ServerConfig contains server configuration
@@ -28,6 +55,229 @@ type BackendConfig struct {
 	AIServicePort string
 }
 
+// ResilienceConfig controls startup retries and dependency health checks
+type ResilienceConfig struct {
+	DBConnectRetries      int           // Attempts before giving up on the initial DB connection
+	DBConnectBackoff      time.Duration // Initial delay between attempts (doubles each retry)
+	DBConnectMaxBackoff   time.Duration
+	BackendHealthInterval time.Duration // How often the backend health is probed
+}
+
+// TrialConfig controls anonymous trial access for public demos
+type TrialConfig struct {
+	Enabled          bool
+	DailyCompletions   int64  // Completions per device/IP per UTC day
+	IPDailyCompletions int64  // Completions per IP per UTC day, across devices
+	RequestsPerMinute  int    // Trial requests per client IP
+	Model              string // Empty selects the cheapest active chat model
+}
+
+// GuestConfig controls guest mode, where unauthenticated clients chat
+// under a temporary user ID bound to a signed cookie
+type GuestConfig struct {
+	Enabled           bool
+	SessionTTL        time.Duration // Lifetime of a guest session cookie
+	DailyTokenLimit   int           // Token quota per guest session per day
+	DailyCostLimitUSD float64
+	RequestsPerMinute int
+}
+
+// PlatformKeysConfig holds the platform-managed provider keys completions
+// fall back to when a user's own key is rejected or exhausted
+type PlatformKeysConfig struct {
+	// By lowercase provider, from PLATFORM_<PROVIDER>_API_KEY
+	Keys map[string]string
+	// Cost multiplier for usage billed through a platform key
+	Markup float64
+}
+
+// ProvisioningConfig controls bulk user import and SCIM provisioning
+type ProvisioningConfig struct {
+	SCIMToken string // Bearer token for /scim/v2; SCIM is disabled when empty
+	InviteURL string // Invitation link base; the token is appended as ?token=
+}
+
+// AnalyticsConfig controls the change feed the analytics service reads
+// chats, messages and usage from
+type AnalyticsConfig struct {
+	CDCToken     string        // Bearer token for /api/v1/cdc; the feed is disabled when empty
+	CDCRetention time.Duration // How long change events are kept; 0 keeps them indefinitely
+}
+
+// SearchConfig controls semantic search over users' documents
+type SearchConfig struct {
+	// Embed documents as they are written; the embeddings are billed to
+	// each document's owner
+	SemanticEnabled bool
+	EmbeddingModel  string
+}
+
+// RoutingConfig controls model fallback when a completion fails
+type RoutingConfig struct {
+	// Models tried in order when a model fails or times out, keyed by the
+	// requested model; "*" applies to models without their own chain
+	Fallbacks      map[string][]string
+	AttemptTimeout time.Duration // Per-model time limit when a fallback is available
+	// Logical models served by several providers, keyed by the name clients
+	// request; each completion goes to the fastest healthy candidate
+	Routes map[string][]string
+	// How much faster (as a fraction) a candidate must be before a route
+	// switches to it, so near-equal providers don't flap
+	RouteHysteresis float64
+}
+
+// MetricsConfig controls what system metrics non-admins can see
+type MetricsConfig struct {
+	// Instance-wide figures are only shown to non-admins when they aggregate
+	// at least this many users
+	MinAggregationUsers int
+}
+
+// CacheConfig controls the completion response cache
+type CacheConfig struct {
+	ResponseTTL        time.Duration // How long a completion is reused; 0 disables the cache
+	ResponseMaxEntries int
+}
+
+// ModerationConfig controls the moderation check run on user messages
+// before a completion
+type ModerationConfig struct {
+	Enabled bool
+	Model   string
+	// Let messages through when the moderation provider fails, instead of
+	// rejecting the completion
+	FailOpen bool
+}
+
+// SecurityConfig controls how users flagged for jailbreak attempts are treated
+type SecurityConfig struct {
+	// Moderate flagged users' messages strictly, failing closed, even when
+	// moderation is otherwise disabled
+	StrictModeration bool
+	FlagWindow       time.Duration // How long a medium or high severity event flags a user
+}
+
+// RetentionConfig controls how long request logs are kept, by category.
+// A zero period keeps that category indefinitely.
+type RetentionConfig struct {
+	MetadataTTL  time.Duration // Usage log rows
+	ErrorBodyTTL time.Duration // Error messages stored on usage log rows
+	Interval     time.Duration
+	// Access log entries of confidential documents
+	DocumentAccessTTL time.Duration
+}
+
+// CleanupConfig controls the periodic deletion of expired tokens, finished
+// jobs and orphaned stored objects
+type CleanupConfig struct {
+	Interval     time.Duration // 0 disables periodic cleanup
+	JobRetention time.Duration // How long finished jobs are kept; 0 keeps them
+	// Stored objects younger than this are never treated as orphans
+	OrphanGrace time.Duration
+}
+
+// QuotaConfig controls when daily and monthly quota usage resets
+type QuotaConfig struct {
+	// Days start at midnight and months on the 1st in this location
+	ResetLocation *time.Location
+	ResetInterval time.Duration // How often due resets are looked for
+}
+
+// PricingConfig controls syncing model prices from a price feed
+type PricingConfig struct {
+	SyncURL      string        // http(s) URL or file path of the feed; empty disables syncing
+	SyncInterval time.Duration // 0 syncs only when an admin asks
+	SyncTimeout  time.Duration
+}
+
+// AuditConfig selects where audit events are written. Sinks lists
+// "stdout", "file", "syslog" and "http", or "none".
+type AuditConfig struct {
+	Sinks []string
+
+	FilePath       string
+	FileMaxBytes   int64 // Size the file is rotated at
+	FileMaxBackups int   // Rotated files kept
+
+	SyslogNetwork string // "tcp" or "udp"; the local syslog daemon when empty
+	SyslogAddr    string
+	SyslogTag     string
+
+	HTTPURL   string
+	HTTPToken string // Sent as a bearer token
+}
+
+// MailConfig is the SMTP relay mail is sent through. Without SMTPHost,
+// mail is only logged.
+type MailConfig struct {
+	SMTPHost     string
+	SMTPPort     string
+	SMTPUsername string
+	SMTPPassword string
+	From         string
+	// Logged mail includes its body, which may hold one-time links
+	LogBodies bool
+}
+
+// WebhookConfig controls deliveries to users' webhooks
+type WebhookConfig struct {
+	Timeout time.Duration
+	// Allows webhooks on loopback and private networks, for development
+	AllowPrivateNetworks bool
+}
+
+// ProxyConfig controls which requests are proxied to the backend. Rules
+// are written as "METHOD[,METHOD] /pattern" or "/pattern" and separated by
+// semicolons.
+type ProxyConfig struct {
+	Allow []string
+	Deny  []string
+	// Refuses paths no allow rule matches; on by default in production
+	DefaultDeny bool
+}
+
+// PasskeyConfig identifies the site to WebAuthn authenticators for
+// passkey login
+type PasskeyConfig struct {
+	RPID         string   // Domain passkeys are scoped to
+	RPName       string   // Shown by the browser when a passkey is created
+	Origins      []string // Origins the login page is served from
+	ChallengeTTL time.Duration
+}
+
+// WidgetConfig caps what widget tokens can allow. Tokens default to these
+// limits and can only lower them.
+type WidgetConfig struct {
+	RequestsPerMinute int     // Per token, across all visitors
+	DailyRequests     int     // Completions per token per UTC day
+	DailyTokens       int     // Tokens per token per UTC day
+	MaxTokens         int     // Output tokens per response
+	MaxCostUSD        float64 // Cost per response
+}
+
+// StorageConfig controls per-user storage limits
+type StorageConfig struct {
+	// Byte limit by user plan; 0 is unlimited. Plans without an entry use "free".
+	PlanLimits map[string]int64
+
+	// Largest document content in bytes by user plan, likewise
+	DocumentLimits map[string]int64
+}
+
+// AttachmentStoreConfig selects where message attachments, document
+// uploads and export snapshots are kept: a directory ("disk") or an S3
+// bucket ("s3")
+type AttachmentStoreConfig struct {
+	Backend string
+	Dir     string // Used by the disk backend
+
+	S3Bucket    string
+	S3Region    string
+	S3Endpoint  string // Optional, e.g. http://minio:9000; defaults to AWS
+	S3AccessKey string
+	S3SecretKey string
+}
+
 // DatabaseConfig contains database configuration
 type DatabaseConfig struct {
 	DSN string
@@ -38,6 +288,10 @@ type AppConfig struct {
 	Name string
 	Version string
 	Environment string
+	MaxAttachmentBytes int64 // Per-file upload limit for message attachments
+	MaxDocumentBytes   int64 // Per-file upload limit for document uploads
+	DocumentIfMatch    bool  // Document updates must name the version they're based on
+	OnboardingSamples  bool  // New users start with sample content
 }
 
 // LoadConfig loads configuration from environment variables
@@ -71,12 +325,346 @@ func LoadConfig() (*Config, error) {
 			Name: getEnv("APP_NAME", "Lio AI API"),
 			Version: getEnv("APP_VERSION", "0.1.0"),
 			Environment: getEnv("ENVIRONMENT", "development"),
+			MaxAttachmentBytes: getEnvInt64("ATTACHMENT_MAX_BYTES", 10<<20),
+			MaxDocumentBytes:   getEnvInt64("DOCUMENT_UPLOAD_MAX_BYTES", 20<<20),
+			DocumentIfMatch:    getEnv("DOCUMENT_REQUIRE_IF_MATCH", "true") == "true",
+			OnboardingSamples:  getEnv("ONBOARDING_SAMPLES", "false") == "true",
 		},
 	}
 
+	config.Resilience = loadResilienceConfig(config.App.Environment)
+	config.Trial = TrialConfig{
+		Enabled:            getEnv("TRIAL_ENABLED", "false") == "true",
+		DailyCompletions:   getEnvInt64("TRIAL_DAILY_COMPLETIONS", 5),
+		IPDailyCompletions: getEnvInt64("TRIAL_IP_DAILY_COMPLETIONS", 20),
+		RequestsPerMinute:  int(getEnvInt64("TRIAL_REQUESTS_PER_MINUTE", 10)),
+		Model:              os.Getenv("TRIAL_MODEL"),
+	}
+	config.Guest = GuestConfig{
+		Enabled:           getEnv("GUEST_MODE_ENABLED", "false") == "true",
+		SessionTTL:        getEnvDuration("GUEST_SESSION_TTL", 24*time.Hour),
+		DailyTokenLimit:   int(getEnvInt64("GUEST_DAILY_TOKENS", 5000)),
+		DailyCostLimitUSD: getEnvFloat("GUEST_DAILY_COST_USD", 0.05),
+		RequestsPerMinute: int(getEnvInt64("GUEST_REQUESTS_PER_MINUTE", 6)),
+	}
+	config.Moderation = ModerationConfig{
+		Enabled:  getEnv("MODERATION_ENABLED", "false") == "true",
+		Model:    getEnv("MODERATION_MODEL", "omni-moderation-latest"),
+		FailOpen: getEnv("MODERATION_FAIL_OPEN", "false") == "true",
+	}
+	config.Security = SecurityConfig{
+		StrictModeration: getEnv("JAILBREAK_STRICT_MODERATION", "false") == "true",
+		FlagWindow:       getEnvDuration("JAILBREAK_FLAG_WINDOW", 24*time.Hour),
+	}
+	config.Retention = RetentionConfig{
+		MetadataTTL:  getEnvDuration("LOG_RETENTION_METADATA", 0),
+		ErrorBodyTTL: getEnvDuration("LOG_RETENTION_ERROR_BODIES", 0),
+		Interval:     getEnvDuration("LOG_RETENTION_INTERVAL", time.Hour),
+
+		DocumentAccessTTL: getEnvDuration("DOCUMENT_ACCESS_LOG_RETENTION", 0),
+	}
+	config.Cleanup = CleanupConfig{
+		Interval:     getEnvDuration("CLEANUP_INTERVAL", time.Hour),
+		JobRetention: getEnvDuration("CLEANUP_JOB_RETENTION", 30*24*time.Hour),
+		OrphanGrace:  getEnvDuration("CLEANUP_ORPHAN_GRACE", 24*time.Hour),
+	}
+	config.Webhooks = WebhookConfig{
+		Timeout:              getEnvDuration("WEBHOOK_TIMEOUT", 10*time.Second),
+		AllowPrivateNetworks: getEnv("WEBHOOK_ALLOW_PRIVATE_NETWORKS", "false") == "true",
+	}
+	config.Proxy = ProxyConfig{
+		// The backend routes the gateway itself proxies
+		Allow: splitRules(getEnv("PROXY_ALLOW",
+			"POST /api/v1/codegen/**;GET /api/v1/stats;GET /api/v1/models;GET /api/v1/models/*;"+
+				"POST /api/v1/models/recommend;POST /api/v1/models/*/health")),
+		// The backend's API docs and its reload and key sync endpoints, which
+		// only the gateway may call
+		Deny: splitRules(getEnv("PROXY_DENY",
+			"/docs/**;/openapi.json;/redoc/**;/api/v1/reload;/api/v1/models/reload;/api/v1/models/sync-keys")),
+		DefaultDeny: getEnv("PROXY_DEFAULT_DENY", strconv.FormatBool(config.App.Environment == "production")) == "true",
+	}
+	config.Passkeys = PasskeyConfig{
+		RPID:         getEnv("WEBAUTHN_RP_ID", "localhost"),
+		RPName:       getEnv("WEBAUTHN_RP_NAME", config.App.Name),
+		Origins:      splitList(getEnv("WEBAUTHN_ORIGINS", "http://localhost:3000")),
+		ChallengeTTL: getEnvDuration("WEBAUTHN_CHALLENGE_TTL", 5*time.Minute),
+	}
+	config.Widget = WidgetConfig{
+		RequestsPerMinute: int(getEnvInt64("WIDGET_REQUESTS_PER_MINUTE", 20)),
+		DailyRequests:     int(getEnvInt64("WIDGET_DAILY_REQUESTS", 500)),
+		DailyTokens:       int(getEnvInt64("WIDGET_DAILY_TOKENS", 200000)),
+		MaxTokens:         int(getEnvInt64("WIDGET_MAX_TOKENS", 512)),
+		MaxCostUSD:        getEnvFloat("WIDGET_MAX_COST_USD", 0.01),
+	}
+	config.Provisioning = ProvisioningConfig{
+		SCIMToken: os.Getenv("SCIM_BEARER_TOKEN"),
+		InviteURL: getEnv("INVITE_URL", "http://localhost:3000/accept-invite"),
+	}
+	config.Analytics = AnalyticsConfig{
+		CDCToken:     os.Getenv("CDC_BEARER_TOKEN"),
+		CDCRetention: getEnvDuration("CDC_RETENTION", 7*24*time.Hour),
+	}
+	config.Search = SearchConfig{
+		SemanticEnabled: getEnv("SEMANTIC_SEARCH_ENABLED", "false") == "true",
+		EmbeddingModel:  getEnv("SEMANTIC_SEARCH_MODEL", "text-embedding-3-small"),
+	}
+
+	fallbacks, err := parseModelFallbacks(os.Getenv("MODEL_FALLBACKS"))
+	if err != nil {
+		return nil, err
+	}
+	routes, err := parseModelRoutes(os.Getenv("MODEL_ROUTES"))
+	if err != nil {
+		return nil, err
+	}
+	config.Routing = RoutingConfig{
+		Fallbacks:       fallbacks,
+		AttemptTimeout:  getEnvDuration("MODEL_ATTEMPT_TIMEOUT", 60*time.Second),
+		Routes:          routes,
+		RouteHysteresis: getEnvFloat("MODEL_ROUTE_HYSTERESIS", 0.2),
+	}
+
+	limits, err := parsePlanSizes("STORAGE_LIMITS", getEnv("STORAGE_LIMITS", "free=100MB,pro=10GB,enterprise=unlimited"))
+	if err != nil {
+		return nil, err
+	}
+	documentLimits, err := parsePlanSizes("DOCUMENT_MAX_CONTENT", getEnv("DOCUMENT_MAX_CONTENT", "free=1MB,pro=10MB,enterprise=50MB"))
+	if err != nil {
+		return nil, err
+	}
+	config.Storage = StorageConfig{PlanLimits: limits, DocumentLimits: documentLimits}
+	config.Attachments = AttachmentStoreConfig{
+		Backend:     getEnv("ATTACHMENT_STORAGE", "disk"),
+		Dir:         getEnv("ATTACHMENT_DIR", "data/attachments"),
+		S3Bucket:    os.Getenv("S3_BUCKET"),
+		S3Region:    getEnv("S3_REGION", "us-east-1"),
+		S3Endpoint:  os.Getenv("S3_ENDPOINT"),
+		S3AccessKey: os.Getenv("AWS_ACCESS_KEY_ID"),
+		S3SecretKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+	}
+	config.Cache = CacheConfig{
+		ResponseTTL:        getEnvDuration("LLM_CACHE_TTL", 0),
+		ResponseMaxEntries: int(getEnvInt64("LLM_CACHE_MAX_ENTRIES", 1000)),
+	}
+	config.Metrics = MetricsConfig{
+		MinAggregationUsers: int(getEnvInt64("METRICS_MIN_AGGREGATION_USERS", 5)),
+	}
+
+	resetLocation, err := time.LoadLocation(getEnv("QUOTA_RESET_TIMEZONE", "UTC"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid QUOTA_RESET_TIMEZONE: %w", err)
+	}
+	config.Quota = QuotaConfig{
+		ResetLocation: resetLocation,
+		ResetInterval: getEnvDuration("QUOTA_RESET_INTERVAL", time.Minute),
+	}
+	config.Pricing = PricingConfig{
+		SyncURL:      os.Getenv("COST_SYNC_URL"),
+		SyncInterval: getEnvDuration("COST_SYNC_INTERVAL", 24*time.Hour),
+		SyncTimeout:  getEnvDuration("COST_SYNC_TIMEOUT", 30*time.Second),
+	}
+	config.Mail = MailConfig{
+		SMTPHost:     os.Getenv("SMTP_HOST"),
+		SMTPPort:     getEnv("SMTP_PORT", "587"),
+		SMTPUsername: os.Getenv("SMTP_USERNAME"),
+		SMTPPassword: os.Getenv("SMTP_PASSWORD"),
+		From:         getEnv("SMTP_FROM", "no-reply@lio.ai"),
+		LogBodies:    config.App.Environment == "development",
+	}
+	config.Audit = AuditConfig{
+		Sinks:          splitList(getEnv("AUDIT_SINKS", "stdout")),
+		FilePath:       getEnv("AUDIT_FILE_PATH", "logs/audit.log"),
+		FileMaxBytes:   getEnvInt64("AUDIT_FILE_MAX_MB", 100) << 20,
+		FileMaxBackups: int(getEnvInt64("AUDIT_FILE_MAX_BACKUPS", 5)),
+		SyslogNetwork:  os.Getenv("AUDIT_SYSLOG_NETWORK"),
+		SyslogAddr:     os.Getenv("AUDIT_SYSLOG_ADDR"),
+		SyslogTag:      getEnv("AUDIT_SYSLOG_TAG", "lio-ai-audit"),
+		HTTPURL:        os.Getenv("AUDIT_HTTP_URL"),
+		HTTPToken:      os.Getenv("AUDIT_HTTP_TOKEN"),
+	}
+	config.PlatformKeys = PlatformKeysConfig{
+		Keys:   loadPlatformKeys(os.Environ()),
+		Markup: getEnvFloat("PLATFORM_KEY_MARKUP", 1.0),
+	}
+
 	return config, nil
 }
 
+// loadPlatformKeys collects the PLATFORM_<PROVIDER>_API_KEY variables of
+// environ by lowercase provider
+func loadPlatformKeys(environ []string) map[string]string {
+	keys := make(map[string]string)
+	for _, kv := range environ {
+		name, value, _ := strings.Cut(kv, "=")
+		provider, ok := strings.CutPrefix(name, "PLATFORM_")
+		if !ok || value == "" {
+			continue
+		}
+		if provider, ok = strings.CutSuffix(provider, "_API_KEY"); ok && provider != "" {
+			keys[strings.ToLower(provider)] = value
+		}
+	}
+	return keys
+}
+
+// parseModelFallbacks reads chains written as
+// "gpt-4=claude-3-sonnet,ollama/llama3;*=ollama/llama3"
+func parseModelFallbacks(value string) (map[string][]string, error) {
+	chains := make(map[string][]string)
+	for _, entry := range strings.Split(value, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		model, list, ok := strings.Cut(entry, "=")
+		model = strings.TrimSpace(model)
+		if !ok || model == "" {
+			return nil, fmt.Errorf("invalid MODEL_FALLBACKS entry %q: expected model=fallback,...", entry)
+		}
+		var chain []string
+		for _, fallback := range strings.Split(list, ",") {
+			if fallback = strings.TrimSpace(fallback); fallback != "" && fallback != model {
+				chain = append(chain, fallback)
+			}
+		}
+		if len(chain) == 0 {
+			return nil, fmt.Errorf("invalid MODEL_FALLBACKS entry %q: no fallback models", entry)
+		}
+		chains[model] = chain
+	}
+	return chains, nil
+}
+
+// parseModelRoutes reads logical models and their candidates written as
+// "gpt-4o=openai/gpt-4o,azure/gpt-4o;fast=gpt-4o-mini,claude-3-haiku".
+// Unlike fallbacks, a candidate may share the logical model's name.
+func parseModelRoutes(value string) (map[string][]string, error) {
+	routes := make(map[string][]string)
+	for _, entry := range strings.Split(value, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		model, list, ok := strings.Cut(entry, "=")
+		model = strings.TrimSpace(model)
+		if !ok || model == "" {
+			return nil, fmt.Errorf("invalid MODEL_ROUTES entry %q: expected model=candidate,...", entry)
+		}
+		var candidates []string
+		seen := make(map[string]bool)
+		for _, candidate := range strings.Split(list, ",") {
+			if candidate = strings.TrimSpace(candidate); candidate != "" && !seen[candidate] {
+				seen[candidate] = true
+				candidates = append(candidates, candidate)
+			}
+		}
+		if len(candidates) < 2 {
+			return nil, fmt.Errorf("invalid MODEL_ROUTES entry %q: a route needs at least two candidates", entry)
+		}
+		routes[model] = candidates
+	}
+	return routes, nil
+}
+
+// parsePlanSizes reads the per-plan sizes of the env variable name,
+// written as "free=100MB,pro=10GB,enterprise=unlimited"
+func parsePlanSizes(name, value string) (map[string]int64, error) {
+	limits := make(map[string]int64)
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		plan, size, ok := strings.Cut(entry, "=")
+		plan = strings.ToLower(strings.TrimSpace(plan))
+		if !ok || plan == "" {
+			return nil, fmt.Errorf("invalid %s entry %q: expected plan=size", name, entry)
+		}
+		bytes, err := parseByteSize(strings.TrimSpace(size))
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s entry %q: %w", name, entry, err)
+		}
+		limits[plan] = bytes
+	}
+	return limits, nil
+}
+
+// parseByteSize reads sizes like "512", "100MB" or "10GB" (1024-based);
+// "unlimited" and "0" mean no limit
+func parseByteSize(value string) (int64, error) {
+	units := []struct {
+		suffix string
+		scale  int64
+	}{{"TB", 1 << 40}, {"GB", 1 << 30}, {"MB", 1 << 20}, {"KB", 1 << 10}, {"B", 1}}
+
+	upper := strings.ToUpper(value)
+	if upper == "UNLIMITED" {
+		return 0, nil
+	}
+	scale := int64(1)
+	for _, u := range units {
+		if strings.HasSuffix(upper, u.suffix) {
+			upper, scale = strings.TrimSpace(strings.TrimSuffix(upper, u.suffix)), u.scale
+			break
+		}
+	}
+	n, err := strconv.ParseInt(upper, 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %q", value)
+	}
+	return n * scale, nil
+}
+
+// loadResilienceConfig applies per-environment defaults, overridable via env.
+// Production waits longer for dependencies; development fails fast.
+func loadResilienceConfig(environment string) ResilienceConfig {
+	rc := ResilienceConfig{
+		DBConnectRetries:      3,
+		DBConnectBackoff:      500 * time.Millisecond,
+		DBConnectMaxBackoff:   5 * time.Second,
+		BackendHealthInterval: 10 * time.Second,
+	}
+	if environment == "production" {
+		rc = ResilienceConfig{
+			DBConnectRetries:      10,
+			DBConnectBackoff:      time.Second,
+			DBConnectMaxBackoff:   30 * time.Second,
+			BackendHealthInterval: 5 * time.Second,
+		}
+	}
+
+	if v, err := strconv.Atoi(os.Getenv("DB_CONNECT_RETRIES")); err == nil && v > 0 {
+		rc.DBConnectRetries = v
+	}
+	rc.DBConnectBackoff = getEnvDuration("DB_CONNECT_BACKOFF", rc.DBConnectBackoff)
+	rc.DBConnectMaxBackoff = getEnvDuration("DB_CONNECT_MAX_BACKOFF", rc.DBConnectMaxBackoff)
+	rc.BackendHealthInterval = getEnvDuration("BACKEND_HEALTH_INTERVAL", rc.BackendHealthInterval)
+
+	return rc
+}
+
+// splitRules reads a semicolon-separated list, dropping empty entries
+func splitRules(value string) []string {
+	var rules []string
+	for _, rule := range strings.Split(value, ";") {
+		if rule = strings.TrimSpace(rule); rule != "" {
+			rules = append(rules, rule)
+		}
+	}
+	return rules
+}
+
+// splitList reads a comma-separated list, dropping empty entries
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
 // getEnv retrieves environment variable with a default value
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
@@ -85,6 +673,30 @@ func getEnv(key, defaultValue string) string {
 	return defaultValue
 }
 
+// getEnvInt64 parses an integer environment variable with a default value
+func getEnvInt64(key string, defaultValue int64) int64 {
+	if v, err := strconv.ParseInt(os.Getenv(key), 10, 64); err == nil && v > 0 {
+		return v
+	}
+	return defaultValue
+}
+
+// getEnvFloat parses a decimal environment variable with a default value
+func getEnvFloat(key string, defaultValue float64) float64 {
+	if v, err := strconv.ParseFloat(os.Getenv(key), 64); err == nil && v > 0 {
+		return v
+	}
+	return defaultValue
+}
+
+// getEnvDuration parses a duration (e.g. "500ms", "5s") with a default value
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	if d, err := time.ParseDuration(os.Getenv(key)); err == nil && d > 0 {
+		return d
+	}
+	return defaultValue
+}
+
 // GetDSN returns the formatted database connection string
 func (c *Config) GetDSN() string {
 	return fmt.Sprintf("file:%s?cache=shared&mode=rwc&_journal_mode=WAL", c.Database.DSN)
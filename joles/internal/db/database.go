@@ -6,6 +6,8 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
 
 	_ "github.com/mattn/go-sqlite3"
 	"lio-ai/internal/config"
@@ -27,15 +29,13 @@ func NewDatabase(cfg *config.Config) (*Database, error) {
 
 	dsn := fmt.Sprintf("file:%s?cache=shared&mode=rwc", cfg.Database.DSN)
 	
-	db, err := sql.Open("sqlite3", dsn)
+	db, err := connectWithRetry(dsn, cfg.Resilience)
 	if err != nil {
-		return nil, fmt.Errorf("failed to connect to database: %w", err)
+		return nil, err
 	}
 
-	// Test the connection
-	if err := db.Ping(); err != nil {
-		return nil, fmt.Errorf("failed to ping database: %w", err)
-	}
+	// Recycle idle connections so a recovered database is picked up again
+	db.SetConnMaxIdleTime(5 * time.Minute)
 
 	log.Println("✓ Database connection established")
 
@@ -47,6 +47,43 @@ func NewDatabase(cfg *config.Config) (*Database, error) {
 	return &Database{conn: db}, nil
 }
 
+// connectWithRetry opens and pings the database, backing off between attempts
+// so the gateway survives a database that is still starting up
+func connectWithRetry(dsn string, rc config.ResilienceConfig) (*sql.DB, error) {
+	attempts := rc.DBConnectRetries
+	if attempts < 1 {
+		attempts = 1
+	}
+	backoff := rc.DBConnectBackoff
+
+	var lastErr error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		db, err := sql.Open("sqlite3", dsn)
+		if err == nil {
+			if err = db.Ping(); err == nil {
+				return db, nil
+			}
+			db.Close()
+			err = fmt.Errorf("failed to ping database: %w", err)
+		} else {
+			err = fmt.Errorf("failed to connect to database: %w", err)
+		}
+		lastErr = err
+
+		if attempt == attempts {
+			break
+		}
+		log.Printf("⚠️  Database not ready (attempt %d/%d): %v — retrying in %s", attempt, attempts, err, backoff)
+		time.Sleep(backoff)
+		backoff *= 2
+		if rc.DBConnectMaxBackoff > 0 && backoff > rc.DBConnectMaxBackoff {
+			backoff = rc.DBConnectMaxBackoff
+		}
+	}
+
+	return nil, fmt.Errorf("database unavailable after %d attempts: %w", attempts, lastErr)
+}
+
 // migrate runs database migrations
 func migrate(db *sql.DB) error {
 	schema := `
@@ -58,7 +95,10 @@ func migrate(db *sql.DB) error {
 		password_hash VARCHAR(255) NOT NULL,
 		full_name VARCHAR(255),
 		role VARCHAR(50) DEFAULT 'user',
+		plan VARCHAR(50) DEFAULT 'free',
 		is_active BOOLEAN DEFAULT 1,
+		merged_into INTEGER,
+		onboarded_at DATETIME,
 		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
 		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
 	);
@@ -67,8 +107,23 @@ func migrate(db *sql.DB) error {
 
 	CREATE TABLE IF NOT EXISTS documents (
 		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id VARCHAR(255),
 		title VARCHAR(255) NOT NULL,
 		content TEXT NOT NULL,
+		collection_id INTEGER,
+		file_name VARCHAR(255),
+		file_content_type VARCHAR(100),
+		file_size INTEGER,
+		file_backend VARCHAR(20),
+		file_key VARCHAR(500),
+		confidential BOOLEAN DEFAULT 0,
+		source_url VARCHAR(2048),
+		language VARCHAR(35),
+		content_type VARCHAR(100),
+		code_language VARCHAR(32),
+		word_count INTEGER DEFAULT 0,
+		is_favorite BOOLEAN DEFAULT 0,
+		version INTEGER NOT NULL DEFAULT 1,
 		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
 		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
 	);
@@ -79,6 +134,20 @@ func migrate(db *sql.DB) error {
 		user_id VARCHAR(255) NOT NULL,
 		title VARCHAR(255) NOT NULL,
 		chat_uuid VARCHAR(255),
+		context_strategy VARCHAR(20) DEFAULT 'truncate',
+		is_pinned BOOLEAN DEFAULT 0,
+		max_output_tokens INTEGER DEFAULT 0,
+		max_cost_usd REAL DEFAULT 0,
+		budget_usd REAL DEFAULT 0,
+		budget_action VARCHAR(10) DEFAULT 'block',
+		persona_id INTEGER,
+		summary TEXT,
+		summary_model VARCHAR(100),
+		summary_at DATETIME,
+		summary_action_items TEXT,
+		summary_stale BOOLEAN DEFAULT 1,
+		context_summary TEXT,
+		context_summary_seq INTEGER,
 		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
 		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
 	);
@@ -89,10 +158,18 @@ func migrate(db *sql.DB) error {
 	CREATE TABLE IF NOT EXISTS messages (
 		id INTEGER PRIMARY KEY AUTOINCREMENT,
 		chat_id INTEGER NOT NULL,
+		seq INTEGER,
 		role VARCHAR(50) NOT NULL,
 		content TEXT NOT NULL,
 		model VARCHAR(100),
 		tokens INTEGER DEFAULT 0,
+		stopped BOOLEAN DEFAULT 0,
+		tool_calls TEXT,
+		tool_call_id VARCHAR(255),
+		bookmarked BOOLEAN DEFAULT 0,
+		truncated BOOLEAN DEFAULT 0,
+		moderation TEXT,
+		context_chunks TEXT,
 		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
 		FOREIGN KEY (chat_id) REFERENCES chats(id) ON DELETE CASCADE
 	);
@@ -111,13 +188,13 @@ func migrate(db *sql.DB) error {
 		cost_usd REAL DEFAULT 0.0,
 		duration_ms INTEGER DEFAULT 0,
 		endpoint VARCHAR(255),
+		provider VARCHAR(50),
+		key_source VARCHAR(20) DEFAULT 'user',
 		success BOOLEAN DEFAULT 1,
 		error_message TEXT,
 		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
 	);
-	CREATE INDEX IF NOT EXISTS idx_usage_user_id ON usage_metrics(user_id);
-	CREATE INDEX IF NOT EXISTS idx_usage_created_at ON usage_metrics(created_at DESC);
-	CREATE INDEX IF NOT EXISTS idx_usage_request_type ON usage_metrics(request_type);
+	-- usage_metrics indexes are built by the usage_metrics_indexes migration
 
 	CREATE TABLE IF NOT EXISTS user_quotas (
 		id INTEGER PRIMARY KEY AUTOINCREMENT,
@@ -144,6 +221,11 @@ func migrate(db *sql.DB) error {
 		cost_per_output_token REAL NOT NULL,
 		operation_type VARCHAR(50) NOT NULL,
 		is_active BOOLEAN DEFAULT 1,
+		deprecated_at DATETIME,
+		sunset_at DATETIME,
+		replacement_model VARCHAR(100),
+		deprecation_notice TEXT,
+		deprecated_by VARCHAR(255),
 		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
 		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
 	);
@@ -166,6 +248,7 @@ func migrate(db *sql.DB) error {
 		user_id VARCHAR(255) NOT NULL,
 		provider VARCHAR(50) NOT NULL,
 		api_key_encrypted TEXT NOT NULL,
+		encryption_version INTEGER DEFAULT 1,
 		models_enabled TEXT,
 		is_active BOOLEAN DEFAULT 1,
 		last_used_at DATETIME,
@@ -175,6 +258,788 @@ func migrate(db *sql.DB) error {
 	);
 	CREATE INDEX IF NOT EXISTS idx_provider_keys_user_id ON provider_api_keys(user_id);
 	CREATE INDEX IF NOT EXISTS idx_provider_keys_provider ON provider_api_keys(provider);
+
+	-- Provider key syncs waiting for the Python backend to become reachable
+	CREATE TABLE IF NOT EXISTS pending_key_syncs (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id VARCHAR(255) NOT NULL UNIQUE,
+		attempts INTEGER DEFAULT 0,
+		last_error TEXT,
+		next_attempt_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+	CREATE INDEX IF NOT EXISTS idx_pending_key_syncs_next ON pending_key_syncs(next_attempt_at);
+
+	-- Anonymous trial completions per device/IP and UTC day
+	CREATE TABLE IF NOT EXISTS trial_usage (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		client_key VARCHAR(64) NOT NULL,
+		day VARCHAR(10) NOT NULL,
+		completions INTEGER DEFAULT 0,
+		tokens INTEGER DEFAULT 0,
+		ip_address VARCHAR(64),
+		last_used_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE(client_key, day)
+	);
+	CREATE INDEX IF NOT EXISTS idx_trial_usage_ip_day ON trial_usage(ip_address, day);
+
+	-- Reusable prompts with {{variable}} placeholders
+	CREATE TABLE IF NOT EXISTS prompt_templates (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id VARCHAR(255) NOT NULL,
+		name VARCHAR(100) NOT NULL,
+		description TEXT,
+		content TEXT NOT NULL,
+		is_shared BOOLEAN DEFAULT 0,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+	CREATE INDEX IF NOT EXISTS idx_prompt_templates_user_id ON prompt_templates(user_id);
+
+	-- Prompts scheduled to run against a chat later
+	CREATE TABLE IF NOT EXISTS scheduled_messages (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		chat_id INTEGER NOT NULL,
+		user_id VARCHAR(255) NOT NULL,
+		content TEXT NOT NULL,
+		model VARCHAR(100),
+		scheduled_at DATETIME NOT NULL,
+		status VARCHAR(20) DEFAULT 'pending',
+		attempts INTEGER DEFAULT 0,
+		last_error TEXT,
+		message_id INTEGER,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (chat_id) REFERENCES chats(id) ON DELETE CASCADE
+	);
+	CREATE INDEX IF NOT EXISTS idx_scheduled_messages_due ON scheduled_messages(status, scheduled_at);
+	CREATE INDEX IF NOT EXISTS idx_scheduled_messages_chat_id ON scheduled_messages(chat_id);
+
+	-- Responses to requests sent with an Idempotency-Key, replayed on retries
+	CREATE TABLE IF NOT EXISTS idempotency_keys (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id VARCHAR(255) NOT NULL,
+		idempotency_key VARCHAR(255) NOT NULL,
+		request_hash VARCHAR(64) NOT NULL,
+		status_code INTEGER DEFAULT 0,
+		content_type VARCHAR(255),
+		response_body BLOB,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE(user_id, idempotency_key)
+	);
+	CREATE INDEX IF NOT EXISTS idx_idempotency_keys_created_at ON idempotency_keys(created_at);
+
+	-- One-time tokens emailed to provisioned users to set their password
+	CREATE TABLE IF NOT EXISTS user_invitations (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id INTEGER NOT NULL,
+		token_hash VARCHAR(64) NOT NULL UNIQUE,
+		expires_at DATETIME NOT NULL,
+		accepted_at DATETIME,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+	);
+	CREATE INDEX IF NOT EXISTS idx_user_invitations_user_id ON user_invitations(user_id);
+
+	-- Asynchronous bulk user imports
+	CREATE TABLE IF NOT EXISTS user_import_jobs (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		created_by VARCHAR(255) NOT NULL,
+		source VARCHAR(20) NOT NULL,
+		status VARCHAR(20) DEFAULT 'queued',
+		total_rows INTEGER DEFAULT 0,
+		processed INTEGER DEFAULT 0,
+		created INTEGER DEFAULT 0,
+		failed INTEGER DEFAULT 0,
+		errors TEXT,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		completed_at DATETIME
+	);
+
+	-- Files uploaded with chat messages (content lives in the attachment store)
+	CREATE TABLE IF NOT EXISTS attachments (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		message_id INTEGER NOT NULL,
+		chat_id INTEGER NOT NULL,
+		user_id VARCHAR(255) NOT NULL,
+		filename VARCHAR(255) NOT NULL,
+		content_type VARCHAR(100),
+		size_bytes INTEGER DEFAULT 0,
+		storage_backend VARCHAR(20) NOT NULL,
+		storage_key VARCHAR(500) NOT NULL,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (message_id) REFERENCES messages(id) ON DELETE CASCADE
+	);
+	CREATE INDEX IF NOT EXISTS idx_attachments_message_id ON attachments(message_id);
+	CREATE INDEX IF NOT EXISTS idx_attachments_chat_id ON attachments(chat_id);
+
+	-- Thumbs up/down ratings of assistant messages, one per user and message
+	CREATE TABLE IF NOT EXISTS message_feedback (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		message_id INTEGER NOT NULL,
+		chat_id INTEGER NOT NULL,
+		user_id VARCHAR(255) NOT NULL,
+		rating VARCHAR(10) NOT NULL,
+		comment TEXT,
+		model VARCHAR(100),
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE(message_id, user_id),
+		FOREIGN KEY (message_id) REFERENCES messages(id) ON DELETE CASCADE
+	);
+	CREATE INDEX IF NOT EXISTS idx_message_feedback_created_at ON message_feedback(created_at);
+
+	-- Admin-managed names (e.g. default-chat) that resolve to a concrete model
+	CREATE TABLE IF NOT EXISTS model_aliases (
+		alias VARCHAR(64) PRIMARY KEY,
+		model VARCHAR(100) NOT NULL,
+		description TEXT,
+		updated_by VARCHAR(255),
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+
+	-- Bytes stored per user, one row per stored object (document, attachment)
+	CREATE TABLE IF NOT EXISTS storage_usage (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id VARCHAR(255) NOT NULL,
+		kind VARCHAR(20) NOT NULL,
+		object_id INTEGER NOT NULL,
+		bytes INTEGER NOT NULL DEFAULT 0,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE(kind, object_id)
+	);
+	CREATE INDEX IF NOT EXISTS idx_storage_usage_user_id ON storage_usage(user_id);
+
+	-- Assistant profiles chats can reference for their system prompt and defaults
+	CREATE TABLE IF NOT EXISTS personas (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id VARCHAR(255) NOT NULL,
+		name VARCHAR(100) NOT NULL,
+		system_prompt TEXT NOT NULL,
+		default_model VARCHAR(100),
+		temperature REAL,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+	CREATE INDEX IF NOT EXISTS idx_personas_user_id ON personas(user_id);
+
+	-- Prompt-injection and jailbreak attempts detected in user messages
+	CREATE TABLE IF NOT EXISTS security_events (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id VARCHAR(255) NOT NULL,
+		chat_id INTEGER,
+		event_type VARCHAR(50) NOT NULL,
+		severity VARCHAR(10) NOT NULL,
+		pattern VARCHAR(100) NOT NULL,
+		excerpt TEXT,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+	CREATE INDEX IF NOT EXISTS idx_security_events_user_id ON security_events(user_id, created_at);
+	CREATE INDEX IF NOT EXISTS idx_security_events_created_at ON security_events(created_at);
+
+	CREATE TABLE IF NOT EXISTS incidents (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		component VARCHAR(150) NOT NULL,
+		kind VARCHAR(20) NOT NULL,
+		detail TEXT,
+		started_at DATETIME NOT NULL,
+		resolved_at DATETIME
+	);
+	CREATE INDEX IF NOT EXISTS idx_incidents_component ON incidents(component, started_at);
+	CREATE INDEX IF NOT EXISTS idx_incidents_started_at ON incidents(started_at);
+
+	-- Last sign of life of the gateway, to date the downtime behind a restart
+	CREATE TABLE IF NOT EXISTS gateway_heartbeat (
+		id INTEGER PRIMARY KEY CHECK (id = 1),
+		started_at DATETIME NOT NULL,
+		last_seen DATETIME NOT NULL
+	);
+
+	-- WebAuthn credentials users log in with instead of a password
+	CREATE TABLE IF NOT EXISTS passkeys (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id INTEGER NOT NULL,
+		credential_id VARCHAR(1400) NOT NULL UNIQUE,
+		public_key BLOB NOT NULL,
+		sign_count INTEGER DEFAULT 0,
+		transports VARCHAR(255),
+		name VARCHAR(100),
+		backup_eligible BOOLEAN DEFAULT 0,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		last_used_at DATETIME,
+		FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+	);
+	CREATE INDEX IF NOT EXISTS idx_passkeys_user_id ON passkeys(user_id);
+
+	-- Challenges of passkey ceremonies in progress, each used once
+	CREATE TABLE IF NOT EXISTS passkey_challenges (
+		id VARCHAR(64) PRIMARY KEY,
+		ceremony VARCHAR(20) NOT NULL,
+		user_id INTEGER,
+		challenge BLOB NOT NULL,
+		expires_at DATETIME NOT NULL
+	);
+	CREATE INDEX IF NOT EXISTS idx_passkey_challenges_expires_at ON passkey_challenges(expires_at);
+
+	-- Audit trail of accounts merged into another
+	CREATE TABLE IF NOT EXISTS identity_merges (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		source_user_id INTEGER NOT NULL,
+		source_email VARCHAR(255) NOT NULL,
+		target_user_id INTEGER NOT NULL,
+		merged_by VARCHAR(255) NOT NULL,
+		method VARCHAR(20) NOT NULL,
+		report TEXT NOT NULL,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+	CREATE INDEX IF NOT EXISTS idx_identity_merges_target ON identity_merges(target_user_id);
+
+	-- Tags on documents, one row per tag
+	CREATE TABLE IF NOT EXISTS document_tags (
+		document_id INTEGER NOT NULL,
+		tag VARCHAR(50) NOT NULL,
+		PRIMARY KEY (document_id, tag)
+	);
+	CREATE INDEX IF NOT EXISTS idx_document_tags_tag ON document_tags(tag);
+
+	-- Folders of documents, nested at most one level
+	CREATE TABLE IF NOT EXISTS collections (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id VARCHAR(255) NOT NULL,
+		name VARCHAR(255) NOT NULL,
+		parent_id INTEGER,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+	CREATE INDEX IF NOT EXISTS idx_collections_user ON collections(user_id);
+
+	-- Public tokens for embedded chat widgets, stored hashed
+	CREATE TABLE IF NOT EXISTS widget_tokens (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id VARCHAR(255) NOT NULL,
+		name VARCHAR(100) NOT NULL,
+		token_hash VARCHAR(64) NOT NULL UNIQUE,
+		token_prefix VARCHAR(20) NOT NULL,
+		persona_id INTEGER NOT NULL,
+		model VARCHAR(100),
+		allowed_origins TEXT NOT NULL,
+		requests_per_minute INTEGER NOT NULL,
+		daily_requests INTEGER NOT NULL,
+		daily_tokens INTEGER NOT NULL,
+		max_tokens INTEGER NOT NULL,
+		max_cost_usd REAL NOT NULL,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		last_used_at DATETIME,
+		revoked_at DATETIME
+	);
+	CREATE INDEX IF NOT EXISTS idx_widget_tokens_user ON widget_tokens(user_id);
+
+	-- Widget completions and tokens per widget token and UTC day
+	CREATE TABLE IF NOT EXISTS widget_usage (
+		token_id INTEGER NOT NULL,
+		day VARCHAR(10) NOT NULL,
+		requests INTEGER NOT NULL DEFAULT 0,
+		tokens INTEGER NOT NULL DEFAULT 0,
+		PRIMARY KEY (token_id, day)
+	);
+
+	-- Response post-processing plugin chains, as JSON; persona_id 0 is
+	-- the user's default for all chats
+	CREATE TABLE IF NOT EXISTS response_plugins (
+		user_id VARCHAR(255) NOT NULL,
+		persona_id INTEGER NOT NULL DEFAULT 0,
+		plugins TEXT NOT NULL,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (user_id, persona_id)
+	);
+
+	-- Ordered chat, message and usage writes for the analytics change feed;
+	-- data is the row as written, as JSON
+	CREATE TABLE IF NOT EXISTS change_events (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		entity VARCHAR(20) NOT NULL,
+		op VARCHAR(10) NOT NULL,
+		entity_id INTEGER NOT NULL,
+		user_id VARCHAR(255) NOT NULL,
+		data TEXT,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+	CREATE INDEX IF NOT EXISTS idx_change_events_created_at ON change_events(created_at);
+	CREATE INDEX IF NOT EXISTS idx_change_events_user ON change_events(user_id);
+
+	-- Users' notification centers
+	CREATE TABLE IF NOT EXISTS notifications (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id VARCHAR(255) NOT NULL,
+		kind VARCHAR(50) NOT NULL,
+		title VARCHAR(255) NOT NULL,
+		body TEXT NOT NULL,
+		data TEXT,
+		read_at DATETIME,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+	CREATE INDEX IF NOT EXISTS idx_notifications_user ON notifications(user_id, created_at DESC);
+
+	-- Passages of documents with their embeddings for semantic search;
+	-- embedding is little-endian float32
+	CREATE TABLE IF NOT EXISTS document_chunks (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		document_id INTEGER NOT NULL,
+		user_id VARCHAR(255) NOT NULL,
+		seq INTEGER NOT NULL,
+		content TEXT NOT NULL,
+		embedding BLOB NOT NULL,
+		model VARCHAR(100) NOT NULL,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+	CREATE INDEX IF NOT EXISTS idx_document_chunks_document ON document_chunks(document_id);
+	CREATE INDEX IF NOT EXISTS idx_document_chunks_user ON document_chunks(user_id, model);
+	-- Full-text index of chunk content; rowid is the chunk id
+	CREATE VIRTUAL TABLE IF NOT EXISTS document_chunks_fts USING fts4(content);
+
+	-- Users' account-wide settings; provider_order is a JSON list
+	CREATE TABLE IF NOT EXISTS user_preferences (
+		user_id VARCHAR(255) PRIMARY KEY,
+		provider_order TEXT NOT NULL DEFAULT '[]',
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+
+	-- Reads of confidential documents; kept after the document is deleted
+	CREATE TABLE IF NOT EXISTS document_access_log (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		document_id INTEGER NOT NULL,
+		user_id VARCHAR(255) NOT NULL,
+		endpoint VARCHAR(100) NOT NULL,
+		ip_address VARCHAR(45),
+		accessed_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+	CREATE INDEX IF NOT EXISTS idx_document_access_log_document ON document_access_log(document_id, accessed_at DESC);
+	CREATE INDEX IF NOT EXISTS idx_document_access_log_accessed_at ON document_access_log(accessed_at);
+
+	-- Copies of users' workspaces in the storage backend; counts is a JSON
+	-- object of rows captured by table
+	CREATE TABLE IF NOT EXISTS workspace_snapshots (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id VARCHAR(255) NOT NULL,
+		source VARCHAR(20) NOT NULL,
+		status VARCHAR(20) DEFAULT 'queued',
+		storage_backend VARCHAR(20),
+		storage_key VARCHAR(500),
+		size_bytes INTEGER DEFAULT 0,
+		sha256 VARCHAR(64),
+		counts TEXT,
+		error TEXT,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		completed_at DATETIME
+	);
+	CREATE INDEX IF NOT EXISTS idx_workspace_snapshots_user ON workspace_snapshots(user_id, created_at DESC);
+
+	CREATE TABLE IF NOT EXISTS workspace_restores (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id VARCHAR(255) NOT NULL,
+		snapshot_id INTEGER NOT NULL,
+		safety_snapshot_id INTEGER,
+		status VARCHAR(20) DEFAULT 'queued',
+		restored TEXT,
+		error TEXT,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		completed_at DATETIME
+	);
+	CREATE INDEX IF NOT EXISTS idx_workspace_restores_user ON workspace_restores(user_id, created_at DESC);
+
+	CREATE TABLE IF NOT EXISTS workspace_snapshot_schedules (
+		user_id VARCHAR(255) PRIMARY KEY,
+		interval_hours INTEGER NOT NULL,
+		keep INTEGER NOT NULL,
+		next_run_at DATETIME,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+	CREATE INDEX IF NOT EXISTS idx_workspace_snapshot_schedules_due ON workspace_snapshot_schedules(next_run_at);
+
+	-- Asynchronous bulk document imports; results is a JSON list of the
+	-- outcome of each entry
+	CREATE TABLE IF NOT EXISTS document_import_jobs (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id VARCHAR(255) NOT NULL,
+		source VARCHAR(20) NOT NULL,
+		status VARCHAR(20) DEFAULT 'queued',
+		total_entries INTEGER DEFAULT 0,
+		processed INTEGER DEFAULT 0,
+		created INTEGER DEFAULT 0,
+		failed INTEGER DEFAULT 0,
+		results TEXT,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		completed_at DATETIME
+	);
+	CREATE INDEX IF NOT EXISTS idx_document_import_jobs_user ON document_import_jobs(user_id);
+
+	-- Quota held by in-flight requests until their actual usage is known;
+	-- rows past expires_at no longer count
+	CREATE TABLE IF NOT EXISTS quota_reservations (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id VARCHAR(255) NOT NULL,
+		tokens INTEGER NOT NULL,
+		cost_usd REAL NOT NULL,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		expires_at DATETIME NOT NULL
+	);
+	CREATE INDEX IF NOT EXISTS idx_quota_reservations_user ON quota_reservations(user_id, expires_at);
+
+	-- Synthetic traffic (health checks, backend callbacks, monitoring) that
+	-- bypasses rate limiting and usage tracking, matched by token or CIDR
+	CREATE TABLE IF NOT EXISTS rate_limit_exemptions (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		name VARCHAR(100) NOT NULL,
+		kind VARCHAR(10) NOT NULL,
+		cidr VARCHAR(50),
+		token_hash VARCHAR(64) UNIQUE,
+		token_prefix VARCHAR(20),
+		created_by VARCHAR(255) NOT NULL,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+
+	-- Revisions of documents' title and content, for history and diffs
+	CREATE TABLE IF NOT EXISTS document_versions (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		document_id INTEGER NOT NULL,
+		version INTEGER NOT NULL,
+		title VARCHAR(255) NOT NULL,
+		content TEXT NOT NULL,
+		content_type VARCHAR(100),
+		word_count INTEGER DEFAULT 0,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE(document_id, version)
+	);
+
+	-- Comments on documents, anchored to a range of their content
+	CREATE TABLE IF NOT EXISTS document_comments (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		document_id INTEGER NOT NULL,
+		user_id VARCHAR(255) NOT NULL,
+		body TEXT NOT NULL,
+		start_offset INTEGER NOT NULL,
+		end_offset INTEGER NOT NULL,
+		quote TEXT NOT NULL,
+		resolved BOOLEAN DEFAULT 0,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (document_id) REFERENCES documents(id) ON DELETE CASCADE
+	);
+	CREATE INDEX IF NOT EXISTS idx_document_comments_document ON document_comments(document_id, start_offset);
+
+	-- Documents attached to chats as context for their completions
+	CREATE TABLE IF NOT EXISTS chat_documents (
+		chat_id INTEGER NOT NULL,
+		document_id INTEGER NOT NULL,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (chat_id, document_id),
+		FOREIGN KEY (chat_id) REFERENCES chats(id) ON DELETE CASCADE
+	);
+
+	-- When users are alerted as their usage nears a quota limit
+	CREATE TABLE IF NOT EXISTS quota_alert_settings (
+		user_id VARCHAR(255) PRIMARY KEY,
+		thresholds TEXT NOT NULL,
+		webhook BOOLEAN DEFAULT 1,
+		email BOOLEAN DEFAULT 0,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+
+	-- Thresholds of quota limits users' usage crossed, once per period
+	CREATE TABLE IF NOT EXISTS quota_alerts (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id VARCHAR(255) NOT NULL,
+		metric VARCHAR(20) NOT NULL,
+		threshold INTEGER NOT NULL,
+		used REAL NOT NULL,
+		limit_value REAL NOT NULL,
+		period_start DATETIME NOT NULL,
+		channels TEXT,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE(user_id, metric, threshold, period_start)
+	);
+	CREATE INDEX IF NOT EXISTS idx_quota_alerts_user ON quota_alerts(user_id, created_at);
+
+	-- Every change to a model's price, by an admin or the price feed sync
+	CREATE TABLE IF NOT EXISTS cost_config_history (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		model_name VARCHAR(100) NOT NULL,
+		cost_per_input_token REAL NOT NULL,
+		cost_per_output_token REAL NOT NULL,
+		previous_input_cost REAL,
+		previous_output_cost REAL,
+		operation_type VARCHAR(50) NOT NULL,
+		is_active BOOLEAN NOT NULL,
+		source VARCHAR(20) NOT NULL,
+		changed_by VARCHAR(255),
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+	CREATE INDEX IF NOT EXISTS idx_cost_config_history_model ON cost_config_history(model_name, created_at);
+
+	-- Monthly spending budgets, separate from quotas; scope is "user" with
+	-- the user's ID in scope_id, or "org" with the organization's
+	CREATE TABLE IF NOT EXISTS budgets (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		scope VARCHAR(20) NOT NULL,
+		scope_id VARCHAR(255) NOT NULL,
+		monthly_usd REAL NOT NULL,
+		mode VARCHAR(20) NOT NULL,
+		updated_by VARCHAR(255),
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE(scope, scope_id)
+	);
+
+	-- usage_metrics summed per UTC hour and day ("YYYY-MM-DD HH:00:00"
+	-- buckets) by the usage rollup job; duration_ms is the total, for averages
+	CREATE TABLE IF NOT EXISTS usage_rollups_hourly (
+		bucket_start VARCHAR(19) NOT NULL,
+		user_id VARCHAR(255) NOT NULL,
+		request_type VARCHAR(50) NOT NULL,
+		endpoint VARCHAR(255) NOT NULL,
+		model_used VARCHAR(100) NOT NULL,
+		requests INTEGER NOT NULL DEFAULT 0,
+		successful INTEGER NOT NULL DEFAULT 0,
+		failed INTEGER NOT NULL DEFAULT 0,
+		tokens_input INTEGER NOT NULL DEFAULT 0,
+		tokens_output INTEGER NOT NULL DEFAULT 0,
+		tokens_total INTEGER NOT NULL DEFAULT 0,
+		cost_usd REAL NOT NULL DEFAULT 0.0,
+		duration_ms INTEGER NOT NULL DEFAULT 0,
+		PRIMARY KEY (bucket_start, user_id, request_type, endpoint, model_used)
+	);
+	CREATE INDEX IF NOT EXISTS idx_usage_rollups_hourly_user ON usage_rollups_hourly(user_id, bucket_start);
+
+	CREATE TABLE IF NOT EXISTS usage_rollups_daily (
+		bucket_start VARCHAR(19) NOT NULL,
+		user_id VARCHAR(255) NOT NULL,
+		request_type VARCHAR(50) NOT NULL,
+		endpoint VARCHAR(255) NOT NULL,
+		model_used VARCHAR(100) NOT NULL,
+		requests INTEGER NOT NULL DEFAULT 0,
+		successful INTEGER NOT NULL DEFAULT 0,
+		failed INTEGER NOT NULL DEFAULT 0,
+		tokens_input INTEGER NOT NULL DEFAULT 0,
+		tokens_output INTEGER NOT NULL DEFAULT 0,
+		tokens_total INTEGER NOT NULL DEFAULT 0,
+		cost_usd REAL NOT NULL DEFAULT 0.0,
+		duration_ms INTEGER NOT NULL DEFAULT 0,
+		PRIMARY KEY (bucket_start, user_id, request_type, endpoint, model_used)
+	);
+	CREATE INDEX IF NOT EXISTS idx_usage_rollups_daily_user ON usage_rollups_daily(user_id, bucket_start);
+
+	-- Usage before rolled_until is in the rollups; later usage is read raw
+	CREATE TABLE IF NOT EXISTS usage_rollup_state (
+		id INTEGER PRIMARY KEY CHECK (id = 1),
+		rolled_until VARCHAR(19) NOT NULL,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+
+	-- Organizations whose members share quota and budget limits; a user
+	-- belongs to at most one
+	CREATE TABLE IF NOT EXISTS organizations (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		name VARCHAR(255) NOT NULL,
+		created_by VARCHAR(255) NOT NULL,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE TABLE IF NOT EXISTS organization_members (
+		org_id INTEGER NOT NULL,
+		user_id VARCHAR(255) NOT NULL UNIQUE,
+		role VARCHAR(20) NOT NULL DEFAULT 'member',
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (org_id, user_id),
+		FOREIGN KEY (org_id) REFERENCES organizations(id) ON DELETE CASCADE
+	);
+
+	-- Limits on the summed usage of an organization's members; 0 is no limit
+	CREATE TABLE IF NOT EXISTS org_quotas (
+		org_id INTEGER PRIMARY KEY,
+		daily_token_limit INTEGER NOT NULL DEFAULT 0,
+		monthly_token_limit INTEGER NOT NULL DEFAULT 0,
+		daily_cost_limit_usd REAL NOT NULL DEFAULT 0.0,
+		monthly_cost_limit_usd REAL NOT NULL DEFAULT 0.0,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (org_id) REFERENCES organizations(id) ON DELETE CASCADE
+	);
+
+	-- Plan tiers (users.plan) with the quota limits and rate limit their
+	-- users get; requests_per_minute 0 is the gateway's default rate
+	CREATE TABLE IF NOT EXISTS plans (
+		name VARCHAR(50) PRIMARY KEY,
+		daily_token_limit INTEGER NOT NULL,
+		monthly_token_limit INTEGER NOT NULL,
+		daily_cost_limit_usd REAL NOT NULL,
+		monthly_cost_limit_usd REAL NOT NULL,
+		requests_per_minute INTEGER NOT NULL DEFAULT 0,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+	INSERT OR IGNORE INTO plans (name, daily_token_limit, monthly_token_limit, daily_cost_limit_usd, monthly_cost_limit_usd, requests_per_minute)
+	VALUES
+		('free', 100000, 3000000, 10.0, 300.0, 300),
+		('pro', 1000000, 30000000, 100.0, 3000.0, 1200),
+		('enterprise', 10000000, 300000000, 1000.0, 30000.0, 0);
+
+	-- Monthly billing statements, closed from the daily usage rollups once a
+	-- UTC month is over; rows are only ever inserted
+	CREATE TABLE IF NOT EXISTS billing_statements (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id VARCHAR(255) NOT NULL,
+		period VARCHAR(7) NOT NULL, -- YYYY-MM
+		period_start DATETIME NOT NULL,
+		period_end DATETIME NOT NULL,
+		requests INTEGER NOT NULL DEFAULT 0,
+		tokens_input INTEGER NOT NULL DEFAULT 0,
+		tokens_output INTEGER NOT NULL DEFAULT 0,
+		tokens_total INTEGER NOT NULL DEFAULT 0,
+		usage_cost_usd REAL NOT NULL DEFAULT 0,
+		adjustments_usd REAL NOT NULL DEFAULT 0,
+		total_usd REAL NOT NULL DEFAULT 0,
+		closed_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE(user_id, period)
+	);
+
+	-- A statement's usage by model
+	CREATE TABLE IF NOT EXISTS billing_statement_lines (
+		statement_id INTEGER NOT NULL,
+		model VARCHAR(255) NOT NULL,
+		requests INTEGER NOT NULL,
+		tokens_input INTEGER NOT NULL,
+		tokens_output INTEGER NOT NULL,
+		tokens_total INTEGER NOT NULL,
+		cost_usd REAL NOT NULL,
+		PRIMARY KEY (statement_id, model)
+	);
+
+	-- Credits (negative) and charges admins add to a user's bill; each is
+	-- closed into the first statement whose month ends after it was added
+	CREATE TABLE IF NOT EXISTS billing_adjustments (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id VARCHAR(255) NOT NULL,
+		amount_usd REAL NOT NULL,
+		reason TEXT NOT NULL,
+		created_by VARCHAR(255),
+		statement_id INTEGER, -- NULL until closed into a statement
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+	CREATE INDEX IF NOT EXISTS idx_billing_adjustments_user ON billing_adjustments(user_id, statement_id);
+
+	-- Usage events that could not be tracked after retries, kept to be
+	-- replayed rather than lost
+	CREATE TABLE IF NOT EXISTS usage_dead_letters (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		payload TEXT NOT NULL, -- the UsageRequest as JSON
+		error TEXT NOT NULL,
+		attempts INTEGER NOT NULL DEFAULT 0,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+
+	-- Login tokens revoked before they expire, by their jti, e.g. on logout
+	CREATE TABLE IF NOT EXISTS revoked_tokens (
+		jti VARCHAR(64) PRIMARY KEY,
+		user_id VARCHAR(255) NOT NULL,
+		expires_at DATETIME NOT NULL,
+		revoked_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+	CREATE INDEX IF NOT EXISTS idx_revoked_tokens_expires_at ON revoked_tokens(expires_at);
+
+	-- Each user's tokens issued before revoked_before (unix seconds) are
+	-- revoked, e.g. after a password change
+	CREATE TABLE IF NOT EXISTS user_token_revocations (
+		user_id VARCHAR(255) PRIMARY KEY,
+		revoked_before INTEGER NOT NULL
+	);
+
+	-- Logins on each device, by the jti of the token issued for them
+	CREATE TABLE IF NOT EXISTS sessions (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id INTEGER NOT NULL,
+		token_id VARCHAR(64) NOT NULL UNIQUE,
+		ip_address VARCHAR(64) NOT NULL DEFAULT '',
+		user_agent TEXT NOT NULL DEFAULT '',
+		expires_at DATETIME NOT NULL,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+	CREATE INDEX IF NOT EXISTS idx_sessions_user_id ON sessions(user_id);
+	CREATE INDEX IF NOT EXISTS idx_sessions_expires_at ON sessions(expires_at);
+
+	-- Personal API keys, stored by the SHA-256 of the key
+	CREATE TABLE IF NOT EXISTS api_keys (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id INTEGER NOT NULL,
+		name VARCHAR(100) NOT NULL,
+		description TEXT,
+		key_hash VARCHAR(64) NOT NULL UNIQUE,
+		key_prefix VARCHAR(20) NOT NULL,
+		scopes TEXT NOT NULL, -- space separated
+		expires_at DATETIME,
+		last_used_at DATETIME,
+		revoked_at DATETIME,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+	CREATE INDEX IF NOT EXISTS idx_api_keys_user_id ON api_keys(user_id);
+
+	-- Users' endpoints receiving signed event payloads
+	CREATE TABLE IF NOT EXISTS webhooks (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id VARCHAR(255) NOT NULL,
+		url TEXT NOT NULL,
+		events TEXT NOT NULL,
+		secret VARCHAR(100) NOT NULL,
+		active BOOLEAN DEFAULT 1,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+	CREATE INDEX IF NOT EXISTS idx_webhooks_user ON webhooks(user_id);
+
+	-- Attempts at sending events to webhooks, newest kept per webhook
+	CREATE TABLE IF NOT EXISTS webhook_deliveries (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		webhook_id INTEGER NOT NULL,
+		event_id VARCHAR(64) NOT NULL,
+		event_type VARCHAR(50) NOT NULL,
+		payload TEXT NOT NULL,
+		status_code INTEGER DEFAULT 0,
+		success BOOLEAN DEFAULT 0,
+		latency_ms INTEGER DEFAULT 0,
+		response_excerpt TEXT,
+		error TEXT,
+		is_test BOOLEAN DEFAULT 0,
+		retry_of INTEGER,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (webhook_id) REFERENCES webhooks(id) ON DELETE CASCADE
+	);
+	CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_webhook ON webhook_deliveries(webhook_id, id DESC);
+
+	-- Progress of table rebuilds by online migrations, so they resume
+	CREATE TABLE IF NOT EXISTS online_migrations (
+		version INTEGER PRIMARY KEY,
+		name VARCHAR(100) NOT NULL,
+		table_name VARCHAR(100) NOT NULL,
+		state VARCHAR(20) NOT NULL,
+		last_id INTEGER DEFAULT 0,
+		max_id INTEGER DEFAULT 0,
+		rows_copied INTEGER DEFAULT 0,
+		rows_total INTEGER DEFAULT 0,
+		error TEXT,
+		started_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		completed_at DATETIME
+	);
+
+	-- Versions of the additive migrations applied to this instance
+	CREATE TABLE IF NOT EXISTS schema_migrations (
+		version INTEGER PRIMARY KEY,
+		name VARCHAR(100) NOT NULL,
+		applied_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
 	`
 
 	if _, err := db.Exec(schema); err != nil {
@@ -182,26 +1047,465 @@ func migrate(db *sql.DB) error {
 	}
 	
 	// Additional migrations for existing databases
-	// Add chat_uuid column if it doesn't exist
-	// Check if chat_uuid column exists
-	var chatUuidExists int
-	err := db.QueryRow("SELECT COUNT(*) FROM pragma_table_info('chats') WHERE name='chat_uuid'").Scan(&chatUuidExists)
-	if err == nil && chatUuidExists == 0 {
-		log.Println("Adding chat_uuid column to chats table...")
-		_, err = db.Exec("ALTER TABLE chats ADD COLUMN chat_uuid VARCHAR(255)")
+	if err := applyMigrations(db); err != nil {
+		return fmt.Errorf("failed to apply schema migrations: %w", err)
+	}
+	
+	log.Println("✓ Database migrations completed")
+	return nil
+}
+
+// Migration is a versioned, idempotent schema change applied after the base schema
+type Migration struct {
+	Version int    `json:"version"`
+	Name    string `json:"name"`
+	up      func(db *sql.DB) error
+	// online rebuilds a table in batches instead of running up
+	online *OnlineMigration
+}
+
+// migrations lists schema changes in the order they were introduced.
+// Append new entries; never renumber or remove existing ones.
+var migrations = []Migration{
+	{Version: 1, Name: "initial_schema", up: func(db *sql.DB) error { return nil }},
+	{Version: 2, Name: "chats_chat_uuid", up: func(db *sql.DB) error {
+		added, err := addColumnIfMissing(db, "chats", "chat_uuid", "VARCHAR(255)")
 		if err != nil {
-			log.Printf("Warning: Could not add chat_uuid column: %v", err)
-		} else {
+			return err
+		}
+		if added {
 			// Create index for the new column
 			_, _ = db.Exec("CREATE UNIQUE INDEX IF NOT EXISTS idx_chats_uuid ON chats(chat_uuid)")
 			log.Println("✓ Added chat_uuid column and index")
 		}
+		return nil
+	}},
+	{Version: 3, Name: "usage_metrics_provider", up: func(db *sql.DB) error {
+		// Per-key analytics
+		if _, err := addColumnIfMissing(db, "usage_metrics", "provider", "VARCHAR(50)"); err != nil {
+			return err
+		}
+		_, err := db.Exec("CREATE INDEX IF NOT EXISTS idx_usage_provider ON usage_metrics(user_id, provider)")
+		return err
+	}},
+	{Version: 4, Name: "usage_metrics_key_source", up: func(db *sql.DB) error {
+		// Whether a request was served by the user's key or the platform fallback key
+		_, err := addColumnIfMissing(db, "usage_metrics", "key_source", "VARCHAR(20) DEFAULT 'user'")
+		return err
+	}},
+	{Version: 5, Name: "pending_key_syncs", up: func(db *sql.DB) error { return nil }},
+	{Version: 6, Name: "messages_stopped", up: func(db *sql.DB) error {
+		// Assistant messages whose generation was stopped by the user
+		_, err := addColumnIfMissing(db, "messages", "stopped", "BOOLEAN DEFAULT 0")
+		return err
+	}},
+	{Version: 7, Name: "chats_context_strategy", up: func(db *sql.DB) error {
+		_, err := addColumnIfMissing(db, "chats", "context_strategy", "VARCHAR(20) DEFAULT 'truncate'")
+		return err
+	}},
+	{Version: 8, Name: "schema_migrations", up: func(db *sql.DB) error { return nil }},
+	{Version: 9, Name: "messages_tool_calls", up: func(db *sql.DB) error {
+		// Function-calling fields for assistant tool calls and tool results
+		if _, err := addColumnIfMissing(db, "messages", "tool_calls", "TEXT"); err != nil {
+			return err
+		}
+		_, err := addColumnIfMissing(db, "messages", "tool_call_id", "VARCHAR(255)")
+		return err
+	}},
+	{Version: 10, Name: "attachments", up: func(db *sql.DB) error { return nil }},
+	{Version: 11, Name: "chat_pins_and_bookmarks", up: func(db *sql.DB) error {
+		if _, err := addColumnIfMissing(db, "chats", "is_pinned", "BOOLEAN DEFAULT 0"); err != nil {
+			return err
+		}
+		_, err := addColumnIfMissing(db, "messages", "bookmarked", "BOOLEAN DEFAULT 0")
+		return err
+	}},
+	// Keys are re-encrypted with additional data at startup by
+	// ProviderKeyRepository.ReencryptLegacyKeys, which needs ENCRYPTION_KEY
+	{Version: 12, Name: "provider_keys_encryption_version", up: func(db *sql.DB) error {
+		_, err := addColumnIfMissing(db, "provider_api_keys", "encryption_version", "INTEGER DEFAULT 1")
+		return err
+	}},
+	{Version: 13, Name: "trial_usage", up: func(db *sql.DB) error { return nil }},
+	{Version: 14, Name: "prompt_templates", up: func(db *sql.DB) error { return nil }},
+	{Version: 15, Name: "scheduled_messages", up: func(db *sql.DB) error { return nil }},
+	{Version: 16, Name: "response_caps", up: func(db *sql.DB) error {
+		// Per-chat defaults for the per-response output token and cost caps
+		if _, err := addColumnIfMissing(db, "chats", "max_output_tokens", "INTEGER DEFAULT 0"); err != nil {
+			return err
+		}
+		if _, err := addColumnIfMissing(db, "chats", "max_cost_usd", "REAL DEFAULT 0"); err != nil {
+			return err
+		}
+		_, err := addColumnIfMissing(db, "messages", "truncated", "BOOLEAN DEFAULT 0")
+		return err
+	}},
+	{Version: 17, Name: "idempotency_keys", up: func(db *sql.DB) error { return nil }},
+	{Version: 18, Name: "user_provisioning", up: func(db *sql.DB) error {
+		// Plan assigned by bulk import and SCIM provisioning
+		_, err := addColumnIfMissing(db, "users", "plan", "VARCHAR(50) DEFAULT 'free'")
+		return err
+	}},
+	{Version: 19, Name: "message_feedback", up: func(db *sql.DB) error { return nil }},
+	{Version: 20, Name: "model_aliases", up: func(db *sql.DB) error { return nil }},
+	{Version: 21, Name: "storage_usage", up: func(db *sql.DB) error {
+		// Charge existing attachments to their uploaders. Documents have no
+		// owner yet, so only documents created from now on are counted.
+		_, err := db.Exec(`
+			INSERT OR IGNORE INTO storage_usage (user_id, kind, object_id, bytes, created_at, updated_at)
+			SELECT user_id, 'attachment', id, size_bytes, created_at, created_at FROM attachments
+		`)
+		return err
+	}},
+	{Version: 22, Name: "messages_seq", up: func(db *sql.DB) error {
+		// Per-chat sequence numbers; existing messages are numbered in
+		// creation order, ties broken by id
+		if _, err := addColumnIfMissing(db, "messages", "seq", "INTEGER"); err != nil {
+			return err
+		}
+		if _, err := db.Exec(`
+			UPDATE messages SET seq = (
+				SELECT COUNT(*) FROM messages m
+				WHERE m.chat_id = messages.chat_id
+				  AND (m.created_at < messages.created_at OR (m.created_at = messages.created_at AND m.id <= messages.id))
+			)
+			WHERE seq IS NULL
+		`); err != nil {
+			return err
+		}
+		_, err := db.Exec("CREATE UNIQUE INDEX IF NOT EXISTS idx_messages_chat_seq ON messages(chat_id, seq)")
+		return err
+	}},
+	{Version: 23, Name: "chat_summaries", up: func(db *sql.DB) error {
+		// Cached conversation summary, marked stale when messages arrive
+		for column, definition := range map[string]string{
+			"summary":       "TEXT",
+			"summary_model": "VARCHAR(100)",
+			"summary_at":    "DATETIME",
+			"summary_stale": "BOOLEAN DEFAULT 1",
+		} {
+			if _, err := addColumnIfMissing(db, "chats", column, definition); err != nil {
+				return err
+			}
+		}
+		return nil
+	}},
+	{Version: 24, Name: "chat_budgets", up: func(db *sql.DB) error {
+		// Total cost budget for a chat, and whether going over blocks or warns
+		if _, err := addColumnIfMissing(db, "chats", "budget_usd", "REAL DEFAULT 0"); err != nil {
+			return err
+		}
+		_, err := addColumnIfMissing(db, "chats", "budget_action", "VARCHAR(10) DEFAULT 'block'")
+		return err
+	}},
+	{Version: 25, Name: "personas", up: func(db *sql.DB) error {
+		_, err := addColumnIfMissing(db, "chats", "persona_id", "INTEGER")
+		return err
+	}},
+	{Version: 26, Name: "chat_action_items", up: func(db *sql.DB) error {
+		// Action items extracted along with the summary, as a JSON array
+		_, err := addColumnIfMissing(db, "chats", "summary_action_items", "TEXT")
+		return err
+	}},
+	{Version: 27, Name: "message_moderation", up: func(db *sql.DB) error {
+		// Moderation outcome for user messages, as JSON
+		_, err := addColumnIfMissing(db, "messages", "moderation", "TEXT")
+		return err
+	}},
+	{Version: 28, Name: "security_events", up: func(db *sql.DB) error { return nil }},
+	{Version: 29, Name: "incidents", up: func(db *sql.DB) error { return nil }},
+	{Version: 30, Name: "documents_user_id", up: func(db *sql.DB) error {
+		// Owner of each document; existing documents are assigned to the
+		// user their storage is charged to, and stay hidden when untracked
+		if _, err := addColumnIfMissing(db, "documents", "user_id", "VARCHAR(255)"); err != nil {
+			return err
+		}
+		if _, err := db.Exec(`
+			UPDATE documents SET user_id = (
+				SELECT user_id FROM storage_usage WHERE kind = 'document' AND object_id = documents.id
+			) WHERE user_id IS NULL
+		`); err != nil {
+			return err
+		}
+		_, err := db.Exec("CREATE INDEX IF NOT EXISTS idx_documents_user ON documents(user_id)")
+		return err
+	}},
+	{Version: 31, Name: "passkeys", up: func(db *sql.DB) error { return nil }},
+	{Version: 32, Name: "identity_merges", up: func(db *sql.DB) error {
+		// Account a merged user was folded into
+		_, err := addColumnIfMissing(db, "users", "merged_into", "INTEGER")
+		return err
+	}},
+	{Version: 33, Name: "document_tags", up: func(db *sql.DB) error { return nil }},
+	{Version: 34, Name: "collections", up: func(db *sql.DB) error {
+		if _, err := addColumnIfMissing(db, "documents", "collection_id", "INTEGER"); err != nil {
+			return err
+		}
+		_, err := db.Exec("CREATE INDEX IF NOT EXISTS idx_documents_collection ON documents(collection_id)")
+		return err
+	}},
+	{Version: 35, Name: "widget_tokens", up: func(db *sql.DB) error { return nil }},
+	{Version: 36, Name: "document_uploads", up: func(db *sql.DB) error {
+		// The original file an uploaded document's text was extracted from
+		for column, definition := range map[string]string{
+			"file_name":         "VARCHAR(255)",
+			"file_content_type": "VARCHAR(100)",
+			"file_size":         "INTEGER",
+			"file_backend":      "VARCHAR(20)",
+			"file_key":          "VARCHAR(500)",
+		} {
+			if _, err := addColumnIfMissing(db, "documents", column, definition); err != nil {
+				return err
+			}
+		}
+		return nil
+	}},
+	{Version: 37, Name: "response_plugins", up: func(db *sql.DB) error { return nil }},
+	{Version: 38, Name: "change_events", up: func(db *sql.DB) error { return nil }},
+	{Version: 39, Name: "model_deprecations", up: func(db *sql.DB) error {
+		// Catalog models marked deprecated, and what replaces them after the sunset
+		for column, definition := range map[string]string{
+			"deprecated_at":      "DATETIME",
+			"sunset_at":          "DATETIME",
+			"replacement_model":  "VARCHAR(100)",
+			"deprecation_notice": "TEXT",
+			"deprecated_by":      "VARCHAR(255)",
+		} {
+			if _, err := addColumnIfMissing(db, "cost_config", column, definition); err != nil {
+				return err
+			}
+		}
+		return nil
+	}},
+	{Version: 40, Name: "document_chunks", up: func(db *sql.DB) error { return nil }},
+	{Version: 41, Name: "user_preferences", up: func(db *sql.DB) error { return nil }},
+	{Version: 42, Name: "document_access_log", up: func(db *sql.DB) error {
+		_, err := addColumnIfMissing(db, "documents", "confidential", "BOOLEAN DEFAULT 0")
+		return err
+	}},
+	{Version: 43, Name: "workspace_snapshots", up: func(db *sql.DB) error { return nil }},
+	{Version: 44, Name: "document_import_jobs", up: func(db *sql.DB) error { return nil }},
+	{Version: 45, Name: "quota_reservations", up: func(db *sql.DB) error { return nil }},
+	{Version: 46, Name: "document_metadata", up: func(db *sql.DB) error {
+		// Where a document came from, its language and media type, and its
+		// length in words
+		for column, definition := range map[string]string{
+			"source_url":   "VARCHAR(2048)",
+			"language":     "VARCHAR(35)",
+			"content_type": "VARCHAR(100)",
+			"word_count":   "INTEGER DEFAULT 0",
+		} {
+			if _, err := addColumnIfMissing(db, "documents", column, definition); err != nil {
+				return err
+			}
+		}
+		return countDocumentWords(db)
+	}},
+	{Version: 47, Name: "rate_limit_exemptions", up: func(db *sql.DB) error { return nil }},
+	{Version: 48, Name: "document_content_types", up: func(db *sql.DB) error {
+		// Content types became markdown, plaintext, html or code; code
+		// documents also name their language
+		if _, err := addColumnIfMissing(db, "documents", "code_language", "VARCHAR(32)"); err != nil {
+			return err
+		}
+		_, err := db.Exec(`UPDATE documents SET content_type = CASE
+			WHEN content_type IN ('markdown', 'plaintext', 'html', 'code') THEN content_type
+			WHEN content_type IN ('text/markdown', 'text/x-markdown') THEN 'markdown'
+			WHEN content_type IN ('text/html', 'application/xhtml+xml') THEN 'html'
+			ELSE 'plaintext' END`)
+		return err
+	}},
+	{Version: 49, Name: "document_versions", up: func(db *sql.DB) error {
+		// Existing documents start their history at their current content
+		_, err := db.Exec(`INSERT OR IGNORE INTO document_versions (document_id, version, title, content, content_type, word_count, created_at)
+			SELECT id, 1, title, content, content_type, COALESCE(word_count, 0), COALESCE(updated_at, created_at) FROM documents`)
+		return err
+	}},
+	{Version: 50, Name: "document_favorites", up: func(db *sql.DB) error {
+		_, err := addColumnIfMissing(db, "documents", "is_favorite", "BOOLEAN DEFAULT 0")
+		return err
+	}},
+	{Version: 51, Name: "online_migrations", up: func(db *sql.DB) error { return nil }},
+	// Usage queries filter by user and period; rebuilt so the index is
+	// filled batch by batch rather than by one long CREATE INDEX
+	{Version: 52, Name: "usage_metrics_indexes", online: &OnlineMigration{
+		Table: "usage_metrics",
+		Schema: `id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id VARCHAR(255) NOT NULL,
+			request_type VARCHAR(50) NOT NULL,
+			resource_id INTEGER,
+			tokens_input INTEGER DEFAULT 0,
+			tokens_output INTEGER DEFAULT 0,
+			tokens_total INTEGER DEFAULT 0,
+			model_used VARCHAR(100),
+			cost_usd REAL DEFAULT 0.0,
+			duration_ms INTEGER DEFAULT 0,
+			endpoint VARCHAR(255),
+			provider VARCHAR(50),
+			key_source VARCHAR(20) DEFAULT 'user',
+			success BOOLEAN DEFAULT 1,
+			error_message TEXT,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP`,
+		PostgresSchema: `id BIGSERIAL PRIMARY KEY,
+			user_id VARCHAR(255) NOT NULL,
+			request_type VARCHAR(50) NOT NULL,
+			resource_id BIGINT,
+			tokens_input INTEGER DEFAULT 0,
+			tokens_output INTEGER DEFAULT 0,
+			tokens_total INTEGER DEFAULT 0,
+			model_used VARCHAR(100),
+			cost_usd DOUBLE PRECISION DEFAULT 0.0,
+			duration_ms INTEGER DEFAULT 0,
+			endpoint VARCHAR(255),
+			provider VARCHAR(50),
+			key_source VARCHAR(20) DEFAULT 'user',
+			success INTEGER DEFAULT 1,
+			error_message TEXT,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP`,
+		Indexes: []string{
+			"CREATE INDEX idx_usage_metrics_user_created ON %s(user_id, created_at)",
+			"CREATE INDEX idx_usage_metrics_user_provider ON %s(user_id, provider)",
+			"CREATE INDEX idx_usage_metrics_created_at ON %s(created_at DESC)",
+			"CREATE INDEX idx_usage_metrics_request_type ON %s(request_type)",
+		},
+		Pause: 10 * time.Millisecond,
+	}},
+	{Version: 53, Name: "webhooks", up: func(db *sql.DB) error { return nil }},
+	{Version: 54, Name: "document_comments", up: func(db *sql.DB) error { return nil }},
+	{Version: 55, Name: "document_edit_versions", up: func(db *sql.DB) error {
+		_, err := addColumnIfMissing(db, "documents", "version", "INTEGER NOT NULL DEFAULT 1")
+		return err
+	}},
+	{Version: 56, Name: "chat_documents", up: func(db *sql.DB) error {
+		_, err := addColumnIfMissing(db, "messages", "context_chunks", "TEXT")
+		return err
+	}},
+	// Users who existed before onboarding samples count as onboarded, so
+	// only new accounts get them
+	{Version: 57, Name: "onboarding_samples", up: func(db *sql.DB) error {
+		added, err := addColumnIfMissing(db, "users", "onboarded_at", "DATETIME")
+		if err != nil || !added {
+			return err
+		}
+		_, err = db.Exec("UPDATE users SET onboarded_at = COALESCE(created_at, CURRENT_TIMESTAMP)")
+		return err
+	}},
+	{Version: 58, Name: "quota_alerts", up: func(db *sql.DB) error { return nil }},
+	{Version: 59, Name: "cost_config_history", up: func(db *sql.DB) error { return nil }},
+	{Version: 60, Name: "budgets", up: func(db *sql.DB) error { return nil }},
+	{Version: 61, Name: "usage_rollups", up: func(db *sql.DB) error { return nil }},
+	{Version: 62, Name: "organizations", up: func(db *sql.DB) error { return nil }},
+	{Version: 63, Name: "plans", up: func(db *sql.DB) error { return nil }},
+	{Version: 64, Name: "billing_statements", up: func(db *sql.DB) error { return nil }},
+	{Version: 65, Name: "usage_dead_letters", up: func(db *sql.DB) error { return nil }},
+	{Version: 66, Name: "token_revocations", up: func(db *sql.DB) error { return nil }},
+	{Version: 67, Name: "sessions", up: func(db *sql.DB) error { return nil }},
+	{Version: 68, Name: "api_keys", up: func(db *sql.DB) error { return nil }},
+	{Version: 69, Name: "chat_context_summaries", up: func(db *sql.DB) error {
+		// Summary of the turns dropped from the context window, through the
+		// message with seq context_summary_seq
+		if _, err := addColumnIfMissing(db, "chats", "context_summary", "TEXT"); err != nil {
+			return err
+		}
+		_, err := addColumnIfMissing(db, "chats", "context_summary_seq", "INTEGER")
+		return err
+	}},
+	{Version: 70, Name: "trial_usage_ip_index", up: func(db *sql.DB) error { return nil }},
+}
+
+// countDocumentWords fills in the word count of documents written before
+// it was kept, splitting content at whitespace as the document service does
+func countDocumentWords(db *sql.DB) error {
+	rows, err := db.Query("SELECT id, content FROM documents WHERE COALESCE(word_count, 0) = 0")
+	if err != nil {
+		return err
+	}
+	counts := make(map[int64]int)
+	for rows.Next() {
+		var id int64
+		var content string
+		if err := rows.Scan(&id, &content); err != nil {
+			rows.Close()
+			return err
+		}
+		counts[id] = len(strings.Fields(content))
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for id, count := range counts {
+		if _, err := db.Exec("UPDATE documents SET word_count = ? WHERE id = ?", count, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Migrations returns the schema migrations known to this build
+func Migrations() []Migration {
+	return migrations
+}
+
+// applyMigrations runs migrations not yet recorded in schema_migrations.
+// Failures are logged and retried on the next start.
+func applyMigrations(db *sql.DB) error {
+	applied := make(map[int]bool)
+	rows, err := db.Query("SELECT version FROM schema_migrations")
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var version int
+		if err := rows.Scan(&version); err != nil {
+			rows.Close()
+			return err
+		}
+		applied[version] = true
+	}
+	rows.Close()
+
+	for _, m := range migrations {
+		if applied[m.Version] {
+			continue
+		}
+		if m.online != nil {
+			err = m.online.run(db, m.Version, m.Name)
+		} else {
+			err = m.up(db)
+		}
+		if err != nil {
+			log.Printf("Warning: Could not apply migration %d (%s): %v", m.Version, m.Name, err)
+			continue
+		}
+		if _, err := db.Exec("INSERT INTO schema_migrations (version, name) VALUES (?, ?)", m.Version, m.Name); err != nil {
+			return err
+		}
+		log.Printf("✓ Applied schema migration %d (%s)", m.Version, m.Name)
 	}
-	
-	log.Println("✓ Database migrations completed")
 	return nil
 }
 
+// addColumnIfMissing adds a column to an existing table when it is not present yet.
+// It reports whether the column was added.
+func addColumnIfMissing(db *sql.DB, table, column, definition string) (bool, error) {
+	var exists int
+	query := fmt.Sprintf("SELECT COUNT(*) FROM pragma_table_info('%s') WHERE name = ?", table)
+	if err := db.QueryRow(query, column).Scan(&exists); err != nil {
+		return false, err
+	}
+	if exists > 0 {
+		return false, nil
+	}
+
+	log.Printf("Adding %s column to %s table...", column, table)
+	if _, err := db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition)); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
 // GetConnection returns the underlying database connection
 func (d *Database) GetConnection() *sql.DB {
 	return d.conn
@@ -0,0 +1,214 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/stdlib"
+	"github.com/mattn/go-sqlite3"
+)
+
+// onlineDialect is the driver-specific SQL an online migration is built
+// from. Queries are written with ? placeholders and rebound per driver.
+type onlineDialect interface {
+	rebind(query string) string
+	// copyTriggers keep the rebuilt table in step with writes to the live
+	// one while it is backfilled
+	copyTriggers(m *OnlineMigration, columns, exprs string) []string
+	dropCopyTriggers(m *OnlineMigration) []string
+	// insertMissing inserts the rows of query the rebuilt table doesn't
+	// hold yet
+	insertMissing(table, columns, query string) string
+	tableColumns(tx *sql.Tx, table string) ([]string, error)
+	// lastKey and keepLastKey carry the live table's key sequence over
+	// to the rebuilt one
+	lastKey(tx *sql.Tx, m *OnlineMigration) (sql.NullInt64, error)
+	keepLastKey(tx *sql.Tx, m *OnlineMigration, last int64) error
+	busy(err error) bool
+}
+
+// dialectOf picks the online migration dialect of db's driver
+func dialectOf(db *sql.DB) onlineDialect {
+	switch db.Driver().(type) {
+	case *stdlib.Driver:
+		return postgresDialect{}
+	default:
+		return sqliteDialect{}
+	}
+}
+
+type sqliteDialect struct{}
+
+func (sqliteDialect) rebind(query string) string { return query }
+
+func (sqliteDialect) copyTriggers(m *OnlineMigration, columns, exprs string) []string {
+	shadow := m.shadow()
+	copyRow := fmt.Sprintf("INSERT OR REPLACE INTO %s (%s) SELECT %s FROM %s WHERE %s = NEW.%s;",
+		shadow, columns, exprs, m.Table, m.key(), m.key())
+	removeRow := fmt.Sprintf("DELETE FROM %s WHERE %s = OLD.%s;", shadow, m.key(), m.key())
+	return []string{
+		fmt.Sprintf("CREATE TRIGGER %s_insert AFTER INSERT ON %s BEGIN %s END", shadow, m.Table, copyRow),
+		fmt.Sprintf("CREATE TRIGGER %s_update AFTER UPDATE ON %s BEGIN %s %s END", shadow, m.Table, removeRow, copyRow),
+		fmt.Sprintf("CREATE TRIGGER %s_delete AFTER DELETE ON %s BEGIN %s END", shadow, m.Table, removeRow),
+	}
+}
+
+func (sqliteDialect) dropCopyTriggers(m *OnlineMigration) []string {
+	var drops []string
+	for _, suffix := range []string{"insert", "update", "delete"} {
+		drops = append(drops, fmt.Sprintf("DROP TRIGGER IF EXISTS %s_%s", m.shadow(), suffix))
+	}
+	return drops
+}
+
+func (sqliteDialect) insertMissing(table, columns, query string) string {
+	return fmt.Sprintf("INSERT OR IGNORE INTO %s (%s) %s", table, columns, query)
+}
+
+func (sqliteDialect) tableColumns(tx *sql.Tx, table string) ([]string, error) {
+	return scanColumns(tx.Query(fmt.Sprintf("SELECT name FROM pragma_table_info('%s') ORDER BY cid", table)))
+}
+
+func (sqliteDialect) lastKey(tx *sql.Tx, m *OnlineMigration) (sql.NullInt64, error) {
+	var seq sql.NullInt64
+	if err := tx.QueryRow("SELECT seq FROM sqlite_sequence WHERE name = ?", m.Table).Scan(&seq); err != nil && err != sql.ErrNoRows {
+		return seq, err
+	}
+	return seq, nil
+}
+
+func (sqliteDialect) keepLastKey(tx *sql.Tx, m *OnlineMigration, last int64) error {
+	_, err := tx.Exec("UPDATE sqlite_sequence SET seq = ? WHERE name = ? AND seq < ?", last, m.Table, last)
+	return err
+}
+
+func (sqliteDialect) busy(err error) bool {
+	var sqliteErr sqlite3.Error
+	return errors.As(err, &sqliteErr) && (sqliteErr.Code == sqlite3.ErrBusy || sqliteErr.Code == sqlite3.ErrLocked)
+}
+
+type postgresDialect struct{}
+
+func (postgresDialect) rebind(query string) string {
+	var b strings.Builder
+	n := 0
+	for _, r := range query {
+		if r == '?' {
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// copyTriggers on Postgres is one row trigger calling a plpgsql function,
+// as trigger bodies can't hold statements directly
+func (postgresDialect) copyTriggers(m *OnlineMigration, columns, exprs string) []string {
+	shadow, key := m.shadow(), m.key()
+	return []string{
+		fmt.Sprintf(`CREATE OR REPLACE FUNCTION %[1]s_sync() RETURNS trigger AS $$
+BEGIN
+	IF TG_OP <> 'INSERT' THEN
+		DELETE FROM %[1]s WHERE %[2]s = OLD.%[2]s;
+	END IF;
+	IF TG_OP <> 'DELETE' THEN
+		DELETE FROM %[1]s WHERE %[2]s = NEW.%[2]s;
+		INSERT INTO %[1]s (%[3]s) SELECT %[4]s FROM %[5]s WHERE %[2]s = NEW.%[2]s;
+	END IF;
+	RETURN NULL;
+END
+$$ LANGUAGE plpgsql`, shadow, key, columns, exprs, m.Table),
+		fmt.Sprintf("DROP TRIGGER IF EXISTS %s_sync ON %s", shadow, m.Table),
+		fmt.Sprintf("CREATE TRIGGER %[1]s_sync AFTER INSERT OR UPDATE OR DELETE ON %[2]s FOR EACH ROW EXECUTE FUNCTION %[1]s_sync()",
+			shadow, m.Table),
+	}
+}
+
+func (postgresDialect) dropCopyTriggers(m *OnlineMigration) []string {
+	return []string{
+		fmt.Sprintf("DROP TRIGGER IF EXISTS %s_sync ON %s", m.shadow(), m.Table),
+		fmt.Sprintf("DROP FUNCTION IF EXISTS %s_sync()", m.shadow()),
+	}
+}
+
+func (postgresDialect) insertMissing(table, columns, query string) string {
+	return fmt.Sprintf("INSERT INTO %s (%s) %s ON CONFLICT DO NOTHING", table, columns, query)
+}
+
+func (postgresDialect) tableColumns(tx *sql.Tx, table string) ([]string, error) {
+	return scanColumns(tx.Query(`SELECT column_name FROM information_schema.columns
+		WHERE table_schema = current_schema() AND table_name = $1 ORDER BY ordinal_position`, table))
+}
+
+// lastKey reads the live table's serial sequence, which goes with it when
+// it is dropped
+func (postgresDialect) lastKey(tx *sql.Tx, m *OnlineMigration) (sql.NullInt64, error) {
+	var seq sql.NullString
+	if err := tx.QueryRow("SELECT pg_get_serial_sequence($1, $2)", m.Table, m.key()).Scan(&seq); err != nil || !seq.Valid {
+		return sql.NullInt64{}, err
+	}
+	var last sql.NullInt64
+	var called bool
+	if err := tx.QueryRow(fmt.Sprintf("SELECT last_value, is_called FROM %s", seq.String)).Scan(&last, &called); err != nil {
+		return last, err
+	}
+	if !called {
+		last.Int64 = 0
+	}
+	return last, nil
+}
+
+// keepLastKey also moves the rebuilt table's sequence past the keys the
+// backfill copied, as explicit keys don't advance it, and takes over the
+// live sequence's name
+func (postgresDialect) keepLastKey(tx *sql.Tx, m *OnlineMigration, last int64) error {
+	var seq sql.NullString
+	if err := tx.QueryRow("SELECT pg_get_serial_sequence($1, $2)", m.Table, m.key()).Scan(&seq); err != nil || !seq.Valid {
+		return err
+	}
+	var copied int64
+	if err := tx.QueryRow(fmt.Sprintf("SELECT COALESCE(MAX(%s), 0) FROM %s", m.key(), m.Table)).Scan(&copied); err != nil {
+		return err
+	}
+	if copied > last {
+		last = copied
+	}
+	if last > 0 {
+		if _, err := tx.Exec("SELECT setval($1, $2)", seq.String, last); err != nil {
+			return err
+		}
+	}
+	_, err := tx.Exec(fmt.Sprintf("ALTER SEQUENCE %s RENAME TO %s_%s_seq", seq.String, m.Table, m.key()))
+	return err
+}
+
+// busy is true for lock timeouts, deadlocks and serialization failures,
+// which a retry can get past
+func (postgresDialect) busy(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && (pgErr.Code == "40001" || pgErr.Code == "40P01" || pgErr.Code == "55P03")
+}
+
+// scanColumns collects the column names rows lists
+func scanColumns(rows *sql.Rows, err error) ([]string, error) {
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var columns []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		columns = append(columns, name)
+	}
+	return columns, rows.Err()
+}
@@ -0,0 +1,356 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+// Online migration states recorded in online_migrations
+const (
+	onlineBackfilling = "backfilling"
+	onlineCompleted   = "completed"
+)
+
+// Attempts at a batch or the cutover while other writers hold the database
+const onlineBusyRetries = 20
+
+// OnlineMigration rebuilds a large table without locking it for the length
+// of the change. The rebuilt table is created next to the live one and kept
+// in sync by triggers while existing rows are copied over in short batches,
+// then swapped in by one transaction. Progress is recorded batch by batch,
+// so a restart resumes the copy where it stopped.
+//
+// Use it for changes that would otherwise rewrite or scan a table holding
+// the whole database lock, such as indexing usage_metrics or messages or
+// changing a column's type, and addColumnIfMissing for plain new columns.
+// Online migrations run on SQLite and Postgres, whose triggers, catalogs
+// and key sequences differ behind onlineDialect. On Postgres, tables other
+// tables hold foreign keys to can't be rebuilt, as dropping them would take
+// the constraints with them.
+type OnlineMigration struct {
+	Table string
+	// Schema is the column and constraint list of the rebuilt table,
+	// as in its CREATE TABLE statement
+	Schema string
+	// PostgresSchema is Schema on Postgres, e.g. with BIGSERIAL for an
+	// AUTOINCREMENT key and INTEGER flags as OpenPostgres binds them;
+	// Schema when empty
+	PostgresSchema string
+	// Indexes are created on the rebuilt table before it is filled, each a
+	// CREATE INDEX statement with %s for the table. Names must differ from
+	// the live table's indexes, which are dropped with it.
+	Indexes []string
+	// Convert gives the expression over the live table's columns each
+	// rebuilt column is copied from; columns both tables have are copied
+	// as they are
+	Convert map[string]string
+	// Key is the integer primary key rows are copied in order of; "id"
+	// when empty
+	Key string
+	// BatchSize rows are copied per transaction; 1000 when zero
+	BatchSize int
+	// Pause between batches leaves the database to other writers
+	Pause time.Duration
+}
+
+// OnlineMigrationProgress is how far an online migration has come
+type OnlineMigrationProgress struct {
+	Version     int        `json:"version"`
+	Name        string     `json:"name"`
+	Table       string     `json:"table"`
+	State       string     `json:"state"`
+	RowsTotal   int64      `json:"rows_total"`
+	RowsCopied  int64      `json:"rows_copied"`
+	Error       string     `json:"error,omitempty"`
+	StartedAt   time.Time  `json:"started_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+}
+
+// OnlineMigrations lists the online migrations started on this instance
+func OnlineMigrations(conn *sql.DB) ([]OnlineMigrationProgress, error) {
+	rows, err := conn.Query(`SELECT version, name, table_name, state, rows_total, rows_copied,
+		COALESCE(error, ''), started_at, updated_at, completed_at
+		FROM online_migrations ORDER BY version`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	progress := make([]OnlineMigrationProgress, 0)
+	for rows.Next() {
+		var p OnlineMigrationProgress
+		var completedAt sql.NullTime
+		if err := rows.Scan(&p.Version, &p.Name, &p.Table, &p.State, &p.RowsTotal, &p.RowsCopied,
+			&p.Error, &p.StartedAt, &p.UpdatedAt, &completedAt); err != nil {
+			return nil, err
+		}
+		if completedAt.Valid {
+			p.CompletedAt = &completedAt.Time
+		}
+		progress = append(progress, p)
+	}
+	return progress, rows.Err()
+}
+
+// run applies the migration recorded as version, resuming a copy an
+// earlier start left unfinished
+func (m *OnlineMigration) run(db *sql.DB, version int, name string) error {
+	dialect := dialectOf(db)
+	var state string
+	var lastID, maxID, copied, total int64
+	err := db.QueryRow(dialect.rebind("SELECT state, last_id, max_id, rows_copied, rows_total FROM online_migrations WHERE version = ?"), version).
+		Scan(&state, &lastID, &maxID, &copied, &total)
+	switch {
+	case err == sql.ErrNoRows:
+		err = retryBusy(dialect, func() (err error) {
+			maxID, total, err = m.prepare(db, version, name)
+			return err
+		})
+		if err != nil {
+			return m.fail(db, version, fmt.Errorf("failed to create %s: %w", m.shadow(), err))
+		}
+	case err != nil:
+		return err
+	case state == onlineCompleted:
+		return nil
+	default:
+		log.Printf("Resuming rebuild of %s at %d/%d rows...", m.Table, copied, total)
+	}
+
+	batch := m.BatchSize
+	if batch <= 0 {
+		batch = 1000
+	}
+	logged := time.Now()
+	for lastID < maxID {
+		var n, next int64
+		err := retryBusy(dialect, func() (err error) {
+			n, next, err = m.copyBatch(db, version, lastID, maxID, batch)
+			return err
+		})
+		if err != nil {
+			return m.fail(db, version, fmt.Errorf("failed to copy %s rows after %s %d: %w", m.Table, m.key(), lastID, err))
+		}
+		if n == 0 {
+			break
+		}
+		lastID, copied = next, copied+n
+		if time.Since(logged) >= 10*time.Second {
+			log.Printf("⏳ Rebuilding %s: %d/%d rows copied", m.Table, copied, total)
+			logged = time.Now()
+		}
+		if m.Pause > 0 {
+			time.Sleep(m.Pause)
+		}
+	}
+
+	if err := retryBusy(dialect, func() error { return m.cutover(db, version) }); err != nil {
+		return m.fail(db, version, fmt.Errorf("failed to swap in rebuilt %s: %w", m.Table, err))
+	}
+	log.Printf("✓ Rebuilt %s (%d rows)", m.Table, copied)
+	return nil
+}
+
+// prepare creates the rebuilt table, its indexes and the triggers copying
+// writes to the live table into it. Rows up to the returned key were
+// written before the triggers and are left to the backfill.
+func (m *OnlineMigration) prepare(db *sql.DB, version int, name string) (maxID, total int64, err error) {
+	dialect := dialectOf(db)
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, 0, err
+	}
+	defer tx.Rollback()
+
+	shadow := m.shadow()
+	schema := m.Schema
+	if _, ok := dialect.(postgresDialect); ok && m.PostgresSchema != "" {
+		schema = m.PostgresSchema
+	}
+	if _, err := tx.Exec(fmt.Sprintf("DROP TABLE IF EXISTS %s", shadow)); err != nil {
+		return 0, 0, err
+	}
+	if _, err := tx.Exec(fmt.Sprintf("CREATE TABLE %s (%s)", shadow, schema)); err != nil {
+		return 0, 0, err
+	}
+	for _, index := range m.Indexes {
+		if _, err := tx.Exec(fmt.Sprintf(index, shadow)); err != nil {
+			return 0, 0, err
+		}
+	}
+
+	columns, exprs, err := m.copyColumns(tx, dialect)
+	if err != nil {
+		return 0, 0, err
+	}
+	for _, trigger := range dialect.copyTriggers(m, columns, exprs) {
+		if _, err := tx.Exec(trigger); err != nil {
+			return 0, 0, err
+		}
+	}
+
+	if err := tx.QueryRow(fmt.Sprintf("SELECT COALESCE(MAX(%s), 0), COUNT(*) FROM %s", m.key(), m.Table)).Scan(&maxID, &total); err != nil {
+		return 0, 0, err
+	}
+	if _, err := tx.Exec(dialect.rebind("DELETE FROM online_migrations WHERE version = ?"), version); err != nil {
+		return 0, 0, err
+	}
+	if _, err := tx.Exec(dialect.rebind(`INSERT INTO online_migrations
+		(version, name, table_name, state, max_id, rows_total) VALUES (?, ?, ?, ?, ?, ?)`),
+		version, name, m.Table, onlineBackfilling, maxID, total); err != nil {
+		return 0, 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, 0, err
+	}
+
+	log.Printf("Rebuilding %s (%d rows)...", m.Table, total)
+	return maxID, total, nil
+}
+
+// copyBatch copies up to batch rows with keys after lastID and up to maxID,
+// skipping rows the triggers have already copied, and records the progress
+func (m *OnlineMigration) copyBatch(db *sql.DB, version int, lastID, maxID int64, batch int) (n, next int64, err error) {
+	dialect := dialectOf(db)
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, 0, err
+	}
+	defer tx.Rollback()
+
+	var upper sql.NullInt64
+	if err := tx.QueryRow(dialect.rebind(fmt.Sprintf("SELECT MAX(%[1]s), COUNT(*) FROM (SELECT %[1]s FROM %[2]s WHERE %[1]s > ? AND %[1]s <= ? ORDER BY %[1]s LIMIT ?) AS batch",
+		m.key(), m.Table)), lastID, maxID, batch).Scan(&upper, &n); err != nil {
+		return 0, 0, err
+	}
+	next = maxID
+	if upper.Valid {
+		next = upper.Int64
+	}
+
+	if n > 0 {
+		columns, exprs, err := m.copyColumns(tx, dialect)
+		if err != nil {
+			return 0, 0, err
+		}
+		query := dialect.insertMissing(m.shadow(), columns, fmt.Sprintf("SELECT %s FROM %s WHERE %s > ? AND %s <= ?",
+			exprs, m.Table, m.key(), m.key()))
+		if _, err := tx.Exec(dialect.rebind(query), lastID, next); err != nil {
+			return 0, 0, err
+		}
+	}
+	if _, err := tx.Exec(dialect.rebind(`UPDATE online_migrations SET last_id = ?, rows_copied = rows_copied + ?, error = NULL,
+		updated_at = CURRENT_TIMESTAMP WHERE version = ?`), next, n, version); err != nil {
+		return 0, 0, err
+	}
+	return n, next, tx.Commit()
+}
+
+// cutover replaces the live table by the rebuilt one in one transaction
+func (m *OnlineMigration) cutover(db *sql.DB, version int) error {
+	dialect := dialectOf(db)
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	shadow := m.shadow()
+	for _, drop := range dialect.dropCopyTriggers(m) {
+		if _, err := tx.Exec(drop); err != nil {
+			return err
+		}
+	}
+
+	// Keep the key sequence from handing out keys of rows deleted before
+	// the swap
+	seq, err := dialect.lastKey(tx, m)
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(fmt.Sprintf("DROP TABLE %s", m.Table)); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(fmt.Sprintf("ALTER TABLE %s RENAME TO %s", shadow, m.Table)); err != nil {
+		return err
+	}
+	if seq.Valid {
+		if err := dialect.keepLastKey(tx, m, seq.Int64); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.Exec(dialect.rebind(`UPDATE online_migrations SET state = ?, error = NULL, updated_at = CURRENT_TIMESTAMP,
+		completed_at = CURRENT_TIMESTAMP WHERE version = ?`), onlineCompleted, version); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// fail records err against the migration, which is retried on the next start
+func (m *OnlineMigration) fail(db *sql.DB, version int, err error) error {
+	_, _ = db.Exec(dialectOf(db).rebind("UPDATE online_migrations SET error = ?, updated_at = CURRENT_TIMESTAMP WHERE version = ?"), err.Error(), version)
+	return err
+}
+
+// retryBusy runs fn again, backing off, while it fails because another
+// connection holds the rows or tables it needs
+func retryBusy(dialect onlineDialect, fn func() error) error {
+	backoff := 50 * time.Millisecond
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || attempt == onlineBusyRetries || !dialect.busy(err) {
+			return err
+		}
+		time.Sleep(backoff)
+		if backoff < time.Second {
+			backoff *= 2
+		}
+	}
+}
+
+// copyColumns lists the rebuilt table's columns filled from the live table
+// and the expressions they are copied from
+func (m *OnlineMigration) copyColumns(tx *sql.Tx, dialect onlineDialect) (columns, exprs string, err error) {
+	live, err := dialect.tableColumns(tx, m.Table)
+	if err != nil {
+		return "", "", err
+	}
+	rebuilt, err := dialect.tableColumns(tx, m.shadow())
+	if err != nil {
+		return "", "", err
+	}
+
+	var names, values []string
+	for _, column := range rebuilt {
+		if expr, ok := m.Convert[column]; ok {
+			names, values = append(names, column), append(values, expr)
+			continue
+		}
+		for _, c := range live {
+			if c == column {
+				names, values = append(names, column), append(values, column)
+				break
+			}
+		}
+	}
+	if len(names) == 0 {
+		return "", "", fmt.Errorf("%s and %s have no columns in common", m.Table, m.shadow())
+	}
+	return strings.Join(names, ", "), strings.Join(values, ", "), nil
+}
+
+func (m *OnlineMigration) shadow() string {
+	return m.Table + "_online"
+}
+
+func (m *OnlineMigration) key() string {
+	if m.Key == "" {
+		return "id"
+	}
+	return m.Key
+}
@@ -0,0 +1,126 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
+)
+
+// newPostgresUsageMetrics returns a connection to a fresh schema of the
+// POSTGRES_TEST_DSN database holding rows usage rows and the
+// online_migrations table, skipping the test without a DSN
+func newPostgresUsageMetrics(t *testing.T, rows int) *sql.DB {
+	t.Helper()
+	dsn := os.Getenv("POSTGRES_TEST_DSN")
+	if dsn == "" {
+		t.Skip("POSTGRES_TEST_DSN is not set")
+	}
+	admin, err := sql.Open("pgx", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { admin.Close() })
+	schema := fmt.Sprintf("online_test_%d", time.Now().UnixNano())
+	mustExec(t, admin, "CREATE SCHEMA "+schema)
+	t.Cleanup(func() { admin.Exec("DROP SCHEMA " + schema + " CASCADE") })
+
+	cfg, err := pgx.ParseConfig(dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.RuntimeParams["search_path"] = schema
+	conn := stdlib.OpenDB(*cfg)
+	t.Cleanup(func() { conn.Close() })
+
+	mustExec(t, conn, `CREATE TABLE online_migrations (
+		version INTEGER PRIMARY KEY,
+		name VARCHAR(100) NOT NULL,
+		table_name VARCHAR(100) NOT NULL,
+		state VARCHAR(20) NOT NULL,
+		last_id BIGINT DEFAULT 0,
+		max_id BIGINT DEFAULT 0,
+		rows_copied BIGINT DEFAULT 0,
+		rows_total BIGINT DEFAULT 0,
+		error TEXT,
+		started_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		completed_at TIMESTAMP
+	)`)
+	mustExec(t, conn, `CREATE TABLE usage_metrics (
+		id BIGSERIAL PRIMARY KEY,
+		user_id VARCHAR(255) NOT NULL,
+		request_type VARCHAR(50) NOT NULL,
+		tokens_total INTEGER DEFAULT 0,
+		provider VARCHAR(50),
+		success INTEGER DEFAULT 1,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	)`)
+	for i := 0; i < rows; i++ {
+		mustExec(t, conn, "INSERT INTO usage_metrics (user_id, request_type, tokens_total, provider) VALUES ($1, 'chat', $2, 'openai')", fmt.Sprint(i%7), i)
+	}
+	mustExec(t, conn, "DELETE FROM usage_metrics WHERE id % 10 = 3")
+	return conn
+}
+
+func postgresUsageMetricsRebuild() *OnlineMigration {
+	for _, m := range Migrations() {
+		if m.Name == "usage_metrics_indexes" {
+			rebuild := *m.online
+			rebuild.BatchSize, rebuild.Pause = 100, 0
+			return &rebuild
+		}
+	}
+	return nil
+}
+
+func TestPostgresUsageMetricsRebuildResumesWithWrites(t *testing.T) {
+	conn := newPostgresUsageMetrics(t, 500)
+	online := postgresUsageMetricsRebuild()
+	if online == nil {
+		t.Fatal("usage_metrics_indexes migration not found")
+	}
+
+	maxID, _, err := online.prepare(conn, 52, "usage_metrics_indexes")
+	if err != nil {
+		t.Fatalf("prepare: %v", err)
+	}
+	if _, _, err := online.copyBatch(conn, 52, 0, maxID, 100); err != nil {
+		t.Fatalf("copyBatch: %v", err)
+	}
+	// Writes made while the rebuild is paused reach the rebuilt table
+	mustExec(t, conn, "INSERT INTO usage_metrics (user_id, request_type, tokens_total) VALUES ('new', 'chat', 1000)")
+	mustExec(t, conn, "UPDATE usage_metrics SET tokens_total = tokens_total + 1 WHERE id IN (2, 400)")
+	mustExec(t, conn, "DELETE FROM usage_metrics WHERE id IN (5, 450)")
+	wantRows, wantTokens := usageMetricsTotals(t, conn)
+
+	if err := online.run(conn, 52, "usage_metrics_indexes"); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if rows, tokens := usageMetricsTotals(t, conn); rows != wantRows || tokens != wantTokens {
+		t.Errorf("usage_metrics has %d rows of %d tokens after the rebuild, want %d of %d", rows, tokens, wantRows, wantTokens)
+	}
+	for _, index := range usageMetricsIndexes {
+		var table string
+		if err := conn.QueryRow("SELECT tablename FROM pg_indexes WHERE schemaname = current_schema() AND indexname = $1", index).Scan(&table); err != nil || table != "usage_metrics" {
+			t.Errorf("index %s is on %q (%v), want usage_metrics", index, table, err)
+		}
+	}
+
+	// New rows take keys past every copied one
+	var id int64
+	if err := conn.QueryRow("INSERT INTO usage_metrics (user_id, request_type) VALUES ('after', 'chat') RETURNING id").Scan(&id); err != nil {
+		t.Fatal(err)
+	}
+	if id <= maxID+1 {
+		t.Errorf("new row got id %d, want one past %d", id, maxID+1)
+	}
+	var state string
+	if err := conn.QueryRow("SELECT state FROM online_migrations WHERE version = 52").Scan(&state); err != nil || state != onlineCompleted {
+		t.Errorf("online migration state = %q (%v), want %s", state, err, onlineCompleted)
+	}
+}
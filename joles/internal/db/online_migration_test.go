@@ -0,0 +1,178 @@
+package db
+
+import (
+	"database/sql"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"lio-ai/internal/config"
+)
+
+var usageMetricsIndexes = []string{
+	"idx_usage_metrics_user_created",
+	"idx_usage_metrics_user_provider",
+	"idx_usage_metrics_created_at",
+	"idx_usage_metrics_request_type",
+}
+
+// newUsageMetricsRebuild returns a migrated database holding rows usage
+// rows, with the usage_metrics_indexes migration undone so it can be run
+// again over them, and that migration in batches of 100
+func newUsageMetricsRebuild(t *testing.T, rows int) (*sql.DB, *OnlineMigration) {
+	t.Helper()
+	database, err := NewDatabase(&config.Config{Database: config.DatabaseConfig{DSN: filepath.Join(t.TempDir(), "test.db")}})
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	t.Cleanup(func() { database.Close() })
+	conn := database.GetConnection()
+
+	var online *OnlineMigration
+	for _, m := range Migrations() {
+		if m.Name == "usage_metrics_indexes" {
+			rebuild := *m.online
+			rebuild.BatchSize, rebuild.Pause = 100, 0
+			online = &rebuild
+		}
+	}
+	if online == nil {
+		t.Fatal("usage_metrics_indexes migration not found")
+	}
+
+	for _, index := range usageMetricsIndexes {
+		mustExec(t, conn, "DROP INDEX "+index)
+	}
+	mustExec(t, conn, "DELETE FROM online_migrations WHERE version = 52")
+	for i := 0; i < rows; i++ {
+		mustExec(t, conn, `INSERT INTO usage_metrics (user_id, request_type, tokens_total, provider, created_at)
+			VALUES (?, 'chat', ?, 'openai', datetime('now', ?))`, i%7, i, -i)
+	}
+	// Gaps in the keys must not end a batch early
+	mustExec(t, conn, "DELETE FROM usage_metrics WHERE id % 10 = 3")
+	return conn, online
+}
+
+func mustExec(t *testing.T, conn *sql.DB, query string, args ...interface{}) {
+	t.Helper()
+	if _, err := conn.Exec(query, args...); err != nil {
+		t.Fatalf("%s: %v", query, err)
+	}
+}
+
+// usageMetricsTotals counts usage_metrics rows and sums their tokens
+func usageMetricsTotals(t *testing.T, conn *sql.DB) (rows, tokens int64) {
+	t.Helper()
+	if err := conn.QueryRow("SELECT COUNT(*), COALESCE(SUM(tokens_total), 0) FROM usage_metrics").Scan(&rows, &tokens); err != nil {
+		t.Fatal(err)
+	}
+	return rows, tokens
+}
+
+func assertUsageMetricsRebuilt(t *testing.T, conn *sql.DB, wantRows, wantTokens int64) {
+	t.Helper()
+	if rows, tokens := usageMetricsTotals(t, conn); rows != wantRows || tokens != wantTokens {
+		t.Errorf("usage_metrics has %d rows of %d tokens after the rebuild, want %d of %d", rows, tokens, wantRows, wantTokens)
+	}
+
+	for _, index := range usageMetricsIndexes {
+		var table string
+		err := conn.QueryRow("SELECT tbl_name FROM sqlite_master WHERE type = 'index' AND name = ?", index).Scan(&table)
+		if err != nil || table != "usage_metrics" {
+			t.Errorf("index %s is on %q (%v), want usage_metrics", index, table, err)
+		}
+	}
+
+	var leftover int
+	if err := conn.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE name LIKE 'usage_metrics_online%'").Scan(&leftover); err != nil {
+		t.Fatal(err)
+	}
+	if leftover != 0 {
+		t.Errorf("%d rebuild tables or triggers left after the cutover", leftover)
+	}
+
+	progress, err := OnlineMigrations(conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var found bool
+	for _, p := range progress {
+		if p.Version != 52 {
+			continue
+		}
+		found = true
+		if p.State != onlineCompleted || p.CompletedAt == nil || p.Error != "" {
+			t.Errorf("progress = %+v, want completed without error", p)
+		}
+	}
+	if !found {
+		t.Error("no progress recorded for version 52")
+	}
+}
+
+func TestUsageMetricsRebuildKeepsRowsAndIndexes(t *testing.T) {
+	conn, online := newUsageMetricsRebuild(t, 1050)
+	rows, tokens := usageMetricsTotals(t, conn)
+	var maxID int64
+	if err := conn.QueryRow("SELECT MAX(id) FROM usage_metrics").Scan(&maxID); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := online.run(conn, 52, "usage_metrics_indexes"); err != nil {
+		t.Fatalf("rebuild failed: %v", err)
+	}
+	assertUsageMetricsRebuilt(t, conn, rows, tokens)
+
+	progress, err := OnlineMigrations(conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range progress {
+		if p.Version == 52 && (p.RowsTotal != rows || p.RowsCopied != rows) {
+			t.Errorf("progress counts %d/%d rows, want %d/%d", p.RowsCopied, p.RowsTotal, rows, rows)
+		}
+	}
+
+	// Keys of rows deleted before the swap are not handed out again
+	result, err := conn.Exec("INSERT INTO usage_metrics (user_id, request_type) VALUES ('1', 'chat')")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id, _ := result.LastInsertId(); id <= maxID {
+		t.Errorf("new row got id %d, want one after %d", id, maxID)
+	}
+
+	var id, parent, notUsed int
+	var plan string
+	if err := conn.QueryRow(`EXPLAIN QUERY PLAN
+		SELECT COUNT(*) FROM usage_metrics WHERE user_id = '1' AND created_at > '2020-01-01'`).Scan(&id, &parent, &notUsed, &plan); err != nil {
+		t.Fatal(err)
+	}
+	if want := "idx_usage_metrics_user_created"; !strings.Contains(plan, want) {
+		t.Errorf("query plan %q does not use %s", plan, want)
+	}
+}
+
+func TestUsageMetricsRebuildResumesWithWrites(t *testing.T) {
+	conn, online := newUsageMetricsRebuild(t, 450)
+
+	// Stop after the first batch, as if the server restarted mid-copy
+	maxID, _, err := online.prepare(conn, 52, "usage_metrics_indexes")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := online.copyBatch(conn, 52, 0, maxID, 100); err != nil {
+		t.Fatal(err)
+	}
+
+	// Writes during the backfill reach the rebuilt table through the triggers
+	mustExec(t, conn, "UPDATE usage_metrics SET tokens_total = tokens_total + 1000 WHERE id IN (1, 400)")
+	mustExec(t, conn, "DELETE FROM usage_metrics WHERE id IN (2, 401)")
+	mustExec(t, conn, "INSERT INTO usage_metrics (user_id, request_type, tokens_total) VALUES ('1', 'chat', 5)")
+	rows, tokens := usageMetricsTotals(t, conn)
+
+	if err := online.run(conn, 52, "usage_metrics_indexes"); err != nil {
+		t.Fatalf("resumed rebuild failed: %v", err)
+	}
+	assertUsageMetricsRebuilt(t, conn, rows, tokens)
+}
@@ -0,0 +1,108 @@
+package export
+
+import (
+	"html"
+	"strings"
+	"unicode"
+)
+
+// keywords common to the languages models usually answer in; good enough
+// for a readable transcript without shipping a full highlighter
+var keywords = map[string]bool{
+	"and": true, "as": true, "async": true, "await": true, "break": true,
+	"case": true, "catch": true, "chan": true, "class": true, "const": true,
+	"continue": true, "def": true, "default": true, "defer": true, "del": true,
+	"do": true, "elif": true, "else": true, "enum": true, "except": true,
+	"export": true, "extends": true, "false": true, "False": true, "finally": true,
+	"fn": true, "for": true, "from": true, "func": true, "function": true,
+	"go": true, "if": true, "impl": true, "import": true, "in": true,
+	"interface": true, "is": true, "lambda": true, "let": true, "map": true,
+	"match": true, "mut": true, "new": true, "nil": true, "None": true,
+	"not": true, "null": true, "or": true, "package": true, "pass": true,
+	"pub": true, "raise": true, "range": true, "return": true, "select": true,
+	"self": true, "static": true, "struct": true, "switch": true, "this": true,
+	"throw": true, "true": true, "True": true, "try": true, "type": true,
+	"typeof": true, "undefined": true, "use": true, "var": true, "void": true,
+	"while": true, "with": true, "yield": true,
+}
+
+// hashComments lists languages where # starts a line comment
+var hashComments = map[string]bool{
+	"python": true, "py": true, "ruby": true, "rb": true, "sh": true,
+	"bash": true, "shell": true, "zsh": true, "yaml": true, "yml": true,
+	"toml": true, "perl": true, "r": true, "dockerfile": true, "makefile": true,
+}
+
+// highlightCode returns HTML-escaped code with keywords, strings, comments
+// and numbers wrapped in spans
+func highlightCode(code, lang string) string {
+	var b strings.Builder
+	src := []rune(code)
+	hash := hashComments[strings.ToLower(lang)]
+
+	span := func(class string, text []rune) {
+		b.WriteString(`<span class="tok-` + class + `">`)
+		b.WriteString(html.EscapeString(string(text)))
+		b.WriteString(`</span>`)
+	}
+
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == '/' && i+1 < len(src) && src[i+1] == '/', c == '#' && hash:
+			j := i
+			for j < len(src) && src[j] != '\n' {
+				j++
+			}
+			span("com", src[i:j])
+			i = j
+		case c == '/' && i+1 < len(src) && src[i+1] == '*':
+			j := i + 2
+			for j < len(src) && !(src[j] == '*' && j+1 < len(src) && src[j+1] == '/') {
+				j++
+			}
+			j = min(j+2, len(src))
+			span("com", src[i:j])
+			i = j
+		case c == '"' || c == '\'' || c == '`':
+			j := i + 1
+			for j < len(src) && src[j] != c {
+				if src[j] == '\\' && c != '`' {
+					j++
+				} else if src[j] == '\n' && c != '`' {
+					break
+				}
+				j++
+			}
+			j = min(j+1, len(src))
+			span("str", src[i:j])
+			i = j
+		case unicode.IsDigit(c) && (i == 0 || !isIdent(src[i-1])):
+			j := i
+			for j < len(src) && (isIdent(src[j]) || src[j] == '.') {
+				j++
+			}
+			span("num", src[i:j])
+			i = j
+		case isIdent(c):
+			j := i
+			for j < len(src) && isIdent(src[j]) {
+				j++
+			}
+			if keywords[string(src[i:j])] {
+				span("kw", src[i:j])
+			} else {
+				b.WriteString(html.EscapeString(string(src[i:j])))
+			}
+			i = j
+		default:
+			b.WriteString(html.EscapeString(string(c)))
+			i++
+		}
+	}
+	return b.String()
+}
+
+func isIdent(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
@@ -0,0 +1,71 @@
+// Package export renders chats and billing statements into standalone files for sharing outside the app.
+package export
+
+import (
+	_ "embed"
+	"html"
+	"html/template"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+
+	"lio-ai/internal/models"
+)
+
+// chatTemplate is the self-contained HTML transcript; styles are inline so
+// the file renders the same when attached to a ticket or opened offline
+//
+//go:embed chat.html.tmpl
+var chatTemplate string
+
+var tmpl = template.Must(template.New("chat").Funcs(template.FuncMap{
+	"render": renderContent,
+	"time":   func(t time.Time) string { return t.UTC().Format("2006-01-02 15:04 UTC") },
+}).Parse(chatTemplate))
+
+// HTML writes a chat transcript as a single HTML document
+func HTML(w io.Writer, chat *models.ChatWithMessages) error {
+	return tmpl.Execute(w, struct {
+		*models.ChatWithMessages
+		ExportedAt time.Time
+	}{chat, time.Now()})
+}
+
+// fencePattern matches ```lang ... ``` code blocks
+var fencePattern = regexp.MustCompile("(?s)```([\\w+#.-]*)[^\\n]*\\n(.*?)(?:```|$)")
+
+// inlineCodePattern matches `code` spans in already-escaped text
+var inlineCodePattern = regexp.MustCompile("`([^`\\n]+)`")
+
+// renderContent turns message text into HTML: fenced code blocks are
+// highlighted, everything else is escaped and split into paragraphs
+func renderContent(content string) template.HTML {
+	var b strings.Builder
+	last := 0
+	for _, m := range fencePattern.FindAllStringSubmatchIndex(content, -1) {
+		renderText(&b, content[last:m[0]])
+		lang := content[m[2]:m[3]]
+		code := strings.TrimSuffix(content[m[4]:m[5]], "\n")
+
+		b.WriteString(`<pre class="code">`)
+		if lang != "" {
+			b.WriteString(`<span class="lang">` + html.EscapeString(lang) + `</span>`)
+		}
+		b.WriteString(`<code>` + highlightCode(code, lang) + `</code></pre>`)
+		last = m[1]
+	}
+	renderText(&b, content[last:])
+	return template.HTML(b.String())
+}
+
+func renderText(b *strings.Builder, text string) {
+	for _, para := range strings.Split(strings.TrimSpace(text), "\n\n") {
+		if strings.TrimSpace(para) == "" {
+			continue
+		}
+		escaped := html.EscapeString(para)
+		escaped = inlineCodePattern.ReplaceAllString(escaped, "<code>$1</code>")
+		b.WriteString("<p>" + strings.ReplaceAll(escaped, "\n", "<br>") + "</p>")
+	}
+}
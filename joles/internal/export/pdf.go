@@ -0,0 +1,105 @@
+package export
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// A4 pages in points, the margin text starts at and the gap between lines
+const (
+	pdfPageWidth  = 595
+	pdfPageHeight = 842
+	pdfMargin     = 50
+	pdfLeading    = 1.4
+)
+
+// Base fonts every PDF reader has, so nothing needs embedding
+const (
+	pdfFontRegular = "F1" // Helvetica
+	pdfFontBold    = "F2" // Helvetica-Bold
+	pdfFontMono    = "F3" // Courier, for columns that line up
+)
+
+// pdfLine is a line of text; an empty Text leaves a blank line
+type pdfLine struct {
+	Font string
+	Size float64
+	Text string
+}
+
+// writePDF writes lines top to bottom onto as many A4 pages as they need
+func writePDF(w io.Writer, lines []pdfLine) error {
+	var pages []string
+	var page strings.Builder
+	y := float64(pdfPageHeight - pdfMargin)
+	for _, l := range lines {
+		height := l.Size * pdfLeading
+		if y-height < pdfMargin && page.Len() > 0 {
+			pages = append(pages, page.String())
+			page.Reset()
+			y = pdfPageHeight - pdfMargin
+		}
+		y -= height
+		if l.Text != "" {
+			fmt.Fprintf(&page, "BT /%s %.1f Tf %d %.1f Td (%s) Tj ET\n", l.Font, l.Size, pdfMargin, y, pdfString(l.Text))
+		}
+	}
+	pages = append(pages, page.String())
+
+	// Objects: 1 catalog, 2 page tree, 3-5 fonts, then a page and its
+	// contents for each page
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"",
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>",
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>",
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Courier /Encoding /WinAnsiEncoding >>",
+	}
+	kids := make([]string, len(pages))
+	for i, content := range pages {
+		pageObj := len(objects) + 1
+		kids[i] = fmt.Sprintf("%d 0 R", pageObj)
+		objects = append(objects,
+			fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %d %d] /Resources << /Font << /F1 3 0 R /F2 4 0 R /F3 5 0 R >> >> /Contents %d 0 R >>",
+				pdfPageWidth, pdfPageHeight, pageObj+1),
+			fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", len(content), content))
+	}
+	objects[1] = fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(pages))
+
+	bw := bufio.NewWriter(w)
+	offset, _ := bw.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, obj := range objects {
+		offsets[i] = offset
+		n, _ := fmt.Fprintf(bw, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+		offset += n
+	}
+	fmt.Fprintf(bw, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(bw, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(bw, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, offset)
+	return bw.Flush()
+}
+
+// pdfString escapes text for a PDF string literal; characters outside
+// Latin-1 are replaced, as the base fonts cannot show them
+func pdfString(text string) string {
+	var b strings.Builder
+	for _, r := range text {
+		switch {
+		case r == '\\' || r == '(' || r == ')':
+			b.WriteByte('\\')
+			b.WriteByte(byte(r))
+		case r < 0x20 || r > 0xff:
+			b.WriteByte('?')
+		case r > 0x7e:
+			fmt.Fprintf(&b, "\\%03o", r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,92 @@
+package export
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+
+	"lio-ai/internal/models"
+)
+
+// statementColumns head the CSV statement: a usage row per model, an
+// adjustment row per adjustment, then the total
+var statementColumns = []string{"type", "description", "requests", "tokens_input", "tokens_output", "tokens_total", "amount_usd"}
+
+// StatementCSV writes a billing statement as CSV
+func StatementCSV(w io.Writer, s *models.BillingStatement) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(statementColumns); err != nil {
+		return err
+	}
+	for _, l := range s.Lines {
+		if err := cw.Write([]string{"usage", statementModel(l.Model), strconv.FormatInt(l.Requests, 10),
+			strconv.FormatInt(l.TokensInput, 10), strconv.FormatInt(l.TokensOutput, 10),
+			strconv.FormatInt(l.TokensTotal, 10), statementAmount(l.CostUSD)}); err != nil {
+			return err
+		}
+	}
+	for _, a := range s.Adjustments {
+		if err := cw.Write([]string{"adjustment", a.Reason, "", "", "", "", statementAmount(a.AmountUSD)}); err != nil {
+			return err
+		}
+	}
+	if err := cw.Write([]string{"total", "Statement " + s.Period, strconv.FormatInt(s.Requests, 10),
+		strconv.FormatInt(s.TokensInput, 10), strconv.FormatInt(s.TokensOutput, 10),
+		strconv.FormatInt(s.TokensTotal, 10), statementAmount(s.TotalUSD)}); err != nil {
+		return err
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// StatementPDF writes a billing statement as a printable PDF
+func StatementPDF(w io.Writer, s *models.BillingStatement) error {
+	text := func(font string, size float64, format string, args ...interface{}) pdfLine {
+		return pdfLine{Font: font, Size: size, Text: fmt.Sprintf(format, args...)}
+	}
+	blank := pdfLine{Font: pdfFontRegular, Size: 10}
+	row := "%-30.30s %9s %13s %13s %13s"
+
+	lines := []pdfLine{
+		text(pdfFontBold, 18, "Statement %s", s.Period),
+		blank,
+		text(pdfFontRegular, 10, "Statement no. %d for user %s", s.ID, s.UserID),
+		text(pdfFontRegular, 10, "Usage from %s to %s (UTC)", s.PeriodStart.UTC().Format("2 January 2006"),
+			s.PeriodEnd.UTC().AddDate(0, 0, -1).Format("2 January 2006")),
+		text(pdfFontRegular, 10, "Closed %s", s.ClosedAt.UTC().Format("2 January 2006 15:04 UTC")),
+		blank,
+		text(pdfFontBold, 12, "Usage by model"),
+		text(pdfFontMono, 8, row, "Model", "Requests", "Input tokens", "Output tokens", "Cost (USD)"),
+	}
+	for _, l := range s.Lines {
+		lines = append(lines, text(pdfFontMono, 8, row, statementModel(l.Model), strconv.FormatInt(l.Requests, 10),
+			strconv.FormatInt(l.TokensInput, 10), strconv.FormatInt(l.TokensOutput, 10), statementAmount(l.CostUSD)))
+	}
+	lines = append(lines, text(pdfFontMono, 8, row, "Total usage", strconv.FormatInt(s.Requests, 10),
+		strconv.FormatInt(s.TokensInput, 10), strconv.FormatInt(s.TokensOutput, 10), statementAmount(s.UsageCostUSD)))
+
+	if len(s.Adjustments) > 0 {
+		adjustment := "%-52.52s %13s %13s"
+		lines = append(lines, blank, text(pdfFontBold, 12, "Adjustments"),
+			text(pdfFontMono, 8, adjustment, "Reason", "Added", "Amount (USD)"))
+		for _, a := range s.Adjustments {
+			lines = append(lines, text(pdfFontMono, 8, adjustment, a.Reason, a.CreatedAt.UTC().Format("2006-01-02"), statementAmount(a.AmountUSD)))
+		}
+		lines = append(lines, text(pdfFontMono, 8, adjustment, "Total adjustments", "", statementAmount(s.AdjustmentsUSD)))
+	}
+
+	lines = append(lines, blank, text(pdfFontBold, 12, "Total due: $%s", statementAmount(s.TotalUSD)))
+	return writePDF(w, lines)
+}
+
+func statementModel(model string) string {
+	if model == "" {
+		return "(no model)"
+	}
+	return model
+}
+
+func statementAmount(usd float64) string {
+	return strconv.FormatFloat(usd, 'f', 6, 64)
+}
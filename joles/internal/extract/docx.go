@@ -0,0 +1,89 @@
+package extract
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// maxDocxXMLBytes bounds the decompressed size of the document part, so a
+// zip bomb cannot exhaust memory
+const maxDocxXMLBytes = 64 << 20
+
+// docxText reads the paragraphs of word/document.xml
+func docxText(data []byte) (string, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrMalformed, err)
+	}
+
+	var part *zip.File
+	for _, f := range zr.File {
+		if f.Name == "word/document.xml" {
+			part = f
+			break
+		}
+	}
+	if part == nil {
+		return "", fmt.Errorf("%w: word/document.xml is missing", ErrMalformed)
+	}
+
+	rc, err := part.Open()
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrMalformed, err)
+	}
+	defer rc.Close()
+
+	var b strings.Builder
+	dec := xml.NewDecoder(io.LimitReader(rc, maxDocxXMLBytes))
+	inText := false
+	cells := 0 // depth of table cells, whose paragraphs stay on the row's line
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", fmt.Errorf("%w: %v", ErrMalformed, err)
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "t":
+				inText = true
+			case "tc":
+				cells++
+			case "tab":
+				b.WriteByte('\t')
+			case "br", "cr":
+				b.WriteByte('\n')
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "t":
+				inText = false
+			case "p":
+				if cells > 0 {
+					b.WriteByte(' ')
+				} else {
+					b.WriteString("\n\n")
+				}
+			case "tc":
+				// Table cells of a row read left to right
+				cells--
+				b.WriteByte('\t')
+			case "tr", "tbl":
+				b.WriteByte('\n')
+			}
+		case xml.CharData:
+			if inText {
+				b.Write(t)
+			}
+		}
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,132 @@
+// Package extract pulls plain text out of uploaded documents so they can be
+// stored, searched and used as chat context like typed-in documents.
+package extract
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+)
+
+// Format is a document format text can be extracted from
+type Format string
+
+// Supported formats
+const (
+	FormatPDF      Format = "pdf"
+	FormatDOCX     Format = "docx"
+	FormatText     Format = "txt"
+	FormatMarkdown Format = "md"
+)
+
+var (
+	// ErrUnsupportedFormat is returned for files that are none of the
+	// supported formats
+	ErrUnsupportedFormat = errors.New("unsupported file type: upload PDF, DOCX, TXT or Markdown")
+	// ErrNoText is returned when a file is readable but has no text in it,
+	// e.g. a scanned PDF
+	ErrNoText = errors.New("no text could be extracted from the file")
+	// ErrMalformed is returned when a file is not valid for its format
+	ErrMalformed = errors.New("file is damaged or not in the format its name says")
+)
+
+var contentTypes = map[Format]string{
+	FormatPDF:      "application/pdf",
+	FormatDOCX:     "application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+	FormatText:     "text/plain",
+	FormatMarkdown: "text/markdown",
+}
+
+// ContentType is the MIME type files of the format are stored with
+func (f Format) ContentType() string {
+	return contentTypes[f]
+}
+
+// Detect picks the format of an upload from its file name, falling back to
+// the content type the client sent
+func Detect(filename, contentType string) (Format, error) {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".pdf":
+		return FormatPDF, nil
+	case ".docx":
+		return FormatDOCX, nil
+	case ".txt", ".text":
+		return FormatText, nil
+	case ".md", ".markdown":
+		return FormatMarkdown, nil
+	}
+
+	mediaType := strings.ToLower(strings.TrimSpace(strings.Split(contentType, ";")[0]))
+	for format, ct := range contentTypes {
+		if mediaType == ct {
+			return format, nil
+		}
+	}
+	if mediaType == "text/x-markdown" {
+		return FormatMarkdown, nil
+	}
+	return "", ErrUnsupportedFormat
+}
+
+// Text extracts the text of a file in the given format. Paragraphs are
+// separated by blank lines; layout, images and styling are dropped.
+func Text(format Format, data []byte) (string, error) {
+	var text string
+	var err error
+	switch format {
+	case FormatPDF:
+		text, err = pdfText(data)
+	case FormatDOCX:
+		text, err = docxText(data)
+	case FormatText, FormatMarkdown:
+		text, err = plainText(data)
+	default:
+		return "", ErrUnsupportedFormat
+	}
+	if err != nil {
+		return "", err
+	}
+
+	text = tidy(text)
+	if text == "" {
+		return "", ErrNoText
+	}
+	return text, nil
+}
+
+// plainText decodes a text file, which must be UTF-8
+func plainText(data []byte) (string, error) {
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	if !utf8.Valid(data) {
+		return "", fmt.Errorf("%w: text files must be UTF-8", ErrMalformed)
+	}
+	return string(data), nil
+}
+
+// tidy normalizes line endings, trims trailing spaces and collapses runs
+// of blank lines
+func tidy(text string) string {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	text = strings.ReplaceAll(text, "\r", "\n")
+	text = strings.ReplaceAll(text, "\x00", "")
+
+	lines := strings.Split(text, "\n")
+	out := make([]string, 0, len(lines))
+	blank := true
+	for _, line := range lines {
+		line = strings.TrimRight(line, " \t")
+		if line == "" {
+			if !blank {
+				out = append(out, "")
+			}
+			blank = true
+			continue
+		}
+		out = append(out, line)
+		blank = false
+	}
+	return strings.TrimSpace(strings.Join(out, "\n"))
+}
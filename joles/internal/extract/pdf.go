@@ -0,0 +1,594 @@
+package extract
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// maxPDFStreamBytes bounds the decompressed size of each stream, so a
+// compression bomb cannot exhaust memory
+const maxPDFStreamBytes = 32 << 20
+
+var (
+	pdfObjectHeader = regexp.MustCompile(`(\d+)\s+\d+\s+obj\b`)
+	pdfRef          = regexp.MustCompile(`(\d+)\s+\d+\s+R`)
+	pdfPageType     = regexp.MustCompile(`/Type\s*/Page\b`)
+	pdfObjStmType   = regexp.MustCompile(`/Type\s*/ObjStm\b`)
+	pdfContents     = regexp.MustCompile(`/Contents\s*(\[[^\]]*\]|\d+\s+\d+\s+R)`)
+	pdfToUnicode    = regexp.MustCompile(`/ToUnicode\s*(\d+)\s+\d+\s+R`)
+	pdfFontDict     = regexp.MustCompile(`/Font\s*<<([^>]*)>>`)
+	pdfFontRef      = regexp.MustCompile(`/Font\s+(\d+)\s+\d+\s+R`)
+	pdfFontEntry    = regexp.MustCompile(`/([^\s/<>\[\]()]+)\s+(\d+)\s+\d+\s+R`)
+	pdfFlate        = regexp.MustCompile(`/Filter\s*\[?\s*/FlateDecode\s*\]?\s*(/[A-Z]|>>|$)`)
+	pdfLength       = regexp.MustCompile(`/Length\s+(\d+)(\s+\d+\s+R)?`)
+	pdfObjStmN      = regexp.MustCompile(`/N\s+(\d+)`)
+	pdfObjStmFirst  = regexp.MustCompile(`/First\s+(\d+)`)
+)
+
+// pdfObject is an indirect object: its dictionary (or whole body when it
+// has no stream) and its raw stream data
+type pdfObject struct {
+	dict   string
+	stream []byte
+}
+
+// pdfText extracts the text shown on the pages of a PDF. Only the text
+// operators of the content streams are read, decoded through the fonts'
+// ToUnicode maps where they have one; encrypted PDFs are not supported.
+func pdfText(data []byte) (string, error) {
+	if !bytes.HasPrefix(bytes.TrimLeft(data, " \t\r\n"), []byte("%PDF-")) {
+		return "", fmt.Errorf("%w: missing PDF header", ErrMalformed)
+	}
+	if bytes.Contains(data, []byte("/Encrypt")) {
+		return "", fmt.Errorf("%w: encrypted PDFs are not supported", ErrUnsupportedFormat)
+	}
+
+	objects := parsePDFObjects(data)
+	if len(objects) == 0 {
+		return "", fmt.Errorf("%w: no PDF objects found", ErrMalformed)
+	}
+
+	// Font resource names (/F1) mapped to their ToUnicode maps. Names are
+	// per page in PDF, but generators reuse them for the same fonts.
+	fonts := make(map[string]*cmap)
+	for _, obj := range objects {
+		var entries string
+		if m := pdfFontDict.FindStringSubmatch(obj.dict); m != nil {
+			entries = m[1]
+		} else if m := pdfFontRef.FindStringSubmatch(obj.dict); m != nil {
+			if ref := objects[atoi(m[1])]; ref != nil {
+				entries = ref.dict
+			}
+		}
+		for _, e := range pdfFontEntry.FindAllStringSubmatch(entries, -1) {
+			font := objects[atoi(e[2])]
+			if font == nil {
+				continue
+			}
+			if m := pdfToUnicode.FindStringSubmatch(font.dict); m != nil {
+				if cm := objects[atoi(m[1])]; cm != nil {
+					fonts[e[1]] = parseCMap(decodePDFStream(cm))
+				}
+			}
+		}
+	}
+
+	// Pages in file order, which is page order for nearly all generators
+	var contents [][]byte
+	nums := make([]int, 0, len(objects))
+	for num := range objects {
+		nums = append(nums, num)
+	}
+	sort.Ints(nums)
+	for _, num := range nums {
+		obj := objects[num]
+		if !pdfPageType.MatchString(obj.dict) {
+			continue
+		}
+		m := pdfContents.FindStringSubmatch(obj.dict)
+		if m == nil {
+			continue
+		}
+		for _, ref := range pdfRef.FindAllStringSubmatch(m[1], -1) {
+			if content := objects[atoi(ref[1])]; content != nil {
+				contents = append(contents, decodePDFStream(content))
+			}
+		}
+	}
+
+	var b strings.Builder
+	for _, content := range contents {
+		showPDFText(&b, content, fonts)
+		b.WriteString("\n\n")
+	}
+	return b.String(), nil
+}
+
+// parsePDFObjects indexes the indirect objects of a PDF by number, including
+// those packed in object streams. Later definitions win, as with
+// incremental updates.
+func parsePDFObjects(data []byte) map[int]*pdfObject {
+	objects := make(map[int]*pdfObject)
+	pos := 0
+	for {
+		loc := pdfObjectHeader.FindSubmatchIndex(data[pos:])
+		if loc == nil {
+			break
+		}
+		num := atoi(string(data[pos+loc[2] : pos+loc[3]]))
+		start := pos + loc[1]
+
+		end := bytes.Index(data[start:], []byte("endobj"))
+		streamAt := bytes.Index(data[start:], []byte("stream"))
+		if end < 0 {
+			end = len(data) - start
+		}
+
+		obj := &pdfObject{}
+		if streamAt >= 0 && streamAt < end {
+			obj.dict = string(data[start : start+streamAt])
+			body := start + streamAt + len("stream")
+			if bytes.HasPrefix(data[body:], []byte("\r\n")) {
+				body += 2
+			} else if body < len(data) && (data[body] == '\n' || data[body] == '\r') {
+				body++
+			}
+			streamEnd := -1
+			if m := pdfLength.FindStringSubmatch(obj.dict); m != nil && m[2] == "" {
+				if n := atoi(m[1]); body+n <= len(data) &&
+					bytes.HasPrefix(bytes.TrimLeft(data[body+n:], " \r\n"), []byte("endstream")) {
+					streamEnd = body + n
+				}
+			}
+			if streamEnd < 0 {
+				i := bytes.Index(data[body:], []byte("endstream"))
+				if i < 0 {
+					break
+				}
+				streamEnd = body + i
+			}
+			obj.stream = data[body:streamEnd]
+			pos = streamEnd
+		} else {
+			obj.dict = string(data[start : start+end])
+			pos = start + end
+		}
+		objects[num] = obj
+	}
+
+	for _, obj := range objects {
+		if pdfObjStmType.MatchString(obj.dict) {
+			unpackObjectStream(obj, objects)
+		}
+	}
+	return objects
+}
+
+// unpackObjectStream adds the objects packed in an object stream; packed
+// objects never have streams of their own
+func unpackObjectStream(stm *pdfObject, objects map[int]*pdfObject) {
+	nm := pdfObjStmN.FindStringSubmatch(stm.dict)
+	fm := pdfObjStmFirst.FindStringSubmatch(stm.dict)
+	if nm == nil || fm == nil {
+		return
+	}
+	data := decodePDFStream(stm)
+	first := atoi(fm[1])
+	if first > len(data) {
+		return
+	}
+
+	header := strings.Fields(string(data[:first]))
+	n := atoi(nm[1])
+	for i := 0; i < n && 2*i+1 < len(header); i++ {
+		num := atoi(header[2*i])
+		start := first + atoi(header[2*i+1])
+		end := len(data)
+		if 2*i+3 < len(header) {
+			end = first + atoi(header[2*i+3])
+		}
+		if start > end || end > len(data) {
+			continue
+		}
+		if _, exists := objects[num]; !exists {
+			objects[num] = &pdfObject{dict: string(data[start:end])}
+		}
+	}
+}
+
+// decodePDFStream returns a stream's data with its filter undone. Only
+// FlateDecode, by far the most common, is supported; other streams decode
+// to nothing.
+func decodePDFStream(obj *pdfObject) []byte {
+	if !strings.Contains(obj.dict, "/Filter") {
+		return obj.stream
+	}
+	if !pdfFlate.MatchString(obj.dict) {
+		return nil
+	}
+	zr, err := zlib.NewReader(bytes.NewReader(obj.stream))
+	if err != nil {
+		return nil
+	}
+	defer zr.Close()
+	// Keep what was decoded from streams with bad checksums
+	decoded, _ := io.ReadAll(io.LimitReader(zr, maxPDFStreamBytes))
+	return decoded
+}
+
+// pdfToken is a lexical token of a content stream or CMap
+type pdfToken struct {
+	kind byte // 's' string, 'n' number, '/' name, '[' array, ']' array end, 'o' operator
+	str  []byte
+	num  float64
+	arr  []pdfToken
+}
+
+// pdfLexer splits content streams into tokens, collecting arrays
+type pdfLexer struct {
+	data []byte
+	pos  int
+}
+
+func isPDFDelimiter(c byte) bool {
+	return strings.IndexByte("()<>[]{}/%", c) >= 0
+}
+
+func isPDFSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\r' || c == '\n' || c == '\f' || c == 0
+}
+
+// next returns the next token, or false at the end of the data
+func (l *pdfLexer) next() (pdfToken, bool) {
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		switch {
+		case isPDFSpace(c):
+			l.pos++
+		case c == '%':
+			for l.pos < len(l.data) && l.data[l.pos] != '\n' && l.data[l.pos] != '\r' {
+				l.pos++
+			}
+		case c == '(':
+			return pdfToken{kind: 's', str: l.literal()}, true
+		case c == '<' && l.pos+1 < len(l.data) && l.data[l.pos+1] == '<',
+			c == '>' && l.pos+1 < len(l.data) && l.data[l.pos+1] == '>':
+			// Dictionaries only appear as operands of operators that
+			// don't show text
+			l.pos += 2
+		case c == '<':
+			return pdfToken{kind: 's', str: l.hex()}, true
+		case c == '[':
+			l.pos++
+			var arr []pdfToken
+			for {
+				tok, ok := l.next()
+				if !ok || tok.kind == ']' {
+					break
+				}
+				arr = append(arr, tok)
+			}
+			return pdfToken{kind: '[', arr: arr}, true
+		case c == ']':
+			l.pos++
+			return pdfToken{kind: ']'}, true
+		case c == '{' || c == '}' || c == '>' || c == ')':
+			l.pos++
+		case c == '/':
+			l.pos++
+			return pdfToken{kind: '/', str: l.word()}, true
+		default:
+			word := l.word()
+			if num, err := strconv.ParseFloat(string(word), 64); err == nil {
+				return pdfToken{kind: 'n', num: num}, true
+			}
+			if string(word) == "ID" {
+				l.skipInlineImage()
+			}
+			return pdfToken{kind: 'o', str: word}, true
+		}
+	}
+	return pdfToken{}, false
+}
+
+// word reads a run of regular characters
+func (l *pdfLexer) word() []byte {
+	start := l.pos
+	for l.pos < len(l.data) && !isPDFSpace(l.data[l.pos]) && !isPDFDelimiter(l.data[l.pos]) {
+		l.pos++
+	}
+	if l.pos == start {
+		l.pos++
+	}
+	return l.data[start:l.pos]
+}
+
+// literal reads a (string), undoing its escapes
+func (l *pdfLexer) literal() []byte {
+	l.pos++
+	var out []byte
+	depth := 1
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		l.pos++
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return out
+			}
+		case '\\':
+			if l.pos >= len(l.data) {
+				return out
+			}
+			e := l.data[l.pos]
+			l.pos++
+			switch e {
+			case 'n':
+				c = '\n'
+			case 'r':
+				c = '\r'
+			case 't':
+				c = '\t'
+			case 'b':
+				c = '\b'
+			case 'f':
+				c = '\f'
+			case '\r':
+				if l.pos < len(l.data) && l.data[l.pos] == '\n' {
+					l.pos++
+				}
+				continue
+			case '\n':
+				continue
+			default:
+				if e >= '0' && e <= '7' {
+					v := int(e - '0')
+					for i := 0; i < 2 && l.pos < len(l.data) && l.data[l.pos] >= '0' && l.data[l.pos] <= '7'; i++ {
+						v = v*8 + int(l.data[l.pos]-'0')
+						l.pos++
+					}
+					c = byte(v)
+				} else {
+					c = e
+				}
+			}
+		}
+		out = append(out, c)
+	}
+	return out
+}
+
+// hex reads a <hex string>
+func (l *pdfLexer) hex() []byte {
+	l.pos++
+	var digits []byte
+	for l.pos < len(l.data) && l.data[l.pos] != '>' {
+		if c := l.data[l.pos]; strings.IndexByte("0123456789abcdefABCDEF", c) >= 0 {
+			digits = append(digits, c)
+		}
+		l.pos++
+	}
+	l.pos++
+	if len(digits)%2 == 1 {
+		digits = append(digits, '0')
+	}
+	out := make([]byte, len(digits)/2)
+	for i := range out {
+		v, _ := strconv.ParseUint(string(digits[2*i:2*i+2]), 16, 8)
+		out[i] = byte(v)
+	}
+	return out
+}
+
+// skipInlineImage skips the binary data of an inline image up to its EI
+func (l *pdfLexer) skipInlineImage() {
+	for i := l.pos; i+2 < len(l.data); i++ {
+		if isPDFSpace(l.data[i]) && l.data[i+1] == 'E' && l.data[i+2] == 'I' &&
+			(i+3 == len(l.data) || isPDFSpace(l.data[i+3])) {
+			l.pos = i + 3
+			return
+		}
+	}
+	l.pos = len(l.data)
+}
+
+// showPDFText writes the text a content stream shows, starting new lines
+// where the text moves down the page
+func showPDFText(b *strings.Builder, content []byte, fonts map[string]*cmap) {
+	lex := &pdfLexer{data: content}
+	var operands []pdfToken
+	var font *cmap
+	var lastX, lastY float64
+
+	newline := func() {
+		if s := b.String(); s != "" && !strings.HasSuffix(s, "\n") {
+			b.WriteByte('\n')
+		}
+	}
+	show := func(s []byte) {
+		b.WriteString(decodePDFString(s, font))
+	}
+
+	for {
+		tok, ok := lex.next()
+		if !ok {
+			return
+		}
+		if tok.kind != 'o' {
+			operands = append(operands, tok)
+			continue
+		}
+
+		switch string(tok.str) {
+		case "Tf":
+			if len(operands) >= 2 && operands[len(operands)-2].kind == '/' {
+				font = fonts[string(operands[len(operands)-2].str)]
+			}
+		case "Tj":
+			if len(operands) > 0 {
+				show(operands[len(operands)-1].str)
+			}
+		case "'", "\"":
+			newline()
+			if len(operands) > 0 {
+				show(operands[len(operands)-1].str)
+			}
+		case "TJ":
+			if len(operands) > 0 {
+				for _, el := range operands[len(operands)-1].arr {
+					switch {
+					case el.kind == 's':
+						show(el.str)
+					case el.kind == 'n' && el.num < -250:
+						// A wide negative kern is a word gap
+						b.WriteByte(' ')
+					}
+				}
+			}
+		case "Td", "TD":
+			if len(operands) >= 2 {
+				if operands[len(operands)-1].num != 0 {
+					newline()
+				} else if operands[len(operands)-2].num > 0 {
+					b.WriteByte(' ')
+				}
+			}
+		case "Tm":
+			if len(operands) >= 6 {
+				x, y := operands[len(operands)-2].num, operands[len(operands)-1].num
+				if y != lastY {
+					newline()
+				} else if x > lastX {
+					b.WriteByte(' ')
+				}
+				lastX, lastY = x, y
+			}
+		case "T*":
+			newline()
+		case "ET":
+			newline()
+		}
+		operands = operands[:0]
+	}
+}
+
+// decodePDFString turns the bytes of a shown string into text, through
+// the font's ToUnicode map when it has one and as PDFDocEncoding otherwise
+func decodePDFString(s []byte, font *cmap) string {
+	if font != nil && len(font.chars) > 0 {
+		var b strings.Builder
+		for i := 0; i+font.codeLen <= len(s); i += font.codeLen {
+			code := 0
+			for _, c := range s[i : i+font.codeLen] {
+				code = code<<8 | int(c)
+			}
+			b.WriteString(font.chars[code])
+		}
+		return b.String()
+	}
+
+	if len(s) >= 2 && s[0] == 0xfe && s[1] == 0xff {
+		return utf16BE(s[2:])
+	}
+	var b strings.Builder
+	for _, c := range s {
+		if c >= 0x20 || c == '\t' || c == '\n' {
+			b.WriteRune(rune(c))
+		}
+	}
+	return b.String()
+}
+
+// cmap is a font's ToUnicode map from character codes to text
+type cmap struct {
+	codeLen int
+	chars   map[int]string
+}
+
+// parseCMap reads the bfchar and bfrange mappings of a ToUnicode CMap
+func parseCMap(data []byte) *cmap {
+	cm := &cmap{codeLen: 1, chars: make(map[int]string)}
+	lex := &pdfLexer{data: data}
+	var operands []pdfToken
+	section := ""
+
+	setCodeLen := func(src []byte) {
+		if len(src) > cm.codeLen {
+			cm.codeLen = len(src)
+		}
+	}
+	for {
+		tok, ok := lex.next()
+		if !ok {
+			return cm
+		}
+		if tok.kind != 'o' {
+			operands = append(operands, tok)
+			if section == "bfchar" && len(operands) == 2 {
+				setCodeLen(operands[0].str)
+				cm.chars[codeOf(operands[0].str)] = utf16BE(operands[1].str)
+				operands = operands[:0]
+			}
+			if section == "bfrange" && len(operands) == 3 {
+				setCodeLen(operands[0].str)
+				lo, hi := codeOf(operands[0].str), codeOf(operands[1].str)
+				if hi-lo > 0xffff {
+					hi = lo + 0xffff
+				}
+				dst := operands[2]
+				for code := lo; code <= hi; code++ {
+					switch {
+					case dst.kind == '[' && code-lo < len(dst.arr):
+						cm.chars[code] = utf16BE(dst.arr[code-lo].str)
+					case dst.kind == 's' && len(dst.str) > 0:
+						// The last byte of the destination counts up
+						next := append([]byte(nil), dst.str...)
+						next[len(next)-1] += byte(code - lo)
+						cm.chars[code] = utf16BE(next)
+					}
+				}
+				operands = operands[:0]
+			}
+			continue
+		}
+
+		switch op := string(tok.str); op {
+		case "beginbfchar", "beginbfrange":
+			section = strings.TrimPrefix(op, "begin")
+		case "endbfchar", "endbfrange":
+			section = ""
+		}
+		operands = operands[:0]
+	}
+}
+
+func codeOf(src []byte) int {
+	code := 0
+	for _, c := range src {
+		code = code<<8 | int(c)
+	}
+	return code
+}
+
+func utf16BE(s []byte) string {
+	units := make([]uint16, 0, len(s)/2)
+	for i := 0; i+1 < len(s); i += 2 {
+		units = append(units, uint16(s[i])<<8|uint16(s[i+1]))
+	}
+	return string(utf16.Decode(units))
+}
+
+func atoi(s string) int {
+	n, _ := strconv.Atoi(strings.TrimSpace(s))
+	return n
+}
@@ -0,0 +1,165 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"lio-ai/internal/models"
+	"lio-ai/internal/services"
+)
+
+// defaultAdminUsageWindow is how far back admin usage reports go when from
+// is left out
+const defaultAdminUsageWindow = 30 * 24 * time.Hour
+
+// GetAdminUsageSummary sums the usage of all users
+// GET /api/v1/admin/usage/summary?from=&to=
+func (h *UsageHandler) GetAdminUsageSummary(c *gin.Context) {
+	from, to, ok := adminUsageRange(c)
+	if !ok {
+		return
+	}
+
+	summary, err := h.usageService.GetAdminUsageSummary(from, to)
+	if err != nil {
+		respondAdminUsageError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, summary)
+}
+
+// GetTopUsers ranks users by their cost, tokens or requests
+// GET /api/v1/admin/usage/top-users?by=cost|tokens|requests&limit=&from=&to=
+func (h *UsageHandler) GetTopUsers(c *gin.Context) {
+	from, to, ok := adminUsageRange(c)
+	if !ok {
+		return
+	}
+	rankBy := c.DefaultQuery("by", models.UsageRankCost)
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be a number", "code": "INVALID_REQUEST"})
+		return
+	}
+
+	users, err := h.usageService.GetTopUsers(from, to, rankBy, limit)
+	if err != nil {
+		respondAdminUsageError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"from":  from,
+		"to":    to,
+		"by":    rankBy,
+		"data":  users,
+		"total": len(users),
+	})
+}
+
+// GetUsageByModel sums the usage of all users per model
+// GET /api/v1/admin/usage/by-model?from=&to=
+func (h *UsageHandler) GetUsageByModel(c *gin.Context) {
+	from, to, ok := adminUsageRange(c)
+	if !ok {
+		return
+	}
+
+	usage, err := h.usageService.GetUsageByModel(from, to)
+	if err != nil {
+		respondAdminUsageError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"from":  from,
+		"to":    to,
+		"data":  usage,
+		"total": len(usage),
+	})
+}
+
+// ListUsageDeadLetters handles GET /api/v1/admin/usage/dead-letters: usage
+// events that could not be tracked after their retries, oldest first
+func (h *UsageHandler) ListUsageDeadLetters(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be a number", "code": "INVALID_REQUEST"})
+		return
+	}
+
+	letters, total, err := h.usageService.ListUsageDeadLetters(limit)
+	if err != nil {
+		respondAdminUsageError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":  letters,
+		"total": total,
+	})
+}
+
+// ReplayUsageDeadLetters handles POST /api/v1/admin/usage/dead-letters/replay:
+// tracks up to limit dead-lettered events again, once what failed them is
+// fixed
+func (h *UsageHandler) ReplayUsageDeadLetters(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be a number", "code": "INVALID_REQUEST"})
+		return
+	}
+
+	replayed, failed, err := h.usageService.ReplayUsageDeadLetters(limit)
+	if errors.Is(err, services.ErrInvalidMessage) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "code": "INVALID_REQUEST"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":    "failed to replay usage dead letters",
+			"code":     "INTERNAL_ERROR",
+			"replayed": replayed,
+			"failed":   failed,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"replayed": replayed,
+		"failed":   failed,
+	})
+}
+
+// adminUsageRange parses an admin report's from and to, the last 30 days
+// by default, answering 400 when either is malformed
+func adminUsageRange(c *gin.Context) (time.Time, time.Time, bool) {
+	var err error
+	to := time.Now()
+	if v := c.Query("to"); v != "" {
+		if to, err = parseFeedbackTime(v, true); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "to must be RFC3339 or YYYY-MM-DD", "code": "INVALID_REQUEST"})
+			return time.Time{}, time.Time{}, false
+		}
+	}
+	from := to.Add(-defaultAdminUsageWindow)
+	if v := c.Query("from"); v != "" {
+		if from, err = parseFeedbackTime(v, false); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "from must be RFC3339 or YYYY-MM-DD", "code": "INVALID_REQUEST"})
+			return time.Time{}, time.Time{}, false
+		}
+	}
+	return from, to, true
+}
+
+func respondAdminUsageError(c *gin.Context, err error) {
+	if errors.Is(err, services.ErrInvalidMessage) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "code": "INVALID_REQUEST"})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get usage report", "code": "FETCH_FAILED"})
+}
@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"lio-ai/internal/models"
+	"lio-ai/internal/services"
+)
+
+// APIKeyHandler handles users' personal API keys
+type APIKeyHandler struct {
+	service *services.APIKeyService
+}
+
+// NewAPIKeyHandler creates a new API key handler
+func NewAPIKeyHandler(service *services.APIKeyService) *APIKeyHandler {
+	return &APIKeyHandler{service: service}
+}
+
+// CreateKey handles POST /api/v1/api-keys-personal. The key is in the
+// response only; store it, it cannot be shown again.
+func (h *APIKeyHandler) CreateKey(c *gin.Context) {
+	userID, ok := sessionUserID(c)
+	if !ok {
+		return
+	}
+	var req models.CreateAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "invalid request: " + err.Error(),
+			"code":  "INVALID_REQUEST",
+		})
+		return
+	}
+
+	key, err := h.service.CreateKey(userID, &req)
+	if err != nil {
+		respondAPIKeyError(c, err, "failed to create API key")
+		return
+	}
+	c.JSON(http.StatusCreated, key)
+}
+
+// ListKeys handles GET /api/v1/api-keys-personal
+func (h *APIKeyHandler) ListKeys(c *gin.Context) {
+	userID, ok := sessionUserID(c)
+	if !ok {
+		return
+	}
+	keys, err := h.service.ListKeys(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "failed to fetch API keys",
+			"code":  "FETCH_FAILED",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": keys, "total": len(keys)})
+}
+
+// GetKey handles GET /api/v1/api-keys-personal/:id
+func (h *APIKeyHandler) GetKey(c *gin.Context) {
+	userID, id, ok := apiKeyParams(c)
+	if !ok {
+		return
+	}
+	key, err := h.service.GetKey(id, userID)
+	if err != nil {
+		respondAPIKeyError(c, err, "failed to fetch API key")
+		return
+	}
+	c.JSON(http.StatusOK, key)
+}
+
+// UpdateKey handles PATCH /api/v1/api-keys-personal/:id
+func (h *APIKeyHandler) UpdateKey(c *gin.Context) {
+	userID, id, ok := apiKeyParams(c)
+	if !ok {
+		return
+	}
+	var req models.UpdateAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "invalid request: " + err.Error(),
+			"code":  "INVALID_REQUEST",
+		})
+		return
+	}
+
+	key, err := h.service.UpdateKey(id, userID, &req)
+	if err != nil {
+		respondAPIKeyError(c, err, "failed to update API key")
+		return
+	}
+	c.JSON(http.StatusOK, key)
+}
+
+// RevokeKey handles DELETE /api/v1/api-keys-personal/:id
+func (h *APIKeyHandler) RevokeKey(c *gin.Context) {
+	userID, id, ok := apiKeyParams(c)
+	if !ok {
+		return
+	}
+	if err := h.service.RevokeKey(id, userID); err != nil {
+		respondAPIKeyError(c, err, "failed to revoke API key")
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// apiKeyParams parses the logged-in user's ID and the :id of one of their
+// keys, writing the error response when either is invalid
+func apiKeyParams(c *gin.Context) (int64, int64, bool) {
+	userID, ok := sessionUserID(c)
+	if !ok {
+		return 0, 0, false
+	}
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "invalid API key id",
+			"code":  "INVALID_REQUEST",
+		})
+		return 0, 0, false
+	}
+	return userID, id, true
+}
+
+func respondAPIKeyError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrInvalidMessage):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "code": "INVALID_REQUEST"})
+	case errors.Is(err, services.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "API key not found", "code": models.ErrCodeNotFound})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message, "code": "INTERNAL_ERROR"})
+	}
+}
@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"lio-ai/internal/repositories"
+	"lio-ai/internal/services"
+)
+
+func TestCreateAPIKeyValidatesScopes(t *testing.T) {
+	conn := newTestDB(t)
+	user := createTestUser(t, conn, "alice", "user")
+	handler := NewAPIKeyHandler(services.NewAPIKeyService(repositories.NewAPIKeyRepository(conn), repositories.NewUserRepository(conn)))
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/api-keys-personal", func(c *gin.Context) {
+		c.Set("user_id", fmt.Sprint(user.ID))
+	}, handler.CreateKey)
+
+	tests := []struct {
+		name     string
+		body     string
+		wantCode int
+	}{
+		{"default scopes", `{"name":"ci"}`, http.StatusCreated},
+		{"known scopes", `{"name":"ci","scopes":["read","write","admin"]}`, http.StatusCreated},
+		{"unknown scope", `{"name":"ci","scopes":["read","root"]}`, http.StatusBadRequest},
+		{"scope in wrong case", `{"name":"ci","scopes":["READ"]}`, http.StatusBadRequest},
+		{"too many scopes", `{"name":"ci","scopes":["read","write","admin","read"]}`, http.StatusBadRequest},
+		{"missing name", `{"scopes":["read"]}`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api-keys-personal", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.wantCode {
+				t.Fatalf("status = %d, want %d (body %s)", w.Code, tt.wantCode, w.Body.String())
+			}
+			var body map[string]interface{}
+			json.Unmarshal(w.Body.Bytes(), &body)
+			if tt.wantCode == http.StatusBadRequest && body["code"] != "INVALID_REQUEST" {
+				t.Errorf("code = %v, want INVALID_REQUEST", body["code"])
+			}
+			if tt.wantCode == http.StatusCreated {
+				if key, _ := body["key"].(string); !strings.HasPrefix(key, services.APIKeyPrefix) {
+					t.Errorf("key = %q, want %s prefix", key, services.APIKeyPrefix)
+				}
+			}
+		})
+	}
+}
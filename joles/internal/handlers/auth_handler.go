@@ -1,11 +1,13 @@
 package handlers
 
 import (
+	"fmt"
 	"log"
 	"net/http"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
+	"lio-ai/internal/audit"
 	"lio-ai/internal/models"
 	"lio-ai/internal/services"
 )
@@ -13,6 +15,9 @@ import (
 // AuthHandler handles authentication endpoints
 type AuthHandler struct {
 	userService *services.UserService
+	audit       *audit.Logger
+	passkeys    *services.PasskeyService
+	onboarding  *services.OnboardingService
 }
 
 // NewAuthHandler creates a new auth handler
@@ -20,6 +25,46 @@ func NewAuthHandler(userService *services.UserService) *AuthHandler {
 	return &AuthHandler{userService: userService}
 }
 
+// SetAuditLogger streams authentication events to the audit sinks
+func (h *AuthHandler) SetAuditLogger(logger *audit.Logger) {
+	h.audit = logger
+}
+
+// SetPasskeyService enables passkey registration and login
+func (h *AuthHandler) SetPasskeyService(passkeys *services.PasskeyService) {
+	h.passkeys = passkeys
+}
+
+// SetOnboarding provisions sample content for users on their first login
+func (h *AuthHandler) SetOnboarding(onboarding *services.OnboardingService) {
+	h.onboarding = onboarding
+}
+
+// provisionSamples fills a user's workspace with sample content the first
+// time they log in. Failures are logged rather than failing the login.
+func (h *AuthHandler) provisionSamples(user *models.User) {
+	if h.onboarding == nil {
+		return
+	}
+	if err := h.onboarding.ProvisionFirstLogin(user); err != nil {
+		log.Printf("⚠️  Failed to provision sample content for user %d: %v", user.ID, err)
+	}
+}
+
+// auditEvent records an authentication event for the current request
+func (h *AuthHandler) auditEvent(c *gin.Context, eventType, outcome, actorID, email string, details map[string]interface{}) {
+	h.audit.Log(audit.Event{
+		Type:       eventType,
+		Outcome:    outcome,
+		ActorID:    actorID,
+		ActorEmail: email,
+		IP:         c.ClientIP(),
+		Method:     c.Request.Method,
+		Path:       c.Request.URL.Path,
+		Details:    details,
+	})
+}
+
 // Register handles user registration
 func (h *AuthHandler) Register(c *gin.Context) {
 	var req models.RegisterRequest
@@ -35,6 +80,7 @@ func (h *AuthHandler) Register(c *gin.Context) {
 	if err != nil {
 		// Log the detailed error securely
 		log.Printf("[AUTH] Registration failed for %s: %v", req.Email, err)
+		h.auditEvent(c, "auth.register", audit.OutcomeFailure, "", req.Email, map[string]interface{}{"reason": err.Error()})
 
 		// Return specific error message to client
 		errorMessage := err.Error()
@@ -57,7 +103,7 @@ func (h *AuthHandler) Register(c *gin.Context) {
 	}
 
 	// Generate JWT token for immediate login after registration
-	token, err := h.userService.GenerateTokenForUser(user)
+	token, err := h.userService.GenerateTokenForUser(user, sessionClient(c))
 	if err != nil {
 		log.Printf("[AUTH] Token generation failed for newly registered user %s: %v", user.Email, err)
 		c.JSON(http.StatusInternalServerError, gin.H{
@@ -69,18 +115,11 @@ func (h *AuthHandler) Register(c *gin.Context) {
 
 	// Log successful registration
 	log.Printf("[AUDIT] User registered: %s (ID: %d)", user.Email, user.ID)
+	h.auditEvent(c, "auth.register", audit.OutcomeSuccess, fmt.Sprint(user.ID), user.Email, nil)
+	h.provisionSamples(user)
 
 	// Set cookie for immediate persistence
-	c.SetSameSite(http.SameSiteLaxMode)
-	c.SetCookie(
-		"auth_token",
-		token,
-		86400, // 24 hours
-		"/",
-		"",
-		true,  // httpOnly
-		false, // secure (false for development)
-	)
+	setAuthCookie(c, token)
 
 	c.JSON(http.StatusCreated, gin.H{
 		"message": "User registered successfully",
@@ -105,10 +144,11 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
-	token, user, err := h.userService.Login(req.Email, req.Password)
+	token, user, err := h.userService.Login(req.Email, req.Password, sessionClient(c))
 	if err != nil {
 		// Log failed login attempt
 		log.Printf("[AUDIT] Login failed for %s: %v (IP: %s)", req.Email, err, c.ClientIP())
+		h.auditEvent(c, "auth.login", audit.OutcomeFailure, "", req.Email, map[string]interface{}{"reason": err.Error()})
 
 		c.JSON(http.StatusUnauthorized, gin.H{
 			"error": "authentication failed",
@@ -119,19 +159,11 @@ func (h *AuthHandler) Login(c *gin.Context) {
 
 	// Log successful login
 	log.Printf("[AUDIT] Login successful: %s (ID: %d, IP: %s)", user.Email, user.ID, c.ClientIP())
+	h.auditEvent(c, "auth.login", audit.OutcomeSuccess, fmt.Sprint(user.ID), user.Email, nil)
+	h.provisionSamples(user)
 
 	// Set cookie with JWT token for persistence across page refreshes
-	// httpOnly=true prevents XSS attacks, secure=false for local development
-	c.SetSameSite(http.SameSiteLaxMode)
-	c.SetCookie(
-		"auth_token",
-		token,
-		86400, // 24 hours
-		"/",
-		"",      // domain (empty = current domain)
-		true,   // httpOnly (prevents JavaScript access for security)
-		false,  // secure (false for development, true for production)
-	)
+	setAuthCookie(c, token)
 
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Login successful",
@@ -146,12 +178,38 @@ func (h *AuthHandler) Login(c *gin.Context) {
 	})
 }
 
-// Logout handles user logout
+// setAuthCookie stores the session JWT in a cookie for persistence across
+// page refreshes. httpOnly prevents JavaScript access; secure is false for
+// local development.
+func setAuthCookie(c *gin.Context, token string) {
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(
+		"auth_token",
+		token,
+		86400, // 24 hours
+		"/",
+		"",    // domain (empty = current domain)
+		true,  // httpOnly
+		false, // secure
+	)
+}
+
+// Logout handles user logout, revoking the token it was called with
 func (h *AuthHandler) Logout(c *gin.Context) {
 	// Extract user from JWT (set by middleware)
 	userID, exists := c.Get("user_id")
 	if exists {
 		log.Printf("[AUDIT] Logout: %s", userID)
+		h.auditEvent(c, "auth.logout", audit.OutcomeSuccess, fmt.Sprint(userID), c.GetString("email"), nil)
+	}
+
+	if err := h.userService.RevokeToken(c.GetString("token_id"), c.GetString("user_id"), c.GetTime("token_expires_at")); err != nil {
+		log.Printf("⚠️  Failed to revoke token on logout for user %s: %v", c.GetString("user_id"), err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "failed to revoke token",
+			"code":  "LOGOUT_FAILED",
+		})
+		return
 	}
 
 	// Clear authentication cookie
@@ -170,11 +228,12 @@ func (h *AuthHandler) Logout(c *gin.Context) {
 	})
 }
 
-// ChangePassword handles password change
+// ChangePassword handles password change. Every token issued before it is
+// revoked; the response carries a new one for the caller.
 func (h *AuthHandler) ChangePassword(c *gin.Context) {
 	// Get user from JWT token (set by middleware)
-	userIDStr, exists := c.Get("user_id")
-	if !exists {
+	userID, err := strconv.ParseInt(c.GetString("user_id"), 10, 64)
+	if err != nil {
 		c.JSON(http.StatusUnauthorized, gin.H{
 			"error": "unauthorized",
 			"code":  "UNAUTHORIZED",
@@ -183,7 +242,7 @@ func (h *AuthHandler) ChangePassword(c *gin.Context) {
 	}
 
 	// Get user details
-	user, err := h.userService.GetUserByUsername(userIDStr.(string))
+	user, err := h.userService.GetUserByID(userID)
 	if err != nil || user == nil {
 		c.JSON(http.StatusNotFound, gin.H{
 			"error": "user not found",
@@ -207,6 +266,7 @@ func (h *AuthHandler) ChangePassword(c *gin.Context) {
 
 	if err := h.userService.ChangePassword(user.ID, req.OldPassword, req.NewPassword); err != nil {
 		log.Printf("[AUDIT] Password change failed for user %s: %v", user.Email, err)
+		h.auditEvent(c, "auth.password_change", audit.OutcomeFailure, fmt.Sprint(user.ID), user.Email, nil)
 
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error": "password change failed",
@@ -217,9 +277,22 @@ func (h *AuthHandler) ChangePassword(c *gin.Context) {
 
 	// Log successful password change
 	log.Printf("[AUDIT] Password changed: %s (ID: %d)", user.Email, user.ID)
+	h.auditEvent(c, "auth.password_change", audit.OutcomeSuccess, fmt.Sprint(user.ID), user.Email, nil)
+
+	// The caller's token was revoked with the rest; keep them signed in
+	token, err := h.userService.GenerateTokenForUser(user, sessionClient(c))
+	if err != nil {
+		log.Printf("[AUTH] Token generation failed after password change for %s: %v", user.Email, err)
+		c.JSON(http.StatusOK, gin.H{
+			"message": "Password changed successfully; please log in again",
+		})
+		return
+	}
+	setAuthCookie(c, token)
 
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Password changed successfully",
+		"token":   token,
 	})
 }
 
@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"lio-ai/internal/auth"
+	"lio-ai/internal/middleware"
+	"lio-ai/internal/models"
+	"lio-ai/internal/repositories"
+	"lio-ai/internal/services"
+)
+
+const testPassword = "Old-password-1"
+
+// newTestUserService wires a user service with token revocation and
+// sessions, as main does
+func newTestUserService(t *testing.T, conn *sql.DB) (*services.UserService, *auth.JWTManager) {
+	t.Helper()
+	t.Setenv("JWT_SECRET_KEY", "test-secret-key-at-least-32-bytes!")
+	jwtManager, err := auth.NewJWTManager()
+	if err != nil {
+		t.Fatalf("NewJWTManager: %v", err)
+	}
+	revocations := repositories.NewTokenRevocationRepository(conn)
+	jwtManager.SetRevocationList(revocations)
+
+	userService := services.NewUserService(repositories.NewUserRepository(conn), jwtManager)
+	userService.SetTokenRevocations(revocations)
+	userService.SetSessions(repositories.NewSessionRepository(conn))
+	return userService, jwtManager
+}
+
+// newTestAuthRouter serves the password and session routes as main
+// does, plus GET /me to check whether a token still authenticates
+func newTestAuthRouter(t *testing.T, conn *sql.DB) (*gin.Engine, *services.UserService) {
+	t.Helper()
+	userService, jwtManager := newTestUserService(t, conn)
+	handler := NewAuthHandler(userService)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(middleware.NewAuthMiddleware(jwtManager))
+	authed := router.Group("/auth", middleware.RequireAuth(), middleware.RequireLoginToken())
+	authed.POST("/password", handler.ChangePassword)
+	authed.GET("/sessions", handler.ListSessions)
+	authed.DELETE("/sessions", handler.TerminateOtherSessions)
+	authed.DELETE("/sessions/:id", handler.TerminateSession)
+	router.GET("/me", middleware.RequireAuth(), func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"user_id": c.GetString("user_id")})
+	})
+	return router, userService
+}
+
+// loginTestUser logs username in from userAgent, creating the user with
+// testPassword on first use
+func loginTestUser(t *testing.T, conn *sql.DB, userService *services.UserService, username, userAgent string) (*models.User, string) {
+	t.Helper()
+	hash, err := auth.HashPassword(testPassword)
+	if err != nil {
+		t.Fatalf("HashPassword: %v", err)
+	}
+	users := repositories.NewUserRepository(conn)
+	user, err := users.GetByUsername(username)
+	if err != nil {
+		t.Fatalf("GetByUsername: %v", err)
+	}
+	if user == nil {
+		user = &models.User{Username: username, Email: username + "@example.com", PasswordHash: hash, Role: "user", IsActive: true}
+		if err := users.Create(user); err != nil {
+			t.Fatalf("create user: %v", err)
+		}
+	}
+	token, _, err := userService.Login(user.Email, testPassword, models.SessionClient{IPAddress: "192.0.2.1", UserAgent: userAgent})
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	return user, token
+}
+
+// serveAuth sends a request with token as bearer, returning the status and
+// decoded body
+func serveAuth(router *gin.Engine, method, path, token, body string) (int, map[string]interface{}) {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	var decoded map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &decoded)
+	return w.Code, decoded
+}
+
+func TestChangePasswordRevokesOldTokens(t *testing.T) {
+	conn := newTestDB(t)
+	router, userService := newTestAuthRouter(t, conn)
+	_, laptop := loginTestUser(t, conn, userService, "alice", "laptop")
+	_, phone := loginTestUser(t, conn, userService, "alice", "phone")
+
+	code, _ := serveAuth(router, http.MethodPost, "/auth/password", laptop, `{"old_password":"wrong","new_password":"New-password-2"}`)
+	if code != http.StatusBadRequest {
+		t.Fatalf("wrong old password: status = %d, want 400", code)
+	}
+	if code, _ := serveAuth(router, http.MethodGet, "/me", phone, ""); code != http.StatusOK {
+		t.Fatalf("a failed password change revoked tokens: status = %d", code)
+	}
+
+	code, body := serveAuth(router, http.MethodPost, "/auth/password", laptop, `{"old_password":"`+testPassword+`","new_password":"New-password-2"}`)
+	if code != http.StatusOK {
+		t.Fatalf("ChangePassword: status = %d, body %v", code, body)
+	}
+	fresh, _ := body["token"].(string)
+	if fresh == "" || fresh == laptop {
+		t.Fatalf("ChangePassword returned token %q, want a new one", fresh)
+	}
+
+	for name, token := range map[string]string{"calling token": laptop, "other device": phone} {
+		if code, _ := serveAuth(router, http.MethodGet, "/me", token, ""); code != http.StatusUnauthorized {
+			t.Errorf("%s after password change: status = %d, want 401", name, code)
+		}
+	}
+	if code, _ := serveAuth(router, http.MethodGet, "/me", fresh, ""); code != http.StatusOK {
+		t.Errorf("new token: status = %d, want 200", code)
+	}
+	if code, body := serveAuth(router, http.MethodGet, "/auth/sessions", fresh, ""); code != http.StatusOK || body["total"] != float64(1) {
+		t.Errorf("sessions after password change: status = %d, body %v, want only the new token's", code, body)
+	}
+}
@@ -50,7 +50,7 @@ func (h *BatchHandler) BatchCreateDocuments(c *gin.Context) {
 	var failed []gin.H
 
 	for i, docReq := range req.Documents {
-		doc, err := h.docService.CreateDocument(&docReq)
+		doc, err := h.docService.CreateDocument(&docReq, c.GetString("user_id"))
 		if err != nil {
 			failed = append(failed, gin.H{
 				"index": i,
@@ -97,7 +97,7 @@ func (h *BatchHandler) BatchDeleteDocuments(c *gin.Context) {
 	var failed []gin.H
 
 	for _, id := range req.IDs {
-		err := h.docService.DeleteDocument(uint(id))
+		err := h.docService.DeleteDocument(uint(id), c.GetString("user_id"))
 		if err != nil {
 			failed = append(failed, gin.H{
 				"id":    id,
@@ -140,11 +140,12 @@ func (h *BatchHandler) BatchDeleteChats(c *gin.Context) {
 		return
 	}
 
+	userID := c.GetString("user_id")
 	var deleted []int64
 	var failed []gin.H
 
 	for _, id := range req.IDs {
-		err := h.chatService.DeleteChat(id)
+		err := h.chatService.DeleteChat(id, userID)
 		if err != nil {
 			failed = append(failed, gin.H{
 				"id":    id,
@@ -234,11 +235,11 @@ func (h *BatchHandler) ExportData(c *gin.Context) {
 	utils.SuccessResponse(c, export)
 }
 
-// BulkUpdateTags updates tags for multiple documents
+// BulkUpdateTags replaces the tags of multiple documents owned by the user
 func (h *BatchHandler) BulkUpdateTags(c *gin.Context) {
 	var req struct {
-		IDs  []int64 `json:"ids" binding:"required"`
-		Tags string  `json:"tags" binding:"required"`
+		IDs  []uint   `json:"ids" binding:"required"`
+		Tags []string `json:"tags" binding:"max=20,dive,min=1,max=50"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -251,17 +252,11 @@ func (h *BatchHandler) BulkUpdateTags(c *gin.Context) {
 		return
 	}
 
-	var updated []int64
+	var updated []uint
 	var failed []gin.H
 
 	for _, id := range req.IDs {
-		_, err := h.db.Exec(`
-			UPDATE documents
-			SET tags = ?, updated_at = CURRENT_TIMESTAMP
-			WHERE id = ?
-		`, req.Tags, id)
-
-		if err != nil {
+		if err := h.docService.SetDocumentTags(id, c.GetString("user_id"), req.Tags); err != nil {
 			failed = append(failed, gin.H{
 				"id":    id,
 				"error": err.Error(),
@@ -0,0 +1,168 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"lio-ai/internal/export"
+	"lio-ai/internal/middleware"
+	"lio-ai/internal/models"
+	"lio-ai/internal/services"
+)
+
+// BillingHandler handles monthly billing statements and adjustments
+type BillingHandler struct {
+	service *services.BillingService
+}
+
+// NewBillingHandler creates a new billing handler
+func NewBillingHandler(service *services.BillingService) *BillingHandler {
+	return &BillingHandler{service: service}
+}
+
+// ListStatements handles GET /api/v1/billing/statements: the user's
+// statements, latest month first, and the adjustments pending for their
+// next one. Admins can list another user's with user_id.
+func (h *BillingHandler) ListStatements(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if v := c.Query("user_id"); v != "" && v != userID {
+		if !middleware.HasRole(c, "admin") {
+			c.JSON(http.StatusForbidden, gin.H{"error": "only admins can view other users' statements", "code": "FORBIDDEN"})
+			return
+		}
+		userID = v
+	}
+
+	statements, err := h.service.ListStatements(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch statements", "code": "FETCH_FAILED"})
+		return
+	}
+	pending, err := h.service.ListPendingAdjustments(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch adjustments", "code": "FETCH_FAILED"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"data":                statements,
+		"total":               len(statements),
+		"pending_adjustments": pending,
+	})
+}
+
+// GetStatement handles GET /api/v1/billing/statements/:id with its usage
+// by model and adjustments
+func (h *BillingHandler) GetStatement(c *gin.Context) {
+	statement, ok := h.statement(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, statement)
+}
+
+// DownloadStatement handles GET /api/v1/billing/statements/:id/download,
+// the statement as format=pdf (default) or csv
+func (h *BillingHandler) DownloadStatement(c *gin.Context) {
+	format := c.DefaultQuery("format", "pdf")
+	if format != "pdf" && format != "csv" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be pdf or csv", "code": "INVALID_FORMAT"})
+		return
+	}
+	statement, ok := h.statement(c)
+	if !ok {
+		return
+	}
+
+	var buf bytes.Buffer
+	contentType := "application/pdf"
+	render := export.StatementPDF
+	if format == "csv" {
+		contentType, render = "text/csv; charset=utf-8", export.StatementCSV
+	}
+	if err := render(&buf, statement); err != nil {
+		log.Printf("Failed to render billing statement %d: %v", statement.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to export statement", "code": "EXPORT_FAILED"})
+		return
+	}
+
+	filename := fmt.Sprintf("statement-%s-%d.%s", statement.Period, statement.ID, format)
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Data(http.StatusOK, contentType, buf.Bytes())
+}
+
+// AddAdjustment handles POST /api/v1/admin/users/:id/billing/adjustments:
+// a credit (negative amount_usd) or charge on the user's next statement
+func (h *BillingHandler) AddAdjustment(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id", "code": "INVALID_ID"})
+		return
+	}
+	var req models.CreateBillingAdjustmentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "code": "INVALID_REQUEST"})
+		return
+	}
+
+	adjustment, err := h.service.AddAdjustment(id, &req, c.GetString("user_id"))
+	if err != nil {
+		respondBillingError(c, err, "failed to add adjustment")
+		return
+	}
+	c.JSON(http.StatusCreated, adjustment)
+}
+
+// ClosePeriod handles POST /api/v1/admin/billing/close: closes a past
+// month for the users without a statement for it, e.g. after an outage of
+// the monthly close
+func (h *BillingHandler) ClosePeriod(c *gin.Context) {
+	var req models.CloseBillingPeriodRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "code": "INVALID_REQUEST"})
+		return
+	}
+
+	closed, err := h.service.CloseMonth(req.Period)
+	if err != nil {
+		respondBillingError(c, err, "failed to close billing period")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"period":     req.Period,
+		"statements": closed,
+	})
+}
+
+// statement loads the :id statement, writing the error response when the
+// caller can't have it
+func (h *BillingHandler) statement(c *gin.Context) (*models.BillingStatement, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid statement id", "code": "INVALID_ID"})
+		return nil, false
+	}
+	statement, err := h.service.GetStatement(id, c.GetString("user_id"), middleware.HasRole(c, "admin"))
+	if err != nil {
+		respondBillingError(c, err, "failed to fetch statement")
+		return nil, false
+	}
+	return statement, true
+}
+
+func respondBillingError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrInvalidMessage):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "code": "INVALID_REQUEST"})
+	case errors.Is(err, services.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error(), "code": models.ErrCodeNotFound})
+	case errors.Is(err, services.ErrBillingPeriodOpen):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "code": "PERIOD_OPEN"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message, "code": "INTERNAL_ERROR"})
+	}
+}
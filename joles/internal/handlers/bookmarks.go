@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"lio-ai/internal/services"
+)
+
+// PinChat handles PATCH /api/v1/chats/:id/pin
+func (h *ChatHandler) PinChat(c *gin.Context) {
+	h.setChatPinned(c, true)
+}
+
+// UnpinChat handles PATCH /api/v1/chats/:id/unpin
+func (h *ChatHandler) UnpinChat(c *gin.Context) {
+	h.setChatPinned(c, false)
+}
+
+func (h *ChatHandler) setChatPinned(c *gin.Context, pinned bool) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "authentication required",
+			"code":  "UNAUTHORIZED",
+		})
+		return
+	}
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "invalid chat id",
+			"code":  "INVALID_ID",
+		})
+		return
+	}
+
+	chat, err := h.service.SetChatPinned(id, userID.(string), pinned)
+	if err != nil {
+		respondChatAccessError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, chat)
+}
+
+// BookmarkMessage handles PATCH /api/v1/chats/:id/messages/:message_id/bookmark
+func (h *ChatHandler) BookmarkMessage(c *gin.Context) {
+	h.setMessageBookmarked(c, true)
+}
+
+// UnbookmarkMessage handles PATCH /api/v1/chats/:id/messages/:message_id/unbookmark
+func (h *ChatHandler) UnbookmarkMessage(c *gin.Context) {
+	h.setMessageBookmarked(c, false)
+}
+
+func (h *ChatHandler) setMessageBookmarked(c *gin.Context, bookmarked bool) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "authentication required",
+			"code":  "UNAUTHORIZED",
+		})
+		return
+	}
+
+	chatID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "invalid chat id",
+			"code":  "INVALID_ID",
+		})
+		return
+	}
+	messageID, err := strconv.ParseInt(c.Param("message_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "invalid message id",
+			"code":  "INVALID_ID",
+		})
+		return
+	}
+
+	message, err := h.service.SetMessageBookmarked(chatID, messageID, userID.(string), bookmarked)
+	if err != nil {
+		respondChatAccessError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, message)
+}
+
+// GetBookmarks handles GET /api/v1/chats/bookmarks
+func (h *ChatHandler) GetBookmarks(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "authentication required",
+			"code":  "UNAUTHORIZED",
+		})
+		return
+	}
+
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if limit > 100 {
+		limit = 100
+	}
+	if limit < 1 {
+		limit = 20
+	}
+
+	bookmarks, err := h.service.GetBookmarks(userID.(string), limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "failed to fetch bookmarks",
+			"code":  "FETCH_FAILED",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":   bookmarks,
+		"limit":  limit,
+		"offset": offset,
+	})
+}
+
+// respondChatAccessError maps ownership and lookup errors to 403/404
+func respondChatAccessError(c *gin.Context, err error) {
+	if errors.Is(err, services.ErrUnauthorized) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "access denied",
+			"code":  "FORBIDDEN",
+		})
+		return
+	}
+	c.JSON(http.StatusNotFound, gin.H{
+		"error": err.Error(),
+		"code":  "NOT_FOUND",
+	})
+}
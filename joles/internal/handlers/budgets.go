@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"lio-ai/internal/models"
+	"lio-ai/internal/services"
+)
+
+// setBudgetHeaders reports a user's monthly budget with the
+// X-Budget-Limit-USD, X-Budget-Remaining-USD and X-Budget-Mode headers.
+// Nothing is set for users without a budget.
+func setBudgetHeaders(c *gin.Context, status *models.BudgetStatus) {
+	if status == nil {
+		return
+	}
+	c.Header("X-Budget-Limit-USD", strconv.FormatFloat(status.MonthlyUSD, 'f', -1, 64))
+	c.Header("X-Budget-Remaining-USD", strconv.FormatFloat(status.RemainingUSD, 'f', 6, 64))
+	c.Header("X-Budget-Mode", status.Mode)
+}
+
+// setExceededBudgetHeaders reports the spent budget err is refused for
+func setExceededBudgetHeaders(c *gin.Context, err error) {
+	var exceeded *services.BudgetExceededError
+	if errors.As(err, &exceeded) {
+		setBudgetHeaders(c, exceeded.Status)
+	}
+}
+
+// GetBudget returns the authenticated user's monthly budget and what they
+// have spent against it
+// GET /api/v1/usage/budget
+func (h *UsageHandler) GetBudget(c *gin.Context) {
+	status, err := h.usageService.GetBudgetStatus(c.GetString("user_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch budget", "code": "FETCH_FAILED"})
+		return
+	}
+	if status == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "no budget is set", "code": models.ErrCodeNotFound})
+		return
+	}
+
+	c.JSON(http.StatusOK, status)
+}
+
+// ListBudgets lists every budget with its spending this month
+// GET /api/v1/admin/budgets
+func (h *UsageHandler) ListBudgets(c *gin.Context) {
+	budgets, err := h.usageService.ListBudgets()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch budgets", "code": "FETCH_FAILED"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":  budgets,
+		"total": len(budgets),
+	})
+}
+
+// SetUserBudget creates or changes a user's monthly budget
+// PUT /api/v1/admin/users/:id/budget
+func (h *UsageHandler) SetUserBudget(c *gin.Context) {
+	var req models.SetBudgetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "code": "INVALID_REQUEST"})
+		return
+	}
+
+	status, err := h.usageService.SetUserBudget(c.Param("id"), &req, c.GetString("user_id"))
+	if err != nil {
+		respondBudgetError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, status)
+}
+
+// DeleteUserBudget removes a user's monthly budget
+// DELETE /api/v1/admin/users/:id/budget
+func (h *UsageHandler) DeleteUserBudget(c *gin.Context) {
+	if err := h.usageService.DeleteUserBudget(c.Param("id")); err != nil {
+		respondBudgetError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func respondBudgetError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrInvalidMessage):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "code": "INVALID_REQUEST"})
+	case errors.Is(err, services.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error(), "code": models.ErrCodeNotFound})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update budget", "code": "UPDATE_FAILED"})
+	}
+}
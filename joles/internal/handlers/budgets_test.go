@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"lio-ai/internal/models"
+	"lio-ai/internal/repositories"
+	"lio-ai/internal/services"
+)
+
+func TestSpentBlockBudgetIsReportedInHeaders(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	conn := newTestDB(t)
+	usageService := services.NewUsageService(repositories.NewUsageRepository(conn))
+	usageService.SetBudgets(repositories.NewBudgetRepository(conn))
+	handler := NewChatHandler(services.NewChatService(repositories.NewChatRepository(conn), usageService))
+
+	if _, err := usageService.SetUserBudget("1", &models.SetBudgetRequest{MonthlyUSD: 0.0001, Mode: models.BudgetModeBlock}, "admin"); err != nil {
+		t.Fatal(err)
+	}
+	err := usageService.TrackUsage(&models.UsageRequest{UserID: "1", RequestType: "chat", ModelUsed: "gpt-4", TokensInput: 10000, Success: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("user_id", "1")
+		c.Next()
+	})
+	router.POST("/embeddings", handler.CreateEmbeddings)
+
+	req := httptest.NewRequest(http.MethodPost, "/embeddings", strings.NewReader(`{"model": "text-embedding-3-small", "input": "hello"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusPaymentRequired {
+		t.Fatalf("status = %d, want 402: %s", w.Code, w.Body.String())
+	}
+	if got := w.Header().Get("X-Budget-Limit-USD"); got != "0.0001" {
+		t.Errorf("X-Budget-Limit-USD = %q, want 0.0001", got)
+	}
+	if got := w.Header().Get("X-Budget-Mode"); got != models.BudgetModeBlock {
+		t.Errorf("X-Budget-Mode = %q, want block", got)
+	}
+	if got := w.Header().Get("X-Budget-Remaining-USD"); got == "" {
+		t.Error("X-Budget-Remaining-USD is not set")
+	}
+}
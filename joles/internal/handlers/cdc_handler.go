@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"lio-ai/internal/services"
+)
+
+// ChangeFeedHandler serves the change feed to the analytics service
+type ChangeFeedHandler struct {
+	service *services.ChangeFeedService
+}
+
+// NewChangeFeedHandler creates a new change feed handler
+func NewChangeFeedHandler(service *services.ChangeFeedService) *ChangeFeedHandler {
+	return &ChangeFeedHandler{service: service}
+}
+
+// ListChanges handles GET /api/v1/cdc?since=&limit=, returning chat,
+// message and usage writes after the since cursor in commit order.
+// Consumers resume from next_cursor until has_more is false.
+func (h *ChangeFeedHandler) ListChanges(c *gin.Context) {
+	since, err := strconv.ParseInt(c.DefaultQuery("since", "0"), 10, 64)
+	if err != nil || since < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "since must be a non-negative event id",
+			"code":  "INVALID_REQUEST",
+		})
+		return
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(services.DefaultChangeFeedLimit)))
+	if err != nil || limit < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "limit must be a positive integer",
+			"code":  "INVALID_REQUEST",
+		})
+		return
+	}
+
+	page, err := h.service.List(since, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "failed to fetch changes",
+			"code":  "FETCH_FAILED",
+		})
+		return
+	}
+	c.JSON(http.StatusOK, page)
+}
@@ -0,0 +1,138 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"lio-ai/internal/models"
+	"lio-ai/internal/services"
+)
+
+// AttachDocuments handles POST /api/v1/chats/:id/context/documents
+func (h *ChatHandler) AttachDocuments(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "authentication required",
+			"code":  "UNAUTHORIZED",
+		})
+		return
+	}
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "invalid chat id",
+			"code":  "INVALID_ID",
+		})
+		return
+	}
+
+	var req models.AttachChatDocumentsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+			"code":  "INVALID_REQUEST",
+		})
+		return
+	}
+
+	docs, err := h.service.AttachDocuments(id, userID.(string), req.DocumentIDs)
+	if err != nil {
+		respondChatDocumentError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":  docs,
+		"total": len(docs),
+	})
+}
+
+// ListDocuments handles GET /api/v1/chats/:id/context/documents
+func (h *ChatHandler) ListDocuments(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "authentication required",
+			"code":  "UNAUTHORIZED",
+		})
+		return
+	}
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "invalid chat id",
+			"code":  "INVALID_ID",
+		})
+		return
+	}
+
+	docs, err := h.service.ListChatDocuments(id, userID.(string))
+	if err != nil {
+		respondChatDocumentError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":  docs,
+		"total": len(docs),
+	})
+}
+
+// DetachDocument handles DELETE /api/v1/chats/:id/context/documents/:document_id
+func (h *ChatHandler) DetachDocument(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "authentication required",
+			"code":  "UNAUTHORIZED",
+		})
+		return
+	}
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "invalid chat id",
+			"code":  "INVALID_ID",
+		})
+		return
+	}
+	documentID, err := strconv.ParseUint(c.Param("document_id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "invalid document id",
+			"code":  "INVALID_ID",
+		})
+		return
+	}
+
+	if err := h.service.DetachDocument(id, userID.(string), uint(documentID)); err != nil {
+		respondChatDocumentError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// respondChatDocumentError maps errors attaching documents to chats
+func respondChatDocumentError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrUnauthorized), errors.Is(err, services.ErrNotFound):
+		respondChatAccessError(c, err)
+	case errors.Is(err, services.ErrInvalidMessage):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+			"code":  "INVALID_REQUEST",
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "failed to update chat documents",
+			"code":  "INTERNAL_ERROR",
+		})
+	}
+}
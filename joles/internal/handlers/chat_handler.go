@@ -1,20 +1,26 @@
 package handlers
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"log"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
+	"lio-ai/internal/export"
 	"lio-ai/internal/models"
 	"lio-ai/internal/services"
 )
 
 // ChatHandler handles HTTP requests for chats
 type ChatHandler struct {
-	service *services.ChatService
+	service   *services.ChatService
+	scheduler *services.MessageScheduler
+	guests    *services.GuestService
 }
 
 // NewChatHandler creates a new chat handler
@@ -22,6 +28,16 @@ func NewChatHandler(service *services.ChatService) *ChatHandler {
 	return &ChatHandler{service: service}
 }
 
+// SetScheduler enables scheduled_at on POST /api/v1/chats/:id/messages
+func (h *ChatHandler) SetScheduler(scheduler *services.MessageScheduler) {
+	h.scheduler = scheduler
+}
+
+// SetGuestService applies guest quotas to completions from guest sessions
+func (h *ChatHandler) SetGuestService(guests *services.GuestService) {
+	h.guests = guests
+}
+
 // CreateChat handles POST /api/v1/chats
 func (h *ChatHandler) CreateChat(c *gin.Context) {
 	// Get authenticated user from JWT token
@@ -43,8 +59,27 @@ func (h *ChatHandler) CreateChat(c *gin.Context) {
 		return
 	}
 
+	var personaID int64
+	if req.PersonaID != nil {
+		personaID = *req.PersonaID
+	}
+
 	// Use authenticated user's ID, NOT client-provided one
-	chat, err := h.service.CreateChat(userID.(string), req.Title)
+	chat, err := h.service.CreateChat(userID.(string), req.Title, req.ContextStrategy, personaID)
+	if errors.Is(err, services.ErrInvalidContextStrategy) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+			"code":  "INVALID_CONTEXT_STRATEGY",
+		})
+		return
+	}
+	if errors.Is(err, services.ErrPersonaNotFound) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+			"code":  "INVALID_PERSONA",
+		})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": "failed to create chat",
@@ -104,9 +139,9 @@ func (h *ChatHandler) GetChatByUUID(c *gin.Context) {
 		return
 	}
 
-	chat, err := h.service.GetChatByUUID(uuid)
+	chat, err := h.service.GetChatByUUID(uuid, c.GetString("user_id"))
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		respondChatAccessError(c, err)
 		return
 	}
 
@@ -166,6 +201,17 @@ func (h *ChatHandler) GetUserChats(c *gin.Context) {
 		return
 	}
 
+	// include=summary adds each chat's cached summary, if it has one
+	if c.Query("include") == "summary" {
+		if err := h.service.AttachChatSummaries(chats); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "failed to fetch chat summaries",
+				"code":  "FETCH_FAILED",
+			})
+			return
+		}
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"data":   chats,
 		"total":  total,
@@ -188,7 +234,16 @@ func (h *ChatHandler) UpdateChat(c *gin.Context) {
 		return
 	}
 
-	chat, err := h.service.UpdateChat(id, req.Title)
+	chat, err := h.service.UpdateChat(id, c.GetString("user_id"), &req)
+	if errors.Is(err, services.ErrInvalidContextStrategy) || errors.Is(err, services.ErrInvalidBudgetAction) ||
+		errors.Is(err, services.ErrPersonaNotFound) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, services.ErrUnauthorized) || errors.Is(err, services.ErrNotFound) {
+		respondChatAccessError(c, err)
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -205,7 +260,11 @@ func (h *ChatHandler) DeleteChat(c *gin.Context) {
 		return
 	}
 
-	if err := h.service.DeleteChat(id); err != nil {
+	if err := h.service.DeleteChat(id, c.GetString("user_id")); err != nil {
+		if errors.Is(err, services.ErrUnauthorized) || errors.Is(err, services.ErrNotFound) {
+			respondChatAccessError(c, err)
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -221,13 +280,32 @@ func (h *ChatHandler) SendMessage(c *gin.Context) {
 		return
 	}
 
+	// Multipart requests carry files alongside the message fields
+	if strings.HasPrefix(c.ContentType(), "multipart/form-data") {
+		h.sendMessageWithAttachments(c, id)
+		return
+	}
+
 	var req models.MessageRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	message, err := h.service.SendMessage(id, req.Role, req.Content, req.Model)
+	if req.ScheduledAt != nil {
+		h.scheduleMessage(c, id, &req)
+		return
+	}
+
+	message, err := h.service.SendMessage(id, c.GetString("user_id"), &req)
+	if errors.Is(err, services.ErrInvalidMessage) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, services.ErrUnauthorized) || errors.Is(err, services.ErrNotFound) {
+		respondChatAccessError(c, err)
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -236,6 +314,99 @@ func (h *ChatHandler) SendMessage(c *gin.Context) {
 	c.JSON(http.StatusCreated, message)
 }
 
+// sendMessageWithAttachments handles multipart POST /api/v1/chats/:id/messages
+// with form fields role, content, model and one or more "files"
+func (h *ChatHandler) sendMessageWithAttachments(c *gin.Context, chatID int64) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "authentication required",
+			"code":  "UNAUTHORIZED",
+		})
+		return
+	}
+
+	form, err := c.MultipartForm()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid multipart form"})
+		return
+	}
+
+	role := c.PostForm("role")
+	if role == "" {
+		role = "user"
+	}
+	req := models.MessageRequest{
+		Role:    role,
+		Content: c.PostForm("content"),
+		Model:   c.PostForm("model"),
+	}
+
+	message, err := h.service.SendMessageWithAttachments(chatID, userID.(string), &req, form.File["files"])
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidMessage):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrUnauthorized), errors.Is(err, services.ErrNotFound):
+			respondChatAccessError(c, err)
+		case errors.Is(err, services.ErrAttachmentTooLarge):
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrStorageLimitExceeded):
+			respondStorageLimit(c, err)
+		case errors.Is(err, services.ErrAttachmentsDisabled):
+			c.JSON(http.StatusNotImplemented, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, message)
+}
+
+// GetAttachment handles GET /api/v1/chats/:id/attachments/:attachment_id
+func (h *ChatHandler) GetAttachment(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "authentication required",
+			"code":  "UNAUTHORIZED",
+		})
+		return
+	}
+
+	chatID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid chat id"})
+		return
+	}
+	attachmentID, err := strconv.ParseInt(c.Param("attachment_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid attachment id"})
+		return
+	}
+
+	attachment, content, err := h.service.GetAttachment(chatID, attachmentID, userID.(string))
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrUnauthorized):
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": "access denied",
+				"code":  "FORBIDDEN",
+			})
+		case errors.Is(err, services.ErrAttachmentsDisabled):
+			c.JSON(http.StatusNotImplemented, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		}
+		return
+	}
+	defer content.Close()
+
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", attachment.Filename))
+	c.DataFromReader(http.StatusOK, attachment.SizeBytes, attachment.ContentType, content, nil)
+}
+
 // SendMessageByUUID handles POST /api/v1/chats/uuid/:uuid/messages
 func (h *ChatHandler) SendMessageByUUID(c *gin.Context) {
 	uuid := c.Param("uuid")
@@ -250,7 +421,15 @@ func (h *ChatHandler) SendMessageByUUID(c *gin.Context) {
 		return
 	}
 
-	message, err := h.service.SendMessageByUUID(uuid, req.Role, req.Content, req.Model)
+	message, err := h.service.SendMessageByUUID(uuid, c.GetString("user_id"), &req)
+	if errors.Is(err, services.ErrInvalidMessage) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, services.ErrUnauthorized) || errors.Is(err, services.ErrNotFound) {
+		respondChatAccessError(c, err)
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -267,9 +446,9 @@ func (h *ChatHandler) GetMessages(c *gin.Context) {
 		return
 	}
 
-	messages, err := h.service.GetChatMessages(id)
+	messages, err := h.service.GetChatMessages(id, c.GetString("user_id"))
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		respondChatAccessError(c, err)
 		return
 	}
 
@@ -287,9 +466,9 @@ func (h *ChatHandler) GetMessagesByUUID(c *gin.Context) {
 		return
 	}
 
-	messages, err := h.service.GetChatMessagesByUUID(uuid)
+	messages, err := h.service.GetChatMessagesByUUID(uuid, c.GetString("user_id"))
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		respondChatAccessError(c, err)
 		return
 	}
 
@@ -307,34 +486,68 @@ func (h *ChatHandler) ChatCompletion(c *gin.Context) {
 		return
 	}
 
-	response, err := h.service.CreateChatCompletion(&req)
+	// Bill the authenticated user (or guest session), NOT a client-provided user_id
+	req.UserID = c.GetString("user_id")
+	req.Provider = c.GetHeader(services.ProviderHeader)
+
+	if c.GetBool("guest") && h.guests != nil {
+		if err := h.guests.Admit(req.UserID, req.Model, req.Message); err != nil {
+			if errors.Is(err, services.ErrGuestQuotaExceeded) {
+				c.JSON(http.StatusTooManyRequests, gin.H{
+					"error":       "guest quota exceeded",
+					"code":        "GUEST_QUOTA_EXCEEDED",
+					"message":     "You've used the guest allowance for today. Create a free account to keep chatting.",
+					"upgrade_url": "/api/v1/auth/register",
+				})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"detail": "failed to check guest quota"})
+			return
+		}
+	}
+
+	response, err := h.service.CreateChatCompletion(c.Request.Context(), &req)
 	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidMessage), errors.Is(err, services.ErrMissingTemplateVariable),
+			errors.Is(err, services.ErrCostCapExceeded), errors.Is(err, services.ErrPersonaNotFound),
+			errors.Is(err, services.ErrProviderNotRouted):
+			c.JSON(http.StatusBadRequest, gin.H{"detail": err.Error()})
+			return
+		case errors.Is(err, services.ErrTemplateNotFound), errors.Is(err, services.ErrNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"detail": err.Error()})
+			return
+		case errors.Is(err, services.ErrChatBudgetExceeded):
+			c.JSON(http.StatusPaymentRequired, gin.H{"detail": err.Error(), "code": "CHAT_BUDGET_EXCEEDED"})
+			return
+		case errors.Is(err, services.ErrBudgetExceeded):
+			setExceededBudgetHeaders(c, err)
+			c.JSON(http.StatusPaymentRequired, gin.H{"detail": err.Error(), "code": models.ErrCodeBudgetExceeded})
+			return
+		case errors.Is(err, services.ErrQuotaExceeded):
+			c.JSON(http.StatusTooManyRequests, gin.H{"detail": err.Error(), "code": models.ErrCodeQuotaExceeded})
+			return
+		case errors.Is(err, services.ErrContentBlocked):
+			var blocked *services.ContentBlockedError
+			errors.As(err, &blocked)
+			c.JSON(http.StatusUnprocessableEntity, gin.H{
+				"detail":     services.ErrContentBlocked.Error(),
+				"code":       "CONTENT_BLOCKED",
+				"categories": blocked.Result.Categories,
+			})
+			return
+		case errors.Is(err, services.ErrModerationUnavailable):
+			c.JSON(http.StatusServiceUnavailable, gin.H{"detail": err.Error(), "code": "MODERATION_UNAVAILABLE"})
+			return
+		case errors.Is(err, services.ErrUnauthorized):
+			c.JSON(http.StatusForbidden, gin.H{"detail": "access denied"})
+			return
+		}
+
 		// Preserve upstream AI service status codes (e.g., 429 rate limit)
 		var aiErr *services.AIServiceError
 		if errors.As(err, &aiErr) && aiErr != nil {
-			status := aiErr.StatusCode
-			if status == 0 {
-				status = http.StatusBadGateway
-			}
-
-			detail := aiErr.Error()
-			// Try extracting a clean "detail" from the upstream JSON
-			var upstream struct {
-				Detail  string `json:"detail"`
-				Message string `json:"message"`
-				Error   string `json:"error"`
-			}
-			if json.Unmarshal([]byte(aiErr.Body), &upstream) == nil {
-				if upstream.Detail != "" {
-					detail = upstream.Detail
-				} else if upstream.Message != "" {
-					detail = upstream.Message
-				} else if upstream.Error != "" {
-					detail = upstream.Error
-				}
-			}
-
-			c.JSON(status, gin.H{"detail": detail})
+			c.JSON(aiErrorStatus(aiErr), gin.H{"detail": aiErrorDetail(aiErr)})
 			return
 		}
 
@@ -342,5 +555,254 @@ func (h *ChatHandler) ChatCompletion(c *gin.Context) {
 		return
 	}
 
+	setDeprecationHeaders(c, response.Deprecation)
+	setBudgetHeaders(c, response.SpendingBudget)
+	if response.Provider != "" {
+		c.Header(services.ProviderHeader, response.Provider)
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// GetChatStats handles GET /api/v1/chats/:id/stats
+func (h *ChatHandler) GetChatStats(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "authentication required",
+			"code":  "UNAUTHORIZED",
+		})
+		return
+	}
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "invalid chat id",
+			"code":  "INVALID_ID",
+		})
+		return
+	}
+
+	stats, err := h.service.GetChatStats(id, userID.(string))
+	if err != nil {
+		respondChatAccessError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, stats)
+}
+
+// ExportChat handles GET /api/v1/chats/:id/export?format=html|json
+func (h *ChatHandler) ExportChat(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "authentication required",
+			"code":  "UNAUTHORIZED",
+		})
+		return
+	}
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "invalid chat id",
+			"code":  "INVALID_ID",
+		})
+		return
+	}
+
+	format := c.DefaultQuery("format", "html")
+	if format != "html" && format != "json" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "format must be html or json",
+			"code":  "INVALID_FORMAT",
+		})
+		return
+	}
+
+	chat, err := h.service.GetChat(id, userID.(string))
+	if err != nil {
+		respondChatAccessError(c, err)
+		return
+	}
+
+	filename := exportFilename(chat.Title, chat.ID) + "." + format
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+
+	if format == "json" {
+		c.JSON(http.StatusOK, chat)
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := export.HTML(&buf, chat); err != nil {
+		log.Printf("Failed to render chat %d export: %v", chat.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "failed to export chat",
+			"code":  "EXPORT_FAILED",
+		})
+		return
+	}
+	c.Data(http.StatusOK, "text/html; charset=utf-8", buf.Bytes())
+}
+
+// exportFilename derives a safe download name from a chat title
+func exportFilename(title string, id int64) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_':
+			return r
+		case r == ' ':
+			return '-'
+		}
+		return -1
+	}, title)
+	if len(name) > 60 {
+		name = name[:60]
+	}
+	if name == "" {
+		return fmt.Sprintf("chat-%d", id)
+	}
+	return name
+}
+
+// StopGeneration handles POST /api/v1/chats/:id/stop
+func (h *ChatHandler) StopGeneration(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "authentication required",
+			"code":  "UNAUTHORIZED",
+		})
+		return
+	}
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "invalid chat id",
+			"code":  "INVALID_ID",
+		})
+		return
+	}
+
+	if err := h.service.StopGeneration(id, userID.(string)); err != nil {
+		switch {
+		case errors.Is(err, services.ErrUnauthorized):
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": "access denied",
+				"code":  "FORBIDDEN",
+			})
+		case errors.Is(err, services.ErrNoGeneration):
+			c.JSON(http.StatusConflict, gin.H{
+				"error": "no generation in progress",
+				"code":  "NOT_RUNNING",
+			})
+		default:
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "chat not found",
+				"code":  "NOT_FOUND",
+			})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "generation stopped"})
+}
+
+// CompareModels handles POST /api/v1/chats/:id/compare
+func (h *ChatHandler) CompareModels(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "authentication required",
+			"code":  "UNAUTHORIZED",
+		})
+		return
+	}
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "invalid chat id",
+			"code":  "INVALID_ID",
+		})
+		return
+	}
+
+	var req models.CompareRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "message and 2 to 4 models are required",
+			"code":  "INVALID_REQUEST",
+		})
+		return
+	}
+
+	response, err := h.service.CompareModels(c.Request.Context(), id, userID.(string), &req)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidMessage):
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+				"code":  "INVALID_REQUEST",
+			})
+		case errors.Is(err, services.ErrContentBlocked):
+			var blocked *services.ContentBlockedError
+			errors.As(err, &blocked)
+			c.JSON(http.StatusUnprocessableEntity, gin.H{
+				"error":      services.ErrContentBlocked.Error(),
+				"code":       "CONTENT_BLOCKED",
+				"categories": blocked.Result.Categories,
+			})
+		case errors.Is(err, services.ErrModerationUnavailable):
+			c.JSON(http.StatusServiceUnavailable, gin.H{
+				"error": err.Error(),
+				"code":  "MODERATION_UNAVAILABLE",
+			})
+		case errors.Is(err, services.ErrUnauthorized):
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": "access denied",
+				"code":  "FORBIDDEN",
+			})
+		default:
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "chat not found",
+				"code":  "NOT_FOUND",
+			})
+		}
+		return
+	}
+
 	c.JSON(http.StatusOK, response)
 }
+
+// aiErrorStatus preserves the upstream AI service status code (e.g. a 429
+// rate limit), defaulting to 502
+func aiErrorStatus(aiErr *services.AIServiceError) int {
+	if aiErr.StatusCode == 0 {
+		return http.StatusBadGateway
+	}
+	return aiErr.StatusCode
+}
+
+// aiErrorDetail extracts a clean "detail" from the upstream JSON, falling
+// back to the raw error
+func aiErrorDetail(aiErr *services.AIServiceError) string {
+	var upstream struct {
+		Detail  string `json:"detail"`
+		Message string `json:"message"`
+		Error   string `json:"error"`
+	}
+	if json.Unmarshal([]byte(aiErr.Body), &upstream) == nil {
+		if upstream.Detail != "" {
+			return upstream.Detail
+		} else if upstream.Message != "" {
+			return upstream.Message
+		} else if upstream.Error != "" {
+			return upstream.Error
+		}
+	}
+	return aiErr.Error()
+}
@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"lio-ai/internal/models"
+	"lio-ai/internal/services"
+)
+
+// GetChatSummary handles GET /api/v1/chats/:id/summary. Query parameters:
+// refresh=true regenerates the summary even if it is current, and model
+// picks the summarizing model.
+func (h *ChatHandler) GetChatSummary(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "authentication required",
+			"code":  "UNAUTHORIZED",
+		})
+		return
+	}
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "invalid chat id",
+			"code":  "INVALID_ID",
+		})
+		return
+	}
+
+	summary, err := h.service.GetChatSummary(c.Request.Context(), id, userID.(string), c.Query("model"), c.Query("refresh") == "true")
+	if err != nil {
+		respondSummaryError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, summary)
+}
+
+// SummarizeChat handles POST /api/v1/chats/:id/summarize. It stores the
+// summary and action items for the chat; the optional body picks the model
+// and sets refresh to regenerate a summary that is still current.
+func (h *ChatHandler) SummarizeChat(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "authentication required",
+			"code":  "UNAUTHORIZED",
+		})
+		return
+	}
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "invalid chat id",
+			"code":  "INVALID_ID",
+		})
+		return
+	}
+
+	var req models.SummarizeChatRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+			"code":  "INVALID_REQUEST",
+		})
+		return
+	}
+
+	summary, err := h.service.GetChatSummary(c.Request.Context(), id, userID.(string), req.Model, req.Refresh)
+	if err != nil {
+		respondSummaryError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, summary)
+}
+
+func respondSummaryError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrInvalidMessage):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+			"code":  "EMPTY_CHAT",
+		})
+	case errors.Is(err, services.ErrSummaryUnavailable):
+		c.JSON(http.StatusBadGateway, gin.H{
+			"error": err.Error(),
+			"code":  "SUMMARY_FAILED",
+		})
+	default:
+		respondChatAccessError(c, err)
+	}
+}
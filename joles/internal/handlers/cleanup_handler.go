@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"lio-ai/internal/services"
+)
+
+// CleanupHandler handles the admin endpoints of garbage collection
+type CleanupHandler struct {
+	service *services.CleanupService
+}
+
+// NewCleanupHandler creates a new cleanup handler
+func NewCleanupHandler(service *services.CleanupService) *CleanupHandler {
+	return &CleanupHandler{service: service}
+}
+
+// GetStats handles GET /api/v1/admin/cleanup, the rows and stored bytes
+// reclaimed since the server started and the last pass
+func (h *CleanupHandler) GetStats(c *gin.Context) {
+	c.JSON(http.StatusOK, h.service.Stats())
+}
+
+// RunCleanup handles POST /api/v1/admin/cleanup/run, cleaning up now
+// instead of waiting for the next pass
+func (h *CleanupHandler) RunCleanup(c *gin.Context) {
+	report, err := h.service.Run()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "failed to clean up expired data",
+			"code":  "INTERNAL_ERROR",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
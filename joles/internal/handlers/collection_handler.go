@@ -0,0 +1,164 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"lio-ai/internal/models"
+	"lio-ai/internal/services"
+)
+
+// CollectionHandler handles document collection HTTP requests
+type CollectionHandler struct {
+	service *services.CollectionService
+}
+
+// NewCollectionHandler creates a new collection handler
+func NewCollectionHandler(service *services.CollectionService) *CollectionHandler {
+	return &CollectionHandler{service: service}
+}
+
+// CreateCollection handles POST /api/v1/collections
+// @Summary Create a collection
+// @Description Create a document collection, at the top level or inside parent_id
+// @Accept json
+// @Produce json
+// @Param collection body models.CreateCollectionRequest true "Collection data"
+// @Success 201 {object} models.Collection
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/v1/collections [post]
+func (h *CollectionHandler) CreateCollection(c *gin.Context) {
+	var req models.CreateCollectionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	collection, err := h.service.CreateCollection(c.GetString("user_id"), &req)
+	if err != nil {
+		respondCollectionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, collection)
+}
+
+// GetCollections handles GET /api/v1/collections
+// @Summary List collections
+// @Description List the current user's collections with their document counts
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/collections [get]
+func (h *CollectionHandler) GetCollections(c *gin.Context) {
+	collections, err := h.service.ListCollections(c.GetString("user_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": collections, "total": len(collections)})
+}
+
+// GetCollection handles GET /api/v1/collections/:id
+// @Summary Get a collection
+// @Produce json
+// @Param id path int true "Collection ID"
+// @Success 200 {object} models.Collection
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/v1/collections/{id} [get]
+func (h *CollectionHandler) GetCollection(c *gin.Context) {
+	id, ok := collectionID(c)
+	if !ok {
+		return
+	}
+
+	collection, err := h.service.GetCollection(id, c.GetString("user_id"))
+	if err != nil {
+		respondCollectionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, collection)
+}
+
+// UpdateCollection handles PUT /api/v1/collections/:id
+// @Summary Rename a collection
+// @Accept json
+// @Produce json
+// @Param id path int true "Collection ID"
+// @Param collection body models.UpdateCollectionRequest true "New name"
+// @Success 200 {object} models.Collection
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/v1/collections/{id} [put]
+func (h *CollectionHandler) UpdateCollection(c *gin.Context) {
+	id, ok := collectionID(c)
+	if !ok {
+		return
+	}
+
+	var req models.UpdateCollectionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	collection, err := h.service.RenameCollection(id, c.GetString("user_id"), req.Name)
+	if err != nil {
+		respondCollectionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, collection)
+}
+
+// DeleteCollection handles DELETE /api/v1/collections/:id
+// @Summary Delete a collection
+// @Description Delete a collection; its documents move up to the parent collection or the top level
+// @Param id path int true "Collection ID"
+// @Success 204
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /api/v1/collections/{id} [delete]
+func (h *CollectionHandler) DeleteCollection(c *gin.Context) {
+	id, ok := collectionID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteCollection(id, c.GetString("user_id")); err != nil {
+		respondCollectionError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func collectionID(c *gin.Context) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid collection ID"})
+		return 0, false
+	}
+	return id, true
+}
+
+// respondCollectionError maps nesting errors to 400 and 409, and lookup
+// failures like those of documents
+func respondCollectionError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrCollectionDepth):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrCollectionNotEmpty):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		respondDocumentError(c, err)
+	}
+}
@@ -0,0 +1,177 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"lio-ai/internal/models"
+	"lio-ai/internal/services"
+)
+
+// CostConfigHandler handles the admin API for model prices
+type CostConfigHandler struct {
+	service *services.CostConfigService
+}
+
+// NewCostConfigHandler creates a new cost config handler
+func NewCostConfigHandler(service *services.CostConfigService) *CostConfigHandler {
+	return &CostConfigHandler{service: service}
+}
+
+// ListCostConfigs handles GET /api/v1/admin/cost-config
+func (h *CostConfigHandler) ListCostConfigs(c *gin.Context) {
+	configs, err := h.service.List()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "failed to fetch cost configs",
+			"code":  "FETCH_FAILED",
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": configs, "total": len(configs)})
+}
+
+// CreateCostConfig handles POST /api/v1/admin/cost-config
+func (h *CostConfigHandler) CreateCostConfig(c *gin.Context) {
+	var req models.CreateCostConfigRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+			"code":  "INVALID_REQUEST",
+		})
+		return
+	}
+
+	config, err := h.service.Create(&req, c.GetString("user_id"))
+	if err != nil {
+		respondCostConfigError(c, err, "failed to create cost config")
+		return
+	}
+	c.JSON(http.StatusCreated, config)
+}
+
+// UpdateCostConfig handles PATCH /api/v1/admin/cost-config/*model. Model
+// names may contain slashes (e.g. ollama/llama3).
+func (h *CostConfigHandler) UpdateCostConfig(c *gin.Context) {
+	var req models.UpdateCostConfigRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+			"code":  "INVALID_REQUEST",
+		})
+		return
+	}
+
+	config, err := h.service.Update(costConfigModel(c), &req, c.GetString("user_id"))
+	if err != nil {
+		respondCostConfigError(c, err, "failed to update cost config")
+		return
+	}
+	c.JSON(http.StatusOK, config)
+}
+
+// DeactivateCostConfig handles DELETE /api/v1/admin/cost-config/*model
+func (h *CostConfigHandler) DeactivateCostConfig(c *gin.Context) {
+	config, err := h.service.Deactivate(costConfigModel(c), c.GetString("user_id"))
+	if err != nil {
+		respondCostConfigError(c, err, "failed to deactivate cost config")
+		return
+	}
+	c.JSON(http.StatusOK, config)
+}
+
+// GetEffectivePrice handles GET /api/v1/admin/cost-config/effective?model=&key_source=
+func (h *CostConfigHandler) GetEffectivePrice(c *gin.Context) {
+	price, err := h.service.EffectivePrice(c.Query("model"), c.Query("key_source"))
+	if err != nil {
+		respondCostConfigError(c, err, "failed to look up price")
+		return
+	}
+	c.JSON(http.StatusOK, price)
+}
+
+// SyncPrices handles POST /api/v1/admin/cost-config/sync, updating prices
+// from the price feed now
+func (h *CostConfigHandler) SyncPrices(c *gin.Context) {
+	report, err := h.service.SyncPrices(c.Request.Context(), c.GetString("user_id"))
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrPriceFeedNotConfigured):
+			c.JSON(http.StatusConflict, gin.H{
+				"error": "no price feed is configured (COST_SYNC_URL)",
+				"code":  "PRICE_FEED_NOT_CONFIGURED",
+			})
+		case errors.Is(err, services.ErrPriceFeedUnavailable):
+			c.JSON(http.StatusBadGateway, gin.H{
+				"error": err.Error(),
+				"code":  "PRICE_FEED_UNAVAILABLE",
+			})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "failed to sync prices",
+				"code":  "SYNC_FAILED",
+			})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// ListPriceHistory handles GET /api/v1/admin/cost-config/history?model=
+func (h *CostConfigHandler) ListPriceHistory(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if limit < 1 || limit > 200 {
+		limit = 50
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	changes, total, err := h.service.History(c.Query("model"), limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "failed to fetch price history",
+			"code":  "FETCH_FAILED",
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"data":   changes,
+		"total":  total,
+		"limit":  limit,
+		"offset": offset,
+	})
+}
+
+func costConfigModel(c *gin.Context) string {
+	return strings.TrimPrefix(c.Param("model"), "/")
+}
+
+func respondCostConfigError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrInvalidCostConfig):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+			"code":  "INVALID_REQUEST",
+		})
+	case errors.Is(err, services.ErrCostConfigExists):
+		c.JSON(http.StatusConflict, gin.H{
+			"error": err.Error(),
+			"code":  "COST_CONFIG_EXISTS",
+		})
+	case errors.Is(err, services.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": err.Error(),
+			"code":  "NOT_FOUND",
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": message,
+			"code":  "INTERNAL_ERROR",
+		})
+	}
+}
@@ -0,0 +1,140 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"lio-ai/internal/models"
+)
+
+// ListComments handles GET /api/v1/documents/:id/comments
+// @Summary List a document's comments
+// @Description Comments in the order of their ranges; outdated is set on those whose range no longer covers their quote
+// @Produce json
+// @Param id path int true "Document ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/v1/documents/{id}/comments [get]
+func (h *DocumentHandler) ListComments(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid document ID"})
+		return
+	}
+
+	comments, err := h.service.ListComments(uint(id), c.GetString("user_id"))
+	if err != nil {
+		respondDocumentError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":  comments,
+		"total": len(comments),
+	})
+}
+
+// CreateComment handles POST /api/v1/documents/:id/comments
+// @Summary Comment on a document
+// @Description Anchor a comment to the characters of the content from start_offset up to end_offset
+// @Accept json
+// @Produce json
+// @Param id path int true "Document ID"
+// @Param comment body models.CreateDocumentCommentRequest true "Comment"
+// @Success 201 {object} models.DocumentComment
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/v1/documents/{id}/comments [post]
+func (h *DocumentHandler) CreateComment(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid document ID"})
+		return
+	}
+
+	var req models.CreateDocumentCommentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	comment, err := h.service.AddComment(uint(id), c.GetString("user_id"), &req)
+	if err != nil {
+		respondDocumentError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, comment)
+}
+
+// UpdateComment handles PATCH /api/v1/documents/:id/comments/:comment_id
+// @Summary Edit or resolve a comment
+// @Accept json
+// @Produce json
+// @Param id path int true "Document ID"
+// @Param comment_id path int true "Comment ID"
+// @Param comment body models.UpdateDocumentCommentRequest true "Changes"
+// @Success 200 {object} models.DocumentComment
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/v1/documents/{id}/comments/{comment_id} [patch]
+func (h *DocumentHandler) UpdateComment(c *gin.Context) {
+	id, commentID, ok := commentParams(c)
+	if !ok {
+		return
+	}
+
+	var req models.UpdateDocumentCommentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	comment, err := h.service.UpdateComment(id, c.GetString("user_id"), commentID, &req)
+	if err != nil {
+		respondDocumentError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, comment)
+}
+
+// DeleteComment handles DELETE /api/v1/documents/:id/comments/:comment_id
+// @Summary Delete a comment
+// @Param id path int true "Document ID"
+// @Param comment_id path int true "Comment ID"
+// @Success 204
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/v1/documents/{id}/comments/{comment_id} [delete]
+func (h *DocumentHandler) DeleteComment(c *gin.Context) {
+	id, commentID, ok := commentParams(c)
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteComment(id, c.GetString("user_id"), commentID); err != nil {
+		respondDocumentError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func commentParams(c *gin.Context) (uint, int64, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid document ID"})
+		return 0, 0, false
+	}
+	commentID, err := strconv.ParseInt(c.Param("comment_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid comment ID"})
+		return 0, 0, false
+	}
+	return uint(id), commentID, true
+}
@@ -1,17 +1,24 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
+	"lio-ai/internal/extract"
 	"lio-ai/internal/models"
+	"lio-ai/internal/repositories"
 	"lio-ai/internal/services"
 )
 
 // DocumentHandler handles document HTTP requests
 type DocumentHandler struct {
 	service *services.DocumentService
+	// Updates must name the version they are based on
+	requireIfMatch bool
 }
 
 // NewDocumentHandler creates a new document handler
@@ -19,6 +26,12 @@ func NewDocumentHandler(service *services.DocumentService) *DocumentHandler {
 	return &DocumentHandler{service: service}
 }
 
+// SetRequireIfMatch makes updates without If-Match or a version fail with
+// 428, so clients can't overwrite edits they haven't seen
+func (h *DocumentHandler) SetRequireIfMatch(require bool) {
+	h.requireIfMatch = require
+}
+
 // CreateDocument handles POST /api/v1/documents
 // @Summary Create a new document
 // @Description Create a new document with title and content
@@ -27,30 +40,169 @@ func NewDocumentHandler(service *services.DocumentService) *DocumentHandler {
 // @Param document body models.CreateDocumentRequest true "Document data"
 // @Success 201 {object} models.DocumentResponse
 // @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 413 {object} map[string]string
 // @Router /api/v1/documents [post]
 func (h *DocumentHandler) CreateDocument(c *gin.Context) {
 	var req models.CreateDocumentRequest
 
-	if err := c.ShouldBindJSON(&req); err != nil {
+	if !h.bindDocument(c, &req) {
+		return
+	}
+
+	doc, err := h.service.CreateDocument(&req, c.GetString("user_id"))
+	if err != nil {
+		if errors.Is(err, services.ErrStorageLimitExceeded) {
+			respondStorageLimit(c, err)
+			return
+		}
+		// The collection to file the document in is missing or not theirs
+		respondDocumentError(c, err)
+		return
+	}
+
+	c.Header("ETag", documentETag(doc.Version))
+	c.JSON(http.StatusCreated, doc)
+}
+
+// UploadDocument handles multipart POST /api/v1/documents/upload
+// @Summary Upload a document file
+// @Description Create a document from a PDF, DOCX, TXT or Markdown file; its text becomes the content and the original is kept
+// @Accept multipart/form-data
+// @Produce json
+// @Param file formData file true "Document file"
+// @Param title formData string false "Title; defaults to the file name"
+// @Param tags formData string false "Comma-separated tags"
+// @Param collection_id formData int false "Collection to file the document in"
+// @Param source_url formData string false "Where the document came from"
+// @Param language formData string false "BCP 47 language tag of the content"
+// @Success 201 {object} models.DocumentResponse
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 413 {object} map[string]string
+// @Failure 415 {object} map[string]string
+// @Failure 422 {object} map[string]string
+// @Failure 501 {object} map[string]string
+// @Router /api/v1/documents/upload [post]
+func (h *DocumentHandler) UploadDocument(c *gin.Context) {
+	file, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "a file is required"})
+		return
+	}
+
+	var req models.UploadDocumentRequest
+	if err := c.ShouldBind(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	var tags []string
+	for _, value := range req.Tags {
+		tags = append(tags, strings.Split(value, ",")...)
+	}
+	req.Tags = tags
 
-	doc, err := h.service.CreateDocument(&req)
+	doc, err := h.service.UploadDocument(c.GetString("user_id"), &req, file)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		switch {
+		case errors.Is(err, services.ErrDocumentFileTooLarge):
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrStorageLimitExceeded):
+			respondStorageLimit(c, err)
+		case errors.Is(err, extract.ErrUnsupportedFormat):
+			c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": err.Error()})
+		case errors.Is(err, extract.ErrNoText), errors.Is(err, extract.ErrMalformed):
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrDocumentUploadsDisabled):
+			c.JSON(http.StatusNotImplemented, gin.H{"error": err.Error()})
+		default:
+			respondDocumentError(c, err)
+		}
 		return
 	}
 
 	c.JSON(http.StatusCreated, doc)
 }
 
+// GetDocumentFile handles GET /api/v1/documents/:id/file
+// @Summary Download a document's file
+// @Description Download the original file of an uploaded document
+// @Produce octet-stream
+// @Param id path int true "Document ID"
+// @Success 200 {file} file
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/v1/documents/{id}/file [get]
+func (h *DocumentHandler) GetDocumentFile(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid document ID"})
+		return
+	}
+
+	doc, content, err := h.service.GetDocumentFile(uint(id), c.GetString("user_id"))
+	if err != nil {
+		if errors.Is(err, services.ErrDocumentUploadsDisabled) {
+			c.JSON(http.StatusNotImplemented, gin.H{"error": err.Error()})
+			return
+		}
+		respondDocumentError(c, err)
+		return
+	}
+	defer content.Close()
+	h.recordRead(c, doc)
+
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", doc.File.Name))
+	c.DataFromReader(http.StatusOK, doc.File.Size, doc.File.ContentType, content, nil)
+}
+
+// ExportDocument handles GET /api/v1/documents/:id/export
+// @Summary Export a document's content
+// @Description Download the content as a file of its content type: Markdown, HTML, plain text, or code with its language's extension
+// @Produce plain
+// @Param id path int true "Document ID"
+// @Success 200 {file} file
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/v1/documents/{id}/export [get]
+func (h *DocumentHandler) ExportDocument(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid document ID"})
+		return
+	}
+
+	doc, export, err := h.service.ExportDocument(uint(id), c.GetString("user_id"))
+	if err != nil {
+		respondDocumentError(c, err)
+		return
+	}
+	h.recordRead(c, doc)
+
+	// Exported HTML is the user's own markup; keep it from running scripts
+	// if a browser opens it from this origin
+	c.Header("Content-Security-Policy", "sandbox")
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", export.Filename))
+	c.Data(http.StatusOK, export.MediaType, []byte(export.Content))
+}
+
 // GetDocuments handles GET /api/v1/documents
 // @Summary Get all documents
-// @Description Retrieve all documents with pagination
+// @Description Retrieve the current user's documents with pagination
 // @Produce json
 // @Param skip query int false "Number of documents to skip" default(0)
 // @Param limit query int false "Maximum documents to return" default(100)
+// @Param tags query string false "Comma-separated tags documents must all carry"
+// @Param collection_id query int false "Only documents in this collection; 0 for those in none"
+// @Param language query string false "Only documents in this language, e.g. en (also matches en-US)"
+// @Param content_type query string false "Only documents with this content type: markdown, plaintext, html or code"
+// @Param code_language query string false "Only code documents in this language, e.g. go"
+// @Param source_url query string false "Only documents whose source URL starts with this"
+// @Param min_words query int false "Only documents with at least this many words"
+// @Param max_words query int false "Only documents with at most this many words"
+// @Param favorites query bool false "Only the user's favorite documents"
 // @Success 200 {object} map[string]interface{}
 // @Failure 400 {object} map[string]string
 // @Router /api/v1/documents [get]
@@ -70,11 +222,40 @@ func (h *DocumentHandler) GetDocuments(c *gin.Context) {
 		}
 	}
 
-	docs, total, err := h.service.GetDocuments(skip, limit)
+	filter := models.DocumentFilter{UserID: c.GetString("user_id"), Skip: skip, Limit: limit}
+	for _, value := range c.QueryArray("tags") {
+		filter.Tags = append(filter.Tags, strings.Split(value, ",")...)
+	}
+	if v := c.Query("collection_id"); v != "" {
+		collectionID, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || collectionID < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid collection ID"})
+			return
+		}
+		filter.CollectionID = &collectionID
+	}
+	filter.Language = c.Query("language")
+	filter.ContentType = c.Query("content_type")
+	filter.CodeLanguage = c.Query("code_language")
+	filter.SourceURL = c.Query("source_url")
+	filter.Favorites = c.Query("favorites") == "true"
+	for param, bound := range map[string]*int{"min_words": &filter.MinWords, "max_words": &filter.MaxWords} {
+		if v := c.Query(param); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 0 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + param})
+				return
+			}
+			*bound = n
+		}
+	}
+
+	docs, total, err := h.service.GetDocuments(filter)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	h.recordRead(c, docs...)
 
 	c.JSON(http.StatusOK, gin.H{
 		"data":  docs,
@@ -89,7 +270,9 @@ func (h *DocumentHandler) GetDocuments(c *gin.Context) {
 // @Description Retrieve a document by ID
 // @Produce json
 // @Param id path int true "Document ID"
+// @Param include query string false "comments to include the document's comments"
 // @Success 200 {object} models.DocumentResponse
+// @Failure 403 {object} map[string]string
 // @Failure 404 {object} map[string]string
 // @Failure 500 {object} map[string]string
 // @Router /api/v1/documents/{id} [get]
@@ -100,25 +283,40 @@ func (h *DocumentHandler) GetDocument(c *gin.Context) {
 		return
 	}
 
-	doc, err := h.service.GetDocument(uint(id))
+	doc, err := h.service.GetDocument(uint(id), c.GetString("user_id"))
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		respondDocumentError(c, err)
 		return
 	}
+	h.recordRead(c, doc)
+	c.Header("ETag", documentETag(doc.Version))
+
+	// include=comments adds the comments on the document
+	if c.Query("include") == "comments" {
+		if err := h.service.AttachComments(doc); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
 
 	c.JSON(http.StatusOK, doc)
 }
 
 // UpdateDocument handles PUT /api/v1/documents/:id
 // @Summary Update a document
-// @Description Update an existing document
+// @Description Update an existing document. The update names the version it is based on, as the ETag in If-Match or in version, and fails with 409 if the document has changed since.
 // @Accept json
 // @Produce json
 // @Param id path int true "Document ID"
+// @Param If-Match header string false "ETag of the version the update is based on, or *"
 // @Param document body models.UpdateDocumentRequest true "Document updates"
 // @Success 200 {object} models.DocumentResponse
 // @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
 // @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 413 {object} map[string]string
+// @Failure 428 {object} map[string]string
 // @Failure 500 {object} map[string]string
 // @Router /api/v1/documents/{id} [put]
 func (h *DocumentHandler) UpdateDocument(c *gin.Context) {
@@ -129,14 +327,69 @@ func (h *DocumentHandler) UpdateDocument(c *gin.Context) {
 	}
 
 	var req models.UpdateDocumentRequest
+	if !h.bindDocument(c, &req) {
+		return
+	}
+	if ifMatch := c.GetHeader("If-Match"); ifMatch != "" {
+		version, ok := parseDocumentETag(ifMatch)
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": `If-Match must be "*" or one document ETag`})
+			return
+		}
+		// "*" only requires the document to exist
+		if version != 0 {
+			req.Version = &version
+		}
+	} else if req.Version == nil && h.requireIfMatch {
+		c.JSON(http.StatusPreconditionRequired, gin.H{
+			"error": "send the document's ETag in If-Match, or its version in version",
+			"code":  "PRECONDITION_REQUIRED",
+		})
+		return
+	}
+
+	doc, err := h.service.UpdateDocument(uint(id), c.GetString("user_id"), &req)
+	if err != nil {
+		if errors.Is(err, services.ErrStorageLimitExceeded) {
+			respondStorageLimit(c, err)
+			return
+		}
+		respondDocumentError(c, err)
+		return
+	}
+
+	c.Header("ETag", documentETag(doc.Version))
+	c.JSON(http.StatusOK, doc)
+}
+
+// MoveDocument handles PUT /api/v1/documents/:id/collection
+// @Summary Move a document
+// @Description File a document in a collection, or at the top level with a null collection_id
+// @Accept json
+// @Produce json
+// @Param id path int true "Document ID"
+// @Param move body models.MoveDocumentRequest true "Target collection"
+// @Success 200 {object} models.DocumentResponse
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/v1/documents/{id}/collection [put]
+func (h *DocumentHandler) MoveDocument(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid document ID"})
+		return
+	}
+
+	var req models.MoveDocumentRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	doc, err := h.service.UpdateDocument(uint(id), &req)
+	doc, err := h.service.MoveDocument(uint(id), c.GetString("user_id"), req.CollectionID)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		respondDocumentError(c, err)
 		return
 	}
 
@@ -149,6 +402,7 @@ func (h *DocumentHandler) UpdateDocument(c *gin.Context) {
 // @Param id path int true "Document ID"
 // @Success 204
 // @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
 // @Failure 404 {object} map[string]string
 // @Failure 500 {object} map[string]string
 // @Router /api/v1/documents/{id} [delete]
@@ -159,10 +413,246 @@ func (h *DocumentHandler) DeleteDocument(c *gin.Context) {
 		return
 	}
 
-	if err := h.service.DeleteDocument(uint(id)); err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	if err := h.service.DeleteDocument(uint(id), c.GetString("user_id")); err != nil {
+		respondDocumentError(c, err)
 		return
 	}
 
 	c.Status(http.StatusNoContent)
 }
+
+// GetAccessLog handles GET /api/v1/documents/:id/access-log
+// @Summary Get a document's access log
+// @Description Reads of the document recorded while it was confidential, newest first
+// @Produce json
+// @Param id path int true "Document ID"
+// @Param skip query int false "Number of entries to skip" default(0)
+// @Param limit query int false "Maximum entries to return" default(100)
+// @Success 200 {object} map[string]interface{}
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/v1/documents/{id}/access-log [get]
+func (h *DocumentHandler) GetAccessLog(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid document ID"})
+		return
+	}
+
+	skip := 0
+	limit := 100
+	if s := c.Query("skip"); s != "" {
+		if val, err := strconv.Atoi(s); err == nil && val >= 0 {
+			skip = val
+		}
+	}
+	if l := c.Query("limit"); l != "" {
+		if val, err := strconv.Atoi(l); err == nil && val > 0 && val <= 1000 {
+			limit = val
+		}
+	}
+
+	entries, total, err := h.service.GetAccessLog(uint(id), c.GetString("user_id"), limit, skip)
+	if err != nil {
+		respondDocumentError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":  entries,
+		"total": total,
+		"skip":  skip,
+		"limit": limit,
+	})
+}
+
+// FavoriteDocument handles PATCH /api/v1/documents/:id/favorite
+// @Summary Star or unstar a document
+// @Description Sets is_favorite when given, otherwise toggles it
+// @Accept json
+// @Produce json
+// @Param id path int true "Document ID"
+// @Param favorite body models.FavoriteDocumentRequest false "Favorite flag"
+// @Success 200 {object} models.DocumentResponse
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/v1/documents/{id}/favorite [patch]
+func (h *DocumentHandler) FavoriteDocument(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid document ID"})
+		return
+	}
+
+	var req models.FavoriteDocumentRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	doc, err := h.service.SetFavorite(uint(id), c.GetString("user_id"), req.IsFavorite)
+	if err != nil {
+		respondDocumentError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, doc)
+}
+
+// GetVersions handles GET /api/v1/documents/:id/versions
+// @Summary List a document's versions
+// @Description Stored revisions of the title and content, newest first, without their content
+// @Produce json
+// @Param id path int true "Document ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/v1/documents/{id}/versions [get]
+func (h *DocumentHandler) GetVersions(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid document ID"})
+		return
+	}
+
+	versions, err := h.service.ListVersions(uint(id), c.GetString("user_id"))
+	if err != nil {
+		respondDocumentError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":  versions,
+		"total": len(versions),
+	})
+}
+
+// DiffDocument handles GET /api/v1/documents/:id/diff
+// @Summary Diff two versions of a document
+// @Description Line-level unified diff between two stored versions
+// @Produce json
+// @Param id path int true "Document ID"
+// @Param from query int false "Older version; defaults to the one before to"
+// @Param to query int false "Newer version; defaults to the latest"
+// @Success 200 {object} models.DocumentDiff
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 422 {object} map[string]string
+// @Router /api/v1/documents/{id}/diff [get]
+func (h *DocumentHandler) DiffDocument(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid document ID"})
+		return
+	}
+
+	var versions [2]int
+	for i, param := range []string{"from", "to"} {
+		if v := c.Query(param); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 1 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": param + " must be a version number"})
+				return
+			}
+			versions[i] = n
+		}
+	}
+
+	diff, err := h.service.DiffVersions(uint(id), c.GetString("user_id"), versions[0], versions[1])
+	if err != nil {
+		if errors.Is(err, services.ErrDiffTooLarge) {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+			return
+		}
+		respondDocumentError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, diff)
+}
+
+// recordRead logs the request as a read of any confidential docs
+func (h *DocumentHandler) recordRead(c *gin.Context, docs ...*models.DocumentResponse) {
+	h.service.RecordRead(documentReader(c), docs...)
+}
+
+// documentReader identifies the caller for document access logs
+func documentReader(c *gin.Context) models.DocumentReader {
+	return models.DocumentReader{
+		UserID:    c.GetString("user_id"),
+		Endpoint:  c.Request.Method + " " + c.FullPath(),
+		IPAddress: c.ClientIP(),
+	}
+}
+
+// respondDocumentError maps document lookup failures to 403 for documents
+// of other users, 404 for missing ones and 500 otherwise
+func respondDocumentError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrUnauthorized):
+		c.JSON(http.StatusForbidden, gin.H{"error": "access denied"})
+	case errors.Is(err, services.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrInvalidMessage):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrDocumentTooLarge):
+		respondDocumentTooLarge(c, err.Error())
+	case errors.Is(err, repositories.ErrVersionConflict):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "code": "VERSION_CONFLICT"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+// documentETag is the entity tag of a document's version
+func documentETag(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+// parseDocumentETag reads an If-Match value, returning 0 for "*". Weak
+// tags are refused, as If-Match compares strongly.
+func parseDocumentETag(value string) (int, bool) {
+	value = strings.TrimSpace(value)
+	if value == "*" {
+		return 0, true
+	}
+	if len(value) < 3 || value[0] != '"' || value[len(value)-1] != '"' {
+		return 0, false
+	}
+	version, err := strconv.Atoi(value[1 : len(value)-1])
+	if err != nil || version < 1 {
+		return 0, false
+	}
+	return version, true
+}
+
+// bindDocument binds a JSON document body, refusing bodies far larger than
+// any plan's document content limit before reading them whole. JSON
+// escaping can take six bytes per content byte, hence the margin.
+func (h *DocumentHandler) bindDocument(c *gin.Context, req any) bool {
+	if max := h.service.MaxContentBytes(); max > 0 {
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, 6*max+1<<20)
+	}
+	if err := c.ShouldBindJSON(req); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			respondDocumentTooLarge(c, fmt.Sprintf("request body exceeds %d bytes", tooLarge.Limit))
+			return false
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return false
+	}
+	return true
+}
+
+// respondDocumentTooLarge writes the 413 for content over the document
+// size limit
+func respondDocumentTooLarge(c *gin.Context, message string) {
+	c.JSON(http.StatusRequestEntityTooLarge, gin.H{
+		"error": message,
+		"code":  "DOCUMENT_TOO_LARGE",
+	})
+}
@@ -0,0 +1,154 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"lio-ai/internal/models"
+	"lio-ai/internal/services"
+)
+
+// maxDocumentImportBytes bounds the size of an uploaded import file
+const maxDocumentImportBytes = 100 << 20
+
+// DocumentImportHandler handles bulk document imports
+type DocumentImportHandler struct {
+	service *services.DocumentImportService
+}
+
+// NewDocumentImportHandler creates a new document import handler
+func NewDocumentImportHandler(service *services.DocumentImportService) *DocumentImportHandler {
+	return &DocumentImportHandler{service: service}
+}
+
+// ImportDocuments handles POST /api/v1/documents/import
+// @Summary Import documents in bulk
+// @Description Create a document from each text or Markdown file of a ZIP archive, or from each line of a JSONL stream, in a background job
+// @Accept multipart/form-data,application/zip,application/x-ndjson
+// @Produce json
+// @Param file formData file false "ZIP or JSONL file; the request body may be sent instead"
+// @Param tags formData string false "Comma-separated tags added to every document"
+// @Param collection_id formData int false "Collection for documents that name none"
+// @Success 202 {object} models.DocumentImportJob
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 413 {object} map[string]string
+// @Failure 415 {object} map[string]string
+// @Router /api/v1/documents/import [post]
+func (h *DocumentImportHandler) ImportDocuments(c *gin.Context) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxDocumentImportBytes)
+
+	var req models.ImportDocumentsRequest
+	var src io.Reader = c.Request.Body
+	source := importSource("", c.ContentType())
+	if strings.HasPrefix(c.ContentType(), "multipart/form-data") {
+		file, err := c.FormFile("file")
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "import files are limited to " + strconv.Itoa(maxDocumentImportBytes) + " bytes"})
+				return
+			}
+			c.JSON(http.StatusBadRequest, gin.H{"error": "multipart upload must include a file field"})
+			return
+		}
+		if err := c.ShouldBind(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		f, err := file.Open()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "failed to read uploaded file"})
+			return
+		}
+		defer f.Close()
+		src = f
+		source = importSource(file.Filename, file.Header.Get("Content-Type"))
+	} else if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	var tags []string
+	for _, value := range req.Tags {
+		tags = append(tags, strings.Split(value, ",")...)
+	}
+	req.Tags = tags
+
+	var entries []models.DocumentImportEntry
+	var err error
+	switch source {
+	case "zip":
+		var data []byte
+		if data, err = io.ReadAll(src); err == nil {
+			entries, err = h.service.ParseDocumentZIP(data)
+		}
+	case "jsonl":
+		entries, err = h.service.ParseDocumentJSONL(src)
+	default:
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "import a ZIP archive or a JSONL file"})
+		return
+	}
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		switch {
+		case errors.As(err, &tooLarge):
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "import files are limited to " + strconv.Itoa(maxDocumentImportBytes) + " bytes"})
+		case errors.Is(err, services.ErrInvalidImport):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": "failed to read import: " + err.Error()})
+		}
+		return
+	}
+
+	job, err := h.service.StartImport(c.GetString("user_id"), source, entries, &req)
+	if err != nil {
+		respondDocumentError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, job)
+}
+
+// GetImportJob handles GET /api/v1/documents/import/:id, reporting each
+// entry as created (with its document_id) or failed (with an error)
+func (h *DocumentImportHandler) GetImportJob(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid import ID"})
+		return
+	}
+
+	job, err := h.service.GetImportJob(id, c.GetString("user_id"))
+	if err != nil {
+		respondDocumentError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, job)
+}
+
+// importSource picks the import format from the file name, falling back
+// to the content type; it is empty for neither ZIP nor JSONL
+func importSource(filename, contentType string) string {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".zip":
+		return "zip"
+	case ".jsonl", ".ndjson":
+		return "jsonl"
+	}
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch mediaType {
+	case "application/zip", "application/x-zip-compressed":
+		return "zip"
+	case "application/x-ndjson", "application/jsonl", "application/x-jsonlines":
+		return "jsonl"
+	}
+	return ""
+}
@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"lio-ai/internal/models"
+	"lio-ai/internal/services"
+)
+
+// DuplicateChat handles POST /api/v1/chats/:id/duplicate. The optional body
+// sets the new title and up_to_message_id to copy only the start of the chat.
+func (h *ChatHandler) DuplicateChat(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "authentication required",
+			"code":  "UNAUTHORIZED",
+		})
+		return
+	}
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "invalid chat id",
+			"code":  "INVALID_ID",
+		})
+		return
+	}
+
+	var req models.DuplicateChatRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+			"code":  "INVALID_REQUEST",
+		})
+		return
+	}
+
+	chat, err := h.service.DuplicateChat(id, userID.(string), &req)
+	if err != nil {
+		if errors.Is(err, services.ErrAttachmentsDisabled) {
+			c.JSON(http.StatusServiceUnavailable, gin.H{
+				"error": "chat has attachments but attachment storage is not enabled",
+				"code":  "ATTACHMENTS_DISABLED",
+			})
+			return
+		}
+		if errors.Is(err, services.ErrStorageLimitExceeded) {
+			respondStorageLimit(c, err)
+			return
+		}
+		respondChatAccessError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, chat)
+}
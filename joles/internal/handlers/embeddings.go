@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"lio-ai/internal/models"
+	"lio-ai/internal/services"
+)
+
+// CreateEmbeddings handles POST /api/v1/embeddings. Input is a string or a
+// list of strings; the response has one vector per input.
+func (h *ChatHandler) CreateEmbeddings(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "authentication required",
+			"code":  "UNAUTHORIZED",
+		})
+		return
+	}
+
+	var req models.EmbeddingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+			"code":  "INVALID_REQUEST",
+		})
+		return
+	}
+
+	response, err := h.service.CreateEmbeddings(c.Request.Context(), userID.(string), &req)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidMessage) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+				"code":  "INVALID_REQUEST",
+			})
+			return
+		}
+		if errors.Is(err, services.ErrQuotaExceeded) {
+			c.JSON(http.StatusTooManyRequests, gin.H{
+				"error": err.Error(),
+				"code":  models.ErrCodeQuotaExceeded,
+			})
+			return
+		}
+		if errors.Is(err, services.ErrBudgetExceeded) {
+			setExceededBudgetHeaders(c, err)
+			c.JSON(http.StatusPaymentRequired, gin.H{
+				"error": err.Error(),
+				"code":  models.ErrCodeBudgetExceeded,
+			})
+			return
+		}
+		var aiErr *services.AIServiceError
+		if errors.As(err, &aiErr) && aiErr != nil {
+			c.JSON(aiErrorStatus(aiErr), gin.H{
+				"error": aiErrorDetail(aiErr),
+				"code":  "AI_SERVICE_ERROR",
+			})
+			return
+		}
+		c.JSON(http.StatusBadGateway, gin.H{
+			"error": err.Error(),
+			"code":  "AI_SERVICE_ERROR",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"lio-ai/internal/models"
+	"lio-ai/internal/services"
+)
+
+// ExemptionHandler handles the admin list of traffic exempt from rate
+// limiting and usage tracking
+type ExemptionHandler struct {
+	service *services.ExemptionService
+}
+
+// NewExemptionHandler creates a new exemption handler
+func NewExemptionHandler(service *services.ExemptionService) *ExemptionHandler {
+	return &ExemptionHandler{service: service}
+}
+
+// ListExemptions handles GET /api/v1/admin/exemptions
+func (h *ExemptionHandler) ListExemptions(c *gin.Context) {
+	exemptions, err := h.service.List()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "failed to fetch exemptions",
+			"code":  "FETCH_FAILED",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":  exemptions,
+		"total": len(exemptions),
+	})
+}
+
+// CreateExemption handles POST /api/v1/admin/exemptions. Token exemptions
+// return their token, to be sent in X-Exemption-Token, only here.
+func (h *ExemptionHandler) CreateExemption(c *gin.Context) {
+	var req models.CreateExemptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+			"code":  "INVALID_REQUEST",
+		})
+		return
+	}
+
+	exemption, err := h.service.Create(c.GetString("user_id"), &req)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidMessage) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+				"code":  "INVALID_REQUEST",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "failed to create exemption",
+			"code":  "CREATE_FAILED",
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, exemption)
+}
+
+// DeleteExemption handles DELETE /api/v1/admin/exemptions/:id
+func (h *ExemptionHandler) DeleteExemption(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "invalid exemption id",
+			"code":  "INVALID_REQUEST",
+		})
+		return
+	}
+
+	if err := h.service.Delete(id); err != nil {
+		if errors.Is(err, services.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "exemption not found",
+				"code":  "NOT_FOUND",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "failed to delete exemption",
+			"code":  "DELETE_FAILED",
+		})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
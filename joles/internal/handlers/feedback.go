@@ -0,0 +1,194 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"lio-ai/internal/models"
+	"lio-ai/internal/services"
+)
+
+// defaultFeedbackWindow is the period summarized when no range is given
+const defaultFeedbackWindow = 30 * 24 * time.Hour
+
+// SubmitFeedback handles POST /api/v1/chats/:id/messages/:message_id/feedback
+func (h *ChatHandler) SubmitFeedback(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "authentication required",
+			"code":  "UNAUTHORIZED",
+		})
+		return
+	}
+
+	chatID, messageID, ok := messageParams(c)
+	if !ok {
+		return
+	}
+
+	var req models.FeedbackRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "rating is required",
+			"code":  "INVALID_REQUEST",
+		})
+		return
+	}
+
+	feedback, err := h.service.SubmitFeedback(chatID, messageID, userID.(string), &req)
+	if err != nil {
+		respondFeedbackError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, feedback)
+}
+
+// DeleteFeedback handles DELETE /api/v1/chats/:id/messages/:message_id/feedback
+func (h *ChatHandler) DeleteFeedback(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "authentication required",
+			"code":  "UNAUTHORIZED",
+		})
+		return
+	}
+
+	chatID, messageID, ok := messageParams(c)
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteFeedback(chatID, messageID, userID.(string)); err != nil {
+		respondFeedbackError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// GetFeedbackSummary handles GET /api/v1/admin/feedback/summary. Query
+// parameters: since and until (RFC3339 or YYYY-MM-DD, until inclusive for
+// dates; default the last 30 days), model, and group_by=day for a daily series.
+func (h *ChatHandler) GetFeedbackSummary(c *gin.Context) {
+	until := time.Now()
+	since := until.Add(-defaultFeedbackWindow)
+	var err error
+	if v := c.Query("since"); v != "" {
+		if since, err = parseFeedbackTime(v, false); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "since must be RFC3339 or YYYY-MM-DD",
+				"code":  "INVALID_REQUEST",
+			})
+			return
+		}
+	}
+	if v := c.Query("until"); v != "" {
+		if until, err = parseFeedbackTime(v, true); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "until must be RFC3339 or YYYY-MM-DD",
+				"code":  "INVALID_REQUEST",
+			})
+			return
+		}
+	}
+	if !since.Before(until) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "since must be before until",
+			"code":  "INVALID_REQUEST",
+		})
+		return
+	}
+
+	groupBy := c.DefaultQuery("group_by", "model")
+	if groupBy != "model" && groupBy != "day" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "group_by must be 'model' or 'day'",
+			"code":  "INVALID_REQUEST",
+		})
+		return
+	}
+	model := c.Query("model")
+
+	stats, err := h.service.GetFeedbackStats(since, until, model, groupBy == "day")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "failed to fetch feedback summary",
+			"code":  "FETCH_FAILED",
+		})
+		return
+	}
+
+	totals := models.FeedbackStats{Model: model}
+	for _, st := range stats {
+		totals.Up += st.Up
+		totals.Down += st.Down
+		totals.Total += st.Total
+		totals.Comments += st.Comments
+	}
+	if totals.Total > 0 {
+		totals.Satisfaction = float64(totals.Up) / float64(totals.Total)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"since":    since,
+		"until":    until,
+		"group_by": groupBy,
+		"totals":   totals,
+		"data":     stats,
+	})
+}
+
+// parseFeedbackTime accepts RFC3339 timestamps or dates; with endOfDay a
+// date means the end of that day
+func parseFeedbackTime(v string, endOfDay bool) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse("2006-01-02", v)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if endOfDay {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t, nil
+}
+
+// messageParams parses the :id and :message_id parameters, writing a 400
+// when either is invalid
+func messageParams(c *gin.Context) (int64, int64, bool) {
+	chatID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "invalid chat id",
+			"code":  "INVALID_ID",
+		})
+		return 0, 0, false
+	}
+	messageID, err := strconv.ParseInt(c.Param("message_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "invalid message id",
+			"code":  "INVALID_ID",
+		})
+		return 0, 0, false
+	}
+	return chatID, messageID, true
+}
+
+func respondFeedbackError(c *gin.Context, err error) {
+	if errors.Is(err, services.ErrInvalidFeedback) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+			"code":  "INVALID_REQUEST",
+		})
+		return
+	}
+	respondChatAccessError(c, err)
+}
@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"lio-ai/internal/models"
+	"lio-ai/internal/services"
+)
+
+// GatewayConfigHandler handles configuration export and import
+type GatewayConfigHandler struct {
+	service *services.GatewayConfigService
+}
+
+// NewGatewayConfigHandler creates a new gateway config handler
+func NewGatewayConfigHandler(service *services.GatewayConfigService) *GatewayConfigHandler {
+	return &GatewayConfigHandler{service: service}
+}
+
+// ExportConfig handles GET /api/v1/admin/config/export
+func (h *GatewayConfigHandler) ExportConfig(c *gin.Context) {
+	doc, err := h.service.Export()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "failed to export configuration",
+			"code":  "EXPORT_FAILED",
+		})
+		return
+	}
+
+	name := "gateway-config.json"
+	if doc.Environment != "" {
+		name = fmt.Sprintf("gateway-config-%s.json", doc.Environment)
+	}
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name))
+	c.JSON(http.StatusOK, doc)
+}
+
+// ImportConfig handles POST /api/v1/admin/config/import. With
+// ?dry_run=true the changes are only previewed.
+func (h *GatewayConfigHandler) ImportConfig(c *gin.Context) {
+	var doc models.GatewayConfig
+	if err := c.ShouldBindJSON(&doc); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+			"code":  "INVALID_REQUEST",
+		})
+		return
+	}
+
+	result, err := h.service.Import(&doc, c.GetString("user_id"), c.Query("dry_run") == "true")
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidConfig) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+				"code":  "INVALID_CONFIG",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "failed to import configuration",
+			"code":  "IMPORT_FAILED",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	"lio-ai/internal/config"
+	"lio-ai/internal/db"
+	"lio-ai/internal/models"
+	"lio-ai/internal/repositories"
+)
+
+// newTestDB opens a migrated SQLite database private to the test
+func newTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	cfg := &config.Config{Database: config.DatabaseConfig{DSN: filepath.Join(t.TempDir(), "test.db")}}
+	database, err := db.NewDatabase(cfg)
+	if err != nil {
+		t.Fatalf("NewDatabase: %v", err)
+	}
+	t.Cleanup(func() { database.Close() })
+	return database.GetConnection()
+}
+
+// createTestUser inserts an active user with the given role
+func createTestUser(t *testing.T, conn *sql.DB, username, role string) *models.User {
+	t.Helper()
+	user := &models.User{
+		Username:     username,
+		Email:        username + "@example.com",
+		PasswordHash: "unused",
+		Role:         role,
+		IsActive:     true,
+	}
+	if err := repositories.NewUserRepository(conn).Create(user); err != nil {
+		t.Fatalf("create user %s: %v", username, err)
+	}
+	return user
+}
@@ -0,0 +1,168 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"lio-ai/internal/audit"
+	"lio-ai/internal/models"
+	"lio-ai/internal/repositories"
+	"lio-ai/internal/services"
+)
+
+// IdentityHandler handles account linking and the admin merge tool
+type IdentityHandler struct {
+	service *services.IdentityService
+	audit   *audit.Logger
+}
+
+// NewIdentityHandler creates a new identity handler
+func NewIdentityHandler(service *services.IdentityService) *IdentityHandler {
+	return &IdentityHandler{service: service}
+}
+
+// SetAuditLogger streams account links to the audit sinks; admin merges
+// are audited with the other admin requests
+func (h *IdentityHandler) SetAuditLogger(logger *audit.Logger) {
+	h.audit = logger
+}
+
+// MergeUsers handles POST /api/v1/admin/users/:id/merge, merging the
+// account in the body into :id
+func (h *IdentityHandler) MergeUsers(c *gin.Context) {
+	targetID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "invalid user id",
+			"code":  "INVALID_REQUEST",
+		})
+		return
+	}
+	var req models.MergeUsersRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "invalid request: " + err.Error(),
+			"code":  "INVALID_REQUEST",
+		})
+		return
+	}
+
+	report, err := h.service.MergeUsers(req.SourceUserID, targetID, c.GetString("user_id"))
+	if err != nil {
+		respondMergeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// LinkAccount handles POST /api/v1/auth/link, merging another account the
+// user owns into the logged-in one
+func (h *IdentityHandler) LinkAccount(c *gin.Context) {
+	userID, ok := sessionUserID(c)
+	if !ok {
+		return
+	}
+	var req models.LinkAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "invalid request",
+			"code":  "INVALID_REQUEST",
+		})
+		return
+	}
+
+	report, err := h.service.LinkAccount(userID, req.Email, req.Password)
+	if err != nil {
+		h.audit.Log(audit.Event{
+			Type:       "auth.account_link",
+			Outcome:    audit.OutcomeFailure,
+			ActorID:    fmt.Sprint(userID),
+			ActorEmail: c.GetString("email"),
+			IP:         c.ClientIP(),
+			Method:     c.Request.Method,
+			Path:       c.Request.URL.Path,
+			Details:    map[string]interface{}{"linked_email": req.Email, "reason": err.Error()},
+		})
+		if errors.Is(err, services.ErrInvalidCredentials) {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"error": "authentication failed",
+				"code":  "INVALID_CREDENTIALS",
+			})
+			return
+		}
+		respondMergeError(c, err)
+		return
+	}
+
+	h.audit.Log(audit.Event{
+		Type:       "auth.account_link",
+		Outcome:    audit.OutcomeSuccess,
+		ActorID:    fmt.Sprint(userID),
+		ActorEmail: c.GetString("email"),
+		IP:         c.ClientIP(),
+		Method:     c.Request.Method,
+		Path:       c.Request.URL.Path,
+		Details:    map[string]interface{}{"linked_email": req.Email, "source_user_id": report.SourceUserID},
+	})
+	c.JSON(http.StatusOK, report)
+}
+
+// ListMerges handles GET /api/v1/admin/identity-merges, the merge audit
+// trail. Query parameters: limit and offset.
+func (h *IdentityHandler) ListMerges(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if limit > 200 {
+		limit = 200
+	}
+	if limit < 1 {
+		limit = 50
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	merges, total, err := h.service.ListMerges(limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "failed to fetch identity merges",
+			"code":  "FETCH_FAILED",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":   merges,
+		"total":  total,
+		"limit":  limit,
+		"offset": offset,
+	})
+}
+
+func respondMergeError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrUserNotFound):
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "user not found",
+			"code":  "NOT_FOUND",
+		})
+	case errors.Is(err, services.ErrSameAccount):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+			"code":  "INVALID_REQUEST",
+		})
+	case errors.Is(err, repositories.ErrAlreadyMerged):
+		c.JSON(http.StatusConflict, gin.H{
+			"error": err.Error(),
+			"code":  "ALREADY_MERGED",
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "failed to merge accounts",
+			"code":  "INTERNAL_ERROR",
+		})
+	}
+}
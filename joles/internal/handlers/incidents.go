@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"lio-ai/internal/middleware"
+	"lio-ai/internal/models"
+	"lio-ai/internal/services"
+	"lio-ai/internal/utils"
+)
+
+// SetIncidents enables the incident history and status endpoints
+func (h *SystemHandler) SetIncidents(incidents *services.IncidentService) {
+	h.incidents = incidents
+}
+
+// GetIncidents handles GET /api/v1/system/incidents. Query parameters:
+// component, since (RFC3339 or YYYY-MM-DD), limit and offset. Incident
+// details, which can name internal hosts, are shown to admins only.
+func (h *SystemHandler) GetIncidents(c *gin.Context) {
+	if h.incidents == nil {
+		utils.ServiceDownError(c, "incident history")
+		return
+	}
+
+	filter := models.IncidentFilter{Component: c.Query("component")}
+	if v := c.Query("since"); v != "" {
+		since, err := parseFeedbackTime(v, false)
+		if err != nil {
+			utils.BadRequestError(c, "since must be RFC3339 or YYYY-MM-DD")
+			return
+		}
+		filter.Since = since
+	}
+
+	filter.Limit, _ = strconv.Atoi(c.DefaultQuery("limit", "50"))
+	filter.Offset, _ = strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if filter.Limit > 200 {
+		filter.Limit = 200
+	}
+	if filter.Limit < 1 {
+		filter.Limit = 50
+	}
+	if filter.Offset < 0 {
+		filter.Offset = 0
+	}
+
+	incidents, total, err := h.incidents.ListIncidents(filter)
+	if err != nil {
+		utils.InternalError(c, "Failed to load incidents")
+		return
+	}
+	if !middleware.HasRole(c, "admin") {
+		for i := range incidents {
+			incidents[i].Detail = ""
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":   incidents,
+		"total":  total,
+		"limit":  filter.Limit,
+		"offset": filter.Offset,
+	})
+}
+
+// GetStatus handles GET /api/v1/status, the public status page JSON:
+// overall and per-component state, 30-day uptime and MTTR, and recent
+// incidents
+func (h *SystemHandler) GetStatus(c *gin.Context) {
+	if h.incidents == nil {
+		utils.ServiceDownError(c, "status page")
+		return
+	}
+
+	page, err := h.incidents.Status()
+	if err != nil {
+		utils.InternalError(c, "Failed to load status")
+		return
+	}
+
+	c.Header("Cache-Control", "public, max-age=30")
+	c.JSON(http.StatusOK, page)
+}
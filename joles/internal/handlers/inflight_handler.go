@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"lio-ai/internal/services"
+)
+
+// InflightHandler handles the admin view of requests being served
+type InflightHandler struct {
+	registry *services.RequestRegistry
+}
+
+// NewInflightHandler creates a new in-flight request handler
+func NewInflightHandler(registry *services.RequestRegistry) *InflightHandler {
+	return &InflightHandler{registry: registry}
+}
+
+// ListInflight handles GET /api/v1/admin/inflight: the requests being
+// served, longest running first, with the upstream each is waiting on
+func (h *InflightHandler) ListInflight(c *gin.Context) {
+	requests := h.registry.List()
+	c.JSON(http.StatusOK, gin.H{
+		"data":  requests,
+		"total": len(requests),
+	})
+}
+
+// CancelInflight handles POST /api/v1/admin/inflight/:id/cancel. The
+// request's context is cancelled, aborting its upstream call; it then
+// fails as it would on the call timing out.
+func (h *InflightHandler) CancelInflight(c *gin.Context) {
+	if !h.registry.Cancel(c.Param("id")) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "no request in flight with this id",
+			"code":  "NOT_FOUND",
+		})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"id":        c.Param("id"),
+		"cancelled": true,
+	})
+}
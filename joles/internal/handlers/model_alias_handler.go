@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"lio-ai/internal/models"
+	"lio-ai/internal/services"
+)
+
+// ModelAliasHandler handles HTTP requests for model aliases
+type ModelAliasHandler struct {
+	service *services.ModelAliasService
+}
+
+// NewModelAliasHandler creates a new model alias handler
+func NewModelAliasHandler(service *services.ModelAliasService) *ModelAliasHandler {
+	return &ModelAliasHandler{service: service}
+}
+
+// ListAliases handles GET /api/v1/model-aliases
+func (h *ModelAliasHandler) ListAliases(c *gin.Context) {
+	aliases, err := h.service.ListAliases()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "failed to fetch model aliases",
+			"code":  "FETCH_FAILED",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":  aliases,
+		"total": len(aliases),
+	})
+}
+
+// SetAlias handles PUT /api/v1/admin/model-aliases/:alias, creating the
+// alias or repointing it at a new model
+func (h *ModelAliasHandler) SetAlias(c *gin.Context) {
+	var req models.ModelAliasRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+			"code":  "INVALID_REQUEST",
+		})
+		return
+	}
+
+	alias, err := h.service.SetAlias(c.Param("alias"), c.GetString("user_id"), &req)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidModelAlias) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+				"code":  "INVALID_REQUEST",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "failed to save model alias",
+			"code":  "UPDATE_FAILED",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, alias)
+}
+
+// DeleteAlias handles DELETE /api/v1/admin/model-aliases/:alias
+func (h *ModelAliasHandler) DeleteAlias(c *gin.Context) {
+	if err := h.service.DeleteAlias(c.Param("alias")); err != nil {
+		if errors.Is(err, services.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "model alias not found",
+				"code":  "NOT_FOUND",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "failed to delete model alias",
+			"code":  "DELETE_FAILED",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "model alias deleted successfully"})
+}
@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"lio-ai/internal/models"
+	"lio-ai/internal/services"
+)
+
+// ModelDeprecationHandler handles the admin model deprecation workflow
+type ModelDeprecationHandler struct {
+	service *services.ModelDeprecationService
+}
+
+// NewModelDeprecationHandler creates a new model deprecation handler
+func NewModelDeprecationHandler(service *services.ModelDeprecationService) *ModelDeprecationHandler {
+	return &ModelDeprecationHandler{service: service}
+}
+
+// ListDeprecations handles GET /api/v1/admin/model-deprecations
+func (h *ModelDeprecationHandler) ListDeprecations(c *gin.Context) {
+	deprecations, err := h.service.ListDeprecations()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "failed to fetch model deprecations",
+			"code":  "FETCH_FAILED",
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": deprecations, "total": len(deprecations)})
+}
+
+// Deprecate handles PUT /api/v1/admin/model-deprecations/*model. Model
+// names may contain slashes (e.g. ollama/llama3).
+func (h *ModelDeprecationHandler) Deprecate(c *gin.Context) {
+	var req models.DeprecateModelRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+			"code":  "INVALID_REQUEST",
+		})
+		return
+	}
+
+	d, err := h.service.Deprecate(deprecationModel(c), c.GetString("user_id"), &req)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidDeprecation):
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+				"code":  "INVALID_DEPRECATION",
+			})
+		case errors.Is(err, services.ErrNotFound):
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": err.Error(),
+				"code":  "NOT_FOUND",
+			})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "failed to deprecate model",
+				"code":  "UPDATE_FAILED",
+			})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, d)
+}
+
+// Undeprecate handles DELETE /api/v1/admin/model-deprecations/*model
+func (h *ModelDeprecationHandler) Undeprecate(c *gin.Context) {
+	if err := h.service.Undeprecate(deprecationModel(c)); err != nil {
+		if errors.Is(err, services.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": err.Error(),
+				"code":  "NOT_FOUND",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "failed to clear model deprecation",
+			"code":  "DELETE_FAILED",
+		})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func deprecationModel(c *gin.Context) string {
+	return strings.TrimPrefix(c.Param("model"), "/")
+}
+
+// setDeprecationHeaders reports a deprecated model with the Deprecation
+// (RFC 9745) and Sunset (RFC 8594) headers, plus a Warning for clients
+// that only log those
+func setDeprecationHeaders(c *gin.Context, notice *models.ModelDeprecationNotice) {
+	if notice == nil {
+		return
+	}
+	c.Header("Deprecation", fmt.Sprintf("@%d", notice.DeprecatedAt.Unix()))
+	c.Header("Sunset", notice.SunsetAt.UTC().Format(http.TimeFormat))
+
+	warning := fmt.Sprintf("model %s is deprecated and will be retired on %s", notice.Model, notice.SunsetAt.UTC().Format("2006-01-02"))
+	if notice.Replaced {
+		warning = fmt.Sprintf("model %s was retired on %s; %s answered instead", notice.Model, notice.SunsetAt.UTC().Format("2006-01-02"), notice.ReplacementModel)
+	}
+	c.Header("Warning", fmt.Sprintf("299 lio-ai %q", warning))
+}
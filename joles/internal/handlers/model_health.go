@@ -0,0 +1,25 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetModelHealth handles GET /api/v1/admin/model-health: each model's
+// rolling latency and health, and the candidate each routed model uses now
+func (h *ChatHandler) GetModelHealth(c *gin.Context) {
+	health := h.service.ModelHealth()
+	if health == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error": "model health tracking is not enabled",
+			"code":  "MODEL_HEALTH_DISABLED",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"models": health.Status(),
+		"routes": h.service.RouteStatuses(),
+	})
+}
@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"lio-ai/internal/services"
+)
+
+// NotificationHandler handles the user's notification center
+type NotificationHandler struct {
+	service *services.NotificationService
+}
+
+// NewNotificationHandler creates a new notification handler
+func NewNotificationHandler(service *services.NotificationService) *NotificationHandler {
+	return &NotificationHandler{service: service}
+}
+
+// ListNotifications handles GET /api/v1/notifications, newest first;
+// unread=true leaves out notifications already read
+func (h *NotificationHandler) ListNotifications(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if limit > 100 {
+		limit = 100
+	}
+	if limit < 1 {
+		limit = 20
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	notifications, unread, err := h.service.List(c.GetString("user_id"), c.Query("unread") == "true", limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "failed to fetch notifications",
+			"code":  "FETCH_FAILED",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":   notifications,
+		"unread": unread,
+		"limit":  limit,
+		"offset": offset,
+	})
+}
+
+// MarkRead handles POST /api/v1/notifications/:id/read
+func (h *NotificationHandler) MarkRead(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "invalid notification id",
+			"code":  "INVALID_ID",
+		})
+		return
+	}
+
+	if err := h.service.MarkRead(id, c.GetString("user_id")); err != nil {
+		if errors.Is(err, services.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "notification not found",
+				"code":  "NOT_FOUND",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "failed to update notification",
+			"code":  "UPDATE_FAILED",
+		})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// MarkAllRead handles POST /api/v1/notifications/read-all
+func (h *NotificationHandler) MarkAllRead(c *gin.Context) {
+	marked, err := h.service.MarkAllRead(c.GetString("user_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "failed to update notifications",
+			"code":  "UPDATE_FAILED",
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"marked": marked})
+}
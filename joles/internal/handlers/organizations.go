@@ -0,0 +1,210 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"lio-ai/internal/middleware"
+	"lio-ai/internal/models"
+	"lio-ai/internal/services"
+)
+
+// OrganizationHandler handles organizations, their members and the quotas
+// and budgets they share
+type OrganizationHandler struct {
+	service      *services.OrganizationService
+	usageService *services.UsageService
+}
+
+// NewOrganizationHandler creates a new organization handler
+func NewOrganizationHandler(service *services.OrganizationService, usageService *services.UsageService) *OrganizationHandler {
+	return &OrganizationHandler{service: service, usageService: usageService}
+}
+
+// CreateOrganization handles POST /api/v1/orgs; the caller becomes its
+// owner
+func (h *OrganizationHandler) CreateOrganization(c *gin.Context) {
+	var req models.CreateOrganizationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "code": "INVALID_REQUEST"})
+		return
+	}
+
+	org, err := h.service.Create(c.GetString("user_id"), &req)
+	if err != nil {
+		respondOrganizationError(c, err, "failed to create organization")
+		return
+	}
+	c.JSON(http.StatusCreated, org)
+}
+
+// GetCurrentOrganization handles GET /api/v1/orgs/current: the caller's
+// organization with its members, quota and budget
+func (h *OrganizationHandler) GetCurrentOrganization(c *gin.Context) {
+	org, err := h.service.GetForUser(c.GetString("user_id"))
+	if err != nil {
+		respondOrganizationError(c, err, "failed to fetch organization")
+		return
+	}
+	if org == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "you do not belong to an organization", "code": models.ErrCodeNotFound})
+		return
+	}
+	c.JSON(http.StatusOK, org)
+}
+
+// GetOrganization handles GET /api/v1/orgs/:id
+func (h *OrganizationHandler) GetOrganization(c *gin.Context) {
+	id, ok := organizationID(c)
+	if !ok {
+		return
+	}
+	org, err := h.service.Get(id, c.GetString("user_id"), middleware.HasRole(c, "admin"))
+	if err != nil {
+		respondOrganizationError(c, err, "failed to fetch organization")
+		return
+	}
+	c.JSON(http.StatusOK, org)
+}
+
+// DeleteOrganization handles DELETE /api/v1/orgs/:id
+func (h *OrganizationHandler) DeleteOrganization(c *gin.Context) {
+	id, ok := organizationID(c)
+	if !ok {
+		return
+	}
+	if err := h.service.Delete(id, c.GetString("user_id"), middleware.HasRole(c, "admin")); err != nil {
+		respondOrganizationError(c, err, "failed to delete organization")
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// AddMember handles POST /api/v1/orgs/:id/members
+func (h *OrganizationHandler) AddMember(c *gin.Context) {
+	id, ok := organizationID(c)
+	if !ok {
+		return
+	}
+	var req models.AddOrganizationMemberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "code": "INVALID_REQUEST"})
+		return
+	}
+
+	member, err := h.service.AddMember(id, c.GetString("user_id"), middleware.HasRole(c, "admin"), &req)
+	if err != nil {
+		respondOrganizationError(c, err, "failed to add member")
+		return
+	}
+	c.JSON(http.StatusCreated, member)
+}
+
+// RemoveMember handles DELETE /api/v1/orgs/:id/members/:user_id; members
+// can remove themselves
+func (h *OrganizationHandler) RemoveMember(c *gin.Context) {
+	id, ok := organizationID(c)
+	if !ok {
+		return
+	}
+	if err := h.service.RemoveMember(id, c.GetString("user_id"), middleware.HasRole(c, "admin"), c.Param("user_id")); err != nil {
+		respondOrganizationError(c, err, "failed to remove member")
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// SetQuota handles PUT /api/v1/admin/orgs/:id/quota
+func (h *OrganizationHandler) SetQuota(c *gin.Context) {
+	id, ok := organizationID(c)
+	if !ok {
+		return
+	}
+	var req models.QuotaUpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "code": "INVALID_REQUEST"})
+		return
+	}
+
+	status, err := h.usageService.SetOrgQuota(id, &req)
+	if err != nil {
+		respondOrganizationError(c, err, "failed to update organization quota")
+		return
+	}
+	c.JSON(http.StatusOK, status)
+}
+
+// DeleteQuota handles DELETE /api/v1/admin/orgs/:id/quota
+func (h *OrganizationHandler) DeleteQuota(c *gin.Context) {
+	id, ok := organizationID(c)
+	if !ok {
+		return
+	}
+	if err := h.usageService.DeleteOrgQuota(id); err != nil {
+		respondOrganizationError(c, err, "failed to delete organization quota")
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// SetBudget handles PUT /api/v1/admin/orgs/:id/budget
+func (h *OrganizationHandler) SetBudget(c *gin.Context) {
+	id, ok := organizationID(c)
+	if !ok {
+		return
+	}
+	var req models.SetBudgetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "code": "INVALID_REQUEST"})
+		return
+	}
+
+	status, err := h.usageService.SetOrgBudget(id, &req, c.GetString("user_id"))
+	if err != nil {
+		respondBudgetError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, status)
+}
+
+// DeleteBudget handles DELETE /api/v1/admin/orgs/:id/budget
+func (h *OrganizationHandler) DeleteBudget(c *gin.Context) {
+	id, ok := organizationID(c)
+	if !ok {
+		return
+	}
+	if err := h.usageService.DeleteOrgBudget(id); err != nil {
+		respondBudgetError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func organizationID(c *gin.Context) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "invalid organization id",
+			"code":  "INVALID_ID",
+		})
+		return 0, false
+	}
+	return id, true
+}
+
+func respondOrganizationError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error(), "code": models.ErrCodeNotFound})
+	case errors.Is(err, services.ErrUnauthorized):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error(), "code": "FORBIDDEN"})
+	case errors.Is(err, services.ErrInvalidMessage):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "code": "INVALID_REQUEST"})
+	case errors.Is(err, services.ErrAlreadyInOrganization):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "code": "ALREADY_IN_ORGANIZATION"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message, "code": "INTERNAL_ERROR"})
+	}
+}
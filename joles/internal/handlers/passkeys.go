@@ -0,0 +1,182 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"lio-ai/internal/audit"
+	"lio-ai/internal/auth"
+	"lio-ai/internal/models"
+	"lio-ai/internal/repositories"
+	"lio-ai/internal/services"
+)
+
+// BeginPasskeyRegistration handles POST /api/v1/auth/passkeys/register/begin
+func (h *AuthHandler) BeginPasskeyRegistration(c *gin.Context) {
+	userID, ok := sessionUserID(c)
+	if !ok {
+		return
+	}
+
+	options, err := h.passkeys.BeginRegistration(userID)
+	if err != nil {
+		if errors.Is(err, services.ErrUserNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "user not found", "code": "USER_NOT_FOUND"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error(), "code": "INTERNAL_ERROR"})
+		return
+	}
+	c.JSON(http.StatusOK, options)
+}
+
+// FinishPasskeyRegistration handles POST /api/v1/auth/passkeys/register/finish
+func (h *AuthHandler) FinishPasskeyRegistration(c *gin.Context) {
+	userID, ok := sessionUserID(c)
+	if !ok {
+		return
+	}
+
+	var req models.PasskeyRegistrationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request: " + err.Error(), "code": "INVALID_REQUEST"})
+		return
+	}
+
+	passkey, err := h.passkeys.FinishRegistration(userID, &req)
+	if err != nil {
+		h.auditEvent(c, "auth.passkey_register", audit.OutcomeFailure, fmt.Sprint(userID), c.GetString("email"), map[string]interface{}{"reason": err.Error()})
+		switch {
+		case errors.Is(err, services.ErrPasskeyChallenge), errors.Is(err, auth.ErrPasskeyInvalid):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "code": "PASSKEY_INVALID"})
+		case errors.Is(err, repositories.ErrPasskeyExists):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "code": "PASSKEY_EXISTS"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error(), "code": "INTERNAL_ERROR"})
+		}
+		return
+	}
+
+	log.Printf("[AUDIT] Passkey registered: user %d (passkey %d)", userID, passkey.ID)
+	h.auditEvent(c, "auth.passkey_register", audit.OutcomeSuccess, fmt.Sprint(userID), c.GetString("email"), map[string]interface{}{"passkey_id": passkey.ID})
+	c.JSON(http.StatusCreated, passkey)
+}
+
+// BeginPasskeyLogin handles POST /api/v1/auth/passkeys/login/begin
+func (h *AuthHandler) BeginPasskeyLogin(c *gin.Context) {
+	var req models.PasskeyLoginBeginRequest
+	// The body is optional; without an email the login is discoverable
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request", "code": "INVALID_REQUEST"})
+			return
+		}
+	}
+
+	options, err := h.passkeys.BeginLogin(req.Email)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error(), "code": "INTERNAL_ERROR"})
+		return
+	}
+	c.JSON(http.StatusOK, options)
+}
+
+// FinishPasskeyLogin handles POST /api/v1/auth/passkeys/login/finish,
+// starting the same session as a password login
+func (h *AuthHandler) FinishPasskeyLogin(c *gin.Context) {
+	var req models.PasskeyLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request", "code": "INVALID_REQUEST"})
+		return
+	}
+
+	user, err := h.passkeys.FinishLogin(&req)
+	if err != nil {
+		log.Printf("[AUDIT] Passkey login failed: %v (IP: %s)", err, c.ClientIP())
+		h.auditEvent(c, "auth.login", audit.OutcomeFailure, "", "", map[string]interface{}{"method": "passkey", "reason": err.Error()})
+
+		if errors.Is(err, services.ErrPasskeyChallenge) || errors.Is(err, auth.ErrPasskeyInvalid) || errors.Is(err, services.ErrUserInactive) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "authentication failed", "code": "INVALID_CREDENTIALS"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "authentication failed", "code": "INTERNAL_ERROR"})
+		return
+	}
+
+	token, err := h.userService.GenerateTokenForUser(user, sessionClient(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "login failed", "code": "TOKEN_GENERATION_FAILED"})
+		return
+	}
+
+	log.Printf("[AUDIT] Login successful: %s (ID: %d, IP: %s, passkey)", user.Email, user.ID, c.ClientIP())
+	h.auditEvent(c, "auth.login", audit.OutcomeSuccess, fmt.Sprint(user.ID), user.Email, map[string]interface{}{"method": "passkey"})
+
+	setAuthCookie(c, token)
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Login successful",
+		"token":   token,
+		"user": gin.H{
+			"id":       user.ID,
+			"username": user.Username,
+			"email":    user.Email,
+			"name":     user.FullName,
+			"role":     user.Role,
+		},
+	})
+}
+
+// ListPasskeys handles GET /api/v1/auth/passkeys
+func (h *AuthHandler) ListPasskeys(c *gin.Context) {
+	userID, ok := sessionUserID(c)
+	if !ok {
+		return
+	}
+
+	passkeys, err := h.passkeys.ListPasskeys(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error(), "code": "INTERNAL_ERROR"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": passkeys, "total": len(passkeys)})
+}
+
+// DeletePasskey handles DELETE /api/v1/auth/passkeys/:id
+func (h *AuthHandler) DeletePasskey(c *gin.Context) {
+	userID, ok := sessionUserID(c)
+	if !ok {
+		return
+	}
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid passkey id", "code": "INVALID_REQUEST"})
+		return
+	}
+
+	if err := h.passkeys.DeletePasskey(userID, id); err != nil {
+		if errors.Is(err, services.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error(), "code": "NOT_FOUND"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error(), "code": "INTERNAL_ERROR"})
+		return
+	}
+
+	h.auditEvent(c, "auth.passkey_delete", audit.OutcomeSuccess, fmt.Sprint(userID), c.GetString("email"), map[string]interface{}{"passkey_id": id})
+	c.Status(http.StatusNoContent)
+}
+
+// sessionUserID returns the numeric ID of the logged-in account, writing
+// an error when the session is not a user's (guests have no account)
+func sessionUserID(c *gin.Context) (int64, bool) {
+	userID, err := strconv.ParseInt(c.GetString("user_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized", "code": "UNAUTHORIZED"})
+		return 0, false
+	}
+	return userID, true
+}
@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"lio-ai/internal/models"
+	"lio-ai/internal/services"
+)
+
+// PersonaHandler handles HTTP requests for assistant personas
+type PersonaHandler struct {
+	service *services.PersonaService
+}
+
+// NewPersonaHandler creates a new persona handler
+func NewPersonaHandler(service *services.PersonaService) *PersonaHandler {
+	return &PersonaHandler{service: service}
+}
+
+// CreatePersona handles POST /api/v1/personas
+func (h *PersonaHandler) CreatePersona(c *gin.Context) {
+	var req models.PersonaRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+			"code":  "INVALID_REQUEST",
+		})
+		return
+	}
+
+	p, err := h.service.CreatePersona(c.GetString("user_id"), &req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "failed to create persona",
+			"code":  "CREATE_FAILED",
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, p)
+}
+
+// GetPersonas handles GET /api/v1/personas
+func (h *PersonaHandler) GetPersonas(c *gin.Context) {
+	personas, err := h.service.ListPersonas(c.GetString("user_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "failed to fetch personas",
+			"code":  "FETCH_FAILED",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":  personas,
+		"total": len(personas),
+	})
+}
+
+// GetPersona handles GET /api/v1/personas/:id
+func (h *PersonaHandler) GetPersona(c *gin.Context) {
+	id, ok := personaID(c)
+	if !ok {
+		return
+	}
+
+	p, err := h.service.GetPersona(id, c.GetString("user_id"))
+	if err != nil {
+		respondPersonaError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, p)
+}
+
+// UpdatePersona handles PUT /api/v1/personas/:id
+func (h *PersonaHandler) UpdatePersona(c *gin.Context) {
+	id, ok := personaID(c)
+	if !ok {
+		return
+	}
+
+	var req models.UpdatePersonaRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+			"code":  "INVALID_REQUEST",
+		})
+		return
+	}
+
+	p, err := h.service.UpdatePersona(id, c.GetString("user_id"), &req)
+	if err != nil {
+		respondPersonaError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, p)
+}
+
+// DeletePersona handles DELETE /api/v1/personas/:id
+func (h *PersonaHandler) DeletePersona(c *gin.Context) {
+	id, ok := personaID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeletePersona(id, c.GetString("user_id")); err != nil {
+		respondPersonaError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "persona deleted successfully"})
+}
+
+// personaID parses the :id parameter, writing a 400 when it is invalid
+func personaID(c *gin.Context) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "invalid persona id",
+			"code":  "INVALID_ID",
+		})
+		return 0, false
+	}
+	return id, true
+}
+
+// respondPersonaError maps persona service errors to HTTP responses
+func respondPersonaError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrUnauthorized):
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "access denied",
+			"code":  "FORBIDDEN",
+		})
+	case errors.Is(err, services.ErrPersonaNotFound):
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "persona not found",
+			"code":  "NOT_FOUND",
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "persona request failed",
+			"code":  "PERSONA_FAILED",
+		})
+	}
+}
@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"lio-ai/internal/models"
+	"lio-ai/internal/services"
+)
+
+// PlanHandler handles plan tiers and moving users between them
+type PlanHandler struct {
+	service *services.PlanService
+}
+
+// NewPlanHandler creates a new plan handler
+func NewPlanHandler(service *services.PlanService) *PlanHandler {
+	return &PlanHandler{service: service}
+}
+
+// ListPlans handles GET /api/v1/plans
+func (h *PlanHandler) ListPlans(c *gin.Context) {
+	plans, err := h.service.List()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch plans", "code": "FETCH_FAILED"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"data":  plans,
+		"total": len(plans),
+	})
+}
+
+// SetPlan handles PUT /api/v1/admin/plans/:name, creating the plan or
+// changing its limits
+func (h *PlanHandler) SetPlan(c *gin.Context) {
+	var req models.SetPlanRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "code": "INVALID_REQUEST"})
+		return
+	}
+
+	plan, updated, err := h.service.Set(c.Param("name"), &req)
+	if err != nil {
+		respondPlanError(c, err, "failed to save plan")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"plan":          plan,
+		"users_updated": updated,
+	})
+}
+
+// AssignPlan handles PUT /api/v1/admin/users/:id/plan: the user moves to
+// the plan and their quota limits become its own
+func (h *PlanHandler) AssignPlan(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id", "code": "INVALID_ID"})
+		return
+	}
+	var req models.AssignPlanRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "code": "INVALID_REQUEST"})
+		return
+	}
+
+	status, err := h.service.AssignUser(id, req.Plan)
+	if err != nil {
+		respondPlanError(c, err, "failed to change plan")
+		return
+	}
+	c.JSON(http.StatusOK, status)
+}
+
+func respondPlanError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrInvalidMessage):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "code": "INVALID_REQUEST"})
+	case errors.Is(err, services.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error(), "code": models.ErrCodeNotFound})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message, "code": "INTERNAL_ERROR"})
+	}
+}
@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"lio-ai/internal/models"
+	"lio-ai/internal/services"
+)
+
+// PreferencesHandler handles users' account-wide settings
+type PreferencesHandler struct {
+	service *services.PreferencesService
+}
+
+// NewPreferencesHandler creates a new preferences handler
+func NewPreferencesHandler(service *services.PreferencesService) *PreferencesHandler {
+	return &PreferencesHandler{service: service}
+}
+
+// GetPreferences handles GET /api/v1/preferences
+func (h *PreferencesHandler) GetPreferences(c *gin.Context) {
+	prefs, err := h.service.Get(c.GetString("user_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "failed to fetch preferences",
+			"code":  "FETCH_FAILED",
+		})
+		return
+	}
+	c.JSON(http.StatusOK, prefs)
+}
+
+// UpdatePreferences handles PUT /api/v1/preferences; settings left out of
+// the request are unchanged
+func (h *PreferencesHandler) UpdatePreferences(c *gin.Context) {
+	var req models.UpdatePreferencesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+			"code":  "INVALID_REQUEST",
+		})
+		return
+	}
+
+	prefs, err := h.service.Update(c.GetString("user_id"), &req)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidPreferences) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+				"code":  "INVALID_REQUEST",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "failed to save preferences",
+			"code":  "UPDATE_FAILED",
+		})
+		return
+	}
+	c.JSON(http.StatusOK, prefs)
+}
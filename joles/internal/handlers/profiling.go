@@ -0,0 +1,26 @@
+package handlers
+
+import (
+	"net/http/pprof"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterProfilingRoutes mounts the net/http/pprof handlers under
+// rg + "/debug/pprof". The group must already enforce admin auth.
+func RegisterProfilingRoutes(rg *gin.RouterGroup) {
+	debug := rg.Group("/debug/pprof")
+	{
+		debug.GET("/", gin.WrapF(pprof.Index))
+		debug.GET("/cmdline", gin.WrapF(pprof.Cmdline))
+		debug.GET("/profile", gin.WrapF(pprof.Profile))
+		debug.GET("/symbol", gin.WrapF(pprof.Symbol))
+		debug.POST("/symbol", gin.WrapF(pprof.Symbol))
+		debug.GET("/trace", gin.WrapF(pprof.Trace))
+
+		// Named profiles: heap, goroutine, allocs, block, mutex, threadcreate
+		debug.GET("/:profile", func(c *gin.Context) {
+			pprof.Handler(c.Param("profile")).ServeHTTP(c.Writer, c.Request)
+		})
+	}
+}
@@ -1,25 +1,29 @@
 package handlers
 
 import (
-	"bytes"
 	"encoding/json"
-	"log"
 	"net/http"
-	"os"
 
 	"github.com/gin-gonic/gin"
 	"lio-ai/internal/models"
 	"lio-ai/internal/repositories"
+	"lio-ai/internal/services"
 )
 
 // ProviderKeyHandler handles provider API key operations
 type ProviderKeyHandler struct {
-	repo *repositories.ProviderKeyRepository
+	repo         *repositories.ProviderKeyRepository
+	usageService *services.UsageService
+	keySync      *services.KeySyncService
 }
 
 // NewProviderKeyHandler creates a new provider key handler
-func NewProviderKeyHandler(repo *repositories.ProviderKeyRepository) *ProviderKeyHandler {
-	return &ProviderKeyHandler{repo: repo}
+func NewProviderKeyHandler(repo *repositories.ProviderKeyRepository, usageService *services.UsageService, keySync *services.KeySyncService) *ProviderKeyHandler {
+	return &ProviderKeyHandler{
+		repo:         repo,
+		usageService: usageService,
+		keySync:      keySync,
+	}
 }
 
 // GetAllKeys gets all provider API keys for the current user
@@ -97,7 +101,7 @@ func (h *ProviderKeyHandler) CreateOrUpdateKey(c *gin.Context) {
 	}
 
 	// Notify Python backend to reload models with new API keys
-	go h.syncAPIKeysToBackend(userID.(string))
+	h.keySync.Sync(userID.(string))
 
 	c.JSON(http.StatusOK, gin.H{
 		"message":  "API key saved successfully",
@@ -105,62 +109,6 @@ func (h *ProviderKeyHandler) CreateOrUpdateKey(c *gin.Context) {
 	})
 }
 
-// syncAPIKeysToBackend sends all user's API keys to Python backend
-func (h *ProviderKeyHandler) syncAPIKeysToBackend(userID string) {
-	backendURL := os.Getenv("BACKEND_URL")
-	if backendURL == "" {
-		backendURL = "http://localhost:8000"
-	}
-
-	// Fetch all active API keys for this user
-	keyResponses, err := h.repo.GetAllByUser(userID)
-	if err != nil {
-		log.Printf("Failed to fetch API keys for sync: %v", err)
-		return
-	}
-
-	// Build API keys map - need to fetch decrypted keys
-	apiKeys := make(map[string]string)
-	for _, keyResp := range keyResponses {
-		if keyResp.IsActive {
-			// Fetch the actual decrypted key
-			fullKey, err := h.repo.GetByUserAndProvider(userID, keyResp.Provider)
-			if err != nil {
-				log.Printf("Failed to fetch key for %s: %v", keyResp.Provider, err)
-				continue
-			}
-			if fullKey != nil {
-				apiKeys[fullKey.Provider] = fullKey.APIKey
-			}
-		}
-	}
-
-	// Send to Python backend
-	payload := map[string]interface{}{
-		"user_id":  userID,
-		"api_keys": apiKeys,
-	}
-
-	jsonData, _ := json.Marshal(payload)
-	resp, err := http.Post(
-		backendURL+"/api/v1/models/sync-keys",
-		"application/json",
-		bytes.NewBuffer(jsonData),
-	)
-
-	if err != nil {
-		log.Printf("Failed to sync API keys to backend: %v", err)
-		return
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode == 200 {
-		log.Printf("✓ API keys synced to Python backend for user %s", userID)
-	} else {
-		log.Printf("Failed to sync API keys: HTTP %d", resp.StatusCode)
-	}
-}
-
 // DeleteKey soft deletes a provider API key
 func (h *ProviderKeyHandler) DeleteKey(c *gin.Context) {
 	// Get authenticated user from JWT token
@@ -186,7 +134,7 @@ func (h *ProviderKeyHandler) DeleteKey(c *gin.Context) {
 	}
 
 	// Sync to Python backend to remove the provider
-	go h.syncAPIKeysToBackend(userID.(string))
+	h.keySync.Sync(userID.(string))
 
 	c.JSON(http.StatusOK, gin.H{
 		"message": "API key deleted successfully",
@@ -287,10 +235,66 @@ func (h *ProviderKeyHandler) SyncAllKeys(c *gin.Context) {
 		return
 	}
 
-	// Trigger sync in background
-	go h.syncAPIKeysToBackend(userID.(string))
+	// Trigger sync in background (queued for retry if the backend is down)
+	h.keySync.Sync(userID.(string))
 
 	c.JSON(http.StatusOK, gin.H{
 		"message": "API keys sync triggered",
 	})
 }
+
+// GetKeyUsage handles GET /api/v1/api-keys/:provider/usage
+func (h *ProviderKeyHandler) GetKeyUsage(c *gin.Context) {
+	// Get authenticated user from JWT token
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "authentication required",
+			"code":  "UNAUTHORIZED",
+		})
+		return
+	}
+
+	provider := c.Param("provider")
+	if provider == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "provider is required"})
+		return
+	}
+
+	key, err := h.repo.GetByUserAndProvider(userID.(string), provider)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch API key"})
+		return
+	}
+
+	usage, err := h.usageService.GetProviderKeyUsage(userID.(string), provider)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch API key usage"})
+		return
+	}
+
+	if key == nil && usage.TotalRequests == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "API key not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"provider": provider,
+		"has_key":  key != nil,
+		"usage":    usage,
+	})
+}
+
+// GetSyncQueue handles GET /api/v1/admin/sync-queue
+func (h *ProviderKeyHandler) GetSyncQueue(c *gin.Context) {
+	pending, err := h.keySync.Pending()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch sync queue"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"pending": pending,
+		"total":   len(pending),
+	})
+}
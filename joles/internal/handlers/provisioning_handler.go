@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"lio-ai/internal/auth"
+	"lio-ai/internal/models"
+	"lio-ai/internal/services"
+)
+
+// maxImportFileBytes bounds the size of an uploaded import file
+const maxImportFileBytes = 5 << 20
+
+// ProvisioningHandler handles bulk user imports and invitation acceptance
+type ProvisioningHandler struct {
+	service *services.ProvisioningService
+}
+
+// NewProvisioningHandler creates a new provisioning handler
+func NewProvisioningHandler(service *services.ProvisioningService) *ProvisioningHandler {
+	return &ProvisioningHandler{service: service}
+}
+
+// ImportUsers handles POST /api/v1/admin/users/import. The CSV is sent as
+// the request body (text/csv) or as the "file" field of a multipart form;
+// rows are provisioned by a background job whose ID is returned.
+func (h *ProvisioningHandler) ImportUsers(c *gin.Context) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxImportFileBytes)
+
+	var src io.Reader = c.Request.Body
+	if strings.HasPrefix(c.ContentType(), "multipart/form-data") {
+		file, err := c.FormFile("file")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "multipart upload must include a file field",
+				"code":  "INVALID_REQUEST",
+			})
+			return
+		}
+		f, err := file.Open()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "failed to read uploaded file",
+				"code":  "INVALID_REQUEST",
+			})
+			return
+		}
+		defer f.Close()
+		src = f
+	}
+
+	rows, err := services.ParseUserCSV(src)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+			"code":  "INVALID_IMPORT",
+		})
+		return
+	}
+
+	job, err := h.service.StartImport(c.GetString("user_id"), "csv", rows)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "failed to start import",
+			"code":  "IMPORT_FAILED",
+		})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, job)
+}
+
+// GetImportJob handles GET /api/v1/admin/users/import/:id
+func (h *ProvisioningHandler) GetImportJob(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "invalid job id",
+			"code":  "INVALID_ID",
+		})
+		return
+	}
+
+	job, err := h.service.GetImportJob(id)
+	if errors.Is(err, services.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "import job not found",
+			"code":  "NOT_FOUND",
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "failed to fetch import job",
+			"code":  "FETCH_FAILED",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, job)
+}
+
+// AcceptInvitation handles POST /api/v1/auth/invitations/accept
+func (h *ProvisioningHandler) AcceptInvitation(c *gin.Context) {
+	var req models.AcceptInvitationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "invalid request",
+			"code":  "INVALID_REQUEST",
+		})
+		return
+	}
+
+	user, err := h.service.AcceptInvitation(req.Token, req.Password)
+	if err != nil {
+		var pwErr *auth.PasswordError
+		switch {
+		case errors.As(err, &pwErr):
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+				"code":  "WEAK_PASSWORD",
+			})
+		case errors.Is(err, services.ErrInvalidInvitation):
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+				"code":  "INVALID_INVITATION",
+			})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "failed to accept invitation",
+				"code":  "INVITATION_FAILED",
+			})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "invitation accepted, you can now log in",
+		"user":    user,
+	})
+}
@@ -5,39 +5,80 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"path"
+	"strings"
 
 	"github.com/gin-gonic/gin"
+	"lio-ai/internal/audit"
+	"lio-ai/internal/services"
 )
 
 // ProxyHandler proxies requests to the Python FastAPI service.
 type ProxyHandler struct {
 	targetURL string
 	client    *http.Client
+	health    *services.BackendHealth
+	policy    *ProxyPolicy
+	audit     *audit.Logger
 }
 
-// NewProxyHandler creates a new proxy handler.
-func NewProxyHandler(targetURL string) *ProxyHandler {
+// NewProxyHandler creates a new proxy handler. Requests fail fast with 503
+// while health reports the backend as down.
+func NewProxyHandler(targetURL string, health *services.BackendHealth) *ProxyHandler {
 	return &ProxyHandler{
 		targetURL: targetURL,
 		client:    &http.Client{},
+		health:    health,
 	}
 }
 
+// SetPolicy restricts the requests proxied to those policy allows; the
+// others get 404, like a route that doesn't exist
+func (ph *ProxyHandler) SetPolicy(policy *ProxyPolicy) {
+	ph.policy = policy
+}
+
+// SetAuditLogger records requests refused by the policy to the audit sinks
+func (ph *ProxyHandler) SetAuditLogger(logger *audit.Logger) {
+	ph.audit = logger
+}
+
 // ProxyRequest proxies an HTTP request to the backend service.
 func (ph *ProxyHandler) ProxyRequest(c *gin.Context) {
-	// Block sensitive endpoints from being proxied
-	blockedPaths := []string{"/docs", "/openapi.json", "/redoc"}
-	for _, blocked := range blockedPaths {
-		if c.Request.URL.Path == blocked {
-			c.JSON(http.StatusNotFound, gin.H{
-				"error": "Not found",
-			})
-			return
-		}
+	// The policy sees the path the backend is sent
+	requestPath := path.Clean("/" + c.Request.URL.Path)
+	if strings.HasSuffix(c.Request.URL.Path, "/") && requestPath != "/" {
+		requestPath += "/"
+	}
+	if allowed, reason := ph.policy.Allows(c.Request.Method, requestPath); !allowed {
+		ph.audit.Log(audit.Event{
+			Type:    "proxy.denied",
+			Outcome: audit.OutcomeDenied,
+			ActorID: c.GetString("user_id"),
+			IP:      c.ClientIP(),
+			Method:  c.Request.Method,
+			Path:    requestPath,
+			Status:  http.StatusNotFound,
+			Details: map[string]interface{}{"reason": reason},
+		})
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Not found",
+		})
+		return
+	}
+
+	// Don't wait on a backend that is known to be down
+	if ph.health != nil && !ph.health.IsAvailable() {
+		c.Header("Retry-After", "5")
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error": "Backend service unavailable",
+			"code":  "BACKEND_UNAVAILABLE",
+		})
+		return
 	}
 
 	// Build target URL - preserve query parameters
-	targetURL := ph.targetURL + c.Request.URL.Path
+	targetURL := ph.targetURL + requestPath
 	
 	// Add user_id from JWT to query parameters if authenticated
 	query := c.Request.URL.Query()
@@ -51,8 +92,10 @@ func (ph *ProxyHandler) ProxyRequest(c *gin.Context) {
 		targetURL += "?" + query.Encode()
 	}
 
-	// Create new request
-	proxyReq, err := http.NewRequest(
+	// Create new request, cancelled along with the inbound one
+	services.MarkUpstream(c.Request.Context(), ph.targetURL+requestPath, "")
+	proxyReq, err := http.NewRequestWithContext(
+		c.Request.Context(),
 		c.Request.Method,
 		targetURL,
 		c.Request.Body,
@@ -76,6 +119,9 @@ func (ph *ProxyHandler) ProxyRequest(c *gin.Context) {
 	resp, err := ph.client.Do(proxyReq)
 	if err != nil {
 		log.Printf("Error proxying request: %v", err)
+		if ph.health != nil {
+			ph.health.MarkDown(err)
+		}
 		c.JSON(http.StatusBadGateway, gin.H{
 			"error": "Failed to reach backend service",
 		})
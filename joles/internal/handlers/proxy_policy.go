@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+)
+
+// proxyRule matches requests by method and path. A rule without methods
+// matches any method; in its pattern, "*" matches one path segment and a
+// final "**" the rest of the path.
+type proxyRule struct {
+	entry    string
+	methods  []string
+	segments []string
+}
+
+// ProxyPolicy decides which requests the proxy forwards to the backend.
+// Deny rules win over allow rules; a path matching neither is forwarded
+// only when the policy isn't default-deny.
+type ProxyPolicy struct {
+	allow       []proxyRule
+	deny        []proxyRule
+	defaultDeny bool
+}
+
+// NewProxyPolicy parses allow and deny rules written as "METHOD[,METHOD]
+// /pattern" or just "/pattern", e.g. "GET,POST /api/v1/models/**"
+func NewProxyPolicy(allow, deny []string, defaultDeny bool) (*ProxyPolicy, error) {
+	p := &ProxyPolicy{defaultDeny: defaultDeny}
+	var err error
+	if p.allow, err = parseProxyRules("PROXY_ALLOW", allow); err != nil {
+		return nil, err
+	}
+	if p.deny, err = parseProxyRules("PROXY_DENY", deny); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Allows reports whether a request may be proxied and, when it may not,
+// why
+func (p *ProxyPolicy) Allows(method, requestPath string) (bool, string) {
+	if p == nil {
+		return true, ""
+	}
+	segments := pathSegments(requestPath)
+	for _, rule := range p.deny {
+		if rule.matches(method, segments) {
+			return false, fmt.Sprintf("denied by %q", rule.entry)
+		}
+	}
+	for _, rule := range p.allow {
+		if rule.matches(method, segments) {
+			return true, ""
+		}
+	}
+	if p.defaultDeny {
+		return false, "no allow rule matches"
+	}
+	return true, ""
+}
+
+func parseProxyRules(name string, entries []string) ([]proxyRule, error) {
+	rules := make([]proxyRule, 0, len(entries))
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		rule := proxyRule{entry: entry}
+		pattern := entry
+		if fields := strings.Fields(entry); len(fields) == 2 {
+			pattern = fields[1]
+			if fields[0] != "*" {
+				for _, method := range strings.Split(fields[0], ",") {
+					if method = strings.ToUpper(strings.TrimSpace(method)); method != "" {
+						rule.methods = append(rule.methods, method)
+					}
+				}
+			}
+		} else if len(fields) != 1 {
+			return nil, fmt.Errorf("invalid %s entry %q: expected [METHOD,...] /path", name, entry)
+		}
+		if !strings.HasPrefix(pattern, "/") {
+			return nil, fmt.Errorf("invalid %s entry %q: the path must start with /", name, entry)
+		}
+		rule.segments = pathSegments(pattern)
+		for i, segment := range rule.segments {
+			if segment == "**" && i != len(rule.segments)-1 {
+				return nil, fmt.Errorf("invalid %s entry %q: ** must end the path", name, entry)
+			}
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+func (r proxyRule) matches(method string, segments []string) bool {
+	if len(r.methods) > 0 && !containsMethod(r.methods, method) {
+		return false
+	}
+	for i, want := range r.segments {
+		if want == "**" {
+			return true
+		}
+		if i >= len(segments) || (want != "*" && want != segments[i]) {
+			return false
+		}
+	}
+	return len(segments) == len(r.segments)
+}
+
+func containsMethod(methods []string, method string) bool {
+	for _, m := range methods {
+		if m == method || (m == http.MethodGet && method == http.MethodHead) {
+			return true
+		}
+	}
+	return false
+}
+
+// pathSegments splits a cleaned path, so "/a//b/../c" is matched as "/a/c"
+func pathSegments(p string) []string {
+	p = strings.Trim(path.Clean("/"+p), "/")
+	if p == "" {
+		return []string{}
+	}
+	return strings.Split(p, "/")
+}
@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"lio-ai/internal/models"
+	"lio-ai/internal/services"
+)
+
+// ListQuotaAlerts lists the authenticated user's quota alerts, newest first
+// GET /api/v1/usage/alerts
+func (h *UsageHandler) ListQuotaAlerts(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	alerts, total, err := h.usageService.ListQuotaAlerts(c.GetString("user_id"), limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch quota alerts", "code": "FETCH_FAILED"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":   alerts,
+		"total":  total,
+		"limit":  limit,
+		"offset": offset,
+	})
+}
+
+// GetQuotaAlertSettings returns the thresholds and channels the
+// authenticated user is alerted at and by
+// GET /api/v1/usage/alerts/settings
+func (h *UsageHandler) GetQuotaAlertSettings(c *gin.Context) {
+	settings, err := h.usageService.GetQuotaAlertSettings(c.GetString("user_id"))
+	if err != nil {
+		respondQuotaAlertError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, settings)
+}
+
+// UpdateQuotaAlertSettings changes the authenticated user's alert
+// thresholds or channels
+// PUT /api/v1/usage/alerts/settings
+func (h *UsageHandler) UpdateQuotaAlertSettings(c *gin.Context) {
+	var req models.UpdateQuotaAlertSettingsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "code": "INVALID_REQUEST"})
+		return
+	}
+
+	settings, err := h.usageService.UpdateQuotaAlertSettings(c.GetString("user_id"), &req)
+	if err != nil {
+		respondQuotaAlertError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, settings)
+}
+
+func respondQuotaAlertError(c *gin.Context, err error) {
+	if errors.Is(err, services.ErrInvalidMessage) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "code": "INVALID_REQUEST"})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update quota alert settings", "code": "UPDATE_FAILED"})
+}
@@ -0,0 +1,126 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"lio-ai/internal/models"
+	"lio-ai/internal/services"
+)
+
+// ResponsePluginHandler handles configuration of response post-processing
+type ResponsePluginHandler struct {
+	service  *services.ResponsePluginService
+	personas *services.PersonaService
+}
+
+// NewResponsePluginHandler creates a new response plugin handler
+func NewResponsePluginHandler(service *services.ResponsePluginService, personas *services.PersonaService) *ResponsePluginHandler {
+	return &ResponsePluginHandler{service: service, personas: personas}
+}
+
+// ListPlugins handles GET /api/v1/response-plugins: the available plugins
+// and the user's configured chains
+func (h *ResponsePluginHandler) ListPlugins(c *gin.Context) {
+	settings, err := h.service.ListSettings(c.GetString("user_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "failed to fetch response plugins",
+			"code":  "FETCH_FAILED",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"available":  h.service.Available(),
+		"configured": settings,
+	})
+}
+
+// SetDefault handles PUT /api/v1/response-plugins, the chain applied to
+// the user's chats unless their persona has its own
+func (h *ResponsePluginHandler) SetDefault(c *gin.Context) {
+	h.set(c, 0)
+}
+
+// SetPersona handles PUT /api/v1/personas/:id/response-plugins
+func (h *ResponsePluginHandler) SetPersona(c *gin.Context) {
+	id, ok := personaID(c)
+	if !ok {
+		return
+	}
+	if _, err := h.personas.GetPersona(id, c.GetString("user_id")); err != nil {
+		respondPersonaError(c, err)
+		return
+	}
+	h.set(c, id)
+}
+
+// DeletePersona handles DELETE /api/v1/personas/:id/response-plugins; the
+// persona's chats fall back to the user's default chain
+func (h *ResponsePluginHandler) DeletePersona(c *gin.Context) {
+	id, ok := personaID(c)
+	if !ok {
+		return
+	}
+	userID := c.GetString("user_id")
+	if _, err := h.personas.GetPersona(id, userID); err != nil {
+		respondPersonaError(c, err)
+		return
+	}
+
+	if err := h.service.DeleteSettings(userID, id); err != nil {
+		if errors.Is(err, services.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": err.Error(),
+				"code":  "NOT_FOUND",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "failed to delete response plugins",
+			"code":  "DELETE_FAILED",
+		})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// GetStats handles GET /api/v1/admin/response-plugins/stats, per-plugin
+// run counts and timings on this instance
+func (h *ResponsePluginHandler) GetStats(c *gin.Context) {
+	stats := h.service.Stats()
+	c.JSON(http.StatusOK, gin.H{
+		"data":  stats,
+		"total": len(stats),
+	})
+}
+
+func (h *ResponsePluginHandler) set(c *gin.Context, personaID int64) {
+	var req models.SetResponsePluginsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+			"code":  "INVALID_REQUEST",
+		})
+		return
+	}
+
+	settings, err := h.service.SetSettings(c.GetString("user_id"), personaID, req.Plugins)
+	if err != nil {
+		if errors.Is(err, services.ErrUnknownResponsePlugin) || errors.Is(err, services.ErrInvalidPluginOptions) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+				"code":  "INVALID_PLUGIN",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "failed to save response plugins",
+			"code":  "UPDATE_FAILED",
+		})
+		return
+	}
+	c.JSON(http.StatusOK, settings)
+}
@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"lio-ai/internal/services"
+)
+
+// RetentionHandler handles log retention and GDPR erasure endpoints
+type RetentionHandler struct {
+	service *services.RetentionService
+}
+
+// NewRetentionHandler creates a new retention handler
+func NewRetentionHandler(service *services.RetentionService) *RetentionHandler {
+	return &RetentionHandler{service: service}
+}
+
+// PurgeUser handles POST /api/v1/admin/users/:id/purge, erasing a user's
+// message contents and logged request bodies. The response reports what
+// was removed.
+func (h *RetentionHandler) PurgeUser(c *gin.Context) {
+	report, err := h.service.PurgeUser(c.Param("id"))
+	if err != nil {
+		if errors.Is(err, services.ErrUserNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "user not found",
+				"code":  "NOT_FOUND",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "failed to purge user content",
+			"code":  "INTERNAL_ERROR",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// EnforceRetention handles POST /api/v1/admin/retention/run, applying the
+// configured log retention periods now instead of waiting for the next pass
+func (h *RetentionHandler) EnforceRetention(c *gin.Context) {
+	report, err := h.service.Enforce()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "failed to enforce log retention",
+			"code":  "INTERNAL_ERROR",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"lio-ai/internal/models"
+	"lio-ai/internal/services"
+)
+
+// scheduleMessage queues a message sent with scheduled_at instead of
+// storing it immediately
+func (h *ChatHandler) scheduleMessage(c *gin.Context, chatID int64, req *models.MessageRequest) {
+	if h.scheduler == nil {
+		c.JSON(http.StatusNotImplemented, gin.H{
+			"error": "scheduled messages are not enabled",
+			"code":  "SCHEDULING_DISABLED",
+		})
+		return
+	}
+
+	scheduled, err := h.scheduler.Schedule(chatID, c.GetString("user_id"), req)
+	if err != nil {
+		respondScheduleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, scheduled)
+}
+
+// GetScheduledMessages handles GET /api/v1/chats/:id/scheduled
+func (h *ChatHandler) GetScheduledMessages(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "invalid chat id",
+			"code":  "INVALID_ID",
+		})
+		return
+	}
+	if h.scheduler == nil {
+		c.JSON(http.StatusOK, gin.H{"data": []models.ScheduledMessage{}, "total": 0})
+		return
+	}
+
+	scheduled, err := h.scheduler.List(id, c.GetString("user_id"))
+	if err != nil {
+		respondScheduleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":  scheduled,
+		"total": len(scheduled),
+	})
+}
+
+// CancelScheduledMessage handles DELETE /api/v1/chats/:id/scheduled/:schedule_id
+func (h *ChatHandler) CancelScheduledMessage(c *gin.Context) {
+	chatID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "invalid chat id",
+			"code":  "INVALID_ID",
+		})
+		return
+	}
+	scheduleID, err := strconv.ParseInt(c.Param("schedule_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "invalid schedule id",
+			"code":  "INVALID_ID",
+		})
+		return
+	}
+	if h.scheduler == nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "scheduled message not found",
+			"code":  "NOT_FOUND",
+		})
+		return
+	}
+
+	if err := h.scheduler.Cancel(chatID, scheduleID, c.GetString("user_id")); err != nil {
+		respondScheduleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "scheduled message cancelled"})
+}
+
+// respondScheduleError maps scheduler errors to HTTP responses
+func respondScheduleError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrInvalidMessage), errors.Is(err, services.ErrInvalidSchedule):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+			"code":  "INVALID_REQUEST",
+		})
+	case errors.Is(err, services.ErrScheduleNotPending):
+		c.JSON(http.StatusConflict, gin.H{
+			"error": err.Error(),
+			"code":  "NOT_PENDING",
+		})
+	default:
+		respondChatAccessError(c, err)
+	}
+}
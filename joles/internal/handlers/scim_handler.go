@@ -0,0 +1,360 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"lio-ai/internal/models"
+	"lio-ai/internal/services"
+)
+
+// SCIM 2.0 schema URNs (RFC 7643, RFC 7644)
+const (
+	scimUserSchema      = "urn:ietf:params:scim:schemas:core:2.0:User"
+	scimListSchema      = "urn:ietf:params:scim:api:messages:2.0:ListResponse"
+	scimErrorSchema     = "urn:ietf:params:scim:api:messages:2.0:Error"
+	scimPatchSchema     = "urn:ietf:params:scim:api:messages:2.0:PatchOp"
+	scimExtensionSchema = "urn:lio:params:scim:schemas:extension:2.0:User"
+
+	scimContentType = "application/scim+json"
+	scimMaxPageSize = 200
+)
+
+// scimFilterPattern matches the one filter IdPs send before provisioning
+var scimFilterPattern = regexp.MustCompile(`^\s*userName\s+eq\s+"([^"]+)"\s*$`)
+
+// SCIMHandler implements a minimal SCIM 2.0 /Users endpoint for identity
+// providers. userName is the user's email; deleting a user deactivates it.
+type SCIMHandler struct {
+	service *services.ProvisioningService
+}
+
+// NewSCIMHandler creates a new SCIM handler
+func NewSCIMHandler(service *services.ProvisioningService) *SCIMHandler {
+	return &SCIMHandler{service: service}
+}
+
+type scimName struct {
+	Formatted string `json:"formatted,omitempty"`
+}
+
+type scimValue struct {
+	Value   string `json:"value"`
+	Primary bool   `json:"primary,omitempty"`
+}
+
+type scimExtension struct {
+	Plan string `json:"plan,omitempty"`
+}
+
+type scimMeta struct {
+	ResourceType string    `json:"resourceType"`
+	Created      time.Time `json:"created"`
+	LastModified time.Time `json:"lastModified"`
+	Location     string    `json:"location"`
+}
+
+// scimUser is the SCIM representation of a user
+type scimUser struct {
+	Schemas     []string       `json:"schemas"`
+	ID          string         `json:"id,omitempty"`
+	UserName    string         `json:"userName"`
+	Name        *scimName      `json:"name,omitempty"`
+	DisplayName string         `json:"displayName,omitempty"`
+	Emails      []scimValue    `json:"emails,omitempty"`
+	Roles       []scimValue    `json:"roles,omitempty"`
+	Active      *bool          `json:"active,omitempty"`
+	Extension   *scimExtension `json:"urn:lio:params:scim:schemas:extension:2.0:User,omitempty"`
+	Meta        *scimMeta      `json:"meta,omitempty"`
+}
+
+// fullName picks the display name an IdP sent
+func (u *scimUser) fullName() string {
+	if u.DisplayName != "" {
+		return u.DisplayName
+	}
+	if u.Name != nil {
+		return u.Name.Formatted
+	}
+	return ""
+}
+
+func toSCIMUser(user *models.User) scimUser {
+	active := user.IsActive
+	out := scimUser{
+		Schemas:     []string{scimUserSchema, scimExtensionSchema},
+		ID:          strconv.FormatInt(user.ID, 10),
+		UserName:    user.Email,
+		DisplayName: user.FullName,
+		Emails:      []scimValue{{Value: user.Email, Primary: true}},
+		Roles:       []scimValue{{Value: user.Role, Primary: true}},
+		Active:      &active,
+		Extension:   &scimExtension{Plan: user.Plan},
+		Meta: &scimMeta{
+			ResourceType: "User",
+			Created:      user.CreatedAt,
+			LastModified: user.UpdatedAt,
+			Location:     fmt.Sprintf("/scim/v2/Users/%d", user.ID),
+		},
+	}
+	if user.FullName != "" {
+		out.Name = &scimName{Formatted: user.FullName}
+	}
+	return out
+}
+
+// scimJSON writes a SCIM response body
+func scimJSON(c *gin.Context, status int, body interface{}) {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		status, payload = http.StatusInternalServerError, []byte(`{"detail":"failed to encode response"}`)
+	}
+	c.Data(status, scimContentType, payload)
+}
+
+// scimError writes a SCIM error response
+func scimError(c *gin.Context, status int, scimType, detail string) {
+	body := gin.H{
+		"schemas": []string{scimErrorSchema},
+		"status":  strconv.Itoa(status),
+		"detail":  detail,
+	}
+	if scimType != "" {
+		body["scimType"] = scimType
+	}
+	scimJSON(c, status, body)
+}
+
+// ListUsers handles GET /scim/v2/Users, supporting filter=userName eq "..."
+// and startIndex/count pagination
+func (h *SCIMHandler) ListUsers(c *gin.Context) {
+	startIndex, _ := strconv.Atoi(c.DefaultQuery("startIndex", "1"))
+	if startIndex < 1 {
+		startIndex = 1
+	}
+	count, _ := strconv.Atoi(c.DefaultQuery("count", "100"))
+	if count < 0 {
+		count = 0
+	}
+	if count > scimMaxPageSize {
+		count = scimMaxPageSize
+	}
+
+	var users []models.User
+	total := 0
+	if filter := c.Query("filter"); filter != "" {
+		m := scimFilterPattern.FindStringSubmatch(filter)
+		if m == nil {
+			scimError(c, http.StatusBadRequest, "invalidFilter", "only 'userName eq \"...\"' filters are supported")
+			return
+		}
+		user, err := h.service.FindUserByEmail(m[1])
+		if err != nil {
+			scimError(c, http.StatusInternalServerError, "", "failed to look up user")
+			return
+		}
+		if user != nil {
+			users, total = []models.User{*user}, 1
+		}
+		startIndex = 1
+	} else {
+		var err error
+		users, total, err = h.service.ListUsers(count, startIndex-1)
+		if err != nil {
+			scimError(c, http.StatusInternalServerError, "", "failed to list users")
+			return
+		}
+	}
+
+	resources := make([]scimUser, 0, len(users))
+	for i := range users {
+		resources = append(resources, toSCIMUser(&users[i]))
+	}
+	scimJSON(c, http.StatusOK, gin.H{
+		"schemas":      []string{scimListSchema},
+		"totalResults": total,
+		"startIndex":   startIndex,
+		"itemsPerPage": len(resources),
+		"Resources":    resources,
+	})
+}
+
+// GetUser handles GET /scim/v2/Users/:id
+func (h *SCIMHandler) GetUser(c *gin.Context) {
+	user, ok := h.loadUser(c)
+	if !ok {
+		return
+	}
+	scimJSON(c, http.StatusOK, toSCIMUser(user))
+}
+
+// CreateUser handles POST /scim/v2/Users, provisioning an invited account
+func (h *SCIMHandler) CreateUser(c *gin.Context) {
+	var req scimUser
+	if err := c.ShouldBindJSON(&req); err != nil {
+		scimError(c, http.StatusBadRequest, "invalidSyntax", "invalid SCIM user")
+		return
+	}
+
+	email := req.UserName
+	for _, e := range req.Emails {
+		if e.Primary {
+			email = e.Value
+		}
+	}
+	row := models.UserImportRow{Email: email, FullName: req.fullName()}
+	if len(req.Roles) > 0 {
+		row.Role = req.Roles[0].Value
+	}
+	if req.Extension != nil {
+		row.Plan = req.Extension.Plan
+	}
+
+	user, err := h.service.ProvisionUser(row)
+	if errors.Is(err, services.ErrUserExists) {
+		scimError(c, http.StatusConflict, "uniqueness", err.Error())
+		return
+	}
+	if err != nil {
+		scimError(c, http.StatusBadRequest, "invalidValue", err.Error())
+		return
+	}
+
+	c.Header("Location", fmt.Sprintf("/scim/v2/Users/%d", user.ID))
+	scimJSON(c, http.StatusCreated, toSCIMUser(user))
+}
+
+// ReplaceUser handles PUT /scim/v2/Users/:id (displayName and active)
+func (h *SCIMHandler) ReplaceUser(c *gin.Context) {
+	user, ok := h.loadUser(c)
+	if !ok {
+		return
+	}
+
+	var req scimUser
+	if err := c.ShouldBindJSON(&req); err != nil {
+		scimError(c, http.StatusBadRequest, "invalidSyntax", "invalid SCIM user")
+		return
+	}
+
+	name := req.fullName()
+	h.update(c, user.ID, &name, req.Active)
+}
+
+// PatchUser handles PATCH /scim/v2/Users/:id. Supported operations are
+// replace of active and displayName, with or without a path.
+func (h *SCIMHandler) PatchUser(c *gin.Context) {
+	user, ok := h.loadUser(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Schemas    []string `json:"schemas"`
+		Operations []struct {
+			Op    string          `json:"op"`
+			Path  string          `json:"path"`
+			Value json.RawMessage `json:"value"`
+		} `json:"Operations"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil || len(req.Operations) == 0 {
+		scimError(c, http.StatusBadRequest, "invalidSyntax", "request must be a "+scimPatchSchema+" with Operations")
+		return
+	}
+
+	var name *string
+	var active *bool
+	for _, op := range req.Operations {
+		if !strings.EqualFold(op.Op, "replace") && !strings.EqualFold(op.Op, "add") {
+			scimError(c, http.StatusBadRequest, "invalidValue", "unsupported operation: "+op.Op)
+			return
+		}
+
+		values := map[string]json.RawMessage{}
+		if op.Path != "" {
+			values[op.Path] = op.Value
+		} else if err := json.Unmarshal(op.Value, &values); err != nil {
+			scimError(c, http.StatusBadRequest, "invalidValue", "operation value must be an object when path is omitted")
+			return
+		}
+
+		for path, raw := range values {
+			switch path {
+			case "active":
+				var v bool
+				if err := json.Unmarshal(raw, &v); err != nil {
+					// Some IdPs send booleans as strings
+					var s string
+					if json.Unmarshal(raw, &s) != nil {
+						scimError(c, http.StatusBadRequest, "invalidValue", "active must be a boolean")
+						return
+					}
+					v = strings.EqualFold(s, "true")
+				}
+				active = &v
+			case "displayName", "name.formatted":
+				var v string
+				if err := json.Unmarshal(raw, &v); err != nil {
+					scimError(c, http.StatusBadRequest, "invalidValue", path+" must be a string")
+					return
+				}
+				name = &v
+			default:
+				scimError(c, http.StatusBadRequest, "invalidPath", "unsupported path: "+path)
+				return
+			}
+		}
+	}
+
+	h.update(c, user.ID, name, active)
+}
+
+// DeleteUser handles DELETE /scim/v2/Users/:id by deactivating the account
+func (h *SCIMHandler) DeleteUser(c *gin.Context) {
+	user, ok := h.loadUser(c)
+	if !ok {
+		return
+	}
+
+	inactive := false
+	if _, err := h.service.UpdateUser(user.ID, nil, &inactive); err != nil {
+		scimError(c, http.StatusInternalServerError, "", "failed to deactivate user")
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (h *SCIMHandler) update(c *gin.Context, id int64, name *string, active *bool) {
+	user, err := h.service.UpdateUser(id, name, active)
+	if err != nil {
+		scimError(c, http.StatusInternalServerError, "", "failed to update user")
+		return
+	}
+	scimJSON(c, http.StatusOK, toSCIMUser(user))
+}
+
+// loadUser resolves the :id parameter, writing a SCIM 404 when unknown
+func (h *SCIMHandler) loadUser(c *gin.Context) (*models.User, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		scimError(c, http.StatusNotFound, "", "user not found")
+		return nil, false
+	}
+	user, err := h.service.GetUser(id)
+	if errors.Is(err, services.ErrNotFound) {
+		scimError(c, http.StatusNotFound, "", "user not found")
+		return nil, false
+	}
+	if err != nil {
+		scimError(c, http.StatusInternalServerError, "", "failed to look up user")
+		return nil, false
+	}
+	return user, true
+}
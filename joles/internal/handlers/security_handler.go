@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"lio-ai/internal/models"
+	"lio-ai/internal/services"
+)
+
+// SecurityHandler handles the admin security review endpoints
+type SecurityHandler struct {
+	service *services.SecurityService
+}
+
+// NewSecurityHandler creates a new security handler
+func NewSecurityHandler(service *services.SecurityService) *SecurityHandler {
+	return &SecurityHandler{service: service}
+}
+
+// ListEvents handles GET /api/v1/admin/security-events. Query parameters:
+// user_id, severity (minimum: low, medium or high), since (RFC3339 or
+// YYYY-MM-DD), limit and offset.
+func (h *SecurityHandler) ListEvents(c *gin.Context) {
+	filter := models.SecurityEventFilter{UserID: c.Query("user_id")}
+
+	if v := c.Query("severity"); v != "" {
+		if models.SeveritiesAtLeast(v) == nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "severity must be 'low', 'medium' or 'high'",
+				"code":  "INVALID_REQUEST",
+			})
+			return
+		}
+		filter.MinSeverity = v
+	}
+	if v := c.Query("since"); v != "" {
+		since, err := parseFeedbackTime(v, false)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "since must be RFC3339 or YYYY-MM-DD",
+				"code":  "INVALID_REQUEST",
+			})
+			return
+		}
+		filter.Since = since
+	}
+
+	filter.Limit, _ = strconv.Atoi(c.DefaultQuery("limit", "50"))
+	filter.Offset, _ = strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if filter.Limit > 200 {
+		filter.Limit = 200
+	}
+	if filter.Limit < 1 {
+		filter.Limit = 50
+	}
+	if filter.Offset < 0 {
+		filter.Offset = 0
+	}
+
+	events, total, err := h.service.ListEvents(filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "failed to fetch security events",
+			"code":  "FETCH_FAILED",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":   events,
+		"total":  total,
+		"limit":  filter.Limit,
+		"offset": filter.Offset,
+	})
+}
@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"lio-ai/internal/models"
+	"lio-ai/internal/services"
+)
+
+// SemanticSearchHandler handles semantic search over users' documents
+type SemanticSearchHandler struct {
+	service *services.SemanticSearchService
+}
+
+// NewSemanticSearchHandler creates a new semantic search handler
+func NewSemanticSearchHandler(service *services.SemanticSearchService) *SemanticSearchHandler {
+	return &SemanticSearchHandler{service: service}
+}
+
+// Search handles GET /api/v1/search/semantic. q is embedded and compared
+// with the user's document chunks; hybrid=true blends in full-text
+// matching, weighted by text_weight (0-1, default 0.3). limit, min_score,
+// collection_id, language and content_type narrow the results.
+func (h *SemanticSearchHandler) Search(c *gin.Context) {
+	q := models.SemanticSearchQuery{
+		UserID:     c.GetString("user_id"),
+		Query:      strings.TrimSpace(c.Query("q")),
+		Hybrid:     c.Query("hybrid") == "true",
+		TextWeight: services.DefaultSearchTextWeight,
+		Reader:     documentReader(c),
+
+		Language:    strings.TrimSpace(c.Query("language")),
+		ContentType: strings.TrimSpace(c.Query("content_type")),
+	}
+	if q.Query == "" {
+		respondInvalidSearch(c, "q is required")
+		return
+	}
+	if len(q.Query) > 2000 {
+		respondInvalidSearch(c, "q must be at most 2000 characters")
+		return
+	}
+
+	var err error
+	if v := c.Query("limit"); v != "" {
+		if q.Limit, err = strconv.Atoi(v); err != nil || q.Limit < 1 || q.Limit > services.MaxSemanticSearchLimit {
+			respondInvalidSearch(c, "limit must be between 1 and "+strconv.Itoa(services.MaxSemanticSearchLimit))
+			return
+		}
+	}
+	if v := c.Query("text_weight"); v != "" {
+		if q.TextWeight, err = strconv.ParseFloat(v, 64); err != nil || q.TextWeight < 0 || q.TextWeight > 1 {
+			respondInvalidSearch(c, "text_weight must be between 0 and 1")
+			return
+		}
+	}
+	if v := c.Query("min_score"); v != "" {
+		if q.MinScore, err = strconv.ParseFloat(v, 64); err != nil || q.MinScore < -1 || q.MinScore > 1 {
+			respondInvalidSearch(c, "min_score must be between -1 and 1")
+			return
+		}
+	}
+	if v := c.Query("collection_id"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || id <= 0 {
+			respondInvalidSearch(c, "invalid collection_id")
+			return
+		}
+		q.CollectionID = &id
+	}
+
+	response, err := h.service.Search(c.Request.Context(), q)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidMessage) {
+			respondInvalidSearch(c, err.Error())
+			return
+		}
+		var aiErr *services.AIServiceError
+		if errors.As(err, &aiErr) && aiErr != nil {
+			c.JSON(aiErrorStatus(aiErr), gin.H{
+				"error": aiErrorDetail(aiErr),
+				"code":  "AI_SERVICE_ERROR",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "failed to search documents",
+			"code":  "SEARCH_FAILED",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// Reindex handles POST /api/v1/search/semantic/reindex, queueing the
+// user's documents that are not yet embedded with the current model
+func (h *SemanticSearchHandler) Reindex(c *gin.Context) {
+	queued, err := h.service.Reindex(c.GetString("user_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "failed to queue documents",
+			"code":  "REINDEX_FAILED",
+		})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"queued": queued,
+		"model":  h.service.Model(),
+	})
+}
+
+func respondInvalidSearch(c *gin.Context, message string) {
+	c.JSON(http.StatusBadRequest, gin.H{
+		"error": message,
+		"code":  "INVALID_REQUEST",
+	})
+}
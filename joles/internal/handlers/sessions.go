@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"lio-ai/internal/audit"
+	"lio-ai/internal/models"
+	"lio-ai/internal/services"
+)
+
+// sessionClient describes the device a login request is made from
+func sessionClient(c *gin.Context) models.SessionClient {
+	return models.SessionClient{IPAddress: c.ClientIP(), UserAgent: c.Request.UserAgent()}
+}
+
+// ListSessions handles GET /api/v1/auth/sessions: the user's active
+// sessions, latest first, with the one making the request marked current
+func (h *AuthHandler) ListSessions(c *gin.Context) {
+	userID, ok := sessionUserID(c)
+	if !ok {
+		return
+	}
+
+	sessions, err := h.userService.ListSessions(userID, c.GetString("token_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch sessions", "code": "FETCH_FAILED"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"data":  sessions,
+		"total": len(sessions),
+	})
+}
+
+// TerminateSession handles DELETE /api/v1/auth/sessions/:id, signing that
+// device out by revoking its token
+func (h *AuthHandler) TerminateSession(c *gin.Context) {
+	userID, ok := sessionUserID(c)
+	if !ok {
+		return
+	}
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid session id", "code": "INVALID_ID"})
+		return
+	}
+
+	if err := h.userService.TerminateSession(userID, id); err != nil {
+		if errors.Is(err, services.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "session not found", "code": models.ErrCodeNotFound})
+			return
+		}
+		log.Printf("⚠️  Failed to terminate session %d of user %d: %v", id, userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to terminate session", "code": "INTERNAL_ERROR"})
+		return
+	}
+	h.auditEvent(c, "auth.session_terminate", audit.OutcomeSuccess, fmt.Sprint(userID), c.GetString("email"), map[string]interface{}{"session_id": id})
+	c.Status(http.StatusNoContent)
+}
+
+// TerminateOtherSessions handles DELETE /api/v1/auth/sessions, signing out
+// every device but the one making the request
+func (h *AuthHandler) TerminateOtherSessions(c *gin.Context) {
+	userID, ok := sessionUserID(c)
+	if !ok {
+		return
+	}
+
+	terminated, err := h.userService.TerminateOtherSessions(userID, c.GetString("token_id"))
+	if err != nil {
+		log.Printf("⚠️  Failed to terminate other sessions of user %d: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to terminate sessions", "code": "INTERNAL_ERROR"})
+		return
+	}
+	h.auditEvent(c, "auth.session_terminate", audit.OutcomeSuccess, fmt.Sprint(userID), c.GetString("email"), map[string]interface{}{"terminated": terminated})
+	c.JSON(http.StatusOK, gin.H{"terminated": terminated})
+}
@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+// sessionIDs decodes a session listing into session IDs by user agent
+func sessionIDs(t *testing.T, code int, body map[string]interface{}) map[string]int64 {
+	t.Helper()
+	if code != http.StatusOK {
+		t.Fatalf("list sessions: status = %d, body %v", code, body)
+	}
+	data, _ := body["data"].([]interface{})
+	ids := make(map[string]int64, len(data))
+	for _, item := range data {
+		s := item.(map[string]interface{})
+		ids[s["user_agent"].(string)] = int64(s["id"].(float64))
+	}
+	return ids
+}
+
+func TestTerminateSessionChecksOwnership(t *testing.T) {
+	conn := newTestDB(t)
+	router, userService := newTestAuthRouter(t, conn)
+	_, alice := loginTestUser(t, conn, userService, "alice", "alice-laptop")
+	_, alicePhone := loginTestUser(t, conn, userService, "alice", "alice-phone")
+	_, bob := loginTestUser(t, conn, userService, "bob", "bob-laptop")
+
+	code, body := serveAuth(router, http.MethodGet, "/auth/sessions", bob, "")
+	bobSessions := sessionIDs(t, code, body)
+	code, body = serveAuth(router, http.MethodGet, "/auth/sessions", alice, "")
+	aliceSessions := sessionIDs(t, code, body)
+	if len(aliceSessions) != 2 || len(bobSessions) != 1 {
+		t.Fatalf("sessions = %v and %v, want each user's own", aliceSessions, bobSessions)
+	}
+
+	path := fmt.Sprintf("/auth/sessions/%d", bobSessions["bob-laptop"])
+	if code, _ := serveAuth(router, http.MethodDelete, path, alice, ""); code != http.StatusNotFound {
+		t.Errorf("terminate another user's session: status = %d, want 404", code)
+	}
+	if code, _ := serveAuth(router, http.MethodGet, "/me", bob, ""); code != http.StatusOK {
+		t.Errorf("bob's token stopped working: status = %d", code)
+	}
+
+	if code, _ := serveAuth(router, http.MethodDelete, "/auth/sessions/abc", alice, ""); code != http.StatusBadRequest {
+		t.Errorf("non-numeric id: status = %d, want 400", code)
+	}
+
+	path = fmt.Sprintf("/auth/sessions/%d", aliceSessions["alice-phone"])
+	if code, _ := serveAuth(router, http.MethodDelete, path, alice, ""); code != http.StatusNoContent {
+		t.Fatalf("terminate own session: status = %d, want 204", code)
+	}
+	if code, _ := serveAuth(router, http.MethodGet, "/me", alicePhone, ""); code != http.StatusUnauthorized {
+		t.Errorf("terminated session's token: status = %d, want 401", code)
+	}
+	if code, _ := serveAuth(router, http.MethodGet, "/me", alice, ""); code != http.StatusOK {
+		t.Errorf("calling session's token: status = %d, want 200", code)
+	}
+}
+
+func TestTerminateOtherSessionsKeepsCurrent(t *testing.T) {
+	conn := newTestDB(t)
+	router, userService := newTestAuthRouter(t, conn)
+	_, laptop := loginTestUser(t, conn, userService, "alice", "laptop")
+	_, phone := loginTestUser(t, conn, userService, "alice", "phone")
+	_, tablet := loginTestUser(t, conn, userService, "alice", "tablet")
+
+	code, body := serveAuth(router, http.MethodDelete, "/auth/sessions", laptop, "")
+	if code != http.StatusOK || body["terminated"] != float64(2) {
+		t.Fatalf("terminate other sessions: status = %d, body %v, want 2 terminated", code, body)
+	}
+
+	for name, token := range map[string]string{"phone": phone, "tablet": tablet} {
+		if code, _ := serveAuth(router, http.MethodGet, "/me", token, ""); code != http.StatusUnauthorized {
+			t.Errorf("%s token: status = %d, want 401", name, code)
+		}
+	}
+	code, body = serveAuth(router, http.MethodGet, "/auth/sessions", laptop, "")
+	if sessions := sessionIDs(t, code, body); len(sessions) != 1 {
+		t.Errorf("remaining sessions = %v, want only laptop", sessions)
+	}
+	data := body["data"].([]interface{})
+	if current, _ := data[0].(map[string]interface{})["current"].(bool); !current {
+		t.Errorf("remaining session is not marked current")
+	}
+}
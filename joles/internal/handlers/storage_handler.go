@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"lio-ai/internal/services"
+)
+
+// StorageHandler handles HTTP requests for storage usage
+type StorageHandler struct {
+	service *services.StorageService
+}
+
+// NewStorageHandler creates a new storage handler
+func NewStorageHandler(service *services.StorageService) *StorageHandler {
+	return &StorageHandler{service: service}
+}
+
+// GetTopConsumers handles GET /api/v1/admin/storage/top?limit=20
+func (h *StorageHandler) GetTopConsumers(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit < 1 || limit > 100 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "limit must be between 1 and 100",
+			"code":  "INVALID_REQUEST",
+		})
+		return
+	}
+
+	consumers, err := h.service.TopConsumers(limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "failed to fetch storage usage",
+			"code":  "FETCH_FAILED",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":  consumers,
+		"total": len(consumers),
+	})
+}
+
+// respondStorageLimit writes the 413 for an upload over the storage limit
+func respondStorageLimit(c *gin.Context, err error) {
+	c.JSON(http.StatusRequestEntityTooLarge, gin.H{
+		"error": err.Error(),
+		"code":  "STORAGE_LIMIT_EXCEEDED",
+	})
+}
@@ -2,18 +2,35 @@ package handlers
 
 import (
 	"database/sql"
+	_ "embed"
+	"encoding/json"
 	"net/http"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"lio-ai/internal/db"
+	"lio-ai/internal/middleware"
 	"lio-ai/internal/models"
+	"lio-ai/internal/repositories"
+	"lio-ai/internal/services"
 	"lio-ai/internal/utils"
 )
 
+// changelogJSON is the API changelog shipped with this build
+//
+//go:embed changelog.json
+var changelogJSON []byte
+
 // SystemHandler handles system-related requests
 type SystemHandler struct {
 	db        *sql.DB
 	startTime time.Time
+	backend   *services.BackendHealth
+	// Smallest group of users whose figures non-admins may see in global metrics
+	minAggregationUsers int
+	cache               *services.ResponseCache
+	usageQueue          *services.UsageQueue
+	incidents           *services.IncidentService
 }
 
 // NewSystemHandler creates a new system handler
@@ -24,6 +41,27 @@ func NewSystemHandler(db *sql.DB) *SystemHandler {
 	}
 }
 
+// SetBackendHealth reports backend reachability in health checks
+func (h *SystemHandler) SetBackendHealth(backend *services.BackendHealth) {
+	h.backend = backend
+}
+
+// SetMinAggregationUsers sets the smallest number of users a global metric
+// must aggregate before non-admins can see it
+func (h *SystemHandler) SetMinAggregationUsers(n int) {
+	h.minAggregationUsers = n
+}
+
+// SetResponseCache reports completion cache hits and misses in metrics
+func (h *SystemHandler) SetResponseCache(cache *services.ResponseCache) {
+	h.cache = cache
+}
+
+// SetUsageQueue reports the usage tracking queue's counters in metrics
+func (h *SystemHandler) SetUsageQueue(queue *services.UsageQueue) {
+	h.usageQueue = queue
+}
+
 // HealthCheck performs a comprehensive health check
 func (h *SystemHandler) HealthCheck(c *gin.Context) {
 	checks := make(map[string]string)
@@ -35,13 +73,19 @@ func (h *SystemHandler) HealthCheck(c *gin.Context) {
 	}
 	checks["database"] = dbStatus
 
+	backendStatus := "up"
+	if h.backend != nil {
+		backendStatus = h.backend.Status().Status
+	}
+	checks["backend"] = backendStatus
+
 	// Calculate uptime
 	uptime := time.Since(h.startTime).String()
 
 	response := models.HealthResponse{
 		Status:    "operational",
 		Gateway:   "up",
-		Backend:   "up", // Will be updated by proxy handler
+		Backend:   backendStatus,
 		Database:  dbStatus,
 		Timestamp: time.Now().Format(time.RFC3339),
 		Version:   "0.1.0",
@@ -55,59 +99,112 @@ func (h *SystemHandler) HealthCheck(c *gin.Context) {
 		c.JSON(http.StatusServiceUnavailable, response)
 		return
 	}
+	// Backend outages degrade AI features but the gateway keeps serving
+	if backendStatus == services.BackendStatusDown {
+		response.Status = "degraded"
+	}
 
 	c.JSON(http.StatusOK, response)
 }
 
-// GetMetrics returns system metrics
+// GetMetrics returns system metrics. Admins see instance-wide figures;
+// other users see their own usage unless they ask for ?scope=global, which
+// only includes groups aggregating at least minAggregationUsers users.
 func (h *SystemHandler) GetMetrics(c *gin.Context) {
-	// Get total users
-	var totalUsers, activeUsers int
-	h.db.QueryRow("SELECT COUNT(*) FROM user_quotas").Scan(&totalUsers)
-	h.db.QueryRow("SELECT COUNT(DISTINCT user_id) FROM usage_metrics WHERE created_at >= datetime('now', '-24 hours')").Scan(&activeUsers)
+	admin := middleware.HasRole(c, "admin")
+	scope := c.Query("scope")
+	if scope == "" {
+		scope = "self"
+		if admin {
+			scope = "global"
+		}
+	}
+	if scope != "self" && scope != "global" {
+		utils.BadRequestError(c, "scope must be 'self' or 'global'")
+		return
+	}
 
-	// Get total chats and documents
-	var totalChats, totalDocs int
-	h.db.QueryRow("SELECT COUNT(*) FROM chats").Scan(&totalChats)
-	h.db.QueryRow("SELECT COUNT(*) FROM documents").Scan(&totalDocs)
+	// Filters for the caller's own slice, and the minimum group size for
+	// global figures shown to non-admins
+	usageUserID, chatFilter := "", ""
+	var args []interface{}
+	minUsers := 0
+	if scope == "self" {
+		usageUserID = c.GetString("user_id")
+		chatFilter = " WHERE user_id = ?"
+		args = append(args, usageUserID)
+	} else if !admin {
+		minUsers = h.minAggregationUsers
+	}
 
-	// Get usage metrics
-	var totalRequests, successfulRequests, failedRequests int64
-	var totalTokens int
-	var totalCost float64
-	var avgLatency float64
+	// Usage is read from the hourly and daily rollups, and raw for the
+	// current hour
+	usage, usageArgs, err := repositories.UsageRollupSource(h.db, usageUserID, time.Time{}, time.Time{})
+	if err != nil {
+		utils.InternalError(c, "failed to read usage")
+		return
+	}
+
+	metrics := models.MetricsResponse{Scope: scope, MinAggregationUsers: minUsers}
+
+	if scope == "global" {
+		// Get total users
+		h.db.QueryRow("SELECT COUNT(*) FROM user_quotas").Scan(&metrics.TotalUsers)
+		h.db.QueryRow("SELECT COUNT(DISTINCT user_id) FROM usage_metrics WHERE created_at >= datetime('now', '-24 hours')").Scan(&metrics.ActiveUsers)
+		h.db.QueryRow("SELECT COUNT(*) FROM documents").Scan(&metrics.TotalDocuments)
+		if h.cache != nil {
+			stats := h.cache.Stats()
+			metrics.ResponseCache = &stats
+		}
+		if h.usageQueue != nil {
+			stats := h.usageQueue.Stats()
+			metrics.UsageQueue = &stats
+		}
+	}
 
+	// Get total chats
+	h.db.QueryRow("SELECT COUNT(*) FROM chats"+chatFilter, args...).Scan(&metrics.TotalChats)
+
+	// Get usage metrics
+	var contributors int
 	h.db.QueryRow(`
 		SELECT 
-			COUNT(*) as total,
-			SUM(CASE WHEN success = 1 THEN 1 ELSE 0 END) as successful,
-			SUM(CASE WHEN success = 0 THEN 1 ELSE 0 END) as failed,
+			COALESCE(SUM(requests), 0) as total,
+			COALESCE(SUM(successful), 0) as successful,
+			COALESCE(SUM(failed), 0) as failed,
 			COALESCE(SUM(tokens_total), 0) as tokens,
 			COALESCE(SUM(cost_usd), 0.0) as cost,
-			COALESCE(AVG(duration_ms), 0.0) as avg_latency
-		FROM usage_metrics
-	`).Scan(&totalRequests, &successfulRequests, &failedRequests, &totalTokens, &totalCost, &avgLatency)
+			COALESCE(CAST(SUM(duration_ms) AS REAL) / SUM(requests), 0.0) as avg_latency,
+			COUNT(DISTINCT user_id) as users
+		FROM `+usage+` AS usage`, usageArgs...).Scan(&metrics.RequestsTotal, &metrics.RequestsSuccessful, &metrics.RequestsFailed,
+		&metrics.TotalTokensUsed, &metrics.TotalCostUSD, &metrics.AverageLatencyMs, &contributors)
+
+	if contributors < minUsers {
+		// Too few users to publish instance-wide figures without exposing them
+		utils.SuccessResponse(c, models.MetricsResponse{Scope: scope, MinAggregationUsers: minUsers, Suppressed: true})
+		return
+	}
 
 	// Get endpoint statistics
 	rows, err := h.db.Query(`
 		SELECT 
 			endpoint,
-			COUNT(*) as request_count,
-			AVG(duration_ms) as avg_time,
-			CAST(SUM(CASE WHEN success = 0 THEN 1 ELSE 0 END) AS REAL) / COUNT(*) * 100 as error_rate
-		FROM usage_metrics
+			SUM(requests) as request_count,
+			CAST(SUM(duration_ms) AS REAL) / SUM(requests) as avg_time,
+			CAST(SUM(failed) AS REAL) / SUM(requests) * 100 as error_rate
+		FROM `+usage+` AS usage
 		GROUP BY endpoint
+		HAVING COUNT(DISTINCT user_id) >= ?
 		ORDER BY request_count DESC
 		LIMIT 10
-	`)
-	
-	var endpointStats []models.EndpointStat
+	`, append(usageArgs, minUsers)...)
+
 	if err == nil {
 		defer rows.Close()
 		for rows.Next() {
 			var stat models.EndpointStat
 			rows.Scan(&stat.Endpoint, &stat.RequestCount, &stat.AverageTimeMs, &stat.ErrorRate)
-			endpointStats = append(endpointStats, stat)
+			metrics.EndpointStats = append(metrics.EndpointStats, stat)
 		}
 	}
 
@@ -115,41 +212,26 @@ func (h *SystemHandler) GetMetrics(c *gin.Context) {
 	modelRows, err := h.db.Query(`
 		SELECT 
 			model_used,
-			COUNT(*) as request_count,
+			SUM(requests) as request_count,
 			SUM(tokens_total) as total_tokens,
 			SUM(cost_usd) as total_cost
-		FROM usage_metrics
+		FROM `+usage+` AS usage
 		WHERE model_used != ''
 		GROUP BY model_used
+		HAVING COUNT(DISTINCT user_id) >= ?
 		ORDER BY request_count DESC
 		LIMIT 10
-	`)
+	`, append(usageArgs, minUsers)...)
 
-	var modelStats []models.ModelStat
 	if err == nil {
 		defer modelRows.Close()
 		for modelRows.Next() {
 			var stat models.ModelStat
 			modelRows.Scan(&stat.ModelName, &stat.RequestCount, &stat.TotalTokens, &stat.TotalCostUSD)
-			modelStats = append(modelStats, stat)
+			metrics.ModelStats = append(metrics.ModelStats, stat)
 		}
 	}
 
-	metrics := models.MetricsResponse{
-		RequestsTotal:      totalRequests,
-		RequestsSuccessful: successfulRequests,
-		RequestsFailed:     failedRequests,
-		AverageLatencyMs:   avgLatency,
-		ActiveUsers:        activeUsers,
-		TotalUsers:         totalUsers,
-		TotalChats:         totalChats,
-		TotalDocuments:     totalDocs,
-		TotalTokensUsed:    totalTokens,
-		TotalCostUSD:       totalCost,
-		EndpointStats:      endpointStats,
-		ModelStats:         modelStats,
-	}
-
 	utils.SuccessResponse(c, metrics)
 }
 
@@ -181,8 +263,14 @@ func (h *SystemHandler) GetInfo(c *gin.Context) {
 	utils.SuccessResponse(c, info)
 }
 
-// GetStats returns quick statistics
+// GetStats returns quick statistics: instance-wide for admins, the
+// caller's own chats and usage for everyone else
 func (h *SystemHandler) GetStats(c *gin.Context) {
+	if !middleware.HasRole(c, "admin") {
+		h.getUserStats(c, c.GetString("user_id"))
+		return
+	}
+
 	var totalChats, totalDocs, totalMessages int
 	h.db.QueryRow("SELECT COUNT(*) FROM chats").Scan(&totalChats)
 	h.db.QueryRow("SELECT COUNT(*) FROM documents").Scan(&totalDocs)
@@ -200,14 +288,114 @@ func (h *SystemHandler) GetStats(c *gin.Context) {
 	`).Scan(&totalRequests, &totalTokens, &totalCost)
 
 	stats := gin.H{
-		"chats":         totalChats,
-		"documents":     totalDocs,
-		"messages":      totalMessages,
-		"api_requests":  totalRequests,
-		"tokens_used":   totalTokens,
+		"scope":          "global",
+		"chats":          totalChats,
+		"documents":      totalDocs,
+		"messages":       totalMessages,
+		"api_requests":   totalRequests,
+		"tokens_used":    totalTokens,
 		"total_cost_usd": totalCost,
-		"timestamp":     time.Now().Format(time.RFC3339),
+		"timestamp":      time.Now().Format(time.RFC3339),
 	}
 
 	utils.SuccessResponse(c, stats)
 }
+
+// getUserStats writes GetStats for a single user's chats and usage
+func (h *SystemHandler) getUserStats(c *gin.Context, userID string) {
+	var totalChats, totalMessages int
+	h.db.QueryRow("SELECT COUNT(*) FROM chats WHERE user_id = ?", userID).Scan(&totalChats)
+	h.db.QueryRow("SELECT COUNT(*) FROM messages m JOIN chats c ON c.id = m.chat_id WHERE c.user_id = ?", userID).Scan(&totalMessages)
+
+	var totalRequests int64
+	var totalTokens int
+	var totalCost float64
+	h.db.QueryRow(`
+		SELECT 
+			COUNT(*),
+			COALESCE(SUM(tokens_total), 0),
+			COALESCE(SUM(cost_usd), 0.0)
+		FROM usage_metrics
+		WHERE user_id = ?
+	`, userID).Scan(&totalRequests, &totalTokens, &totalCost)
+
+	utils.SuccessResponse(c, gin.H{
+		"scope":          "self",
+		"chats":          totalChats,
+		"messages":       totalMessages,
+		"api_requests":   totalRequests,
+		"tokens_used":    totalTokens,
+		"total_cost_usd": totalCost,
+		"timestamp":      time.Now().Format(time.RFC3339),
+	})
+}
+
+// GetChangelog returns the API changelog and the schema migrations applied
+// to this instance, so clients can check compatibility programmatically
+func (h *SystemHandler) GetChangelog(c *gin.Context) {
+	var changelog struct {
+		Releases []json.RawMessage `json:"releases"`
+	}
+	if err := json.Unmarshal(changelogJSON, &changelog); err != nil {
+		utils.InternalError(c, "Failed to load changelog")
+		return
+	}
+
+	type appliedMigration struct {
+		Version   int       `json:"version"`
+		Name      string    `json:"name"`
+		AppliedAt time.Time `json:"applied_at"`
+	}
+
+	applied := make([]appliedMigration, 0)
+	appliedVersions := make(map[int]bool)
+	rows, err := h.db.Query("SELECT version, name, applied_at FROM schema_migrations ORDER BY version")
+	if err != nil {
+		utils.InternalError(c, "Failed to load schema migrations")
+		return
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var m appliedMigration
+		if err := rows.Scan(&m.Version, &m.Name, &m.AppliedAt); err != nil {
+			utils.InternalError(c, "Failed to load schema migrations")
+			return
+		}
+		applied = append(applied, m)
+		appliedVersions[m.Version] = true
+	}
+
+	// Migrations known to this build but not yet applied (e.g. a failed ALTER)
+	pending := make([]db.Migration, 0)
+	latest := 0
+	for _, m := range db.Migrations() {
+		if m.Version > latest {
+			latest = m.Version
+		}
+		if !appliedVersions[m.Version] {
+			pending = append(pending, m)
+		}
+	}
+
+	// Table rebuilds in progress resume on the next start until completed
+	online, err := db.OnlineMigrations(h.db)
+	if err != nil {
+		utils.InternalError(c, "Failed to load schema migrations")
+		return
+	}
+
+	schemaVersion := 0
+	if len(applied) > 0 {
+		schemaVersion = applied[len(applied)-1].Version
+	}
+
+	utils.SuccessResponse(c, gin.H{
+		"api_version":           "v1",
+		"schema_version":        schemaVersion,
+		"latest_schema_version": latest,
+		"applied_migrations":    applied,
+		"pending_migrations":    pending,
+		"online_migrations":     online,
+		"releases":              changelog.Releases,
+	})
+}
@@ -0,0 +1,181 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"lio-ai/internal/models"
+	"lio-ai/internal/services"
+)
+
+// TemplateHandler handles HTTP requests for prompt templates
+type TemplateHandler struct {
+	service *services.TemplateService
+}
+
+// NewTemplateHandler creates a new template handler
+func NewTemplateHandler(service *services.TemplateService) *TemplateHandler {
+	return &TemplateHandler{service: service}
+}
+
+// CreateTemplate handles POST /api/v1/templates
+func (h *TemplateHandler) CreateTemplate(c *gin.Context) {
+	var req models.PromptTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+			"code":  "INVALID_REQUEST",
+		})
+		return
+	}
+
+	t, err := h.service.CreateTemplate(c.GetString("user_id"), &req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "failed to create template",
+			"code":  "CREATE_FAILED",
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, t)
+}
+
+// GetTemplates handles GET /api/v1/templates
+func (h *TemplateHandler) GetTemplates(c *gin.Context) {
+	templates, err := h.service.ListTemplates(c.GetString("user_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "failed to fetch templates",
+			"code":  "FETCH_FAILED",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":  templates,
+		"total": len(templates),
+	})
+}
+
+// GetTemplate handles GET /api/v1/templates/:id
+func (h *TemplateHandler) GetTemplate(c *gin.Context) {
+	id, ok := templateID(c)
+	if !ok {
+		return
+	}
+
+	t, err := h.service.GetTemplate(id, c.GetString("user_id"))
+	if err != nil {
+		respondTemplateError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, t)
+}
+
+// UpdateTemplate handles PUT /api/v1/templates/:id
+func (h *TemplateHandler) UpdateTemplate(c *gin.Context) {
+	id, ok := templateID(c)
+	if !ok {
+		return
+	}
+
+	var req models.UpdatePromptTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+			"code":  "INVALID_REQUEST",
+		})
+		return
+	}
+
+	t, err := h.service.UpdateTemplate(id, c.GetString("user_id"), &req)
+	if err != nil {
+		respondTemplateError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, t)
+}
+
+// DeleteTemplate handles DELETE /api/v1/templates/:id
+func (h *TemplateHandler) DeleteTemplate(c *gin.Context) {
+	id, ok := templateID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteTemplate(id, c.GetString("user_id")); err != nil {
+		respondTemplateError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "template deleted successfully"})
+}
+
+// RenderTemplate handles POST /api/v1/templates/:id/render
+func (h *TemplateHandler) RenderTemplate(c *gin.Context) {
+	id, ok := templateID(c)
+	if !ok {
+		return
+	}
+
+	var req models.RenderTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+			"code":  "INVALID_REQUEST",
+		})
+		return
+	}
+
+	rendered, err := h.service.Render(id, c.GetString("user_id"), req.Variables)
+	if err != nil {
+		respondTemplateError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"content": rendered})
+}
+
+// templateID parses the :id parameter, writing a 400 when it is invalid
+func templateID(c *gin.Context) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "invalid template id",
+			"code":  "INVALID_ID",
+		})
+		return 0, false
+	}
+	return id, true
+}
+
+// respondTemplateError maps template service errors to HTTP responses
+func respondTemplateError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrMissingTemplateVariable):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+			"code":  "MISSING_VARIABLES",
+		})
+	case errors.Is(err, services.ErrUnauthorized):
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "access denied",
+			"code":  "FORBIDDEN",
+		})
+	case errors.Is(err, services.ErrTemplateNotFound):
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "template not found",
+			"code":  "NOT_FOUND",
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "template request failed",
+			"code":  "TEMPLATE_FAILED",
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"lio-ai/internal/models"
+	"lio-ai/internal/services"
+)
+
+// CountTokens handles POST /api/v1/tokens/count. The body has a model and
+// either text or messages; message counts include the chat framing the
+// provider adds. Counts are exact for OpenAI models and estimated, within
+// about 10%, for others.
+func (h *ChatHandler) CountTokens(c *gin.Context) {
+	var req models.TokenCountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+			"code":  "INVALID_REQUEST",
+		})
+		return
+	}
+
+	response, err := h.service.CountTokens(&req)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidMessage) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+				"code":  "INVALID_REQUEST",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "failed to count tokens",
+			"code":  "INTERNAL_ERROR",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// EstimateCost handles POST /api/v1/usage/estimate. The body has a model,
+// messages and optionally max_tokens; the response has the prompt's
+// tokens and the range the request's cost would fall in, for a price
+// preview before sending it.
+func (h *ChatHandler) EstimateCost(c *gin.Context) {
+	var req models.CostEstimateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+			"code":  "INVALID_REQUEST",
+		})
+		return
+	}
+
+	estimate, err := h.service.EstimateCost(c.GetString("user_id"), &req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "failed to estimate cost",
+			"code":  "INTERNAL_ERROR",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, estimate)
+}
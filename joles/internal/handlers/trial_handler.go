@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"lio-ai/internal/models"
+	"lio-ai/internal/services"
+)
+
+// TrialFingerprintHeader carries the client's device fingerprint for trial quotas
+const TrialFingerprintHeader = "X-Device-Fingerprint"
+
+// TrialHandler handles anonymous trial requests
+type TrialHandler struct {
+	service *services.TrialService
+}
+
+// NewTrialHandler creates a new trial handler
+func NewTrialHandler(service *services.TrialService) *TrialHandler {
+	return &TrialHandler{service: service}
+}
+
+// clientKey identifies the trial client from its IP and device fingerprint
+func (h *TrialHandler) clientKey(c *gin.Context) string {
+	return services.TrialClientKey(c.ClientIP(), c.GetHeader(TrialFingerprintHeader))
+}
+
+// ChatCompletion handles POST /api/v1/trial/chat/completions
+func (h *TrialHandler) ChatCompletion(c *gin.Context) {
+	var req models.TrialCompletionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "message is required (max 4000 characters)",
+			"code":  "INVALID_REQUEST",
+		})
+		return
+	}
+
+	key := h.clientKey(c)
+	resp, err := h.service.Complete(c.Request.Context(), key, c.ClientIP(), req.Message)
+	if errors.Is(err, services.ErrTrialExhausted) {
+		quota, _ := h.service.GetQuota(key)
+		body := gin.H{
+			"error":       "trial limit reached",
+			"code":        "TRIAL_EXHAUSTED",
+			"message":     "You've used all free trial messages for today. Create a free account to keep chatting.",
+			"upgrade_url": "/api/v1/auth/register",
+		}
+		if quota != nil {
+			body["limit"] = quota.Limit
+			body["resets_at"] = quota.ResetsAt
+		}
+		c.JSON(http.StatusTooManyRequests, body)
+		return
+	}
+	if err != nil {
+		if _, ok := services.IsAIServiceError(err); ok {
+			c.JSON(http.StatusBadGateway, gin.H{
+				"error": "AI service unavailable",
+				"code":  "AI_SERVICE_ERROR",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "failed to complete trial request",
+			"code":  "TRIAL_FAILED",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// GetQuota handles GET /api/v1/trial/quota
+func (h *TrialHandler) GetQuota(c *gin.Context) {
+	quota, err := h.service.GetQuota(h.clientKey(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "failed to fetch trial quota",
+			"code":  "FETCH_FAILED",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, quota)
+}
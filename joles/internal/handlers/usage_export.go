@@ -0,0 +1,139 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"lio-ai/internal/middleware"
+	"lio-ai/internal/models"
+)
+
+// Window exported when from is left out, and how many rows are written
+// between flushes
+const (
+	defaultUsageExportWindow = 30 * 24 * time.Hour
+	usageExportFlushRows     = 500
+)
+
+var usageExportColumns = []string{
+	"id", "user_id", "created_at", "request_type", "resource_id", "endpoint", "provider", "model_used",
+	"key_source", "tokens_input", "tokens_output", "tokens_total", "cost_usd", "duration_ms", "success", "error_message",
+}
+
+// ExportUsage streams raw usage rows as CSV or JSON Lines for
+// reconciliation. Users export their own usage; admins export every
+// user's, or one user's with user_id.
+// GET /api/v1/usage/export?from=&to=&format=csv|jsonl
+func (h *UsageHandler) ExportUsage(c *gin.Context) {
+	filter := models.UsageExportFilter{UserID: c.GetString("user_id"), To: time.Now()}
+	if middleware.HasRole(c, "admin") {
+		filter.UserID = c.Query("user_id")
+	} else if v := c.Query("user_id"); v != "" && v != filter.UserID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "only admins can export other users' usage", "code": "FORBIDDEN"})
+		return
+	}
+
+	var err error
+	if v := c.Query("to"); v != "" {
+		if filter.To, err = parseFeedbackTime(v, true); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "to must be RFC3339 or YYYY-MM-DD", "code": "INVALID_REQUEST"})
+			return
+		}
+	}
+	filter.From = filter.To.Add(-defaultUsageExportWindow)
+	if v := c.Query("from"); v != "" {
+		if filter.From, err = parseFeedbackTime(v, false); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "from must be RFC3339 or YYYY-MM-DD", "code": "INVALID_REQUEST"})
+			return
+		}
+	}
+	if !filter.From.Before(filter.To) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from must be before to", "code": "INVALID_REQUEST"})
+		return
+	}
+
+	format := c.DefaultQuery("format", "csv")
+	var contentType string
+	switch format {
+	case "csv":
+		contentType = "text/csv; charset=utf-8"
+	case "jsonl":
+		contentType = "application/x-ndjson"
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be 'csv' or 'jsonl'", "code": "INVALID_REQUEST"})
+		return
+	}
+
+	filename := fmt.Sprintf("usage-%s-%s.%s", filter.From.UTC().Format("20060102"), filter.To.UTC().Format("20060102"), format)
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Status(http.StatusOK)
+
+	// Rows are written as they are read; an error part way through can
+	// only cut the export short
+	rows := 0
+	var write func(*models.UsageMetric) error
+	if format == "csv" {
+		w := csv.NewWriter(c.Writer)
+		if err := w.Write(usageExportColumns); err != nil {
+			return
+		}
+		write = func(m *models.UsageMetric) error {
+			if err := w.Write(usageExportRecord(m)); err != nil {
+				return err
+			}
+			if rows++; rows%usageExportFlushRows == 0 {
+				w.Flush()
+				c.Writer.Flush()
+			}
+			return w.Error()
+		}
+		defer w.Flush()
+	} else {
+		enc := json.NewEncoder(c.Writer)
+		write = func(m *models.UsageMetric) error {
+			if err := enc.Encode(m); err != nil {
+				return err
+			}
+			if rows++; rows%usageExportFlushRows == 0 {
+				c.Writer.Flush()
+			}
+			return nil
+		}
+	}
+
+	if err := h.usageService.ExportUsage(filter, write); err != nil {
+		log.Printf("⚠️  Usage export stopped after %d rows: %v", rows, err)
+	}
+}
+
+func usageExportRecord(m *models.UsageMetric) []string {
+	resourceID := ""
+	if m.ResourceID != 0 {
+		resourceID = strconv.FormatInt(m.ResourceID, 10)
+	}
+	return []string{
+		strconv.FormatInt(m.ID, 10),
+		m.UserID,
+		m.CreatedAt.UTC().Format(time.RFC3339Nano),
+		m.RequestType,
+		resourceID,
+		m.Endpoint,
+		m.Provider,
+		m.ModelUsed,
+		m.KeySource,
+		strconv.Itoa(m.TokensInput),
+		strconv.Itoa(m.TokensOutput),
+		strconv.Itoa(m.TokensTotal),
+		strconv.FormatFloat(m.CostUSD, 'f', -1, 64),
+		strconv.FormatInt(m.DurationMs, 10),
+		strconv.FormatBool(m.Success),
+		m.ErrorMessage,
+	}
+}
@@ -1,10 +1,14 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 
+	"lio-ai/internal/middleware"
 	"lio-ai/internal/models"
 	"lio-ai/internal/services"
 )
@@ -21,12 +25,12 @@ func NewUsageHandler(usageService *services.UsageService) *UsageHandler {
 	}
 }
 
-// GetQuotaStatus retrieves the current quota status
+// GetQuotaStatus retrieves the current quota status of the authenticated
+// user. Admins can see another user's with user_id.
 // GET /api/v1/usage/quota
 func (h *UsageHandler) GetQuotaStatus(c *gin.Context) {
-	userID := c.Query("user_id")
-	if userID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "user_id is required"})
+	userID, ok := usageUserID(c)
+	if !ok {
 		return
 	}
 
@@ -39,12 +43,12 @@ func (h *UsageHandler) GetQuotaStatus(c *gin.Context) {
 	c.JSON(http.StatusOK, status)
 }
 
-// GetUsageSummary retrieves aggregated usage statistics
+// GetUsageSummary retrieves aggregated usage statistics of the
+// authenticated user. Admins can see another user's with user_id.
 // GET /api/v1/usage/summary
 func (h *UsageHandler) GetUsageSummary(c *gin.Context) {
-	userID := c.Query("user_id")
-	if userID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "user_id is required"})
+	userID, ok := usageUserID(c)
+	if !ok {
 		return
 	}
 
@@ -54,8 +58,21 @@ func (h *UsageHandler) GetUsageSummary(c *gin.Context) {
 		return
 	}
 
-	summary, err := h.usageService.GetUsageSummary(userID, period)
+	filter, ok := usageFilter(c)
+	if !ok {
+		return
+	}
+	from, to, ok := usageRange(c)
+	if !ok {
+		return
+	}
+
+	summary, err := h.usageService.GetUsageSummary(userID, period, from, to, filter)
 	if err != nil {
+		if errors.Is(err, services.ErrInvalidMessage) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -63,6 +80,60 @@ func (h *UsageHandler) GetUsageSummary(c *gin.Context) {
 	c.JSON(http.StatusOK, summary)
 }
 
+// usageUserID is the user whose usage a request is for: the authenticated
+// user, or the one named by user_id for admins. Other users naming
+// someone else get a 403.
+func usageUserID(c *gin.Context) (string, bool) {
+	userID := c.GetString("user_id")
+	if v := c.Query("user_id"); v != "" && v != userID {
+		if !middleware.HasRole(c, "admin") {
+			c.JSON(http.StatusForbidden, gin.H{"error": "only admins can view other users' usage", "code": "FORBIDDEN"})
+			return "", false
+		}
+		userID = v
+	}
+	return userID, true
+}
+
+// usageFilter parses the model, provider, endpoint and success filters of
+// a usage summary, writing a 400 when one is invalid
+func usageFilter(c *gin.Context) (*models.UsageFilter, bool) {
+	filter := &models.UsageFilter{
+		Model:    c.Query("model"),
+		Provider: c.Query("provider"),
+		Endpoint: c.Query("endpoint"),
+	}
+	if v := c.Query("success"); v != "" {
+		success, err := strconv.ParseBool(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "success must be true or false"})
+			return nil, false
+		}
+		filter.Success = &success
+	}
+	return filter, true
+}
+
+// usageRange parses the optional from and to of a usage summary, as
+// RFC3339 with any offset or as a YYYY-MM-DD UTC day (to's day included),
+// writing a 400 when either is invalid
+func usageRange(c *gin.Context) (from, to time.Time, ok bool) {
+	var err error
+	if v := c.Query("from"); v != "" {
+		if from, err = parseFeedbackTime(v, false); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "from must be RFC3339 or YYYY-MM-DD"})
+			return from, to, false
+		}
+	}
+	if v := c.Query("to"); v != "" {
+		if to, err = parseFeedbackTime(v, true); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "to must be RFC3339 or YYYY-MM-DD"})
+			return from, to, false
+		}
+	}
+	return from, to, true
+}
+
 // TrackUsage manually tracks a usage event (internal endpoint)
 // POST /api/v1/usage/track
 func (h *UsageHandler) TrackUsage(c *gin.Context) {
@@ -130,12 +201,12 @@ func (h *UsageHandler) UpdateQuota(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "quota updated successfully"})
 }
 
-// GetDashboard returns a comprehensive dashboard of usage metrics
+// GetDashboard returns a comprehensive dashboard of the authenticated
+// user's usage metrics. Admins can see another user's with user_id.
 // GET /api/v1/usage/dashboard
 func (h *UsageHandler) GetDashboard(c *gin.Context) {
-	userID := c.Query("user_id")
-	if userID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "user_id is required"})
+	userID, ok := usageUserID(c)
+	if !ok {
 		return
 	}
 
@@ -147,14 +218,14 @@ func (h *UsageHandler) GetDashboard(c *gin.Context) {
 	}
 
 	// Get daily summary
-	dailySummary, err := h.usageService.GetUsageSummary(userID, "daily")
+	dailySummary, err := h.usageService.GetUsageSummary(userID, "daily", time.Time{}, time.Time{}, nil)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
 	// Get monthly summary
-	monthlySummary, err := h.usageService.GetUsageSummary(userID, "monthly")
+	monthlySummary, err := h.usageService.GetUsageSummary(userID, "monthly", time.Time{}, time.Time{}, nil)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -169,3 +240,26 @@ func (h *UsageHandler) GetDashboard(c *gin.Context) {
 
 	c.JSON(http.StatusOK, dashboard)
 }
+
+// SimulateUsage projects the authenticated user's monthly cost had their
+// recent usage gone to other models
+// POST /api/v1/usage/simulate
+func (h *UsageHandler) SimulateUsage(c *gin.Context) {
+	var req models.SimulateUsageRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "code": "INVALID_REQUEST"})
+		return
+	}
+
+	sim, err := h.usageService.SimulateUsage(c.GetString("user_id"), &req)
+	if err != nil {
+		if errors.Is(err, services.ErrUnknownModel) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "code": "UNKNOWN_MODEL"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to simulate usage", "code": "SIMULATION_FAILED"})
+		return
+	}
+
+	c.JSON(http.StatusOK, sim)
+}
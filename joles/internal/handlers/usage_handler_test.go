@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"lio-ai/internal/models"
+	"lio-ai/internal/repositories"
+	"lio-ai/internal/services"
+)
+
+// newTestUsageRouter serves the usage endpoints as the user and roles
+// named by the X-Test-User and X-Test-Role headers
+func newTestUsageRouter(t *testing.T) (*gin.Engine, *services.UsageService) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	usageService := services.NewUsageService(repositories.NewUsageRepository(newTestDB(t)))
+	handler := NewUsageHandler(usageService)
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("user_id", c.GetHeader("X-Test-User"))
+		if role := c.GetHeader("X-Test-Role"); role != "" {
+			c.Set("roles", []string{role})
+		}
+		c.Next()
+	})
+	router.GET("/usage/quota", handler.GetQuotaStatus)
+	router.GET("/usage/summary", handler.GetUsageSummary)
+	router.GET("/usage/dashboard", handler.GetDashboard)
+	return router, usageService
+}
+
+func TestUsageEndpointsScopeToAuthenticatedUser(t *testing.T) {
+	router, usageService := newTestUsageRouter(t)
+	for userID, tokens := range map[string]int{"1": 100, "2": 900} {
+		err := usageService.TrackUsage(&models.UsageRequest{
+			UserID: userID, RequestType: "chat", ModelUsed: "gpt-4", TokensInput: tokens, Success: true,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name       string
+		path       string
+		user, role string
+		wantStatus int
+		wantUser   string
+	}{
+		{"quota is the caller's", "/usage/quota", "1", "", http.StatusOK, "1"},
+		{"summary is the caller's", "/usage/summary?period=all_time", "1", "", http.StatusOK, "1"},
+		{"dashboard is the caller's", "/usage/dashboard", "1", "", http.StatusOK, "1"},
+		{"naming yourself is allowed", "/usage/summary?period=all_time&user_id=1", "1", "", http.StatusOK, "1"},
+		{"quota of another user", "/usage/quota?user_id=2", "1", "", http.StatusForbidden, ""},
+		{"summary of another user", "/usage/summary?user_id=2", "1", "", http.StatusForbidden, ""},
+		{"dashboard of another user", "/usage/dashboard?user_id=2", "1", "", http.StatusForbidden, ""},
+		{"admin quota override", "/usage/quota?user_id=2", "1", "admin", http.StatusOK, "2"},
+		{"admin summary override", "/usage/summary?period=all_time&user_id=2", "1", "admin", http.StatusOK, "2"},
+		{"admin dashboard override", "/usage/dashboard?user_id=2", "1", "admin", http.StatusOK, "2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			req.Header.Set("X-Test-User", tt.user)
+			req.Header.Set("X-Test-Role", tt.role)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			var body map[string]interface{}
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatal(err)
+			}
+			if tt.wantStatus != http.StatusOK {
+				if body["code"] != "FORBIDDEN" {
+					t.Errorf("code = %v, want FORBIDDEN", body["code"])
+				}
+				return
+			}
+			if got := fmt.Sprint(body["user_id"]); got != tt.wantUser {
+				t.Errorf("user_id = %s, want %s", got, tt.wantUser)
+			}
+		})
+	}
+}
+
+func TestUsageSummaryCountsOnlyTheCallersUsage(t *testing.T) {
+	router, usageService := newTestUsageRouter(t)
+	for userID, tokens := range map[string]int{"1": 100, "2": 900} {
+		err := usageService.TrackUsage(&models.UsageRequest{
+			UserID: userID, RequestType: "chat", ModelUsed: "gpt-4", TokensInput: tokens, Success: true,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/usage/summary?period=all_time", nil)
+	req.Header.Set("X-Test-User", "1")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	var summary models.UsageSummary
+	if err := json.Unmarshal(w.Body.Bytes(), &summary); err != nil {
+		t.Fatal(err)
+	}
+	if summary.TotalRequests != 1 || summary.TotalTokensInput != 100 {
+		t.Errorf("summary = %d requests, %d input tokens; want user 1's 1 and 100", summary.TotalRequests, summary.TotalTokensInput)
+	}
+}
@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"lio-ai/internal/services"
+)
+
+// defaultResourceUsageWindow is how far back usage by resource goes when
+// from is left out
+const defaultResourceUsageWindow = 30 * 24 * time.Hour
+
+// GetUsageByResource lists the cost and tokens of the authenticated user's
+// usage per chat or document, most expensive first, with their titles.
+// Admins can see another user's with user_id.
+// GET /api/v1/usage/by-resource?type=chat|document&from=&to=&limit=&offset=
+func (h *UsageHandler) GetUsageByResource(c *gin.Context) {
+	userID, ok := usageUserID(c)
+	if !ok {
+		return
+	}
+
+	resourceType := c.Query("type")
+	if resourceType == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "type is required", "code": "INVALID_REQUEST"})
+		return
+	}
+
+	var err error
+	to := time.Now()
+	if v := c.Query("to"); v != "" {
+		if to, err = parseFeedbackTime(v, true); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "to must be RFC3339 or YYYY-MM-DD", "code": "INVALID_REQUEST"})
+			return
+		}
+	}
+	from := to.Add(-defaultResourceUsageWindow)
+	if v := c.Query("from"); v != "" {
+		if from, err = parseFeedbackTime(v, false); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "from must be RFC3339 or YYYY-MM-DD", "code": "INVALID_REQUEST"})
+			return
+		}
+	}
+
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	usage, total, err := h.usageService.GetUsageByResource(userID, resourceType, from, to, limit, offset)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidMessage) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "code": "INVALID_REQUEST"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get usage by resource", "code": "FETCH_FAILED"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"type":   resourceType,
+		"from":   from,
+		"to":     to,
+		"data":   usage,
+		"total":  total,
+		"limit":  limit,
+		"offset": offset,
+	})
+}
@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// usageStreamKeepAlive is how often an idle usage stream sends a comment,
+// so proxies don't close it
+const usageStreamKeepAlive = 25 * time.Second
+
+// StreamUsage pushes the user's usage to them as Server-Sent Events as it
+// is tracked, so dashboards can update without polling. Each "usage"
+// event's data is the usage row as JSON and its id the row's. Admins can
+// stream another user's usage with user_id.
+// GET /api/v1/usage/stream
+func (h *UsageHandler) StreamUsage(c *gin.Context) {
+	userID, ok := usageUserID(c)
+	if !ok {
+		return
+	}
+
+	events, unsubscribe := h.usageService.SubscribeUsage(userID)
+	defer unsubscribe()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	fmt.Fprint(c.Writer, ": connected\n\n")
+	c.Writer.Flush()
+
+	keepAlive := time.NewTicker(usageStreamKeepAlive)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(c.Writer, ": keep-alive\n\n"); err != nil {
+				return
+			}
+		case metric := <-events:
+			data, err := json.Marshal(metric)
+			if err != nil {
+				continue
+			}
+			if _, err := fmt.Fprintf(c.Writer, "id: %d\nevent: usage\ndata: %s\n\n", metric.ID, data); err != nil {
+				return
+			}
+		}
+		c.Writer.Flush()
+	}
+}
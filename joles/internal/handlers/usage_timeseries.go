@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"lio-ai/internal/models"
+	"lio-ai/internal/services"
+)
+
+// Windows charted when from is left out: a day of hours, or 30 days
+const (
+	defaultHourlyTimeseriesWindow = 24 * time.Hour
+	defaultDailyTimeseriesWindow  = 30 * 24 * time.Hour
+)
+
+// GetUsageTimeseries returns a usage metric summed into hourly or daily
+// UTC buckets so the dashboard can chart it. Admins can chart another
+// user's usage with user_id.
+// GET /api/v1/usage/timeseries?granularity=hour|day&metric=tokens|cost|requests&from=&to=
+func (h *UsageHandler) GetUsageTimeseries(c *gin.Context) {
+	userID, ok := usageUserID(c)
+	if !ok {
+		return
+	}
+
+	granularity := c.DefaultQuery("granularity", models.UsageGranularityDay)
+	metric := c.DefaultQuery("metric", models.UsageMetricTokens)
+
+	var err error
+	to := time.Now()
+	if v := c.Query("to"); v != "" {
+		if to, err = parseFeedbackTime(v, true); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "to must be RFC3339 or YYYY-MM-DD", "code": "INVALID_REQUEST"})
+			return
+		}
+	}
+	from := to.Add(-defaultDailyTimeseriesWindow)
+	if granularity == models.UsageGranularityHour {
+		from = to.Add(-defaultHourlyTimeseriesWindow)
+	}
+	if v := c.Query("from"); v != "" {
+		if from, err = parseFeedbackTime(v, false); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "from must be RFC3339 or YYYY-MM-DD", "code": "INVALID_REQUEST"})
+			return
+		}
+	}
+
+	series, err := h.usageService.GetUsageTimeseries(userID, granularity, metric, from, to)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidMessage) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "code": "INVALID_REQUEST"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get usage time series", "code": "FETCH_FAILED"})
+		return
+	}
+
+	c.JSON(http.StatusOK, series)
+}
@@ -0,0 +1,168 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"lio-ai/internal/models"
+	"lio-ai/internal/services"
+)
+
+// WebhookHandler handles users' webhooks and the console for testing them
+type WebhookHandler struct {
+	service *services.WebhookService
+}
+
+// NewWebhookHandler creates a new webhook handler
+func NewWebhookHandler(service *services.WebhookService) *WebhookHandler {
+	return &WebhookHandler{service: service}
+}
+
+// ListEvents handles GET /api/v1/webhooks/events
+func (h *WebhookHandler) ListEvents(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"events": models.WebhookEvents})
+}
+
+// ListWebhooks handles GET /api/v1/webhooks
+func (h *WebhookHandler) ListWebhooks(c *gin.Context) {
+	webhooks, err := h.service.List(c.GetString("user_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "failed to fetch webhooks",
+			"code":  "FETCH_FAILED",
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": webhooks})
+}
+
+// CreateWebhook handles POST /api/v1/webhooks. The response is the only
+// time the signing secret is shown.
+func (h *WebhookHandler) CreateWebhook(c *gin.Context) {
+	var req models.CreateWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+			"code":  "INVALID_REQUEST",
+		})
+		return
+	}
+
+	webhook, err := h.service.Create(c.GetString("user_id"), &req)
+	if err != nil {
+		h.respondError(c, err, "failed to create webhook")
+		return
+	}
+	c.JSON(http.StatusCreated, webhook)
+}
+
+// DeleteWebhook handles DELETE /api/v1/webhooks/:id
+func (h *WebhookHandler) DeleteWebhook(c *gin.Context) {
+	id, ok := webhookID(c)
+	if !ok {
+		return
+	}
+	if err := h.service.Delete(id, c.GetString("user_id")); err != nil {
+		h.respondError(c, err, "failed to delete webhook")
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// TestWebhook handles POST /api/v1/webhooks/:id/test: it sends a signed
+// sample payload of the chosen event type and returns the delivery, with
+// the receiver's status, the latency and an excerpt of its response. A
+// receiver that fails still answers 200; the delivery has success false.
+func (h *WebhookHandler) TestWebhook(c *gin.Context) {
+	id, ok := webhookID(c)
+	if !ok {
+		return
+	}
+	var req models.TestWebhookRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+				"code":  "INVALID_REQUEST",
+			})
+			return
+		}
+	}
+
+	delivery, err := h.service.Test(id, c.GetString("user_id"), req.EventType)
+	if err != nil {
+		h.respondError(c, err, "failed to send test delivery")
+		return
+	}
+	c.JSON(http.StatusOK, delivery)
+}
+
+// ListDeliveries handles GET /api/v1/webhooks/:id/deliveries, newest first
+func (h *WebhookHandler) ListDeliveries(c *gin.Context) {
+	id, ok := webhookID(c)
+	if !ok {
+		return
+	}
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	deliveries, err := h.service.Deliveries(id, c.GetString("user_id"), limit)
+	if err != nil {
+		h.respondError(c, err, "failed to fetch deliveries")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": deliveries, "limit": limit})
+}
+
+// RetryDelivery handles POST /api/v1/webhooks/:id/deliveries/:delivery_id/retry,
+// sending the delivery's payload again and returning the new delivery
+func (h *WebhookHandler) RetryDelivery(c *gin.Context) {
+	id, ok := webhookID(c)
+	if !ok {
+		return
+	}
+	deliveryID, err := strconv.ParseInt(c.Param("delivery_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "invalid delivery id",
+			"code":  "INVALID_ID",
+		})
+		return
+	}
+
+	delivery, err := h.service.Retry(id, c.GetString("user_id"), deliveryID)
+	if err != nil {
+		h.respondError(c, err, "failed to retry delivery")
+		return
+	}
+	c.JSON(http.StatusOK, delivery)
+}
+
+func webhookID(c *gin.Context) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "invalid webhook id",
+			"code":  "INVALID_ID",
+		})
+		return 0, false
+	}
+	return id, true
+}
+
+func (h *WebhookHandler) respondError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error(), "code": "NOT_FOUND"})
+	case errors.Is(err, services.ErrInvalidMessage), errors.Is(err, services.ErrWebhookAddress):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "code": "INVALID_REQUEST"})
+	case errors.Is(err, services.ErrWebhookLimit):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "code": "WEBHOOK_LIMIT_REACHED"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message, "code": "INTERNAL_ERROR"})
+	}
+}
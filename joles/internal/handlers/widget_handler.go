@@ -0,0 +1,161 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"lio-ai/internal/models"
+	"lio-ai/internal/services"
+)
+
+// WidgetHandler handles widget tokens and the completions of embedded
+// chat widgets
+type WidgetHandler struct {
+	service *services.WidgetService
+}
+
+// NewWidgetHandler creates a new widget handler
+func NewWidgetHandler(service *services.WidgetService) *WidgetHandler {
+	return &WidgetHandler{service: service}
+}
+
+// CreateToken handles POST /api/v1/widget-tokens. The token is only
+// included in this response.
+func (h *WidgetHandler) CreateToken(c *gin.Context) {
+	var req models.CreateWidgetTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "invalid request: " + err.Error(),
+			"code":  "INVALID_REQUEST",
+		})
+		return
+	}
+
+	token, err := h.service.CreateToken(c.GetString("user_id"), &req)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrPersonaNotFound), errors.Is(err, services.ErrUnauthorized):
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "persona not found",
+				"code":  "NOT_FOUND",
+			})
+		case errors.Is(err, services.ErrInvalidWidgetOrigin):
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+				"code":  "INVALID_REQUEST",
+			})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "failed to create widget token",
+				"code":  "INTERNAL_ERROR",
+			})
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, token)
+}
+
+// ListTokens handles GET /api/v1/widget-tokens
+func (h *WidgetHandler) ListTokens(c *gin.Context) {
+	tokens, err := h.service.ListTokens(c.GetString("user_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "failed to fetch widget tokens",
+			"code":  "FETCH_FAILED",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": tokens, "total": len(tokens)})
+}
+
+// RevokeToken handles DELETE /api/v1/widget-tokens/:id
+func (h *WidgetHandler) RevokeToken(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "invalid widget token id",
+			"code":  "INVALID_REQUEST",
+		})
+		return
+	}
+
+	if err := h.service.RevokeToken(id, c.GetString("user_id")); err != nil {
+		if errors.Is(err, services.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "widget token not found",
+				"code":  "NOT_FOUND",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "failed to revoke widget token",
+			"code":  "INTERNAL_ERROR",
+		})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// ChatCompletion handles POST /api/v1/widget/chat/completions, behind
+// WidgetAuth
+func (h *WidgetHandler) ChatCompletion(c *gin.Context) {
+	token := c.MustGet("widget_token").(*models.WidgetToken)
+
+	var req models.WidgetCompletionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "message is required (max 4000 characters)",
+			"code":  "INVALID_REQUEST",
+		})
+		return
+	}
+
+	resp, err := h.service.Complete(c.Request.Context(), token, c.GetString("widget_session"), &req)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrWidgetQuotaExceeded), errors.Is(err, services.ErrQuotaExceeded):
+			c.JSON(http.StatusTooManyRequests, gin.H{
+				"error": "this chat is unavailable right now, please try again later",
+				"code":  "WIDGET_QUOTA_EXCEEDED",
+			})
+		case errors.Is(err, services.ErrNotFound), errors.Is(err, services.ErrUnauthorized):
+			// Chats of other visitors look the same as missing ones
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "chat not found",
+				"code":  "NOT_FOUND",
+			})
+		case errors.Is(err, services.ErrContentBlocked):
+			c.JSON(http.StatusUnprocessableEntity, gin.H{
+				"error": services.ErrContentBlocked.Error(),
+				"code":  "CONTENT_BLOCKED",
+			})
+		case errors.Is(err, services.ErrInvalidMessage), errors.Is(err, services.ErrCostCapExceeded):
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+				"code":  "INVALID_REQUEST",
+			})
+		default:
+			if _, ok := services.IsAIServiceError(err); ok {
+				c.JSON(http.StatusBadGateway, gin.H{
+					"error": "AI service unavailable",
+					"code":  "AI_SERVICE_ERROR",
+				})
+				return
+			}
+			log.Printf("Widget %d completion failed: %v", token.ID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "failed to complete request",
+				"code":  "INTERNAL_ERROR",
+			})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
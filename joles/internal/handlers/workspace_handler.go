@@ -0,0 +1,182 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"lio-ai/internal/models"
+	"lio-ai/internal/services"
+)
+
+// WorkspaceHandler handles snapshots and restores of users' workspaces
+type WorkspaceHandler struct {
+	service *services.WorkspaceService
+}
+
+// NewWorkspaceHandler creates a new workspace handler
+func NewWorkspaceHandler(service *services.WorkspaceService) *WorkspaceHandler {
+	return &WorkspaceHandler{service: service}
+}
+
+// CreateSnapshot handles POST /api/v1/workspace/snapshots, queueing a
+// snapshot of the user's chats, documents and settings
+func (h *WorkspaceHandler) CreateSnapshot(c *gin.Context) {
+	snapshot, err := h.service.CreateSnapshot(c.GetString("user_id"), models.SnapshotTriggerManual)
+	if err != nil {
+		respondWorkspaceError(c, err)
+		return
+	}
+	c.JSON(http.StatusAccepted, snapshot)
+}
+
+// ListSnapshots handles GET /api/v1/workspace/snapshots
+func (h *WorkspaceHandler) ListSnapshots(c *gin.Context) {
+	snapshots, err := h.service.ListSnapshots(c.GetString("user_id"))
+	if err != nil {
+		respondWorkspaceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": snapshots})
+}
+
+// GetSnapshot handles GET /api/v1/workspace/snapshots/:id
+func (h *WorkspaceHandler) GetSnapshot(c *gin.Context) {
+	id, ok := workspaceJobID(c)
+	if !ok {
+		return
+	}
+	snapshot, err := h.service.GetSnapshot(id, c.GetString("user_id"))
+	if err != nil {
+		respondWorkspaceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, snapshot)
+}
+
+// DeleteSnapshot handles DELETE /api/v1/workspace/snapshots/:id
+func (h *WorkspaceHandler) DeleteSnapshot(c *gin.Context) {
+	id, ok := workspaceJobID(c)
+	if !ok {
+		return
+	}
+	if err := h.service.DeleteSnapshot(id, c.GetString("user_id")); err != nil {
+		respondWorkspaceError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// Restore handles POST /api/v1/workspace/restore. The workspace is replaced
+// by the snapshot named by snapshot_id, or by the latest one taken at or
+// before at; a snapshot of the current workspace is taken first so the
+// restore can be undone.
+func (h *WorkspaceHandler) Restore(c *gin.Context) {
+	var req models.RestoreWorkspaceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+			"code":  "INVALID_REQUEST",
+		})
+		return
+	}
+
+	job, err := h.service.Restore(c.GetString("user_id"), &req)
+	if err != nil {
+		respondWorkspaceError(c, err)
+		return
+	}
+	c.JSON(http.StatusAccepted, job)
+}
+
+// GetRestore handles GET /api/v1/workspace/restores/:id
+func (h *WorkspaceHandler) GetRestore(c *gin.Context) {
+	id, ok := workspaceJobID(c)
+	if !ok {
+		return
+	}
+	job, err := h.service.GetRestore(id, c.GetString("user_id"))
+	if err != nil {
+		respondWorkspaceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, job)
+}
+
+// GetSchedule handles GET /api/v1/workspace/snapshot-schedule
+func (h *WorkspaceHandler) GetSchedule(c *gin.Context) {
+	schedule, err := h.service.GetSchedule(c.GetString("user_id"))
+	if err != nil {
+		respondWorkspaceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, schedule)
+}
+
+// SetSchedule handles PUT /api/v1/workspace/snapshot-schedule;
+// interval_hours 0 turns scheduled snapshots off
+func (h *WorkspaceHandler) SetSchedule(c *gin.Context) {
+	var req models.SetSnapshotScheduleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+			"code":  "INVALID_REQUEST",
+		})
+		return
+	}
+
+	schedule, err := h.service.SetSchedule(c.GetString("user_id"), &req)
+	if err != nil {
+		respondWorkspaceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, schedule)
+}
+
+func workspaceJobID(c *gin.Context) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "invalid id",
+			"code":  "INVALID_REQUEST",
+		})
+		return 0, false
+	}
+	return id, true
+}
+
+func respondWorkspaceError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": err.Error(),
+			"code":  "NOT_FOUND",
+		})
+	case errors.Is(err, services.ErrInvalidMessage):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+			"code":  "INVALID_REQUEST",
+		})
+	case errors.Is(err, services.ErrWorkspaceBusy):
+		c.JSON(http.StatusConflict, gin.H{
+			"error": err.Error(),
+			"code":  "WORKSPACE_BUSY",
+		})
+	case errors.Is(err, services.ErrSnapshotUnusable):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error": err.Error(),
+			"code":  "SNAPSHOT_UNUSABLE",
+		})
+	case errors.Is(err, services.ErrWorkspaceSnapshotsDisabled):
+		c.JSON(http.StatusNotImplemented, gin.H{
+			"error": err.Error(),
+			"code":  "NOT_ENABLED",
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "workspace request failed",
+			"code":  "INTERNAL_ERROR",
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package mail
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+
+	"lio-ai/internal/config"
+)
+
+// Mailer sends plain-text email
+type Mailer interface {
+	Send(to, subject, body string) error
+}
+
+// NewMailerFromConfig builds an SMTP mailer when cfg names an SMTP host,
+// otherwise a mailer that only logs. Message bodies (which may hold
+// one-time links) are logged only when cfg.LogBodies is set, e.g. in
+// development.
+func NewMailerFromConfig(cfg config.MailConfig) Mailer {
+	if cfg.SMTPHost == "" {
+		return &LogMailer{LogBodies: cfg.LogBodies}
+	}
+	return &SMTPMailer{
+		Addr:     net.JoinHostPort(cfg.SMTPHost, cfg.SMTPPort),
+		Host:     cfg.SMTPHost,
+		Username: cfg.SMTPUsername,
+		Password: cfg.SMTPPassword,
+		From:     cfg.From,
+	}
+}
+
+// SMTPMailer sends mail through an SMTP relay, using STARTTLS when offered
+type SMTPMailer struct {
+	Addr     string
+	Host     string
+	Username string
+	Password string
+	From     string
+}
+
+// Send delivers a message to one recipient
+func (m *SMTPMailer) Send(to, subject, body string) error {
+	if strings.ContainsAny(to+subject, "\r\n") {
+		return fmt.Errorf("invalid mail header")
+	}
+
+	var auth smtp.Auth
+	if m.Username != "" {
+		auth = smtp.PlainAuth("", m.Username, m.Password, m.Host)
+	}
+
+	msg := strings.Join([]string{
+		"From: " + m.From,
+		"To: " + to,
+		"Subject: " + subject,
+		"Date: " + time.Now().UTC().Format(time.RFC1123Z),
+		"MIME-Version: 1.0",
+		"Content-Type: text/plain; charset=utf-8",
+		"",
+		body,
+	}, "\r\n")
+
+	if err := smtp.SendMail(m.Addr, auth, m.From, []string{to}, []byte(msg)); err != nil {
+		return fmt.Errorf("failed to send mail to %s: %w", to, err)
+	}
+	return nil
+}
+
+// LogMailer writes messages to the log instead of sending them
+type LogMailer struct {
+	LogBodies bool
+}
+
+// Send logs a message
+func (m *LogMailer) Send(to, subject, body string) error {
+	if m.LogBodies {
+		log.Printf("📧 Mail to %s: %s\n%s", to, subject, body)
+	} else {
+		log.Printf("📧 SMTP not configured, mail to %s not sent: %s", to, subject)
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package mail
+
+import (
+	"testing"
+
+	"lio-ai/internal/config"
+)
+
+func TestNewMailerFromConfig(t *testing.T) {
+	mailer := NewMailerFromConfig(config.MailConfig{
+		SMTPHost:     "smtp.example.com",
+		SMTPPort:     "2525",
+		SMTPUsername: "user",
+		SMTPPassword: "secret",
+		From:         "team@example.com",
+	})
+	smtpMailer, ok := mailer.(*SMTPMailer)
+	if !ok {
+		t.Fatalf("mailer is %T, want *SMTPMailer", mailer)
+	}
+	want := SMTPMailer{Addr: "smtp.example.com:2525", Host: "smtp.example.com", Username: "user", Password: "secret", From: "team@example.com"}
+	if *smtpMailer != want {
+		t.Errorf("mailer = %+v, want %+v", *smtpMailer, want)
+	}
+}
+
+func TestNewMailerFromConfigWithoutHostLogs(t *testing.T) {
+	mailer := NewMailerFromConfig(config.MailConfig{SMTPPort: "587", LogBodies: true})
+	logMailer, ok := mailer.(*LogMailer)
+	if !ok {
+		t.Fatalf("mailer is %T, want *LogMailer", mailer)
+	}
+	if !logMailer.LogBodies {
+		t.Error("LogBodies not carried over from the config")
+	}
+}
+
+func TestSMTPMailerRejectsHeaderInjection(t *testing.T) {
+	mailer := &SMTPMailer{Addr: "127.0.0.1:1", Host: "127.0.0.1", From: "no-reply@lio.ai"}
+	for _, to := range []string{"a@example.com\r\nBcc: b@example.com", "a@example.com\n"} {
+		if err := mailer.Send(to, "subject", "body"); err == nil || err.Error() != "invalid mail header" {
+			t.Errorf("Send(%q) err = %v, want invalid mail header", to, err)
+		}
+	}
+}
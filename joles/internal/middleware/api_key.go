@@ -0,0 +1,95 @@
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"lio-ai/internal/models"
+	"lio-ai/internal/services"
+)
+
+// APIKeyHeader is where a personal API key can be sent, instead of as an
+// Authorization bearer token
+const APIKeyHeader = "X-API-Key"
+
+// APIKeyAuth authenticates requests presenting a personal API key, as its
+// owner within the key's scopes: read for GET and HEAD requests, write for
+// any other. Owners who are admins keep the role only with the admin
+// scope. It runs before NewAuthMiddleware, which leaves requests it
+// authenticated alone; auth_method is set to "api_key".
+func APIKeyAuth(apiKeys *services.APIKeyService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		secret := c.GetHeader(APIKeyHeader)
+		if bearer := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer "); strings.HasPrefix(bearer, services.APIKeyPrefix) {
+			secret = bearer
+		}
+		if secret == "" || isPublicAuthEndpoint(c.Request.URL.Path) {
+			c.Next()
+			return
+		}
+
+		key, user, err := apiKeys.Authenticate(secret)
+		if err != nil {
+			if errors.Is(err, services.ErrAPIKeyInvalid) {
+				c.JSON(http.StatusUnauthorized, gin.H{
+					"error": "invalid or expired API key",
+					"code":  "INVALID_API_KEY",
+				})
+			} else {
+				log.Printf("Failed to authenticate API key: %v", err)
+				c.JSON(http.StatusInternalServerError, gin.H{
+					"error": "internal server error",
+					"code":  "INTERNAL_ERROR",
+				})
+			}
+			c.Abort()
+			return
+		}
+
+		scope := models.APIKeyScopeWrite
+		if c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead {
+			scope = models.APIKeyScopeRead
+		}
+		if !services.HasScope(key, scope) {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": fmt.Sprintf("API key lacks the %s scope", scope),
+				"code":  "INSUFFICIENT_SCOPE",
+			})
+			c.Abort()
+			return
+		}
+
+		role := user.Role
+		if role == "admin" && !services.HasScope(key, models.APIKeyScopeAdmin) {
+			role = "user"
+		}
+		c.Set("user_id", fmt.Sprint(user.ID))
+		c.Set("email", user.Email)
+		c.Set("roles", []string{role})
+		c.Set("authenticated", true)
+		c.Set("auth_method", "api_key")
+		c.Set("api_key_id", key.ID)
+
+		c.Next()
+	}
+}
+
+// RequireLoginToken rejects requests authenticated by an API key, for
+// endpoints such as managing the keys themselves that need a login
+func RequireLoginToken() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetString("auth_method") == "api_key" {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": "API keys cannot be used here; log in instead",
+				"code":  "LOGIN_REQUIRED",
+			})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
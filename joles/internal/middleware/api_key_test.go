@@ -0,0 +1,123 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"lio-ai/internal/models"
+	"lio-ai/internal/repositories"
+	"lio-ai/internal/services"
+)
+
+// apiKeyRouter serves /data for either method and /keys behind
+// RequireLoginToken, echoing who the request authenticated as
+func apiKeyRouter(apiKeys *services.APIKeyService) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(APIKeyAuth(apiKeys))
+	echo := func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{
+			"user_id":     c.GetString("user_id"),
+			"auth_method": c.GetString("auth_method"),
+			"roles":       c.GetStringSlice("roles"),
+		})
+	}
+	router.GET("/data", echo)
+	router.POST("/data", echo)
+	router.GET("/keys", RequireLoginToken(), echo)
+	return router
+}
+
+func serveAPIKey(router *gin.Engine, method, path, header, value string) (int, map[string]interface{}) {
+	req := httptest.NewRequest(method, path, strings.NewReader("{}"))
+	if header != "" {
+		req.Header.Set(header, value)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	var body map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &body)
+	return w.Code, body
+}
+
+func TestAPIKeyAuth(t *testing.T) {
+	conn := newTestDB(t)
+	user := createTestUser(t, conn, "alice", "admin")
+	apiKeys := services.NewAPIKeyService(repositories.NewAPIKeyRepository(conn), repositories.NewUserRepository(conn))
+	router := apiKeyRouter(apiKeys)
+
+	newKey := func(name string, scopes ...string) *models.CreatedAPIKey {
+		t.Helper()
+		key, err := apiKeys.CreateKey(user.ID, &models.CreateAPIKeyRequest{Name: name, Scopes: scopes})
+		if err != nil {
+			t.Fatalf("CreateKey: %v", err)
+		}
+		return key
+	}
+	readKey := newKey("read", models.APIKeyScopeRead)
+	writeKey := newKey("write", models.APIKeyScopeRead, models.APIKeyScopeWrite)
+	adminKey := newKey("admin", models.APIKeyScopeRead, models.APIKeyScopeAdmin)
+	revokedKey := newKey("revoked")
+	if err := apiKeys.RevokeKey(revokedKey.ID, user.ID); err != nil {
+		t.Fatalf("RevokeKey: %v", err)
+	}
+	expiredKey := newKey("expired")
+	if _, err := conn.Exec(`UPDATE api_keys SET expires_at = ? WHERE id = ?`, time.Now().Add(-time.Minute).UTC(), expiredKey.ID); err != nil {
+		t.Fatalf("expire key: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		method   string
+		path     string
+		header   string
+		value    string
+		wantCode int
+		wantErr  string
+		wantRole string
+	}{
+		{"read key via bearer token", http.MethodGet, "/data", "Authorization", "Bearer " + readKey.Key, http.StatusOK, "", "user"},
+		{"read key via header", http.MethodGet, "/data", APIKeyHeader, readKey.Key, http.StatusOK, "", "user"},
+		{"read key on write method", http.MethodPost, "/data", APIKeyHeader, readKey.Key, http.StatusForbidden, "INSUFFICIENT_SCOPE", ""},
+		{"write key on write method", http.MethodPost, "/data", APIKeyHeader, writeKey.Key, http.StatusOK, "", "user"},
+		{"admin scope keeps admin role", http.MethodGet, "/data", APIKeyHeader, adminKey.Key, http.StatusOK, "", "admin"},
+		{"unknown key", http.MethodGet, "/data", APIKeyHeader, services.APIKeyPrefix + "nope", http.StatusUnauthorized, "INVALID_API_KEY", ""},
+		{"revoked key", http.MethodGet, "/data", APIKeyHeader, revokedKey.Key, http.StatusUnauthorized, "INVALID_API_KEY", ""},
+		{"expired key", http.MethodGet, "/data", APIKeyHeader, expiredKey.Key, http.StatusUnauthorized, "INVALID_API_KEY", ""},
+		{"login token passes through", http.MethodGet, "/data", "Authorization", "Bearer eyJhbGciOi", http.StatusOK, "", ""},
+		{"key on login-only route", http.MethodGet, "/keys", APIKeyHeader, writeKey.Key, http.StatusForbidden, "LOGIN_REQUIRED", ""},
+		{"no key on login-only route", http.MethodGet, "/keys", "", "", http.StatusOK, "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, body := serveAPIKey(router, tt.method, tt.path, tt.header, tt.value)
+			if code != tt.wantCode {
+				t.Fatalf("status = %d, want %d (body %v)", code, tt.wantCode, body)
+			}
+			if tt.wantErr != "" {
+				if body["code"] != tt.wantErr {
+					t.Errorf("code = %v, want %s", body["code"], tt.wantErr)
+				}
+				return
+			}
+			if tt.wantRole == "" {
+				if body["auth_method"] != "" {
+					t.Errorf("auth_method = %v, want unset", body["auth_method"])
+				}
+				return
+			}
+			if body["auth_method"] != "api_key" {
+				t.Errorf("auth_method = %v, want api_key", body["auth_method"])
+			}
+			roles, _ := body["roles"].([]interface{})
+			if len(roles) != 1 || roles[0] != tt.wantRole {
+				t.Errorf("roles = %v, want [%s]", body["roles"], tt.wantRole)
+			}
+		})
+	}
+}
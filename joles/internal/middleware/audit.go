@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"lio-ai/internal/audit"
+)
+
+// AuditMiddleware records an audit event of eventType for every request in
+// the group, after the handler (and any role checks) have run. Register it
+// before RequireRole so denied attempts are captured too.
+func AuditMiddleware(logger *audit.Logger, eventType string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		outcome := audit.OutcomeSuccess
+		switch status := c.Writer.Status(); {
+		case status == http.StatusUnauthorized || status == http.StatusForbidden:
+			outcome = audit.OutcomeDenied
+		case status >= 400:
+			outcome = audit.OutcomeFailure
+		}
+
+		event := audit.Event{
+			Type:    eventType,
+			Outcome: outcome,
+			IP:      c.ClientIP(),
+			Method:  c.Request.Method,
+			Path:    c.Request.URL.Path,
+			Status:  c.Writer.Status(),
+		}
+		if userID, ok := c.Get("user_id"); ok {
+			event.ActorID, _ = userID.(string)
+		}
+		if email, ok := c.Get("email"); ok {
+			event.ActorEmail, _ = email.(string)
+		}
+		logger.Log(event)
+	}
+}
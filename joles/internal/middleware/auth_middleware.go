@@ -1,6 +1,7 @@
 package middleware
 
 import (
+	"errors"
 	"strings"
 
 	"github.com/gin-gonic/gin"
@@ -17,6 +18,12 @@ func NewAuthMiddleware(jwtManager *auth.JWTManager) gin.HandlerFunc {
 			return
 		}
 
+		// Already authenticated by a personal API key (APIKeyAuth)
+		if c.GetString("auth_method") == "api_key" {
+			c.Next()
+			return
+		}
+
 		// Get token from Authorization header or cookie
 		token := ""
 
@@ -46,6 +53,9 @@ func NewAuthMiddleware(jwtManager *auth.JWTManager) gin.HandlerFunc {
 
 		// Validate JWT token (only if token exists)
 		claims, err := jwtManager.ValidateToken(token)
+		if err == nil && claims.Guest {
+			err = errors.New("guest session tokens do not authenticate a user")
+		}
 		if err != nil {
 			c.JSON(401, gin.H{
 				"error": "invalid or expired token",
@@ -60,6 +70,11 @@ func NewAuthMiddleware(jwtManager *auth.JWTManager) gin.HandlerFunc {
 		c.Set("email", claims.Email)
 		c.Set("roles", claims.Roles)
 		c.Set("authenticated", true)
+		// The token's jti and expiry, for logout to revoke it
+		c.Set("token_id", claims.ID)
+		if claims.ExpiresAt != nil {
+			c.Set("token_expires_at", claims.ExpiresAt.Time)
+		}
 
 		c.Next()
 	}
@@ -134,12 +149,32 @@ func RequireRole(requiredRoles ...string) gin.HandlerFunc {
 	}
 }
 
+// HasRole reports whether the authenticated user has the given role
+func HasRole(c *gin.Context, role string) bool {
+	roles, ok := c.Get("roles")
+	if !ok {
+		return false
+	}
+	userRoles, _ := roles.([]string)
+	for _, r := range userRoles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
 // isPublicAuthEndpoint checks if the path is a public authentication endpoint
 // that should bypass auth validation completely
 func isPublicAuthEndpoint(path string) bool {
 	publicEndpoints := []string{
 		"/api/v1/auth/register",
 		"/api/v1/auth/login",
+		"/api/v1/auth/invitations/accept",
+		"/api/v1/auth/passkeys/login/",
+		"/scim/v2/",       // Authenticated by SCIMAuth
+		"/api/v1/widget/", // Authenticated by WidgetAuth
+		"/api/v1/cdc",     // Authenticated by ServiceTokenAuth
 	}
 
 	for _, endpoint := range publicEndpoints {
@@ -33,6 +33,13 @@ func CSRFMiddleware() gin.HandlerFunc {
 			return
 		}
 
+		// API keys are sent in a header, which other sites cannot make a
+		// browser add
+		if c.GetString("auth_method") == "api_key" {
+			c.Next()
+			return
+		}
+
 		// Get or generate CSRF token
 		token, err := c.Cookie(CSRFCookieName)
 		if err != nil || token == "" {
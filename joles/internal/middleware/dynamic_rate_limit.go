@@ -0,0 +1,121 @@
+package middleware
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"lio-ai/internal/services"
+)
+
+// quotaCacheTTL bounds how often quota consumption is re-read per user
+const quotaCacheTTL = 30 * time.Second
+
+// throttleStep maps a quota consumption threshold to a rate reduction
+type throttleStep struct {
+	percentUsed float64
+	factor      float64
+}
+
+// throttleSteps reduce the allowed rate as daily quota consumption grows.
+// Level 0 is unthrottled; each subsequent step is one level higher.
+var throttleSteps = []throttleStep{
+	{percentUsed: 75, factor: 0.5},
+	{percentUsed: 90, factor: 0.2},
+	{percentUsed: 100, factor: 0.05},
+}
+
+// ThrottleLevel returns the throttle level and rate factor for a quota usage percentage
+func ThrottleLevel(percentUsed float64) (int, float64) {
+	level, factor := 0, 1.0
+	for i, step := range throttleSteps {
+		if percentUsed >= step.percentUsed {
+			level, factor = i+1, step.factor
+		}
+	}
+	return level, factor
+}
+
+type quotaUsage struct {
+	percentUsed float64
+	rps         float64 // Unthrottled rate of the user's plan
+	expiresAt   time.Time
+}
+
+// quotaUsageCache caches per-user daily quota consumption
+type quotaUsageCache struct {
+	entries map[string]quotaUsage
+	mu      sync.Mutex
+}
+
+func (qc *quotaUsageCache) get(userID string, usageService *services.UsageService) (float64, float64) {
+	qc.mu.Lock()
+	entry, ok := qc.entries[userID]
+	qc.mu.Unlock()
+	if ok && time.Now().Before(entry.expiresAt) {
+		return entry.percentUsed, entry.rps
+	}
+
+	percentUsed, rps := 0.0, float64(defaultRPS)
+	status, err := usageService.GetQuotaStatus(userID)
+	if err != nil {
+		log.Printf("Failed to read quota for rate limiting (user=%s): %v", userID, err)
+	} else {
+		percentUsed = math.Max(status.DailyTokensPercentUsed, status.DailyCostPercentUsed)
+		if status.RequestsPerMinute > 0 {
+			rps = float64(status.RequestsPerMinute) / 60
+		}
+	}
+
+	qc.mu.Lock()
+	qc.entries[userID] = quotaUsage{percentUsed: percentUsed, rps: rps, expiresAt: time.Now().Add(quotaCacheTTL)}
+	qc.mu.Unlock()
+	return percentUsed, rps
+}
+
+// DynamicRateLimitMiddleware rate limits authenticated users by user ID, at
+// their plan's rate when it has one, and progressively lowers their allowed
+// rate as they approach their daily token/cost quota. Anonymous requests fall back to per-IP limiting, and
+// exempt requests are not limited.
+func DynamicRateLimitMiddleware(limiter *RateLimiter, usageService *services.UsageService) gin.HandlerFunc {
+	cache := &quotaUsageCache{entries: make(map[string]quotaUsage)}
+
+	return func(c *gin.Context) {
+		if exempt(c) {
+			c.Next()
+			return
+		}
+
+		userID := c.GetString("user_id")
+		if userID == "" {
+			RateLimitMiddleware(limiter)(c)
+			return
+		}
+
+		percentUsed, planRPS := cache.get(userID, usageService)
+		level, factor := ThrottleLevel(percentUsed)
+
+		rps := planRPS * factor
+		burst := int(math.Max(1, math.Round(defaultBurst*factor)))
+
+		c.Header("X-RateLimit-Limit", fmt.Sprintf("%.2f", rps))
+		c.Header("X-RateLimit-Throttle-Level", fmt.Sprintf("%d", level))
+		c.Header("X-Quota-Daily-Percent-Used", fmt.Sprintf("%.1f", percentUsed))
+
+		if !limiter.AllowAt("user:"+userID, rps, burst) {
+			c.JSON(429, gin.H{
+				"error":          "Rate limit exceeded",
+				"retry_after":    int(math.Ceil(1 / rps)),
+				"throttle_level": level,
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
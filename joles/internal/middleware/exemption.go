@@ -0,0 +1,32 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"lio-ai/internal/services"
+)
+
+// ExemptionTokenHeader carries the token of a rate limit exemption
+const ExemptionTokenHeader = "X-Exemption-Token"
+
+// exemptionKey is the gin context key set to the name of the exemption a
+// request matched
+const exemptionKey = "rate_limit_exemption"
+
+// RateLimitExemptions recognizes synthetic traffic on the admin exemption
+// list, by the token in X-Exemption-Token or the client address. Matching
+// requests skip rate limiting and are kept out of usage tracking and
+// quotas; it must run before the rate limiter.
+func RateLimitExemptions(exemptions *services.ExemptionService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if e := exemptions.Match(c.GetHeader(ExemptionTokenHeader), c.ClientIP()); e != nil {
+			c.Set(exemptionKey, e.Name)
+			c.Request = c.Request.WithContext(services.WithUsageExemption(c.Request.Context()))
+		}
+		c.Next()
+	}
+}
+
+// exempt reports whether the request matched a rate limit exemption
+func exempt(c *gin.Context) bool {
+	return c.GetString(exemptionKey) != ""
+}
@@ -3,6 +3,7 @@ package middleware
 import (
 	"fmt"
 	"log"
+	"strings"
 	"sync"
 	"time"
 
@@ -10,6 +11,12 @@ import (
 	"golang.org/x/time/rate"
 )
 
+// Default per-client rate limit
+const (
+	defaultRPS   = 100
+	defaultBurst = 10
+)
+
 // RateLimiter implements token bucket rate limiting.
 type RateLimiter struct {
 	limiters map[string]*rate.Limiter
@@ -38,13 +45,34 @@ func (rl *RateLimiter) Allow(clientID string) bool {
 
 	if !exists {
 		// Default: 100 requests per second, burst of 10
-		rl.AddClient(clientID, 100, 10)
+		rl.AddClient(clientID, defaultRPS, defaultBurst)
 		limiter, _ = rl.limiters[clientID]
 	}
 
 	return limiter.Allow()
 }
 
+// AllowAt checks if the request is allowed, adjusting the client's
+// limit first when it differs from the given rate.
+func (rl *RateLimiter) AllowAt(clientID string, rps float64, burst int) bool {
+	rl.mu.Lock()
+	limiter, exists := rl.limiters[clientID]
+	if !exists {
+		limiter = rate.NewLimiter(rate.Limit(rps), burst)
+		rl.limiters[clientID] = limiter
+	}
+	rl.mu.Unlock()
+
+	if limiter.Limit() != rate.Limit(rps) {
+		limiter.SetLimit(rate.Limit(rps))
+	}
+	if limiter.Burst() != burst {
+		limiter.SetBurst(burst)
+	}
+
+	return limiter.Allow()
+}
+
 // RateLimitMiddleware creates a Gin middleware for rate limiting.
 func RateLimitMiddleware(limiter *RateLimiter) gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -108,9 +136,18 @@ func CORSMiddleware() gin.HandlerFunc {
 		if isAllowed {
 			c.Writer.Header().Set("Access-Control-Allow-Origin", origin)
 			c.Writer.Header().Set("Access-Control-Allow-Credentials", "true")
+			// Documents' versions, sent back in If-Match on update, and
+			// the ID admins see a request under in GET /admin/inflight
+			c.Writer.Header().Set("Access-Control-Expose-Headers", "ETag, X-Request-ID")
+		} else if origin != "" && strings.HasPrefix(c.Request.URL.Path, "/api/v1/widget/") {
+			// Widgets are embedded on any site; WidgetAuth checks the
+			// origin against the widget token. No cookies are involved.
+			c.Writer.Header().Set("Access-Control-Allow-Origin", origin)
+			c.Writer.Header().Set("Access-Control-Expose-Headers", WidgetSessionHeader)
+			c.Writer.Header().Add("Vary", "Origin")
 		}
 		
-		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With")
+		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With, Idempotency-Key, If-Match, X-Widget-Token, X-Widget-Session")
 		c.Writer.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS, GET, PUT, DELETE, PATCH")
 
 		if c.Request.Method == "OPTIONS" {
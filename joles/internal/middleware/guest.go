@@ -0,0 +1,90 @@
+package middleware
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"log"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"lio-ai/internal/auth"
+)
+
+// GuestCookieName holds the signed guest session token
+const GuestCookieName = "guest_session"
+
+// GuestIDPrefix marks user IDs that belong to guest sessions
+const GuestIDPrefix = "guest_"
+
+// GuestSession lets unauthenticated clients use a route as a guest.
+// Authenticated requests pass through unchanged. Otherwise the guest
+// session cookie is validated, or a guest ID derived from the client's IP
+// and user agent is issued in a signed cookie valid for ttl, so dropping
+// the cookie does not start a fresh quota; user_id is set to the guest ID
+// with guest=true. Guests are limited to requestsPerMinute per client IP
+// on the routes using this.
+func GuestSession(jwtManager *auth.JWTManager, ttl time.Duration, limiter *RateLimiter, requestsPerMinute int) gin.HandlerFunc {
+	rps := float64(requestsPerMinute) / 60
+	burst := int(math.Max(1, math.Ceil(float64(requestsPerMinute)/10)))
+
+	return func(c *gin.Context) {
+		if authenticated, _ := c.Get("authenticated"); authenticated == true {
+			c.Next()
+			return
+		}
+
+		guestID := ""
+		if token, err := c.Cookie(GuestCookieName); err == nil && token != "" {
+			if claims, err := jwtManager.ValidateToken(token); err == nil && claims.Guest {
+				guestID = claims.UserID
+			}
+		}
+		if guestID == "" {
+			guestID = guestClientID(c)
+			token, err := jwtManager.GenerateGuestToken(guestID, ttl)
+			if err != nil {
+				log.Printf("Failed to issue guest session: %v", err)
+				c.JSON(http.StatusInternalServerError, gin.H{
+					"error": "internal server error",
+					"code":  "INTERNAL_ERROR",
+				})
+				c.Abort()
+				return
+			}
+			c.SetSameSite(http.SameSiteLaxMode)
+			c.SetCookie(
+				GuestCookieName,
+				token,
+				int(ttl.Seconds()),
+				"/",
+				"",
+				true,  // httpOnly
+				false, // secure (false for development)
+			)
+		}
+
+		if requestsPerMinute > 0 && !limiter.AllowAt("guest:"+c.ClientIP(), rps, burst) {
+			c.JSON(http.StatusTooManyRequests, gin.H{
+				"error":       "Rate limit exceeded",
+				"code":        "GUEST_RATE_LIMITED",
+				"retry_after": int(math.Ceil(1 / rps)),
+				"message":     "Guest access is rate limited. Create a free account for higher limits.",
+			})
+			c.Abort()
+			return
+		}
+
+		c.Set("user_id", guestID)
+		c.Set("guest", true)
+		c.Next()
+	}
+}
+
+// guestClientID derives a guest ID from the client's IP and user agent.
+// Hashing keeps both out of the database.
+func guestClientID(c *gin.Context) string {
+	sum := sha256.Sum256([]byte(c.ClientIP() + "|" + c.Request.UserAgent()))
+	return GuestIDPrefix + hex.EncodeToString(sum[:16])
+}
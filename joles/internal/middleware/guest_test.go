@@ -0,0 +1,85 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"lio-ai/internal/auth"
+)
+
+func newGuestRouter(t *testing.T, requestsPerMinute int) *gin.Engine {
+	t.Helper()
+	t.Setenv("JWT_SECRET_KEY", "test-secret-key-at-least-32-bytes!")
+	jwtManager, err := auth.NewJWTManager()
+	if err != nil {
+		t.Fatalf("NewJWTManager: %v", err)
+	}
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/chat", GuestSession(jwtManager, time.Hour, NewRateLimiter(), requestsPerMinute), func(c *gin.Context) {
+		c.String(http.StatusOK, c.GetString("user_id"))
+	})
+	return router
+}
+
+func serveGuest(router *gin.Engine, ip, userAgent string, cookies ...*http.Cookie) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/chat", nil)
+	req.RemoteAddr = ip + ":1234"
+	req.Header.Set("User-Agent", userAgent)
+	for _, cookie := range cookies {
+		req.AddCookie(cookie)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestGuestSessionKeepsGuestIDWithoutCookie(t *testing.T) {
+	router := newGuestRouter(t, 0)
+
+	first := serveGuest(router, "192.0.2.1", "browser")
+	second := serveGuest(router, "192.0.2.1", "browser")
+	if first.Code != http.StatusOK || second.Code != http.StatusOK {
+		t.Fatalf("status = %d and %d, want 200", first.Code, second.Code)
+	}
+	guestID := first.Body.String()
+	if guestID == "" || second.Body.String() != guestID {
+		t.Errorf("guest IDs without a cookie = %q and %q, want the same", guestID, second.Body.String())
+	}
+	if other := serveGuest(router, "192.0.2.2", "browser").Body.String(); other == guestID {
+		t.Errorf("another client got the same guest ID %q", other)
+	}
+
+	// The cookie keeps the guest ID when the client's IP changes
+	var cookie *http.Cookie
+	for _, c := range first.Result().Cookies() {
+		if c.Name == GuestCookieName {
+			cookie = c
+		}
+	}
+	if cookie == nil {
+		t.Fatalf("no %s cookie was set", GuestCookieName)
+	}
+	if moved := serveGuest(router, "198.51.100.7", "browser", cookie).Body.String(); moved != guestID {
+		t.Errorf("guest ID with the cookie from another IP = %q, want %q", moved, guestID)
+	}
+}
+
+func TestGuestSessionRateLimitsPerClientIP(t *testing.T) {
+	// 10 a minute allows a burst of one
+	router := newGuestRouter(t, 10)
+
+	if w := serveGuest(router, "192.0.2.1", "browser"); w.Code != http.StatusOK {
+		t.Fatalf("first request: status = %d, want 200", w.Code)
+	}
+	// Neither a new user agent nor dropping the cookie gets around the limit
+	if w := serveGuest(router, "192.0.2.1", "another browser"); w.Code != http.StatusTooManyRequests {
+		t.Errorf("second request from the IP: status = %d, want 429", w.Code)
+	}
+	if w := serveGuest(router, "192.0.2.2", "browser"); w.Code != http.StatusOK {
+		t.Errorf("request from another IP: status = %d, want 200", w.Code)
+	}
+}
@@ -0,0 +1,148 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"lio-ai/internal/repositories"
+)
+
+const (
+	// IdempotencyKeyHeader is the request header carrying the client's key
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// IdempotencyKeyTTL is how long a key's response is replayed
+	IdempotencyKeyTTL = 24 * time.Hour
+
+	maxIdempotencyKeyLength = 255
+)
+
+// Idempotency replays the stored response when an authenticated request is
+// retried with the same Idempotency-Key, instead of running the handler
+// again. Keys are scoped to the user. A key reused with a different request
+// body is rejected, and 5xx and 429 responses are not stored so the client
+// can retry them. Requests without the header pass through unchanged.
+func Idempotency(repo *repositories.IdempotencyRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(IdempotencyKeyHeader)
+		if key == "" {
+			c.Next()
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"error": "Idempotency-Key must be at most 255 characters",
+				"code":  "INVALID_IDEMPOTENCY_KEY",
+			})
+			return
+		}
+		userID := c.GetString("user_id")
+		if userID == "" {
+			c.Next()
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"error": "failed to read request body",
+				"code":  "INVALID_REQUEST",
+			})
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		hash := requestHash(c.Request.Method, c.Request.URL.Path, body)
+
+		existing, err := repo.Reserve(userID, key, hash, IdempotencyKeyTTL)
+		if err != nil {
+			log.Printf("Idempotency check failed: %v", err)
+			c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{
+				"error": "could not check Idempotency-Key, please retry",
+				"code":  "IDEMPOTENCY_UNAVAILABLE",
+			})
+			return
+		}
+		if existing != nil {
+			replayIdempotent(c, existing, hash)
+			return
+		}
+
+		capture := &bodyCaptureWriter{ResponseWriter: c.Writer}
+		c.Writer = capture
+		completed := false
+		defer func() {
+			// Free the key if the handler panicked or the response is retryable
+			if !completed {
+				if err := repo.Release(userID, key); err != nil {
+					log.Printf("Failed to release idempotency key: %v", err)
+				}
+			}
+		}()
+
+		c.Next()
+
+		status := c.Writer.Status()
+		if status >= 500 || status == http.StatusTooManyRequests {
+			return
+		}
+		if err := repo.Complete(userID, key, status, c.Writer.Header().Get("Content-Type"), capture.body.Bytes()); err != nil {
+			log.Printf("Failed to store idempotent response: %v", err)
+			return
+		}
+		completed = true
+	}
+}
+
+// replayIdempotent answers a retried request from its stored record
+func replayIdempotent(c *gin.Context, rec *repositories.IdempotencyRecord, hash string) {
+	switch {
+	case rec.RequestHash != hash:
+		c.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{
+			"error": "Idempotency-Key was already used with a different request",
+			"code":  "IDEMPOTENCY_KEY_MISMATCH",
+		})
+	case rec.StatusCode == 0:
+		c.AbortWithStatusJSON(http.StatusConflict, gin.H{
+			"error": "a request with this Idempotency-Key is still being processed",
+			"code":  "IDEMPOTENCY_IN_PROGRESS",
+		})
+	default:
+		contentType := rec.ContentType
+		if contentType == "" {
+			contentType = "application/json; charset=utf-8"
+		}
+		c.Header("Idempotent-Replayed", "true")
+		c.Data(rec.StatusCode, contentType, rec.ResponseBody)
+		c.Abort()
+	}
+}
+
+// requestHash fingerprints a request so a reused key can be told apart
+// from a genuine retry
+func requestHash(method, path string, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(method + " " + path + "\n"))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// bodyCaptureWriter keeps a copy of the response body as it is written
+type bodyCaptureWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *bodyCaptureWriter) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *bodyCaptureWriter) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
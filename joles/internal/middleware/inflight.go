@@ -0,0 +1,28 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"lio-ai/internal/services"
+)
+
+// RequestIDHeader carries the ID a request is tracked under, as listed by
+// GET /api/v1/admin/inflight
+const RequestIDHeader = "X-Request-ID"
+
+// TrackInflight registers each request in the registry while it is being
+// served, under a context an admin can cancel. It must run after
+// authentication so requests are listed with their user.
+func TrackInflight(registry *services.RequestRegistry) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		route := c.FullPath()
+		if route == "" {
+			route = c.Request.URL.Path
+		}
+		id, ctx, done := registry.Begin(c.Request.Context(), c.Request.Method, route, c.Request.URL.Path, c.GetString("user_id"))
+		defer done()
+
+		c.Header(RequestIDHeader, id)
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
@@ -0,0 +1,31 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"lio-ai/internal/utils"
+)
+
+// ResponseVersioning rejects requests whose Accept header only names
+// response schema versions (application/vnd.lio.v<N>+json) this server
+// does not have, listing the ones it does
+func ResponseVersioning() gin.HandlerFunc {
+	supported := make([]string, 0, utils.LatestResponseVersion)
+	for v := utils.ResponseV1; v <= utils.LatestResponseVersion; v++ {
+		supported = append(supported, fmt.Sprintf("application/vnd.lio.v%d+json", v))
+	}
+
+	return func(c *gin.Context) {
+		if _, ok := utils.NegotiateResponseVersion(c.GetHeader("Accept")); !ok {
+			c.AbortWithStatusJSON(http.StatusNotAcceptable, gin.H{
+				"error":     "unsupported response schema version",
+				"code":      "NOT_ACCEPTABLE",
+				"supported": supported,
+			})
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,27 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SCIMAuth authenticates identity provider requests with a static bearer
+// token, answering failures with a SCIM error body
+func SCIMAuth(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		presented := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if token == "" || subtle.ConstantTimeCompare([]byte(presented), []byte(token)) != 1 {
+			c.Header("WWW-Authenticate", `Bearer realm="scim"`)
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"schemas": []string{"urn:ietf:params:scim:api:messages:2.0:Error"},
+				"status":  "401",
+				"detail":  "invalid or missing bearer token",
+			})
+			return
+		}
+		c.Next()
+	}
+}
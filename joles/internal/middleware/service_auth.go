@@ -0,0 +1,25 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ServiceTokenAuth authenticates another backend service with a static
+// bearer token. An empty token rejects every request.
+func ServiceTokenAuth(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		presented := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if token == "" || subtle.ConstantTimeCompare([]byte(presented), []byte(token)) != 1 {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"error": "invalid or missing service token",
+				"code":  "UNAUTHORIZED",
+			})
+			return
+		}
+		c.Next()
+	}
+}
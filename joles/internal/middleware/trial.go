@@ -0,0 +1,29 @@
+package middleware
+
+import (
+	"math"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TrialRateLimit limits anonymous trial requests to requestsPerMinute per
+// client IP, whatever device fingerprint they send. Zero disables it.
+func TrialRateLimit(limiter *RateLimiter, requestsPerMinute int) gin.HandlerFunc {
+	rps := float64(requestsPerMinute) / 60
+	burst := int(math.Max(1, math.Ceil(float64(requestsPerMinute)/10)))
+
+	return func(c *gin.Context) {
+		if requestsPerMinute > 0 && !limiter.AllowAt("trial:"+c.ClientIP(), rps, burst) {
+			c.JSON(http.StatusTooManyRequests, gin.H{
+				"error":       "Rate limit exceeded",
+				"code":        "TRIAL_RATE_LIMITED",
+				"retry_after": int(math.Ceil(1 / rps)),
+				"message":     "Trial access is rate limited. Create a free account for higher limits.",
+			})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
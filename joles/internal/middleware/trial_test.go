@@ -0,0 +1,36 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestTrialRateLimitIsPerIPWhateverTheFingerprint(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/trial", TrialRateLimit(NewRateLimiter(), 10), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	serve := func(ip, fingerprint string) int {
+		req := httptest.NewRequest(http.MethodPost, "/trial", nil)
+		req.RemoteAddr = ip + ":1234"
+		req.Header.Set("X-Device-Fingerprint", fingerprint)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	// A burst of 1 at 10 per minute
+	if code := serve("10.0.0.1", "a"); code != http.StatusOK {
+		t.Fatalf("first request = %d, want 200", code)
+	}
+	if code := serve("10.0.0.1", "b"); code != http.StatusTooManyRequests {
+		t.Errorf("second request with a new fingerprint = %d, want 429", code)
+	}
+	if code := serve("10.0.0.2", "a"); code != http.StatusOK {
+		t.Errorf("request from another IP = %d, want 200", code)
+	}
+}
@@ -0,0 +1,77 @@
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"lio-ai/internal/services"
+)
+
+// Headers an embedded widget sends: its public token, and the visitor
+// session its conversations belong to
+const (
+	WidgetTokenHeader   = "X-Widget-Token"
+	WidgetSessionHeader = "X-Widget-Session"
+)
+
+// WidgetAuth authenticates embedded chat widgets by their public token.
+// The request must come from one of the token's origins and stay within
+// its per-minute rate, shared by all of the widget's visitors. A visitor
+// without a valid session is given a new one in the X-Widget-Session
+// response header. The token is set as widget_token and the session as
+// widget_session.
+func WidgetAuth(widgets *services.WidgetService, limiter *RateLimiter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token, err := widgets.Authenticate(c.GetHeader(WidgetTokenHeader), c.GetHeader("Origin"))
+		if err != nil {
+			switch {
+			case errors.Is(err, services.ErrWidgetTokenInvalid):
+				c.JSON(http.StatusUnauthorized, gin.H{
+					"error": "invalid widget token",
+					"code":  "INVALID_WIDGET_TOKEN",
+				})
+			case errors.Is(err, services.ErrWidgetOrigin):
+				c.JSON(http.StatusForbidden, gin.H{
+					"error": err.Error(),
+					"code":  "WIDGET_ORIGIN_FORBIDDEN",
+				})
+			default:
+				log.Printf("Failed to authenticate widget: %v", err)
+				c.JSON(http.StatusInternalServerError, gin.H{
+					"error": "internal server error",
+					"code":  "INTERNAL_ERROR",
+				})
+			}
+			c.Abort()
+			return
+		}
+
+		rpm := token.Limits.RequestsPerMinute
+		rps := float64(rpm) / 60
+		burst := int(math.Max(1, math.Ceil(float64(rpm)/10)))
+		if !limiter.AllowAt(fmt.Sprintf("widget:%d", token.ID), rps, burst) {
+			c.JSON(http.StatusTooManyRequests, gin.H{
+				"error":       "Rate limit exceeded",
+				"code":        "WIDGET_RATE_LIMITED",
+				"retry_after": int(math.Ceil(1 / rps)),
+			})
+			c.Abort()
+			return
+		}
+
+		session, err := uuid.Parse(c.GetHeader(WidgetSessionHeader))
+		if err != nil {
+			session = uuid.New()
+		}
+		c.Header(WidgetSessionHeader, session.String())
+
+		c.Set("widget_token", token)
+		c.Set("widget_session", session.String())
+		c.Next()
+	}
+}
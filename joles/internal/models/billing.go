@@ -0,0 +1,64 @@
+package models
+
+import "time"
+
+// BillingPeriodFormat is how a statement's month is written
+const BillingPeriodFormat = "2006-01"
+
+// BillingStatement is a user's bill for a UTC month, closed once the month
+// is over and never changed after. TotalUSD is the usage cost plus the
+// adjustments closed into it.
+type BillingStatement struct {
+	ID             int64     `json:"id"`
+	UserID         string    `json:"user_id"`
+	Period         string    `json:"period"`
+	PeriodStart    time.Time `json:"period_start"`
+	PeriodEnd      time.Time `json:"period_end"`
+	Requests       int64     `json:"requests"`
+	TokensInput    int64     `json:"tokens_input"`
+	TokensOutput   int64     `json:"tokens_output"`
+	TokensTotal    int64     `json:"tokens_total"`
+	UsageCostUSD   float64   `json:"usage_cost_usd"`
+	AdjustmentsUSD float64   `json:"adjustments_usd"`
+	TotalUSD       float64   `json:"total_usd"`
+	ClosedAt       time.Time `json:"closed_at"`
+
+	// Set when a single statement is fetched
+	Lines       []BillingStatementLine `json:"lines,omitempty"`
+	Adjustments []BillingAdjustment    `json:"adjustments,omitempty"`
+}
+
+// BillingStatementLine is a statement's usage of one model
+type BillingStatementLine struct {
+	Model        string  `json:"model"`
+	Requests     int64   `json:"requests"`
+	TokensInput  int64   `json:"tokens_input"`
+	TokensOutput int64   `json:"tokens_output"`
+	TokensTotal  int64   `json:"tokens_total"`
+	CostUSD      float64 `json:"cost_usd"`
+}
+
+// BillingAdjustment is a credit (negative) or charge on a user's bill. It
+// is pending until the month it was added in is closed.
+type BillingAdjustment struct {
+	ID          int64     `json:"id"`
+	UserID      string    `json:"user_id"`
+	AmountUSD   float64   `json:"amount_usd"`
+	Reason      string    `json:"reason"`
+	CreatedBy   string    `json:"created_by,omitempty"`
+	StatementID *int64    `json:"statement_id,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// CreateBillingAdjustmentRequest adds an adjustment to a user's next
+// statement
+type CreateBillingAdjustmentRequest struct {
+	AmountUSD float64 `json:"amount_usd" binding:"required"`
+	Reason    string  `json:"reason" binding:"required,max=500"`
+}
+
+// CloseBillingPeriodRequest closes a past month (YYYY-MM) into statements
+// for the users who have none for it yet
+type CloseBillingPeriodRequest struct {
+	Period string `json:"period" binding:"required"`
+}
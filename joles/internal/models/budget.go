@@ -0,0 +1,51 @@
+package models
+
+import "time"
+
+// Budget scopes: a user's own budget, or one shared by an organization's
+// members with the organization's ID as scope ID
+const (
+	BudgetScopeUser = "user"
+	BudgetScopeOrg  = "org"
+)
+
+// What happens once a budget is spent: requests go through with a warning,
+// are sent to the cheapest chat model, or are refused
+const (
+	BudgetModeWarn     = "warn"
+	BudgetModeThrottle = "throttle"
+	BudgetModeBlock    = "block"
+)
+
+// ErrCodeBudgetExceeded is the error code of requests a spent "block"
+// budget refuses
+const ErrCodeBudgetExceeded = "BUDGET_EXCEEDED"
+
+// Budget is a monthly USD spending limit, kept apart from quotas. Months
+// start when quotas' do.
+type Budget struct {
+	ID         int64     `json:"id"`
+	Scope      string    `json:"scope"`
+	ScopeID    string    `json:"scope_id"`
+	MonthlyUSD float64   `json:"monthly_usd"`
+	Mode       string    `json:"mode"`
+	UpdatedBy  string    `json:"updated_by,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// SetBudgetRequest creates or changes a budget
+type SetBudgetRequest struct {
+	MonthlyUSD float64 `json:"monthly_usd" binding:"required,gt=0"`
+	Mode       string  `json:"mode"` // "block" when empty
+}
+
+// BudgetStatus is a budget with what has been spent against it this month
+type BudgetStatus struct {
+	Budget
+	SpentUSD     float64   `json:"spent_usd"`
+	RemainingUSD float64   `json:"remaining_usd"`
+	Exceeded     bool      `json:"exceeded"`
+	PeriodStart  time.Time `json:"period_start"`
+	ResetsAt     time.Time `json:"resets_at"`
+}
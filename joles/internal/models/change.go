@@ -0,0 +1,41 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Change feed entities
+const (
+	ChangeEntityChat    = "chat"
+	ChangeEntityMessage = "message"
+	ChangeEntityUsage   = "usage"
+)
+
+// Change feed operations
+const (
+	ChangeOpInsert = "insert"
+	ChangeOpUpdate = "update"
+	ChangeOpDelete = "delete"
+)
+
+// ChangeEvent is one write to a chat, message or usage row, in the order
+// the writes were committed. Data holds the row as written: the full row
+// for inserts and edits, only the changed columns for partial updates, and
+// nothing for deletes.
+type ChangeEvent struct {
+	ID        int64           `json:"id"` // Cursor; strictly increasing
+	Entity    string          `json:"entity"`
+	Op        string          `json:"op"`
+	EntityID  int64           `json:"entity_id"`
+	UserID    string          `json:"user_id"`
+	Data      json.RawMessage `json:"data,omitempty"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// ChangeFeedPage is a batch of change events after a cursor
+type ChangeFeedPage struct {
+	Data       []ChangeEvent `json:"data"`
+	NextCursor int64         `json:"next_cursor"` // Pass as since to continue
+	HasMore    bool          `json:"has_more"`
+}
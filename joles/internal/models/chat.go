@@ -1,63 +1,335 @@
 package models
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
 
 // Chat represents a chat conversation
 type Chat struct {
-	ID        int64     `json:"id"`
-	UserID    string    `json:"user_id"`
-	Title     string    `json:"title"`
-	ChatUUID  string    `json:"chat_uuid"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID       int64  `json:"id"`
+	UserID   string `json:"user_id"`
+	Title    string `json:"title"`
+	ChatUUID string `json:"chat_uuid"`
+	// How history is fitted into the model's context window: "truncate" or "summarize"
+	ContextStrategy string `json:"context_strategy"`
+	IsPinned        bool   `json:"is_pinned"` // Pinned chats are listed first
+	// Default caps applied to every response in the chat; 0 means no cap
+	MaxOutputTokens int     `json:"max_output_tokens,omitempty"`
+	MaxCostUSD      float64 `json:"max_cost_usd,omitempty"`
+	// Total cost budget for the chat; 0 means none. BudgetAction is
+	// "block" (the default) or "warn" once the budget is spent.
+	BudgetUSD    float64 `json:"budget_usd,omitempty"`
+	BudgetAction string  `json:"budget_action,omitempty"`
+	PersonaID    *int64  `json:"persona_id,omitempty"` // Persona whose system prompt and defaults apply
+	// Cached summary, included in chat lists with include=summary
+	Summary   *ChatSummary `json:"summary,omitempty"`
+	CreatedAt time.Time    `json:"created_at"`
+	UpdatedAt time.Time    `json:"updated_at"`
+}
+
+// Chat budget actions
+const (
+	BudgetActionBlock = "block"
+	BudgetActionWarn  = "warn"
+)
+
+// ChatBudgetStatus reports spending against a chat's budget
+type ChatBudgetStatus struct {
+	BudgetUSD    float64 `json:"budget_usd"`
+	SpentUSD     float64 `json:"spent_usd"`
+	RemainingUSD float64 `json:"remaining_usd"` // 0 once the budget is spent
+	Exceeded     bool    `json:"exceeded"`
+	Action       string  `json:"action"`
 }
 
+// ChatSummary is a short generated summary of a conversation
+type ChatSummary struct {
+	ChatID      int64     `json:"chat_id"`
+	Summary     string    `json:"summary"`
+	Model       string    `json:"model,omitempty"`
+	ActionItems []string  `json:"action_items"` // Follow-ups and open tasks from the conversation
+	Stale       bool      `json:"stale"`        // Messages arrived after it was generated
+	GeneratedAt time.Time `json:"generated_at"`
+}
+
+// Context window strategies
+const (
+	ContextStrategyTruncate  = "truncate"
+	ContextStrategySummarize = "summarize"
+)
+
 // Message represents a single message in a chat
 type Message struct {
+	ID         int64   `json:"id"`
+	ChatID     int64   `json:"chat_id"`
+	Seq        int64   `json:"seq"`  // Position in the chat, starting at 1
+	Role       string  `json:"role"` // "user", "assistant", "system", "tool"
+	Content    string  `json:"content"`
+	Model      *string `json:"model,omitempty"`
+	Tokens     int     `json:"tokens,omitempty"`
+	Stopped    bool    `json:"stopped,omitempty"`   // Generation was cancelled by the user
+	Truncated  bool    `json:"truncated,omitempty"` // Generation hit a token or cost cap
+	Bookmarked bool    `json:"bookmarked,omitempty"`
+	// Function calling: tool_calls requested by an assistant message, and
+	// the call a "tool" message answers
+	ToolCalls   json.RawMessage   `json:"tool_calls,omitempty"`
+	ToolCallID  *string           `json:"tool_call_id,omitempty"`
+	Moderation  *ModerationResult `json:"moderation,omitempty"` // Set on moderated user messages
+	Attachments []Attachment      `json:"attachments,omitempty"`
+	// Passages of the chat's documents put in the prompt of an assistant
+	// message
+	ContextChunks []ContextChunk `json:"context_chunks,omitempty"`
+	CreatedAt     time.Time      `json:"created_at"`
+}
+
+// ChatDocument is a document attached to a chat, whose passages relevant
+// to each prompt are added to the completion's context
+type ChatDocument struct {
+	ChatID     int64     `json:"chat_id"`
+	DocumentID uint      `json:"document_id"`
+	Title      string    `json:"title"`
+	AttachedAt time.Time `json:"attached_at"`
+}
+
+// AttachChatDocumentsRequest attaches documents to a chat as context
+type AttachChatDocumentsRequest struct {
+	DocumentIDs []uint `json:"document_ids" binding:"required,min=1,max=20,dive,gt=0"`
+}
+
+// ContextChunk identifies a passage of an attached document used as
+// context for a completion
+type ContextChunk struct {
+	DocumentID uint    `json:"document_id"`
+	Title      string  `json:"title"`
+	ChunkID    int64   `json:"chunk_id,omitempty"` // Set when found by semantic search
+	Seq        int     `json:"seq"`                // Position of the passage in the document
+	Score      float64 `json:"score"`
+}
+
+// ModerationResult is the outcome of checking a message with the
+// moderation provider
+type ModerationResult struct {
+	Flagged    bool      `json:"flagged"`
+	Categories []string  `json:"categories,omitempty"` // Categories the provider flagged
+	Model      string    `json:"model,omitempty"`
+	Strict     bool      `json:"strict,omitempty"` // Checked strictly after a jailbreak attempt
+	CheckedAt  time.Time `json:"checked_at"`
+}
+
+// Attachment is a file (e.g. an image) uploaded with a message
+type Attachment struct {
+	ID             int64     `json:"id"`
+	MessageID      int64     `json:"message_id"`
+	ChatID         int64     `json:"chat_id"`
+	UserID         string    `json:"user_id"`
+	Filename       string    `json:"filename"`
+	ContentType    string    `json:"content_type"`
+	SizeBytes      int64     `json:"size_bytes"`
+	StorageBackend string    `json:"-"`
+	StorageKey     string    `json:"-"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// Bookmark is a bookmarked message with the chat it belongs to
+type Bookmark struct {
+	Message
+	ChatTitle string `json:"chat_title"`
+	ChatUUID  string `json:"chat_uuid"`
+}
+
+// Feedback ratings
+const (
+	FeedbackUp   = "up"
+	FeedbackDown = "down"
+)
+
+// MessageFeedback is a user's rating of an assistant message
+type MessageFeedback struct {
 	ID        int64     `json:"id"`
+	MessageID int64     `json:"message_id"`
 	ChatID    int64     `json:"chat_id"`
-	Role      string    `json:"role"` // "user", "assistant", "system"
-	Content   string    `json:"content"`
-	Model     *string   `json:"model,omitempty"`
-	Tokens    int       `json:"tokens,omitempty"`
+	UserID    string    `json:"user_id"`
+	Rating    string    `json:"rating"` // "up" or "down"
+	Comment   string    `json:"comment,omitempty"`
+	Model     string    `json:"model,omitempty"` // Model that produced the message
 	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// FeedbackRequest rates a message; submitting again replaces the rating
+type FeedbackRequest struct {
+	Rating  string `json:"rating" binding:"required"`
+	Comment string `json:"comment"`
+}
+
+// FeedbackStats aggregates ratings for one model and period
+type FeedbackStats struct {
+	Period       string  `json:"period,omitempty"` // Day (YYYY-MM-DD) when grouped by day
+	Model        string  `json:"model"`
+	Up           int     `json:"up"`
+	Down         int     `json:"down"`
+	Total        int     `json:"total"`
+	Satisfaction float64 `json:"satisfaction"` // Up / Total
+	Comments     int     `json:"comments"`
 }
 
 // ChatWithMessages represents a chat with its messages
 type ChatWithMessages struct {
 	Chat
-	Messages []Message `json:"messages"`
+	Budget   *ChatBudgetStatus `json:"budget,omitempty"` // Set when the chat has a budget
+	Messages []Message         `json:"messages"`
+}
+
+// SummarizeChatRequest (re)generates a chat's summary; all fields are optional
+type SummarizeChatRequest struct {
+	Model   string `json:"model"`   // Defaults to the chat's most recent assistant model
+	Refresh bool   `json:"refresh"` // Regenerate even if the cached summary is current
+}
+
+// DuplicateChatRequest copies a chat; all fields are optional
+type DuplicateChatRequest struct {
+	Title string `json:"title"` // Defaults to "<original title> (copy)"
+	// Copy messages up to and including this one; 0 copies all of them
+	UpToMessageID int64 `json:"up_to_message_id"`
 }
 
 // ChatRequest represents the request to create a new chat
 type ChatRequest struct {
-	Title string `json:"title" binding:"required"`
+	Title           string `json:"title" binding:"required"`
+	ContextStrategy string `json:"context_strategy,omitempty"` // "truncate" (default) or "summarize"
+	// Per-response caps for the chat (PUT only); 0 clears a cap
+	MaxOutputTokens *int     `json:"max_output_tokens,omitempty" binding:"omitempty,min=0"`
+	MaxCostUSD      *float64 `json:"max_cost_usd,omitempty" binding:"omitempty,min=0"`
+	// Total cost budget for the chat (PUT only); 0 clears it
+	BudgetUSD    *float64 `json:"budget_usd,omitempty" binding:"omitempty,min=0"`
+	BudgetAction string   `json:"budget_action,omitempty"` // "block" or "warn"
+	PersonaID    *int64   `json:"persona_id,omitempty"`    // 0 clears the persona (PUT)
 }
 
 // MessageRequest represents the request to send a message
 type MessageRequest struct {
-	Role    string `json:"role" binding:"required"`
-	Content string `json:"content" binding:"required"`
-	Model   string `json:"model,omitempty"`
+	Role       string          `json:"role" binding:"required"`
+	Content    string          `json:"content"` // Required unless tool_calls is set
+	Model      string          `json:"model,omitempty"`
+	ToolCalls  json.RawMessage `json:"tool_calls,omitempty"`
+	ToolCallID string          `json:"tool_call_id,omitempty"`
+	// When set, the message is sent and answered at this time instead of now
+	ScheduledAt *time.Time `json:"scheduled_at,omitempty"`
+	// Set by the server on replies cut off by a response cap
+	Truncated bool `json:"-"`
+	// Set by the server on user messages that passed moderation
+	Moderation *ModerationResult `json:"-"`
+	// Set by the server on replies drawing on the chat's documents
+	ContextChunks []ContextChunk `json:"-"`
+}
+
+// Scheduled message statuses
+const (
+	ScheduleStatusPending   = "pending"
+	ScheduleStatusRunning   = "running"
+	ScheduleStatusCompleted = "completed"
+	ScheduleStatusFailed    = "failed"
+	ScheduleStatusCancelled = "cancelled"
+)
+
+// ScheduledMessage is a prompt queued to run against a chat at a future time
+type ScheduledMessage struct {
+	ID          int64     `json:"id"`
+	ChatID      int64     `json:"chat_id"`
+	UserID      string    `json:"user_id"`
+	Content     string    `json:"content"`
+	Model       string    `json:"model,omitempty"`
+	ScheduledAt time.Time `json:"scheduled_at"`
+	Status      string    `json:"status"`
+	Attempts    int       `json:"attempts"`
+	LastError   string    `json:"last_error,omitempty"`
+	MessageID   *int64    `json:"message_id,omitempty"` // Assistant reply once completed
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
 }
 
 // ChatCompletionRequest represents a request for chat completion
 type ChatCompletionRequest struct {
-	ChatID   int64  `json:"chat_id,omitempty"`
-	Message  string `json:"message" binding:"required"`
-	Model    string `json:"model,omitempty"`
-	Stream   bool   `json:"stream,omitempty"`
-	UserID   string `json:"user_id,omitempty"`
-	Title    string `json:"title,omitempty"`
+	ChatID  int64  `json:"chat_id,omitempty"`
+	Message string `json:"message"` // Required unless template_id is set
+	Model   string `json:"model,omitempty"`
+	Stream  bool   `json:"stream,omitempty"`
+	UserID  string `json:"user_id,omitempty"`
+	Title   string `json:"title,omitempty"`
+	// Persona for the new chat when chat_id is not set
+	PersonaID int64 `json:"persona_id,omitempty"`
+	// Owner of the persona when it is not the user's own, set for widget
+	// visitors chatting with the widget owner's persona
+	PersonaOwnerID string `json:"-"`
+	// Prompt template rendered as the user message; message, if also set,
+	// is appended after it
+	TemplateID int64             `json:"template_id,omitempty"`
+	Variables  map[string]string `json:"variables,omitempty"`
+	// Function calling: tool definitions passed to the provider, and the
+	// tool_call_id when message is a tool result
+	Tools      json.RawMessage `json:"tools,omitempty"`
+	ToolChoice json.RawMessage `json:"tool_choice,omitempty"`
+	ToolCallID string          `json:"tool_call_id,omitempty"`
+	// Caps for this response, combined with the chat's caps (the lower wins)
+	MaxTokens  int     `json:"max_tokens,omitempty" binding:"omitempty,min=0"`
+	MaxCostUSD float64 `json:"max_cost_usd,omitempty" binding:"omitempty,min=0"`
+	// Provider a routed model is pinned to, from the X-Provider header
+	Provider string `json:"-"`
 }
 
 // ChatCompletionResponse represents the response from chat completion
 type ChatCompletionResponse struct {
-	ChatID    int64     `json:"chat_id"`
-	MessageID int64     `json:"message_id"`
-	Role      string    `json:"role"`
-	Content   string    `json:"content"`
-	Model     *string   `json:"model,omitempty"`
-	Tokens    int       `json:"tokens"`
-	CreatedAt time.Time `json:"created_at"`
+	ChatID       int64   `json:"chat_id"`
+	MessageID    int64   `json:"message_id"`
+	Role         string  `json:"role"`
+	Content      string  `json:"content"`
+	Model        *string `json:"model,omitempty"`         // Model that answered
+	ModelAlias   string  `json:"model_alias,omitempty"`   // Alias the request named, if any
+	Route        string  `json:"route,omitempty"`         // Routed logical model the request named, if any
+	Provider     string  `json:"provider,omitempty"`      // Provider chosen for a routed model
+	FallbackFrom string  `json:"fallback_from,omitempty"` // Requested model, when a fallback answered
+	Tokens       int     `json:"tokens"`
+	Stopped      bool    `json:"stopped,omitempty"`
+	Truncated    bool    `json:"truncated,omitempty"` // Output was cut off by max_tokens or max_cost_usd
+	Cached       bool    `json:"cached,omitempty"`    // Served from the response cache; no tokens were billed
+	// Set when the requested model is deprecated; Model is the replacement
+	// once it is past its sunset
+	Deprecation *ModelDeprecationNotice `json:"deprecation,omitempty"`
+	// The user's monthly budget after this response, and the requested
+	// model when a spent "throttle" budget sent it to the cheapest one
+	SpendingBudget *BudgetStatus `json:"spending_budget,omitempty"`
+	ThrottledFrom  string        `json:"throttled_from,omitempty"`
+	// Chat budget after this response, and a warning when a "warn" budget is spent
+	Budget        *ChatBudgetStatus `json:"budget,omitempty"`
+	BudgetWarning string            `json:"budget_warning,omitempty"`
+	ToolCalls     json.RawMessage   `json:"tool_calls,omitempty"`
+	// Passages of the chat's attached documents added to the prompt
+	ContextChunks []ContextChunk `json:"context_chunks,omitempty"`
+	CreatedAt     time.Time      `json:"created_at"`
+}
+
+// CompareRequest runs one prompt against several models
+type CompareRequest struct {
+	Message string   `json:"message" binding:"required"`
+	Models  []string `json:"models" binding:"required,min=2,max=4"`
+}
+
+// ModelComparison is one model's answer in a comparison
+type ModelComparison struct {
+	Model        string  `json:"model"`
+	MessageID    int64   `json:"message_id,omitempty"`
+	Content      string  `json:"content,omitempty"`
+	LatencyMs    int64   `json:"latency_ms"`
+	TokensInput  int     `json:"tokens_input"`
+	TokensOutput int     `json:"tokens_output"`
+	CostUSD      float64 `json:"cost_usd"`
+	Error        string  `json:"error,omitempty"`
+}
+
+// CompareResponse holds side-by-side results for a comparison
+type CompareResponse struct {
+	ChatID        int64             `json:"chat_id"`
+	UserMessageID int64             `json:"user_message_id"`
+	Results       []ModelComparison `json:"results"`
 }
@@ -0,0 +1,38 @@
+package models
+
+import "time"
+
+// CleanupReport is the outcome of one garbage collection pass
+type CleanupReport struct {
+	InvitationsDeleted   int64 `json:"invitations_deleted"`    // Expired invitation tokens
+	ChallengesDeleted    int64 `json:"challenges_deleted"`     // Expired passkey challenges
+	RevokedTokensDeleted int64 `json:"revoked_tokens_deleted"` // Revocations of expired tokens
+	SessionsDeleted      int64 `json:"sessions_deleted"`       // Sessions of expired tokens
+	ReservationsDeleted  int64 `json:"reservations_deleted"`   // Lapsed quota holds
+	GuestSessionsDeleted int64 `json:"guest_sessions_deleted"` // Quotas of expired guest sessions
+	TrialUsageDeleted    int64 `json:"trial_usage_deleted"`    // Trial counters of past days
+	JobsDeleted          int64 `json:"jobs_deleted"`           // Finished import and restore jobs
+	BlobsDeleted         int64 `json:"blobs_deleted"`          // Stored objects no row refers to
+	BytesReclaimed       int64 `json:"bytes_reclaimed"`        // Size of the deleted objects
+	// Set when the storage backend cannot list its objects, so orphans
+	// were not looked for
+	BlobScanSkipped bool      `json:"blob_scan_skipped,omitempty"`
+	StartedAt       time.Time `json:"started_at"`
+	CompletedAt     time.Time `json:"completed_at"`
+}
+
+// RowsDeleted is the number of database rows the pass removed
+func (r *CleanupReport) RowsDeleted() int64 {
+	return r.InvitationsDeleted + r.ChallengesDeleted + r.RevokedTokensDeleted + r.SessionsDeleted +
+		r.ReservationsDeleted + r.GuestSessionsDeleted + r.TrialUsageDeleted + r.JobsDeleted
+}
+
+// CleanupStats sums the garbage collection passes since the server started
+type CleanupStats struct {
+	Runs           int64          `json:"runs"`
+	RowsDeleted    int64          `json:"rows_deleted"`
+	BlobsDeleted   int64          `json:"blobs_deleted"`
+	BytesReclaimed int64          `json:"bytes_reclaimed"`
+	LastRun        *CleanupReport `json:"last_run,omitempty"`
+	LastError      string         `json:"last_error,omitempty"`
+}
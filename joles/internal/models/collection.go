@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+// Collection is a folder of a user's documents. Collections nest one level
+// deep: a collection with a parent cannot have children of its own.
+type Collection struct {
+	ID            int64     `json:"id"`
+	UserID        string    `json:"user_id"`
+	Name          string    `json:"name"`
+	ParentID      *int64    `json:"parent_id"`
+	DocumentCount int64     `json:"document_count"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// CreateCollectionRequest represents the request payload for creating a
+// collection, at the top level or inside parent_id
+type CreateCollectionRequest struct {
+	Name     string `json:"name" binding:"required,min=1,max=255"`
+	ParentID *int64 `json:"parent_id" binding:"omitempty,gt=0"`
+}
+
+// UpdateCollectionRequest represents the request payload for renaming a
+// collection
+type UpdateCollectionRequest struct {
+	Name string `json:"name" binding:"required,min=1,max=255"`
+}
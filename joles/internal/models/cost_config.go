@@ -0,0 +1,78 @@
+package models
+
+import "time"
+
+// CreateCostConfigRequest prices a model that has no cost config yet
+type CreateCostConfigRequest struct {
+	ModelName          string   `json:"model_name" binding:"required,max=100"`
+	CostPerInputToken  *float64 `json:"cost_per_input_token" binding:"required"`
+	CostPerOutputToken *float64 `json:"cost_per_output_token" binding:"required"`
+	OperationType      string   `json:"operation_type"` // "chat" when empty
+}
+
+// UpdateCostConfigRequest changes a model's pricing; fields left out are
+// kept
+type UpdateCostConfigRequest struct {
+	CostPerInputToken  *float64 `json:"cost_per_input_token"`
+	CostPerOutputToken *float64 `json:"cost_per_output_token"`
+	OperationType      *string  `json:"operation_type"`
+	IsActive           *bool    `json:"is_active"`
+}
+
+// EffectivePrice is what usage of a model is charged right now: the
+// cost config of the model an alias points at, or the "default" one when
+// that model has no active config, with the platform key markup applied
+type EffectivePrice struct {
+	Model      string  `json:"model"`
+	Alias      string  `json:"alias,omitempty"` // Alias Model was reached through
+	PricedAs   string  `json:"priced_as"`       // Model name of the cost config charged
+	IsFallback bool    `json:"is_fallback"`     // Priced by the "default" config
+	KeySource  string  `json:"key_source"`
+	Markup     float64 `json:"markup"`
+
+	CostPerInputToken  float64 `json:"cost_per_input_token"`
+	CostPerOutputToken float64 `json:"cost_per_output_token"`
+	// USD charged for 1,000 input and 1,000 output tokens
+	InputCostPer1K  float64 `json:"input_cost_per_1k_usd"`
+	OutputCostPer1K float64 `json:"output_cost_per_1k_usd"`
+}
+
+// Where a price change came from
+const (
+	CostConfigSourceAdmin = "admin"
+	CostConfigSourceSync  = "sync"
+)
+
+// CostConfigChange records a model's price after a change, and the price
+// it replaced (nil when the model was first priced)
+type CostConfigChange struct {
+	ID                 int64     `json:"id"`
+	ModelName          string    `json:"model_name"`
+	CostPerInputToken  float64   `json:"cost_per_input_token"`
+	CostPerOutputToken float64   `json:"cost_per_output_token"`
+	PreviousInputCost  *float64  `json:"previous_input_cost"`
+	PreviousOutputCost *float64  `json:"previous_output_cost"`
+	OperationType      string    `json:"operation_type"`
+	IsActive           bool      `json:"is_active"`
+	Source             string    `json:"source"`
+	ChangedBy          string    `json:"changed_by,omitempty"`
+	CreatedAt          time.Time `json:"created_at"`
+}
+
+// PriceFeed is the document the price sync reads: per-token prices by
+// model, in the units of cost_config
+type PriceFeed struct {
+	UpdatedAt *time.Time          `json:"updated_at,omitempty"`
+	Prices    []CostConfigSetting `json:"prices"`
+}
+
+// PriceSyncReport describes one sync of cost configs from the price feed
+type PriceSyncReport struct {
+	Source      string    `json:"source"`
+	StartedAt   time.Time `json:"started_at"`
+	CompletedAt time.Time `json:"completed_at"`
+	Created     []string  `json:"created"`   // Models priced for the first time
+	Updated     []string  `json:"updated"`   // Models whose price changed
+	Unchanged   int       `json:"unchanged"` // Models already at the feed's price
+	Skipped     []string  `json:"skipped"`   // Entries rejected, with the reason
+}
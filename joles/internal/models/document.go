@@ -2,44 +2,244 @@ package models
 
 import "time"
 
+// Document content types, which pick how content is exported and split for
+// semantic search. Code documents also name their programming language.
+const (
+	DocumentContentPlaintext = "plaintext"
+	DocumentContentMarkdown  = "markdown"
+	DocumentContentHTML      = "html"
+	DocumentContentCode      = "code"
+)
+
 // Document represents a document in the system
 // @Description Document model with timestamps
 type Document struct {
-	ID        uint      `json:"id"`
-	Title     string    `json:"title"`
-	Content   string    `json:"content"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID           uint          `json:"id"`
+	UserID       string        `json:"user_id"`
+	Title        string        `json:"title"`
+	Content      string        `json:"content"`
+	Tags         []string      `json:"tags"`
+	CollectionID *int64        `json:"collection_id"`
+	File         *DocumentFile `json:"file,omitempty"`
+	Confidential bool          `json:"confidential"` // Reads are recorded in the access log
+	IsFavorite   bool          `json:"is_favorite"`
+	Version      int           `json:"version"` // Counts updates; the ETag
+	SourceURL    string        `json:"source_url"`
+	Language     string        `json:"language"`      // BCP 47 tag, lowercased
+	ContentType  string        `json:"content_type"`  // One of the DocumentContent types
+	CodeLanguage string        `json:"code_language"` // Lowercased, for code documents
+	WordCount    int           `json:"word_count"`    // Computed from the content
+	CreatedAt    time.Time     `json:"created_at"`
+	UpdatedAt    time.Time     `json:"updated_at"`
 }
 
-// CreateDocumentRequest represents the request payload for creating a document
+// CreateDocumentRequest represents the request payload for creating a
+// document, filed at the top level unless collection_id is set
 type CreateDocumentRequest struct {
-	Title   string `json:"title" binding:"required,min=1,max=255"`
-	Content string `json:"content" binding:"required,min=1"`
+	Title        string   `json:"title" binding:"required,min=1,max=255"`
+	Content      string   `json:"content" binding:"required,min=1"`
+	Tags         []string `json:"tags" binding:"omitempty,max=20,dive,min=1,max=50"`
+	CollectionID *int64   `json:"collection_id" binding:"omitempty,gt=0"`
+	Confidential bool     `json:"confidential"`
+	SourceURL    string   `json:"source_url" binding:"omitempty,max=2048"`
+	Language     string   `json:"language" binding:"omitempty,max=35"`
+	// markdown, plaintext (the default), html or code; the matching media
+	// types, e.g. text/markdown, are accepted too
+	ContentType  string `json:"content_type" binding:"omitempty,max=100"`
+	CodeLanguage string `json:"code_language" binding:"omitempty,max=32"`
+}
+
+// UploadDocumentRequest represents the form fields sent with an uploaded
+// file; the title defaults to the file name
+type UploadDocumentRequest struct {
+	Title        string   `form:"title" binding:"omitempty,max=255"`
+	Tags         []string `form:"tags" binding:"omitempty,max=20,dive,min=1,max=50"`
+	CollectionID *int64   `form:"collection_id" binding:"omitempty,gt=0"`
+	Confidential bool     `form:"confidential"`
+	SourceURL    string   `form:"source_url" binding:"omitempty,max=2048"`
+	Language     string   `form:"language" binding:"omitempty,max=35"`
+}
+
+// DocumentFile describes the original file of an uploaded document
+type DocumentFile struct {
+	Name        string `json:"name"`
+	ContentType string `json:"content_type"`
+	Size        int64  `json:"size"`
+	// Where the file is kept; not exposed to clients
+	Backend string `json:"-"`
+	Key     string `json:"-"`
 }
 
 // UpdateDocumentRequest represents the request payload for updating a document
 type UpdateDocumentRequest struct {
 	Title   *string `json:"title" binding:"omitempty,min=1,max=255"`
 	Content *string `json:"content" binding:"omitempty,min=1"`
+	// Replaces the document's tags when set; an empty list clears them
+	Tags *[]string `json:"tags" binding:"omitempty,max=20,dive,min=1,max=50"`
+	// Starts or stops recording reads in the document's access log
+	Confidential *bool `json:"confidential"`
+	// Metadata replaced when set; an empty string clears it
+	SourceURL    *string `json:"source_url" binding:"omitempty,max=2048"`
+	Language     *string `json:"language" binding:"omitempty,max=35"`
+	ContentType  *string `json:"content_type" binding:"omitempty,max=100"`
+	CodeLanguage *string `json:"code_language" binding:"omitempty,max=32"`
+	// The version the update is based on, when not sent in If-Match; the
+	// update fails with a conflict if the document has changed since
+	Version *int `json:"version" binding:"omitempty,min=1"`
+}
+
+// FavoriteDocumentRequest sets whether a document is a favorite; without
+// is_favorite the flag is toggled
+type FavoriteDocumentRequest struct {
+	IsFavorite *bool `json:"is_favorite"`
+}
+
+// MoveDocumentRequest represents the request payload for moving a document
+// between collections; a null collection_id moves it to the top level
+type MoveDocumentRequest struct {
+	CollectionID *int64 `json:"collection_id" binding:"omitempty,gt=0"`
+}
+
+// DocumentFilter narrows a user's document list; zero values match all
+type DocumentFilter struct {
+	UserID string
+	// Documents carrying all of these tags
+	Tags []string
+	// Documents in this collection; 0 matches documents in none
+	CollectionID *int64
+	Skip         int
+	Limit        int
+
+	Language     string // Documents in this language or a variant of it
+	ContentType  string
+	CodeLanguage string
+	// Documents whose source_url starts with this
+	SourceURL string
+	// Word count bounds; 0 leaves a bound open
+	MinWords int
+	MaxWords int
+	// Only the user's favorite documents
+	Favorites bool
+	// Only these documents, when set
+	IDs []uint
 }
 
 // DocumentResponse represents the response payload for a document
 type DocumentResponse struct {
-	ID        uint      `json:"id"`
-	Title     string    `json:"title"`
-	Content   string    `json:"content"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID           uint          `json:"id"`
+	UserID       string        `json:"user_id"`
+	Title        string        `json:"title"`
+	Content      string        `json:"content"`
+	Tags         []string      `json:"tags"`
+	CollectionID *int64        `json:"collection_id"`
+	File         *DocumentFile `json:"file,omitempty"`
+	Confidential bool          `json:"confidential"`
+	IsFavorite   bool          `json:"is_favorite"`
+	Version      int           `json:"version"`
+	SourceURL    string        `json:"source_url,omitempty"`
+	Language     string        `json:"language,omitempty"`
+	ContentType  string        `json:"content_type,omitempty"`
+	CodeLanguage string        `json:"code_language,omitempty"`
+	WordCount    int           `json:"word_count"`
+	CreatedAt    time.Time     `json:"created_at"`
+	UpdatedAt    time.Time     `json:"updated_at"`
+	// Comments on the document, included with include=comments
+	Comments *[]DocumentComment `json:"comments,omitempty"`
 }
 
 // ToResponse converts Document model to DocumentResponse
 func (d *Document) ToResponse() *DocumentResponse {
 	return &DocumentResponse{
-		ID:        d.ID,
-		Title:     d.Title,
-		Content:   d.Content,
-		CreatedAt: d.CreatedAt,
-		UpdatedAt: d.UpdatedAt,
+		ID:           d.ID,
+		UserID:       d.UserID,
+		Title:        d.Title,
+		Content:      d.Content,
+		Tags:         d.Tags,
+		CollectionID: d.CollectionID,
+		File:         d.File,
+		Confidential: d.Confidential,
+		IsFavorite:   d.IsFavorite,
+		Version:      d.Version,
+		SourceURL:    d.SourceURL,
+		Language:     d.Language,
+		ContentType:  d.ContentType,
+		CodeLanguage: d.CodeLanguage,
+		WordCount:    d.WordCount,
+		CreatedAt:    d.CreatedAt,
+		UpdatedAt:    d.UpdatedAt,
 	}
 }
+
+// DocumentReader identifies who read a document and how, for the access
+// log of confidential documents
+type DocumentReader struct {
+	UserID    string
+	Endpoint  string // Method and route, e.g. "GET /api/v1/documents/:id"
+	IPAddress string
+}
+
+// DocumentAccess is one recorded read of a confidential document
+type DocumentAccess struct {
+	ID         int64     `json:"id"`
+	DocumentID uint      `json:"document_id"`
+	UserID     string    `json:"user_id"`
+	Endpoint   string    `json:"endpoint"`
+	IPAddress  string    `json:"ip_address,omitempty"`
+	AccessedAt time.Time `json:"accessed_at"`
+}
+
+// DocumentVersion is a stored revision of a document's title and content.
+// Version 1 is the document as created; every edit changing either adds
+// the next.
+type DocumentVersion struct {
+	ID          int64     `json:"id"`
+	DocumentID  uint      `json:"document_id"`
+	Version     int       `json:"version"`
+	Title       string    `json:"title"`
+	Content     string    `json:"content,omitempty"`
+	ContentType string    `json:"content_type,omitempty"`
+	WordCount   int       `json:"word_count"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// DocumentDiff is a line-level unified diff between two document versions
+type DocumentDiff struct {
+	DocumentID uint   `json:"document_id"`
+	From       int    `json:"from"`
+	To         int    `json:"to"`
+	Additions  int    `json:"additions"`
+	Deletions  int    `json:"deletions"`
+	Unified    string `json:"unified"` // Empty when the versions match
+}
+
+// DocumentComment is a comment on a document, anchored to the characters
+// (Unicode code points) of its content from StartOffset up to EndOffset.
+// Quote is the text the range covered when the comment was made; the
+// comment is Outdated once the content at the range no longer matches it.
+type DocumentComment struct {
+	ID          int64     `json:"id"`
+	DocumentID  uint      `json:"document_id"`
+	UserID      string    `json:"user_id"`
+	Body        string    `json:"body"`
+	StartOffset int       `json:"start_offset"`
+	EndOffset   int       `json:"end_offset"`
+	Quote       string    `json:"quote"`
+	Resolved    bool      `json:"resolved"`
+	Outdated    bool      `json:"outdated"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// CreateDocumentCommentRequest comments on a range of a document's content;
+// an empty range comments on the point before start_offset
+type CreateDocumentCommentRequest struct {
+	Body        string `json:"body" binding:"required,max=10000"`
+	StartOffset *int   `json:"start_offset" binding:"required,min=0"`
+	EndOffset   *int   `json:"end_offset" binding:"required,min=0"`
+}
+
+// UpdateDocumentCommentRequest edits a comment's body or resolves it
+type UpdateDocumentCommentRequest struct {
+	Body     *string `json:"body" binding:"omitempty,min=1,max=10000"`
+	Resolved *bool   `json:"resolved"`
+}
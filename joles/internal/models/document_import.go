@@ -0,0 +1,44 @@
+package models
+
+import "time"
+
+// DocumentImportEntry is one document to create in a bulk import. Entries
+// that could not be read carry the reason in Error and are reported as
+// failed.
+type DocumentImportEntry struct {
+	Entry   int    // 1-based position in the source: ZIP file or JSONL line
+	Name    string // File name in the ZIP, or the line's title
+	Request CreateDocumentRequest
+	Error   string
+}
+
+// DocumentImportResult reports the outcome of one entry of an import
+type DocumentImportResult struct {
+	Entry      int    `json:"entry"`
+	Name       string `json:"name,omitempty"`
+	DocumentID uint   `json:"document_id,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+// DocumentImportJob is a background import of documents from a ZIP of
+// text and Markdown files or a JSONL stream
+type DocumentImportJob struct {
+	ID           int64                  `json:"id"`
+	UserID       string                 `json:"user_id"`
+	Source       string                 `json:"source"` // "zip" or "jsonl"
+	Status       string                 `json:"status"` // One of the ImportStatus values
+	TotalEntries int                    `json:"total_entries"`
+	Processed    int                    `json:"processed"`
+	Created      int                    `json:"created"`
+	Failed       int                    `json:"failed"`
+	Results      []DocumentImportResult `json:"results"`
+	CreatedAt    time.Time              `json:"created_at"`
+	CompletedAt  *time.Time             `json:"completed_at,omitempty"`
+}
+
+// ImportDocumentsRequest holds the form fields sent with an import. Tags
+// and collection_id apply to every entry; JSONL lines may set their own.
+type ImportDocumentsRequest struct {
+	Tags         []string `form:"tags" binding:"omitempty,max=20,dive,min=1,max=50"`
+	CollectionID *int64   `form:"collection_id" binding:"omitempty,gt=0"`
+}
@@ -0,0 +1,44 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+)
+
+// EmbeddingInput is the text to embed: a single string or a list of strings
+type EmbeddingInput []string
+
+// UnmarshalJSON accepts either a string or an array of strings
+func (in *EmbeddingInput) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*in = EmbeddingInput{single}
+		return nil
+	}
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return errors.New("input must be a string or an array of strings")
+	}
+	*in = list
+	return nil
+}
+
+// EmbeddingRequest asks for vectors for one or more inputs
+type EmbeddingRequest struct {
+	Model string         `json:"model" binding:"required"`
+	Input EmbeddingInput `json:"input" binding:"required"`
+}
+
+// Embedding is the vector for the input at Index
+type Embedding struct {
+	Index     int       `json:"index"`
+	Embedding []float64 `json:"embedding"`
+}
+
+// EmbeddingResponse holds one vector per input, in input order
+type EmbeddingResponse struct {
+	Model     string      `json:"model"`
+	Data      []Embedding `json:"data"`
+	Tokens    int         `json:"tokens"`
+	KeySource string      `json:"key_source"` // "user" or "platform"
+}
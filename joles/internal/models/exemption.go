@@ -0,0 +1,42 @@
+package models
+
+import "time"
+
+// Ways a rate limit exemption recognizes requests
+const (
+	ExemptionKindToken = "token"
+	ExemptionKindCIDR  = "cidr"
+)
+
+// RateLimitExemption lets synthetic traffic such as health checkers, the
+// AI backend's callbacks and monitoring probes bypass rate limiting and
+// usage tracking, so it neither gets throttled nor counts against quotas
+// and metrics. Token exemptions match requests presenting the token, which
+// is only shown when the exemption is created; CIDR exemptions match
+// requests from an address in the range.
+type RateLimitExemption struct {
+	ID          int64     `json:"id"`
+	Name        string    `json:"name"`
+	Kind        string    `json:"kind"`
+	CIDR        string    `json:"cidr,omitempty"`
+	TokenPrefix string    `json:"token_prefix,omitempty"`
+	CreatedBy   string    `json:"created_by"`
+	CreatedAt   time.Time `json:"created_at"`
+	// Hash of the token; not exposed to clients
+	TokenHash string `json:"-"`
+}
+
+// CreateExemptionRequest represents the request to add an exemption. A
+// token is generated for token exemptions; cidr is required for CIDR ones
+// and may be a single address.
+type CreateExemptionRequest struct {
+	Name string `json:"name" binding:"required,min=1,max=100"`
+	Kind string `json:"kind" binding:"required,oneof=token cidr"`
+	CIDR string `json:"cidr,omitempty" binding:"max=50"`
+}
+
+// CreatedExemption is returned once, when an exemption is created
+type CreatedExemption struct {
+	*RateLimitExemption
+	Token string `json:"token,omitempty"`
+}
@@ -0,0 +1,79 @@
+package models
+
+import "time"
+
+// GatewayConfigFormat is the version of the exported configuration document
+const GatewayConfigFormat = 1
+
+// GatewayConfig is the gateway's runtime configuration as a single document,
+// used to promote settings from one environment to another. Cost configs and
+// model aliases live in the database and are applied on import; plans,
+// features, routing and policies come from environment variables and are
+// only compared.
+type GatewayConfig struct {
+	Format       int                    `json:"format"`
+	Environment  string                 `json:"environment,omitempty"`
+	ExportedAt   time.Time              `json:"exported_at"`
+	CostConfigs  []CostConfigSetting    `json:"cost_configs"`
+	ModelAliases []ModelAliasSetting    `json:"model_aliases"`
+	Plans        map[string]PlanSetting `json:"plans"`
+	Features     map[string]bool        `json:"features"`
+	Routing      *RoutingSetting        `json:"routing"`
+	Policies     map[string]int64       `json:"policies"`
+}
+
+// CostConfigSetting is a model's pricing without database bookkeeping
+type CostConfigSetting struct {
+	ModelName          string  `json:"model_name"`
+	CostPerInputToken  float64 `json:"cost_per_input_token"`
+	CostPerOutputToken float64 `json:"cost_per_output_token"`
+	OperationType      string  `json:"operation_type"`
+	IsActive           bool    `json:"is_active"`
+}
+
+// ModelAliasSetting is an alias without database bookkeeping
+type ModelAliasSetting struct {
+	Alias       string `json:"alias"`
+	Model       string `json:"model"`
+	Description string `json:"description,omitempty"`
+}
+
+// PlanSetting holds the limits of a user plan
+type PlanSetting struct {
+	StorageLimitBytes int64 `json:"storage_limit_bytes"` // 0 means unlimited
+}
+
+// RoutingSetting is the model fallback and latency routing table
+type RoutingSetting struct {
+	Fallbacks             map[string][]string `json:"fallbacks"`
+	AttemptTimeoutSeconds int64               `json:"attempt_timeout_seconds"`
+	Routes                map[string][]string `json:"routes,omitempty"` // Latency-routed logical models
+}
+
+// Configuration change actions
+const (
+	ConfigActionCreate     = "create"
+	ConfigActionUpdate     = "update"
+	ConfigActionDelete     = "delete"
+	ConfigActionDeactivate = "deactivate"
+)
+
+// ConfigChange is one difference between the running configuration and an
+// imported one
+type ConfigChange struct {
+	Section string      `json:"section"` // e.g. "cost_configs"
+	Key     string      `json:"key"`
+	Action  string      `json:"action"` // "create", "update", "delete" or "deactivate"
+	Before  interface{} `json:"before,omitempty"`
+	After   interface{} `json:"after,omitempty"`
+	// Set from environment variables, so import reports but cannot apply it
+	RequiresEnv bool `json:"requires_env,omitempty"`
+}
+
+// ConfigImportResult describes what an import changed, or would change
+// when previewed with dry_run
+type ConfigImportResult struct {
+	DryRun  bool           `json:"dry_run"`
+	Applied int            `json:"applied"` // Changes written to the database
+	Changes []ConfigChange `json:"changes"`
+}
@@ -0,0 +1,57 @@
+package models
+
+import "time"
+
+// MergeReport counts what an identity merge moved from the merged account
+// to the surviving one
+type MergeReport struct {
+	SourceUserID        int64     `json:"source_user_id"` // Merged and deactivated
+	TargetUserID        int64     `json:"target_user_id"` // Surviving account
+	Chats               int64     `json:"chats"`
+	Documents           int64     `json:"documents"`
+	DocumentComments    int64     `json:"document_comments"`
+	Collections         int64     `json:"collections"`
+	ProviderKeys        int64     `json:"provider_keys"`
+	ProviderKeysSkipped int64     `json:"provider_keys_skipped"` // The surviving account already had a key for the provider
+	UsageRecords        int64     `json:"usage_records"`
+	Templates           int64     `json:"templates"`
+	Personas            int64     `json:"personas"`
+	ScheduledMessages   int64     `json:"scheduled_messages"`
+	Attachments         int64     `json:"attachments"`
+	Feedback            int64     `json:"feedback"`
+	Passkeys            int64     `json:"passkeys"`
+	WidgetTokens        int64     `json:"widget_tokens"`
+	Webhooks            int64     `json:"webhooks"`
+	SecurityEvents      int64     `json:"security_events"`
+	MergedAt            time.Time `json:"merged_at"`
+}
+
+// IdentityMerge is the audit record of one merge
+type IdentityMerge struct {
+	ID           int64       `json:"id"`
+	SourceUserID int64       `json:"source_user_id"`
+	SourceEmail  string      `json:"source_email"`
+	TargetUserID int64       `json:"target_user_id"`
+	MergedBy     string      `json:"merged_by"` // User ID of the admin, or of the target for self-service links
+	Method       string      `json:"method"`
+	Report       MergeReport `json:"report"`
+	CreatedAt    time.Time   `json:"created_at"`
+}
+
+// How a merge was requested
+const (
+	MergeMethodAdmin = "admin"
+	MergeMethodLink  = "link"
+)
+
+// MergeUsersRequest names the account an admin merges into another
+type MergeUsersRequest struct {
+	SourceUserID int64 `json:"source_user_id" binding:"required,gt=0"`
+}
+
+// LinkAccountRequest proves ownership of another account, by its
+// password, to merge it into the logged-in one
+type LinkAccountRequest struct {
+	Email    string `json:"email" binding:"required,email"`
+	Password string `json:"password" binding:"required"`
+}
@@ -0,0 +1,64 @@
+package models
+
+import "time"
+
+// Components incidents are recorded against. Model health incidents use
+// IncidentComponentModelPrefix followed by the model name.
+const (
+	IncidentComponentGateway     = "gateway"
+	IncidentComponentBackend     = "backend"
+	IncidentComponentModelPrefix = "model:"
+)
+
+// Incident kinds. Restarts and outages count as downtime; degraded
+// periods don't.
+const (
+	IncidentKindRestart  = "restart"
+	IncidentKindOutage   = "outage"
+	IncidentKindDegraded = "degraded"
+)
+
+// Component states on the status page
+const (
+	ComponentOperational = "operational"
+	ComponentDegraded    = "degraded"
+	ComponentOutage      = "outage"
+)
+
+// Incident is a period a component was down or degraded
+type Incident struct {
+	ID         int64      `json:"id"`
+	Component  string     `json:"component"`
+	Kind       string     `json:"kind"`
+	Detail     string     `json:"detail,omitempty"`
+	StartedAt  time.Time  `json:"started_at"`
+	ResolvedAt *time.Time `json:"resolved_at,omitempty"` // Nil while ongoing
+}
+
+// IncidentFilter narrows the incident history; zero values match all
+type IncidentFilter struct {
+	Component string
+	Since     time.Time
+	Limit     int
+	Offset    int
+}
+
+// ComponentStatus is a component's current state and record over the
+// status window
+type ComponentStatus struct {
+	Name          string  `json:"name"`
+	Status        string  `json:"status"`
+	UptimePercent float64 `json:"uptime_percent"`
+	Incidents     int     `json:"incidents"`
+	// Mean time to recovery of the window's resolved incidents
+	MTTRSeconds float64 `json:"mttr_seconds"`
+}
+
+// StatusPage is the public service status
+type StatusPage struct {
+	Status      string            `json:"status"`
+	WindowDays  int               `json:"window_days"`
+	Components  []ComponentStatus `json:"components"`
+	Incidents   []Incident        `json:"recent_incidents"`
+	GeneratedAt time.Time         `json:"generated_at"`
+}
@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// InflightRequest is a request the gateway is serving right now
+type InflightRequest struct {
+	ID     string `json:"id"`
+	Method string `json:"method"`
+	// Route pattern matched, e.g. /api/v1/chats/:id/messages, and the
+	// path requested
+	Route  string `json:"route"`
+	Path   string `json:"path"`
+	UserID string `json:"user_id,omitempty"`
+	// Service the request is waiting on, and for completions the model
+	// asked for; empty until the request calls out
+	Upstream      string    `json:"upstream,omitempty"`
+	UpstreamModel string    `json:"upstream_model,omitempty"`
+	StartedAt     time.Time `json:"started_at"`
+	ElapsedMS     int64     `json:"elapsed_ms"`
+	// Set once an admin has cancelled the request
+	Cancelled bool `json:"cancelled"`
+}
@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// ModelAlias maps a stable name clients use (e.g. "default-chat") to the
+// concrete model that serves it
+type ModelAlias struct {
+	Alias       string    `json:"alias"`
+	Model       string    `json:"model"`
+	Description string    `json:"description,omitempty"`
+	UpdatedBy   string    `json:"updated_by,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// ModelAliasRequest creates or repoints an alias
+type ModelAliasRequest struct {
+	Model       string `json:"model" binding:"required,max=100"`
+	Description string `json:"description,omitempty" binding:"max=500"`
+}
@@ -0,0 +1,38 @@
+package models
+
+import "time"
+
+// ModelDeprecation marks a catalog model as deprecated. Requests for it
+// keep working with a warning until SunsetAt; after that they are served
+// by ReplacementModel when one is set.
+type ModelDeprecation struct {
+	Model            string    `json:"model"`
+	DeprecatedAt     time.Time `json:"deprecated_at"`
+	SunsetAt         time.Time `json:"sunset_at"`
+	ReplacementModel string    `json:"replacement_model,omitempty"`
+	Notice           string    `json:"notice,omitempty"` // Shown to users, e.g. a migration guide link
+	DeprecatedBy     string    `json:"deprecated_by,omitempty"`
+}
+
+// Sunset reports whether the sunset date has passed at now
+func (d *ModelDeprecation) Sunset(now time.Time) bool {
+	return !now.Before(d.SunsetAt)
+}
+
+// DeprecateModelRequest marks a model deprecated or changes its deprecation
+type DeprecateModelRequest struct {
+	SunsetAt         time.Time `json:"sunset_at" binding:"required"`
+	ReplacementModel string    `json:"replacement_model" binding:"max=100"`
+	Notice           string    `json:"notice" binding:"max=500"`
+}
+
+// ModelDeprecationNotice is returned with a completion from a deprecated
+// model, and as Deprecation, Sunset and Warning headers
+type ModelDeprecationNotice struct {
+	Model            string    `json:"model"` // Model the request named
+	DeprecatedAt     time.Time `json:"deprecated_at"`
+	SunsetAt         time.Time `json:"sunset_at"`
+	ReplacementModel string    `json:"replacement_model,omitempty"`
+	Replaced         bool      `json:"replaced,omitempty"` // The sunset has passed and the replacement answered
+	Notice           string    `json:"notice,omitempty"`
+}
@@ -0,0 +1,23 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Notification kinds
+const (
+	NotificationKindModelDeprecation = "model_deprecation"
+)
+
+// Notification is a message in a user's notification center
+type Notification struct {
+	ID        int64           `json:"id"`
+	UserID    string          `json:"-"`
+	Kind      string          `json:"kind"`
+	Title     string          `json:"title"`
+	Body      string          `json:"body"`
+	Data      json.RawMessage `json:"data,omitempty"` // Kind-specific details, e.g. the deprecated model
+	ReadAt    *time.Time      `json:"read_at,omitempty"`
+	CreatedAt time.Time       `json:"created_at"`
+}
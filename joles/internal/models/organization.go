@@ -0,0 +1,87 @@
+package models
+
+import "time"
+
+// Roles in an organization; owners manage its members
+const (
+	OrgRoleOwner  = "owner"
+	OrgRoleMember = "member"
+)
+
+// Organization groups users who share quota and budget limits. A user
+// belongs to at most one organization.
+type Organization struct {
+	ID        int64     `json:"id"`
+	Name      string    `json:"name"`
+	CreatedBy string    `json:"created_by"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	// Filled in when a single organization is fetched
+	Members []OrganizationMember `json:"members,omitempty"`
+	Quota   *OrgQuotaStatus      `json:"quota,omitempty"`
+	Budget  *BudgetStatus        `json:"budget,omitempty"`
+}
+
+// OrganizationMember is a user's membership of an organization
+type OrganizationMember struct {
+	OrgID     int64     `json:"org_id"`
+	UserID    string    `json:"user_id"`
+	Role      string    `json:"role"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// CreateOrganizationRequest creates an organization owned by the caller
+type CreateOrganizationRequest struct {
+	Name string `json:"name" binding:"required"`
+}
+
+// AddOrganizationMemberRequest adds a user to an organization
+type AddOrganizationMemberRequest struct {
+	UserID string `json:"user_id" binding:"required"`
+	Role   string `json:"role"` // "member" when empty
+}
+
+// OrgQuota holds the daily and monthly limits on the summed usage of an
+// organization's members, on top of each member's own quota. A limit of 0
+// is no limit.
+type OrgQuota struct {
+	OrgID               int64     `json:"org_id"`
+	DailyTokenLimit     int       `json:"daily_token_limit"`
+	MonthlyTokenLimit   int       `json:"monthly_token_limit"`
+	DailyCostLimitUSD   float64   `json:"daily_cost_limit_usd"`
+	MonthlyCostLimitUSD float64   `json:"monthly_cost_limit_usd"`
+	UpdatedAt           time.Time `json:"updated_at"`
+}
+
+// OrgQuotaStatus is an organization's quota with its members' usage this
+// day and month
+type OrgQuotaStatus struct {
+	OrgQuota
+	DailyTokensUsed    int     `json:"daily_tokens_used"`
+	MonthlyTokensUsed  int     `json:"monthly_tokens_used"`
+	DailyCostUsedUSD   float64 `json:"daily_cost_used_usd"`
+	MonthlyCostUsedUSD float64 `json:"monthly_cost_used_usd"`
+
+	// Tokens and estimated cost held by members' requests still in flight
+	ReservedTokens  int     `json:"reserved_tokens"`
+	ReservedCostUSD float64 `json:"reserved_cost_usd"`
+}
+
+// Allows reports whether tokens and cost more fit within every limit,
+// counting what is reserved
+func (s *OrgQuotaStatus) Allows(tokens int, cost float64) bool {
+	if s.DailyTokenLimit > 0 && s.DailyTokensUsed+s.ReservedTokens+tokens > s.DailyTokenLimit {
+		return false
+	}
+	if s.MonthlyTokenLimit > 0 && s.MonthlyTokensUsed+s.ReservedTokens+tokens > s.MonthlyTokenLimit {
+		return false
+	}
+	if s.DailyCostLimitUSD > 0 && s.DailyCostUsedUSD+s.ReservedCostUSD+cost > s.DailyCostLimitUSD {
+		return false
+	}
+	if s.MonthlyCostLimitUSD > 0 && s.MonthlyCostUsedUSD+s.ReservedCostUSD+cost > s.MonthlyCostLimitUSD {
+		return false
+	}
+	return true
+}
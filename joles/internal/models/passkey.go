@@ -0,0 +1,135 @@
+package models
+
+import "time"
+
+// Passkey ceremonies, each started with its own challenge
+const (
+	PasskeyCeremonyRegistration = "registration"
+	PasskeyCeremonyLogin        = "login"
+)
+
+// Passkey is a WebAuthn credential a user can log in with
+type Passkey struct {
+	ID             int64      `json:"id"`
+	UserID         int64      `json:"-"`
+	CredentialID   string     `json:"credential_id"` // base64url, as browsers report it
+	PublicKey      []byte     `json:"-"`             // COSE_Key
+	SignCount      uint32     `json:"-"`
+	Transports     []string   `json:"transports,omitempty"`
+	Name           string     `json:"name"`
+	BackupEligible bool       `json:"synced"` // Synced between the user's devices
+	CreatedAt      time.Time  `json:"created_at"`
+	LastUsedAt     *time.Time `json:"last_used_at,omitempty"`
+}
+
+// PasskeyChallenge is the server's side of a ceremony in progress
+type PasskeyChallenge struct {
+	ID        string
+	Ceremony  string
+	UserID    int64 // Registering user; 0 for logins
+	Challenge []byte
+	ExpiresAt time.Time
+}
+
+// PasskeyDescriptor identifies one credential to the browser
+type PasskeyDescriptor struct {
+	Type       string   `json:"type"`
+	ID         string   `json:"id"`
+	Transports []string `json:"transports,omitempty"`
+}
+
+// PasskeyRegistrationOptions starts a registration ceremony. PublicKey is
+// passed to navigator.credentials.create() (after decoding its base64url
+// fields, or as is to PublicKeyCredential.parseCreationOptionsFromJSON).
+type PasskeyRegistrationOptions struct {
+	ChallengeID string                    `json:"challenge_id"`
+	ExpiresAt   time.Time                 `json:"expires_at"`
+	PublicKey   PasskeyCreationParameters `json:"publicKey"`
+}
+
+// PasskeyCreationParameters are PublicKeyCredentialCreationOptions in
+// their JSON form
+type PasskeyCreationParameters struct {
+	Challenge string `json:"challenge"`
+	RP        struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	} `json:"rp"`
+	User struct {
+		ID          string `json:"id"`
+		Name        string `json:"name"`
+		DisplayName string `json:"displayName"`
+	} `json:"user"`
+	PubKeyCredParams []PasskeyAlgorithm `json:"pubKeyCredParams"`
+	Timeout          int64              `json:"timeout"` // Milliseconds
+	Attestation      string             `json:"attestation"`
+	Selection        struct {
+		ResidentKey      string `json:"residentKey"`
+		RequireResident  bool   `json:"requireResidentKey"`
+		UserVerification string `json:"userVerification"`
+	} `json:"authenticatorSelection"`
+	ExcludeCredentials []PasskeyDescriptor `json:"excludeCredentials"`
+}
+
+// PasskeyAlgorithm is a public key algorithm offered at registration
+type PasskeyAlgorithm struct {
+	Type string `json:"type"`
+	Alg  int    `json:"alg"`
+}
+
+// PasskeyLoginOptions starts a login ceremony; PublicKey is passed to
+// navigator.credentials.get()
+type PasskeyLoginOptions struct {
+	ChallengeID string                   `json:"challenge_id"`
+	ExpiresAt   time.Time                `json:"expires_at"`
+	PublicKey   PasskeyRequestParameters `json:"publicKey"`
+}
+
+// PasskeyRequestParameters are PublicKeyCredentialRequestOptions in their
+// JSON form. AllowCredentials is empty for discoverable logins, where the
+// browser offers whichever passkeys it holds for the site.
+type PasskeyRequestParameters struct {
+	Challenge        string              `json:"challenge"`
+	RPID             string              `json:"rpId"`
+	Timeout          int64               `json:"timeout"` // Milliseconds
+	UserVerification string              `json:"userVerification"`
+	AllowCredentials []PasskeyDescriptor `json:"allowCredentials"`
+}
+
+// PasskeyLoginBeginRequest optionally names the account logging in, to
+// list its passkeys; without it the login is discoverable
+type PasskeyLoginBeginRequest struct {
+	Email string `json:"email" binding:"omitempty,email"`
+}
+
+// PasskeyRegistrationRequest completes a registration ceremony with the
+// browser's PublicKeyCredential, in its toJSON() form
+type PasskeyRegistrationRequest struct {
+	ChallengeID string `json:"challenge_id" binding:"required"`
+	Name        string `json:"name" binding:"max=100"`
+	Credential  struct {
+		ID       string `json:"id" binding:"required"`
+		Type     string `json:"type" binding:"required,eq=public-key"`
+		Response struct {
+			ClientDataJSON    string   `json:"clientDataJSON" binding:"required"`
+			AttestationObject string   `json:"attestationObject" binding:"required"`
+			Transports        []string `json:"transports"`
+		} `json:"response" binding:"required"`
+	} `json:"credential" binding:"required"`
+}
+
+// PasskeyLoginRequest completes a login ceremony with the browser's
+// PublicKeyCredential, in its toJSON() form
+type PasskeyLoginRequest struct {
+	ChallengeID string `json:"challenge_id" binding:"required"`
+	Credential  struct {
+		ID       string `json:"id" binding:"required"`
+		Type     string `json:"type" binding:"required,eq=public-key"`
+		Response struct {
+			ClientDataJSON    string `json:"clientDataJSON" binding:"required"`
+			AuthenticatorData string `json:"authenticatorData" binding:"required"`
+			Signature         string `json:"signature" binding:"required"`
+			UserHandle        string `json:"userHandle"`
+		} `json:"response" binding:"required"`
+	} `json:"credential" binding:"required"`
+}
@@ -0,0 +1,34 @@
+package models
+
+import "time"
+
+// Persona is a saved assistant profile: a system prompt with default
+// model settings that chats can reference
+type Persona struct {
+	ID           int64     `json:"id"`
+	UserID       string    `json:"user_id"`
+	Name         string    `json:"name"`
+	SystemPrompt string    `json:"system_prompt"`
+	DefaultModel string    `json:"default_model,omitempty"` // Used when a completion names no model
+	Temperature  *float64  `json:"temperature,omitempty"`   // Provider default when unset
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// PersonaRequest represents the request to create a persona
+type PersonaRequest struct {
+	Name         string   `json:"name" binding:"required,min=1,max=100"`
+	SystemPrompt string   `json:"system_prompt" binding:"required,min=1"`
+	DefaultModel string   `json:"default_model,omitempty" binding:"max=100"`
+	Temperature  *float64 `json:"temperature,omitempty" binding:"omitempty,min=0,max=2"`
+}
+
+// UpdatePersonaRequest represents the request to update a persona
+type UpdatePersonaRequest struct {
+	Name         *string  `json:"name,omitempty" binding:"omitempty,min=1,max=100"`
+	SystemPrompt *string  `json:"system_prompt,omitempty" binding:"omitempty,min=1"`
+	DefaultModel *string  `json:"default_model,omitempty" binding:"omitempty,max=100"`
+	Temperature  *float64 `json:"temperature,omitempty" binding:"omitempty,min=0,max=2"`
+	// Set to drop the persona's temperature and use the provider default
+	ClearTemperature bool `json:"clear_temperature,omitempty"`
+}
@@ -0,0 +1,38 @@
+package models
+
+import "time"
+
+// DefaultPlan is the plan of users who have none
+const DefaultPlan = "free"
+
+// Plan is a tier users are on (User.Plan), with the quota limits their
+// quota gets and their rate limit. RequestsPerMinute 0 is the gateway's
+// default rate.
+type Plan struct {
+	Name                string    `json:"name"`
+	DailyTokenLimit     int       `json:"daily_token_limit"`
+	MonthlyTokenLimit   int       `json:"monthly_token_limit"`
+	DailyCostLimitUSD   float64   `json:"daily_cost_limit_usd"`
+	MonthlyCostLimitUSD float64   `json:"monthly_cost_limit_usd"`
+	RequestsPerMinute   int       `json:"requests_per_minute"`
+	CreatedAt           time.Time `json:"created_at"`
+	UpdatedAt           time.Time `json:"updated_at"`
+}
+
+// SetPlanRequest creates a plan, with every limit, or changes the limits
+// given of an existing one
+type SetPlanRequest struct {
+	DailyTokenLimit     *int     `json:"daily_token_limit,omitempty"`
+	MonthlyTokenLimit   *int     `json:"monthly_token_limit,omitempty"`
+	DailyCostLimitUSD   *float64 `json:"daily_cost_limit_usd,omitempty"`
+	MonthlyCostLimitUSD *float64 `json:"monthly_cost_limit_usd,omitempty"`
+	RequestsPerMinute   *int     `json:"requests_per_minute,omitempty"`
+
+	// Also reset the quota limits of every user on the plan to the new ones
+	ApplyToUsers bool `json:"apply_to_users"`
+}
+
+// AssignPlanRequest moves a user to a plan
+type AssignPlanRequest struct {
+	Plan string `json:"plan" binding:"required"`
+}
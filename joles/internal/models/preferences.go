@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// UserPreferences are a user's account-wide settings
+type UserPreferences struct {
+	UserID string `json:"user_id"`
+	// Providers to prefer, most preferred first, when a routed model is
+	// served by several; providers not listed come after, fastest first
+	ProviderOrder []string  `json:"provider_order"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// UpdatePreferencesRequest changes the settings that are set
+type UpdatePreferencesRequest struct {
+	// Replaces the provider order when set; an empty list clears it
+	ProviderOrder *[]string `json:"provider_order" binding:"omitempty,max=10,dive,min=1,max=50"`
+}
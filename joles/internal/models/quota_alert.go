@@ -0,0 +1,50 @@
+package models
+
+import "time"
+
+// Quota metrics alerts are raised on
+const (
+	QuotaMetricDailyTokens   = "daily_tokens"
+	QuotaMetricMonthlyTokens = "monthly_tokens"
+	QuotaMetricDailyCost     = "daily_cost"
+	QuotaMetricMonthlyCost   = "monthly_cost"
+)
+
+// DefaultQuotaAlertThresholds are the percentages of a limit users are
+// alerted at until they choose their own
+var DefaultQuotaAlertThresholds = []int{80, 100}
+
+// QuotaAlertSettings is when and how a user is told their usage is
+// nearing a quota limit
+type QuotaAlertSettings struct {
+	// Percentages of each daily and monthly limit, ascending
+	Thresholds []int `json:"thresholds"`
+	// Send quota.threshold_crossed to the user's webhooks subscribed to it
+	Webhook bool `json:"webhook"`
+	// Email the user
+	Email     bool       `json:"email"`
+	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+}
+
+// UpdateQuotaAlertSettingsRequest changes a user's alert settings; fields
+// left out keep their value
+type UpdateQuotaAlertSettingsRequest struct {
+	Thresholds []int `json:"thresholds" binding:"omitempty,max=10,dive,min=1,max=100"`
+	Webhook    *bool `json:"webhook"`
+	Email      *bool `json:"email"`
+}
+
+// QuotaAlert records usage crossing a threshold of a limit. Each threshold
+// of a metric alerts once per day or month.
+type QuotaAlert struct {
+	ID          int64     `json:"id"`
+	UserID      string    `json:"-"`
+	Metric      string    `json:"metric"`
+	Threshold   int       `json:"threshold"`
+	Used        float64   `json:"used"`
+	Limit       float64   `json:"limit"`
+	PeriodStart time.Time `json:"period_start"`
+	// How the user was told: webhook, email, both or neither
+	Channels  []string  `json:"channels"`
+	CreatedAt time.Time `json:"created_at"`
+}
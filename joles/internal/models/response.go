@@ -85,6 +85,26 @@ type MetricsResponse struct {
 	TotalCostUSD       float64            `json:"total_cost_usd"`
 	EndpointStats      []EndpointStat     `json:"endpoint_stats,omitempty"`
 	ModelStats         []ModelStat        `json:"model_stats,omitempty"`
+	// "global" for instance-wide figures or "self" for the caller's own usage
+	Scope string `json:"scope"`
+	// Global figures shown to non-admins cover at least this many users;
+	// smaller groups are left out, and Suppressed is set if the totals were
+	MinAggregationUsers int  `json:"min_aggregation_users,omitempty"`
+	Suppressed          bool `json:"suppressed,omitempty"`
+	// Completion cache counters, when the cache is enabled (global scope only)
+	ResponseCache *ResponseCacheStats `json:"response_cache,omitempty"`
+	// Usage tracking queue counters, including events dropped (global
+	// scope only)
+	UsageQueue *UsageQueueStats `json:"usage_queue,omitempty"`
+}
+
+// ResponseCacheStats reports how often completions were served from cache
+type ResponseCacheStats struct {
+	Hits    int64   `json:"hits"`
+	Misses  int64   `json:"misses"`
+	HitRate float64 `json:"hit_rate"`
+	Entries int     `json:"entries"`
+	TTL     string  `json:"ttl"`
 }
 
 // EndpointStat represents statistics for an endpoint
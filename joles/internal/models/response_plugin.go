@@ -0,0 +1,43 @@
+package models
+
+import "time"
+
+// ResponsePluginConfig enables one post-processing plugin with its options
+type ResponsePluginConfig struct {
+	Name    string            `json:"name" binding:"required,max=50"`
+	Options map[string]string `json:"options,omitempty"`
+}
+
+// ResponsePluginSettings is the plugin chain applied to a user's assistant
+// output, in order. PersonaID 0 is the user's default for all chats; a
+// persona's own settings replace it for chats with that persona.
+type ResponsePluginSettings struct {
+	UserID    string                 `json:"user_id"`
+	PersonaID int64                  `json:"persona_id"`
+	Plugins   []ResponsePluginConfig `json:"plugins"`
+	UpdatedAt time.Time              `json:"updated_at"`
+}
+
+// SetResponsePluginsRequest replaces a plugin chain; an empty list turns
+// post-processing off
+type SetResponsePluginsRequest struct {
+	Plugins []ResponsePluginConfig `json:"plugins" binding:"max=10,dive"`
+}
+
+// ResponsePluginInfo describes an available plugin and its options
+type ResponsePluginInfo struct {
+	Name        string            `json:"name"`
+	Description string            `json:"description"`
+	Options     map[string]string `json:"options,omitempty"` // Option name to description
+}
+
+// ResponsePluginStats reports how often a plugin ran and how long it took
+type ResponsePluginStats struct {
+	Name    string  `json:"name"`
+	Runs    int64   `json:"runs"`
+	Errors  int64   `json:"errors"`
+	Changed int64   `json:"changed"` // Runs that altered the output
+	TotalMs float64 `json:"total_ms"`
+	AvgMs   float64 `json:"avg_ms"`
+	MaxMs   float64 `json:"max_ms"`
+}
@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+// PurgeReport lists what a GDPR erasure removed for one user. Usage
+// metadata (tokens, cost, model) is kept for billing and left to the
+// metadata retention period.
+type PurgeReport struct {
+	UserID                  string    `json:"user_id"`
+	MessagesScrubbed        int64     `json:"messages_scrubbed"`   // Content, tool calls and moderation cleared
+	ChatsScrubbed           int64     `json:"chats_scrubbed"`      // Titles and summaries cleared
+	ScheduledCancelled      int64     `json:"scheduled_cancelled"` // Pending scheduled prompts cancelled and cleared
+	AttachmentsRemoved      int64     `json:"attachments_removed"` // Files deleted from storage
+	FeedbackCommentsRemoved int64     `json:"feedback_comments_removed"`
+	ErrorBodiesRemoved      int64     `json:"error_bodies_removed"` // Usage log error messages
+	SecurityExcerptsRemoved int64     `json:"security_excerpts_removed"`
+	IdempotencyKeysRemoved  int64     `json:"idempotency_keys_removed"` // Stored responses replayed on retries
+	ChangeEventsRemoved     int64     `json:"change_events_removed"`    // Analytics change feed entries
+	CompletedAt             time.Time `json:"completed_at"`
+}
+
+// RetentionReport is the outcome of one retention run
+type RetentionReport struct {
+	MetadataDeleted    int64 `json:"metadata_deleted"`     // Usage log rows past the metadata retention
+	ErrorBodiesCleared int64 `json:"error_bodies_cleared"` // Error messages past the error body retention
+	// Confidential document reads past the access log retention
+	DocumentAccessDeleted int64 `json:"document_access_deleted"`
+}
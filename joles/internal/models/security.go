@@ -0,0 +1,46 @@
+package models
+
+import "time"
+
+// Security event severities, lowest first
+const (
+	SeverityLow    = "low"
+	SeverityMedium = "medium"
+	SeverityHigh   = "high"
+)
+
+// SecurityEventJailbreak is logged for prompt-injection and jailbreak attempts
+const SecurityEventJailbreak = "jailbreak_attempt"
+
+// SecurityEvent is a suspicious user action recorded for review
+type SecurityEvent struct {
+	ID        int64     `json:"id"`
+	UserID    string    `json:"user_id"`
+	ChatID    *int64    `json:"chat_id,omitempty"`
+	EventType string    `json:"event_type"`
+	Severity  string    `json:"severity"`
+	Pattern   string    `json:"pattern"`           // Detector rule that matched
+	Excerpt   string    `json:"excerpt,omitempty"` // Text around the match
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// SecurityEventFilter narrows the security review list; zero values match all
+type SecurityEventFilter struct {
+	UserID      string
+	MinSeverity string
+	Since       time.Time
+	Limit       int
+	Offset      int
+}
+
+// SeveritiesAtLeast lists min and the severities above it; an unknown
+// or empty min returns nil
+func SeveritiesAtLeast(min string) []string {
+	all := []string{SeverityLow, SeverityMedium, SeverityHigh}
+	for i, severity := range all {
+		if severity == min {
+			return all[i:]
+		}
+	}
+	return nil
+}
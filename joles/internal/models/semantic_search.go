@@ -0,0 +1,69 @@
+package models
+
+import "time"
+
+// DocumentChunk is a passage of a document with its embedding
+type DocumentChunk struct {
+	ID         int64
+	DocumentID uint
+	UserID     string
+	Seq        int // Position of the passage in the document
+	Content    string
+	Embedding  []float32
+	Model      string
+}
+
+// SemanticSearchQuery is a user's semantic search. With Hybrid set, the
+// score blends vector similarity with full-text matching, weighted by
+// TextWeight.
+type SemanticSearchQuery struct {
+	UserID     string
+	Query      string
+	Limit      int
+	Hybrid     bool
+	TextWeight float64
+	// Only chunks of documents in this collection
+	CollectionID *int64
+	// Only chunks of documents in this language (or a variant of it) and
+	// with this content type
+	Language    string
+	ContentType string
+	// Only chunks of these documents, when set
+	DocumentIDs []uint
+	// Results scoring below this are dropped
+	MinScore float64
+	// Recorded as reading the confidential documents in the results
+	Reader DocumentReader
+}
+
+// SearchResultDocument identifies the document a search result came from
+type SearchResultDocument struct {
+	ID           uint          `json:"id"`
+	Title        string        `json:"title"`
+	Tags         []string      `json:"tags"`
+	CollectionID *int64        `json:"collection_id"`
+	File         *DocumentFile `json:"file,omitempty"`
+	Confidential bool          `json:"confidential"`
+	UpdatedAt    time.Time     `json:"updated_at"`
+}
+
+// SemanticSearchResult is a document chunk matching a semantic search
+type SemanticSearchResult struct {
+	ChunkID int64   `json:"chunk_id"`
+	Seq     int     `json:"seq"`
+	Content string  `json:"content"`
+	Score   float64 `json:"score"`
+	// Cosine similarity of the chunk and query embeddings
+	VectorScore float64 `json:"vector_score"`
+	// Share of the query's terms the chunk contains; hybrid searches only
+	TextScore *float64             `json:"text_score,omitempty"`
+	Document  SearchResultDocument `json:"document"`
+}
+
+// SemanticSearchResponse holds the best matching chunks, highest score first
+type SemanticSearchResponse struct {
+	Query  string                 `json:"query"`
+	Model  string                 `json:"model"`
+	Hybrid bool                   `json:"hybrid"`
+	Data   []SemanticSearchResult `json:"data"`
+}
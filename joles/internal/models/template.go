@@ -0,0 +1,37 @@
+package models
+
+import "time"
+
+// PromptTemplate is a reusable prompt with {{variable}} placeholders
+type PromptTemplate struct {
+	ID          int64     `json:"id"`
+	UserID      string    `json:"user_id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description,omitempty"`
+	Content     string    `json:"content"`
+	Variables   []string  `json:"variables"` // Placeholder names found in content
+	IsShared    bool      `json:"is_shared"` // Visible to (but not editable by) every user
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// PromptTemplateRequest represents the request to create a prompt template
+type PromptTemplateRequest struct {
+	Name        string `json:"name" binding:"required,min=1,max=100"`
+	Description string `json:"description,omitempty" binding:"max=500"`
+	Content     string `json:"content" binding:"required,min=1"`
+	IsShared    bool   `json:"is_shared,omitempty"`
+}
+
+// UpdatePromptTemplateRequest represents the request to update a prompt template
+type UpdatePromptTemplateRequest struct {
+	Name        *string `json:"name,omitempty" binding:"omitempty,min=1,max=100"`
+	Description *string `json:"description,omitempty" binding:"omitempty,max=500"`
+	Content     *string `json:"content,omitempty" binding:"omitempty,min=1"`
+	IsShared    *bool   `json:"is_shared,omitempty"`
+}
+
+// RenderTemplateRequest supplies values for a template's variables
+type RenderTemplateRequest struct {
+	Variables map[string]string `json:"variables"`
+}
@@ -0,0 +1,27 @@
+package models
+
+// TokenCountMessage is a chat message whose tokens are counted
+type TokenCountMessage struct {
+	Role    string `json:"role" binding:"required"`
+	Name    string `json:"name,omitempty"`
+	Content string `json:"content"`
+}
+
+// TokenCountRequest asks for the token count of text or of a chat
+// request's messages; exactly one of Text and Messages is set
+type TokenCountRequest struct {
+	Model    string              `json:"model" binding:"required"`
+	Text     string              `json:"text,omitempty"`
+	Messages []TokenCountMessage `json:"messages,omitempty" binding:"omitempty,dive"`
+}
+
+// TokenCountResponse is the token count for a model in Encoding.
+// Estimated is set when the model's encoding isn't known, and the count
+// estimates Encoding's.
+type TokenCountResponse struct {
+	Model      string `json:"model"`
+	ModelAlias string `json:"model_alias,omitempty"`
+	Encoding   string `json:"encoding"`
+	Tokens     int    `json:"tokens"`
+	Estimated  bool   `json:"estimated"`
+}
@@ -15,6 +15,8 @@ type UsageMetric struct {
 	CostUSD         float64   `json:"cost_usd"`
 	DurationMs      int64     `json:"duration_ms"`
 	Endpoint        string    `json:"endpoint"`
+	Provider        string    `json:"provider,omitempty"` // openai, anthropic, google, cohere
+	KeySource       string    `json:"key_source,omitempty"` // "user" or "platform" (fallback)
 	Success         bool      `json:"success"`
 	ErrorMessage    string    `json:"error_message,omitempty"`
 	CreatedAt       time.Time `json:"created_at"`
@@ -66,6 +68,19 @@ type UsageSummary struct {
 	CodeGenRequests     int                 `json:"code_gen_requests"`
 	ModelsUsed          map[string]int      `json:"models_used"`
 	EndpointBreakdown   []UsageByEndpoint   `json:"endpoint_breakdown"`
+
+	// The range of usage summed, in UTC: [From, To). From is left out for
+	// all_time and To when the range runs up to now.
+	From *time.Time `json:"from,omitempty"`
+	To   *time.Time `json:"to,omitempty"`
+}
+
+// UsageFilter narrows a usage summary to usage matching every field set
+type UsageFilter struct {
+	Model    string
+	Provider string
+	Endpoint string
+	Success  *bool
 }
 
 // UsageByEndpoint represents usage breakdown by API endpoint
@@ -99,6 +114,23 @@ type QuotaStatus struct {
 	MonthlyCostPercentUsed   float64   `json:"monthly_cost_percent_used"`
 	LastResetDaily           time.Time `json:"last_reset_daily"`
 	LastResetMonthly         time.Time `json:"last_reset_monthly"`
+	// Stored bytes against the plan's limit, when storage accounting is on
+	Storage *StorageUsage `json:"storage,omitempty"`
+
+	// Tokens and estimated cost held by requests still in flight
+	ReservedTokens  int     `json:"reserved_tokens"`
+	ReservedCostUSD float64 `json:"reserved_cost_usd"`
+
+	// When daily and monthly usage next resets
+	DailyResetsAt   time.Time `json:"daily_resets_at"`
+	MonthlyResetsAt time.Time `json:"monthly_resets_at"`
+
+	// The quota shared with the user's organization, when it has one
+	Organization *OrgQuotaStatus `json:"organization,omitempty"`
+
+	// The user's plan and its rate limit; 0 is the gateway's default rate
+	Plan              string `json:"plan,omitempty"`
+	RequestsPerMinute int    `json:"requests_per_minute,omitempty"`
 }
 
 // UsageRequest represents a request to track usage
@@ -110,9 +142,78 @@ type UsageRequest struct {
 	TokensOutput int    `json:"tokens_output"`
 	ModelUsed    string `json:"model_used"`
 	Endpoint     string `json:"endpoint"`
+	Provider     string `json:"provider,omitempty"`
+	KeySource    string `json:"key_source,omitempty"`
 	DurationMs   int64  `json:"duration_ms"`
 	Success      bool   `json:"success"`
 	ErrorMessage string `json:"error_message,omitempty"`
+
+	// Quota reservation the request settles; set by services, never bound
+	ReservationID int64 `json:"-"`
+}
+
+// ProviderKeyUsage represents usage analytics for a single stored provider key
+type ProviderKeyUsage struct {
+	Provider           string         `json:"provider"`
+	TotalRequests      int            `json:"total_requests"`
+	SuccessfulRequests int            `json:"successful_requests"`
+	FailedRequests     int            `json:"failed_requests"`
+	TotalTokensInput   int            `json:"total_tokens_input"`
+	TotalTokensOutput  int            `json:"total_tokens_output"`
+	TotalTokens        int            `json:"total_tokens"`
+	EstimatedCostUSD   float64        `json:"estimated_cost_usd"`
+	ModelsUsed         map[string]int `json:"models_used"`
+	LastUsedAt         *time.Time     `json:"last_used_at,omitempty"`
+	LastError          string         `json:"last_error,omitempty"`
+	LastErrorAt        *time.Time     `json:"last_error_at,omitempty"`
+	FailingSince       *time.Time     `json:"failing_since,omitempty"` // First failure after the most recent success
+}
+
+// ChatStats represents usage statistics for a single conversation
+type ChatStats struct {
+	ChatID            int64            `json:"chat_id"`
+	TotalMessages     int              `json:"total_messages"`
+	TotalRequests     int              `json:"total_requests"`
+	FailedRequests    int              `json:"failed_requests"`
+	TotalTokensInput  int              `json:"total_tokens_input"`
+	TotalTokensOutput int              `json:"total_tokens_output"`
+	TotalTokens       int              `json:"total_tokens"`
+	TotalCostUSD      float64          `json:"total_cost_usd"`
+	AverageLatencyMs  float64          `json:"average_latency_ms"` // Successful requests only
+	Models            []ModelUsageStat `json:"models"`
+}
+
+// ModelUsageStat represents one model's share of a conversation's usage
+type ModelUsageStat struct {
+	Model            string  `json:"model"`
+	Requests         int     `json:"requests"`
+	TokensInput      int     `json:"tokens_input"`
+	TokensOutput     int     `json:"tokens_output"`
+	TotalCostUSD     float64 `json:"total_cost_usd"`
+	AverageLatencyMs float64 `json:"average_latency_ms"`
+}
+
+// TrialCompletionRequest represents an anonymous trial completion request
+type TrialCompletionRequest struct {
+	Message string `json:"message" binding:"required,max=4000"`
+}
+
+// TrialCompletionResponse represents an anonymous trial completion
+type TrialCompletionResponse struct {
+	Content   string `json:"content"`
+	Model     string `json:"model"`
+	Tokens    int    `json:"tokens,omitempty"`
+	Limit     int    `json:"limit"`
+	Remaining int    `json:"remaining"`
+}
+
+// TrialQuota represents the trial allowance left for a device/IP
+type TrialQuota struct {
+	Limit     int       `json:"limit"`
+	Used      int       `json:"used"`
+	Remaining int       `json:"remaining"`
+	Model     string    `json:"model"`
+	ResetsAt  time.Time `json:"resets_at"`
 }
 
 // QuotaUpdateRequest represents a request to update user quota
@@ -122,3 +223,125 @@ type QuotaUpdateRequest struct {
 	DailyCostLimitUSD   *float64 `json:"daily_cost_limit_usd,omitempty"`
 	MonthlyCostLimitUSD *float64 `json:"monthly_cost_limit_usd,omitempty"`
 }
+
+// Kinds of stored objects counted against a user's storage
+const (
+	StorageKindDocument   = "document"
+	StorageKindAttachment = "attachment"
+)
+
+// StorageUsage is a user's stored bytes against their plan's limit
+type StorageUsage struct {
+	UserID         string           `json:"user_id"`
+	Plan           string           `json:"plan,omitempty"`
+	BytesUsed      int64            `json:"bytes_used"`
+	BytesLimit     int64            `json:"bytes_limit"` // 0 means unlimited
+	BytesRemaining int64            `json:"bytes_remaining,omitempty"`
+	PercentUsed    float64          `json:"percent_used"`
+	Objects        int              `json:"objects"`
+	BytesByKind    map[string]int64 `json:"bytes_by_kind"`
+
+	// Largest content a single document may have; 0 means unlimited
+	DocumentMaxBytes int64 `json:"document_max_bytes"`
+}
+
+// UsageExportFilter selects the usage_metrics rows to export
+type UsageExportFilter struct {
+	// Only this user's rows; empty exports every user's
+	UserID string
+	// Rows created at or after From and before To
+	From time.Time
+	To   time.Time
+}
+
+// Usage time series granularities and metrics
+const (
+	UsageGranularityHour = "hour"
+	UsageGranularityDay  = "day"
+
+	UsageMetricTokens   = "tokens"
+	UsageMetricCost     = "cost"
+	UsageMetricRequests = "requests"
+)
+
+// UsageTimeseries is a usage metric summed into hourly or daily UTC
+// buckets over [From, To), with empty buckets included
+type UsageTimeseries struct {
+	Granularity string                 `json:"granularity"`
+	Metric      string                 `json:"metric"`
+	From        time.Time              `json:"from"`
+	To          time.Time              `json:"to"`
+	Total       float64                `json:"total"`
+	Data        []UsageTimeseriesPoint `json:"data"`
+}
+
+// UsageTimeseriesPoint is one bucket of a usage time series
+type UsageTimeseriesPoint struct {
+	BucketStart time.Time `json:"bucket_start"`
+	Value       float64   `json:"value"`
+}
+
+// Resources usage can be attributed to, by the request type recorded
+// with their ID in resource_id
+const (
+	UsageResourceChat     = "chat"
+	UsageResourceDocument = "document"
+)
+
+// UsageByResource is the usage recorded against one chat or document
+type UsageByResource struct {
+	ResourceType string    `json:"resource_type"`
+	ResourceID   int64     `json:"resource_id"`
+	Title        string    `json:"title,omitempty"` // Empty once the resource is deleted
+	Requests     int       `json:"requests"`
+	TokensInput  int       `json:"tokens_input"`
+	TokensOutput int       `json:"tokens_output"`
+	TotalTokens  int       `json:"total_tokens"`
+	TotalCostUSD float64   `json:"total_cost_usd"`
+	LastUsedAt   time.Time `json:"last_used_at"`
+}
+
+// AdminUsageSummary is the usage of all users created in [From, To)
+type AdminUsageSummary struct {
+	From               time.Time `json:"from"`
+	To                 time.Time `json:"to"`
+	TotalRequests      int       `json:"total_requests"`
+	SuccessfulRequests int       `json:"successful_requests"`
+	FailedRequests     int       `json:"failed_requests"`
+	TotalTokensInput   int       `json:"total_tokens_input"`
+	TotalTokensOutput  int       `json:"total_tokens_output"`
+	TotalTokens        int       `json:"total_tokens"`
+	TotalCostUSD       float64   `json:"total_cost_usd"`
+	AverageDurationMs  float64   `json:"average_duration_ms"`
+	ActiveUsers        int       `json:"active_users"`
+	// Requests per request type (chat, embedding, ...)
+	RequestsByType map[string]int `json:"requests_by_type"`
+}
+
+// Metrics admin usage reports can rank users by
+const (
+	UsageRankCost     = "cost"
+	UsageRankTokens   = "tokens"
+	UsageRankRequests = "requests"
+)
+
+// UserUsage is one user's usage in an admin report
+type UserUsage struct {
+	UserID       string  `json:"user_id"`
+	Username     string  `json:"username,omitempty"` // Empty for users that no longer exist
+	Email        string  `json:"email,omitempty"`
+	Requests     int     `json:"requests"`
+	TotalTokens  int     `json:"total_tokens"`
+	TotalCostUSD float64 `json:"total_cost_usd"`
+}
+
+// ModelUsage is the usage of one model across users in an admin report
+type ModelUsage struct {
+	Model        string  `json:"model"`
+	Requests     int     `json:"requests"`
+	TokensInput  int     `json:"tokens_input"`
+	TokensOutput int     `json:"tokens_output"`
+	TotalTokens  int     `json:"total_tokens"`
+	TotalCostUSD float64 `json:"total_cost_usd"`
+	Users        int     `json:"users"`
+}
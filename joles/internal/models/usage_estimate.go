@@ -0,0 +1,36 @@
+package models
+
+// CostEstimateRequest asks what a chat request would cost before it is
+// sent. MaxTokens bounds the response as it would for the completion.
+type CostEstimateRequest struct {
+	Model     string              `json:"model" binding:"required"`
+	Messages  []TokenCountMessage `json:"messages" binding:"required,min=1,dive"`
+	MaxTokens int                 `json:"max_tokens,omitempty" binding:"omitempty,min=1"`
+}
+
+// CostRange is what a request is expected to cost: at least Min, at most
+// Max, and Expected for a response of the user's usual length
+type CostRange struct {
+	Min      float64 `json:"min"`
+	Expected float64 `json:"expected"`
+	Max      float64 `json:"max"`
+}
+
+// CostEstimate is the price preview of a chat request. The input cost is
+// known from the prompt; the output cost depends on the response's length,
+// from none up to OutputTokensMax.
+type CostEstimate struct {
+	Model      string `json:"model"`
+	ModelAlias string `json:"model_alias,omitempty"`
+	// False when the model has no pricing of its own and the default
+	// prices were used
+	Priced bool `json:"priced"`
+
+	InputTokens          int `json:"input_tokens"`
+	OutputTokensExpected int `json:"output_tokens_expected"`
+	OutputTokensMax      int `json:"output_tokens_max"`
+
+	InputCostUSD  float64   `json:"input_cost_usd"`
+	OutputCostUSD CostRange `json:"output_cost_usd"`
+	TotalCostUSD  CostRange `json:"total_cost_usd"`
+}
@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// UsageDeadLetter is a usage event that could not be tracked after its
+// retries, kept until it is replayed
+type UsageDeadLetter struct {
+	ID        int64        `json:"id"`
+	Request   UsageRequest `json:"request"`
+	Error     string       `json:"error"`
+	Attempts  int          `json:"attempts"`
+	CreatedAt time.Time    `json:"created_at"`
+	UpdatedAt time.Time    `json:"updated_at"`
+}
+
+// UsageQueueStats counts what became of the usage events queued for
+// tracking since the process started
+type UsageQueueStats struct {
+	Pending  int `json:"pending"`
+	Capacity int `json:"capacity"`
+	// Events tracked, retries of failed attempts, events moved to the dead
+	// letter table, and events lost because even that failed
+	Tracked      int64 `json:"tracked"`
+	Retries      int64 `json:"retries"`
+	DeadLettered int64 `json:"dead_lettered"`
+	Dropped      int64 `json:"dropped"`
+}
@@ -0,0 +1,42 @@
+package models
+
+import "time"
+
+// SimulateUsageRequest prices a user's recent usage with other models
+type SimulateUsageRequest struct {
+	Models []string `json:"models" binding:"required,min=1,max=10,dive,required,max=100"`
+	// Days of usage the profile is taken from; defaults to 30
+	Days int `json:"days" binding:"omitempty,min=1,max=90"`
+	// Only usage of this request type ("chat", "code_generation"); all when empty
+	RequestType string `json:"request_type" binding:"max=50"`
+}
+
+// ModelUsageProfile is the recorded usage of one model in the profile
+type ModelUsageProfile struct {
+	Model          string  `json:"model"`
+	Requests       int64   `json:"requests"`
+	TokensInput    int64   `json:"tokens_input"`
+	TokensOutput   int64   `json:"tokens_output"`
+	MonthlyCostUSD float64 `json:"monthly_cost_usd"` // At current prices
+}
+
+// ModelCostProjection is the profile's projected cost had it all gone to
+// one model
+type ModelCostProjection struct {
+	Model          string  `json:"model"`
+	MonthlyCostUSD float64 `json:"monthly_cost_usd"`
+	DeltaUSD       float64 `json:"delta_usd"`               // Against the current monthly cost; negative saves
+	DeltaPercent   float64 `json:"delta_percent,omitempty"` // Unset when there is no current cost
+}
+
+// UsageSimulation compares a usage profile's monthly cost across models.
+// Projections assume the same token counts and request mix, scaled from
+// the profile's days to a 30-day month.
+type UsageSimulation struct {
+	Days                  int                   `json:"days"`
+	Since                 time.Time             `json:"since"`
+	RequestType           string                `json:"request_type,omitempty"`
+	Profile               []ModelUsageProfile   `json:"profile"`
+	CurrentMonthlyCostUSD float64               `json:"current_monthly_cost_usd"`
+	Projections           []ModelCostProjection `json:"projections"` // Cheapest first
+}
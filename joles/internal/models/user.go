@@ -12,32 +12,59 @@ type User struct {
 	PasswordHash string    `json:"-"` // Never expose in JSON
 	IsActive     bool      `json:"is_active"`
 	Role         string    `json:"role"` // "admin", "user", "developer"
+	Plan         string    `json:"plan,omitempty"`
 	CreatedAt    time.Time `json:"created_at"`
 	UpdatedAt    time.Time `json:"updated_at"`
 }
 
-// APIKey represents an API key for authentication
+// APIKey represents a personal API key for authentication. It acts as its
+// owner within its scopes; the key itself is only shown when it is
+// created.
 type APIKey struct {
-	ID          int64     `json:"id"`
-	UserID      int64     `json:"user_id"`
-	Key         string    `json:"key"`
-	Name        string    `json:"name"`
-	Description string    `json:"description,omitempty"`
-	IsActive    bool      `json:"is_active"`
-	ExpiresAt   time.Time `json:"expires_at,omitempty"`
-	LastUsedAt  time.Time `json:"last_used_at,omitempty"`
-	CreatedAt   time.Time `json:"created_at"`
-}
-
-// Session represents a user session
+	ID          int64      `json:"id"`
+	UserID      int64      `json:"user_id"`
+	Name        string     `json:"name"`
+	Description string     `json:"description,omitempty"`
+	KeyPrefix   string     `json:"key_prefix"`
+	Scopes      []string   `json:"scopes"`
+	IsActive    bool       `json:"is_active"` // Unrevoked and unexpired
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt  *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt   *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// API key scopes: read allows GET and HEAD requests, write any other, and
+// admin keeps an admin owner's role
+const (
+	APIKeyScopeRead  = "read"
+	APIKeyScopeWrite = "write"
+	APIKeyScopeAdmin = "admin"
+)
+
+// CreatedAPIKey is returned once, when an API key is created
+type CreatedAPIKey struct {
+	*APIKey
+	Key string `json:"key"`
+}
+
+// Session represents a user session: a login token and the device it was
+// issued to
 type Session struct {
 	ID        int64     `json:"id"`
 	UserID    int64     `json:"user_id"`
-	Token     string    `json:"token"`
+	TokenID   string    `json:"-"` // jti of the session's token
 	IPAddress string    `json:"ip_address"`
 	UserAgent string    `json:"user_agent"`
 	ExpiresAt time.Time `json:"expires_at"`
 	CreatedAt time.Time `json:"created_at"`
+	Current   bool      `json:"current"` // Made with the token of the request
+}
+
+// SessionClient is the device a session is started from
+type SessionClient struct {
+	IPAddress string
+	UserAgent string
 }
 
 // LoginRequest represents a login request
@@ -54,17 +81,33 @@ type RegisterRequest struct {
 	FullName string `json:"full_name,omitempty"`
 }
 
+// AcceptInvitationRequest sets the password of an invited account
+type AcceptInvitationRequest struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required,min=8"`
+}
+
 // LoginResponse represents a login response
 type LoginResponse struct {
 	User  *User  `json:"user"`
 	Token string `json:"token"`
 }
 
-// CreateAPIKeyRequest represents a request to create an API key
+// CreateAPIKeyRequest represents a request to create an API key. Scopes
+// default to read and write; keys without expires_at never expire.
 type CreateAPIKeyRequest struct {
-	Name        string    `json:"name" binding:"required"`
-	Description string    `json:"description,omitempty"`
-	ExpiresAt   time.Time `json:"expires_at,omitempty"`
+	Name        string     `json:"name" binding:"required,min=1,max=100"`
+	Description string     `json:"description,omitempty" binding:"max=500"`
+	Scopes      []string   `json:"scopes,omitempty" binding:"omitempty,max=3,dive,oneof=read write admin"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+}
+
+// UpdateAPIKeyRequest renames an API key or changes its scopes; fields left
+// out are unchanged
+type UpdateAPIKeyRequest struct {
+	Name        *string  `json:"name,omitempty" binding:"omitempty,min=1,max=100"`
+	Description *string  `json:"description,omitempty" binding:"omitempty,max=500"`
+	Scopes      []string `json:"scopes,omitempty" binding:"omitempty,min=1,max=3,dive,oneof=read write admin"`
 }
 
 // UserProfile represents user profile information
@@ -107,3 +150,53 @@ type ProviderAPIKeyResponse struct {
 	CreatedAt     time.Time  `json:"created_at"`
 	HasKey        bool       `json:"has_key"` // Indicates if key is set
 }
+
+// PendingKeySync represents a provider key sync queued until the backend is reachable
+type PendingKeySync struct {
+	ID            int64     `json:"id"`
+	UserID        string    `json:"user_id"`
+	Attempts      int       `json:"attempts"`
+	LastError     string    `json:"last_error,omitempty"`
+	NextAttemptAt time.Time `json:"next_attempt_at"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// User import job statuses
+const (
+	ImportStatusQueued      = "queued"
+	ImportStatusRunning     = "running"
+	ImportStatusCompleted   = "completed"
+	ImportStatusInterrupted = "interrupted" // The server restarted mid-import
+)
+
+// UserImportRow is one account to provision in a bulk import
+type UserImportRow struct {
+	Row      int    `json:"row"` // 1-based line in the source file, after the header
+	Email    string `json:"email"`
+	FullName string `json:"name,omitempty"`
+	Role     string `json:"role,omitempty"`
+	Plan     string `json:"plan,omitempty"`
+}
+
+// UserImportError reports why a row was not imported
+type UserImportError struct {
+	Row   int    `json:"row"`
+	Email string `json:"email,omitempty"`
+	Error string `json:"error"`
+}
+
+// UserImportJob tracks an asynchronous bulk user import
+type UserImportJob struct {
+	ID          int64             `json:"id"`
+	CreatedBy   string            `json:"created_by"`
+	Source      string            `json:"source"` // "csv"
+	Status      string            `json:"status"`
+	TotalRows   int               `json:"total_rows"`
+	Processed   int               `json:"processed"`
+	Created     int               `json:"created"`
+	Failed      int               `json:"failed"`
+	Errors      []UserImportError `json:"errors"`
+	CreatedAt   time.Time         `json:"created_at"`
+	CompletedAt *time.Time        `json:"completed_at,omitempty"`
+}
@@ -0,0 +1,66 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Webhook event types
+const (
+	WebhookEventDocumentCreated = "document.created"
+	WebhookEventDocumentUpdated = "document.updated"
+	WebhookEventDocumentDeleted = "document.deleted"
+	WebhookEventQuotaThreshold  = "quota.threshold_crossed"
+)
+
+// WebhookEvents lists the event types webhooks can subscribe to
+var WebhookEvents = []string{
+	WebhookEventDocumentCreated,
+	WebhookEventDocumentUpdated,
+	WebhookEventDocumentDeleted,
+	WebhookEventQuotaThreshold,
+}
+
+// Webhook is a user's HTTP endpoint that receives signed event payloads
+type Webhook struct {
+	ID     int64    `json:"id"`
+	UserID string   `json:"-"`
+	URL    string   `json:"url"`
+	Events []string `json:"events"`
+	// Secret signs payloads; it is only shown when the webhook is created
+	Secret    string    `json:"secret,omitempty"`
+	Active    bool      `json:"active"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// WebhookDelivery is one attempt at sending an event to a webhook
+type WebhookDelivery struct {
+	ID        int64           `json:"id"`
+	WebhookID int64           `json:"webhook_id"`
+	EventID   string          `json:"event_id"`
+	EventType string          `json:"event_type"`
+	Payload   json.RawMessage `json:"payload"`
+	// StatusCode is 0 when no response was received
+	StatusCode      int    `json:"status_code"`
+	Success         bool   `json:"success"`
+	LatencyMs       int64  `json:"latency_ms"`
+	ResponseExcerpt string `json:"response_excerpt,omitempty"`
+	Error           string `json:"error,omitempty"`
+	Test            bool   `json:"test"`
+	// RetryOf is the delivery this one resent
+	RetryOf   *int64    `json:"retry_of,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// CreateWebhookRequest registers a webhook; no events subscribes to all
+type CreateWebhookRequest struct {
+	URL    string   `json:"url" binding:"required"`
+	Events []string `json:"events"`
+}
+
+// TestWebhookRequest picks the event type of a test delivery; the
+// webhook's first event when empty
+type TestWebhookRequest struct {
+	EventType string `json:"event_type"`
+}
@@ -0,0 +1,68 @@
+package models
+
+import "time"
+
+// WidgetLimits constrain what a widget token can spend
+type WidgetLimits struct {
+	RequestsPerMinute int     `json:"requests_per_minute"`
+	DailyRequests     int     `json:"daily_requests"`
+	DailyTokens       int     `json:"daily_tokens"`
+	MaxTokens         int     `json:"max_tokens"`   // Output tokens per response
+	MaxCostUSD        float64 `json:"max_cost_usd"` // Cost per response
+}
+
+// WidgetToken is a public credential for an embedded chat widget. It only
+// allows completions with one persona, from the listed origins, within its
+// limits; the token itself is only shown when it is created.
+type WidgetToken struct {
+	ID             int64        `json:"id"`
+	UserID         string       `json:"user_id"`
+	Name           string       `json:"name"`
+	TokenPrefix    string       `json:"token_prefix"`
+	PersonaID      int64        `json:"persona_id"`
+	Model          string       `json:"model,omitempty"` // The persona's default model when empty
+	AllowedOrigins []string     `json:"allowed_origins"`
+	Limits         WidgetLimits `json:"limits"`
+	CreatedAt      time.Time    `json:"created_at"`
+	LastUsedAt     *time.Time   `json:"last_used_at,omitempty"`
+	RevokedAt      *time.Time   `json:"revoked_at,omitempty"`
+}
+
+// CreateWidgetTokenRequest represents the request to create a widget token.
+// Limits left at 0 default to the instance maximums, and higher values are
+// lowered to them.
+type CreateWidgetTokenRequest struct {
+	Name              string   `json:"name" binding:"required,min=1,max=100"`
+	PersonaID         int64    `json:"persona_id" binding:"required,gt=0"`
+	Model             string   `json:"model,omitempty" binding:"max=100"`
+	AllowedOrigins    []string `json:"allowed_origins" binding:"required,min=1,max=20,dive,url"`
+	RequestsPerMinute int      `json:"requests_per_minute,omitempty" binding:"min=0"`
+	DailyRequests     int      `json:"daily_requests,omitempty" binding:"min=0"`
+	DailyTokens       int      `json:"daily_tokens,omitempty" binding:"min=0"`
+	MaxTokens         int      `json:"max_tokens,omitempty" binding:"min=0"`
+	MaxCostUSD        float64  `json:"max_cost_usd,omitempty" binding:"min=0"`
+}
+
+// CreatedWidgetToken is returned once, when a widget token is created
+type CreatedWidgetToken struct {
+	*WidgetToken
+	Token string `json:"token"`
+}
+
+// WidgetCompletionRequest is a message sent from an embedded widget
+type WidgetCompletionRequest struct {
+	Message string `json:"message" binding:"required,min=1,max=4000"`
+	// Continues one of the visitor's conversations
+	ChatID int64 `json:"chat_id,omitempty" binding:"omitempty,gt=0"`
+}
+
+// WidgetCompletionResponse is the answer returned to an embedded widget
+type WidgetCompletionResponse struct {
+	ChatID    int64  `json:"chat_id"`
+	MessageID int64  `json:"message_id"`
+	Content   string `json:"content"`
+	Tokens    int    `json:"tokens"`
+	Truncated bool   `json:"truncated,omitempty"`
+	// Visitor session to send back in X-Widget-Session to continue chats
+	SessionID string `json:"session_id"`
+}
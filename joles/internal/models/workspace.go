@@ -0,0 +1,86 @@
+package models
+
+import "time"
+
+// Workspace snapshot and restore job statuses
+const (
+	WorkspaceJobQueued      = "queued"
+	WorkspaceJobRunning     = "running"
+	WorkspaceJobCompleted   = "completed"
+	WorkspaceJobFailed      = "failed"
+	WorkspaceJobInterrupted = "interrupted" // The server restarted mid-job
+)
+
+// What started a snapshot
+const (
+	SnapshotTriggerManual     = "manual"
+	SnapshotTriggerScheduled  = "scheduled"
+	SnapshotTriggerPreRestore = "pre_restore" // Taken automatically before a restore
+)
+
+// WorkspaceSnapshotFormat is the version of the snapshot file layout
+const WorkspaceSnapshotFormat = 1
+
+// WorkspaceSnapshot is a copy of a user's chats, documents and settings
+// kept in the storage backend. SHA256 is the digest of the stored file,
+// checked before it is restored.
+type WorkspaceSnapshot struct {
+	ID          int64            `json:"id"`
+	UserID      string           `json:"user_id"`
+	Trigger     string           `json:"trigger"`
+	Status      string           `json:"status"`
+	Backend     string           `json:"-"`
+	Key         string           `json:"-"`
+	SizeBytes   int64            `json:"size_bytes"`
+	SHA256      string           `json:"sha256,omitempty"`
+	Counts      map[string]int64 `json:"counts"` // Rows captured, by table
+	Error       string           `json:"error,omitempty"`
+	CreatedAt   time.Time        `json:"created_at"`
+	CompletedAt *time.Time       `json:"completed_at,omitempty"`
+}
+
+// WorkspaceSnapshotData is the content of a snapshot file: the user's rows
+// of each workspace table, as column values
+type WorkspaceSnapshotData struct {
+	Format    int                                 `json:"format"`
+	UserID    string                              `json:"user_id"`
+	CreatedAt time.Time                           `json:"created_at"`
+	Tables    map[string][]map[string]interface{} `json:"tables"`
+}
+
+// WorkspaceRestore is a job restoring a user's workspace from a snapshot
+type WorkspaceRestore struct {
+	ID         int64  `json:"id"`
+	UserID     string `json:"user_id"`
+	SnapshotID int64  `json:"snapshot_id"`
+	// Snapshot of the workspace as it was before the restore, to undo it
+	SafetySnapshotID *int64           `json:"safety_snapshot_id,omitempty"`
+	Status           string           `json:"status"`
+	Restored         map[string]int64 `json:"restored"` // Rows restored, by table
+	Error            string           `json:"error,omitempty"`
+	CreatedAt        time.Time        `json:"created_at"`
+	CompletedAt      *time.Time       `json:"completed_at,omitempty"`
+}
+
+// RestoreWorkspaceRequest names the snapshot to restore, or a point in
+// time to restore the latest completed snapshot taken at or before
+type RestoreWorkspaceRequest struct {
+	SnapshotID int64      `json:"snapshot_id" binding:"omitempty,gt=0"`
+	At         *time.Time `json:"at"`
+}
+
+// SnapshotSchedule takes snapshots of a user's workspace every
+// IntervalHours, keeping the newest Keep scheduled ones
+type SnapshotSchedule struct {
+	UserID        string     `json:"user_id"`
+	IntervalHours int        `json:"interval_hours"` // 0 turns scheduled snapshots off
+	Keep          int        `json:"keep"`
+	NextRunAt     *time.Time `json:"next_run_at,omitempty"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+// SetSnapshotScheduleRequest replaces a user's snapshot schedule
+type SetSnapshotScheduleRequest struct {
+	IntervalHours int `json:"interval_hours" binding:"min=0,max=720"`
+	Keep          int `json:"keep" binding:"omitempty,min=1,max=30"`
+}
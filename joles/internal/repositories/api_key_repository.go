@@ -0,0 +1,148 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"lio-ai/internal/models"
+)
+
+// APIKeyRepository stores users' personal API keys
+type APIKeyRepository struct {
+	db *sql.DB
+}
+
+// NewAPIKeyRepository creates a new API key repository
+func NewAPIKeyRepository(db *sql.DB) *APIKeyRepository {
+	return &APIKeyRepository{db: db}
+}
+
+const apiKeyColumns = `id, user_id, name, COALESCE(description, ''), key_prefix, scopes, expires_at, last_used_at, revoked_at, created_at`
+
+// Create stores an API key under the hash of its key
+func (r *APIKeyRepository) Create(k *models.APIKey, keyHash string) error {
+	now := time.Now()
+	result, err := r.db.Exec(`
+		INSERT INTO api_keys (user_id, name, description, key_hash, key_prefix, scopes, expires_at, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`, k.UserID, k.Name, k.Description, keyHash, k.KeyPrefix, strings.Join(k.Scopes, " "), k.ExpiresAt, now)
+	if err != nil {
+		return fmt.Errorf("failed to create API key: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to get last insert id: %w", err)
+	}
+	k.ID = id
+	k.CreatedAt = now
+	k.IsActive = true
+	return nil
+}
+
+// GetActiveByHash retrieves an unrevoked API key by the hash of its key,
+// or nil. Expired keys are returned as inactive.
+func (r *APIKeyRepository) GetActiveByHash(keyHash string) (*models.APIKey, error) {
+	k, err := scanAPIKey(r.db.QueryRow(
+		"SELECT "+apiKeyColumns+" FROM api_keys WHERE key_hash = ? AND revoked_at IS NULL", keyHash,
+	))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get API key: %w", err)
+	}
+	return k, nil
+}
+
+// Get retrieves one of a user's API keys, or nil
+func (r *APIKeyRepository) Get(id, userID int64) (*models.APIKey, error) {
+	k, err := scanAPIKey(r.db.QueryRow("SELECT "+apiKeyColumns+" FROM api_keys WHERE id = ? AND user_id = ?", id, userID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get API key: %w", err)
+	}
+	return k, nil
+}
+
+// ListByUser retrieves a user's API keys, newest first
+func (r *APIKeyRepository) ListByUser(userID int64) ([]models.APIKey, error) {
+	rows, err := r.db.Query("SELECT "+apiKeyColumns+" FROM api_keys WHERE user_id = ? ORDER BY id DESC", userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list API keys: %w", err)
+	}
+	defer rows.Close()
+
+	keys := make([]models.APIKey, 0)
+	for rows.Next() {
+		k, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan API key: %w", err)
+		}
+		keys = append(keys, *k)
+	}
+	return keys, rows.Err()
+}
+
+// Update saves an API key's name, description and scopes
+func (r *APIKeyRepository) Update(k *models.APIKey) error {
+	_, err := r.db.Exec(
+		"UPDATE api_keys SET name = ?, description = ?, scopes = ? WHERE id = ? AND user_id = ?",
+		k.Name, k.Description, strings.Join(k.Scopes, " "), k.ID, k.UserID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update API key: %w", err)
+	}
+	return nil
+}
+
+// Revoke revokes one of a user's API keys, reporting whether it was
+// unrevoked
+func (r *APIKeyRepository) Revoke(id, userID int64) (bool, error) {
+	result, err := r.db.Exec(
+		"UPDATE api_keys SET revoked_at = ? WHERE id = ? AND user_id = ? AND revoked_at IS NULL",
+		time.Now(), id, userID,
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to revoke API key: %w", err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to revoke API key: %w", err)
+	}
+	return n > 0, nil
+}
+
+// RecordUse sets when an API key was last used
+func (r *APIKeyRepository) RecordUse(id int64) error {
+	if _, err := r.db.Exec("UPDATE api_keys SET last_used_at = ? WHERE id = ?", time.Now(), id); err != nil {
+		return fmt.Errorf("failed to record API key use: %w", err)
+	}
+	return nil
+}
+
+func scanAPIKey(row interface{ Scan(...interface{}) error }) (*models.APIKey, error) {
+	var k models.APIKey
+	var scopes string
+	var expires, lastUsed, revoked sql.NullTime
+	if err := row.Scan(&k.ID, &k.UserID, &k.Name, &k.Description, &k.KeyPrefix, &scopes,
+		&expires, &lastUsed, &revoked, &k.CreatedAt); err != nil {
+		return nil, err
+	}
+	k.Scopes = strings.Fields(scopes)
+	if expires.Valid {
+		k.ExpiresAt = &expires.Time
+	}
+	if lastUsed.Valid {
+		k.LastUsedAt = &lastUsed.Time
+	}
+	if revoked.Valid {
+		k.RevokedAt = &revoked.Time
+	}
+	k.IsActive = !revoked.Valid && (!expires.Valid || expires.Time.After(time.Now()))
+	return &k, nil
+}
@@ -0,0 +1,263 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"lio-ai/internal/models"
+)
+
+// BillingRepository handles billing statements and adjustments
+type BillingRepository struct {
+	db *sql.DB
+}
+
+// NewBillingRepository creates a new billing repository
+func NewBillingRepository(db *sql.DB) *BillingRepository {
+	return &BillingRepository{db: db}
+}
+
+const billingStatementColumns = `id, user_id, period, period_start, period_end, requests, tokens_input, tokens_output,
+	tokens_total, usage_cost_usd, adjustments_usd, total_usd, closed_at`
+
+const billingAdjustmentColumns = `id, user_id, amount_usd, reason, COALESCE(created_by, ''), statement_id, created_at`
+
+// RolledUntil returns the hour usage before which is in the rollups
+// statements are closed from
+func (r *BillingRepository) RolledUntil() (time.Time, error) {
+	return UsageRollupWatermark(r.db)
+}
+
+// CloseMonth closes [start, end) into a statement for every registered
+// user with usage in it or adjustments added before end who has no
+// statement for period yet, returning how many were closed. Usage is read
+// from the daily rollups, which must cover the month; the adjustments are
+// closed into the statements, in one transaction.
+func (r *BillingRepository) CloseMonth(period string, start, end time.Time) (int, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	startBucket, endBucket := start.UTC().Format(rollupBucketFormat), end.UTC().Format(rollupBucketFormat)
+	rows, err := tx.Query(`
+		SELECT user_id FROM (
+			SELECT user_id FROM usage_rollups_daily WHERE bucket_start >= ? AND bucket_start < ?
+			UNION
+			SELECT user_id FROM billing_adjustments WHERE statement_id IS NULL AND julianday(created_at) < julianday(?)
+		)
+		WHERE user_id IN (SELECT CAST(id AS TEXT) FROM users)
+			AND user_id NOT IN (SELECT user_id FROM billing_statements WHERE period = ?)
+	`, startBucket, endBucket, endBucket, period)
+	if err != nil {
+		return 0, fmt.Errorf("failed to find users to bill: %w", err)
+	}
+	var users []string
+	for rows.Next() {
+		var userID string
+		if err := rows.Scan(&userID); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan user to bill: %w", err)
+		}
+		users = append(users, userID)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("failed to find users to bill: %w", err)
+	}
+
+	now := time.Now()
+	for _, userID := range users {
+		s := &models.BillingStatement{UserID: userID, Period: period, PeriodStart: start.UTC(), PeriodEnd: end.UTC(), ClosedAt: now}
+		lines, err := billingLines(tx, userID, startBucket, endBucket)
+		if err != nil {
+			return 0, err
+		}
+		for _, l := range lines {
+			s.Requests += l.Requests
+			s.TokensInput += l.TokensInput
+			s.TokensOutput += l.TokensOutput
+			s.TokensTotal += l.TokensTotal
+			s.UsageCostUSD += l.CostUSD
+		}
+		if err := tx.QueryRow(`
+			SELECT COALESCE(SUM(amount_usd), 0) FROM billing_adjustments
+			WHERE user_id = ? AND statement_id IS NULL AND julianday(created_at) < julianday(?)
+		`, userID, endBucket).Scan(&s.AdjustmentsUSD); err != nil {
+			return 0, fmt.Errorf("failed to sum billing adjustments: %w", err)
+		}
+		s.TotalUSD = s.UsageCostUSD + s.AdjustmentsUSD
+
+		result, err := tx.Exec(`
+			INSERT INTO billing_statements (user_id, period, period_start, period_end, requests, tokens_input, tokens_output,
+				tokens_total, usage_cost_usd, adjustments_usd, total_usd, closed_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		`, s.UserID, s.Period, s.PeriodStart, s.PeriodEnd, s.Requests, s.TokensInput, s.TokensOutput,
+			s.TokensTotal, s.UsageCostUSD, s.AdjustmentsUSD, s.TotalUSD, s.ClosedAt)
+		if err != nil {
+			return 0, fmt.Errorf("failed to create billing statement: %w", err)
+		}
+		if s.ID, err = result.LastInsertId(); err != nil {
+			return 0, fmt.Errorf("failed to get billing statement id: %w", err)
+		}
+		for _, l := range lines {
+			if _, err := tx.Exec(`
+				INSERT INTO billing_statement_lines (statement_id, model, requests, tokens_input, tokens_output, tokens_total, cost_usd)
+				VALUES (?, ?, ?, ?, ?, ?, ?)
+			`, s.ID, l.Model, l.Requests, l.TokensInput, l.TokensOutput, l.TokensTotal, l.CostUSD); err != nil {
+				return 0, fmt.Errorf("failed to create billing statement line: %w", err)
+			}
+		}
+		if _, err := tx.Exec(`
+			UPDATE billing_adjustments SET statement_id = ?
+			WHERE user_id = ? AND statement_id IS NULL AND julianday(created_at) < julianday(?)
+		`, s.ID, userID, endBucket); err != nil {
+			return 0, fmt.Errorf("failed to close billing adjustments: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit billing statements: %w", err)
+	}
+	return len(users), nil
+}
+
+// billingLines sums a user's daily rollups in [startBucket, endBucket) by
+// model, most expensive first
+func billingLines(tx *sql.Tx, userID, startBucket, endBucket string) ([]models.BillingStatementLine, error) {
+	rows, err := tx.Query(`
+		SELECT model_used, SUM(requests), SUM(tokens_input), SUM(tokens_output), SUM(tokens_total), SUM(cost_usd)
+		FROM usage_rollups_daily
+		WHERE user_id = ? AND bucket_start >= ? AND bucket_start < ?
+		GROUP BY model_used
+		ORDER BY SUM(cost_usd) DESC, model_used
+	`, userID, startBucket, endBucket)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sum usage to bill: %w", err)
+	}
+	defer rows.Close()
+
+	lines := make([]models.BillingStatementLine, 0)
+	for rows.Next() {
+		var l models.BillingStatementLine
+		if err := rows.Scan(&l.Model, &l.Requests, &l.TokensInput, &l.TokensOutput, &l.TokensTotal, &l.CostUSD); err != nil {
+			return nil, fmt.Errorf("failed to scan usage to bill: %w", err)
+		}
+		lines = append(lines, l)
+	}
+	return lines, rows.Err()
+}
+
+// ListStatements retrieves a user's statements, latest month first
+func (r *BillingRepository) ListStatements(userID string) ([]models.BillingStatement, error) {
+	rows, err := r.db.Query("SELECT "+billingStatementColumns+" FROM billing_statements WHERE user_id = ? ORDER BY period DESC", userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list billing statements: %w", err)
+	}
+	defer rows.Close()
+
+	statements := make([]models.BillingStatement, 0)
+	for rows.Next() {
+		s, err := scanBillingStatement(rows)
+		if err != nil {
+			return nil, err
+		}
+		statements = append(statements, *s)
+	}
+	return statements, rows.Err()
+}
+
+// GetStatement retrieves a statement with its lines and adjustments, or
+// nil when there is none
+func (r *BillingRepository) GetStatement(id int64) (*models.BillingStatement, error) {
+	s, err := scanBillingStatement(r.db.QueryRow("SELECT "+billingStatementColumns+" FROM billing_statements WHERE id = ?", id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.Query(`
+		SELECT model, requests, tokens_input, tokens_output, tokens_total, cost_usd
+		FROM billing_statement_lines WHERE statement_id = ?
+		ORDER BY cost_usd DESC, model
+	`, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get billing statement lines: %w", err)
+	}
+	defer rows.Close()
+	s.Lines = make([]models.BillingStatementLine, 0)
+	for rows.Next() {
+		var l models.BillingStatementLine
+		if err := rows.Scan(&l.Model, &l.Requests, &l.TokensInput, &l.TokensOutput, &l.TokensTotal, &l.CostUSD); err != nil {
+			return nil, fmt.Errorf("failed to scan billing statement line: %w", err)
+		}
+		s.Lines = append(s.Lines, l)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if s.Adjustments, err = r.listAdjustments("statement_id = ?", id); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// CreateAdjustment adds a pending adjustment to a user's bill
+func (r *BillingRepository) CreateAdjustment(a *models.BillingAdjustment) error {
+	a.CreatedAt = time.Now()
+	result, err := r.db.Exec(`
+		INSERT INTO billing_adjustments (user_id, amount_usd, reason, created_by, created_at)
+		VALUES (?, ?, ?, ?, ?)
+	`, a.UserID, a.AmountUSD, a.Reason, a.CreatedBy, a.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create billing adjustment: %w", err)
+	}
+	a.ID, err = result.LastInsertId()
+	return err
+}
+
+// ListPendingAdjustments retrieves a user's adjustments not yet closed
+// into a statement, oldest first
+func (r *BillingRepository) ListPendingAdjustments(userID string) ([]models.BillingAdjustment, error) {
+	return r.listAdjustments("user_id = ? AND statement_id IS NULL", userID)
+}
+
+func (r *BillingRepository) listAdjustments(where string, args ...interface{}) ([]models.BillingAdjustment, error) {
+	rows, err := r.db.Query("SELECT "+billingAdjustmentColumns+" FROM billing_adjustments WHERE "+where+" ORDER BY created_at, id", args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list billing adjustments: %w", err)
+	}
+	defer rows.Close()
+
+	adjustments := make([]models.BillingAdjustment, 0)
+	for rows.Next() {
+		var a models.BillingAdjustment
+		var statementID sql.NullInt64
+		if err := rows.Scan(&a.ID, &a.UserID, &a.AmountUSD, &a.Reason, &a.CreatedBy, &statementID, &a.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan billing adjustment: %w", err)
+		}
+		if statementID.Valid {
+			a.StatementID = &statementID.Int64
+		}
+		adjustments = append(adjustments, a)
+	}
+	return adjustments, rows.Err()
+}
+
+func scanBillingStatement(row interface{ Scan(...interface{}) error }) (*models.BillingStatement, error) {
+	s := &models.BillingStatement{}
+	err := row.Scan(&s.ID, &s.UserID, &s.Period, &s.PeriodStart, &s.PeriodEnd, &s.Requests, &s.TokensInput, &s.TokensOutput,
+		&s.TokensTotal, &s.UsageCostUSD, &s.AdjustmentsUSD, &s.TotalUSD, &s.ClosedAt)
+	if err == sql.ErrNoRows {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan billing statement: %w", err)
+	}
+	return s, nil
+}
@@ -0,0 +1,95 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"lio-ai/internal/models"
+)
+
+// BudgetRepository handles spending budgets
+type BudgetRepository struct {
+	db *sql.DB
+}
+
+// NewBudgetRepository creates a new budget repository
+func NewBudgetRepository(db *sql.DB) *BudgetRepository {
+	return &BudgetRepository{db: db}
+}
+
+const budgetColumns = `id, scope, scope_id, monthly_usd, mode, COALESCE(updated_by, ''), created_at, updated_at`
+
+// Get retrieves the budget of scope and scopeID
+func (r *BudgetRepository) Get(scope, scopeID string) (*models.Budget, error) {
+	b, err := scanBudget(r.db.QueryRow("SELECT "+budgetColumns+" FROM budgets WHERE scope = ? AND scope_id = ?", scope, scopeID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return b, err
+}
+
+// List retrieves every budget
+func (r *BudgetRepository) List() ([]models.Budget, error) {
+	rows, err := r.db.Query("SELECT " + budgetColumns + " FROM budgets ORDER BY scope, scope_id")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list budgets: %w", err)
+	}
+	defer rows.Close()
+
+	budgets := make([]models.Budget, 0)
+	for rows.Next() {
+		b, err := scanBudget(rows)
+		if err != nil {
+			return nil, err
+		}
+		budgets = append(budgets, *b)
+	}
+	return budgets, rows.Err()
+}
+
+// Upsert creates a budget or changes its amount and mode
+func (r *BudgetRepository) Upsert(b *models.Budget) error {
+	now := time.Now()
+	_, err := r.db.Exec(`
+		INSERT INTO budgets (scope, scope_id, monthly_usd, mode, updated_by, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(scope, scope_id) DO UPDATE SET
+			monthly_usd = excluded.monthly_usd,
+			mode = excluded.mode,
+			updated_by = excluded.updated_by,
+			updated_at = excluded.updated_at
+	`, b.Scope, b.ScopeID, b.MonthlyUSD, b.Mode, b.UpdatedBy, now, now)
+	if err != nil {
+		return fmt.Errorf("failed to save budget: %w", err)
+	}
+	saved, err := r.Get(b.Scope, b.ScopeID)
+	if err != nil {
+		return err
+	}
+	*b = *saved
+	return nil
+}
+
+// Delete removes the budget of scope and scopeID, reporting whether there
+// was one
+func (r *BudgetRepository) Delete(scope, scopeID string) (bool, error) {
+	result, err := r.db.Exec("DELETE FROM budgets WHERE scope = ? AND scope_id = ?", scope, scopeID)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete budget: %w", err)
+	}
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
+
+func scanBudget(row interface{ Scan(...interface{}) error }) (*models.Budget, error) {
+	b := &models.Budget{}
+	err := row.Scan(&b.ID, &b.Scope, &b.ScopeID, &b.MonthlyUSD, &b.Mode, &b.UpdatedBy, &b.CreatedAt, &b.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan budget: %w", err)
+	}
+	return b, nil
+}
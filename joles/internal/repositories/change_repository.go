@@ -0,0 +1,93 @@
+package repositories
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"lio-ai/internal/models"
+)
+
+// execer is satisfied by *sql.DB and *sql.Tx, so changes can be recorded
+// inside the transaction that makes them
+type execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+// ChangeRepository handles the change feed consumed by the analytics
+// service
+type ChangeRepository struct {
+	db *sql.DB
+}
+
+// NewChangeRepository creates a new change repository
+func NewChangeRepository(db *sql.DB) *ChangeRepository {
+	return &ChangeRepository{db: db}
+}
+
+// record appends a change event through exec. A nil repository records
+// nothing, so writers don't need to know whether the feed is enabled.
+func (r *ChangeRepository) record(exec execer, entity, op string, entityID int64, userID string, data interface{}) error {
+	if r == nil {
+		return nil
+	}
+	var payload interface{}
+	if data != nil {
+		b, err := json.Marshal(data)
+		if err != nil {
+			return fmt.Errorf("failed to encode change: %w", err)
+		}
+		payload = string(b)
+	}
+
+	query := `
+		INSERT INTO change_events (entity, op, entity_id, user_id, data, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`
+	if _, err := exec.Exec(query, entity, op, entityID, userID, payload, time.Now()); err != nil {
+		return fmt.Errorf("failed to record change: %w", err)
+	}
+	return nil
+}
+
+// ListSince retrieves up to limit events after the cursor, oldest first
+func (r *ChangeRepository) ListSince(since int64, limit int) ([]models.ChangeEvent, error) {
+	query := `
+		SELECT id, entity, op, entity_id, user_id, data, created_at
+		FROM change_events
+		WHERE id > ?
+		ORDER BY id ASC
+		LIMIT ?
+	`
+
+	rows, err := r.db.Query(query, since, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get change events: %w", err)
+	}
+	defer rows.Close()
+
+	events := make([]models.ChangeEvent, 0)
+	for rows.Next() {
+		var e models.ChangeEvent
+		var data sql.NullString
+		if err := rows.Scan(&e.ID, &e.Entity, &e.Op, &e.EntityID, &e.UserID, &data, &e.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan change event: %w", err)
+		}
+		if data.Valid {
+			e.Data = json.RawMessage(data.String)
+		}
+		events = append(events, e)
+	}
+
+	return events, rows.Err()
+}
+
+// DeleteBefore deletes events recorded before cutoff
+func (r *ChangeRepository) DeleteBefore(cutoff time.Time) (int64, error) {
+	result, err := r.db.Exec("DELETE FROM change_events WHERE created_at < ?", cutoff)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete change events: %w", err)
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,74 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"lio-ai/internal/models"
+)
+
+// ChatDocumentRepository handles the documents attached to chats as context
+type ChatDocumentRepository struct {
+	db *sql.DB
+}
+
+// NewChatDocumentRepository creates a new chat document repository
+func NewChatDocumentRepository(db *sql.DB) *ChatDocumentRepository {
+	return &ChatDocumentRepository{db: db}
+}
+
+// Attach attaches documents to a chat; documents already attached are
+// left as they are
+func (r *ChatDocumentRepository) Attach(chatID int64, documentIDs []uint) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	for _, id := range documentIDs {
+		if _, err := tx.Exec("INSERT OR IGNORE INTO chat_documents (chat_id, document_id, created_at) VALUES (?, ?, ?)",
+			chatID, id, now); err != nil {
+			return fmt.Errorf("failed to attach document: %w", err)
+		}
+	}
+	return tx.Commit()
+}
+
+// List retrieves the documents attached to a chat in the order they were
+// attached. Documents deleted since are left out.
+func (r *ChatDocumentRepository) List(chatID int64) ([]models.ChatDocument, error) {
+	rows, err := r.db.Query(`
+		SELECT cd.chat_id, cd.document_id, d.title, cd.created_at
+		FROM chat_documents cd
+		JOIN documents d ON d.id = cd.document_id
+		WHERE cd.chat_id = ?
+		ORDER BY cd.created_at, cd.document_id
+	`, chatID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get chat documents: %w", err)
+	}
+	defer rows.Close()
+
+	docs := make([]models.ChatDocument, 0)
+	for rows.Next() {
+		var doc models.ChatDocument
+		if err := rows.Scan(&doc.ChatID, &doc.DocumentID, &doc.Title, &doc.AttachedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan chat document: %w", err)
+		}
+		docs = append(docs, doc)
+	}
+	return docs, rows.Err()
+}
+
+// Detach removes a document from a chat, reporting whether it was attached
+func (r *ChatDocumentRepository) Detach(chatID int64, documentID uint) (bool, error) {
+	result, err := r.db.Exec("DELETE FROM chat_documents WHERE chat_id = ? AND document_id = ?", chatID, documentID)
+	if err != nil {
+		return false, fmt.Errorf("failed to detach document: %w", err)
+	}
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
@@ -2,17 +2,30 @@ package repositories
 
 import (
 	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
 	"lio-ai/internal/models"
 )
 
+// ErrChatNotFound is returned when a chat lookup matches no chat
+var ErrChatNotFound = errors.New("chat not found")
+
 // ChatRepository handles database operations for chats
 type ChatRepository struct {
 	db *sql.DB
+	// Serializes message inserts in this process so readers of the shared
+	// cache don't race the sequence lookup; the unique (chat_id, seq) index
+	// guards against other processes
+	seqMu sync.Mutex
+	// Change feed writes are recorded to; nil when the feed is disabled
+	changes *ChangeRepository
 }
 
 // NewChatRepository creates a new chat repository
@@ -20,37 +33,45 @@ func NewChatRepository(db *sql.DB) *ChatRepository {
 	return &ChatRepository{db: db}
 }
 
+// SetChangeFeed records chat and message writes to the change feed
+func (r *ChatRepository) SetChangeFeed(changes *ChangeRepository) {
+	r.changes = changes
+}
+
+// chatChange is the change feed payload of a chat, without the cached
+// summary
+func chatChange(chat *models.Chat) *models.Chat {
+	c := *chat
+	c.Summary = nil
+	return &c
+}
+
 // CreateChat creates a new chat
 func (r *ChatRepository) CreateChat(chat *models.Chat) error {
 	// Generate UUID for the chat
 	chat.ChatUUID = uuid.New().String()
 	
 	query := `
-		INSERT INTO chats (user_id, title, chat_uuid, created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?)
+		INSERT INTO chats (user_id, title, chat_uuid, context_strategy, persona_id, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		RETURNING id
 	`
 	
 	now := time.Now()
-	result, err := r.db.Exec(query, chat.UserID, chat.Title, chat.ChatUUID, now, now)
+	err := r.db.QueryRow(query, chat.UserID, chat.Title, chat.ChatUUID, chat.ContextStrategy, chat.PersonaID, now, now).Scan(&chat.ID)
 	if err != nil {
 		return fmt.Errorf("failed to create chat: %w", err)
 	}
 
-	id, err := result.LastInsertId()
-	if err != nil {
-		return fmt.Errorf("failed to get last insert id: %w", err)
-	}
-
-	chat.ID = id
 	chat.CreatedAt = now
 	chat.UpdatedAt = now
-	return nil
+	return r.changes.record(r.db, models.ChangeEntityChat, models.ChangeOpInsert, chat.ID, chat.UserID, chatChange(chat))
 }
 
 // GetChatByID retrieves a chat by its ID
 func (r *ChatRepository) GetChatByID(id int64) (*models.Chat, error) {
 	query := `
-		SELECT id, user_id, title, chat_uuid, created_at, updated_at
+		SELECT id, user_id, title, chat_uuid, context_strategy, is_pinned, max_output_tokens, max_cost_usd, budget_usd, COALESCE(budget_action, 'block'), persona_id, created_at, updated_at
 		FROM chats
 		WHERE id = ?
 	`
@@ -61,11 +82,18 @@ func (r *ChatRepository) GetChatByID(id int64) (*models.Chat, error) {
 		&chat.UserID,
 		&chat.Title,
 		&chat.ChatUUID,
+		&chat.ContextStrategy,
+		&chat.IsPinned,
+		&chat.MaxOutputTokens,
+		&chat.MaxCostUSD,
+		&chat.BudgetUSD,
+		&chat.BudgetAction,
+		&chat.PersonaID,
 		&chat.CreatedAt,
 		&chat.UpdatedAt,
 	)
 	if err == sql.ErrNoRows {
-		return nil, fmt.Errorf("chat not found")
+		return nil, ErrChatNotFound
 	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to get chat: %w", err)
@@ -77,7 +105,7 @@ func (r *ChatRepository) GetChatByID(id int64) (*models.Chat, error) {
 // GetChatByUUID retrieves a chat by its UUID
 func (r *ChatRepository) GetChatByUUID(chatUUID string) (*models.Chat, error) {
 	query := `
-		SELECT id, user_id, title, chat_uuid, created_at, updated_at
+		SELECT id, user_id, title, chat_uuid, context_strategy, is_pinned, max_output_tokens, max_cost_usd, budget_usd, COALESCE(budget_action, 'block'), persona_id, created_at, updated_at
 		FROM chats
 		WHERE chat_uuid = ?
 	`
@@ -88,11 +116,18 @@ func (r *ChatRepository) GetChatByUUID(chatUUID string) (*models.Chat, error) {
 		&chat.UserID,
 		&chat.Title,
 		&chat.ChatUUID,
+		&chat.ContextStrategy,
+		&chat.IsPinned,
+		&chat.MaxOutputTokens,
+		&chat.MaxCostUSD,
+		&chat.BudgetUSD,
+		&chat.BudgetAction,
+		&chat.PersonaID,
 		&chat.CreatedAt,
 		&chat.UpdatedAt,
 	)
 	if err == sql.ErrNoRows {
-		return nil, fmt.Errorf("chat not found")
+		return nil, ErrChatNotFound
 	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to get chat: %w", err)
@@ -104,10 +139,10 @@ func (r *ChatRepository) GetChatByUUID(chatUUID string) (*models.Chat, error) {
 // GetChatsByUserID retrieves all chats for a user
 func (r *ChatRepository) GetChatsByUserID(userID string, limit, offset int) ([]models.Chat, error) {
 	query := `
-		SELECT id, user_id, title, chat_uuid, created_at, updated_at
+		SELECT id, user_id, title, chat_uuid, context_strategy, is_pinned, max_output_tokens, max_cost_usd, budget_usd, COALESCE(budget_action, 'block'), persona_id, created_at, updated_at
 		FROM chats
 		WHERE user_id = ?
-		ORDER BY updated_at DESC
+		ORDER BY is_pinned DESC, updated_at DESC
 		LIMIT ? OFFSET ?
 	`
 
@@ -128,6 +163,13 @@ func (r *ChatRepository) GetChatsByUserID(userID string, limit, offset int) ([]m
 			&chat.UserID,
 			&chat.Title,
 			&chat.ChatUUID,
+			&chat.ContextStrategy,
+			&chat.IsPinned,
+			&chat.MaxOutputTokens,
+			&chat.MaxCostUSD,
+			&chat.BudgetUSD,
+			&chat.BudgetAction,
+			&chat.PersonaID,
 			&chat.CreatedAt,
 			&chat.UpdatedAt,
 		)
@@ -141,84 +183,193 @@ func (r *ChatRepository) GetChatsByUserID(userID string, limit, offset int) ([]m
 	return chats, nil
 }
 
-// UpdateChat updates a chat
+// UpdateChat updates a chat, which must belong to chat.UserID
 func (r *ChatRepository) UpdateChat(chat *models.Chat) error {
 	query := `
 		UPDATE chats
-		SET title = ?, updated_at = ?
-		WHERE id = ?
+		SET title = ?, context_strategy = ?, max_output_tokens = ?, max_cost_usd = ?, budget_usd = ?, budget_action = ?, persona_id = ?, updated_at = ?
+		WHERE id = ? AND user_id = ?
 	`
 
 	now := time.Now()
-	_, err := r.db.Exec(query, chat.Title, now, chat.ID)
+	result, err := r.db.Exec(query, chat.Title, chat.ContextStrategy, chat.MaxOutputTokens, chat.MaxCostUSD, chat.BudgetUSD, chat.BudgetAction, chat.PersonaID, now, chat.ID, chat.UserID)
 	if err != nil {
 		return fmt.Errorf("failed to update chat: %w", err)
 	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrChatNotFound
+	}
 
 	chat.UpdatedAt = now
-	return nil
+	return r.changes.record(r.db, models.ChangeEntityChat, models.ChangeOpUpdate, chat.ID, chat.UserID, chatChange(chat))
 }
 
-// DeleteChat deletes a chat and its messages
-func (r *ChatRepository) DeleteChat(id int64) error {
+// DeleteChat deletes a user's chat and its messages
+func (r *ChatRepository) DeleteChat(id int64, userID string) error {
 	tx, err := r.db.Begin()
 	if err != nil {
 		return fmt.Errorf("failed to begin transaction: %w", err)
 	}
 	defer tx.Rollback()
 
-	// Delete messages first
+	// Delete chat, then attachments, scheduled messages, attached documents
+	// and messages
+	result, err := tx.Exec("DELETE FROM chats WHERE id = ? AND user_id = ?", id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete chat: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrChatNotFound
+	}
+
+	_, err = tx.Exec("DELETE FROM attachments WHERE chat_id = ?", id)
+	if err != nil {
+		return fmt.Errorf("failed to delete attachments: %w", err)
+	}
+
+	_, err = tx.Exec("DELETE FROM scheduled_messages WHERE chat_id = ?", id)
+	if err != nil {
+		return fmt.Errorf("failed to delete scheduled messages: %w", err)
+	}
+
+	_, err = tx.Exec("DELETE FROM chat_documents WHERE chat_id = ?", id)
+	if err != nil {
+		return fmt.Errorf("failed to delete chat documents: %w", err)
+	}
+
 	_, err = tx.Exec("DELETE FROM messages WHERE chat_id = ?", id)
 	if err != nil {
 		return fmt.Errorf("failed to delete messages: %w", err)
 	}
 
-	// Delete chat
-	_, err = tx.Exec("DELETE FROM chats WHERE id = ?", id)
+	if err := r.changes.record(tx, models.ChangeEntityChat, models.ChangeOpDelete, id, userID, nil); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// CopyChat creates chat with copies of messages in one transaction,
+// keeping their original timestamps. Chat and message IDs are set on the
+// arguments; attachments are not copied.
+func (r *ChatRepository) CopyChat(chat *models.Chat, messages []models.Message) error {
+	tx, err := r.db.Begin()
 	if err != nil {
-		return fmt.Errorf("failed to delete chat: %w", err)
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	chat.ChatUUID = uuid.New().String()
+	now := time.Now()
+	result, err := tx.Exec(`
+		INSERT INTO chats (user_id, title, chat_uuid, context_strategy, max_output_tokens, max_cost_usd, budget_usd, budget_action, persona_id, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, chat.UserID, chat.Title, chat.ChatUUID, chat.ContextStrategy, chat.MaxOutputTokens, chat.MaxCostUSD, chat.BudgetUSD, chat.BudgetAction, chat.PersonaID, now, now)
+	if err != nil {
+		return fmt.Errorf("failed to create chat: %w", err)
+	}
+	if chat.ID, err = result.LastInsertId(); err != nil {
+		return fmt.Errorf("failed to get last insert id: %w", err)
+	}
+	chat.CreatedAt = now
+	chat.UpdatedAt = now
+	if err := r.changes.record(tx, models.ChangeEntityChat, models.ChangeOpInsert, chat.ID, chat.UserID, chatChange(chat)); err != nil {
+		return err
+	}
+
+	stmt, err := tx.Prepare(`
+		INSERT INTO messages (chat_id, seq, role, content, model, tokens, stopped, truncated, tool_calls, tool_call_id, moderation, context_chunks, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare message copy: %w", err)
+	}
+	defer stmt.Close()
+
+	for i := range messages {
+		m := &messages[i]
+		var toolCalls interface{}
+		if len(m.ToolCalls) > 0 {
+			toolCalls = string(m.ToolCalls)
+		}
+		moderation, err := moderationJSON(m.Moderation)
+		if err != nil {
+			return err
+		}
+		contextChunks, err := contextChunksJSON(m.ContextChunks)
+		if err != nil {
+			return err
+		}
+		result, err := stmt.Exec(chat.ID, i+1, m.Role, m.Content, m.Model, m.Tokens, m.Stopped, m.Truncated, toolCalls, m.ToolCallID, moderation, contextChunks, m.CreatedAt)
+		if err != nil {
+			return fmt.Errorf("failed to copy message: %w", err)
+		}
+		if m.ID, err = result.LastInsertId(); err != nil {
+			return fmt.Errorf("failed to get last insert id: %w", err)
+		}
+		m.ChatID = chat.ID
+		m.Seq = int64(i + 1)
+		m.Bookmarked = false
+		if err := r.changes.record(tx, models.ChangeEntityMessage, models.ChangeOpInsert, m.ID, chat.UserID, m); err != nil {
+			return err
+		}
 	}
 
 	return tx.Commit()
 }
 
-// CreateMessage creates a new message in a chat
+// CreateMessage creates a new message in a chat. The next sequence number
+// is taken inside the INSERT itself, so the read and the write happen in
+// one transaction and concurrent writers never share a position.
 func (r *ChatRepository) CreateMessage(message *models.Message) error {
 	query := `
-		INSERT INTO messages (chat_id, role, content, model, tokens, created_at)
-		VALUES (?, ?, ?, ?, ?, ?)
+		INSERT INTO messages (chat_id, seq, role, content, model, tokens, stopped, truncated, tool_calls, tool_call_id, moderation, context_chunks, created_at)
+		VALUES (?, (SELECT COALESCE(MAX(seq), 0) + 1 FROM messages WHERE chat_id = ?), ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		RETURNING id, seq
 	`
 
-	now := time.Now()
-	result, err := r.db.Exec(query, message.ChatID, message.Role, message.Content, message.Model, message.Tokens, now)
+	var toolCalls interface{}
+	if len(message.ToolCalls) > 0 {
+		toolCalls = string(message.ToolCalls)
+	}
+	moderation, err := moderationJSON(message.Moderation)
 	if err != nil {
-		return fmt.Errorf("failed to create message: %w", err)
+		return err
+	}
+	contextChunks, err := contextChunksJSON(message.ContextChunks)
+	if err != nil {
+		return err
 	}
 
-	id, err := result.LastInsertId()
+	r.seqMu.Lock()
+	defer r.seqMu.Unlock()
+
+	now := time.Now()
+	err = r.db.QueryRow(query, message.ChatID, message.ChatID, message.Role, message.Content, message.Model, message.Tokens, message.Stopped, message.Truncated, toolCalls, message.ToolCallID, moderation, contextChunks, now).
+		Scan(&message.ID, &message.Seq)
 	if err != nil {
-		return fmt.Errorf("failed to get last insert id: %w", err)
+		return fmt.Errorf("failed to create message: %w", err)
 	}
 
-	message.ID = id
 	message.CreatedAt = now
 
-	// Update chat's updated_at
-	_, err = r.db.Exec("UPDATE chats SET updated_at = ? WHERE id = ?", now, message.ChatID)
-	if err != nil {
+	// Update chat's updated_at; any cached summary no longer covers the chat
+	var userID string
+	err = r.db.QueryRow("UPDATE chats SET updated_at = ?, summary_stale = 1 WHERE id = ? RETURNING user_id", now, message.ChatID).Scan(&userID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
 		return fmt.Errorf("failed to update chat timestamp: %w", err)
 	}
 
-	return nil
+	return r.changes.record(r.db, models.ChangeEntityMessage, models.ChangeOpInsert, message.ID, userID, message)
 }
 
 // GetMessagesByChatID retrieves all messages for a chat
 func (r *ChatRepository) GetMessagesByChatID(chatID int64) ([]models.Message, error) {
 	query := `
-		SELECT id, chat_id, role, content, model, tokens, created_at
+		SELECT id, chat_id, COALESCE(seq, 0), role, content, model, tokens, stopped, truncated, bookmarked, tool_calls, tool_call_id, moderation, context_chunks, created_at
 		FROM messages
 		WHERE chat_id = ?
-		ORDER BY created_at ASC
+		ORDER BY seq ASC, id ASC
 	`
 
 	rows, err := r.db.Query(query, chatID)
@@ -229,23 +380,502 @@ func (r *ChatRepository) GetMessagesByChatID(chatID int64) ([]models.Message, er
 
 	var messages []models.Message
 	for rows.Next() {
-		var message models.Message
+		message, err := scanMessage(rows)
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, *message)
+	}
+
+	return messages, nil
+}
+
+// GetMessageByID retrieves a message by its ID
+func (r *ChatRepository) GetMessageByID(id int64) (*models.Message, error) {
+	query := `
+		SELECT id, chat_id, COALESCE(seq, 0), role, content, model, tokens, stopped, truncated, bookmarked, tool_calls, tool_call_id, moderation, context_chunks, created_at
+		FROM messages
+		WHERE id = ?
+	`
+
+	message, err := scanMessage(r.db.QueryRow(query, id))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("message not found")
+	}
+	return message, err
+}
+
+// scanMessage scans a message from a row
+func scanMessage(row interface{ Scan(...interface{}) error }) (*models.Message, error) {
+	message := &models.Message{}
+	var toolCalls, moderation, contextChunks sql.NullString
+	err := row.Scan(
+		&message.ID,
+		&message.ChatID,
+		&message.Seq,
+		&message.Role,
+		&message.Content,
+		&message.Model,
+		&message.Tokens,
+		&message.Stopped,
+		&message.Truncated,
+		&message.Bookmarked,
+		&toolCalls,
+		&message.ToolCallID,
+		&moderation,
+		&contextChunks,
+		&message.CreatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan message: %w", err)
+	}
+	if toolCalls.Valid && toolCalls.String != "" {
+		message.ToolCalls = json.RawMessage(toolCalls.String)
+	}
+	if moderation.Valid && moderation.String != "" {
+		message.Moderation = &models.ModerationResult{}
+		if err := json.Unmarshal([]byte(moderation.String), message.Moderation); err != nil {
+			return nil, fmt.Errorf("failed to decode message moderation: %w", err)
+		}
+	}
+	if contextChunks.Valid && contextChunks.String != "" {
+		if err := json.Unmarshal([]byte(contextChunks.String), &message.ContextChunks); err != nil {
+			return nil, fmt.Errorf("failed to decode message context: %w", err)
+		}
+	}
+	return message, nil
+}
+
+// moderationJSON encodes a moderation outcome for storage, or NULL when
+// the message was not moderated
+func moderationJSON(result *models.ModerationResult) (interface{}, error) {
+	if result == nil {
+		return nil, nil
+	}
+	encoded, err := json.Marshal(result)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode message moderation: %w", err)
+	}
+	return string(encoded), nil
+}
+
+// contextChunksJSON encodes the document passages a reply drew on for
+// storage, or NULL when it drew on none
+func contextChunksJSON(chunks []models.ContextChunk) (interface{}, error) {
+	if len(chunks) == 0 {
+		return nil, nil
+	}
+	encoded, err := json.Marshal(chunks)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode message context: %w", err)
+	}
+	return string(encoded), nil
+}
+
+// GetChatSummary retrieves a chat's cached summary, or nil if none was generated
+func (r *ChatRepository) GetChatSummary(chatID int64) (*models.ChatSummary, error) {
+	summary, err := scanChatSummary(r.db.QueryRow(`
+		SELECT id, summary, summary_model, summary_at, COALESCE(summary_stale, 1), summary_action_items
+		FROM chats
+		WHERE id = ?
+	`, chatID))
+	if err == sql.ErrNoRows {
+		return nil, ErrChatNotFound
+	}
+	return summary, err
+}
+
+// GetChatSummaries retrieves the cached summaries of several chats by chat
+// ID; chats without a summary are left out
+func (r *ChatRepository) GetChatSummaries(chatIDs []int64) (map[int64]*models.ChatSummary, error) {
+	summaries := make(map[int64]*models.ChatSummary)
+	if len(chatIDs) == 0 {
+		return summaries, nil
+	}
+
+	args := make([]interface{}, 0, len(chatIDs))
+	for _, id := range chatIDs {
+		args = append(args, id)
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(chatIDs)), ",")
+	rows, err := r.db.Query(`
+		SELECT id, summary, summary_model, summary_at, COALESCE(summary_stale, 1), summary_action_items
+		FROM chats
+		WHERE summary IS NOT NULL AND id IN (`+placeholders+`)
+	`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get chat summaries: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		summary, err := scanChatSummary(rows)
+		if err != nil {
+			return nil, err
+		}
+		summaries[summary.ChatID] = summary
+	}
+	return summaries, nil
+}
+
+// SaveChatSummary stores a summary covering messages up to throughSeq. It
+// stays stale if messages arrived while it was being generated.
+func (r *ChatRepository) SaveChatSummary(s *models.ChatSummary, throughSeq int64) error {
+	actionItems, err := json.Marshal(s.ActionItems)
+	if err != nil {
+		return fmt.Errorf("failed to encode action items: %w", err)
+	}
+	_, err = r.db.Exec(`
+		UPDATE chats SET
+			summary = ?,
+			summary_model = ?,
+			summary_at = ?,
+			summary_action_items = ?,
+			summary_stale = COALESCE((SELECT MAX(seq) FROM messages WHERE chat_id = ?), 0) > ?
+		WHERE id = ?
+	`, s.Summary, s.Model, s.GeneratedAt, string(actionItems), s.ChatID, throughSeq, s.ChatID)
+	if err != nil {
+		return fmt.Errorf("failed to save chat summary: %w", err)
+	}
+	return nil
+}
+
+// GetContextSummary retrieves the cached summary of the turns dropped from
+// a chat's context window and the seq of the last message it covers; the
+// summary is empty when there is none
+func (r *ChatRepository) GetContextSummary(chatID int64) (string, int64, error) {
+	var summary sql.NullString
+	var throughSeq sql.NullInt64
+	err := r.db.QueryRow("SELECT context_summary, context_summary_seq FROM chats WHERE id = ?", chatID).
+		Scan(&summary, &throughSeq)
+	if err == sql.ErrNoRows {
+		return "", 0, ErrChatNotFound
+	}
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to get context summary: %w", err)
+	}
+	return summary.String, throughSeq.Int64, nil
+}
+
+// SaveContextSummary caches the summary of a chat's messages through
+// throughSeq
+func (r *ChatRepository) SaveContextSummary(chatID int64, summary string, throughSeq int64) error {
+	_, err := r.db.Exec("UPDATE chats SET context_summary = ?, context_summary_seq = ? WHERE id = ?", summary, throughSeq, chatID)
+	if err != nil {
+		return fmt.Errorf("failed to save context summary: %w", err)
+	}
+	return nil
+}
+
+// scanChatSummary scans a chat's summary columns, returning nil when the
+// chat has no summary
+func scanChatSummary(row interface{ Scan(...interface{}) error }) (*models.ChatSummary, error) {
+	var chatID int64
+	var summary, model, actionItems sql.NullString
+	var generatedAt sql.NullTime
+	var stale bool
+	err := row.Scan(&chatID, &summary, &model, &generatedAt, &stale, &actionItems)
+	if err == sql.ErrNoRows {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get chat summary: %w", err)
+	}
+	if !summary.Valid {
+		return nil, nil
+	}
+
+	s := &models.ChatSummary{
+		ChatID:      chatID,
+		Summary:     summary.String,
+		Model:       model.String,
+		ActionItems: []string{},
+		Stale:       stale,
+		GeneratedAt: generatedAt.Time,
+	}
+	if actionItems.Valid && actionItems.String != "" {
+		if err := json.Unmarshal([]byte(actionItems.String), &s.ActionItems); err != nil {
+			return nil, fmt.Errorf("failed to decode action items: %w", err)
+		}
+	}
+	return s, nil
+}
+
+// SetChatPinned pins or unpins a chat without touching updated_at
+func (r *ChatRepository) SetChatPinned(id int64, pinned bool) error {
+	_, err := r.db.Exec("UPDATE chats SET is_pinned = ? WHERE id = ?", pinned, id)
+	if err != nil {
+		return fmt.Errorf("failed to update chat pin: %w", err)
+	}
+	return nil
+}
+
+// SetMessageBookmarked bookmarks or un-bookmarks a message
+func (r *ChatRepository) SetMessageBookmarked(id int64, bookmarked bool) error {
+	_, err := r.db.Exec("UPDATE messages SET bookmarked = ? WHERE id = ?", bookmarked, id)
+	if err != nil {
+		return fmt.Errorf("failed to update message bookmark: %w", err)
+	}
+	return nil
+}
+
+// GetBookmarksByUserID retrieves bookmarked messages across a user's chats, newest first
+func (r *ChatRepository) GetBookmarksByUserID(userID string, limit, offset int) ([]models.Bookmark, error) {
+	query := `
+		SELECT m.id, m.chat_id, COALESCE(m.seq, 0), m.role, m.content, m.model, m.tokens, m.stopped, m.truncated, m.bookmarked, m.tool_calls, m.tool_call_id, m.created_at,
+		       c.title, c.chat_uuid
+		FROM messages m
+		JOIN chats c ON c.id = m.chat_id
+		WHERE c.user_id = ? AND m.bookmarked = 1
+		ORDER BY m.created_at DESC
+		LIMIT ? OFFSET ?
+	`
+
+	rows, err := r.db.Query(query, userID, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get bookmarks: %w", err)
+	}
+	defer rows.Close()
+
+	bookmarks := make([]models.Bookmark, 0)
+	for rows.Next() {
+		var b models.Bookmark
+		var toolCalls, chatUUID sql.NullString
 		err := rows.Scan(
-			&message.ID,
-			&message.ChatID,
-			&message.Role,
-			&message.Content,
-			&message.Model,
-			&message.Tokens,
-			&message.CreatedAt,
+			&b.ID,
+			&b.ChatID,
+			&b.Seq,
+			&b.Role,
+			&b.Content,
+			&b.Model,
+			&b.Tokens,
+			&b.Stopped,
+			&b.Truncated,
+			&b.Bookmarked,
+			&toolCalls,
+			&b.ToolCallID,
+			&b.CreatedAt,
+			&b.ChatTitle,
+			&chatUUID,
 		)
 		if err != nil {
-			return nil, fmt.Errorf("failed to scan message: %w", err)
+			return nil, fmt.Errorf("failed to scan bookmark: %w", err)
+		}
+		if toolCalls.Valid && toolCalls.String != "" {
+			b.ToolCalls = json.RawMessage(toolCalls.String)
 		}
-		messages = append(messages, message)
+		b.ChatUUID = chatUUID.String
+		bookmarks = append(bookmarks, b)
 	}
 
-	return messages, nil
+	return bookmarks, nil
+}
+
+// CreateAttachment records an uploaded attachment
+func (r *ChatRepository) CreateAttachment(a *models.Attachment) error {
+	query := `
+		INSERT INTO attachments (message_id, chat_id, user_id, filename, content_type, size_bytes, storage_backend, storage_key, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+
+	now := time.Now()
+	result, err := r.db.Exec(query, a.MessageID, a.ChatID, a.UserID, a.Filename, a.ContentType, a.SizeBytes, a.StorageBackend, a.StorageKey, now)
+	if err != nil {
+		return fmt.Errorf("failed to create attachment: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to get last insert id: %w", err)
+	}
+
+	a.ID = id
+	a.CreatedAt = now
+	return nil
+}
+
+// GetAttachmentsByChatID retrieves all attachments in a chat
+func (r *ChatRepository) GetAttachmentsByChatID(chatID int64) ([]models.Attachment, error) {
+	query := `
+		SELECT id, message_id, chat_id, user_id, filename, COALESCE(content_type, ''), size_bytes, storage_backend, storage_key, created_at
+		FROM attachments
+		WHERE chat_id = ?
+		ORDER BY id ASC
+	`
+
+	rows, err := r.db.Query(query, chatID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get attachments: %w", err)
+	}
+	defer rows.Close()
+
+	var attachments []models.Attachment
+	for rows.Next() {
+		a, err := scanAttachment(rows)
+		if err != nil {
+			return nil, err
+		}
+		attachments = append(attachments, *a)
+	}
+
+	return attachments, nil
+}
+
+// GetAttachmentsByUserID retrieves all attachments a user uploaded
+func (r *ChatRepository) GetAttachmentsByUserID(userID string) ([]models.Attachment, error) {
+	query := `
+		SELECT id, message_id, chat_id, user_id, filename, COALESCE(content_type, ''), size_bytes, storage_backend, storage_key, created_at
+		FROM attachments
+		WHERE user_id = ?
+		ORDER BY id ASC
+	`
+
+	rows, err := r.db.Query(query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get attachments: %w", err)
+	}
+	defer rows.Close()
+
+	var attachments []models.Attachment
+	for rows.Next() {
+		a, err := scanAttachment(rows)
+		if err != nil {
+			return nil, err
+		}
+		attachments = append(attachments, *a)
+	}
+
+	return attachments, nil
+}
+
+// DeleteAttachmentsByUserID removes the rows of all a user's attachments
+func (r *ChatRepository) DeleteAttachmentsByUserID(userID string) (int64, error) {
+	result, err := r.db.Exec("DELETE FROM attachments WHERE user_id = ?", userID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete attachments: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// GetAttachmentByID retrieves an attachment by its ID
+func (r *ChatRepository) GetAttachmentByID(id int64) (*models.Attachment, error) {
+	query := `
+		SELECT id, message_id, chat_id, user_id, filename, COALESCE(content_type, ''), size_bytes, storage_backend, storage_key, created_at
+		FROM attachments
+		WHERE id = ?
+	`
+
+	a, err := scanAttachment(r.db.QueryRow(query, id))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("attachment not found")
+	}
+	return a, err
+}
+
+// scanAttachment scans an attachment from a row
+func scanAttachment(row interface{ Scan(...interface{}) error }) (*models.Attachment, error) {
+	a := &models.Attachment{}
+	err := row.Scan(
+		&a.ID,
+		&a.MessageID,
+		&a.ChatID,
+		&a.UserID,
+		&a.Filename,
+		&a.ContentType,
+		&a.SizeBytes,
+		&a.StorageBackend,
+		&a.StorageKey,
+		&a.CreatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan attachment: %w", err)
+	}
+	return a, nil
+}
+
+// UpsertFeedback records a user's rating of a message, replacing any
+// earlier rating by the same user
+func (r *ChatRepository) UpsertFeedback(f *models.MessageFeedback) error {
+	query := `
+		INSERT INTO message_feedback (message_id, chat_id, user_id, rating, comment, model, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(message_id, user_id) DO UPDATE SET
+			rating = excluded.rating,
+			comment = excluded.comment,
+			updated_at = excluded.updated_at
+	`
+
+	now := time.Now()
+	_, err := r.db.Exec(query, f.MessageID, f.ChatID, f.UserID, f.Rating, f.Comment, f.Model, now, now)
+	if err != nil {
+		return fmt.Errorf("failed to save feedback: %w", err)
+	}
+
+	err = r.db.QueryRow(
+		"SELECT id, created_at, updated_at FROM message_feedback WHERE message_id = ? AND user_id = ?",
+		f.MessageID, f.UserID,
+	).Scan(&f.ID, &f.CreatedAt, &f.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to get feedback: %w", err)
+	}
+	return nil
+}
+
+// DeleteFeedback removes a user's rating of a message
+func (r *ChatRepository) DeleteFeedback(messageID int64, userID string) (bool, error) {
+	result, err := r.db.Exec("DELETE FROM message_feedback WHERE message_id = ? AND user_id = ?", messageID, userID)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete feedback: %w", err)
+	}
+	n, _ := result.RowsAffected()
+	return n > 0, nil
+}
+
+// GetFeedbackStats aggregates ratings created in [since, until) per model,
+// and per day as well when byDay is set. An empty model matches all models.
+func (r *ChatRepository) GetFeedbackStats(since, until time.Time, model string, byDay bool) ([]models.FeedbackStats, error) {
+	period := "''"
+	if byDay {
+		period = "strftime('%Y-%m-%d', created_at)"
+	}
+	query := `
+		SELECT ` + period + ` AS period,
+		       COALESCE(model, '') AS model,
+		       SUM(CASE WHEN rating = 'up' THEN 1 ELSE 0 END),
+		       SUM(CASE WHEN rating = 'down' THEN 1 ELSE 0 END),
+		       COUNT(*),
+		       SUM(CASE WHEN COALESCE(comment, '') <> '' THEN 1 ELSE 0 END)
+		FROM message_feedback
+		WHERE created_at >= ? AND created_at < ? AND (? = '' OR model = ?)
+		GROUP BY period, model
+		ORDER BY period ASC, model ASC
+	`
+
+	rows, err := r.db.Query(query, since, until, model, model)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get feedback stats: %w", err)
+	}
+	defer rows.Close()
+
+	stats := make([]models.FeedbackStats, 0)
+	for rows.Next() {
+		var st models.FeedbackStats
+		if err := rows.Scan(&st.Period, &st.Model, &st.Up, &st.Down, &st.Total, &st.Comments); err != nil {
+			return nil, fmt.Errorf("failed to scan feedback stats: %w", err)
+		}
+		if st.Total > 0 {
+			st.Satisfaction = float64(st.Up) / float64(st.Total)
+		}
+		stats = append(stats, st)
+	}
+
+	return stats, rows.Err()
 }
 
 // CountChatsByUserID counts the total number of chats for a user
@@ -260,3 +890,14 @@ func (r *ChatRepository) CountChatsByUserID(userID string) (int, error) {
 
 	return count, nil
 }
+
+// CountMessagesByChatID counts the messages in a chat
+func (r *ChatRepository) CountMessagesByChatID(chatID int64) (int, error) {
+	var count int
+	err := r.db.QueryRow("SELECT COUNT(*) FROM messages WHERE chat_id = ?", chatID).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count messages: %w", err)
+	}
+
+	return count, nil
+}
@@ -0,0 +1,105 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// CleanupRepository deletes rows that have outlived their use
+type CleanupRepository struct {
+	db *sql.DB
+}
+
+// NewCleanupRepository creates a new cleanup repository
+func NewCleanupRepository(db *sql.DB) *CleanupRepository {
+	return &CleanupRepository{db: db}
+}
+
+// DeleteExpiredInvitations deletes invitation tokens past their expiry,
+// accepted or not
+func (r *CleanupRepository) DeleteExpiredInvitations(now time.Time) (int64, error) {
+	return r.delete("invitations", "DELETE FROM user_invitations WHERE expires_at < ?", now)
+}
+
+// DeleteExpiredChallenges deletes passkey ceremonies that were never
+// finished
+func (r *CleanupRepository) DeleteExpiredChallenges(now time.Time) (int64, error) {
+	return r.delete("passkey challenges", "DELETE FROM passkey_challenges WHERE expires_at < ?", now)
+}
+
+// DeleteExpiredRevokedTokens deletes revocations of tokens that have
+// expired anyway
+func (r *CleanupRepository) DeleteExpiredRevokedTokens(now time.Time) (int64, error) {
+	return r.delete("revoked tokens", "DELETE FROM revoked_tokens WHERE expires_at < ?", now)
+}
+
+// DeleteExpiredSessions deletes sessions whose tokens have expired
+func (r *CleanupRepository) DeleteExpiredSessions(now time.Time) (int64, error) {
+	return r.delete("sessions", "DELETE FROM sessions WHERE expires_at < ?", now)
+}
+
+// DeleteExpiredReservations deletes quota holds that were never settled
+// and no longer count
+func (r *CleanupRepository) DeleteExpiredReservations(now time.Time) (int64, error) {
+	return r.delete("quota reservations", "DELETE FROM quota_reservations WHERE expires_at <= ?", now)
+}
+
+// DeleteGuestQuotasBefore deletes the quotas of guest sessions started
+// before cutoff, whose session cookies have expired
+func (r *CleanupRepository) DeleteGuestQuotasBefore(guestIDPrefix string, cutoff time.Time) (int64, error) {
+	return r.delete("guest quotas", "DELETE FROM user_quotas WHERE substr(user_id, 1, ?) = ? AND created_at < ?",
+		len(guestIDPrefix), guestIDPrefix, cutoff)
+}
+
+// DeleteTrialUsageBefore deletes trial counters of UTC days before day
+func (r *CleanupRepository) DeleteTrialUsageBefore(day string) (int64, error) {
+	return r.delete("trial usage", "DELETE FROM trial_usage WHERE day < ?", day)
+}
+
+// DeleteFinishedJobsBefore deletes user import, document import and
+// restore jobs that finished, failed or were interrupted before cutoff
+func (r *CleanupRepository) DeleteFinishedJobsBefore(cutoff time.Time) (int64, error) {
+	var total int64
+	for _, table := range []string{"user_import_jobs", "document_import_jobs", "workspace_restores"} {
+		n, err := r.delete(table, "DELETE FROM "+table+
+			" WHERE status NOT IN ('queued', 'running') AND COALESCE(completed_at, created_at) < ?", cutoff)
+		if err != nil {
+			return total, err
+		}
+		total += n
+	}
+	return total, nil
+}
+
+// StorageKeys returns every storage key a row refers to: uploaded
+// document files, chat attachments and workspace snapshots
+func (r *CleanupRepository) StorageKeys() (map[string]bool, error) {
+	rows, err := r.db.Query(`
+		SELECT file_key FROM documents WHERE file_key IS NOT NULL
+		UNION SELECT storage_key FROM attachments WHERE storage_key IS NOT NULL
+		UNION SELECT storage_key FROM workspace_snapshots WHERE storage_key IS NOT NULL
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get storage keys: %w", err)
+	}
+	defer rows.Close()
+
+	keys := make(map[string]bool)
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			return nil, fmt.Errorf("failed to scan storage key: %w", err)
+		}
+		keys[key] = true
+	}
+	return keys, rows.Err()
+}
+
+func (r *CleanupRepository) delete(what, query string, args ...interface{}) (int64, error) {
+	result, err := r.db.Exec(query, args...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete %s: %w", what, err)
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,128 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"lio-ai/internal/models"
+)
+
+// CollectionRepository handles document collection database operations
+type CollectionRepository struct {
+	db *sql.DB
+}
+
+// NewCollectionRepository creates a new collection repository
+func NewCollectionRepository(db *sql.DB) *CollectionRepository {
+	return &CollectionRepository{db: db}
+}
+
+const collectionColumns = `id, user_id, name, parent_id, created_at, updated_at,
+	(SELECT COUNT(*) FROM documents WHERE documents.collection_id = collections.id)`
+
+// Create creates a new collection
+func (r *CollectionRepository) Create(c *models.Collection) error {
+	now := time.Now()
+	result, err := r.db.Exec(
+		"INSERT INTO collections (user_id, name, parent_id, created_at, updated_at) VALUES (?, ?, ?, ?, ?)",
+		c.UserID, c.Name, c.ParentID, now, now,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create collection: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to get last insert id: %w", err)
+	}
+	c.ID = id
+	c.CreatedAt = now
+	c.UpdatedAt = now
+	return nil
+}
+
+// GetByID retrieves a collection by ID, whoever owns it, or nil
+func (r *CollectionRepository) GetByID(id int64) (*models.Collection, error) {
+	c, err := scanCollection(r.db.QueryRow("SELECT "+collectionColumns+" FROM collections WHERE id = ?", id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get collection: %w", err)
+	}
+	return c, nil
+}
+
+// ListByUser retrieves a user's collections, ordered by name
+func (r *CollectionRepository) ListByUser(userID string) ([]models.Collection, error) {
+	rows, err := r.db.Query("SELECT "+collectionColumns+" FROM collections WHERE user_id = ? ORDER BY name, id", userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list collections: %w", err)
+	}
+	defer rows.Close()
+
+	collections := make([]models.Collection, 0)
+	for rows.Next() {
+		c, err := scanCollection(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan collection: %w", err)
+		}
+		collections = append(collections, *c)
+	}
+	return collections, rows.Err()
+}
+
+// CountChildren returns the number of collections nested in a collection
+func (r *CollectionRepository) CountChildren(id int64) (int, error) {
+	var n int
+	if err := r.db.QueryRow("SELECT COUNT(*) FROM collections WHERE parent_id = ?", id).Scan(&n); err != nil {
+		return 0, fmt.Errorf("failed to count collections: %w", err)
+	}
+	return n, nil
+}
+
+// Rename renames a collection owned by userID
+func (r *CollectionRepository) Rename(id int64, userID, name string) error {
+	_, err := r.db.Exec(
+		"UPDATE collections SET name = ?, updated_at = ? WHERE id = ? AND user_id = ?",
+		name, time.Now(), id, userID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to rename collection: %w", err)
+	}
+	return nil
+}
+
+// Delete removes a collection owned by userID, moving its documents into
+// its parent, or to the top level
+func (r *CollectionRepository) Delete(id int64, userID string, parentID *int64) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(
+		"UPDATE documents SET collection_id = ? WHERE collection_id = ? AND user_id = ?",
+		parentID, id, userID,
+	); err != nil {
+		return fmt.Errorf("failed to move collection documents: %w", err)
+	}
+	if _, err := tx.Exec("DELETE FROM collections WHERE id = ? AND user_id = ?", id, userID); err != nil {
+		return fmt.Errorf("failed to delete collection: %w", err)
+	}
+	return tx.Commit()
+}
+
+func scanCollection(row interface{ Scan(...interface{}) error }) (*models.Collection, error) {
+	var c models.Collection
+	var parentID sql.NullInt64
+	if err := row.Scan(&c.ID, &c.UserID, &c.Name, &parentID, &c.CreatedAt, &c.UpdatedAt, &c.DocumentCount); err != nil {
+		return nil, err
+	}
+	if parentID.Valid {
+		c.ParentID = &parentID.Int64
+	}
+	return &c, nil
+}
@@ -0,0 +1,178 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"lio-ai/internal/models"
+)
+
+// CostConfigRepository manages the per-model prices usage is charged at
+type CostConfigRepository struct {
+	db *sql.DB
+}
+
+// NewCostConfigRepository creates a new cost config repository
+func NewCostConfigRepository(db *sql.DB) *CostConfigRepository {
+	return &CostConfigRepository{db: db}
+}
+
+const costConfigColumns = `id, model_name, cost_per_input_token, cost_per_output_token, operation_type, is_active, created_at, updated_at`
+
+// List retrieves every cost config, active or not, by model name
+func (r *CostConfigRepository) List() ([]models.CostConfig, error) {
+	rows, err := r.db.Query("SELECT " + costConfigColumns + " FROM cost_config ORDER BY model_name ASC")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list cost configs: %w", err)
+	}
+	defer rows.Close()
+
+	configs := make([]models.CostConfig, 0)
+	for rows.Next() {
+		c, err := scanCostConfig(rows)
+		if err != nil {
+			return nil, err
+		}
+		configs = append(configs, *c)
+	}
+	return configs, rows.Err()
+}
+
+// Get retrieves a model's cost config, active or not
+func (r *CostConfigRepository) Get(modelName string) (*models.CostConfig, error) {
+	c, err := scanCostConfig(r.db.QueryRow("SELECT "+costConfigColumns+" FROM cost_config WHERE model_name = ?", modelName))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return c, err
+}
+
+// Create stores a new cost config, recording it in the price history as
+// coming from source
+func (r *CostConfigRepository) Create(c *models.CostConfig, source, changedBy string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	result, err := tx.Exec(`
+		INSERT INTO cost_config (model_name, cost_per_input_token, cost_per_output_token, operation_type, is_active, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, c.ModelName, c.CostPerInputToken, c.CostPerOutputToken, c.OperationType, c.IsActive, now, now)
+	if err != nil {
+		return fmt.Errorf("failed to create cost config: %w", err)
+	}
+	if err := recordCostChange(tx, c, nil, source, changedBy, now); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit cost config: %w", err)
+	}
+	c.ID, _ = result.LastInsertId()
+	c.CreatedAt, c.UpdatedAt = now, now
+	return nil
+}
+
+// Update saves a cost config's prices, operation type and whether it is
+// active, recording the change from previous in the price history as
+// coming from source
+func (r *CostConfigRepository) Update(c, previous *models.CostConfig, source, changedBy string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	_, err = tx.Exec(`
+		UPDATE cost_config
+		SET cost_per_input_token = ?, cost_per_output_token = ?, operation_type = ?, is_active = ?, updated_at = ?
+		WHERE id = ?
+	`, c.CostPerInputToken, c.CostPerOutputToken, c.OperationType, c.IsActive, now, c.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update cost config: %w", err)
+	}
+	if err := recordCostChange(tx, c, previous, source, changedBy, now); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit cost config: %w", err)
+	}
+	c.UpdatedAt = now
+	return nil
+}
+
+func recordCostChange(tx *sql.Tx, c, previous *models.CostConfig, source, changedBy string, now time.Time) error {
+	var previousInput, previousOutput interface{}
+	if previous != nil {
+		previousInput, previousOutput = previous.CostPerInputToken, previous.CostPerOutputToken
+	}
+	_, err := tx.Exec(`
+		INSERT INTO cost_config_history (model_name, cost_per_input_token, cost_per_output_token, previous_input_cost, previous_output_cost,
+			operation_type, is_active, source, changed_by, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, c.ModelName, c.CostPerInputToken, c.CostPerOutputToken, previousInput, previousOutput,
+		c.OperationType, c.IsActive, source, changedBy, now)
+	if err != nil {
+		return fmt.Errorf("failed to record cost config change: %w", err)
+	}
+	return nil
+}
+
+// ListChanges retrieves price changes, newest first, of one model or of
+// every model when modelName is empty, and how many there are in all
+func (r *CostConfigRepository) ListChanges(modelName string, limit, offset int) ([]models.CostConfigChange, int, error) {
+	where, args := "", []interface{}{}
+	if modelName != "" {
+		where, args = "WHERE model_name = ?", append(args, modelName)
+	}
+
+	var total int
+	if err := r.db.QueryRow("SELECT COUNT(*) FROM cost_config_history "+where, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count cost config changes: %w", err)
+	}
+	rows, err := r.db.Query(`
+		SELECT id, model_name, cost_per_input_token, cost_per_output_token, previous_input_cost, previous_output_cost,
+			operation_type, is_active, source, COALESCE(changed_by, ''), created_at
+		FROM cost_config_history `+where+`
+		ORDER BY created_at DESC, id DESC
+		LIMIT ? OFFSET ?
+	`, append(args, limit, offset)...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list cost config changes: %w", err)
+	}
+	defer rows.Close()
+
+	changes := make([]models.CostConfigChange, 0)
+	for rows.Next() {
+		var ch models.CostConfigChange
+		var previousInput, previousOutput sql.NullFloat64
+		if err := rows.Scan(&ch.ID, &ch.ModelName, &ch.CostPerInputToken, &ch.CostPerOutputToken, &previousInput, &previousOutput,
+			&ch.OperationType, &ch.IsActive, &ch.Source, &ch.ChangedBy, &ch.CreatedAt); err != nil {
+			return nil, 0, fmt.Errorf("failed to scan cost config change: %w", err)
+		}
+		if previousInput.Valid {
+			ch.PreviousInputCost = &previousInput.Float64
+		}
+		if previousOutput.Valid {
+			ch.PreviousOutputCost = &previousOutput.Float64
+		}
+		changes = append(changes, ch)
+	}
+	return changes, total, rows.Err()
+}
+
+func scanCostConfig(row interface{ Scan(...interface{}) error }) (*models.CostConfig, error) {
+	c := &models.CostConfig{}
+	err := row.Scan(&c.ID, &c.ModelName, &c.CostPerInputToken, &c.CostPerOutputToken, &c.OperationType, &c.IsActive, &c.CreatedAt, &c.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan cost config: %w", err)
+	}
+	return c, nil
+}
@@ -0,0 +1,65 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"lio-ai/internal/models"
+)
+
+// DocumentAccessRepository handles the access log of confidential
+// documents
+type DocumentAccessRepository struct {
+	db *sql.DB
+}
+
+// NewDocumentAccessRepository creates a new document access repository
+func NewDocumentAccessRepository(db *sql.DB) *DocumentAccessRepository {
+	return &DocumentAccessRepository{db: db}
+}
+
+// Record appends a read to a document's access log
+func (r *DocumentAccessRepository) Record(a *models.DocumentAccess) error {
+	a.AccessedAt = time.Now()
+	result, err := r.db.Exec(`
+		INSERT INTO document_access_log (document_id, user_id, endpoint, ip_address, accessed_at)
+		VALUES (?, ?, ?, NULLIF(?, ''), ?)
+	`, a.DocumentID, a.UserID, a.Endpoint, a.IPAddress, a.AccessedAt)
+	if err != nil {
+		return fmt.Errorf("failed to record document access: %w", err)
+	}
+	a.ID, err = result.LastInsertId()
+	return err
+}
+
+// ListByDocument retrieves a document's access log, newest first, with
+// the total count
+func (r *DocumentAccessRepository) ListByDocument(documentID uint, limit, offset int) ([]models.DocumentAccess, int64, error) {
+	var total int64
+	if err := r.db.QueryRow("SELECT COUNT(*) FROM document_access_log WHERE document_id = ?", documentID).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count document access: %w", err)
+	}
+
+	rows, err := r.db.Query(`
+		SELECT id, document_id, user_id, endpoint, COALESCE(ip_address, ''), accessed_at
+		FROM document_access_log
+		WHERE document_id = ?
+		ORDER BY accessed_at DESC, id DESC
+		LIMIT ? OFFSET ?
+	`, documentID, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get document access: %w", err)
+	}
+	defer rows.Close()
+
+	entries := make([]models.DocumentAccess, 0)
+	for rows.Next() {
+		var a models.DocumentAccess
+		if err := rows.Scan(&a.ID, &a.DocumentID, &a.UserID, &a.Endpoint, &a.IPAddress, &a.AccessedAt); err != nil {
+			return nil, 0, fmt.Errorf("failed to scan document access: %w", err)
+		}
+		entries = append(entries, a)
+	}
+	return entries, total, rows.Err()
+}
@@ -0,0 +1,217 @@
+package repositories
+
+import (
+	"database/sql"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"lio-ai/internal/models"
+)
+
+// DocumentChunkRepository handles the embedded document passages used by
+// semantic search, and their full-text index
+type DocumentChunkRepository struct {
+	db *sql.DB
+}
+
+// NewDocumentChunkRepository creates a new document chunk repository
+func NewDocumentChunkRepository(db *sql.DB) *DocumentChunkRepository {
+	return &DocumentChunkRepository{db: db}
+}
+
+// Replace swaps a document's chunks for chunks, in one transaction
+func (r *DocumentChunkRepository) Replace(documentID uint, chunks []models.DocumentChunk) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := deleteDocumentChunks(tx, documentID); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for i := range chunks {
+		chunk := &chunks[i]
+		result, err := tx.Exec(`
+			INSERT INTO document_chunks (document_id, user_id, seq, content, embedding, model, created_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?)
+		`, documentID, chunk.UserID, chunk.Seq, chunk.Content, encodeEmbedding(chunk.Embedding), chunk.Model, now)
+		if err != nil {
+			return fmt.Errorf("failed to create document chunk: %w", err)
+		}
+		if chunk.ID, err = result.LastInsertId(); err != nil {
+			return fmt.Errorf("failed to get last insert id: %w", err)
+		}
+		chunk.DocumentID = documentID
+		if _, err := tx.Exec("INSERT INTO document_chunks_fts (rowid, content) VALUES (?, ?)", chunk.ID, chunk.Content); err != nil {
+			return fmt.Errorf("failed to index document chunk: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit document chunks: %w", err)
+	}
+	return nil
+}
+
+// DeleteByDocument removes a document's chunks
+func (r *DocumentChunkRepository) DeleteByDocument(documentID uint) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := deleteDocumentChunks(tx, documentID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func deleteDocumentChunks(tx *sql.Tx, documentID uint) error {
+	if _, err := tx.Exec(`
+		DELETE FROM document_chunks_fts
+		WHERE rowid IN (SELECT id FROM document_chunks WHERE document_id = ?)
+	`, documentID); err != nil {
+		return fmt.Errorf("failed to delete document chunk index: %w", err)
+	}
+	if _, err := tx.Exec("DELETE FROM document_chunks WHERE document_id = ?", documentID); err != nil {
+		return fmt.Errorf("failed to delete document chunks: %w", err)
+	}
+	return nil
+}
+
+// ListVectors retrieves the embeddings of the filter user's chunks made
+// with model, only for the documents matching the filter. Content is not
+// loaded.
+func (r *DocumentChunkRepository) ListVectors(model string, filter models.DocumentFilter) ([]models.DocumentChunk, error) {
+	where, args := documentConditions(filter, "d.")
+	query := `
+		SELECT c.id, c.document_id, c.seq, c.embedding
+		FROM document_chunks c
+		JOIN documents d ON d.id = c.document_id
+		WHERE c.user_id = ? AND c.model = ? AND ` + strings.Join(where, " AND ")
+	args = append([]interface{}{filter.UserID, model}, args...)
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get document chunks: %w", err)
+	}
+	defer rows.Close()
+
+	chunks := make([]models.DocumentChunk, 0)
+	for rows.Next() {
+		var chunk models.DocumentChunk
+		var embedding []byte
+		if err := rows.Scan(&chunk.ID, &chunk.DocumentID, &chunk.Seq, &embedding); err != nil {
+			return nil, fmt.Errorf("failed to scan document chunk: %w", err)
+		}
+		chunk.Embedding = decodeEmbedding(embedding)
+		chunks = append(chunks, chunk)
+	}
+	return chunks, rows.Err()
+}
+
+// MatchText retrieves the content of userID's chunks made with model that
+// match an FTS4 query, by chunk id
+func (r *DocumentChunkRepository) MatchText(userID, model, match string, limit int) (map[int64]string, error) {
+	query := `
+		SELECT c.id, c.content
+		FROM document_chunks_fts f
+		JOIN document_chunks c ON c.id = f.rowid
+		WHERE document_chunks_fts MATCH ? AND c.user_id = ? AND c.model = ?
+		LIMIT ?
+	`
+	rows, err := r.db.Query(query, match, userID, model, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search document chunks: %w", err)
+	}
+	defer rows.Close()
+
+	matches := make(map[int64]string)
+	for rows.Next() {
+		var id int64
+		var content string
+		if err := rows.Scan(&id, &content); err != nil {
+			return nil, fmt.Errorf("failed to scan document chunk: %w", err)
+		}
+		matches[id] = content
+	}
+	return matches, rows.Err()
+}
+
+// GetContents retrieves the content of chunks, by id
+func (r *DocumentChunkRepository) GetContents(ids []int64) (map[int64]string, error) {
+	contents := make(map[int64]string, len(ids))
+	if len(ids) == 0 {
+		return contents, nil
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(ids)), ",")
+	args := make([]interface{}, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+
+	rows, err := r.db.Query("SELECT id, content FROM document_chunks WHERE id IN ("+placeholders+")", args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get document chunks: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int64
+		var content string
+		if err := rows.Scan(&id, &content); err != nil {
+			return nil, fmt.Errorf("failed to scan document chunk: %w", err)
+		}
+		contents[id] = content
+	}
+	return contents, rows.Err()
+}
+
+// UnindexedDocumentIDs retrieves userID's documents with no chunks made
+// with model
+func (r *DocumentChunkRepository) UnindexedDocumentIDs(userID, model string) ([]uint, error) {
+	query := `
+		SELECT d.id FROM documents d
+		WHERE d.user_id = ? AND d.content != ''
+			AND NOT EXISTS (SELECT 1 FROM document_chunks c WHERE c.document_id = d.id AND c.model = ?)
+		ORDER BY d.id
+	`
+	rows, err := r.db.Query(query, userID, model)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get unindexed documents: %w", err)
+	}
+	defer rows.Close()
+
+	ids := make([]uint, 0)
+	for rows.Next() {
+		var id uint
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan document id: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+func encodeEmbedding(v []float32) []byte {
+	b := make([]byte, 4*len(v))
+	for i, f := range v {
+		binary.LittleEndian.PutUint32(b[4*i:], math.Float32bits(f))
+	}
+	return b
+}
+
+func decodeEmbedding(b []byte) []float32 {
+	v := make([]float32, len(b)/4)
+	for i := range v {
+		v[i] = math.Float32frombits(binary.LittleEndian.Uint32(b[4*i:]))
+	}
+	return v
+}
@@ -0,0 +1,112 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"lio-ai/internal/models"
+)
+
+// DocumentCommentRepository handles comments anchored to documents' content
+type DocumentCommentRepository struct {
+	db *sql.DB
+}
+
+// NewDocumentCommentRepository creates a new document comment repository
+func NewDocumentCommentRepository(db *sql.DB) *DocumentCommentRepository {
+	return &DocumentCommentRepository{db: db}
+}
+
+const documentCommentColumns = "id, document_id, user_id, body, start_offset, end_offset, quote, resolved, created_at, updated_at"
+
+// Create stores a new comment
+func (r *DocumentCommentRepository) Create(comment *models.DocumentComment) error {
+	now := time.Now()
+	result, err := r.db.Exec(`
+		INSERT INTO document_comments (document_id, user_id, body, start_offset, end_offset, quote, resolved, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, 0, ?, ?)
+	`, comment.DocumentID, comment.UserID, comment.Body, comment.StartOffset, comment.EndOffset, comment.Quote, now, now)
+	if err != nil {
+		return fmt.Errorf("failed to create document comment: %w", err)
+	}
+	if comment.ID, err = result.LastInsertId(); err != nil {
+		return fmt.Errorf("failed to get last insert id: %w", err)
+	}
+	comment.CreatedAt, comment.UpdatedAt = now, now
+	return nil
+}
+
+// ListByDocument retrieves a document's comments in the order of their
+// ranges
+func (r *DocumentCommentRepository) ListByDocument(documentID uint) ([]models.DocumentComment, error) {
+	rows, err := r.db.Query("SELECT "+documentCommentColumns+` FROM document_comments
+		WHERE document_id = ? ORDER BY start_offset, end_offset, id`, documentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get document comments: %w", err)
+	}
+	defer rows.Close()
+
+	comments := make([]models.DocumentComment, 0)
+	for rows.Next() {
+		comment, err := scanDocumentComment(rows)
+		if err != nil {
+			return nil, err
+		}
+		comments = append(comments, *comment)
+	}
+	return comments, rows.Err()
+}
+
+// Get retrieves one of a document's comments, or nil when there is none
+func (r *DocumentCommentRepository) Get(id int64, documentID uint) (*models.DocumentComment, error) {
+	row := r.db.QueryRow("SELECT "+documentCommentColumns+" FROM document_comments WHERE id = ? AND document_id = ?", id, documentID)
+	comment, err := scanDocumentComment(row)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return comment, err
+}
+
+// Update replaces a comment's body and resolved flag
+func (r *DocumentCommentRepository) Update(comment *models.DocumentComment) error {
+	now := time.Now()
+	if _, err := r.db.Exec(`
+		UPDATE document_comments SET body = ?, resolved = ?, updated_at = ?
+		WHERE id = ? AND document_id = ?
+	`, comment.Body, comment.Resolved, now, comment.ID, comment.DocumentID); err != nil {
+		return fmt.Errorf("failed to update document comment: %w", err)
+	}
+	comment.UpdatedAt = now
+	return nil
+}
+
+// Delete removes one of a document's comments, reporting whether it existed
+func (r *DocumentCommentRepository) Delete(id int64, documentID uint) (bool, error) {
+	result, err := r.db.Exec("DELETE FROM document_comments WHERE id = ? AND document_id = ?", id, documentID)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete document comment: %w", err)
+	}
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
+
+// DeleteByDocument removes all of a document's comments
+func (r *DocumentCommentRepository) DeleteByDocument(documentID uint) error {
+	if _, err := r.db.Exec("DELETE FROM document_comments WHERE document_id = ?", documentID); err != nil {
+		return fmt.Errorf("failed to delete document comments: %w", err)
+	}
+	return nil
+}
+
+func scanDocumentComment(row interface{ Scan(...interface{}) error }) (*models.DocumentComment, error) {
+	var comment models.DocumentComment
+	if err := row.Scan(&comment.ID, &comment.DocumentID, &comment.UserID, &comment.Body, &comment.StartOffset,
+		&comment.EndOffset, &comment.Quote, &comment.Resolved, &comment.CreatedAt, &comment.UpdatedAt); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to scan document comment: %w", err)
+	}
+	return &comment, nil
+}
@@ -0,0 +1,105 @@
+package repositories
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"lio-ai/internal/models"
+)
+
+// DocumentImportRepository stores bulk document import jobs
+type DocumentImportRepository struct {
+	db *sql.DB
+}
+
+// NewDocumentImportRepository creates a new document import repository
+func NewDocumentImportRepository(db *sql.DB) *DocumentImportRepository {
+	return &DocumentImportRepository{db: db}
+}
+
+// Create stores a queued import job
+func (r *DocumentImportRepository) Create(job *models.DocumentImportJob) error {
+	now := time.Now()
+	job.Status = models.ImportStatusQueued
+	result, err := r.db.Exec(`
+		INSERT INTO document_import_jobs (user_id, source, status, total_entries, results, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, job.UserID, job.Source, job.Status, job.TotalEntries, "[]", now)
+	if err != nil {
+		return fmt.Errorf("failed to create import job: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to get last insert id: %w", err)
+	}
+	job.ID = id
+	job.CreatedAt = now
+	if job.Results == nil {
+		job.Results = []models.DocumentImportResult{}
+	}
+	return nil
+}
+
+// UpdateProgress saves a job's status, counters and entry results
+func (r *DocumentImportRepository) UpdateProgress(job *models.DocumentImportJob) error {
+	resultsJSON, err := json.Marshal(job.Results)
+	if err != nil {
+		return fmt.Errorf("failed to encode import results: %w", err)
+	}
+	_, err = r.db.Exec(`
+		UPDATE document_import_jobs
+		SET status = ?, processed = ?, created = ?, failed = ?, results = ?, completed_at = ?
+		WHERE id = ?
+	`, job.Status, job.Processed, job.Created, job.Failed, string(resultsJSON), job.CompletedAt, job.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update import job: %w", err)
+	}
+	return nil
+}
+
+// GetByID retrieves an import job, or nil if it does not exist
+func (r *DocumentImportRepository) GetByID(id int64) (*models.DocumentImportJob, error) {
+	job := &models.DocumentImportJob{}
+	var resultsJSON sql.NullString
+	var completedAt sql.NullTime
+	err := r.db.QueryRow(`
+		SELECT id, user_id, source, status, total_entries, processed, created, failed, results, created_at, completed_at
+		FROM document_import_jobs
+		WHERE id = ?
+	`, id).Scan(
+		&job.ID, &job.UserID, &job.Source, &job.Status, &job.TotalEntries, &job.Processed,
+		&job.Created, &job.Failed, &resultsJSON, &job.CreatedAt, &completedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get import job: %w", err)
+	}
+
+	job.Results = []models.DocumentImportResult{}
+	if resultsJSON.Valid && resultsJSON.String != "" {
+		if err := json.Unmarshal([]byte(resultsJSON.String), &job.Results); err != nil {
+			return nil, fmt.Errorf("failed to decode import results: %w", err)
+		}
+	}
+	if completedAt.Valid {
+		job.CompletedAt = &completedAt.Time
+	}
+	return job, nil
+}
+
+// FailInterrupted completes jobs left running by a previous process
+func (r *DocumentImportRepository) FailInterrupted() error {
+	_, err := r.db.Exec(`
+		UPDATE document_import_jobs SET status = ?, completed_at = ?
+		WHERE status IN (?, ?)
+	`, models.ImportStatusInterrupted, time.Now(), models.ImportStatusQueued, models.ImportStatusRunning)
+	if err != nil {
+		return fmt.Errorf("failed to update interrupted import jobs: %w", err)
+	}
+	return nil
+}
@@ -2,12 +2,18 @@ package repositories
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"lio-ai/internal/models"
 )
 
+// ErrVersionConflict is returned when a document update is based on a
+// version other than the document's current one
+var ErrVersionConflict = errors.New("document has changed since the version given")
+
 // DocumentRepository handles document database operations
 type DocumentRepository struct {
 	db *sql.DB
@@ -18,10 +24,26 @@ func NewDocumentRepository(db *sql.DB) *DocumentRepository {
 	return &DocumentRepository{db: db}
 }
 
+const documentColumns = `id, COALESCE(user_id, ''), title, content, collection_id,
+	file_name, file_content_type, file_size, file_backend, file_key, COALESCE(confidential, 0), COALESCE(is_favorite, 0), COALESCE(version, 1),
+	COALESCE(source_url, ''), COALESCE(language, ''), COALESCE(content_type, ''), COALESCE(code_language, ''),
+	COALESCE(word_count, 0), created_at, updated_at`
+
 // Create creates a new document
 func (r *DocumentRepository) Create(doc *models.Document) error {
-	query := `INSERT INTO documents (title, content, created_at, updated_at) VALUES (?, ?, ?, ?)`
-	result, err := r.db.Exec(query, doc.Title, doc.Content, time.Now(), time.Now())
+	var file models.DocumentFile
+	if doc.File != nil {
+		file = *doc.File
+	}
+	query := `INSERT INTO documents (user_id, title, content, collection_id,
+		file_name, file_content_type, file_size, file_backend, file_key, confidential,
+		source_url, language, content_type, code_language, word_count, created_at, updated_at)
+		VALUES (?, ?, ?, ?, NULLIF(?, ''), NULLIF(?, ''), NULLIF(?, 0), NULLIF(?, ''), NULLIF(?, ''), ?,
+		NULLIF(?, ''), NULLIF(?, ''), NULLIF(?, ''), NULLIF(?, ''), ?, ?, ?)`
+	now := time.Now()
+	result, err := r.db.Exec(query, doc.UserID, doc.Title, doc.Content, doc.CollectionID,
+		file.Name, file.ContentType, file.Size, file.Backend, file.Key, doc.Confidential,
+		doc.SourceURL, doc.Language, doc.ContentType, doc.CodeLanguage, doc.WordCount, now, now)
 	if err != nil {
 		return fmt.Errorf("failed to create document: %w", err)
 	}
@@ -32,38 +54,104 @@ func (r *DocumentRepository) Create(doc *models.Document) error {
 	}
 
 	doc.ID = uint(id)
-	return nil
+	doc.Version = 1
+	doc.CreatedAt, doc.UpdatedAt = now, now
+	return r.SetTags(doc.ID, doc.Tags)
 }
 
-// GetByID retrieves a document by ID
+// GetByID retrieves a document by ID, whoever owns it
 func (r *DocumentRepository) GetByID(id uint) (*models.Document, error) {
-	query := `SELECT id, title, content, created_at, updated_at FROM documents WHERE id = ?`
-	row := r.db.QueryRow(query, id)
-
-	var doc models.Document
-	err := row.Scan(&doc.ID, &doc.Title, &doc.Content, &doc.CreatedAt, &doc.UpdatedAt)
+	query := `SELECT ` + documentColumns + ` FROM documents WHERE id = ?`
+	doc, err := scanDocument(r.db.QueryRow(query, id))
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
 		}
 		return nil, fmt.Errorf("failed to get document: %w", err)
 	}
-	return &doc, nil
+	if err := r.loadTags([]*models.Document{doc}); err != nil {
+		return nil, err
+	}
+	return doc, nil
 }
 
-// GetAll retrieves all documents with pagination
-func (r *DocumentRepository) GetAll(skip, limit int) ([]*models.Document, int64, error) {
+// documentConditions builds the SQL conditions selecting the documents
+// that match the filter, with column names prefixed by prefix (e.g. "d.")
+func documentConditions(filter models.DocumentFilter, prefix string) ([]string, []interface{}) {
+	where := []string{prefix + "user_id = ?"}
+	args := []interface{}{filter.UserID}
+	if len(filter.Tags) > 0 {
+		// Documents carrying every one of the tags
+		where = append(where, prefix+`id IN (
+			SELECT document_id FROM document_tags WHERE tag IN (?`+strings.Repeat(", ?", len(filter.Tags)-1)+`)
+			GROUP BY document_id HAVING COUNT(*) = ?
+		)`)
+		for _, tag := range filter.Tags {
+			args = append(args, tag)
+		}
+		args = append(args, len(filter.Tags))
+	}
+	if filter.CollectionID != nil {
+		if *filter.CollectionID == 0 {
+			where = append(where, prefix+"collection_id IS NULL")
+		} else {
+			where = append(where, prefix+"collection_id = ?")
+			args = append(args, *filter.CollectionID)
+		}
+	}
+	if filter.Language != "" {
+		// "en" also matches "en-us" and "en-gb"
+		where = append(where, "("+prefix+"language = ? OR "+prefix+"language LIKE ?)")
+		args = append(args, filter.Language, filter.Language+"-%")
+	}
+	if filter.ContentType != "" {
+		where = append(where, prefix+"content_type = ?")
+		args = append(args, filter.ContentType)
+	}
+	if filter.CodeLanguage != "" {
+		where = append(where, prefix+"code_language = ?")
+		args = append(args, filter.CodeLanguage)
+	}
+	if filter.SourceURL != "" {
+		where = append(where, "substr("+prefix+"source_url, 1, ?) = ?")
+		args = append(args, len(filter.SourceURL), filter.SourceURL)
+	}
+	if filter.Favorites {
+		where = append(where, prefix+"is_favorite = 1")
+	}
+	if filter.MinWords > 0 {
+		where = append(where, prefix+"word_count >= ?")
+		args = append(args, filter.MinWords)
+	}
+	if filter.MaxWords > 0 {
+		where = append(where, prefix+"word_count <= ?")
+		args = append(args, filter.MaxWords)
+	}
+	if len(filter.IDs) > 0 {
+		where = append(where, prefix+"id IN (?"+strings.Repeat(", ?", len(filter.IDs)-1)+")")
+		for _, id := range filter.IDs {
+			args = append(args, id)
+		}
+	}
+	return where, args
+}
+
+// GetAll retrieves the documents matching the filter with pagination
+func (r *DocumentRepository) GetAll(filter models.DocumentFilter) ([]*models.Document, int64, error) {
+	where, args := documentConditions(filter, "")
+	clause := "WHERE " + strings.Join(where, " AND ")
+
 	// Get total count
-	countQuery := `SELECT COUNT(*) FROM documents`
+	countQuery := `SELECT COUNT(*) FROM documents ` + clause
 	var total int64
-	err := r.db.QueryRow(countQuery).Scan(&total)
+	err := r.db.QueryRow(countQuery, args...).Scan(&total)
 	if err != nil {
 		return nil, 0, fmt.Errorf("failed to count documents: %w", err)
 	}
 
 	// Get paginated results
-	query := `SELECT id, title, content, created_at, updated_at FROM documents LIMIT ? OFFSET ?`
-	rows, err := r.db.Query(query, limit, skip)
+	query := `SELECT ` + documentColumns + ` FROM documents ` + clause + ` ORDER BY id LIMIT ? OFFSET ?`
+	rows, err := r.db.Query(query, append(args, filter.Limit, filter.Skip)...)
 	if err != nil {
 		return nil, 0, fmt.Errorf("failed to get documents: %w", err)
 	}
@@ -71,51 +159,109 @@ func (r *DocumentRepository) GetAll(skip, limit int) ([]*models.Document, int64,
 
 	var docs []*models.Document
 	for rows.Next() {
-		var doc models.Document
-		if err := rows.Scan(&doc.ID, &doc.Title, &doc.Content, &doc.CreatedAt, &doc.UpdatedAt); err != nil {
+		doc, err := scanDocument(rows)
+		if err != nil {
 			return nil, 0, fmt.Errorf("failed to scan document: %w", err)
 		}
-		docs = append(docs, &doc)
+		docs = append(docs, doc)
 	}
 
 	if err = rows.Err(); err != nil {
 		return nil, 0, fmt.Errorf("rows error: %w", err)
 	}
+	rows.Close()
 
+	if err := r.loadTags(docs); err != nil {
+		return nil, 0, err
+	}
 	return docs, total, nil
 }
 
-// Update updates an existing document
-func (r *DocumentRepository) Update(id uint, updates *models.Document) (*models.Document, error) {
+// Update updates an existing document owned by userID and counts the
+// update in its version, returning nil when the user has no such document.
+// Unless expectedVersion is 0, the update is made only if the document is
+// still at that version; otherwise it fails with ErrVersionConflict.
+func (r *DocumentRepository) Update(id uint, userID string, updates *models.Document, expectedVersion int) (*models.Document, error) {
 	doc, err := r.GetByID(id)
 	if err != nil {
 		return nil, err
 	}
-	if doc == nil {
+	if doc == nil || doc.UserID != userID {
 		return nil, nil
 	}
+	if expectedVersion != 0 && doc.Version != expectedVersion {
+		return nil, fmt.Errorf("%w: document %d is at version %d", ErrVersionConflict, id, doc.Version)
+	}
 
 	if updates.Title != "" {
 		doc.Title = updates.Title
 	}
 	if updates.Content != "" {
 		doc.Content = updates.Content
+		doc.WordCount = updates.WordCount
 	}
 	doc.UpdatedAt = time.Now()
 
-	query := `UPDATE documents SET title = ?, content = ?, updated_at = ? WHERE id = ?`
-	_, err = r.db.Exec(query, doc.Title, doc.Content, doc.UpdatedAt, id)
+	// Checked again on write, so an update landing between the read and
+	// this write conflicts rather than being lost
+	query := `UPDATE documents SET title = ?, content = ?, word_count = ?, updated_at = ?, version = COALESCE(version, 1) + 1
+		WHERE id = ? AND user_id = ? AND (? = 0 OR COALESCE(version, 1) = ?)`
+	result, err := r.db.Exec(query, doc.Title, doc.Content, doc.WordCount, doc.UpdatedAt, id, userID, expectedVersion, expectedVersion)
 	if err != nil {
 		return nil, fmt.Errorf("failed to update document: %w", err)
 	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		if expectedVersion == 0 {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("%w: document %d was updated concurrently", ErrVersionConflict, id)
+	}
+	doc.Version++
+
+	if updates.Tags != nil {
+		if err := r.SetTags(id, updates.Tags); err != nil {
+			return nil, err
+		}
+		doc.Tags = updates.Tags
+	}
 
 	return doc, nil
 }
 
-// Delete deletes a document
-func (r *DocumentRepository) Delete(id uint) error {
-	query := `DELETE FROM documents WHERE id = ?`
-	result, err := r.db.Exec(query, id)
+// SetConfidential marks a document owned by userID confidential, or not
+func (r *DocumentRepository) SetConfidential(id uint, userID string, confidential bool) error {
+	if _, err := r.db.Exec(`UPDATE documents SET confidential = ? WHERE id = ? AND user_id = ?`, confidential, id, userID); err != nil {
+		return fmt.Errorf("failed to update document: %w", err)
+	}
+	return nil
+}
+
+// SetFavorite marks a document owned by userID as a favorite, or not
+func (r *DocumentRepository) SetFavorite(id uint, userID string, favorite bool) error {
+	if _, err := r.db.Exec(`UPDATE documents SET is_favorite = ? WHERE id = ? AND user_id = ?`, favorite, id, userID); err != nil {
+		return fmt.Errorf("failed to update document: %w", err)
+	}
+	return nil
+}
+
+// SetMetadata replaces the source URL, language, content type and code
+// language of a document owned by userID; empty values clear them
+func (r *DocumentRepository) SetMetadata(id uint, userID string, sourceURL, language, contentType, codeLanguage string) error {
+	if _, err := r.db.Exec(
+		`UPDATE documents SET source_url = NULLIF(?, ''), language = NULLIF(?, ''), content_type = NULLIF(?, ''),
+		code_language = NULLIF(?, ''), updated_at = ?
+		WHERE id = ? AND user_id = ?`,
+		sourceURL, language, contentType, codeLanguage, time.Now(), id, userID,
+	); err != nil {
+		return fmt.Errorf("failed to update document: %w", err)
+	}
+	return nil
+}
+
+// Delete deletes a document owned by userID
+func (r *DocumentRepository) Delete(id uint, userID string) error {
+	query := `DELETE FROM documents WHERE id = ? AND user_id = ?`
+	result, err := r.db.Exec(query, id, userID)
 	if err != nil {
 		return fmt.Errorf("failed to delete document: %w", err)
 	}
@@ -129,5 +275,98 @@ func (r *DocumentRepository) Delete(id uint) error {
 		return fmt.Errorf("document not found")
 	}
 
+	if _, err := r.db.Exec(`DELETE FROM document_tags WHERE document_id = ?`, id); err != nil {
+		return fmt.Errorf("failed to delete document tags: %w", err)
+	}
+	return nil
+}
+
+// SetCollection moves a document owned by userID into a collection, or out
+// of any with nil, reporting whether the document exists
+func (r *DocumentRepository) SetCollection(id uint, userID string, collectionID *int64) (bool, error) {
+	result, err := r.db.Exec(
+		`UPDATE documents SET collection_id = ?, updated_at = ? WHERE id = ? AND user_id = ?`,
+		collectionID, time.Now(), id, userID,
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to move document: %w", err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to move document: %w", err)
+	}
+	return n > 0, nil
+}
+
+// SetTags replaces the tags of a document
+func (r *DocumentRepository) SetTags(id uint, tags []string) error {
+	if _, err := r.db.Exec(`DELETE FROM document_tags WHERE document_id = ?`, id); err != nil {
+		return fmt.Errorf("failed to clear document tags: %w", err)
+	}
+	for _, tag := range tags {
+		if _, err := r.db.Exec(`INSERT OR IGNORE INTO document_tags (document_id, tag) VALUES (?, ?)`, id, tag); err != nil {
+			return fmt.Errorf("failed to tag document: %w", err)
+		}
+	}
 	return nil
 }
+
+// loadTags fills in the tags of documents, sorted by name
+func (r *DocumentRepository) loadTags(docs []*models.Document) error {
+	if len(docs) == 0 {
+		return nil
+	}
+	byID := make(map[uint]*models.Document, len(docs))
+	args := make([]interface{}, 0, len(docs))
+	for _, doc := range docs {
+		doc.Tags = []string{}
+		byID[doc.ID] = doc
+		args = append(args, doc.ID)
+	}
+
+	rows, err := r.db.Query(`
+		SELECT document_id, tag FROM document_tags
+		WHERE document_id IN (?`+strings.Repeat(", ?", len(docs)-1)+`)
+		ORDER BY tag
+	`, args...)
+	if err != nil {
+		return fmt.Errorf("failed to get document tags: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id uint
+		var tag string
+		if err := rows.Scan(&id, &tag); err != nil {
+			return fmt.Errorf("failed to scan document tag: %w", err)
+		}
+		if doc := byID[id]; doc != nil {
+			doc.Tags = append(doc.Tags, tag)
+		}
+	}
+	return rows.Err()
+}
+
+func scanDocument(row interface{ Scan(...interface{}) error }) (*models.Document, error) {
+	var doc models.Document
+	var collectionID, fileSize sql.NullInt64
+	var fileName, fileType, fileBackend, fileKey sql.NullString
+	if err := row.Scan(&doc.ID, &doc.UserID, &doc.Title, &doc.Content, &collectionID,
+		&fileName, &fileType, &fileSize, &fileBackend, &fileKey, &doc.Confidential, &doc.IsFavorite, &doc.Version,
+		&doc.SourceURL, &doc.Language, &doc.ContentType, &doc.CodeLanguage, &doc.WordCount, &doc.CreatedAt, &doc.UpdatedAt); err != nil {
+		return nil, err
+	}
+	if collectionID.Valid {
+		doc.CollectionID = &collectionID.Int64
+	}
+	if fileKey.Valid {
+		doc.File = &models.DocumentFile{
+			Name:        fileName.String,
+			ContentType: fileType.String,
+			Size:        fileSize.Int64,
+			Backend:     fileBackend.String,
+			Key:         fileKey.String,
+		}
+	}
+	return &doc, nil
+}
@@ -0,0 +1,118 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"lio-ai/internal/models"
+)
+
+// DocumentVersionRepository handles the stored revisions of documents
+type DocumentVersionRepository struct {
+	db *sql.DB
+}
+
+// NewDocumentVersionRepository creates a new document version repository
+func NewDocumentVersionRepository(db *sql.DB) *DocumentVersionRepository {
+	return &DocumentVersionRepository{db: db}
+}
+
+// Record stores doc's current title and content as its next version and
+// drops its oldest versions past keep; 0 keeps them all
+func (r *DocumentVersionRepository) Record(doc *models.Document, keep int) (*models.DocumentVersion, error) {
+	v := &models.DocumentVersion{
+		DocumentID:  doc.ID,
+		Title:       doc.Title,
+		Content:     doc.Content,
+		ContentType: doc.ContentType,
+		WordCount:   doc.WordCount,
+		CreatedAt:   time.Now(),
+	}
+	if err := r.db.QueryRow(
+		`SELECT COALESCE(MAX(version), 0) + 1 FROM document_versions WHERE document_id = ?`, doc.ID,
+	).Scan(&v.Version); err != nil {
+		return nil, fmt.Errorf("failed to number document version: %w", err)
+	}
+
+	result, err := r.db.Exec(`
+		INSERT INTO document_versions (document_id, version, title, content, content_type, word_count, created_at)
+		VALUES (?, ?, ?, ?, NULLIF(?, ''), ?, ?)
+	`, v.DocumentID, v.Version, v.Title, v.Content, v.ContentType, v.WordCount, v.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record document version: %w", err)
+	}
+	if v.ID, err = result.LastInsertId(); err != nil {
+		return nil, err
+	}
+
+	if keep > 0 && v.Version > keep {
+		if _, err := r.db.Exec(
+			`DELETE FROM document_versions WHERE document_id = ? AND version <= ?`, doc.ID, v.Version-keep,
+		); err != nil {
+			return nil, fmt.Errorf("failed to prune document versions: %w", err)
+		}
+	}
+	return v, nil
+}
+
+// List retrieves a document's versions without their content, newest
+// first
+func (r *DocumentVersionRepository) List(documentID uint) ([]models.DocumentVersion, error) {
+	rows, err := r.db.Query(`
+		SELECT id, document_id, version, title, COALESCE(content_type, ''), COALESCE(word_count, 0), created_at
+		FROM document_versions
+		WHERE document_id = ?
+		ORDER BY version DESC
+	`, documentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get document versions: %w", err)
+	}
+	defer rows.Close()
+
+	versions := make([]models.DocumentVersion, 0)
+	for rows.Next() {
+		var v models.DocumentVersion
+		if err := rows.Scan(&v.ID, &v.DocumentID, &v.Version, &v.Title, &v.ContentType, &v.WordCount, &v.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan document version: %w", err)
+		}
+		versions = append(versions, v)
+	}
+	return versions, rows.Err()
+}
+
+// Get retrieves one version of a document with its content, or nil
+func (r *DocumentVersionRepository) Get(documentID uint, version int) (*models.DocumentVersion, error) {
+	var v models.DocumentVersion
+	err := r.db.QueryRow(`
+		SELECT id, document_id, version, title, content, COALESCE(content_type, ''), COALESCE(word_count, 0), created_at
+		FROM document_versions
+		WHERE document_id = ? AND version = ?
+	`, documentID, version).Scan(&v.ID, &v.DocumentID, &v.Version, &v.Title, &v.Content, &v.ContentType, &v.WordCount, &v.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get document version: %w", err)
+	}
+	return &v, nil
+}
+
+// Latest is the number of a document's newest version, or 0
+func (r *DocumentVersionRepository) Latest(documentID uint) (int, error) {
+	var version int
+	if err := r.db.QueryRow(
+		`SELECT COALESCE(MAX(version), 0) FROM document_versions WHERE document_id = ?`, documentID,
+	).Scan(&version); err != nil {
+		return 0, fmt.Errorf("failed to get document versions: %w", err)
+	}
+	return version, nil
+}
+
+// DeleteByDocument drops a deleted document's versions
+func (r *DocumentVersionRepository) DeleteByDocument(documentID uint) error {
+	if _, err := r.db.Exec(`DELETE FROM document_versions WHERE document_id = ?`, documentID); err != nil {
+		return fmt.Errorf("failed to delete document versions: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"lio-ai/internal/models"
+)
+
+// ExemptionRepository stores the rate limit and usage tracking exemptions
+type ExemptionRepository struct {
+	db *sql.DB
+}
+
+// NewExemptionRepository creates a new exemption repository
+func NewExemptionRepository(db *sql.DB) *ExemptionRepository {
+	return &ExemptionRepository{db: db}
+}
+
+// Create stores an exemption, token exemptions under the hash of their token
+func (r *ExemptionRepository) Create(e *models.RateLimitExemption) error {
+	now := time.Now()
+	result, err := r.db.Exec(`
+		INSERT INTO rate_limit_exemptions (name, kind, cidr, token_hash, token_prefix, created_by, created_at)
+		VALUES (?, ?, NULLIF(?, ''), NULLIF(?, ''), NULLIF(?, ''), ?, ?)
+	`, e.Name, e.Kind, e.CIDR, e.TokenHash, e.TokenPrefix, e.CreatedBy, now)
+	if err != nil {
+		return fmt.Errorf("failed to create exemption: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to get last insert id: %w", err)
+	}
+	e.ID = id
+	e.CreatedAt = now
+	return nil
+}
+
+// List retrieves every exemption, oldest first
+func (r *ExemptionRepository) List() ([]models.RateLimitExemption, error) {
+	rows, err := r.db.Query(`
+		SELECT id, name, kind, COALESCE(cidr, ''), COALESCE(token_hash, ''), COALESCE(token_prefix, ''), created_by, created_at
+		FROM rate_limit_exemptions ORDER BY id
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list exemptions: %w", err)
+	}
+	defer rows.Close()
+
+	exemptions := make([]models.RateLimitExemption, 0)
+	for rows.Next() {
+		var e models.RateLimitExemption
+		if err := rows.Scan(&e.ID, &e.Name, &e.Kind, &e.CIDR, &e.TokenHash, &e.TokenPrefix, &e.CreatedBy, &e.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan exemption: %w", err)
+		}
+		exemptions = append(exemptions, e)
+	}
+	return exemptions, rows.Err()
+}
+
+// Delete removes an exemption, reporting whether it existed
+func (r *ExemptionRepository) Delete(id int64) (bool, error) {
+	result, err := r.db.Exec("DELETE FROM rate_limit_exemptions WHERE id = ?", id)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete exemption: %w", err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to delete exemption: %w", err)
+	}
+	return n > 0, nil
+}
@@ -0,0 +1,102 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"lio-ai/internal/models"
+)
+
+// GatewayConfigRepository reads and writes the database-backed parts of the
+// gateway configuration
+type GatewayConfigRepository struct {
+	db *sql.DB
+}
+
+// NewGatewayConfigRepository creates a new gateway config repository
+func NewGatewayConfigRepository(db *sql.DB) *GatewayConfigRepository {
+	return &GatewayConfigRepository{db: db}
+}
+
+// ListCostConfigs returns every cost config, active or not, by model name
+func (r *GatewayConfigRepository) ListCostConfigs() ([]models.CostConfigSetting, error) {
+	rows, err := r.db.Query(`
+		SELECT model_name, cost_per_input_token, cost_per_output_token, operation_type, is_active
+		FROM cost_config
+		ORDER BY model_name ASC
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list cost configs: %w", err)
+	}
+	defer rows.Close()
+
+	configs := make([]models.CostConfigSetting, 0)
+	for rows.Next() {
+		var c models.CostConfigSetting
+		if err := rows.Scan(&c.ModelName, &c.CostPerInputToken, &c.CostPerOutputToken, &c.OperationType, &c.IsActive); err != nil {
+			return nil, fmt.Errorf("failed to scan cost config: %w", err)
+		}
+		configs = append(configs, c)
+	}
+	return configs, rows.Err()
+}
+
+// Apply writes cost configs and aliases in one transaction. Cost configs
+// named in deactivate are kept for historical usage but marked inactive;
+// aliases named in deleteAliases are removed.
+func (r *GatewayConfigRepository) Apply(costs []models.CostConfigSetting, deactivate []string, aliases []models.ModelAliasSetting, deleteAliases []string, updatedBy string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	for _, c := range costs {
+		_, err := tx.Exec(`
+			INSERT INTO cost_config (model_name, cost_per_input_token, cost_per_output_token, operation_type, is_active, created_at, updated_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?)
+			ON CONFLICT(model_name) DO UPDATE SET
+				cost_per_input_token = excluded.cost_per_input_token,
+				cost_per_output_token = excluded.cost_per_output_token,
+				operation_type = excluded.operation_type,
+				is_active = excluded.is_active,
+				updated_at = excluded.updated_at
+		`, c.ModelName, c.CostPerInputToken, c.CostPerOutputToken, c.OperationType, c.IsActive, now, now)
+		if err != nil {
+			return fmt.Errorf("failed to save cost config %s: %w", c.ModelName, err)
+		}
+	}
+	for _, name := range deactivate {
+		if _, err := tx.Exec("UPDATE cost_config SET is_active = 0, updated_at = ? WHERE model_name = ?", now, name); err != nil {
+			return fmt.Errorf("failed to deactivate cost config %s: %w", name, err)
+		}
+	}
+
+	// Remove aliases first so a model freed by a deleted alias can be reused
+	for _, alias := range deleteAliases {
+		if _, err := tx.Exec("DELETE FROM model_aliases WHERE alias = ?", alias); err != nil {
+			return fmt.Errorf("failed to delete model alias %s: %w", alias, err)
+		}
+	}
+	for _, a := range aliases {
+		_, err := tx.Exec(`
+			INSERT INTO model_aliases (alias, model, description, updated_by, created_at, updated_at)
+			VALUES (?, ?, ?, ?, ?, ?)
+			ON CONFLICT(alias) DO UPDATE SET
+				model = excluded.model,
+				description = excluded.description,
+				updated_by = excluded.updated_by,
+				updated_at = excluded.updated_at
+		`, a.Alias, a.Model, a.Description, updatedBy, now, now)
+		if err != nil {
+			return fmt.Errorf("failed to save model alias %s: %w", a.Alias, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit configuration: %w", err)
+	}
+	return nil
+}
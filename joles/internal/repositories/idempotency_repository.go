@@ -0,0 +1,87 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// IdempotencyRecord is a request processed under an Idempotency-Key.
+// StatusCode is 0 while the original request is still running.
+type IdempotencyRecord struct {
+	RequestHash  string
+	StatusCode   int
+	ContentType  string
+	ResponseBody []byte
+	CreatedAt    time.Time
+}
+
+// IdempotencyRepository stores responses for Idempotency-Key replays
+type IdempotencyRepository struct {
+	db *sql.DB
+}
+
+// NewIdempotencyRepository creates a new idempotency repository
+func NewIdempotencyRepository(db *sql.DB) *IdempotencyRepository {
+	return &IdempotencyRepository{db: db}
+}
+
+// Reserve claims a key for a new request. It returns (nil, nil) when the
+// key was free and is now held by the caller, or the existing record when
+// the key has already been used within ttl. Expired keys are purged first.
+func (r *IdempotencyRepository) Reserve(userID, key, requestHash string, ttl time.Duration) (*IdempotencyRecord, error) {
+	now := time.Now()
+	if _, err := r.db.Exec("DELETE FROM idempotency_keys WHERE created_at < ?", now.Add(-ttl)); err != nil {
+		return nil, fmt.Errorf("failed to purge idempotency keys: %w", err)
+	}
+
+	result, err := r.db.Exec(`
+		INSERT INTO idempotency_keys (user_id, idempotency_key, request_hash, created_at)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT(user_id, idempotency_key) DO NOTHING
+	`, userID, key, requestHash, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reserve idempotency key: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 1 {
+		return nil, nil
+	}
+
+	rec := &IdempotencyRecord{}
+	var contentType sql.NullString
+	err = r.db.QueryRow(`
+		SELECT request_hash, status_code, content_type, response_body, created_at
+		FROM idempotency_keys
+		WHERE user_id = ? AND idempotency_key = ?
+	`, userID, key).Scan(&rec.RequestHash, &rec.StatusCode, &contentType, &rec.ResponseBody, &rec.CreatedAt)
+	if err == sql.ErrNoRows {
+		// Released between the insert and the read; let the caller retry
+		return nil, fmt.Errorf("idempotency key was released concurrently")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get idempotency key: %w", err)
+	}
+	rec.ContentType = contentType.String
+	return rec, nil
+}
+
+// Complete stores the response for a reserved key
+func (r *IdempotencyRepository) Complete(userID, key string, statusCode int, contentType string, body []byte) error {
+	_, err := r.db.Exec(`
+		UPDATE idempotency_keys SET status_code = ?, content_type = ?, response_body = ?
+		WHERE user_id = ? AND idempotency_key = ?
+	`, statusCode, contentType, body, userID, key)
+	if err != nil {
+		return fmt.Errorf("failed to store idempotent response: %w", err)
+	}
+	return nil
+}
+
+// Release frees a reserved key so the request can be retried
+func (r *IdempotencyRepository) Release(userID, key string) error {
+	_, err := r.db.Exec("DELETE FROM idempotency_keys WHERE user_id = ? AND idempotency_key = ?", userID, key)
+	if err != nil {
+		return fmt.Errorf("failed to release idempotency key: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,270 @@
+package repositories
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"lio-ai/internal/models"
+)
+
+// ErrAlreadyMerged is returned when either account of a merge has itself
+// been merged away
+var ErrAlreadyMerged = errors.New("account was already merged into another")
+
+// IdentityRepository merges user accounts and keeps the audit trail
+type IdentityRepository struct {
+	db *sql.DB
+	// Provider keys are encrypted bound to their owner and are
+	// re-encrypted for the surviving account
+	keys *ProviderKeyRepository
+}
+
+// NewIdentityRepository creates a new identity repository
+func NewIdentityRepository(db *sql.DB, keys *ProviderKeyRepository) *IdentityRepository {
+	return &IdentityRepository{db: db, keys: keys}
+}
+
+// Merge moves everything the source account owns to the target account,
+// deactivates the source and records the merge, in one transaction.
+// Rows the target already has an equivalent of (a key for the same
+// provider, a quota) keep the target's; the source's usage counters are
+// added to the target's quota.
+func (r *IdentityRepository) Merge(source *models.User, targetID int64, mergedBy, method string) (*models.MergeReport, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var targetMerged sql.NullInt64
+	if err := tx.QueryRow("SELECT merged_into FROM users WHERE id = ?", targetID).Scan(&targetMerged); err != nil {
+		return nil, fmt.Errorf("failed to get target account: %w", err)
+	}
+	if targetMerged.Valid {
+		return nil, ErrAlreadyMerged
+	}
+
+	now := time.Now()
+	result, err := tx.Exec(
+		"UPDATE users SET is_active = 0, merged_into = ?, updated_at = ? WHERE id = ? AND merged_into IS NULL",
+		targetID, now, source.ID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to deactivate merged account: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return nil, ErrAlreadyMerged
+	}
+
+	from, to := strconv.FormatInt(source.ID, 10), strconv.FormatInt(targetID, 10)
+	report := &models.MergeReport{SourceUserID: source.ID, TargetUserID: targetID, MergedAt: now}
+
+	if err := r.moveProviderKeys(tx, from, to, report); err != nil {
+		return nil, err
+	}
+
+	steps := []struct {
+		count *int64
+		query string
+		args  []interface{}
+	}{
+		{&report.Chats, "UPDATE chats SET user_id = ? WHERE user_id = ?", []interface{}{to, from}},
+		{&report.Documents, "UPDATE documents SET user_id = ? WHERE user_id = ?", []interface{}{to, from}},
+		{&report.DocumentComments, "UPDATE document_comments SET user_id = ? WHERE user_id = ?", []interface{}{to, from}},
+		{&report.Collections, "UPDATE collections SET user_id = ? WHERE user_id = ?", []interface{}{to, from}},
+		{&report.UsageRecords, "UPDATE usage_metrics SET user_id = ? WHERE user_id = ?", []interface{}{to, from}},
+		{nil, moveUsageRollupsSQL("usage_rollups_hourly"), []interface{}{to, from}},
+		{nil, "DELETE FROM usage_rollups_hourly WHERE user_id = ?", []interface{}{from}},
+		{nil, moveUsageRollupsSQL("usage_rollups_daily"), []interface{}{to, from}},
+		{nil, "DELETE FROM usage_rollups_daily WHERE user_id = ?", []interface{}{from}},
+		{&report.Templates, "UPDATE prompt_templates SET user_id = ? WHERE user_id = ?", []interface{}{to, from}},
+		{&report.Personas, "UPDATE personas SET user_id = ? WHERE user_id = ?", []interface{}{to, from}},
+		{&report.ScheduledMessages, "UPDATE scheduled_messages SET user_id = ? WHERE user_id = ?", []interface{}{to, from}},
+		{&report.Attachments, "UPDATE attachments SET user_id = ? WHERE user_id = ?", []interface{}{to, from}},
+		{nil, "UPDATE storage_usage SET user_id = ? WHERE user_id = ?", []interface{}{to, from}},
+		{&report.Feedback, "UPDATE OR IGNORE message_feedback SET user_id = ? WHERE user_id = ?", []interface{}{to, from}},
+		{nil, "DELETE FROM message_feedback WHERE user_id = ?", []interface{}{from}},
+		{&report.SecurityEvents, "UPDATE security_events SET user_id = ? WHERE user_id = ?", []interface{}{to, from}},
+		{&report.Passkeys, "UPDATE passkeys SET user_id = ? WHERE user_id = ?", []interface{}{targetID, source.ID}},
+		{&report.WidgetTokens, "UPDATE widget_tokens SET user_id = ? WHERE user_id = ?", []interface{}{to, from}},
+		{&report.Webhooks, "UPDATE webhooks SET user_id = ? WHERE user_id = ?", []interface{}{to, from}},
+		{nil, "UPDATE OR IGNORE response_plugins SET user_id = ? WHERE user_id = ?", []interface{}{to, from}},
+		{nil, "DELETE FROM response_plugins WHERE user_id = ?", []interface{}{from}},
+		{nil, "UPDATE OR IGNORE user_preferences SET user_id = ? WHERE user_id = ?", []interface{}{to, from}},
+		{nil, "DELETE FROM user_preferences WHERE user_id = ?", []interface{}{from}},
+		{nil, "UPDATE notifications SET user_id = ? WHERE user_id = ?", []interface{}{to, from}},
+		{nil, "UPDATE document_chunks SET user_id = ? WHERE user_id = ?", []interface{}{to, from}},
+		{nil, "UPDATE document_access_log SET user_id = ? WHERE user_id = ?", []interface{}{to, from}},
+		// Snapshots stay with the source account, whose workspace they hold
+		{nil, "DELETE FROM workspace_snapshot_schedules WHERE user_id = ?", []interface{}{from}},
+		{nil, "UPDATE OR IGNORE pending_key_syncs SET user_id = ? WHERE user_id = ?", []interface{}{to, from}},
+		{nil, "DELETE FROM pending_key_syncs WHERE user_id = ?", []interface{}{from}},
+
+		// Usage so far this period counts against the surviving quota
+		{nil, `
+			UPDATE user_quotas SET
+				daily_tokens_used = user_quotas.daily_tokens_used + s.daily_tokens_used,
+				monthly_tokens_used = user_quotas.monthly_tokens_used + s.monthly_tokens_used,
+				daily_cost_used_usd = user_quotas.daily_cost_used_usd + s.daily_cost_used_usd,
+				monthly_cost_used_usd = user_quotas.monthly_cost_used_usd + s.monthly_cost_used_usd,
+				updated_at = ?
+			FROM (SELECT * FROM user_quotas WHERE user_id = ?) AS s
+			WHERE user_quotas.user_id = ?`, []interface{}{now, from, to}},
+		{nil, "UPDATE OR IGNORE user_quotas SET user_id = ? WHERE user_id = ?", []interface{}{to, from}},
+		{nil, "DELETE FROM user_quotas WHERE user_id = ?", []interface{}{from}},
+		// The target keeps their own alert settings if they have any; alerts
+		// already sent for the same period and threshold stay with the source
+		{nil, "UPDATE OR IGNORE quota_alert_settings SET user_id = ? WHERE user_id = ?", []interface{}{to, from}},
+		{nil, "DELETE FROM quota_alert_settings WHERE user_id = ?", []interface{}{from}},
+		{nil, "UPDATE OR IGNORE quota_alerts SET user_id = ? WHERE user_id = ?", []interface{}{to, from}},
+		// The target keeps their own budget if they have one
+		{nil, "UPDATE OR IGNORE budgets SET scope_id = ? WHERE scope = 'user' AND scope_id = ?", []interface{}{to, from}},
+		{nil, "DELETE FROM budgets WHERE scope = 'user' AND scope_id = ?", []interface{}{from}},
+		// The target keeps their own organization if they are in one
+		{nil, "UPDATE OR IGNORE organization_members SET user_id = ? WHERE user_id = ?", []interface{}{to, from}},
+		{nil, "DELETE FROM organization_members WHERE user_id = ?", []interface{}{from}},
+		// Closed statements stay with the merged account; pending adjustments
+		// go on the target's next one
+		{nil, "UPDATE billing_adjustments SET user_id = ? WHERE user_id = ? AND statement_id IS NULL", []interface{}{to, from}},
+
+		// Replays and invitations of the merged account no longer apply
+		{nil, "DELETE FROM idempotency_keys WHERE user_id = ?", []interface{}{from}},
+		{nil, "DELETE FROM user_invitations WHERE user_id = ?", []interface{}{source.ID}},
+		{nil, "DELETE FROM passkey_challenges WHERE user_id = ?", []interface{}{source.ID}},
+	}
+	for _, step := range steps {
+		result, err := tx.Exec(step.query, step.args...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to merge accounts: %w", err)
+		}
+		if step.count != nil {
+			if *step.count, err = result.RowsAffected(); err != nil {
+				return nil, fmt.Errorf("failed to merge accounts: %w", err)
+			}
+		}
+	}
+
+	reportJSON, err := json.Marshal(report)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode merge report: %w", err)
+	}
+	_, err = tx.Exec(`
+		INSERT INTO identity_merges (source_user_id, source_email, target_user_id, merged_by, method, report, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, source.ID, source.Email, targetID, mergedBy, method, string(reportJSON), now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record merge: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit merge: %w", err)
+	}
+	return report, nil
+}
+
+// moveProviderKeys re-encrypts the source's provider keys for the target.
+// A provider the target has an active key for keeps the target's key.
+func (r *IdentityRepository) moveProviderKeys(tx *sql.Tx, from, to string, report *models.MergeReport) error {
+	rows, err := tx.Query(`
+		SELECT id, provider, api_key_encrypted, encryption_version
+		FROM provider_api_keys WHERE user_id = ?
+	`, from)
+	if err != nil {
+		return fmt.Errorf("failed to get provider keys: %w", err)
+	}
+	type sourceKey struct {
+		id        int64
+		provider  string
+		encrypted string
+		version   int
+	}
+	var keys []sourceKey
+	for rows.Next() {
+		var k sourceKey
+		if err := rows.Scan(&k.id, &k.provider, &k.encrypted, &k.version); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan provider key: %w", err)
+		}
+		keys = append(keys, k)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to get provider keys: %w", err)
+	}
+
+	for _, k := range keys {
+		var targetActive bool
+		err := tx.QueryRow("SELECT is_active FROM provider_api_keys WHERE user_id = ? AND provider = ?", to, k.provider).Scan(&targetActive)
+		if err != nil && err != sql.ErrNoRows {
+			return fmt.Errorf("failed to get provider key: %w", err)
+		}
+		if err == nil {
+			if targetActive {
+				if _, err := tx.Exec("DELETE FROM provider_api_keys WHERE id = ?", k.id); err != nil {
+					return fmt.Errorf("failed to delete provider key: %w", err)
+				}
+				report.ProviderKeysSkipped++
+				continue
+			}
+			// The target's key was deleted; the source's replaces it
+			if _, err := tx.Exec("DELETE FROM provider_api_keys WHERE user_id = ? AND provider = ?", to, k.provider); err != nil {
+				return fmt.Errorf("failed to delete provider key: %w", err)
+			}
+		}
+
+		plaintext, err := r.keys.decryptVersion(k.encrypted, k.version, from, k.provider)
+		if err != nil {
+			return fmt.Errorf("failed to decrypt %s key %d: %w", k.provider, k.id, err)
+		}
+		encrypted, err := r.keys.encrypt(plaintext, keyAAD(to, k.provider))
+		if err != nil {
+			return fmt.Errorf("failed to encrypt API key: %w", err)
+		}
+		_, err = tx.Exec(`
+			UPDATE provider_api_keys SET user_id = ?, api_key_encrypted = ?, encryption_version = ?, updated_at = ?
+			WHERE id = ?
+		`, to, encrypted, keyEncryptionBound, time.Now(), k.id)
+		if err != nil {
+			return fmt.Errorf("failed to move provider key: %w", err)
+		}
+		report.ProviderKeys++
+	}
+	return nil
+}
+
+// List retrieves the merge audit trail, newest first, with the total count
+func (r *IdentityRepository) List(limit, offset int) ([]models.IdentityMerge, int, error) {
+	var total int
+	if err := r.db.QueryRow("SELECT COUNT(*) FROM identity_merges").Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count identity merges: %w", err)
+	}
+
+	rows, err := r.db.Query(`
+		SELECT id, source_user_id, source_email, target_user_id, merged_by, method, report, created_at
+		FROM identity_merges
+		ORDER BY id DESC
+		LIMIT ? OFFSET ?
+	`, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list identity merges: %w", err)
+	}
+	defer rows.Close()
+
+	merges := make([]models.IdentityMerge, 0)
+	for rows.Next() {
+		var m models.IdentityMerge
+		var report string
+		if err := rows.Scan(&m.ID, &m.SourceUserID, &m.SourceEmail, &m.TargetUserID, &m.MergedBy, &m.Method, &report, &m.CreatedAt); err != nil {
+			return nil, 0, fmt.Errorf("failed to scan identity merge: %w", err)
+		}
+		if err := json.Unmarshal([]byte(report), &m.Report); err != nil {
+			return nil, 0, fmt.Errorf("failed to decode merge report %d: %w", m.ID, err)
+		}
+		merges = append(merges, m)
+	}
+	return merges, total, rows.Err()
+}
@@ -0,0 +1,132 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"testing"
+)
+
+// mergeFixtures are rows a source account owns before a merge, with the
+// query counting a user's rows afterwards
+var mergeFixtures = []struct {
+	name   string
+	insert string
+	count  string
+}{
+	{
+		"webhooks",
+		`INSERT INTO webhooks (user_id, url, events, secret) VALUES (?, 'https://example.com/hook', 'document.created', 'secret')`,
+		`SELECT COUNT(*) FROM webhooks WHERE user_id = ?`,
+	},
+	{
+		"document comments",
+		`INSERT INTO document_comments (document_id, user_id, body, start_offset, end_offset, quote) VALUES (1, ?, 'Typo', 0, 4, 'Teh ')`,
+		`SELECT COUNT(*) FROM document_comments WHERE user_id = ?`,
+	},
+	{
+		"quota alert settings",
+		`INSERT INTO quota_alert_settings (user_id, thresholds, webhook, email) VALUES (?, '50,80', 1, 1)`,
+		`SELECT COUNT(*) FROM quota_alert_settings WHERE user_id = ?`,
+	},
+	{
+		"quota alerts",
+		`INSERT INTO quota_alerts (user_id, metric, threshold, used, limit_value, period_start) VALUES (?, 'daily_tokens', 80, 80, 100, '2026-10-01')`,
+		`SELECT COUNT(*) FROM quota_alerts WHERE user_id = ?`,
+	},
+	{
+		"budgets",
+		`INSERT INTO budgets (scope, scope_id, monthly_usd, mode) VALUES ('user', ?, 20, 'warn')`,
+		`SELECT COUNT(*) FROM budgets WHERE scope = 'user' AND scope_id = ?`,
+	},
+}
+
+func countRows(t *testing.T, conn *sql.DB, query string, args ...interface{}) int {
+	t.Helper()
+	var n int
+	if err := conn.QueryRow(query, args...).Scan(&n); err != nil {
+		t.Fatalf("%s: %v", query, err)
+	}
+	return n
+}
+
+func TestMergeMovesOwnedRows(t *testing.T) {
+	conn := newTestDB(t)
+	source := createTestUser(t, conn, "source", "user")
+	target := createTestUser(t, conn, "target", "user")
+	from, to := fmt.Sprint(source.ID), fmt.Sprint(target.ID)
+
+	for _, f := range mergeFixtures {
+		if _, err := conn.Exec(f.insert, from); err != nil {
+			t.Fatalf("insert %s: %v", f.name, err)
+		}
+	}
+
+	report, err := NewIdentityRepository(conn, NewProviderKeyRepository(conn)).Merge(source, target.ID, to, "admin")
+	if err != nil {
+		t.Fatalf("Merge: %v", err)
+	}
+
+	for _, f := range mergeFixtures {
+		if n := countRows(t, conn, f.count, from); n != 0 {
+			t.Errorf("%s: %d rows left with the merged account", f.name, n)
+		}
+		if n := countRows(t, conn, f.count, to); n != 1 {
+			t.Errorf("%s: %d rows moved to the target, want 1", f.name, n)
+		}
+	}
+	if report.Webhooks != 1 || report.DocumentComments != 1 {
+		t.Errorf("report counts %d webhooks and %d document comments, want 1 each", report.Webhooks, report.DocumentComments)
+	}
+}
+
+func TestMergeKeepsTargetSettings(t *testing.T) {
+	conn := newTestDB(t)
+	source := createTestUser(t, conn, "source", "user")
+	target := createTestUser(t, conn, "target", "user")
+	from, to := fmt.Sprint(source.ID), fmt.Sprint(target.ID)
+
+	for _, stmt := range []struct {
+		query  string
+		userID string
+	}{
+		{`INSERT INTO quota_alert_settings (user_id, thresholds) VALUES (?, 'source')`, from},
+		{`INSERT INTO quota_alert_settings (user_id, thresholds) VALUES (?, 'target')`, to},
+		{`INSERT INTO budgets (scope, scope_id, monthly_usd, mode) VALUES ('user', ?, 5, 'block')`, from},
+		{`INSERT INTO budgets (scope, scope_id, monthly_usd, mode) VALUES ('user', ?, 50, 'warn')`, to},
+		// An organization whose ID is the source's user ID is not the source
+		{`INSERT INTO budgets (scope, scope_id, monthly_usd, mode) VALUES ('org', ?, 100, 'block')`, from},
+	} {
+		if _, err := conn.Exec(stmt.query, stmt.userID); err != nil {
+			t.Fatalf("%s: %v", stmt.query, err)
+		}
+	}
+
+	if _, err := NewIdentityRepository(conn, NewProviderKeyRepository(conn)).Merge(source, target.ID, to, "admin"); err != nil {
+		t.Fatalf("Merge: %v", err)
+	}
+
+	var thresholds string
+	if err := conn.QueryRow("SELECT thresholds FROM quota_alert_settings WHERE user_id = ?", to).Scan(&thresholds); err != nil {
+		t.Fatalf("read alert settings: %v", err)
+	}
+	if thresholds != "target" {
+		t.Errorf("target's alert settings = %q, want its own", thresholds)
+	}
+	if n := countRows(t, conn, "SELECT COUNT(*) FROM quota_alert_settings WHERE user_id = ?", from); n != 0 {
+		t.Errorf("%d alert settings left with the merged account", n)
+	}
+
+	var budget float64
+	if err := conn.QueryRow("SELECT monthly_usd FROM budgets WHERE scope = 'user' AND scope_id = ?", to).Scan(&budget); err != nil {
+		t.Fatalf("read budget: %v", err)
+	}
+	if budget != 50 {
+		t.Errorf("target's budget = $%.2f, want its own $50.00", budget)
+	}
+	if n := countRows(t, conn, "SELECT COUNT(*) FROM budgets WHERE scope = 'user' AND scope_id = ?", from); n != 0 {
+		t.Errorf("%d budgets left with the merged account", n)
+	}
+	if n := countRows(t, conn, "SELECT COUNT(*) FROM budgets WHERE scope = 'org' AND scope_id = ?", from); n != 1 {
+		t.Errorf("organization budget was touched by the merge")
+	}
+}
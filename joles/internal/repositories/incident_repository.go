@@ -0,0 +1,176 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"lio-ai/internal/models"
+)
+
+// IncidentRepository handles database operations for incidents and the
+// gateway heartbeat
+type IncidentRepository struct {
+	db *sql.DB
+}
+
+// NewIncidentRepository creates a new incident repository
+func NewIncidentRepository(db *sql.DB) *IncidentRepository {
+	return &IncidentRepository{db: db}
+}
+
+// Open starts an incident unless the component already has one of that
+// kind ongoing, reporting whether one was started
+func (r *IncidentRepository) Open(component, kind, detail string, startedAt time.Time) (bool, error) {
+	query := `
+		INSERT INTO incidents (component, kind, detail, started_at)
+		SELECT ?, ?, ?, ?
+		WHERE NOT EXISTS (
+			SELECT 1 FROM incidents WHERE component = ? AND kind = ? AND resolved_at IS NULL
+		)
+	`
+	result, err := r.db.Exec(query, component, kind, detail, startedAt, component, kind)
+	if err != nil {
+		return false, fmt.Errorf("failed to open incident: %w", err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to open incident: %w", err)
+	}
+	return n > 0, nil
+}
+
+// Create records an incident that is already over, such as a restart
+func (r *IncidentRepository) Create(i *models.Incident) error {
+	query := `
+		INSERT INTO incidents (component, kind, detail, started_at, resolved_at)
+		VALUES (?, ?, ?, ?, ?)
+	`
+	result, err := r.db.Exec(query, i.Component, i.Kind, i.Detail, i.StartedAt, i.ResolvedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create incident: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to get last insert id: %w", err)
+	}
+	i.ID = id
+	return nil
+}
+
+// Resolve ends the component's ongoing incident of that kind, if any
+func (r *IncidentRepository) Resolve(component, kind string, resolvedAt time.Time) (bool, error) {
+	result, err := r.db.Exec(
+		"UPDATE incidents SET resolved_at = ? WHERE component = ? AND kind = ? AND resolved_at IS NULL",
+		resolvedAt, component, kind,
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to resolve incident: %w", err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to resolve incident: %w", err)
+	}
+	return n > 0, nil
+}
+
+// ResolveAllOpen ends every ongoing incident at resolvedAt. Used at
+// startup for incidents the previous process never saw recover.
+func (r *IncidentRepository) ResolveAllOpen(resolvedAt time.Time) (int64, error) {
+	result, err := r.db.Exec("UPDATE incidents SET resolved_at = MAX(started_at, ?) WHERE resolved_at IS NULL", resolvedAt)
+	if err != nil {
+		return 0, fmt.Errorf("failed to resolve incidents: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// List retrieves incidents matching the filter, newest first, with the
+// total number of matches. Since matches incidents still ongoing or
+// resolved after it.
+func (r *IncidentRepository) List(filter models.IncidentFilter) ([]models.Incident, int, error) {
+	var where []string
+	var args []interface{}
+	if filter.Component != "" {
+		where = append(where, "component = ?")
+		args = append(args, filter.Component)
+	}
+	if !filter.Since.IsZero() {
+		where = append(where, "(resolved_at IS NULL OR resolved_at >= ?)")
+		args = append(args, filter.Since)
+	}
+	clause := ""
+	if len(where) > 0 {
+		clause = "WHERE " + strings.Join(where, " AND ")
+	}
+
+	var total int
+	if err := r.db.QueryRow("SELECT COUNT(*) FROM incidents "+clause, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count incidents: %w", err)
+	}
+
+	query := `
+		SELECT id, component, kind, COALESCE(detail, ''), started_at, resolved_at
+		FROM incidents
+		` + clause + `
+		ORDER BY started_at DESC, id DESC
+	`
+	if filter.Limit > 0 {
+		query += " LIMIT ? OFFSET ?"
+		args = append(args, filter.Limit, filter.Offset)
+	}
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list incidents: %w", err)
+	}
+	defer rows.Close()
+
+	incidents := make([]models.Incident, 0)
+	for rows.Next() {
+		var i models.Incident
+		var resolvedAt sql.NullTime
+		if err := rows.Scan(&i.ID, &i.Component, &i.Kind, &i.Detail, &i.StartedAt, &resolvedAt); err != nil {
+			return nil, 0, fmt.Errorf("failed to scan incident: %w", err)
+		}
+		if resolvedAt.Valid {
+			i.ResolvedAt = &resolvedAt.Time
+		}
+		incidents = append(incidents, i)
+	}
+	return incidents, total, rows.Err()
+}
+
+// LastHeartbeat returns when the gateway was last seen running, or nil on
+// the first start
+func (r *IncidentRepository) LastHeartbeat() (*time.Time, error) {
+	var lastSeen time.Time
+	err := r.db.QueryRow("SELECT last_seen FROM gateway_heartbeat WHERE id = 1").Scan(&lastSeen)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get gateway heartbeat: %w", err)
+	}
+	return &lastSeen, nil
+}
+
+// StartHeartbeat records that the gateway started at now
+func (r *IncidentRepository) StartHeartbeat(now time.Time) error {
+	query := `
+		INSERT INTO gateway_heartbeat (id, started_at, last_seen) VALUES (1, ?, ?)
+		ON CONFLICT(id) DO UPDATE SET started_at = excluded.started_at, last_seen = excluded.last_seen
+	`
+	if _, err := r.db.Exec(query, now, now); err != nil {
+		return fmt.Errorf("failed to record gateway start: %w", err)
+	}
+	return nil
+}
+
+// Heartbeat records that the gateway is still running at now
+func (r *IncidentRepository) Heartbeat(now time.Time) error {
+	if _, err := r.db.Exec("UPDATE gateway_heartbeat SET last_seen = ? WHERE id = 1", now); err != nil {
+		return fmt.Errorf("failed to record gateway heartbeat: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// InvitationRepository stores one-time account invitation tokens
+type InvitationRepository struct {
+	db *sql.DB
+}
+
+// NewInvitationRepository creates a new invitation repository
+func NewInvitationRepository(db *sql.DB) *InvitationRepository {
+	return &InvitationRepository{db: db}
+}
+
+// Create stores an invitation for a user; only the token's hash is kept
+func (r *InvitationRepository) Create(userID int64, tokenHash string, expiresAt time.Time) error {
+	_, err := r.db.Exec(`
+		INSERT INTO user_invitations (user_id, token_hash, expires_at, created_at)
+		VALUES (?, ?, ?, ?)
+	`, userID, tokenHash, expiresAt, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to create invitation: %w", err)
+	}
+	return nil
+}
+
+// Accept consumes an unexpired, unused invitation and returns its user ID,
+// or 0 if the token is unknown, used or expired
+func (r *InvitationRepository) Accept(tokenHash string) (int64, error) {
+	now := time.Now()
+	var userID int64
+	err := r.db.QueryRow(`
+		SELECT user_id FROM user_invitations
+		WHERE token_hash = ? AND accepted_at IS NULL AND expires_at > ?
+	`, tokenHash, now).Scan(&userID)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to get invitation: %w", err)
+	}
+
+	// Conditional update so a token can only be redeemed once
+	result, err := r.db.Exec(`
+		UPDATE user_invitations SET accepted_at = ?
+		WHERE token_hash = ? AND accepted_at IS NULL
+	`, now, tokenHash)
+	if err != nil {
+		return 0, fmt.Errorf("failed to accept invitation: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n != 1 {
+		return 0, nil
+	}
+	return userID, nil
+}
@@ -0,0 +1,113 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"lio-ai/internal/models"
+)
+
+// ModelAliasRepository handles database operations for model aliases
+type ModelAliasRepository struct {
+	db *sql.DB
+}
+
+// NewModelAliasRepository creates a new model alias repository
+func NewModelAliasRepository(db *sql.DB) *ModelAliasRepository {
+	return &ModelAliasRepository{db: db}
+}
+
+// Upsert creates an alias or repoints an existing one
+func (r *ModelAliasRepository) Upsert(a *models.ModelAlias) error {
+	query := `
+		INSERT INTO model_aliases (alias, model, description, updated_by, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT(alias) DO UPDATE SET
+			model = excluded.model,
+			description = excluded.description,
+			updated_by = excluded.updated_by,
+			updated_at = excluded.updated_at
+	`
+
+	now := time.Now()
+	if _, err := r.db.Exec(query, a.Alias, a.Model, a.Description, a.UpdatedBy, now, now); err != nil {
+		return fmt.Errorf("failed to save model alias: %w", err)
+	}
+
+	stored, err := r.Get(a.Alias)
+	if err != nil {
+		return err
+	}
+	*a = *stored
+	return nil
+}
+
+// Get retrieves an alias, or nil if it does not exist
+func (r *ModelAliasRepository) Get(alias string) (*models.ModelAlias, error) {
+	query := `
+		SELECT alias, model, COALESCE(description, ''), COALESCE(updated_by, ''), created_at, updated_at
+		FROM model_aliases
+		WHERE alias = ?
+	`
+
+	a, err := scanModelAlias(r.db.QueryRow(query, alias))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return a, err
+}
+
+// IsTarget reports whether any alias points at model
+func (r *ModelAliasRepository) IsTarget(model string) (bool, error) {
+	var n int
+	if err := r.db.QueryRow("SELECT COUNT(*) FROM model_aliases WHERE model = ?", model).Scan(&n); err != nil {
+		return false, fmt.Errorf("failed to check model aliases: %w", err)
+	}
+	return n > 0, nil
+}
+
+// List retrieves all aliases ordered by name
+func (r *ModelAliasRepository) List() ([]models.ModelAlias, error) {
+	rows, err := r.db.Query(`
+		SELECT alias, model, COALESCE(description, ''), COALESCE(updated_by, ''), created_at, updated_at
+		FROM model_aliases
+		ORDER BY alias ASC
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list model aliases: %w", err)
+	}
+	defer rows.Close()
+
+	aliases := make([]models.ModelAlias, 0)
+	for rows.Next() {
+		a, err := scanModelAlias(rows)
+		if err != nil {
+			return nil, err
+		}
+		aliases = append(aliases, *a)
+	}
+	return aliases, rows.Err()
+}
+
+// Delete removes an alias, reporting whether it existed
+func (r *ModelAliasRepository) Delete(alias string) (bool, error) {
+	result, err := r.db.Exec("DELETE FROM model_aliases WHERE alias = ?", alias)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete model alias: %w", err)
+	}
+	n, _ := result.RowsAffected()
+	return n > 0, nil
+}
+
+func scanModelAlias(row interface{ Scan(...interface{}) error }) (*models.ModelAlias, error) {
+	a := &models.ModelAlias{}
+	err := row.Scan(&a.Alias, &a.Model, &a.Description, &a.UpdatedBy, &a.CreatedAt, &a.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan model alias: %w", err)
+	}
+	return a, nil
+}
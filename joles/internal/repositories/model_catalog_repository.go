@@ -0,0 +1,137 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"lio-ai/internal/models"
+)
+
+// ModelCatalogRepository handles the lifecycle of the models in the
+// cost_config catalog
+type ModelCatalogRepository struct {
+	db *sql.DB
+}
+
+// NewModelCatalogRepository creates a new model catalog repository
+func NewModelCatalogRepository(db *sql.DB) *ModelCatalogRepository {
+	return &ModelCatalogRepository{db: db}
+}
+
+const deprecationColumns = `model_name, deprecated_at, sunset_at, replacement_model, deprecation_notice, deprecated_by`
+
+func scanDeprecation(row interface{ Scan(...interface{}) error }) (*models.ModelDeprecation, error) {
+	d := &models.ModelDeprecation{}
+	var replacement, notice, by sql.NullString
+	if err := row.Scan(&d.Model, &d.DeprecatedAt, &d.SunsetAt, &replacement, &notice, &by); err != nil {
+		return nil, err
+	}
+	d.ReplacementModel = replacement.String
+	d.Notice = notice.String
+	d.DeprecatedBy = by.String
+	return d, nil
+}
+
+// Exists reports whether model is in the catalog
+func (r *ModelCatalogRepository) Exists(model string) (bool, error) {
+	var count int
+	if err := r.db.QueryRow("SELECT COUNT(*) FROM cost_config WHERE model_name = ?", model).Scan(&count); err != nil {
+		return false, fmt.Errorf("failed to look up model: %w", err)
+	}
+	return count > 0, nil
+}
+
+// GetDeprecation retrieves a model's deprecation, or nil when it is not
+// deprecated
+func (r *ModelCatalogRepository) GetDeprecation(model string) (*models.ModelDeprecation, error) {
+	row := r.db.QueryRow(`SELECT `+deprecationColumns+` FROM cost_config WHERE model_name = ? AND deprecated_at IS NOT NULL`, model)
+	d, err := scanDeprecation(row)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get model deprecation: %w", err)
+	}
+	return d, nil
+}
+
+// ListDeprecations retrieves every deprecated model, soonest sunset first
+func (r *ModelCatalogRepository) ListDeprecations() ([]models.ModelDeprecation, error) {
+	rows, err := r.db.Query(`SELECT ` + deprecationColumns + ` FROM cost_config WHERE deprecated_at IS NOT NULL ORDER BY sunset_at ASC, model_name ASC`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list model deprecations: %w", err)
+	}
+	defer rows.Close()
+
+	deprecations := make([]models.ModelDeprecation, 0)
+	for rows.Next() {
+		d, err := scanDeprecation(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan model deprecation: %w", err)
+		}
+		deprecations = append(deprecations, *d)
+	}
+	return deprecations, rows.Err()
+}
+
+// SetDeprecation marks d.Model deprecated, keeping the original
+// deprecated_at when it already was. It reports whether the model exists.
+func (r *ModelCatalogRepository) SetDeprecation(d *models.ModelDeprecation) (bool, error) {
+	query := `
+		UPDATE cost_config
+		SET deprecated_at = COALESCE(deprecated_at, ?), sunset_at = ?, replacement_model = NULLIF(?, ''),
+			deprecation_notice = NULLIF(?, ''), deprecated_by = ?, updated_at = ?
+		WHERE model_name = ?
+		RETURNING deprecated_at
+	`
+
+	now := time.Now()
+	err := r.db.QueryRow(query, now, d.SunsetAt, d.ReplacementModel, d.Notice, d.DeprecatedBy, now, d.Model).Scan(&d.DeprecatedAt)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to deprecate model: %w", err)
+	}
+	return true, nil
+}
+
+// ClearDeprecation returns a deprecated model to normal service, reporting
+// whether it was deprecated
+func (r *ModelCatalogRepository) ClearDeprecation(model string) (bool, error) {
+	result, err := r.db.Exec(`
+		UPDATE cost_config
+		SET deprecated_at = NULL, sunset_at = NULL, replacement_model = NULL, deprecation_notice = NULL,
+			deprecated_by = NULL, updated_at = ?
+		WHERE model_name = ? AND deprecated_at IS NOT NULL
+	`, time.Now(), model)
+	if err != nil {
+		return false, fmt.Errorf("failed to clear model deprecation: %w", err)
+	}
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
+
+// UsersOfModelSince lists the registered users with a request served by
+// model since the cutoff; guests and widget visitors have no account
+func (r *ModelCatalogRepository) UsersOfModelSince(model string, since time.Time) ([]string, error) {
+	rows, err := r.db.Query(`
+		SELECT DISTINCT user_id FROM usage_metrics
+		WHERE model_used = ? AND created_at >= ? AND user_id IN (SELECT CAST(id AS TEXT) FROM users)
+	`, model, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get model users: %w", err)
+	}
+	defer rows.Close()
+
+	users := make([]string, 0)
+	for rows.Next() {
+		var userID string
+		if err := rows.Scan(&userID); err != nil {
+			return nil, fmt.Errorf("failed to scan model user: %w", err)
+		}
+		users = append(users, userID)
+	}
+	return users, rows.Err()
+}
@@ -0,0 +1,124 @@
+package repositories
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"lio-ai/internal/models"
+)
+
+// NotificationRepository handles users' notification centers
+type NotificationRepository struct {
+	db *sql.DB
+}
+
+// NewNotificationRepository creates a new notification repository
+func NewNotificationRepository(db *sql.DB) *NotificationRepository {
+	return &NotificationRepository{db: db}
+}
+
+// CreateMany adds a copy of n for each user in one transaction
+func (r *NotificationRepository) CreateMany(userIDs []string, n *models.Notification) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(`
+		INSERT INTO notifications (user_id, kind, title, body, data, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare notification: %w", err)
+	}
+	defer stmt.Close()
+
+	var data interface{}
+	if len(n.Data) > 0 {
+		data = string(n.Data)
+	}
+	now := time.Now()
+	for _, userID := range userIDs {
+		if _, err := stmt.Exec(userID, n.Kind, n.Title, n.Body, data, now); err != nil {
+			return fmt.Errorf("failed to create notification: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit notifications: %w", err)
+	}
+	n.CreatedAt = now
+	return nil
+}
+
+// ListByUser retrieves a user's notifications, newest first
+func (r *NotificationRepository) ListByUser(userID string, unreadOnly bool, limit, offset int) ([]models.Notification, error) {
+	query := `
+		SELECT id, user_id, kind, title, body, data, read_at, created_at
+		FROM notifications
+		WHERE user_id = ? AND (? = 0 OR read_at IS NULL)
+		ORDER BY created_at DESC, id DESC
+		LIMIT ? OFFSET ?
+	`
+
+	rows, err := r.db.Query(query, userID, unreadOnly, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get notifications: %w", err)
+	}
+	defer rows.Close()
+
+	notifications := make([]models.Notification, 0)
+	for rows.Next() {
+		var n models.Notification
+		var data sql.NullString
+		var readAt sql.NullTime
+		if err := rows.Scan(&n.ID, &n.UserID, &n.Kind, &n.Title, &n.Body, &data, &readAt, &n.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan notification: %w", err)
+		}
+		if data.Valid {
+			n.Data = json.RawMessage(data.String)
+		}
+		if readAt.Valid {
+			n.ReadAt = &readAt.Time
+		}
+		notifications = append(notifications, n)
+	}
+
+	return notifications, rows.Err()
+}
+
+// CountUnread counts a user's unread notifications
+func (r *NotificationRepository) CountUnread(userID string) (int, error) {
+	var count int
+	err := r.db.QueryRow("SELECT COUNT(*) FROM notifications WHERE user_id = ? AND read_at IS NULL", userID).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count notifications: %w", err)
+	}
+	return count, nil
+}
+
+// MarkRead marks one of a user's notifications read, reporting whether it
+// exists
+func (r *NotificationRepository) MarkRead(id int64, userID string) (bool, error) {
+	result, err := r.db.Exec(`
+		UPDATE notifications SET read_at = COALESCE(read_at, ?)
+		WHERE id = ? AND user_id = ?
+	`, time.Now(), id, userID)
+	if err != nil {
+		return false, fmt.Errorf("failed to mark notification read: %w", err)
+	}
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
+
+// MarkAllRead marks all of a user's notifications read
+func (r *NotificationRepository) MarkAllRead(userID string) (int64, error) {
+	result, err := r.db.Exec("UPDATE notifications SET read_at = ? WHERE user_id = ? AND read_at IS NULL", time.Now(), userID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to mark notifications read: %w", err)
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,280 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"lio-ai/internal/models"
+)
+
+// OrganizationRepository handles organizations, their members and their
+// shared quotas
+type OrganizationRepository struct {
+	db *sql.DB
+}
+
+// NewOrganizationRepository creates a new organization repository
+func NewOrganizationRepository(db *sql.DB) *OrganizationRepository {
+	return &OrganizationRepository{db: db}
+}
+
+// orgQuotaUsage selects each org_quotas row with its members' usage this
+// day and month, from their user_quotas counters, and what their unexpired
+// reservations hold (as of the one arg)
+const orgQuotaUsage = `
+	SELECT oq.org_id, oq.daily_token_limit, oq.monthly_token_limit, oq.daily_cost_limit_usd,
+		oq.monthly_cost_limit_usd, oq.updated_at,
+		COALESCE(used.daily_tokens, 0) AS daily_tokens_used, COALESCE(used.monthly_tokens, 0) AS monthly_tokens_used,
+		COALESCE(used.daily_cost, 0) AS daily_cost_used_usd, COALESCE(used.monthly_cost, 0) AS monthly_cost_used_usd,
+		COALESCE(held.tokens, 0) AS reserved_tokens, COALESCE(held.cost, 0) AS reserved_cost_usd
+	FROM org_quotas oq
+	LEFT JOIN (
+		SELECT m.org_id, SUM(q.daily_tokens_used) AS daily_tokens, SUM(q.monthly_tokens_used) AS monthly_tokens,
+			SUM(q.daily_cost_used_usd) AS daily_cost, SUM(q.monthly_cost_used_usd) AS monthly_cost
+		FROM organization_members m JOIN user_quotas q ON q.user_id = m.user_id
+		GROUP BY m.org_id
+	) used ON used.org_id = oq.org_id
+	LEFT JOIN (
+		SELECT m.org_id, SUM(r.tokens) AS tokens, SUM(r.cost_usd) AS cost
+		FROM organization_members m JOIN quota_reservations r ON r.user_id = m.user_id
+		WHERE r.expires_at > ?
+		GROUP BY m.org_id
+	) held ON held.org_id = oq.org_id`
+
+// Create stores an organization with ownerID as its owner, filling in its
+// ID and timestamps
+func (r *OrganizationRepository) Create(org *models.Organization, ownerID string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	result, err := tx.Exec(`
+		INSERT INTO organizations (name, created_by, created_at, updated_at)
+		VALUES (?, ?, ?, ?)
+	`, org.Name, org.CreatedBy, now, now)
+	if err != nil {
+		return fmt.Errorf("failed to create organization: %w", err)
+	}
+	if org.ID, err = result.LastInsertId(); err != nil {
+		return fmt.Errorf("failed to create organization: %w", err)
+	}
+	if _, err := tx.Exec(`
+		INSERT INTO organization_members (org_id, user_id, role, created_at)
+		VALUES (?, ?, ?, ?)
+	`, org.ID, ownerID, models.OrgRoleOwner, now); err != nil {
+		return fmt.Errorf("failed to add organization owner: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to create organization: %w", err)
+	}
+	org.CreatedAt, org.UpdatedAt = now, now
+	return nil
+}
+
+const organizationColumns = `o.id, o.name, o.created_by, o.created_at, o.updated_at`
+
+// GetByID retrieves an organization, or nil when there is none
+func (r *OrganizationRepository) GetByID(id int64) (*models.Organization, error) {
+	return scanOrganization(r.db.QueryRow("SELECT "+organizationColumns+" FROM organizations o WHERE o.id = ?", id))
+}
+
+// GetByUser retrieves the organization userID belongs to, or nil when they
+// belong to none
+func (r *OrganizationRepository) GetByUser(userID string) (*models.Organization, error) {
+	return scanOrganization(r.db.QueryRow(`
+		SELECT `+organizationColumns+` FROM organizations o
+		JOIN organization_members m ON m.org_id = o.id
+		WHERE m.user_id = ?
+	`, userID))
+}
+
+// Delete removes an organization with its members, quota and budget,
+// reporting whether there was one
+func (r *OrganizationRepository) Delete(id int64) (bool, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, q := range []string{
+		"DELETE FROM organization_members WHERE org_id = ?",
+		"DELETE FROM org_quotas WHERE org_id = ?",
+	} {
+		if _, err := tx.Exec(q, id); err != nil {
+			return false, fmt.Errorf("failed to delete organization: %w", err)
+		}
+	}
+	if _, err := tx.Exec("DELETE FROM budgets WHERE scope = ? AND scope_id = ?", models.BudgetScopeOrg, strconv.FormatInt(id, 10)); err != nil {
+		return false, fmt.Errorf("failed to delete organization budget: %w", err)
+	}
+	result, err := tx.Exec("DELETE FROM organizations WHERE id = ?", id)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete organization: %w", err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, tx.Commit()
+}
+
+// ListMembers retrieves an organization's members, owners first
+func (r *OrganizationRepository) ListMembers(orgID int64) ([]models.OrganizationMember, error) {
+	rows, err := r.db.Query(`
+		SELECT org_id, user_id, role, created_at FROM organization_members
+		WHERE org_id = ?
+		ORDER BY CASE role WHEN 'owner' THEN 0 ELSE 1 END, created_at, user_id
+	`, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list organization members: %w", err)
+	}
+	defer rows.Close()
+
+	members := make([]models.OrganizationMember, 0)
+	for rows.Next() {
+		var m models.OrganizationMember
+		if err := rows.Scan(&m.OrgID, &m.UserID, &m.Role, &m.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan organization member: %w", err)
+		}
+		members = append(members, m)
+	}
+	return members, rows.Err()
+}
+
+// GetMember retrieves userID's membership of an organization, or nil when
+// they are not a member
+func (r *OrganizationRepository) GetMember(orgID int64, userID string) (*models.OrganizationMember, error) {
+	m := &models.OrganizationMember{}
+	err := r.db.QueryRow(`
+		SELECT org_id, user_id, role, created_at FROM organization_members
+		WHERE org_id = ? AND user_id = ?
+	`, orgID, userID).Scan(&m.OrgID, &m.UserID, &m.Role, &m.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get organization member: %w", err)
+	}
+	return m, nil
+}
+
+// AddMember adds a user to an organization, filling in CreatedAt
+func (r *OrganizationRepository) AddMember(m *models.OrganizationMember) error {
+	now := time.Now()
+	_, err := r.db.Exec(`
+		INSERT INTO organization_members (org_id, user_id, role, created_at)
+		VALUES (?, ?, ?, ?)
+	`, m.OrgID, m.UserID, m.Role, now)
+	if err != nil {
+		if err.Error() == "UNIQUE constraint failed: organization_members.user_id" {
+			return errors.New("user already belongs to an organization")
+		}
+		return fmt.Errorf("failed to add organization member: %w", err)
+	}
+	m.CreatedAt = now
+	return nil
+}
+
+// RemoveMember removes a user from an organization, reporting whether they
+// were a member
+func (r *OrganizationRepository) RemoveMember(orgID int64, userID string) (bool, error) {
+	result, err := r.db.Exec("DELETE FROM organization_members WHERE org_id = ? AND user_id = ?", orgID, userID)
+	if err != nil {
+		return false, fmt.Errorf("failed to remove organization member: %w", err)
+	}
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
+
+// CountOwners counts an organization's owners
+func (r *OrganizationRepository) CountOwners(orgID int64) (int, error) {
+	var count int
+	err := r.db.QueryRow("SELECT COUNT(*) FROM organization_members WHERE org_id = ? AND role = ?", orgID, models.OrgRoleOwner).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count organization owners: %w", err)
+	}
+	return count, nil
+}
+
+// GetQuotaStatus retrieves an organization's quota with its members' usage,
+// or nil when it has no quota
+func (r *OrganizationRepository) GetQuotaStatus(orgID int64) (*models.OrgQuotaStatus, error) {
+	s := &models.OrgQuotaStatus{}
+	err := r.db.QueryRow("SELECT * FROM ("+orgQuotaUsage+") WHERE org_id = ?", time.Now(), orgID).Scan(
+		&s.OrgID, &s.DailyTokenLimit, &s.MonthlyTokenLimit, &s.DailyCostLimitUSD,
+		&s.MonthlyCostLimitUSD, &s.UpdatedAt,
+		&s.DailyTokensUsed, &s.MonthlyTokensUsed, &s.DailyCostUsedUSD, &s.MonthlyCostUsedUSD,
+		&s.ReservedTokens, &s.ReservedCostUSD,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get organization quota: %w", err)
+	}
+	return s, nil
+}
+
+// SetQuota creates or replaces an organization's quota limits
+func (r *OrganizationRepository) SetQuota(q *models.OrgQuota) error {
+	now := time.Now()
+	_, err := r.db.Exec(`
+		INSERT INTO org_quotas (org_id, daily_token_limit, monthly_token_limit, daily_cost_limit_usd, monthly_cost_limit_usd, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT(org_id) DO UPDATE SET
+			daily_token_limit = excluded.daily_token_limit,
+			monthly_token_limit = excluded.monthly_token_limit,
+			daily_cost_limit_usd = excluded.daily_cost_limit_usd,
+			monthly_cost_limit_usd = excluded.monthly_cost_limit_usd,
+			updated_at = excluded.updated_at
+	`, q.OrgID, q.DailyTokenLimit, q.MonthlyTokenLimit, q.DailyCostLimitUSD, q.MonthlyCostLimitUSD, now)
+	if err != nil {
+		return fmt.Errorf("failed to save organization quota: %w", err)
+	}
+	q.UpdatedAt = now
+	return nil
+}
+
+// DeleteQuota removes an organization's quota, reporting whether it had one
+func (r *OrganizationRepository) DeleteQuota(orgID int64) (bool, error) {
+	result, err := r.db.Exec("DELETE FROM org_quotas WHERE org_id = ?", orgID)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete organization quota: %w", err)
+	}
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
+
+// GetCostSince sums the cost of the usage of an organization's current
+// members recorded at or after since
+func (r *OrganizationRepository) GetCostSince(orgID int64, since time.Time) (float64, error) {
+	var cost float64
+	err := r.db.QueryRow(`
+		SELECT COALESCE(SUM(cost_usd), 0) FROM usage_metrics
+		WHERE user_id IN (SELECT user_id FROM organization_members WHERE org_id = ?)
+			AND julianday(created_at) >= julianday(?)
+	`, orgID, since.UTC()).Scan(&cost)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get organization cost: %w", err)
+	}
+	return cost, nil
+}
+
+func scanOrganization(row *sql.Row) (*models.Organization, error) {
+	org := &models.Organization{}
+	err := row.Scan(&org.ID, &org.Name, &org.CreatedBy, &org.CreatedAt, &org.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get organization: %w", err)
+	}
+	return org, nil
+}
@@ -0,0 +1,169 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"lio-ai/internal/models"
+)
+
+// ErrPasskeyExists is returned when a credential is already registered
+var ErrPasskeyExists = errors.New("passkey already registered")
+
+// PasskeyRepository stores users' passkeys and the challenges of
+// ceremonies in progress
+type PasskeyRepository struct {
+	db *sql.DB
+}
+
+// NewPasskeyRepository creates a new passkey repository
+func NewPasskeyRepository(db *sql.DB) *PasskeyRepository {
+	return &PasskeyRepository{db: db}
+}
+
+const passkeyColumns = "id, user_id, credential_id, public_key, sign_count, COALESCE(transports, ''), COALESCE(name, ''), backup_eligible, created_at, last_used_at"
+
+// Create stores a passkey registered by a user
+func (r *PasskeyRepository) Create(p *models.Passkey) error {
+	query := `
+		INSERT INTO passkeys (user_id, credential_id, public_key, sign_count, transports, name, backup_eligible, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`
+	now := time.Now()
+	result, err := r.db.Exec(query, p.UserID, p.CredentialID, p.PublicKey, p.SignCount,
+		strings.Join(p.Transports, ","), p.Name, p.BackupEligible, now)
+	if err != nil {
+		if err.Error() == "UNIQUE constraint failed: passkeys.credential_id" {
+			return ErrPasskeyExists
+		}
+		return fmt.Errorf("failed to create passkey: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to get last insert id: %w", err)
+	}
+	p.ID = id
+	p.CreatedAt = now
+	return nil
+}
+
+// GetByCredentialID retrieves a passkey by its credential ID, or nil
+func (r *PasskeyRepository) GetByCredentialID(credentialID string) (*models.Passkey, error) {
+	p, err := scanPasskey(r.db.QueryRow("SELECT "+passkeyColumns+" FROM passkeys WHERE credential_id = ?", credentialID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get passkey: %w", err)
+	}
+	return p, nil
+}
+
+// ListByUser retrieves a user's passkeys, oldest first
+func (r *PasskeyRepository) ListByUser(userID int64) ([]models.Passkey, error) {
+	rows, err := r.db.Query("SELECT "+passkeyColumns+" FROM passkeys WHERE user_id = ? ORDER BY id", userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list passkeys: %w", err)
+	}
+	defer rows.Close()
+
+	passkeys := make([]models.Passkey, 0)
+	for rows.Next() {
+		p, err := scanPasskey(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan passkey: %w", err)
+		}
+		passkeys = append(passkeys, *p)
+	}
+	return passkeys, rows.Err()
+}
+
+// RecordUse stores the signature counter of a successful login. The update
+// only applies while the stored counter is still the one that was checked,
+// so two logins racing with the same counter cannot both succeed.
+func (r *PasskeyRepository) RecordUse(id int64, checkedCount, signCount uint32) (bool, error) {
+	result, err := r.db.Exec(
+		"UPDATE passkeys SET sign_count = ?, last_used_at = ? WHERE id = ? AND sign_count = ?",
+		signCount, time.Now(), id, checkedCount,
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to record passkey use: %w", err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to record passkey use: %w", err)
+	}
+	return n > 0, nil
+}
+
+// Delete removes one of a user's passkeys, reporting whether it existed
+func (r *PasskeyRepository) Delete(id, userID int64) (bool, error) {
+	result, err := r.db.Exec("DELETE FROM passkeys WHERE id = ? AND user_id = ?", id, userID)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete passkey: %w", err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to delete passkey: %w", err)
+	}
+	return n > 0, nil
+}
+
+// CreateChallenge stores the challenge of a new ceremony, clearing out
+// expired ones
+func (r *PasskeyRepository) CreateChallenge(c *models.PasskeyChallenge) error {
+	if _, err := r.db.Exec("DELETE FROM passkey_challenges WHERE expires_at < ?", time.Now()); err != nil {
+		return fmt.Errorf("failed to prune passkey challenges: %w", err)
+	}
+	_, err := r.db.Exec(
+		"INSERT INTO passkey_challenges (id, ceremony, user_id, challenge, expires_at) VALUES (?, ?, ?, ?, ?)",
+		c.ID, c.Ceremony, c.UserID, c.Challenge, c.ExpiresAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create passkey challenge: %w", err)
+	}
+	return nil
+}
+
+// ConsumeChallenge removes and returns an unexpired challenge of ceremony,
+// or nil when there is none. A challenge can only be consumed once.
+func (r *PasskeyRepository) ConsumeChallenge(id, ceremony string) (*models.PasskeyChallenge, error) {
+	c := models.PasskeyChallenge{ID: id, Ceremony: ceremony}
+	var userID sql.NullInt64
+	err := r.db.QueryRow(
+		"DELETE FROM passkey_challenges WHERE id = ? AND ceremony = ? RETURNING user_id, challenge, expires_at",
+		id, ceremony,
+	).Scan(&userID, &c.Challenge, &c.ExpiresAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to consume passkey challenge: %w", err)
+	}
+	if !c.ExpiresAt.After(time.Now()) {
+		return nil, nil
+	}
+	c.UserID = userID.Int64
+	return &c, nil
+}
+
+func scanPasskey(row interface{ Scan(...interface{}) error }) (*models.Passkey, error) {
+	var p models.Passkey
+	var transports string
+	var lastUsed sql.NullTime
+	if err := row.Scan(&p.ID, &p.UserID, &p.CredentialID, &p.PublicKey, &p.SignCount, &transports,
+		&p.Name, &p.BackupEligible, &p.CreatedAt, &lastUsed); err != nil {
+		return nil, err
+	}
+	if transports != "" {
+		p.Transports = strings.Split(transports, ",")
+	}
+	if lastUsed.Valid {
+		p.LastUsedAt = &lastUsed.Time
+	}
+	return &p, nil
+}
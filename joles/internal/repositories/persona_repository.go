@@ -0,0 +1,137 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"lio-ai/internal/models"
+)
+
+// PersonaRepository handles database operations for assistant personas
+type PersonaRepository struct {
+	db *sql.DB
+}
+
+// NewPersonaRepository creates a new persona repository
+func NewPersonaRepository(db *sql.DB) *PersonaRepository {
+	return &PersonaRepository{db: db}
+}
+
+// Create creates a new persona
+func (r *PersonaRepository) Create(p *models.Persona) error {
+	query := `
+		INSERT INTO personas (user_id, name, system_prompt, default_model, temperature, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`
+
+	now := time.Now()
+	result, err := r.db.Exec(query, p.UserID, p.Name, p.SystemPrompt, p.DefaultModel, p.Temperature, now, now)
+	if err != nil {
+		return fmt.Errorf("failed to create persona: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to get last insert id: %w", err)
+	}
+
+	p.ID = id
+	p.CreatedAt = now
+	p.UpdatedAt = now
+	return nil
+}
+
+// GetByID retrieves a persona by its ID, or nil if it doesn't exist
+func (r *PersonaRepository) GetByID(id int64) (*models.Persona, error) {
+	query := `
+		SELECT id, user_id, name, system_prompt, COALESCE(default_model, ''), temperature, created_at, updated_at
+		FROM personas
+		WHERE id = ?
+	`
+
+	p, err := scanPersona(r.db.QueryRow(query, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return p, err
+}
+
+// ListByUser retrieves the user's personas
+func (r *PersonaRepository) ListByUser(userID string) ([]models.Persona, error) {
+	query := `
+		SELECT id, user_id, name, system_prompt, COALESCE(default_model, ''), temperature, created_at, updated_at
+		FROM personas
+		WHERE user_id = ?
+		ORDER BY name ASC
+	`
+
+	rows, err := r.db.Query(query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get personas: %w", err)
+	}
+	defer rows.Close()
+
+	personas := make([]models.Persona, 0)
+	for rows.Next() {
+		p, err := scanPersona(rows)
+		if err != nil {
+			return nil, err
+		}
+		personas = append(personas, *p)
+	}
+
+	return personas, nil
+}
+
+// Update saves a persona's editable fields
+func (r *PersonaRepository) Update(p *models.Persona) error {
+	query := `
+		UPDATE personas
+		SET name = ?, system_prompt = ?, default_model = ?, temperature = ?, updated_at = ?
+		WHERE id = ?
+	`
+
+	now := time.Now()
+	if _, err := r.db.Exec(query, p.Name, p.SystemPrompt, p.DefaultModel, p.Temperature, now, p.ID); err != nil {
+		return fmt.Errorf("failed to update persona: %w", err)
+	}
+
+	p.UpdatedAt = now
+	return nil
+}
+
+// Delete deletes a persona and detaches it from the chats using it
+func (r *PersonaRepository) Delete(id int64) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("UPDATE chats SET persona_id = NULL WHERE persona_id = ?", id); err != nil {
+		return fmt.Errorf("failed to detach persona: %w", err)
+	}
+	if _, err := tx.Exec("DELETE FROM personas WHERE id = ?", id); err != nil {
+		return fmt.Errorf("failed to delete persona: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+// scanPersona scans a persona from a row
+func scanPersona(row interface{ Scan(...interface{}) error }) (*models.Persona, error) {
+	p := &models.Persona{}
+	var temperature sql.NullFloat64
+	err := row.Scan(&p.ID, &p.UserID, &p.Name, &p.SystemPrompt, &p.DefaultModel, &temperature, &p.CreatedAt, &p.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan persona: %w", err)
+	}
+	if temperature.Valid {
+		p.Temperature = &temperature.Float64
+	}
+	return p, nil
+}
@@ -0,0 +1,160 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"time"
+
+	"lio-ai/internal/models"
+)
+
+// PlanRepository handles plan tiers and provisions the quota limits of
+// their users
+type PlanRepository struct {
+	db *sql.DB
+}
+
+// NewPlanRepository creates a new plan repository
+func NewPlanRepository(db *sql.DB) *PlanRepository {
+	return &PlanRepository{db: db}
+}
+
+const planColumns = `name, daily_token_limit, monthly_token_limit, daily_cost_limit_usd, monthly_cost_limit_usd,
+	requests_per_minute, created_at, updated_at`
+
+// userPlanName selects the plan of the user whose ID as text is the one
+// arg; non-numeric IDs such as guests' have the default plan
+const userPlanName = `COALESCE((SELECT plan FROM users WHERE CAST(id AS TEXT) = ?), '` + models.DefaultPlan + `')`
+
+// applyUserPlanSQL sets the quota limits of a user (the second and third
+// args) to those of their plan, leaving usage as it is. Users whose plan
+// has no row keep their limits.
+const applyUserPlanSQL = `
+	UPDATE user_quotas
+	SET daily_token_limit = p.daily_token_limit,
+		monthly_token_limit = p.monthly_token_limit,
+		daily_cost_limit_usd = p.daily_cost_limit_usd,
+		monthly_cost_limit_usd = p.monthly_cost_limit_usd,
+		updated_at = ?
+	FROM plans p
+	WHERE user_quotas.user_id = ? AND p.name = ` + userPlanName
+
+// List retrieves every plan, smallest daily token limit first
+func (r *PlanRepository) List() ([]models.Plan, error) {
+	rows, err := r.db.Query("SELECT " + planColumns + " FROM plans ORDER BY daily_token_limit, name")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list plans: %w", err)
+	}
+	defer rows.Close()
+
+	plans := make([]models.Plan, 0)
+	for rows.Next() {
+		p, err := scanPlan(rows)
+		if err != nil {
+			return nil, err
+		}
+		plans = append(plans, *p)
+	}
+	return plans, rows.Err()
+}
+
+// Get retrieves a plan, or nil when there is none
+func (r *PlanRepository) Get(name string) (*models.Plan, error) {
+	p, err := scanPlan(r.db.QueryRow("SELECT "+planColumns+" FROM plans WHERE name = ?", name))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return p, err
+}
+
+// GetForUser retrieves the plan userID is on, or nil when it has no row
+func (r *PlanRepository) GetForUser(userID string) (*models.Plan, error) {
+	p, err := scanPlan(r.db.QueryRow("SELECT "+planColumns+" FROM plans WHERE name = "+userPlanName, userID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return p, err
+}
+
+// Save creates or replaces a plan
+func (r *PlanRepository) Save(p *models.Plan) error {
+	now := time.Now()
+	_, err := r.db.Exec(`
+		INSERT INTO plans (name, daily_token_limit, monthly_token_limit, daily_cost_limit_usd, monthly_cost_limit_usd,
+			requests_per_minute, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(name) DO UPDATE SET
+			daily_token_limit = excluded.daily_token_limit,
+			monthly_token_limit = excluded.monthly_token_limit,
+			daily_cost_limit_usd = excluded.daily_cost_limit_usd,
+			monthly_cost_limit_usd = excluded.monthly_cost_limit_usd,
+			requests_per_minute = excluded.requests_per_minute,
+			updated_at = excluded.updated_at
+	`, p.Name, p.DailyTokenLimit, p.MonthlyTokenLimit, p.DailyCostLimitUSD, p.MonthlyCostLimitUSD,
+		p.RequestsPerMinute, now, now)
+	if err != nil {
+		return fmt.Errorf("failed to save plan: %w", err)
+	}
+	saved, err := r.Get(p.Name)
+	if err != nil {
+		return err
+	}
+	*p = *saved
+	return nil
+}
+
+// AssignUser moves a user to a plan and sets their quota limits to its
+// own, creating their quota if they have none, in one transaction
+func (r *PlanRepository) AssignUser(userID int64, plan string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	id := strconv.FormatInt(userID, 10)
+	if _, err := tx.Exec("UPDATE users SET plan = ?, updated_at = ? WHERE id = ?", plan, now, userID); err != nil {
+		return fmt.Errorf("failed to update user plan: %w", err)
+	}
+	if _, err := tx.Exec("INSERT OR IGNORE INTO user_quotas (user_id, created_at, updated_at) VALUES (?, ?, ?)", id, now, now); err != nil {
+		return fmt.Errorf("failed to create user quota: %w", err)
+	}
+	if _, err := tx.Exec(applyUserPlanSQL, now, id, id); err != nil {
+		return fmt.Errorf("failed to apply plan limits: %w", err)
+	}
+	return tx.Commit()
+}
+
+// ApplyToUsers sets the quota limits of every user on a plan who has a
+// quota to the plan's, returning how many were updated
+func (r *PlanRepository) ApplyToUsers(plan string) (int64, error) {
+	result, err := r.db.Exec(`
+		UPDATE user_quotas
+		SET daily_token_limit = p.daily_token_limit,
+			monthly_token_limit = p.monthly_token_limit,
+			daily_cost_limit_usd = p.daily_cost_limit_usd,
+			monthly_cost_limit_usd = p.monthly_cost_limit_usd,
+			updated_at = ?
+		FROM plans p, users u
+		WHERE p.name = ? AND COALESCE(u.plan, ?) = p.name AND user_quotas.user_id = CAST(u.id AS TEXT)
+	`, time.Now(), plan, models.DefaultPlan)
+	if err != nil {
+		return 0, fmt.Errorf("failed to apply plan limits: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+func scanPlan(row interface{ Scan(...interface{}) error }) (*models.Plan, error) {
+	p := &models.Plan{}
+	err := row.Scan(&p.Name, &p.DailyTokenLimit, &p.MonthlyTokenLimit, &p.DailyCostLimitUSD, &p.MonthlyCostLimitUSD,
+		&p.RequestsPerMinute, &p.CreatedAt, &p.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan plan: %w", err)
+	}
+	return p, nil
+}
@@ -0,0 +1,55 @@
+package repositories
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"lio-ai/internal/models"
+)
+
+// PreferencesRepository stores users' account-wide settings
+type PreferencesRepository struct {
+	db *sql.DB
+}
+
+// NewPreferencesRepository creates a new preferences repository
+func NewPreferencesRepository(db *sql.DB) *PreferencesRepository {
+	return &PreferencesRepository{db: db}
+}
+
+// Get retrieves a user's preferences; nil when none are set
+func (r *PreferencesRepository) Get(userID string) (*models.UserPreferences, error) {
+	var p models.UserPreferences
+	var order string
+	err := r.db.QueryRow(
+		"SELECT user_id, provider_order, updated_at FROM user_preferences WHERE user_id = ?", userID,
+	).Scan(&p.UserID, &order, &p.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get preferences: %w", err)
+	}
+	if err := json.Unmarshal([]byte(order), &p.ProviderOrder); err != nil {
+		return nil, fmt.Errorf("failed to decode provider order: %w", err)
+	}
+	return &p, nil
+}
+
+// Set replaces a user's preferences
+func (r *PreferencesRepository) Set(p *models.UserPreferences) error {
+	order, err := json.Marshal(p.ProviderOrder)
+	if err != nil {
+		return fmt.Errorf("failed to encode provider order: %w", err)
+	}
+	p.UpdatedAt = time.Now()
+	if _, err := r.db.Exec(`
+		INSERT INTO user_preferences (user_id, provider_order, updated_at) VALUES (?, ?, ?)
+		ON CONFLICT(user_id) DO UPDATE SET provider_order = excluded.provider_order, updated_at = excluded.updated_at
+	`, p.UserID, string(order), p.UpdatedAt); err != nil {
+		return fmt.Errorf("failed to save preferences: %w", err)
+	}
+	return nil
+}
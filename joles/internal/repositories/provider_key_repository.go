@@ -11,10 +11,17 @@ import (
 	"fmt"
 	"io"
 	"lio-ai/internal/models"
+	"log"
 	"os"
 	"time"
 )
 
+// Provider key ciphertext schemes, stored in provider_api_keys.encryption_version
+const (
+	keyEncryptionLegacy = 1 // AES-GCM without additional data
+	keyEncryptionBound  = 2 // AES-GCM bound to the row's user_id and provider
+)
+
 // ProviderKeyRepository handles provider API key operations
 type ProviderKeyRepository struct {
 	db            *sql.DB
@@ -47,8 +54,14 @@ func NewProviderKeyRepository(db *sql.DB) *ProviderKeyRepository {
 	}
 }
 
-// encrypt encrypts the API key using AES-256
-func (r *ProviderKeyRepository) encrypt(plaintext string) (string, error) {
+// keyAAD binds a ciphertext to its owner so it cannot be copied into another
+// user's (or another provider's) row and decrypted there
+func keyAAD(userID, provider string) []byte {
+	return []byte("provider_api_keys:" + userID + ":" + provider)
+}
+
+// encrypt encrypts the API key using AES-256-GCM with the given additional data
+func (r *ProviderKeyRepository) encrypt(plaintext string, aad []byte) (string, error) {
 	block, err := aes.NewCipher(r.encryptionKey)
 	if err != nil {
 		return "", err
@@ -64,12 +77,12 @@ func (r *ProviderKeyRepository) encrypt(plaintext string) (string, error) {
 		return "", err
 	}
 
-	ciphertext := gcm.Seal(nonce, nonce, []byte(plaintext), nil)
+	ciphertext := gcm.Seal(nonce, nonce, []byte(plaintext), aad)
 	return base64.StdEncoding.EncodeToString(ciphertext), nil
 }
 
-// decrypt decrypts the API key
-func (r *ProviderKeyRepository) decrypt(ciphertext string) (string, error) {
+// decrypt decrypts the API key; aad must match what it was encrypted with
+func (r *ProviderKeyRepository) decrypt(ciphertext string, aad []byte) (string, error) {
 	data, err := base64.StdEncoding.DecodeString(ciphertext)
 	if err != nil {
 		return "", err
@@ -91,7 +104,7 @@ func (r *ProviderKeyRepository) decrypt(ciphertext string) (string, error) {
 	}
 
 	nonce, ciphertextBytes := data[:nonceSize], data[nonceSize:]
-	plaintext, err := gcm.Open(nil, nonce, ciphertextBytes, nil)
+	plaintext, err := gcm.Open(nil, nonce, ciphertextBytes, aad)
 	if err != nil {
 		return "", err
 	}
@@ -99,9 +112,72 @@ func (r *ProviderKeyRepository) decrypt(ciphertext string) (string, error) {
 	return string(plaintext), nil
 }
 
+// decryptVersion decrypts a stored key according to its encryption version
+func (r *ProviderKeyRepository) decryptVersion(ciphertext string, version int, userID, provider string) (string, error) {
+	if version < keyEncryptionBound {
+		return r.decrypt(ciphertext, nil)
+	}
+	return r.decrypt(ciphertext, keyAAD(userID, provider))
+}
+
+// ReencryptLegacyKeys rewrites keys stored without additional data so they
+// are bound to their user and provider. It is safe to run repeatedly and
+// returns the number of keys re-encrypted.
+func (r *ProviderKeyRepository) ReencryptLegacyKeys() (int, error) {
+	rows, err := r.db.Query(`
+		SELECT id, user_id, provider, api_key_encrypted
+		FROM provider_api_keys
+		WHERE encryption_version < ?
+	`, keyEncryptionBound)
+	if err != nil {
+		return 0, err
+	}
+
+	type legacyKey struct {
+		id                          int64
+		userID, provider, encrypted string
+	}
+	var legacy []legacyKey
+	for rows.Next() {
+		var k legacyKey
+		if err := rows.Scan(&k.id, &k.userID, &k.provider, &k.encrypted); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		legacy = append(legacy, k)
+	}
+	rows.Close()
+
+	migrated := 0
+	for _, k := range legacy {
+		plaintext, err := r.decrypt(k.encrypted, nil)
+		if err != nil {
+			log.Printf("⚠️  Could not re-encrypt provider key %d (%s): %v", k.id, k.provider, err)
+			continue
+		}
+		encrypted, err := r.encrypt(plaintext, keyAAD(k.userID, k.provider))
+		if err != nil {
+			return migrated, fmt.Errorf("failed to encrypt API key: %w", err)
+		}
+
+		// Guard on the version so a concurrent Create isn't overwritten
+		_, err = r.db.Exec(`
+			UPDATE provider_api_keys
+			SET api_key_encrypted = ?, encryption_version = ?
+			WHERE id = ? AND encryption_version < ?
+		`, encrypted, keyEncryptionBound, k.id, keyEncryptionBound)
+		if err != nil {
+			return migrated, err
+		}
+		migrated++
+	}
+
+	return migrated, nil
+}
+
 // Create creates or updates a provider API key for a user
 func (r *ProviderKeyRepository) Create(key *models.ProviderAPIKey) error {
-	encrypted, err := r.encrypt(key.APIKey)
+	encrypted, err := r.encrypt(key.APIKey, keyAAD(key.UserID, key.Provider))
 	if err != nil {
 		return fmt.Errorf("failed to encrypt API key: %w", err)
 	}
@@ -113,17 +189,18 @@ func (r *ProviderKeyRepository) Create(key *models.ProviderAPIKey) error {
 	}
 
 	query := `
-		INSERT INTO provider_api_keys (user_id, provider, api_key_encrypted, models_enabled, is_active, created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?)
+		INSERT INTO provider_api_keys (user_id, provider, api_key_encrypted, encryption_version, models_enabled, is_active, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
 		ON CONFLICT(user_id, provider) DO UPDATE SET
 			api_key_encrypted = excluded.api_key_encrypted,
+			encryption_version = excluded.encryption_version,
 			models_enabled = excluded.models_enabled,
 			is_active = 1,
 			updated_at = excluded.updated_at
 	`
 
 	now := time.Now()
-	result, err := r.db.Exec(query, key.UserID, key.Provider, encrypted, modelsJSON, true, now, now)
+	result, err := r.db.Exec(query, key.UserID, key.Provider, encrypted, keyEncryptionBound, modelsJSON, true, now, now)
 	if err != nil {
 		return err
 	}
@@ -139,7 +216,7 @@ func (r *ProviderKeyRepository) Create(key *models.ProviderAPIKey) error {
 // GetByUserAndProvider gets a specific provider key for a user
 func (r *ProviderKeyRepository) GetByUserAndProvider(userID, provider string) (*models.ProviderAPIKey, error) {
 	query := `
-		SELECT id, user_id, provider, api_key_encrypted, models_enabled, is_active, last_used_at, created_at, updated_at
+		SELECT id, user_id, provider, api_key_encrypted, encryption_version, models_enabled, is_active, last_used_at, created_at, updated_at
 		FROM provider_api_keys
 		WHERE user_id = ? AND provider = ? AND is_active = 1
 	`
@@ -147,9 +224,10 @@ func (r *ProviderKeyRepository) GetByUserAndProvider(userID, provider string) (*
 	key := &models.ProviderAPIKey{}
 	var lastUsedAt sql.NullTime
 	var modelsEnabled string
+	var encryptionVersion int
 
 	err := r.db.QueryRow(query, userID, provider).Scan(
-		&key.ID, &key.UserID, &key.Provider, &key.APIKeyEncrypted,
+		&key.ID, &key.UserID, &key.Provider, &key.APIKeyEncrypted, &encryptionVersion,
 		&modelsEnabled, &key.IsActive, &lastUsedAt, &key.CreatedAt, &key.UpdatedAt,
 	)
 
@@ -166,7 +244,7 @@ func (r *ProviderKeyRepository) GetByUserAndProvider(userID, provider string) (*
 	key.ModelsEnabled = modelsEnabled
 
 	// Decrypt the API key
-	decrypted, err := r.decrypt(key.APIKeyEncrypted)
+	decrypted, err := r.decryptVersion(key.APIKeyEncrypted, encryptionVersion, key.UserID, key.Provider)
 	if err != nil {
 		return nil, fmt.Errorf("failed to decrypt API key: %w", err)
 	}
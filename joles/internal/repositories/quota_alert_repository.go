@@ -0,0 +1,134 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"lio-ai/internal/models"
+)
+
+// QuotaAlertRepository handles users' quota alert settings and the alerts
+// raised for them
+type QuotaAlertRepository struct {
+	db *sql.DB
+}
+
+// NewQuotaAlertRepository creates a new quota alert repository
+func NewQuotaAlertRepository(db *sql.DB) *QuotaAlertRepository {
+	return &QuotaAlertRepository{db: db}
+}
+
+// GetSettings retrieves a user's alert settings, or the defaults when they
+// have none
+func (r *QuotaAlertRepository) GetSettings(userID string) (*models.QuotaAlertSettings, error) {
+	var thresholds string
+	var updatedAt time.Time
+	settings := &models.QuotaAlertSettings{}
+	err := r.db.QueryRow("SELECT thresholds, webhook, email, updated_at FROM quota_alert_settings WHERE user_id = ?", userID).
+		Scan(&thresholds, &settings.Webhook, &settings.Email, &updatedAt)
+	if err == sql.ErrNoRows {
+		return &models.QuotaAlertSettings{
+			Thresholds: append([]int(nil), models.DefaultQuotaAlertThresholds...),
+			Webhook:    true,
+		}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get quota alert settings: %w", err)
+	}
+	settings.Thresholds = splitThresholds(thresholds)
+	settings.UpdatedAt = &updatedAt
+	return settings, nil
+}
+
+// SaveSettings stores a user's alert settings
+func (r *QuotaAlertRepository) SaveSettings(userID string, settings *models.QuotaAlertSettings) error {
+	now := time.Now()
+	parts := make([]string, len(settings.Thresholds))
+	for i, t := range settings.Thresholds {
+		parts[i] = strconv.Itoa(t)
+	}
+	_, err := r.db.Exec(`
+		INSERT INTO quota_alert_settings (user_id, thresholds, webhook, email, updated_at)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(user_id) DO UPDATE SET
+			thresholds = excluded.thresholds, webhook = excluded.webhook,
+			email = excluded.email, updated_at = excluded.updated_at
+	`, userID, strings.Join(parts, ","), settings.Webhook, settings.Email, now)
+	if err != nil {
+		return fmt.Errorf("failed to save quota alert settings: %w", err)
+	}
+	settings.UpdatedAt = &now
+	return nil
+}
+
+// Record stores an alert unless one was already raised for its metric and
+// threshold in the same period, reporting whether it was stored
+func (r *QuotaAlertRepository) Record(alert *models.QuotaAlert) (bool, error) {
+	now := time.Now()
+	result, err := r.db.Exec(`
+		INSERT OR IGNORE INTO quota_alerts (user_id, metric, threshold, used, limit_value, period_start, channels, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`, alert.UserID, alert.Metric, alert.Threshold, alert.Used, alert.Limit, alert.PeriodStart,
+		strings.Join(alert.Channels, ","), now)
+	if err != nil {
+		return false, fmt.Errorf("failed to record quota alert: %w", err)
+	}
+	if n, err := result.RowsAffected(); err != nil || n == 0 {
+		return false, err
+	}
+	alert.ID, err = result.LastInsertId()
+	alert.CreatedAt = now
+	return true, err
+}
+
+// ListByUser retrieves a user's alerts, newest first
+func (r *QuotaAlertRepository) ListByUser(userID string, limit, offset int) ([]models.QuotaAlert, error) {
+	rows, err := r.db.Query(`
+		SELECT id, user_id, metric, threshold, used, limit_value, period_start, COALESCE(channels, ''), created_at
+		FROM quota_alerts
+		WHERE user_id = ?
+		ORDER BY created_at DESC, id DESC
+		LIMIT ? OFFSET ?
+	`, userID, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get quota alerts: %w", err)
+	}
+	defer rows.Close()
+
+	alerts := make([]models.QuotaAlert, 0)
+	for rows.Next() {
+		var a models.QuotaAlert
+		var channels string
+		if err := rows.Scan(&a.ID, &a.UserID, &a.Metric, &a.Threshold, &a.Used, &a.Limit, &a.PeriodStart, &channels, &a.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan quota alert: %w", err)
+		}
+		a.Channels = []string{}
+		if channels != "" {
+			a.Channels = strings.Split(channels, ",")
+		}
+		alerts = append(alerts, a)
+	}
+	return alerts, rows.Err()
+}
+
+// CountByUser counts a user's alerts
+func (r *QuotaAlertRepository) CountByUser(userID string) (int, error) {
+	var count int
+	if err := r.db.QueryRow("SELECT COUNT(*) FROM quota_alerts WHERE user_id = ?", userID).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count quota alerts: %w", err)
+	}
+	return count, nil
+}
+
+func splitThresholds(s string) []int {
+	thresholds := make([]int, 0)
+	for _, part := range strings.Split(s, ",") {
+		if t, err := strconv.Atoi(strings.TrimSpace(part)); err == nil {
+			thresholds = append(thresholds, t)
+		}
+	}
+	return thresholds
+}
@@ -0,0 +1,103 @@
+package repositories
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"lio-ai/internal/models"
+)
+
+// ResponsePluginRepository stores users' response post-processing settings
+type ResponsePluginRepository struct {
+	db *sql.DB
+}
+
+// NewResponsePluginRepository creates a new response plugin repository
+func NewResponsePluginRepository(db *sql.DB) *ResponsePluginRepository {
+	return &ResponsePluginRepository{db: db}
+}
+
+// Get retrieves a user's plugin chain for a persona, or their default with
+// personaID 0; nil when none is set
+func (r *ResponsePluginRepository) Get(userID string, personaID int64) (*models.ResponsePluginSettings, error) {
+	s, err := scanResponsePluginSettings(r.db.QueryRow(
+		"SELECT user_id, persona_id, plugins, updated_at FROM response_plugins WHERE user_id = ? AND persona_id = ?",
+		userID, personaID,
+	))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get response plugins: %w", err)
+	}
+	return s, nil
+}
+
+// ListByUser retrieves all of a user's plugin chains, the default first
+func (r *ResponsePluginRepository) ListByUser(userID string) ([]models.ResponsePluginSettings, error) {
+	rows, err := r.db.Query(
+		"SELECT user_id, persona_id, plugins, updated_at FROM response_plugins WHERE user_id = ? ORDER BY persona_id",
+		userID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list response plugins: %w", err)
+	}
+	defer rows.Close()
+
+	settings := make([]models.ResponsePluginSettings, 0)
+	for rows.Next() {
+		s, err := scanResponsePluginSettings(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan response plugins: %w", err)
+		}
+		settings = append(settings, *s)
+	}
+	return settings, rows.Err()
+}
+
+// Set replaces a user's plugin chain for a persona, or their default
+func (r *ResponsePluginRepository) Set(s *models.ResponsePluginSettings) error {
+	plugins, err := json.Marshal(s.Plugins)
+	if err != nil {
+		return fmt.Errorf("failed to encode response plugins: %w", err)
+	}
+	s.UpdatedAt = time.Now()
+	if _, err := r.db.Exec(`
+		INSERT INTO response_plugins (user_id, persona_id, plugins, updated_at) VALUES (?, ?, ?, ?)
+		ON CONFLICT(user_id, persona_id) DO UPDATE SET plugins = excluded.plugins, updated_at = excluded.updated_at
+	`, s.UserID, s.PersonaID, string(plugins), s.UpdatedAt); err != nil {
+		return fmt.Errorf("failed to save response plugins: %w", err)
+	}
+	return nil
+}
+
+// Delete removes a user's plugin chain for a persona, reporting whether
+// there was one
+func (r *ResponsePluginRepository) Delete(userID string, personaID int64) (bool, error) {
+	result, err := r.db.Exec("DELETE FROM response_plugins WHERE user_id = ? AND persona_id = ?", userID, personaID)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete response plugins: %w", err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to delete response plugins: %w", err)
+	}
+	return n > 0, nil
+}
+
+func scanResponsePluginSettings(row interface{ Scan(...interface{}) error }) (*models.ResponsePluginSettings, error) {
+	var s models.ResponsePluginSettings
+	var plugins string
+	if err := row.Scan(&s.UserID, &s.PersonaID, &plugins, &s.UpdatedAt); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(plugins), &s.Plugins); err != nil {
+		return nil, err
+	}
+	if s.Plugins == nil {
+		s.Plugins = []models.ResponsePluginConfig{}
+	}
+	return &s, nil
+}
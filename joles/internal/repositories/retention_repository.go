@@ -0,0 +1,102 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"lio-ai/internal/models"
+)
+
+// RetentionRepository expires request logs and erases user content
+type RetentionRepository struct {
+	db *sql.DB
+}
+
+// NewRetentionRepository creates a new retention repository
+func NewRetentionRepository(db *sql.DB) *RetentionRepository {
+	return &RetentionRepository{db: db}
+}
+
+// DeleteUsageMetricsBefore deletes usage log rows recorded before cutoff
+func (r *RetentionRepository) DeleteUsageMetricsBefore(cutoff time.Time) (int64, error) {
+	result, err := r.db.Exec("DELETE FROM usage_metrics WHERE created_at < ?", cutoff)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete usage metrics: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// ClearErrorBodiesBefore removes the error messages of usage log rows
+// recorded before cutoff, keeping the rows themselves
+func (r *RetentionRepository) ClearErrorBodiesBefore(cutoff time.Time) (int64, error) {
+	result, err := r.db.Exec("UPDATE usage_metrics SET error_message = NULL WHERE error_message IS NOT NULL AND created_at < ?", cutoff)
+	if err != nil {
+		return 0, fmt.Errorf("failed to clear usage error messages: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// DeleteDocumentAccessBefore deletes confidential document reads recorded
+// before cutoff
+func (r *RetentionRepository) DeleteDocumentAccessBefore(cutoff time.Time) (int64, error) {
+	result, err := r.db.Exec("DELETE FROM document_access_log WHERE accessed_at < ?", cutoff)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete document access log: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// PurgeUserContent scrubs everything the user wrote, or that was written
+// back to them, in one transaction. Rows are kept so chat structure,
+// ratings and usage totals stay consistent; only their contents go.
+func (r *RetentionRepository) PurgeUserContent(userID string) (*models.PurgeReport, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	report := &models.PurgeReport{UserID: userID}
+	steps := []struct {
+		count *int64
+		query string
+		args  []interface{}
+	}{
+		{&report.MessagesScrubbed, `
+			UPDATE messages SET content = '', tool_calls = NULL, moderation = NULL
+			WHERE chat_id IN (SELECT id FROM chats WHERE user_id = ?)`, nil},
+		{&report.ChatsScrubbed, `
+			UPDATE chats SET title = '[purged]', summary = NULL, summary_model = NULL, summary_at = NULL,
+				summary_action_items = NULL, summary_stale = 1
+			WHERE user_id = ?`, nil},
+		{&report.ScheduledCancelled, `
+			UPDATE scheduled_messages SET content = '', last_error = NULL,
+				status = CASE WHEN status = ? THEN ? ELSE status END
+			WHERE user_id = ?`, []interface{}{models.ScheduleStatusPending, models.ScheduleStatusCancelled}},
+		{&report.FeedbackCommentsRemoved, `
+			UPDATE message_feedback SET comment = NULL WHERE user_id = ? AND comment IS NOT NULL`, nil},
+		{&report.ErrorBodiesRemoved, `
+			UPDATE usage_metrics SET error_message = NULL WHERE user_id = ? AND error_message IS NOT NULL`, nil},
+		{&report.SecurityExcerptsRemoved, `
+			UPDATE security_events SET excerpt = NULL WHERE user_id = ? AND excerpt IS NOT NULL`, nil},
+		{&report.IdempotencyKeysRemoved, `
+			DELETE FROM idempotency_keys WHERE user_id = ?`, nil},
+		{&report.ChangeEventsRemoved, `
+			DELETE FROM change_events WHERE user_id = ?`, nil},
+	}
+	for _, step := range steps {
+		result, err := tx.Exec(step.query, append(step.args, userID)...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to purge user content: %w", err)
+		}
+		if *step.count, err = result.RowsAffected(); err != nil {
+			return nil, fmt.Errorf("failed to purge user content: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit purge: %w", err)
+	}
+	return report, nil
+}
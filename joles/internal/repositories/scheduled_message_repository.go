@@ -0,0 +1,174 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"lio-ai/internal/models"
+)
+
+// ScheduledMessageRepository handles database operations for scheduled messages
+type ScheduledMessageRepository struct {
+	db *sql.DB
+}
+
+// NewScheduledMessageRepository creates a new scheduled message repository
+func NewScheduledMessageRepository(db *sql.DB) *ScheduledMessageRepository {
+	return &ScheduledMessageRepository{db: db}
+}
+
+// Create queues a scheduled message
+func (r *ScheduledMessageRepository) Create(m *models.ScheduledMessage) error {
+	query := `
+		INSERT INTO scheduled_messages (chat_id, user_id, content, model, scheduled_at, status, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`
+
+	now := time.Now()
+	m.Status = models.ScheduleStatusPending
+	result, err := r.db.Exec(query, m.ChatID, m.UserID, m.Content, m.Model, m.ScheduledAt.UTC(), m.Status, now, now)
+	if err != nil {
+		return fmt.Errorf("failed to schedule message: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to get last insert id: %w", err)
+	}
+
+	m.ID = id
+	m.CreatedAt = now
+	m.UpdatedAt = now
+	return nil
+}
+
+// GetByID retrieves a scheduled message by its ID
+func (r *ScheduledMessageRepository) GetByID(id int64) (*models.ScheduledMessage, error) {
+	messages, err := r.list(`WHERE id = ?`, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(messages) == 0 {
+		return nil, fmt.Errorf("scheduled message not found")
+	}
+	return &messages[0], nil
+}
+
+// GetByChatID retrieves a chat's scheduled messages, soonest first
+func (r *ScheduledMessageRepository) GetByChatID(chatID int64) ([]models.ScheduledMessage, error) {
+	return r.list(`WHERE chat_id = ? ORDER BY scheduled_at ASC`, chatID)
+}
+
+// ClaimDue marks up to limit due messages as running and returns them.
+// Each row is claimed with a conditional update so that two schedulers
+// never run the same message.
+func (r *ScheduledMessageRepository) ClaimDue(now time.Time, limit int) ([]models.ScheduledMessage, error) {
+	due, err := r.list(`WHERE status = ? AND scheduled_at <= ? ORDER BY scheduled_at ASC LIMIT ?`,
+		models.ScheduleStatusPending, now.UTC(), limit)
+	if err != nil {
+		return nil, err
+	}
+
+	claimed := make([]models.ScheduledMessage, 0, len(due))
+	for _, m := range due {
+		result, err := r.db.Exec(`
+			UPDATE scheduled_messages
+			SET status = ?, attempts = attempts + 1, updated_at = ?
+			WHERE id = ? AND status = ?
+		`, models.ScheduleStatusRunning, time.Now(), m.ID, models.ScheduleStatusPending)
+		if err != nil {
+			return claimed, fmt.Errorf("failed to claim scheduled message: %w", err)
+		}
+		if n, _ := result.RowsAffected(); n == 1 {
+			m.Status = models.ScheduleStatusRunning
+			m.Attempts++
+			claimed = append(claimed, m)
+		}
+	}
+	return claimed, nil
+}
+
+// MarkCompleted records the assistant reply for a scheduled message
+func (r *ScheduledMessageRepository) MarkCompleted(id, messageID int64) error {
+	_, err := r.db.Exec(`
+		UPDATE scheduled_messages SET status = ?, message_id = ?, last_error = NULL, updated_at = ?
+		WHERE id = ?
+	`, models.ScheduleStatusCompleted, messageID, time.Now(), id)
+	if err != nil {
+		return fmt.Errorf("failed to update scheduled message: %w", err)
+	}
+	return nil
+}
+
+// MarkFailed records a failed run
+func (r *ScheduledMessageRepository) MarkFailed(id int64, lastError string) error {
+	_, err := r.db.Exec(`
+		UPDATE scheduled_messages SET status = ?, last_error = ?, updated_at = ?
+		WHERE id = ?
+	`, models.ScheduleStatusFailed, lastError, time.Now(), id)
+	if err != nil {
+		return fmt.Errorf("failed to update scheduled message: %w", err)
+	}
+	return nil
+}
+
+// Cancel cancels a scheduled message that has not started yet
+func (r *ScheduledMessageRepository) Cancel(id int64) (bool, error) {
+	result, err := r.db.Exec(`
+		UPDATE scheduled_messages SET status = ?, updated_at = ?
+		WHERE id = ? AND status = ?
+	`, models.ScheduleStatusCancelled, time.Now(), id, models.ScheduleStatusPending)
+	if err != nil {
+		return false, fmt.Errorf("failed to cancel scheduled message: %w", err)
+	}
+	n, err := result.RowsAffected()
+	return n == 1, err
+}
+
+// FailInterrupted marks messages left running by a previous process as
+// failed. They may already have posted their prompt, so re-running them
+// could duplicate it.
+func (r *ScheduledMessageRepository) FailInterrupted() (int64, error) {
+	result, err := r.db.Exec(`
+		UPDATE scheduled_messages SET status = ?, last_error = ?, updated_at = ?
+		WHERE status = ?
+	`, models.ScheduleStatusFailed, "interrupted by server restart", time.Now(), models.ScheduleStatusRunning)
+	if err != nil {
+		return 0, fmt.Errorf("failed to update interrupted scheduled messages: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+func (r *ScheduledMessageRepository) list(clause string, args ...interface{}) ([]models.ScheduledMessage, error) {
+	query := `
+		SELECT id, chat_id, user_id, content, COALESCE(model, ''), scheduled_at, status, attempts,
+		       COALESCE(last_error, ''), message_id, created_at, updated_at
+		FROM scheduled_messages
+	` + clause
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get scheduled messages: %w", err)
+	}
+	defer rows.Close()
+
+	messages := make([]models.ScheduledMessage, 0)
+	for rows.Next() {
+		var m models.ScheduledMessage
+		var messageID sql.NullInt64
+		err := rows.Scan(
+			&m.ID, &m.ChatID, &m.UserID, &m.Content, &m.Model, &m.ScheduledAt, &m.Status, &m.Attempts,
+			&m.LastError, &messageID, &m.CreatedAt, &m.UpdatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan scheduled message: %w", err)
+		}
+		if messageID.Valid {
+			m.MessageID = &messageID.Int64
+		}
+		messages = append(messages, m)
+	}
+
+	return messages, nil
+}
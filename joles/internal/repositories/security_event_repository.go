@@ -0,0 +1,122 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"lio-ai/internal/models"
+)
+
+// SecurityEventRepository handles database operations for security events
+type SecurityEventRepository struct {
+	db *sql.DB
+}
+
+// NewSecurityEventRepository creates a new security event repository
+func NewSecurityEventRepository(db *sql.DB) *SecurityEventRepository {
+	return &SecurityEventRepository{db: db}
+}
+
+// Create records a security event
+func (r *SecurityEventRepository) Create(e *models.SecurityEvent) error {
+	query := `
+		INSERT INTO security_events (user_id, chat_id, event_type, severity, pattern, excerpt, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`
+
+	now := time.Now()
+	result, err := r.db.Exec(query, e.UserID, e.ChatID, e.EventType, e.Severity, e.Pattern, e.Excerpt, now)
+	if err != nil {
+		return fmt.Errorf("failed to create security event: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to get last insert id: %w", err)
+	}
+
+	e.ID = id
+	e.CreatedAt = now
+	return nil
+}
+
+// List retrieves events matching the filter, newest first, with the total
+// number of matches
+func (r *SecurityEventRepository) List(filter models.SecurityEventFilter) ([]models.SecurityEvent, int, error) {
+	var where []string
+	var args []interface{}
+	if filter.UserID != "" {
+		where = append(where, "user_id = ?")
+		args = append(args, filter.UserID)
+	}
+	if severities := models.SeveritiesAtLeast(filter.MinSeverity); severities != nil {
+		where = append(where, "severity IN ("+strings.TrimSuffix(strings.Repeat("?,", len(severities)), ",")+")")
+		for _, severity := range severities {
+			args = append(args, severity)
+		}
+	}
+	if !filter.Since.IsZero() {
+		where = append(where, "created_at >= ?")
+		args = append(args, filter.Since)
+	}
+	clause := ""
+	if len(where) > 0 {
+		clause = "WHERE " + strings.Join(where, " AND ")
+	}
+
+	var total int
+	if err := r.db.QueryRow("SELECT COUNT(*) FROM security_events "+clause, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count security events: %w", err)
+	}
+
+	query := `
+		SELECT id, user_id, chat_id, event_type, severity, pattern, COALESCE(excerpt, ''), created_at
+		FROM security_events
+		` + clause + `
+		ORDER BY created_at DESC, id DESC
+		LIMIT ? OFFSET ?
+	`
+	rows, err := r.db.Query(query, append(args, filter.Limit, filter.Offset)...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list security events: %w", err)
+	}
+	defer rows.Close()
+
+	events := make([]models.SecurityEvent, 0)
+	for rows.Next() {
+		var e models.SecurityEvent
+		if err := rows.Scan(&e.ID, &e.UserID, &e.ChatID, &e.EventType, &e.Severity, &e.Pattern, &e.Excerpt, &e.CreatedAt); err != nil {
+			return nil, 0, fmt.Errorf("failed to scan security event: %w", err)
+		}
+		events = append(events, e)
+	}
+	return events, total, rows.Err()
+}
+
+// HasRecent reports whether the user has an event of at least minSeverity
+// since the given time
+func (r *SecurityEventRepository) HasRecent(userID, minSeverity string, since time.Time) (bool, error) {
+	severities := models.SeveritiesAtLeast(minSeverity)
+	if severities == nil {
+		return false, nil
+	}
+
+	query := `
+		SELECT EXISTS (
+			SELECT 1 FROM security_events
+			WHERE user_id = ? AND created_at >= ? AND severity IN (` + strings.TrimSuffix(strings.Repeat("?,", len(severities)), ",") + `)
+		)
+	`
+	args := []interface{}{userID, since}
+	for _, severity := range severities {
+		args = append(args, severity)
+	}
+
+	var exists bool
+	if err := r.db.QueryRow(query, args...).Scan(&exists); err != nil {
+		return false, fmt.Errorf("failed to check security events: %w", err)
+	}
+	return exists, nil
+}
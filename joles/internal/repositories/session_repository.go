@@ -0,0 +1,116 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"lio-ai/internal/models"
+)
+
+// SessionRepository handles users' logins on their devices
+type SessionRepository struct {
+	db *sql.DB
+}
+
+// NewSessionRepository creates a new session repository
+func NewSessionRepository(db *sql.DB) *SessionRepository {
+	return &SessionRepository{db: db}
+}
+
+// Create records a session
+func (r *SessionRepository) Create(s *models.Session) error {
+	s.CreatedAt = time.Now()
+	result, err := r.db.Exec(`
+		INSERT INTO sessions (user_id, token_id, ip_address, user_agent, expires_at, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, s.UserID, s.TokenID, s.IPAddress, s.UserAgent, s.ExpiresAt, s.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create session: %w", err)
+	}
+	s.ID, err = result.LastInsertId()
+	return err
+}
+
+// ListActive retrieves a user's sessions that have not expired, latest
+// first
+func (r *SessionRepository) ListActive(userID int64, now time.Time) ([]models.Session, error) {
+	rows, err := r.db.Query(`
+		SELECT id, user_id, token_id, ip_address, user_agent, expires_at, created_at
+		FROM sessions WHERE user_id = ? AND expires_at > ?
+		ORDER BY created_at DESC, id DESC
+	`, userID, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sessions: %w", err)
+	}
+	defer rows.Close()
+
+	sessions := make([]models.Session, 0)
+	for rows.Next() {
+		var s models.Session
+		if err := rows.Scan(&s.ID, &s.UserID, &s.TokenID, &s.IPAddress, &s.UserAgent, &s.ExpiresAt, &s.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan session: %w", err)
+		}
+		sessions = append(sessions, s)
+	}
+	return sessions, rows.Err()
+}
+
+// Terminate ends one of a user's sessions, revoking its token, and reports
+// whether there was one
+func (r *SessionRepository) Terminate(userID, id int64) (bool, error) {
+	n, err := r.terminate("user_id = ? AND id = ?", userID, id)
+	return n > 0, err
+}
+
+// TerminateOthers ends a user's sessions other than the one of the token
+// keepTokenID, revoking their tokens, and returns how many were ended
+func (r *SessionRepository) TerminateOthers(userID int64, keepTokenID string) (int64, error) {
+	return r.terminate("user_id = ? AND token_id != ?", userID, keepTokenID)
+}
+
+// DeleteByTokenID deletes the session of a token revoked otherwise, e.g. on
+// logout
+func (r *SessionRepository) DeleteByTokenID(tokenID string) error {
+	if _, err := r.db.Exec("DELETE FROM sessions WHERE token_id = ?", tokenID); err != nil {
+		return fmt.Errorf("failed to delete session: %w", err)
+	}
+	return nil
+}
+
+// TerminateAll ends all of a user's sessions, revoking their tokens, e.g.
+// on a password change
+func (r *SessionRepository) TerminateAll(userID int64) error {
+	_, err := r.terminate("user_id = ?", userID)
+	return err
+}
+
+// terminate revokes the tokens of the sessions matching where and deletes
+// them, in one transaction
+func (r *SessionRepository) terminate(where string, args ...interface{}) (int64, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`
+		INSERT INTO revoked_tokens (jti, user_id, expires_at, revoked_at)
+		SELECT token_id, CAST(user_id AS TEXT), expires_at, ? FROM sessions WHERE `+where+`
+		ON CONFLICT(jti) DO NOTHING
+	`, append([]interface{}{time.Now()}, args...)...); err != nil {
+		return 0, fmt.Errorf("failed to revoke session tokens: %w", err)
+	}
+	result, err := tx.Exec("DELETE FROM sessions WHERE "+where, args...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete sessions: %w", err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit session termination: %w", err)
+	}
+	return n, nil
+}
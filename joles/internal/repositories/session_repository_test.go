@@ -0,0 +1,129 @@
+package repositories
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"lio-ai/internal/models"
+)
+
+func createTestSessions(t *testing.T, repo *SessionRepository, userID int64, tokenIDs ...string) []*models.Session {
+	t.Helper()
+	sessions := make([]*models.Session, 0, len(tokenIDs))
+	for _, tokenID := range tokenIDs {
+		s := &models.Session{UserID: userID, TokenID: tokenID, UserAgent: tokenID, ExpiresAt: time.Now().Add(time.Hour)}
+		if err := repo.Create(s); err != nil {
+			t.Fatalf("Create session %s: %v", tokenID, err)
+		}
+		sessions = append(sessions, s)
+	}
+	return sessions
+}
+
+func assertRevoked(t *testing.T, revocations *TokenRevocationRepository, userID string, want map[string]bool) {
+	t.Helper()
+	for tokenID, wantRevoked := range want {
+		revoked, err := revocations.IsRevoked(tokenID, userID, time.Now())
+		if err != nil {
+			t.Fatalf("IsRevoked(%s): %v", tokenID, err)
+		}
+		if revoked != wantRevoked {
+			t.Errorf("IsRevoked(%s) = %v, want %v", tokenID, revoked, wantRevoked)
+		}
+	}
+}
+
+func activeTokenIDs(t *testing.T, repo *SessionRepository, userID int64) map[string]bool {
+	t.Helper()
+	sessions, err := repo.ListActive(userID, time.Now())
+	if err != nil {
+		t.Fatalf("ListActive: %v", err)
+	}
+	ids := make(map[string]bool, len(sessions))
+	for _, s := range sessions {
+		ids[s.TokenID] = true
+	}
+	return ids
+}
+
+func TestTerminateRevokesTokenAndDeletesSession(t *testing.T) {
+	conn := newTestDB(t)
+	alice := createTestUser(t, conn, "alice", "user")
+	bob := createTestUser(t, conn, "bob", "user")
+	repo := NewSessionRepository(conn)
+	revocations := NewTokenRevocationRepository(conn)
+	sessions := createTestSessions(t, repo, alice.ID, "laptop", "phone")
+	bobs := createTestSessions(t, repo, bob.ID, "bob-laptop")
+
+	// Another user's session is not found, and stays
+	found, err := repo.Terminate(alice.ID, bobs[0].ID)
+	if err != nil {
+		t.Fatalf("Terminate: %v", err)
+	}
+	if found {
+		t.Errorf("Terminate found another user's session")
+	}
+	assertRevoked(t, revocations, fmt.Sprint(bob.ID), map[string]bool{"bob-laptop": false})
+	if !activeTokenIDs(t, repo, bob.ID)["bob-laptop"] {
+		t.Errorf("another user's session was deleted")
+	}
+
+	found, err = repo.Terminate(alice.ID, sessions[0].ID)
+	if err != nil {
+		t.Fatalf("Terminate: %v", err)
+	}
+	if !found {
+		t.Fatalf("Terminate did not find the session")
+	}
+	assertRevoked(t, revocations, fmt.Sprint(alice.ID), map[string]bool{"laptop": true, "phone": false})
+	if active := activeTokenIDs(t, repo, alice.ID); active["laptop"] || !active["phone"] {
+		t.Errorf("active sessions = %v, want only phone", active)
+	}
+
+	// The session is gone, so a second attempt finds nothing
+	if found, err := repo.Terminate(alice.ID, sessions[0].ID); err != nil || found {
+		t.Errorf("Terminate again = %v, %v, want false", found, err)
+	}
+}
+
+func TestTerminateOthersKeepsCurrentSession(t *testing.T) {
+	conn := newTestDB(t)
+	alice := createTestUser(t, conn, "alice", "user")
+	bob := createTestUser(t, conn, "bob", "user")
+	repo := NewSessionRepository(conn)
+	revocations := NewTokenRevocationRepository(conn)
+	createTestSessions(t, repo, alice.ID, "laptop", "phone", "tablet")
+	createTestSessions(t, repo, bob.ID, "bob-laptop")
+
+	n, err := repo.TerminateOthers(alice.ID, "laptop")
+	if err != nil {
+		t.Fatalf("TerminateOthers: %v", err)
+	}
+	if n != 2 {
+		t.Errorf("TerminateOthers ended %d sessions, want 2", n)
+	}
+	assertRevoked(t, revocations, fmt.Sprint(alice.ID), map[string]bool{"laptop": false, "phone": true, "tablet": true})
+	assertRevoked(t, revocations, fmt.Sprint(bob.ID), map[string]bool{"bob-laptop": false})
+	if active := activeTokenIDs(t, repo, alice.ID); len(active) != 1 || !active["laptop"] {
+		t.Errorf("active sessions = %v, want only laptop", active)
+	}
+	if !activeTokenIDs(t, repo, bob.ID)["bob-laptop"] {
+		t.Errorf("another user's session was ended")
+	}
+}
+
+func TestTerminateAllRevokesEverySession(t *testing.T) {
+	conn := newTestDB(t)
+	alice := createTestUser(t, conn, "alice", "user")
+	repo := NewSessionRepository(conn)
+	createTestSessions(t, repo, alice.ID, "laptop", "phone")
+
+	if err := repo.TerminateAll(alice.ID); err != nil {
+		t.Fatalf("TerminateAll: %v", err)
+	}
+	assertRevoked(t, NewTokenRevocationRepository(conn), fmt.Sprint(alice.ID), map[string]bool{"laptop": true, "phone": true})
+	if active := activeTokenIDs(t, repo, alice.ID); len(active) != 0 {
+		t.Errorf("active sessions = %v, want none", active)
+	}
+}
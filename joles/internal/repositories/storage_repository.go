@@ -0,0 +1,129 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"lio-ai/internal/models"
+)
+
+// StorageRepository tracks the bytes each user has stored
+type StorageRepository struct {
+	db *sql.DB
+}
+
+// NewStorageRepository creates a new storage repository
+func NewStorageRepository(db *sql.DB) *StorageRepository {
+	return &StorageRepository{db: db}
+}
+
+// Record sets the size of a stored object. An object keeps the user it was
+// first charged to when its size changes.
+func (r *StorageRepository) Record(userID, kind string, objectID, bytes int64) error {
+	now := time.Now()
+	_, err := r.db.Exec(`
+		INSERT INTO storage_usage (user_id, kind, object_id, bytes, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT(kind, object_id) DO UPDATE SET bytes = excluded.bytes, updated_at = excluded.updated_at
+	`, userID, kind, objectID, bytes, now, now)
+	if err != nil {
+		return fmt.Errorf("failed to record storage usage: %w", err)
+	}
+	return nil
+}
+
+// Owner returns the user an object is charged to and its recorded size,
+// or "" when the object is not tracked
+func (r *StorageRepository) Owner(kind string, objectID int64) (string, int64, error) {
+	var userID string
+	var bytes int64
+	err := r.db.QueryRow("SELECT user_id, bytes FROM storage_usage WHERE kind = ? AND object_id = ?", kind, objectID).Scan(&userID, &bytes)
+	if err == sql.ErrNoRows {
+		return "", 0, nil
+	}
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to get storage owner: %w", err)
+	}
+	return userID, bytes, nil
+}
+
+// Remove stops counting the given objects
+func (r *StorageRepository) Remove(kind string, objectIDs ...int64) error {
+	if len(objectIDs) == 0 {
+		return nil
+	}
+	args := make([]interface{}, 0, len(objectIDs)+1)
+	args = append(args, kind)
+	for _, id := range objectIDs {
+		args = append(args, id)
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(objectIDs)), ",")
+	_, err := r.db.Exec("DELETE FROM storage_usage WHERE kind = ? AND object_id IN ("+placeholders+")", args...)
+	if err != nil {
+		return fmt.Errorf("failed to remove storage usage: %w", err)
+	}
+	return nil
+}
+
+// GetUsage totals a user's stored bytes by kind
+func (r *StorageRepository) GetUsage(userID string) (*models.StorageUsage, error) {
+	rows, err := r.db.Query(`
+		SELECT kind, COALESCE(SUM(bytes), 0), COUNT(*)
+		FROM storage_usage
+		WHERE user_id = ?
+		GROUP BY kind
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get storage usage: %w", err)
+	}
+	defer rows.Close()
+
+	usage := &models.StorageUsage{UserID: userID, BytesByKind: make(map[string]int64)}
+	for rows.Next() {
+		var kind string
+		var bytes int64
+		var count int
+		if err := rows.Scan(&kind, &bytes, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan storage usage: %w", err)
+		}
+		usage.BytesByKind[kind] = bytes
+		usage.BytesUsed += bytes
+		usage.Objects += count
+	}
+	return usage, rows.Err()
+}
+
+// TopConsumers lists the users storing the most bytes, largest first
+func (r *StorageRepository) TopConsumers(limit int) ([]models.StorageUsage, error) {
+	rows, err := r.db.Query(`
+		SELECT s.user_id, COALESCE(u.plan, ''), SUM(s.bytes), COUNT(*),
+		       SUM(CASE WHEN s.kind = 'document' THEN s.bytes ELSE 0 END),
+		       SUM(CASE WHEN s.kind = 'attachment' THEN s.bytes ELSE 0 END)
+		FROM storage_usage s
+		LEFT JOIN users u ON CAST(u.id AS TEXT) = s.user_id
+		GROUP BY s.user_id
+		ORDER BY SUM(s.bytes) DESC
+		LIMIT ?
+	`, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get top storage consumers: %w", err)
+	}
+	defer rows.Close()
+
+	consumers := make([]models.StorageUsage, 0)
+	for rows.Next() {
+		var u models.StorageUsage
+		var documents, attachments int64
+		if err := rows.Scan(&u.UserID, &u.Plan, &u.BytesUsed, &u.Objects, &documents, &attachments); err != nil {
+			return nil, fmt.Errorf("failed to scan storage consumer: %w", err)
+		}
+		u.BytesByKind = map[string]int64{
+			models.StorageKindDocument:   documents,
+			models.StorageKindAttachment: attachments,
+		}
+		consumers = append(consumers, u)
+	}
+	return consumers, rows.Err()
+}
@@ -0,0 +1,89 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"lio-ai/internal/models"
+)
+
+// SyncQueueRepository handles the queue of pending provider key syncs
+type SyncQueueRepository struct {
+	db *sql.DB
+}
+
+// NewSyncQueueRepository creates a new sync queue repository
+func NewSyncQueueRepository(db *sql.DB) *SyncQueueRepository {
+	return &SyncQueueRepository{db: db}
+}
+
+// Enqueue records a pending sync for a user, keeping a single entry per user
+func (r *SyncQueueRepository) Enqueue(userID, lastError string) error {
+	query := `
+		INSERT INTO pending_key_syncs (user_id, attempts, last_error, next_attempt_at, created_at, updated_at)
+		VALUES (?, 1, ?, ?, ?, ?)
+		ON CONFLICT(user_id) DO UPDATE SET
+			last_error = excluded.last_error,
+			updated_at = excluded.updated_at
+	`
+
+	now := time.Now()
+	if _, err := r.db.Exec(query, userID, lastError, now, now, now); err != nil {
+		return fmt.Errorf("failed to enqueue key sync: %w", err)
+	}
+	return nil
+}
+
+// GetDue retrieves pending syncs whose next attempt time has passed
+func (r *SyncQueueRepository) GetDue(limit int) ([]models.PendingKeySync, error) {
+	return r.list(`WHERE next_attempt_at <= ? ORDER BY next_attempt_at ASC LIMIT ?`, time.Now(), limit)
+}
+
+// GetAll retrieves every pending sync
+func (r *SyncQueueRepository) GetAll() ([]models.PendingKeySync, error) {
+	return r.list(`ORDER BY created_at ASC`)
+}
+
+func (r *SyncQueueRepository) list(clause string, args ...interface{}) ([]models.PendingKeySync, error) {
+	query := `
+		SELECT id, user_id, attempts, last_error, next_attempt_at, created_at, updated_at
+		FROM pending_key_syncs
+	` + clause
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pending key syncs: %w", err)
+	}
+	defer rows.Close()
+
+	pending := make([]models.PendingKeySync, 0)
+	for rows.Next() {
+		var p models.PendingKeySync
+		var lastError sql.NullString
+		if err := rows.Scan(&p.ID, &p.UserID, &p.Attempts, &lastError, &p.NextAttemptAt, &p.CreatedAt, &p.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan pending key sync: %w", err)
+		}
+		p.LastError = lastError.String
+		pending = append(pending, p)
+	}
+
+	return pending, rows.Err()
+}
+
+// MarkFailed records a failed attempt and schedules the next one
+func (r *SyncQueueRepository) MarkFailed(id int64, lastError string, nextAttemptAt time.Time) error {
+	query := `
+		UPDATE pending_key_syncs
+		SET attempts = attempts + 1, last_error = ?, next_attempt_at = ?, updated_at = ?
+		WHERE id = ?
+	`
+	_, err := r.db.Exec(query, lastError, nextAttemptAt, time.Now(), id)
+	return err
+}
+
+// Delete removes a pending sync once it has been delivered
+func (r *SyncQueueRepository) Delete(id int64) error {
+	_, err := r.db.Exec(`DELETE FROM pending_key_syncs WHERE id = ?`, id)
+	return err
+}
@@ -0,0 +1,123 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"lio-ai/internal/models"
+)
+
+// TemplateRepository handles database operations for prompt templates
+type TemplateRepository struct {
+	db *sql.DB
+}
+
+// NewTemplateRepository creates a new template repository
+func NewTemplateRepository(db *sql.DB) *TemplateRepository {
+	return &TemplateRepository{db: db}
+}
+
+// Create creates a new prompt template
+func (r *TemplateRepository) Create(t *models.PromptTemplate) error {
+	query := `
+		INSERT INTO prompt_templates (user_id, name, description, content, is_shared, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`
+
+	now := time.Now()
+	result, err := r.db.Exec(query, t.UserID, t.Name, t.Description, t.Content, t.IsShared, now, now)
+	if err != nil {
+		return fmt.Errorf("failed to create template: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to get last insert id: %w", err)
+	}
+
+	t.ID = id
+	t.CreatedAt = now
+	t.UpdatedAt = now
+	return nil
+}
+
+// GetByID retrieves a prompt template by its ID, or nil if it doesn't exist
+func (r *TemplateRepository) GetByID(id int64) (*models.PromptTemplate, error) {
+	query := `
+		SELECT id, user_id, name, COALESCE(description, ''), content, is_shared, created_at, updated_at
+		FROM prompt_templates
+		WHERE id = ?
+	`
+
+	t, err := scanTemplate(r.db.QueryRow(query, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return t, err
+}
+
+// ListVisible retrieves the user's own templates and templates shared by others
+func (r *TemplateRepository) ListVisible(userID string) ([]models.PromptTemplate, error) {
+	query := `
+		SELECT id, user_id, name, COALESCE(description, ''), content, is_shared, created_at, updated_at
+		FROM prompt_templates
+		WHERE user_id = ? OR is_shared = 1
+		ORDER BY name ASC
+	`
+
+	rows, err := r.db.Query(query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get templates: %w", err)
+	}
+	defer rows.Close()
+
+	templates := make([]models.PromptTemplate, 0)
+	for rows.Next() {
+		t, err := scanTemplate(rows)
+		if err != nil {
+			return nil, err
+		}
+		templates = append(templates, *t)
+	}
+
+	return templates, nil
+}
+
+// Update saves a prompt template's editable fields
+func (r *TemplateRepository) Update(t *models.PromptTemplate) error {
+	query := `
+		UPDATE prompt_templates
+		SET name = ?, description = ?, content = ?, is_shared = ?, updated_at = ?
+		WHERE id = ?
+	`
+
+	now := time.Now()
+	if _, err := r.db.Exec(query, t.Name, t.Description, t.Content, t.IsShared, now, t.ID); err != nil {
+		return fmt.Errorf("failed to update template: %w", err)
+	}
+
+	t.UpdatedAt = now
+	return nil
+}
+
+// Delete deletes a prompt template
+func (r *TemplateRepository) Delete(id int64) error {
+	if _, err := r.db.Exec("DELETE FROM prompt_templates WHERE id = ?", id); err != nil {
+		return fmt.Errorf("failed to delete template: %w", err)
+	}
+	return nil
+}
+
+// scanTemplate scans a prompt template from a row
+func scanTemplate(row interface{ Scan(...interface{}) error }) (*models.PromptTemplate, error) {
+	t := &models.PromptTemplate{}
+	err := row.Scan(&t.ID, &t.UserID, &t.Name, &t.Description, &t.Content, &t.IsShared, &t.CreatedAt, &t.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan template: %w", err)
+	}
+	return t, nil
+}
@@ -0,0 +1,55 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// TokenRevocationRepository handles login tokens revoked before they expire
+type TokenRevocationRepository struct {
+	db *sql.DB
+}
+
+// NewTokenRevocationRepository creates a new token revocation repository
+func NewTokenRevocationRepository(db *sql.DB) *TokenRevocationRepository {
+	return &TokenRevocationRepository{db: db}
+}
+
+// RevokeToken revokes the token with the given jti until it expires
+func (r *TokenRevocationRepository) RevokeToken(tokenID, userID string, expiresAt time.Time) error {
+	_, err := r.db.Exec(`
+		INSERT INTO revoked_tokens (jti, user_id, expires_at, revoked_at) VALUES (?, ?, ?, ?)
+		ON CONFLICT(jti) DO NOTHING
+	`, tokenID, userID, expiresAt, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to revoke token: %w", err)
+	}
+	return nil
+}
+
+// RevokeUserTokens revokes every token of a user issued before before
+func (r *TokenRevocationRepository) RevokeUserTokens(userID string, before time.Time) error {
+	_, err := r.db.Exec(`
+		INSERT INTO user_token_revocations (user_id, revoked_before) VALUES (?, ?)
+		ON CONFLICT(user_id) DO UPDATE SET revoked_before = MAX(revoked_before, excluded.revoked_before)
+	`, userID, before.Unix())
+	if err != nil {
+		return fmt.Errorf("failed to revoke user tokens: %w", err)
+	}
+	return nil
+}
+
+// IsRevoked reports whether the token with the given jti, issued to userID
+// at issuedAt, was revoked
+func (r *TokenRevocationRepository) IsRevoked(tokenID, userID string, issuedAt time.Time) (bool, error) {
+	var revoked bool
+	err := r.db.QueryRow(`
+		SELECT EXISTS (SELECT 1 FROM revoked_tokens WHERE jti = ?)
+			OR EXISTS (SELECT 1 FROM user_token_revocations WHERE user_id = ? AND revoked_before > ?)
+	`, tokenID, userID, issuedAt.Unix()).Scan(&revoked)
+	if err != nil {
+		return false, fmt.Errorf("failed to check token revocation: %w", err)
+	}
+	return revoked, nil
+}
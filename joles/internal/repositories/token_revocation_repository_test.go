@@ -0,0 +1,80 @@
+package repositories
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRevokeTokenByJTI(t *testing.T) {
+	repo := NewTokenRevocationRepository(newTestDB(t))
+	issued := time.Now().Add(-time.Minute)
+
+	if err := repo.RevokeToken("jti-1", "1", time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("RevokeToken: %v", err)
+	}
+	// Revoking again, e.g. on a repeated logout, is not an error
+	if err := repo.RevokeToken("jti-1", "1", time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("RevokeToken again: %v", err)
+	}
+
+	tests := []struct {
+		tokenID string
+		want    bool
+	}{
+		{"jti-1", true},
+		{"jti-2", false},
+	}
+	for _, tt := range tests {
+		revoked, err := repo.IsRevoked(tt.tokenID, "1", issued)
+		if err != nil {
+			t.Fatalf("IsRevoked(%s): %v", tt.tokenID, err)
+		}
+		if revoked != tt.want {
+			t.Errorf("IsRevoked(%s) = %v, want %v", tt.tokenID, revoked, tt.want)
+		}
+	}
+}
+
+func TestRevokeUserTokensCutoff(t *testing.T) {
+	repo := NewTokenRevocationRepository(newTestDB(t))
+	cutoff := time.Now().Truncate(time.Second)
+
+	if err := repo.RevokeUserTokens("1", cutoff); err != nil {
+		t.Fatalf("RevokeUserTokens: %v", err)
+	}
+	// An earlier cutoff does not move the later one back
+	if err := repo.RevokeUserTokens("1", cutoff.Add(-time.Hour)); err != nil {
+		t.Fatalf("RevokeUserTokens earlier: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		userID   string
+		issuedAt time.Time
+		want     bool
+	}{
+		{"issued before the cutoff", "1", cutoff.Add(-time.Minute), true},
+		{"issued a second before", "1", cutoff.Add(-time.Second), true},
+		{"issued at the cutoff", "1", cutoff, false},
+		{"issued after the cutoff", "1", cutoff.Add(time.Minute), false},
+		{"another user", "2", cutoff.Add(-time.Minute), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			revoked, err := repo.IsRevoked("jti", tt.userID, tt.issuedAt)
+			if err != nil {
+				t.Fatalf("IsRevoked: %v", err)
+			}
+			if revoked != tt.want {
+				t.Errorf("IsRevoked = %v, want %v", revoked, tt.want)
+			}
+		})
+	}
+
+	if err := repo.RevokeUserTokens("1", cutoff.Add(time.Hour)); err != nil {
+		t.Fatalf("RevokeUserTokens later: %v", err)
+	}
+	if revoked, _ := repo.IsRevoked("jti", "1", cutoff.Add(time.Minute)); !revoked {
+		t.Errorf("a later cutoff did not replace the earlier one")
+	}
+}
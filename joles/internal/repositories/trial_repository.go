@@ -0,0 +1,86 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// TrialRepository tracks anonymous trial usage per client and day
+type TrialRepository struct {
+	db *sql.DB
+}
+
+// NewTrialRepository creates a new trial repository
+func NewTrialRepository(db *sql.DB) *TrialRepository {
+	return &TrialRepository{db: db}
+}
+
+// Reserve claims one completion for a client if it is under the daily
+// limit and its IP under ipLimit across all of the IP's clients. The
+// checks and increment happen in one statement so concurrent requests
+// cannot overshoot either limit.
+func (r *TrialRepository) Reserve(clientKey, day, ip string, limit, ipLimit int) (bool, error) {
+	query := `
+		INSERT INTO trial_usage (client_key, day, completions, ip_address, last_used_at)
+		SELECT ?, ?, 1, ?, ?
+		WHERE (SELECT COALESCE(SUM(completions), 0) FROM trial_usage WHERE ip_address = ? AND day = ?) < ?
+		ON CONFLICT(client_key, day) DO UPDATE SET
+			completions = completions + 1,
+			ip_address = excluded.ip_address,
+			last_used_at = excluded.last_used_at
+		WHERE completions < ?
+	`
+
+	if limit <= 0 || ipLimit <= 0 {
+		return false, nil
+	}
+	result, err := r.db.Exec(query, clientKey, day, ip, time.Now(), ip, day, ipLimit, limit)
+	if err != nil {
+		return false, fmt.Errorf("failed to reserve trial completion: %w", err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to reserve trial completion: %w", err)
+	}
+	return n > 0, nil
+}
+
+// Release gives back a reserved completion, e.g. when the AI call failed
+func (r *TrialRepository) Release(clientKey, day string) error {
+	_, err := r.db.Exec(`
+		UPDATE trial_usage SET completions = completions - 1
+		WHERE client_key = ? AND day = ? AND completions > 0
+	`, clientKey, day)
+	if err != nil {
+		return fmt.Errorf("failed to release trial completion: %w", err)
+	}
+	return nil
+}
+
+// AddTokens records tokens consumed by a client's trial completion
+func (r *TrialRepository) AddTokens(clientKey, day string, tokens int) error {
+	_, err := r.db.Exec(`
+		UPDATE trial_usage SET tokens = tokens + ?
+		WHERE client_key = ? AND day = ?
+	`, tokens, clientKey, day)
+	if err != nil {
+		return fmt.Errorf("failed to record trial tokens: %w", err)
+	}
+	return nil
+}
+
+// GetUsed returns how many completions a client has used on a day
+func (r *TrialRepository) GetUsed(clientKey, day string) (int, error) {
+	var used int
+	err := r.db.QueryRow(`
+		SELECT completions FROM trial_usage WHERE client_key = ? AND day = ?
+	`, clientKey, day).Scan(&used)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to get trial usage: %w", err)
+	}
+	return used, nil
+}
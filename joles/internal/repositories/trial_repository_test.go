@@ -0,0 +1,51 @@
+package repositories
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestTrialReserveCapsEachIPAcrossClientKeys(t *testing.T) {
+	repo := NewTrialRepository(newTestDB(t))
+	const day = "2026-01-02"
+
+	// Fresh fingerprints from one IP stop at its ceiling
+	for i := 0; i < 6; i++ {
+		ok, err := repo.Reserve(fmt.Sprintf("key-%d", i), day, "10.0.0.1", 5, 4)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := i < 4; ok != want {
+			t.Errorf("reservation %d from the IP = %v, want %v", i, ok, want)
+		}
+	}
+
+	// Other IPs, and other days, have their own ceiling
+	if ok, err := repo.Reserve("key-0", day, "10.0.0.2", 5, 4); err != nil || !ok {
+		t.Errorf("reservation from another IP = %v, %v; want it allowed", ok, err)
+	}
+	if ok, err := repo.Reserve("key-5", "2026-01-03", "10.0.0.1", 5, 4); err != nil || !ok {
+		t.Errorf("reservation the next day = %v, %v; want it allowed", ok, err)
+	}
+
+	// Released completions free up the IP again
+	if err := repo.Release("key-0", day); err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := repo.Reserve("key-9", day, "10.0.0.1", 5, 4); err != nil || !ok {
+		t.Errorf("reservation after a release = %v, %v; want it allowed", ok, err)
+	}
+}
+
+func TestTrialReserveKeepsThePerClientLimit(t *testing.T) {
+	repo := NewTrialRepository(newTestDB(t))
+	for i := 0; i < 3; i++ {
+		ok, err := repo.Reserve("key", "2026-01-02", "10.0.0.1", 2, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := i < 2; ok != want {
+			t.Errorf("reservation %d = %v, want %v", i, ok, want)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package repositories
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"lio-ai/internal/models"
+)
+
+// AddUsageDeadLetter keeps a usage event that could not be tracked
+func (r *UsageRepository) AddUsageDeadLetter(req *models.UsageRequest, trackErr string, attempts int) error {
+	payload, err := json.Marshal(req)
+	if err != nil {
+		return fmt.Errorf("failed to encode usage dead letter: %w", err)
+	}
+	now := time.Now()
+	if _, err := r.db.Exec(`
+		INSERT INTO usage_dead_letters (payload, error, attempts, created_at, updated_at) VALUES (?, ?, ?, ?, ?)
+	`, string(payload), trackErr, attempts, now, now); err != nil {
+		return fmt.Errorf("failed to add usage dead letter: %w", err)
+	}
+	return nil
+}
+
+// ListUsageDeadLetters retrieves up to limit dead letters, oldest first
+func (r *UsageRepository) ListUsageDeadLetters(limit int) ([]models.UsageDeadLetter, error) {
+	rows, err := r.db.Query(`
+		SELECT id, payload, error, attempts, created_at, updated_at
+		FROM usage_dead_letters ORDER BY id LIMIT ?
+	`, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list usage dead letters: %w", err)
+	}
+	defer rows.Close()
+
+	letters := make([]models.UsageDeadLetter, 0)
+	for rows.Next() {
+		var l models.UsageDeadLetter
+		var payload string
+		if err := rows.Scan(&l.ID, &payload, &l.Error, &l.Attempts, &l.CreatedAt, &l.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan usage dead letter: %w", err)
+		}
+		if err := json.Unmarshal([]byte(payload), &l.Request); err != nil {
+			return nil, fmt.Errorf("failed to decode usage dead letter %d: %w", l.ID, err)
+		}
+		letters = append(letters, l)
+	}
+	return letters, rows.Err()
+}
+
+// CountUsageDeadLetters counts the dead letters waiting to be replayed
+func (r *UsageRepository) CountUsageDeadLetters() (int, error) {
+	var n int
+	if err := r.db.QueryRow("SELECT COUNT(*) FROM usage_dead_letters").Scan(&n); err != nil {
+		return 0, fmt.Errorf("failed to count usage dead letters: %w", err)
+	}
+	return n, nil
+}
+
+// DeleteUsageDeadLetter removes a dead letter once it has been tracked
+func (r *UsageRepository) DeleteUsageDeadLetter(id int64) error {
+	if _, err := r.db.Exec("DELETE FROM usage_dead_letters WHERE id = ?", id); err != nil {
+		return fmt.Errorf("failed to delete usage dead letter: %w", err)
+	}
+	return nil
+}
+
+// FailUsageDeadLetter records another failed replay of a dead letter
+func (r *UsageRepository) FailUsageDeadLetter(id int64, trackErr string) error {
+	if _, err := r.db.Exec(`
+		UPDATE usage_dead_letters SET error = ?, attempts = attempts + 1, updated_at = ? WHERE id = ?
+	`, trackErr, time.Now(), id); err != nil {
+		return fmt.Errorf("failed to update usage dead letter: %w", err)
+	}
+	return nil
+}
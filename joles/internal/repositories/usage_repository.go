@@ -10,7 +10,8 @@ import (
 
 // UsageRepository handles database operations for usage tracking
 type UsageRepository struct {
-	db *sql.DB
+	db      *sql.DB
+	changes *ChangeRepository
 }
 
 // NewUsageRepository creates a new usage repository
@@ -18,35 +19,44 @@ func NewUsageRepository(db *sql.DB) *UsageRepository {
 	return &UsageRepository{db: db}
 }
 
+// SetChangeFeed records tracked usage to the change feed
+func (r *UsageRepository) SetChangeFeed(changes *ChangeRepository) {
+	r.changes = changes
+}
+
 // TrackUsage records a usage metric
 func (r *UsageRepository) TrackUsage(metric *models.UsageMetric) error {
 	query := `
 		INSERT INTO usage_metrics (
 			user_id, request_type, resource_id, tokens_input, tokens_output,
 			tokens_total, model_used, cost_usd, duration_ms, endpoint,
-			success, error_message, created_at
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+			provider, key_source, success, error_message, created_at
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		RETURNING id
 	`
 
 	now := time.Now()
-	result, err := r.db.Exec(query,
+	err := r.db.QueryRow(query,
 		metric.UserID, metric.RequestType, metric.ResourceID,
 		metric.TokensInput, metric.TokensOutput, metric.TokensTotal,
 		metric.ModelUsed, metric.CostUSD, metric.DurationMs,
-		metric.Endpoint, metric.Success, metric.ErrorMessage, now,
-	)
+		metric.Endpoint, metric.Provider, metric.KeySource, metric.Success, metric.ErrorMessage, now,
+	).Scan(&metric.ID)
 	if err != nil {
 		return fmt.Errorf("failed to track usage: %w", err)
 	}
 
-	id, err := result.LastInsertId()
-	if err != nil {
-		return fmt.Errorf("failed to get last insert id: %w", err)
-	}
-
-	metric.ID = id
 	metric.CreatedAt = now
-	return nil
+	return r.changes.record(r.db, models.ChangeEntityUsage, models.ChangeOpInsert, metric.ID, metric.UserID, metric)
+}
+
+// QuotaExists reports whether a user has a quota yet, without creating one
+func (r *UsageRepository) QuotaExists(userID string) (bool, error) {
+	var exists bool
+	if err := r.db.QueryRow("SELECT EXISTS (SELECT 1 FROM user_quotas WHERE user_id = ?)", userID).Scan(&exists); err != nil {
+		return false, fmt.Errorf("failed to check user quota: %w", err)
+	}
+	return exists, nil
 }
 
 // GetUserQuota retrieves or creates a user quota
@@ -79,7 +89,8 @@ func (r *UsageRepository) GetUserQuota(userID string) (*models.UserQuota, error)
 	return quota, nil
 }
 
-// CreateUserQuota creates a new user quota with defaults
+// CreateUserQuota creates a new user quota with the limits of the user's
+// plan, or the defaults when the plan has no row
 func (r *UsageRepository) CreateUserQuota(userID string) (*models.UserQuota, error) {
 	query := `
 		INSERT INTO user_quotas (user_id, created_at, updated_at)
@@ -87,29 +98,14 @@ func (r *UsageRepository) CreateUserQuota(userID string) (*models.UserQuota, err
 	`
 
 	now := time.Now()
-	result, err := r.db.Exec(query, userID, now, now)
-	if err != nil {
+	if _, err := r.db.Exec(query, userID, now, now); err != nil {
 		return nil, fmt.Errorf("failed to create user quota: %w", err)
 	}
+	if _, err := r.db.Exec(applyUserPlanSQL, now, userID, userID); err != nil {
+		return nil, fmt.Errorf("failed to apply plan limits: %w", err)
+	}
 
-	id, _ := result.LastInsertId()
-	
-	return &models.UserQuota{
-		ID:                  id,
-		UserID:              userID,
-		DailyTokenLimit:     100000,
-		MonthlyTokenLimit:   3000000,
-		DailyTokensUsed:     0,
-		MonthlyTokensUsed:   0,
-		DailyCostLimitUSD:   10.0,
-		MonthlyCostLimitUSD: 300.0,
-		DailyCostUsedUSD:    0.0,
-		MonthlyCostUsedUSD:  0.0,
-		LastResetDaily:      now,
-		LastResetMonthly:    now,
-		CreatedAt:           now,
-		UpdatedAt:           now,
-	}, nil
+	return r.GetUserQuota(userID)
 }
 
 // UpdateQuotaUsage updates the quota usage
@@ -132,36 +128,134 @@ func (r *UsageRepository) UpdateQuotaUsage(userID string, tokens int, cost float
 	return nil
 }
 
-// ResetDailyQuota resets daily usage if needed
-func (r *UsageRepository) ResetDailyQuota(userID string) error {
-	query := `
+// liveReservations sums the quota held by a user's unexpired reservations
+const liveReservations = `
+	SELECT COALESCE(SUM(tokens), 0), COALESCE(SUM(cost_usd), 0) FROM quota_reservations
+	WHERE user_id = ? AND expires_at > ?
+`
+
+// GetReservedQuota returns the tokens and cost held by a user's in-flight
+// requests
+func (r *UsageRepository) GetReservedQuota(userID string) (int, float64, error) {
+	var tokens int
+	var cost float64
+	if err := r.db.QueryRow(liveReservations, userID, time.Now()).Scan(&tokens, &cost); err != nil {
+		return 0, 0, fmt.Errorf("failed to get reserved quota: %w", err)
+	}
+	return tokens, cost, nil
+}
+
+// ReserveQuota holds tokens and cost against a user's quota until expiresAt,
+// in one statement so concurrent reservations cannot together pass a
+// limit. It returns 0 when the reservation would exceed a daily or monthly
+// limit, counting usage so far and other live reservations. The limits of
+// the user's organization, when it has a quota, are checked the same way
+// against all its members' usage and reservations.
+func (r *UsageRepository) ReserveQuota(userID string, tokens int, cost float64, expiresAt time.Time) (int64, error) {
+	now := time.Now()
+	if _, err := r.db.Exec("DELETE FROM quota_reservations WHERE user_id = ? AND expires_at <= ?", userID, now); err != nil {
+		return 0, fmt.Errorf("failed to expire quota reservations: %w", err)
+	}
+
+	result, err := r.db.Exec(`
+		INSERT INTO quota_reservations (user_id, tokens, cost_usd, created_at, expires_at)
+		SELECT q.user_id, ?, ?, ?, ?
+		FROM user_quotas q,
+			(SELECT COALESCE(SUM(tokens), 0) AS tokens, COALESCE(SUM(cost_usd), 0) AS cost
+			 FROM quota_reservations WHERE user_id = ? AND expires_at > ?) held
+		WHERE q.user_id = ?
+			AND q.daily_tokens_used + held.tokens + ? <= q.daily_token_limit
+			AND q.monthly_tokens_used + held.tokens + ? <= q.monthly_token_limit
+			AND q.daily_cost_used_usd + held.cost + ? <= q.daily_cost_limit_usd
+			AND q.monthly_cost_used_usd + held.cost + ? <= q.monthly_cost_limit_usd
+			AND NOT EXISTS (
+				SELECT 1 FROM (`+orgQuotaUsage+`) o
+				JOIN organization_members m ON m.org_id = o.org_id
+				WHERE m.user_id = q.user_id AND (
+					(o.daily_token_limit > 0 AND o.daily_tokens_used + o.reserved_tokens + ? > o.daily_token_limit)
+					OR (o.monthly_token_limit > 0 AND o.monthly_tokens_used + o.reserved_tokens + ? > o.monthly_token_limit)
+					OR (o.daily_cost_limit_usd > 0 AND o.daily_cost_used_usd + o.reserved_cost_usd + ? > o.daily_cost_limit_usd)
+					OR (o.monthly_cost_limit_usd > 0 AND o.monthly_cost_used_usd + o.reserved_cost_usd + ? > o.monthly_cost_limit_usd)
+				)
+			)
+	`, tokens, cost, now, expiresAt, userID, now, userID, tokens, tokens, cost, cost,
+		now, tokens, tokens, cost, cost)
+	if err != nil {
+		return 0, fmt.Errorf("failed to reserve quota: %w", err)
+	}
+	if n, err := result.RowsAffected(); err != nil || n == 0 {
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
+// ReleaseReservation drops a reservation without charging anything
+func (r *UsageRepository) ReleaseReservation(id int64) error {
+	if _, err := r.db.Exec("DELETE FROM quota_reservations WHERE id = ?", id); err != nil {
+		return fmt.Errorf("failed to release quota reservation: %w", err)
+	}
+	return nil
+}
+
+// SettleReservation replaces a reservation with the usage the request
+// actually had, in one transaction
+func (r *UsageRepository) SettleReservation(id int64, userID string, tokens int, cost float64) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM quota_reservations WHERE id = ?", id); err != nil {
+		return fmt.Errorf("failed to settle quota reservation: %w", err)
+	}
+	_, err = tx.Exec(`
+		UPDATE user_quotas
+		SET daily_tokens_used = daily_tokens_used + ?,
+			monthly_tokens_used = monthly_tokens_used + ?,
+			daily_cost_used_usd = daily_cost_used_usd + ?,
+			monthly_cost_used_usd = monthly_cost_used_usd + ?,
+			updated_at = ?
+		WHERE user_id = ?
+	`, tokens, tokens, cost, cost, time.Now(), userID)
+	if err != nil {
+		return fmt.Errorf("failed to update quota usage: %w", err)
+	}
+	return tx.Commit()
+}
+
+// ResetDailyQuotas starts a new day at dayStart for every quota last reset
+// before it, returning how many were reset
+func (r *UsageRepository) ResetDailyQuotas(dayStart time.Time) (int64, error) {
+	result, err := r.db.Exec(`
 		UPDATE user_quotas
 		SET daily_tokens_used = 0,
 			daily_cost_used_usd = 0.0,
 			last_reset_daily = ?,
 			updated_at = ?
-		WHERE user_id = ?
-	`
-
-	now := time.Now()
-	_, err := r.db.Exec(query, now, now, userID)
-	return err
+		WHERE julianday(last_reset_daily) < julianday(?)
+	`, dayStart, time.Now(), dayStart)
+	if err != nil {
+		return 0, fmt.Errorf("failed to reset daily quotas: %w", err)
+	}
+	return result.RowsAffected()
 }
 
-// ResetMonthlyQuota resets monthly usage if needed
-func (r *UsageRepository) ResetMonthlyQuota(userID string) error {
-	query := `
+// ResetMonthlyQuotas starts a new month at monthStart for every quota last
+// reset before it, returning how many were reset
+func (r *UsageRepository) ResetMonthlyQuotas(monthStart time.Time) (int64, error) {
+	result, err := r.db.Exec(`
 		UPDATE user_quotas
 		SET monthly_tokens_used = 0,
 			monthly_cost_used_usd = 0.0,
 			last_reset_monthly = ?,
 			updated_at = ?
-		WHERE user_id = ?
-	`
-
-	now := time.Now()
-	_, err := r.db.Exec(query, now, now, userID)
-	return err
+		WHERE julianday(last_reset_monthly) < julianday(?)
+	`, monthStart, time.Now(), monthStart)
+	if err != nil {
+		return 0, fmt.Errorf("failed to reset monthly quotas: %w", err)
+	}
+	return result.RowsAffected()
 }
 
 // GetCostConfig retrieves cost configuration for a model
@@ -191,43 +285,55 @@ func (r *UsageRepository) GetCostConfig(modelName string) (*models.CostConfig, e
 	return config, nil
 }
 
-// GetUsageSummary retrieves aggregated usage for a user
-func (r *UsageRepository) GetUsageSummary(userID, period string) (*models.UsageSummary, error) {
-	var whereClause string
-	now := time.Now()
+// GetCheapestModel returns the active model with the lowest combined token
+// price for an operation type, ignoring the "default" fallback row
+func (r *UsageRepository) GetCheapestModel(operationType string) (string, error) {
+	var model string
+	err := r.db.QueryRow(`
+		SELECT model_name FROM cost_config
+		WHERE is_active = 1 AND operation_type = ? AND model_name != 'default'
+		ORDER BY cost_per_input_token + cost_per_output_token ASC
+		LIMIT 1
+	`, operationType).Scan(&model)
+	if err == sql.ErrNoRows {
+		return "", fmt.Errorf("no active %s models configured", operationType)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get cheapest model: %w", err)
+	}
+	return model, nil
+}
 
-	switch period {
-	case "daily":
-		whereClause = fmt.Sprintf("AND created_at >= '%s'", now.AddDate(0, 0, -1).Format(time.RFC3339))
-	case "monthly":
-		whereClause = fmt.Sprintf("AND created_at >= '%s'", now.AddDate(0, -1, 0).Format(time.RFC3339))
-	default:
-		whereClause = ""
+// GetUsageSummary retrieves aggregated usage for a user created in
+// [since, until), of the usage filter matches when it is not nil. A zero
+// since or until leaves that end of the range open.
+func (r *UsageRepository) GetUsageSummary(userID string, since, until time.Time, filter *models.UsageFilter) (*models.UsageSummary, error) {
+	source, args, err := FilteredUsageSource(r.db, userID, since, until, filter)
+	if err != nil {
+		return nil, err
 	}
 
-	query := fmt.Sprintf(`
-		SELECT 
-			COUNT(*) as total_requests,
-			SUM(CASE WHEN success = 1 THEN 1 ELSE 0 END) as successful_requests,
-			SUM(CASE WHEN success = 0 THEN 1 ELSE 0 END) as failed_requests,
+	query := `
+		SELECT
+			COALESCE(SUM(requests), 0) as total_requests,
+			COALESCE(SUM(successful), 0) as successful_requests,
+			COALESCE(SUM(failed), 0) as failed_requests,
 			COALESCE(SUM(tokens_input), 0) as total_tokens_input,
 			COALESCE(SUM(tokens_output), 0) as total_tokens_output,
 			COALESCE(SUM(tokens_total), 0) as total_tokens,
 			COALESCE(SUM(cost_usd), 0.0) as total_cost_usd,
-			COALESCE(AVG(duration_ms), 0) as average_duration_ms,
-			SUM(CASE WHEN request_type = 'chat' THEN 1 ELSE 0 END) as chat_requests,
-			SUM(CASE WHEN request_type = 'code_generation' THEN 1 ELSE 0 END) as code_gen_requests
-		FROM usage_metrics
-		WHERE user_id = ? %s
-	`, whereClause)
+			COALESCE(CAST(SUM(duration_ms) AS REAL) / SUM(requests), 0) as average_duration_ms,
+			COALESCE(SUM(CASE WHEN request_type = 'chat' THEN requests ELSE 0 END), 0) as chat_requests,
+			COALESCE(SUM(CASE WHEN request_type = 'code_generation' THEN requests ELSE 0 END), 0) as code_gen_requests
+		FROM ` + source + ` AS usage
+	`
 
 	summary := &models.UsageSummary{
 		UserID: userID,
-		Period: period,
 		ModelsUsed: make(map[string]int),
 	}
 
-	err := r.db.QueryRow(query, userID).Scan(
+	err = r.db.QueryRow(query, args...).Scan(
 		&summary.TotalRequests, &summary.SuccessfulRequests, &summary.FailedRequests,
 		&summary.TotalTokensInput, &summary.TotalTokensOutput, &summary.TotalTokens,
 		&summary.TotalCostUSD, &summary.AverageDurationMs, &summary.ChatRequests,
@@ -241,35 +347,28 @@ func (r *UsageRepository) GetUsageSummary(userID, period string) (*models.UsageS
 	return summary, nil
 }
 
-// GetUsageByEndpoint retrieves usage breakdown by endpoint
-func (r *UsageRepository) GetUsageByEndpoint(userID, period string) ([]models.UsageByEndpoint, error) {
-	var whereClause string
-	now := time.Now()
-
-	switch period {
-	case "daily":
-		whereClause = fmt.Sprintf("AND created_at >= '%s'", now.AddDate(0, 0, -1).Format(time.RFC3339))
-	case "monthly":
-		whereClause = fmt.Sprintf("AND created_at >= '%s'", now.AddDate(0, -1, 0).Format(time.RFC3339))
-	default:
-		whereClause = ""
+// GetUsageByEndpoint retrieves usage breakdown by endpoint, filtered as
+// for GetUsageSummary
+func (r *UsageRepository) GetUsageByEndpoint(userID string, since, until time.Time, filter *models.UsageFilter) ([]models.UsageByEndpoint, error) {
+	source, args, err := FilteredUsageSource(r.db, userID, since, until, filter)
+	if err != nil {
+		return nil, err
 	}
 
-	query := fmt.Sprintf(`
-		SELECT 
+	query := `
+		SELECT
 			endpoint,
-			COUNT(*) as request_count,
+			SUM(requests) as request_count,
 			COALESCE(SUM(tokens_total), 0) as total_tokens,
 			COALESCE(SUM(cost_usd), 0.0) as total_cost_usd,
-			COALESCE(AVG(duration_ms), 0) as average_duration_ms,
-			CAST(SUM(CASE WHEN success = 1 THEN 1 ELSE 0 END) AS REAL) / COUNT(*) * 100 as success_rate
-		FROM usage_metrics
-		WHERE user_id = ? %s
+			COALESCE(CAST(SUM(duration_ms) AS REAL) / SUM(requests), 0) as average_duration_ms,
+			CAST(SUM(successful) AS REAL) / SUM(requests) * 100 as success_rate
+		FROM ` + source + ` AS usage
 		GROUP BY endpoint
 		ORDER BY request_count DESC
-	`, whereClause)
+	`
 
-	rows, err := r.db.Query(query, userID)
+	rows, err := r.db.Query(query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get usage by endpoint: %w", err)
 	}
@@ -314,3 +413,462 @@ func (r *UsageRepository) UpdateUserQuota(userID string, updates map[string]inte
 
 	return err
 }
+
+// GetChatUsage aggregates completion usage recorded against a chat
+// (request_type "chat", resource_id = chat ID). TotalMessages is left
+// for the caller.
+func (r *UsageRepository) GetChatUsage(userID string, chatID int64) (*models.ChatStats, error) {
+	query := `
+		SELECT 
+			COUNT(*) as total_requests,
+			COALESCE(SUM(CASE WHEN success = 0 THEN 1 ELSE 0 END), 0) as failed_requests,
+			COALESCE(SUM(tokens_input), 0) as total_tokens_input,
+			COALESCE(SUM(tokens_output), 0) as total_tokens_output,
+			COALESCE(SUM(tokens_total), 0) as total_tokens,
+			COALESCE(SUM(cost_usd), 0.0) as total_cost_usd,
+			COALESCE(AVG(CASE WHEN success = 1 THEN duration_ms END), 0) as average_latency_ms
+		FROM usage_metrics
+		WHERE user_id = ? AND request_type = 'chat' AND resource_id = ?
+	`
+
+	stats := &models.ChatStats{
+		ChatID: chatID,
+		Models: make([]models.ModelUsageStat, 0),
+	}
+
+	err := r.db.QueryRow(query, userID, chatID).Scan(
+		&stats.TotalRequests, &stats.FailedRequests,
+		&stats.TotalTokensInput, &stats.TotalTokensOutput, &stats.TotalTokens,
+		&stats.TotalCostUSD, &stats.AverageLatencyMs,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get chat usage: %w", err)
+	}
+
+	if stats.TotalRequests == 0 {
+		return stats, nil
+	}
+
+	// Breakdown by model
+	rows, err := r.db.Query(`
+		SELECT model_used, COUNT(*),
+			COALESCE(SUM(tokens_input), 0), COALESCE(SUM(tokens_output), 0),
+			COALESCE(SUM(cost_usd), 0.0),
+			COALESCE(AVG(CASE WHEN success = 1 THEN duration_ms END), 0)
+		FROM usage_metrics
+		WHERE user_id = ? AND request_type = 'chat' AND resource_id = ?
+		GROUP BY model_used
+		ORDER BY COUNT(*) DESC
+	`, userID, chatID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get chat models: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var stat models.ModelUsageStat
+		var model sql.NullString
+		if err := rows.Scan(&model, &stat.Requests, &stat.TokensInput, &stat.TokensOutput, &stat.TotalCostUSD, &stat.AverageLatencyMs); err != nil {
+			return nil, fmt.Errorf("failed to scan chat model: %w", err)
+		}
+		stat.Model = model.String
+		stats.Models = append(stats.Models, stat)
+	}
+
+	return stats, nil
+}
+
+// GetProviderKeyUsage aggregates usage attributed to a user's provider key
+func (r *UsageRepository) GetProviderKeyUsage(userID, provider string) (*models.ProviderKeyUsage, error) {
+	query := `
+		SELECT 
+			COUNT(*) as total_requests,
+			COALESCE(SUM(CASE WHEN success = 1 THEN 1 ELSE 0 END), 0) as successful_requests,
+			COALESCE(SUM(CASE WHEN success = 0 THEN 1 ELSE 0 END), 0) as failed_requests,
+			COALESCE(SUM(tokens_input), 0) as total_tokens_input,
+			COALESCE(SUM(tokens_output), 0) as total_tokens_output,
+			COALESCE(SUM(tokens_total), 0) as total_tokens,
+			COALESCE(SUM(cost_usd), 0.0) as total_cost_usd
+		FROM usage_metrics
+		WHERE user_id = ? AND provider = ?
+	`
+
+	usage := &models.ProviderKeyUsage{
+		Provider:   provider,
+		ModelsUsed: make(map[string]int),
+	}
+
+	err := r.db.QueryRow(query, userID, provider).Scan(
+		&usage.TotalRequests, &usage.SuccessfulRequests, &usage.FailedRequests,
+		&usage.TotalTokensInput, &usage.TotalTokensOutput, &usage.TotalTokens,
+		&usage.EstimatedCostUSD,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get provider key usage: %w", err)
+	}
+
+	if usage.TotalRequests == 0 {
+		return usage, nil
+	}
+
+	// Breakdown by model
+	rows, err := r.db.Query(`
+		SELECT model_used, COUNT(*)
+		FROM usage_metrics
+		WHERE user_id = ? AND provider = ?
+		GROUP BY model_used
+	`, userID, provider)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get provider models: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var model sql.NullString
+		var count int
+		if err := rows.Scan(&model, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan provider model: %w", err)
+		}
+		usage.ModelsUsed[model.String] = count
+	}
+
+	// Most recent request of any outcome
+	var lastUsed sql.NullTime
+	err = r.db.QueryRow(`
+		SELECT created_at FROM usage_metrics
+		WHERE user_id = ? AND provider = ?
+		ORDER BY created_at DESC LIMIT 1
+	`, userID, provider).Scan(&lastUsed)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, fmt.Errorf("failed to get last usage: %w", err)
+	}
+	if lastUsed.Valid {
+		usage.LastUsedAt = &lastUsed.Time
+	}
+
+	// Most recent failure
+	var lastError sql.NullString
+	var lastErrorAt sql.NullTime
+	err = r.db.QueryRow(`
+		SELECT error_message, created_at FROM usage_metrics
+		WHERE user_id = ? AND provider = ? AND success = 0
+		ORDER BY created_at DESC LIMIT 1
+	`, userID, provider).Scan(&lastError, &lastErrorAt)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, fmt.Errorf("failed to get last error: %w", err)
+	}
+	if lastErrorAt.Valid {
+		usage.LastError = lastError.String
+		usage.LastErrorAt = &lastErrorAt.Time
+	}
+
+	// First failure in the current streak of failures (no success since)
+	var failingSince sql.NullTime
+	err = r.db.QueryRow(`
+		SELECT created_at FROM usage_metrics
+		WHERE user_id = ? AND provider = ? AND success = 0
+			AND created_at > COALESCE((
+				SELECT MAX(created_at) FROM usage_metrics
+				WHERE user_id = ? AND provider = ? AND success = 1
+			), '')
+		ORDER BY created_at ASC LIMIT 1
+	`, userID, provider, userID, provider).Scan(&failingSince)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, fmt.Errorf("failed to get failure streak: %w", err)
+	}
+	if failingSince.Valid {
+		usage.FailingSince = &failingSince.Time
+	}
+
+	return usage, nil
+}
+
+// GetUsageProfile sums a user's successful requests since the cutoff by
+// model, optionally for one request type. MonthlyCostUSD is left to the
+// caller.
+func (r *UsageRepository) GetUsageProfile(userID string, since time.Time, requestType string) ([]models.ModelUsageProfile, error) {
+	created, args := usageCreatedIn(since, time.Time{})
+	query := `
+		SELECT COALESCE(model_used, ''), COUNT(*), COALESCE(SUM(tokens_input), 0), COALESCE(SUM(tokens_output), 0)
+		FROM usage_metrics
+		WHERE user_id = ? AND ` + created + ` AND success = 1 AND (? = '' OR request_type = ?)
+		GROUP BY COALESCE(model_used, '')
+		ORDER BY COUNT(*) DESC
+	`
+
+	args = append([]interface{}{userID}, append(args, requestType, requestType)...)
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get usage profile: %w", err)
+	}
+	defer rows.Close()
+
+	profile := make([]models.ModelUsageProfile, 0)
+	for rows.Next() {
+		var p models.ModelUsageProfile
+		if err := rows.Scan(&p.Model, &p.Requests, &p.TokensInput, &p.TokensOutput); err != nil {
+			return nil, fmt.Errorf("failed to scan usage profile: %w", err)
+		}
+		profile = append(profile, p)
+	}
+
+	return profile, rows.Err()
+}
+
+// ExportUsage calls fn with each usage_metrics row matching filter, oldest
+// first, reading rows as fn consumes them. An error from fn stops the
+// export and is returned.
+func (r *UsageRepository) ExportUsage(filter models.UsageExportFilter, fn func(*models.UsageMetric) error) error {
+	created, args := usageCreatedIn(filter.From, filter.To)
+	rows, err := r.db.Query(`
+		SELECT id, user_id, request_type, COALESCE(resource_id, 0), tokens_input, tokens_output, tokens_total,
+			COALESCE(model_used, ''), cost_usd, duration_ms, COALESCE(endpoint, ''), COALESCE(provider, ''),
+			COALESCE(key_source, ''), success, COALESCE(error_message, ''), created_at
+		FROM usage_metrics
+		WHERE (? = '' OR user_id = ?) AND `+created+`
+		ORDER BY created_at, id
+	`, append([]interface{}{filter.UserID, filter.UserID}, args...)...)
+	if err != nil {
+		return fmt.Errorf("failed to export usage: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var m models.UsageMetric
+		if err := rows.Scan(&m.ID, &m.UserID, &m.RequestType, &m.ResourceID, &m.TokensInput, &m.TokensOutput, &m.TokensTotal,
+			&m.ModelUsed, &m.CostUSD, &m.DurationMs, &m.Endpoint, &m.Provider,
+			&m.KeySource, &m.Success, &m.ErrorMessage, &m.CreatedAt); err != nil {
+			return fmt.Errorf("failed to scan usage metric: %w", err)
+		}
+		if err := fn(&m); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// usageTimeseriesValues are the SQL aggregates of each time series metric
+var usageTimeseriesValues = map[string]string{
+	models.UsageMetricTokens:   "COALESCE(SUM(tokens_total), 0)",
+	models.UsageMetricCost:     "COALESCE(SUM(cost_usd), 0)",
+	models.UsageMetricRequests: "COUNT(*)",
+}
+
+// GetUsageTimeseries sums a metric of a user's usage created in [from, to)
+// into hourly or daily UTC buckets, leaving out buckets without usage
+func (r *UsageRepository) GetUsageTimeseries(userID, metric string, hourly bool, from, to time.Time) (map[time.Time]float64, error) {
+	value, ok := usageTimeseriesValues[metric]
+	if !ok {
+		return nil, fmt.Errorf("unknown usage metric %q", metric)
+	}
+	bucket := "strftime('%Y-%m-%dT00:00:00Z', created_at)"
+	if hourly {
+		bucket = "strftime('%Y-%m-%dT%H:00:00Z', created_at)"
+	}
+
+	created, args := usageCreatedIn(from, to)
+	rows, err := r.db.Query(`
+		SELECT `+bucket+` AS bucket, `+value+`
+		FROM usage_metrics
+		WHERE user_id = ? AND `+created+`
+		GROUP BY bucket
+	`, append([]interface{}{userID}, args...)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get usage time series: %w", err)
+	}
+	defer rows.Close()
+
+	buckets := make(map[time.Time]float64)
+	for rows.Next() {
+		var start string
+		var v float64
+		if err := rows.Scan(&start, &v); err != nil {
+			return nil, fmt.Errorf("failed to scan usage time series: %w", err)
+		}
+		t, err := time.Parse(time.RFC3339, start)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse usage bucket %q: %w", start, err)
+		}
+		buckets[t] = v
+	}
+	return buckets, rows.Err()
+}
+
+// GetCostSince sums the cost of a user's usage created at or after since
+func (r *UsageRepository) GetCostSince(userID string, since time.Time) (float64, error) {
+	var cost float64
+	err := r.db.QueryRow("SELECT COALESCE(SUM(cost_usd), 0) FROM usage_metrics WHERE user_id = ? AND julianday(created_at) >= julianday(?)", userID, since.UTC()).Scan(&cost)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get usage cost: %w", err)
+	}
+	return cost, nil
+}
+
+// usageResourceTables are the tables resources' titles are read from
+var usageResourceTables = map[string]string{
+	models.UsageResourceChat:     "chats",
+	models.UsageResourceDocument: "documents",
+}
+
+// GetUsageByResource sums a user's usage created in [from, to) per chat or
+// document, most expensive first, with the resource's title when it still
+// exists and is the user's. It also returns how many resources there are.
+func (r *UsageRepository) GetUsageByResource(userID, resourceType string, from, to time.Time, limit, offset int) ([]models.UsageByResource, int, error) {
+	table, ok := usageResourceTables[resourceType]
+	if !ok {
+		return nil, 0, fmt.Errorf("unknown usage resource type %q", resourceType)
+	}
+	where := `user_id = ? AND request_type = ? AND resource_id > 0
+		AND julianday(created_at) >= julianday(?) AND julianday(created_at) < julianday(?)`
+	args := []interface{}{userID, resourceType, from.UTC(), to.UTC()}
+
+	var total int
+	if err := r.db.QueryRow("SELECT COUNT(DISTINCT resource_id) FROM usage_metrics WHERE "+where, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count usage by resource: %w", err)
+	}
+
+	rows, err := r.db.Query(`
+		SELECT u.resource_id, COALESCE(t.title, ''), u.requests, u.tokens_input, u.tokens_output, u.tokens_total, u.cost_usd, u.last_used_at
+		FROM (
+			SELECT resource_id, COUNT(*) AS requests,
+				COALESCE(SUM(tokens_input), 0) AS tokens_input, COALESCE(SUM(tokens_output), 0) AS tokens_output,
+				COALESCE(SUM(tokens_total), 0) AS tokens_total, COALESCE(SUM(cost_usd), 0) AS cost_usd,
+				strftime('%Y-%m-%dT%H:%M:%SZ', MAX(julianday(created_at))) AS last_used_at
+			FROM usage_metrics
+			WHERE `+where+`
+			GROUP BY resource_id
+		) AS u
+		LEFT JOIN `+table+` t ON t.id = u.resource_id AND t.user_id = ?
+		ORDER BY u.cost_usd DESC, u.tokens_total DESC, u.resource_id
+		LIMIT ? OFFSET ?
+	`, append(args, userID, limit, offset)...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get usage by resource: %w", err)
+	}
+	defer rows.Close()
+
+	usage := make([]models.UsageByResource, 0)
+	for rows.Next() {
+		u := models.UsageByResource{ResourceType: resourceType}
+		var lastUsed string
+		if err := rows.Scan(&u.ResourceID, &u.Title, &u.Requests, &u.TokensInput, &u.TokensOutput, &u.TotalTokens, &u.TotalCostUSD, &lastUsed); err != nil {
+			return nil, 0, fmt.Errorf("failed to scan usage by resource: %w", err)
+		}
+		if u.LastUsedAt, err = time.Parse(time.RFC3339, lastUsed); err != nil {
+			return nil, 0, fmt.Errorf("failed to parse last use %q: %w", lastUsed, err)
+		}
+		usage = append(usage, u)
+	}
+	return usage, total, rows.Err()
+}
+
+// GetAdminUsageSummary sums the usage of all users created in [from, to)
+func (r *UsageRepository) GetAdminUsageSummary(from, to time.Time) (*models.AdminUsageSummary, error) {
+	source, args, err := UsageRollupSource(r.db, "", from, to)
+	if err != nil {
+		return nil, err
+	}
+	summary := &models.AdminUsageSummary{From: from, To: to, RequestsByType: make(map[string]int)}
+	err = r.db.QueryRow(`
+		SELECT COALESCE(SUM(requests), 0), COALESCE(SUM(successful), 0), COALESCE(SUM(failed), 0),
+			COALESCE(SUM(tokens_input), 0), COALESCE(SUM(tokens_output), 0), COALESCE(SUM(tokens_total), 0),
+			COALESCE(SUM(cost_usd), 0), COALESCE(CAST(SUM(duration_ms) AS REAL) / SUM(requests), 0),
+			COUNT(DISTINCT user_id)
+		FROM `+source+` AS usage
+	`, args...).Scan(&summary.TotalRequests, &summary.SuccessfulRequests, &summary.FailedRequests,
+		&summary.TotalTokensInput, &summary.TotalTokensOutput, &summary.TotalTokens,
+		&summary.TotalCostUSD, &summary.AverageDurationMs, &summary.ActiveUsers)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get usage summary: %w", err)
+	}
+
+	rows, err := r.db.Query("SELECT request_type, SUM(requests) FROM "+source+" AS usage GROUP BY request_type", args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get usage by request type: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var requestType string
+		var count int
+		if err := rows.Scan(&requestType, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan usage by request type: %w", err)
+		}
+		summary.RequestsByType[requestType] = count
+	}
+	return summary, rows.Err()
+}
+
+// usageRankColumns are what admin reports rank users by
+var usageRankColumns = map[string]string{
+	models.UsageRankCost:     "total_cost_usd",
+	models.UsageRankTokens:   "total_tokens",
+	models.UsageRankRequests: "requests",
+}
+
+// GetTopUsers returns the users with the most usage created in [from, to)
+// by cost, tokens or requests
+func (r *UsageRepository) GetTopUsers(from, to time.Time, rankBy string, limit int) ([]models.UserUsage, error) {
+	column, ok := usageRankColumns[rankBy]
+	if !ok {
+		return nil, fmt.Errorf("unknown usage rank %q", rankBy)
+	}
+	source, args, err := UsageRollupSource(r.db, "", from, to)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := r.db.Query(`
+		SELECT u.user_id, COALESCE(users.username, ''), COALESCE(users.email, ''), u.requests, u.total_tokens, u.total_cost_usd
+		FROM (
+			SELECT user_id, SUM(requests) AS requests, SUM(tokens_total) AS total_tokens, SUM(cost_usd) AS total_cost_usd
+			FROM `+source+` AS usage
+			GROUP BY user_id
+		) AS u
+		LEFT JOIN users ON CAST(users.id AS TEXT) = u.user_id
+		ORDER BY u.`+column+` DESC, u.user_id
+		LIMIT ?
+	`, append(args, limit)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get top users: %w", err)
+	}
+	defer rows.Close()
+
+	users := make([]models.UserUsage, 0)
+	for rows.Next() {
+		var u models.UserUsage
+		if err := rows.Scan(&u.UserID, &u.Username, &u.Email, &u.Requests, &u.TotalTokens, &u.TotalCostUSD); err != nil {
+			return nil, fmt.Errorf("failed to scan top user: %w", err)
+		}
+		users = append(users, u)
+	}
+	return users, rows.Err()
+}
+
+// GetUsageByModel sums the usage of all users created in [from, to) per
+// model, most expensive first
+func (r *UsageRepository) GetUsageByModel(from, to time.Time) ([]models.ModelUsage, error) {
+	source, args, err := UsageRollupSource(r.db, "", from, to)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := r.db.Query(`
+		SELECT model_used, SUM(requests), SUM(tokens_input), SUM(tokens_output), SUM(tokens_total),
+			SUM(cost_usd), COUNT(DISTINCT user_id)
+		FROM `+source+` AS usage
+		GROUP BY model_used
+		ORDER BY SUM(cost_usd) DESC, SUM(requests) DESC, model_used
+	`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get usage by model: %w", err)
+	}
+	defer rows.Close()
+
+	usage := make([]models.ModelUsage, 0)
+	for rows.Next() {
+		var m models.ModelUsage
+		if err := rows.Scan(&m.Model, &m.Requests, &m.TokensInput, &m.TokensOutput, &m.TotalTokens, &m.TotalCostUSD, &m.Users); err != nil {
+			return nil, fmt.Errorf("failed to scan usage by model: %w", err)
+		}
+		usage = append(usage, m)
+	}
+	return usage, rows.Err()
+}
@@ -0,0 +1,129 @@
+package repositories
+
+import (
+	"database/sql"
+	"sync"
+	"testing"
+	"time"
+)
+
+// createTestQuota gives userID a quota of dailyTokens tokens a day
+func createTestQuota(t *testing.T, conn *sql.DB, userID string, dailyTokens int) {
+	t.Helper()
+	if _, err := conn.Exec(`INSERT INTO user_quotas (user_id, daily_token_limit) VALUES (?, ?)`, userID, dailyTokens); err != nil {
+		t.Fatalf("create quota: %v", err)
+	}
+}
+
+func quotaHeld(t *testing.T, conn *sql.DB, userID string) (used, reserved int) {
+	t.Helper()
+	err := conn.QueryRow(`
+		SELECT q.daily_tokens_used, COALESCE((SELECT SUM(tokens) FROM quota_reservations r WHERE r.user_id = q.user_id), 0)
+		FROM user_quotas q WHERE q.user_id = ?
+	`, userID).Scan(&used, &reserved)
+	if err != nil {
+		t.Fatalf("read quota: %v", err)
+	}
+	return used, reserved
+}
+
+func TestReserveQuotaConcurrentlyNeverOvershoots(t *testing.T) {
+	conn := newTestDB(t)
+	repo := NewUsageRepository(conn)
+	createTestQuota(t, conn, "1", 1000)
+
+	const workers = 50
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		granted []int64
+		errs    []error
+	)
+	start := make(chan struct{})
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			id, err := repo.ReserveQuota("1", 100, 0, time.Now().Add(time.Minute))
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				errs = append(errs, err)
+			} else if id != 0 {
+				granted = append(granted, id)
+			}
+		}()
+	}
+	close(start)
+	wg.Wait()
+
+	for _, err := range errs {
+		t.Errorf("ReserveQuota: %v", err)
+	}
+	if len(granted) != 10 {
+		t.Errorf("granted %d reservations of 100 tokens against a limit of 1000, want 10", len(granted))
+	}
+	if _, reserved := quotaHeld(t, conn, "1"); reserved > 1000 {
+		t.Errorf("reserved %d tokens, over the limit of 1000", reserved)
+	}
+}
+
+func TestSettleAndReleaseReservation(t *testing.T) {
+	conn := newTestDB(t)
+	repo := NewUsageRepository(conn)
+	createTestQuota(t, conn, "1", 300)
+	expires := time.Now().Add(time.Minute)
+
+	var ids []int64
+	for i := 0; i < 3; i++ {
+		id, err := repo.ReserveQuota("1", 100, 0, expires)
+		if err != nil || id == 0 {
+			t.Fatalf("ReserveQuota %d = %d, %v", i, id, err)
+		}
+		ids = append(ids, id)
+	}
+	if id, err := repo.ReserveQuota("1", 1, 0, expires); err != nil || id != 0 {
+		t.Fatalf("ReserveQuota over the limit = %d, %v, want denied", id, err)
+	}
+
+	// Settling charges the actual usage instead of the estimate
+	if err := repo.SettleReservation(ids[0], "1", 40, 0); err != nil {
+		t.Fatalf("SettleReservation: %v", err)
+	}
+	if used, reserved := quotaHeld(t, conn, "1"); used != 40 || reserved != 200 {
+		t.Errorf("after settling: used %d, reserved %d, want 40 and 200", used, reserved)
+	}
+
+	// Releasing refunds the reservation without charging anything
+	if err := repo.ReleaseReservation(ids[1]); err != nil {
+		t.Fatalf("ReleaseReservation: %v", err)
+	}
+	if used, reserved := quotaHeld(t, conn, "1"); used != 40 || reserved != 100 {
+		t.Errorf("after releasing: used %d, reserved %d, want 40 and 100", used, reserved)
+	}
+
+	id, err := repo.ReserveQuota("1", 160, 0, expires)
+	if err != nil || id == 0 {
+		t.Errorf("ReserveQuota of the freed 160 tokens = %d, %v, want granted", id, err)
+	}
+	if id, err := repo.ReserveQuota("1", 1, 0, expires); err != nil || id != 0 {
+		t.Errorf("ReserveQuota past the limit again = %d, %v, want denied", id, err)
+	}
+}
+
+func TestReserveQuotaIgnoresExpiredReservations(t *testing.T) {
+	conn := newTestDB(t)
+	repo := NewUsageRepository(conn)
+	createTestQuota(t, conn, "1", 100)
+
+	if id, err := repo.ReserveQuota("1", 100, 0, time.Now().Add(-time.Second)); err != nil || id == 0 {
+		t.Fatalf("ReserveQuota = %d, %v", id, err)
+	}
+	if id, err := repo.ReserveQuota("1", 100, 0, time.Now().Add(time.Minute)); err != nil || id == 0 {
+		t.Errorf("ReserveQuota after the first expired = %d, %v, want granted", id, err)
+	}
+	if _, reserved := quotaHeld(t, conn, "1"); reserved != 100 {
+		t.Errorf("reserved %d tokens, want the expired reservation dropped", reserved)
+	}
+}
@@ -0,0 +1,320 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"lio-ai/internal/models"
+)
+
+// rollupBucketFormat is how rollup buckets and the rollup watermark are
+// stored, always in UTC
+const rollupBucketFormat = "2006-01-02 15:04:05"
+
+// rawTimeFormat is how times are compared with usage_metrics.created_at
+const rawTimeFormat = "2006-01-02 15:04:05.999999999"
+
+// usageCreatedIn returns a condition on usage_metrics.created_at for rows
+// created in [since, until) and its args; a zero since or until leaves
+// that end open. The date bounds keep created_at's index usable whatever
+// format or zone a row's time was stored in; julianday then compares the
+// instants exactly.
+func usageCreatedIn(since, until time.Time) (string, []interface{}) {
+	conds := []string{"created_at IS NOT NULL"}
+	var args []interface{}
+	if !since.IsZero() {
+		since = since.UTC()
+		conds = append(conds, "created_at >= ?", "julianday(created_at) >= julianday(?)")
+		args = append(args, since.AddDate(0, 0, -1).Format("2006-01-02"), since.Format(rawTimeFormat))
+	}
+	if !until.IsZero() {
+		until = until.UTC()
+		conds = append(conds, "created_at < ?", "julianday(created_at) < julianday(?)")
+		args = append(args, until.AddDate(0, 0, 2).Format("2006-01-02"), until.Format(rawTimeFormat))
+	}
+	return strings.Join(conds, " AND "), args
+}
+
+// rollupMaxHours is the most hours rolled up in one transaction, so a
+// backlog is caught up on without holding the write lock for long
+const rollupMaxHours = 24
+
+// rollupColumns are the columns of both rollup tables and of the rows
+// UsageRollupSource selects
+const rollupColumns = `user_id, request_type, endpoint, model_used, requests, successful, failed,
+	tokens_input, tokens_output, tokens_total, cost_usd, duration_ms`
+
+// rawRollupColumns are rollupColumns of a single usage_metrics row
+const rawRollupColumns = `user_id, request_type, COALESCE(endpoint, '') AS endpoint, COALESCE(model_used, '') AS model_used,
+	1 AS requests, CASE WHEN success = 1 THEN 1 ELSE 0 END AS successful, CASE WHEN success = 0 THEN 1 ELSE 0 END AS failed,
+	COALESCE(tokens_input, 0) AS tokens_input, COALESCE(tokens_output, 0) AS tokens_output,
+	COALESCE(tokens_total, 0) AS tokens_total, COALESCE(cost_usd, 0) AS cost_usd, COALESCE(duration_ms, 0) AS duration_ms`
+
+// rollupSums sums rollupColumns by bucket and the grouping columns
+const rollupSums = `SUM(requests), SUM(successful), SUM(failed), SUM(tokens_input), SUM(tokens_output),
+	SUM(tokens_total), SUM(cost_usd), SUM(duration_ms)`
+
+// UsageRollupWatermark returns the hour usage before which is in the
+// rollups, or the zero time when nothing has been rolled up yet
+func UsageRollupWatermark(db *sql.DB) (time.Time, error) {
+	var until string
+	err := db.QueryRow("SELECT rolled_until FROM usage_rollup_state WHERE id = 1").Scan(&until)
+	if err == sql.ErrNoRows {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to get usage rollup watermark: %w", err)
+	}
+	return time.ParseInLocation(rollupBucketFormat, until, time.UTC)
+}
+
+// UsageRollupSource returns a subquery with rollupColumns for the usage of
+// userID (everyone when empty) created in [since, until), or at or after
+// since when until is zero, and its args. Whole days before the watermark
+// are read from the daily rollups, other whole hours before it from the
+// hourly ones, and the rest - the current hour, and the part hours since
+// and until fall in - from usage_metrics.
+func UsageRollupSource(db *sql.DB, userID string, since, until time.Time) (string, []interface{}, error) {
+	watermark, err := UsageRollupWatermark(db)
+	if err != nil {
+		return "", nil, err
+	}
+	since = since.UTC()
+	firstHour := since.Truncate(time.Hour)
+	if firstHour.Before(since) {
+		firstHour = firstHour.Add(time.Hour)
+	}
+	firstDay := time.Date(firstHour.Year(), firstHour.Month(), firstHour.Day(), 0, 0, 0, 0, time.UTC)
+	if firstDay.Before(firstHour) {
+		firstDay = firstDay.AddDate(0, 0, 1)
+	}
+	rolledTo := watermark
+	if !until.IsZero() {
+		if lastHour := until.UTC().Truncate(time.Hour); lastHour.Before(rolledTo) {
+			rolledTo = lastHour
+		}
+	}
+	lastDay := time.Date(rolledTo.Year(), rolledTo.Month(), rolledTo.Day(), 0, 0, 0, 0, time.UTC)
+
+	userFilter := ""
+	if userID != "" {
+		userFilter = " AND user_id = ?"
+	}
+	var parts []string
+	var args []interface{}
+	addPart := func(query string, partArgs ...interface{}) {
+		parts = append(parts, query+userFilter)
+		args = append(args, partArgs...)
+		if userID != "" {
+			args = append(args, userID)
+		}
+	}
+
+	rawFrom := firstHour
+	if rolledTo.After(firstHour) {
+		if firstDay.Before(lastDay) {
+			addPart(`SELECT `+rollupColumns+` FROM usage_rollups_daily WHERE bucket_start >= ? AND bucket_start < ?`,
+				firstDay.Format(rollupBucketFormat), lastDay.Format(rollupBucketFormat))
+			addPart(`SELECT `+rollupColumns+` FROM usage_rollups_hourly
+				WHERE ((bucket_start >= ? AND bucket_start < ?) OR (bucket_start >= ? AND bucket_start < ?))`,
+				firstHour.Format(rollupBucketFormat), firstDay.Format(rollupBucketFormat),
+				lastDay.Format(rollupBucketFormat), rolledTo.Format(rollupBucketFormat))
+		} else {
+			addPart(`SELECT `+rollupColumns+` FROM usage_rollups_hourly WHERE bucket_start >= ? AND bucket_start < ?`,
+				firstHour.Format(rollupBucketFormat), rolledTo.Format(rollupBucketFormat))
+		}
+		rawFrom = rolledTo
+	}
+
+	// The date bound keeps created_at's index usable whatever format a row's
+	// time was stored in; julianday then compares the instants exactly
+	raw := `SELECT ` + rawRollupColumns + ` FROM usage_metrics
+		WHERE created_at >= ? AND julianday(created_at) >= julianday(?)
+		AND (julianday(created_at) < julianday(?) OR julianday(created_at) >= julianday(?))`
+	rawArgs := []interface{}{since.AddDate(0, 0, -1).Format("2006-01-02"), since.Format(rawTimeFormat),
+		firstHour.Format(rollupBucketFormat), rawFrom.Format(rollupBucketFormat)}
+	if !until.IsZero() {
+		raw += ` AND julianday(created_at) < julianday(?)`
+		rawArgs = append(rawArgs, until.UTC().Format(rawTimeFormat))
+	}
+	addPart(raw, rawArgs...)
+
+	return "(" + strings.Join(parts, " UNION ALL ") + ")", args, nil
+}
+
+// FilteredUsageSource is UsageRollupSource for the usage filter matches.
+// Model and endpoint filter the rollups; provider and success are not
+// rolled up, so filtering on either reads usage_metrics alone.
+func FilteredUsageSource(db *sql.DB, userID string, since, until time.Time, filter *models.UsageFilter) (string, []interface{}, error) {
+	if filter == nil {
+		filter = &models.UsageFilter{}
+	}
+	var conds []string
+	var args []interface{}
+	if filter.Model != "" {
+		conds = append(conds, "model_used = ?")
+		args = append(args, filter.Model)
+	}
+	if filter.Endpoint != "" {
+		conds = append(conds, "endpoint = ?")
+		args = append(args, filter.Endpoint)
+	}
+
+	if filter.Provider == "" && filter.Success == nil {
+		source, sourceArgs, err := UsageRollupSource(db, userID, since, until)
+		if err != nil || len(conds) == 0 {
+			return source, sourceArgs, err
+		}
+		return "(SELECT * FROM " + source + " AS unfiltered WHERE " + strings.Join(conds, " AND ") + ")",
+			append(sourceArgs, args...), nil
+	}
+
+	timeCond, rawArgs := usageCreatedIn(since, until)
+	raw := []string{timeCond}
+	if userID != "" {
+		raw = append(raw, "user_id = ?")
+		rawArgs = append(rawArgs, userID)
+	}
+	if filter.Provider != "" {
+		raw = append(raw, "provider = ?")
+		rawArgs = append(rawArgs, filter.Provider)
+	}
+	if filter.Success != nil {
+		raw = append(raw, "success = ?")
+		rawArgs = append(rawArgs, *filter.Success)
+	}
+	source := "(SELECT " + rawRollupColumns + " FROM usage_metrics WHERE " + strings.Join(raw, " AND ") + ")"
+	if len(conds) > 0 {
+		source = "(SELECT * FROM " + source + " AS unfiltered WHERE " + strings.Join(conds, " AND ") + ")"
+	}
+	return source, append(rawArgs, args...), nil
+}
+
+// RollupUsage sums usage_metrics into the hourly and daily rollups for the
+// hours between the watermark and until (an hour boundary), and moves the
+// watermark to until. It returns how many hours were rolled up. The first
+// run starts from the hour of the oldest usage. Usage recorded into an hour
+// after it is rolled up is only counted once the hour is rolled up again,
+// so until should trail the current time.
+func (r *UsageRepository) RollupUsage(until time.Time) (int, error) {
+	until = until.UTC().Truncate(time.Hour)
+	from, err := UsageRollupWatermark(r.db)
+	if err != nil {
+		return 0, err
+	}
+	if from.IsZero() {
+		var oldest sql.NullString
+		if err := r.db.QueryRow("SELECT strftime('%Y-%m-%d %H:00:00', MIN(created_at)) FROM usage_metrics").Scan(&oldest); err != nil {
+			return 0, fmt.Errorf("failed to find oldest usage: %w", err)
+		}
+		if !oldest.Valid {
+			// No usage yet: the rollups start here
+			return 0, r.setRollupWatermark(r.db, until)
+		}
+		if from, err = time.ParseInLocation(rollupBucketFormat, oldest.String, time.UTC); err != nil {
+			return 0, fmt.Errorf("failed to parse oldest usage time %q: %w", oldest.String, err)
+		}
+	}
+
+	hours := 0
+	for from.Before(until) {
+		end := from.Add(rollupMaxHours * time.Hour)
+		if end.After(until) {
+			end = until
+		}
+		if err := r.rollupHours(from, end); err != nil {
+			return hours, err
+		}
+		hours += int(end.Sub(from) / time.Hour)
+		from = end
+	}
+	return hours, nil
+}
+
+// rollupHours rebuilds the hourly rollups of [from, to), the daily rollups
+// of the days those hours fall in, and moves the watermark to to
+func (r *UsageRepository) rollupHours(from, to time.Time) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin usage rollup: %w", err)
+	}
+	defer tx.Rollback()
+
+	fromBucket, toBucket := from.Format(rollupBucketFormat), to.Format(rollupBucketFormat)
+	if _, err := tx.Exec("DELETE FROM usage_rollups_hourly WHERE bucket_start >= ? AND bucket_start < ?", fromBucket, toBucket); err != nil {
+		return fmt.Errorf("failed to clear hourly usage rollups: %w", err)
+	}
+	_, err = tx.Exec(`
+		INSERT INTO usage_rollups_hourly (bucket_start, `+rollupColumns+`)
+		SELECT bucket_start, user_id, request_type, endpoint, model_used, `+rollupSums+`
+		FROM (
+			SELECT strftime('%Y-%m-%d %H:00:00', created_at) AS bucket_start, `+rawRollupColumns+`
+			FROM usage_metrics
+			WHERE created_at >= ? AND julianday(created_at) >= julianday(?) AND julianday(created_at) < julianday(?)
+		) AS raw
+		GROUP BY bucket_start, user_id, request_type, endpoint, model_used
+	`, from.AddDate(0, 0, -1).Format("2006-01-02"), fromBucket, toBucket)
+	if err != nil {
+		return fmt.Errorf("failed to roll up hourly usage: %w", err)
+	}
+
+	// Every day the hours fall in is summed again from its hourly rollups
+	firstDay := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.UTC)
+	lastDay := time.Date(to.Year(), to.Month(), to.Day(), 0, 0, 0, 0, time.UTC)
+	if lastDay.Before(to) {
+		lastDay = lastDay.AddDate(0, 0, 1)
+	}
+	dayFrom, dayTo := firstDay.Format(rollupBucketFormat), lastDay.Format(rollupBucketFormat)
+	if _, err := tx.Exec("DELETE FROM usage_rollups_daily WHERE bucket_start >= ? AND bucket_start < ?", dayFrom, dayTo); err != nil {
+		return fmt.Errorf("failed to clear daily usage rollups: %w", err)
+	}
+	_, err = tx.Exec(`
+		INSERT INTO usage_rollups_daily (bucket_start, `+rollupColumns+`)
+		SELECT substr(bucket_start, 1, 10) || ' 00:00:00', user_id, request_type, endpoint, model_used, `+rollupSums+`
+		FROM usage_rollups_hourly
+		WHERE bucket_start >= ? AND bucket_start < ?
+		GROUP BY substr(bucket_start, 1, 10), user_id, request_type, endpoint, model_used
+	`, dayFrom, dayTo)
+	if err != nil {
+		return fmt.Errorf("failed to roll up daily usage: %w", err)
+	}
+
+	if err := r.setRollupWatermark(tx, to); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit usage rollup: %w", err)
+	}
+	return nil
+}
+
+func (r *UsageRepository) setRollupWatermark(exec interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}, until time.Time) error {
+	_, err := exec.Exec(`
+		INSERT INTO usage_rollup_state (id, rolled_until, updated_at) VALUES (1, ?, ?)
+		ON CONFLICT(id) DO UPDATE SET rolled_until = excluded.rolled_until, updated_at = excluded.updated_at
+	`, until.UTC().Format(rollupBucketFormat), time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to save usage rollup watermark: %w", err)
+	}
+	return nil
+}
+
+// moveUsageRollupsSQL moves the rows of a rollup table from one user (the
+// second arg) to another (the first), adding them to the rows the other
+// user already has for the same buckets
+func moveUsageRollupsSQL(table string) string {
+	return `
+		INSERT INTO ` + table + ` (bucket_start, ` + rollupColumns + `)
+		SELECT bucket_start, ?, request_type, endpoint, model_used, requests, successful, failed,
+			tokens_input, tokens_output, tokens_total, cost_usd, duration_ms
+		FROM ` + table + ` WHERE user_id = ?
+		ON CONFLICT(bucket_start, user_id, request_type, endpoint, model_used) DO UPDATE SET
+			requests = requests + excluded.requests, successful = successful + excluded.successful,
+			failed = failed + excluded.failed, tokens_input = tokens_input + excluded.tokens_input,
+			tokens_output = tokens_output + excluded.tokens_output, tokens_total = tokens_total + excluded.tokens_total,
+			cost_usd = cost_usd + excluded.cost_usd, duration_ms = duration_ms + excluded.duration_ms`
+}
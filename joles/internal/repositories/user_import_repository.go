@@ -0,0 +1,105 @@
+package repositories
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"lio-ai/internal/models"
+)
+
+// UserImportRepository stores bulk user import jobs
+type UserImportRepository struct {
+	db *sql.DB
+}
+
+// NewUserImportRepository creates a new user import repository
+func NewUserImportRepository(db *sql.DB) *UserImportRepository {
+	return &UserImportRepository{db: db}
+}
+
+// Create stores a queued import job
+func (r *UserImportRepository) Create(job *models.UserImportJob) error {
+	now := time.Now()
+	job.Status = models.ImportStatusQueued
+	result, err := r.db.Exec(`
+		INSERT INTO user_import_jobs (created_by, source, status, total_rows, errors, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, job.CreatedBy, job.Source, job.Status, job.TotalRows, "[]", now)
+	if err != nil {
+		return fmt.Errorf("failed to create import job: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to get last insert id: %w", err)
+	}
+	job.ID = id
+	job.CreatedAt = now
+	if job.Errors == nil {
+		job.Errors = []models.UserImportError{}
+	}
+	return nil
+}
+
+// UpdateProgress saves a job's status, counters and row errors
+func (r *UserImportRepository) UpdateProgress(job *models.UserImportJob) error {
+	errorsJSON, err := json.Marshal(job.Errors)
+	if err != nil {
+		return fmt.Errorf("failed to encode import errors: %w", err)
+	}
+	_, err = r.db.Exec(`
+		UPDATE user_import_jobs
+		SET status = ?, processed = ?, created = ?, failed = ?, errors = ?, completed_at = ?
+		WHERE id = ?
+	`, job.Status, job.Processed, job.Created, job.Failed, string(errorsJSON), job.CompletedAt, job.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update import job: %w", err)
+	}
+	return nil
+}
+
+// GetByID retrieves an import job, or nil if it does not exist
+func (r *UserImportRepository) GetByID(id int64) (*models.UserImportJob, error) {
+	job := &models.UserImportJob{}
+	var errorsJSON sql.NullString
+	var completedAt sql.NullTime
+	err := r.db.QueryRow(`
+		SELECT id, created_by, source, status, total_rows, processed, created, failed, errors, created_at, completed_at
+		FROM user_import_jobs
+		WHERE id = ?
+	`, id).Scan(
+		&job.ID, &job.CreatedBy, &job.Source, &job.Status, &job.TotalRows, &job.Processed,
+		&job.Created, &job.Failed, &errorsJSON, &job.CreatedAt, &completedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get import job: %w", err)
+	}
+
+	job.Errors = []models.UserImportError{}
+	if errorsJSON.Valid && errorsJSON.String != "" {
+		if err := json.Unmarshal([]byte(errorsJSON.String), &job.Errors); err != nil {
+			return nil, fmt.Errorf("failed to decode import errors: %w", err)
+		}
+	}
+	if completedAt.Valid {
+		job.CompletedAt = &completedAt.Time
+	}
+	return job, nil
+}
+
+// FailInterrupted completes jobs left running by a previous process
+func (r *UserImportRepository) FailInterrupted() error {
+	_, err := r.db.Exec(`
+		UPDATE user_import_jobs SET status = ?, completed_at = ?
+		WHERE status IN (?, ?)
+	`, models.ImportStatusInterrupted, time.Now(), models.ImportStatusQueued, models.ImportStatusRunning)
+	if err != nil {
+		return fmt.Errorf("failed to update interrupted import jobs: %w", err)
+	}
+	return nil
+}
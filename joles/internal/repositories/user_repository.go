@@ -22,10 +22,15 @@ func NewUserRepository(db *sql.DB) *UserRepository {
 // Create inserts a new user
 func (r *UserRepository) Create(user *models.User) error {
 	query := `
-		INSERT INTO users (username, email, password_hash, full_name, role, is_active, created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		INSERT INTO users (username, email, password_hash, full_name, role, plan, is_active, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
 
+	plan := user.Plan
+	if plan == "" {
+		plan = "free"
+	}
+
 	now := time.Now()
 	result, err := r.db.Exec(
 		query,
@@ -34,6 +39,7 @@ func (r *UserRepository) Create(user *models.User) error {
 		user.PasswordHash,
 		user.FullName,
 		user.Role,
+		plan,
 		user.IsActive,
 		now,
 		now,
@@ -52,6 +58,9 @@ func (r *UserRepository) Create(user *models.User) error {
 	id, err := result.LastInsertId()
 	if err == nil {
 		user.ID = id
+		user.Plan = plan
+		user.CreatedAt = now
+		user.UpdatedAt = now
 	}
 
 	return nil
@@ -60,7 +69,7 @@ func (r *UserRepository) Create(user *models.User) error {
 // GetByEmail retrieves a user by email
 func (r *UserRepository) GetByEmail(email string) (*models.User, error) {
 	query := `
-		SELECT id, username, email, password_hash, full_name, role, is_active, created_at, updated_at
+		SELECT id, username, email, password_hash, full_name, role, COALESCE(plan, 'free'), is_active, created_at, updated_at
 		FROM users
 		WHERE email = ? AND is_active = 1
 	`
@@ -73,6 +82,7 @@ func (r *UserRepository) GetByEmail(email string) (*models.User, error) {
 		&user.PasswordHash,
 		&user.FullName,
 		&user.Role,
+		&user.Plan,
 		&user.IsActive,
 		&user.CreatedAt,
 		&user.UpdatedAt,
@@ -91,7 +101,7 @@ func (r *UserRepository) GetByEmail(email string) (*models.User, error) {
 // GetByUsername retrieves a user by username
 func (r *UserRepository) GetByUsername(username string) (*models.User, error) {
 	query := `
-		SELECT id, username, email, password_hash, full_name, role, is_active, created_at, updated_at
+		SELECT id, username, email, password_hash, full_name, role, COALESCE(plan, 'free'), is_active, created_at, updated_at
 		FROM users
 		WHERE username = ? AND is_active = 1
 	`
@@ -104,6 +114,7 @@ func (r *UserRepository) GetByUsername(username string) (*models.User, error) {
 		&user.PasswordHash,
 		&user.FullName,
 		&user.Role,
+		&user.Plan,
 		&user.IsActive,
 		&user.CreatedAt,
 		&user.UpdatedAt,
@@ -122,7 +133,7 @@ func (r *UserRepository) GetByUsername(username string) (*models.User, error) {
 // GetByID retrieves a user by ID
 func (r *UserRepository) GetByID(id int64) (*models.User, error) {
 	query := `
-		SELECT id, username, email, password_hash, full_name, role, is_active, created_at, updated_at
+		SELECT id, username, email, password_hash, full_name, role, COALESCE(plan, 'free'), is_active, created_at, updated_at
 		FROM users
 		WHERE id = ? AND is_active = 1
 	`
@@ -135,6 +146,7 @@ func (r *UserRepository) GetByID(id int64) (*models.User, error) {
 		&user.PasswordHash,
 		&user.FullName,
 		&user.Role,
+		&user.Plan,
 		&user.IsActive,
 		&user.CreatedAt,
 		&user.UpdatedAt,
@@ -168,3 +180,114 @@ func (r *UserRepository) UpdateLastLogin(userID int64) error {
 	_, err := r.db.Exec(query, time.Now(), userID)
 	return err
 }
+
+// ClaimOnboarding marks a user's sample content as provisioned, reporting
+// whether this call did so; only the first claim for a user succeeds
+func (r *UserRepository) ClaimOnboarding(userID int64) (bool, error) {
+	result, err := r.db.Exec(`UPDATE users SET onboarded_at = ? WHERE id = ? AND onboarded_at IS NULL`, time.Now(), userID)
+	if err != nil {
+		return false, fmt.Errorf("failed to claim onboarding: %w", err)
+	}
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
+
+// ActivateWithPassword sets the password of an invited account and activates it
+func (r *UserRepository) ActivateWithPassword(userID int64, passwordHash string) error {
+	query := `UPDATE users SET password_hash = ?, is_active = 1, updated_at = ? WHERE id = ?`
+	_, err := r.db.Exec(query, passwordHash, time.Now(), userID)
+	return err
+}
+
+// allUserColumns selects a user regardless of is_active, for provisioning
+const allUserColumns = `id, username, email, password_hash, COALESCE(full_name, ''), role, COALESCE(plan, 'free'), is_active, created_at, updated_at`
+
+// FindByID retrieves a user by ID, including deactivated and invited users
+func (r *UserRepository) FindByID(id int64) (*models.User, error) {
+	user, err := scanUser(r.db.QueryRow("SELECT "+allUserColumns+" FROM users WHERE id = ?", id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return user, err
+}
+
+// FindByEmail retrieves a user by email, including deactivated and invited users
+func (r *UserRepository) FindByEmail(email string) (*models.User, error) {
+	user, err := scanUser(r.db.QueryRow("SELECT "+allUserColumns+" FROM users WHERE email = ? COLLATE NOCASE", email))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return user, err
+}
+
+// List retrieves users ordered by ID, including deactivated and invited users
+func (r *UserRepository) List(limit, offset int) ([]models.User, error) {
+	rows, err := r.db.Query("SELECT "+allUserColumns+" FROM users ORDER BY id ASC LIMIT ? OFFSET ?", limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list users: %w", err)
+	}
+	defer rows.Close()
+
+	users := make([]models.User, 0)
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, *user)
+	}
+	return users, nil
+}
+
+// Count returns the number of users, including deactivated and invited users
+func (r *UserRepository) Count() (int, error) {
+	var count int
+	if err := r.db.QueryRow("SELECT COUNT(*) FROM users").Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count users: %w", err)
+	}
+	return count, nil
+}
+
+// UsernameExists reports whether a username is taken
+func (r *UserRepository) UsernameExists(username string) (bool, error) {
+	var count int
+	if err := r.db.QueryRow("SELECT COUNT(*) FROM users WHERE username = ?", username).Scan(&count); err != nil {
+		return false, fmt.Errorf("failed to check username: %w", err)
+	}
+	return count > 0, nil
+}
+
+// UpdateProvisioned updates the fields managed by provisioning
+func (r *UserRepository) UpdateProvisioned(user *models.User) error {
+	query := `UPDATE users SET full_name = ?, role = ?, plan = ?, is_active = ?, updated_at = ? WHERE id = ?`
+	now := time.Now()
+	if _, err := r.db.Exec(query, user.FullName, user.Role, user.Plan, user.IsActive, now, user.ID); err != nil {
+		return fmt.Errorf("failed to update user: %w", err)
+	}
+	user.UpdatedAt = now
+	return nil
+}
+
+// scanUser scans a user selected with allUserColumns
+func scanUser(row interface{ Scan(...interface{}) error }) (*models.User, error) {
+	user := &models.User{}
+	err := row.Scan(
+		&user.ID,
+		&user.Username,
+		&user.Email,
+		&user.PasswordHash,
+		&user.FullName,
+		&user.Role,
+		&user.Plan,
+		&user.IsActive,
+		&user.CreatedAt,
+		&user.UpdatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan user: %w", err)
+	}
+	return user, nil
+}
@@ -0,0 +1,220 @@
+package repositories
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"lio-ai/internal/models"
+)
+
+// WebhookRepository handles users' webhooks and their delivery logs
+type WebhookRepository struct {
+	db *sql.DB
+}
+
+// NewWebhookRepository creates a new webhook repository
+func NewWebhookRepository(db *sql.DB) *WebhookRepository {
+	return &WebhookRepository{db: db}
+}
+
+const webhookColumns = "id, user_id, url, events, secret, active, created_at, updated_at"
+
+// Create stores a new webhook
+func (r *WebhookRepository) Create(w *models.Webhook) error {
+	now := time.Now()
+	result, err := r.db.Exec(`
+		INSERT INTO webhooks (user_id, url, events, secret, active, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, w.UserID, w.URL, strings.Join(w.Events, ","), w.Secret, w.Active, now, now)
+	if err != nil {
+		return fmt.Errorf("failed to create webhook: %w", err)
+	}
+	w.ID, err = result.LastInsertId()
+	w.CreatedAt, w.UpdatedAt = now, now
+	return err
+}
+
+// ListByUser retrieves a user's webhooks, oldest first
+func (r *WebhookRepository) ListByUser(userID string) ([]models.Webhook, error) {
+	rows, err := r.db.Query("SELECT "+webhookColumns+" FROM webhooks WHERE user_id = ? ORDER BY id", userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhooks: %w", err)
+	}
+	defer rows.Close()
+
+	webhooks := make([]models.Webhook, 0)
+	for rows.Next() {
+		w, err := scanWebhook(rows)
+		if err != nil {
+			return nil, err
+		}
+		webhooks = append(webhooks, *w)
+	}
+	return webhooks, rows.Err()
+}
+
+// ListForEvent retrieves a user's active webhooks subscribed to event
+func (r *WebhookRepository) ListForEvent(userID, event string) ([]models.Webhook, error) {
+	webhooks, err := r.ListByUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	subscribed := webhooks[:0]
+	for _, w := range webhooks {
+		if !w.Active {
+			continue
+		}
+		for _, e := range w.Events {
+			if e == event {
+				subscribed = append(subscribed, w)
+				break
+			}
+		}
+	}
+	return subscribed, nil
+}
+
+// CountByUser counts a user's webhooks
+func (r *WebhookRepository) CountByUser(userID string) (int, error) {
+	var count int
+	if err := r.db.QueryRow("SELECT COUNT(*) FROM webhooks WHERE user_id = ?", userID).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count webhooks: %w", err)
+	}
+	return count, nil
+}
+
+// Get retrieves one of a user's webhooks, or nil when there is none
+func (r *WebhookRepository) Get(id int64, userID string) (*models.Webhook, error) {
+	row := r.db.QueryRow("SELECT "+webhookColumns+" FROM webhooks WHERE id = ? AND user_id = ?", id, userID)
+	w, err := scanWebhook(row)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return w, err
+}
+
+// Delete removes one of a user's webhooks and its deliveries, reporting
+// whether it existed
+func (r *WebhookRepository) Delete(id int64, userID string) (bool, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec("DELETE FROM webhooks WHERE id = ? AND user_id = ?", id, userID)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete webhook: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return false, nil
+	}
+	if _, err := tx.Exec("DELETE FROM webhook_deliveries WHERE webhook_id = ?", id); err != nil {
+		return false, fmt.Errorf("failed to delete webhook deliveries: %w", err)
+	}
+	return true, tx.Commit()
+}
+
+// RecordDelivery stores a delivery attempt, dropping the webhook's oldest
+// deliveries beyond keep
+func (r *WebhookRepository) RecordDelivery(d *models.WebhookDelivery, keep int) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	result, err := tx.Exec(`
+		INSERT INTO webhook_deliveries (webhook_id, event_id, event_type, payload, status_code, success,
+			latency_ms, response_excerpt, error, is_test, retry_of, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, d.WebhookID, d.EventID, d.EventType, string(d.Payload), d.StatusCode, d.Success,
+		d.LatencyMs, d.ResponseExcerpt, d.Error, d.Test, d.RetryOf, now)
+	if err != nil {
+		return fmt.Errorf("failed to record webhook delivery: %w", err)
+	}
+	if d.ID, err = result.LastInsertId(); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`
+		DELETE FROM webhook_deliveries WHERE webhook_id = ? AND id NOT IN (
+			SELECT id FROM webhook_deliveries WHERE webhook_id = ? ORDER BY id DESC LIMIT ?
+		)
+	`, d.WebhookID, d.WebhookID, keep); err != nil {
+		return fmt.Errorf("failed to prune webhook deliveries: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit webhook delivery: %w", err)
+	}
+	d.CreatedAt = now
+	return nil
+}
+
+const webhookDeliveryColumns = `id, webhook_id, event_id, event_type, payload, status_code, success, latency_ms,
+	COALESCE(response_excerpt, ''), COALESCE(error, ''), is_test, retry_of, created_at`
+
+// ListDeliveries retrieves a webhook's most recent deliveries, newest first
+func (r *WebhookRepository) ListDeliveries(webhookID int64, limit int) ([]models.WebhookDelivery, error) {
+	rows, err := r.db.Query("SELECT "+webhookDeliveryColumns+" FROM webhook_deliveries WHERE webhook_id = ? ORDER BY id DESC LIMIT ?",
+		webhookID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	deliveries := make([]models.WebhookDelivery, 0)
+	for rows.Next() {
+		d, err := scanWebhookDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, *d)
+	}
+	return deliveries, rows.Err()
+}
+
+// GetDelivery retrieves one of a webhook's deliveries, or nil when there
+// is none
+func (r *WebhookRepository) GetDelivery(id, webhookID int64) (*models.WebhookDelivery, error) {
+	row := r.db.QueryRow("SELECT "+webhookDeliveryColumns+" FROM webhook_deliveries WHERE id = ? AND webhook_id = ?", id, webhookID)
+	d, err := scanWebhookDelivery(row)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return d, err
+}
+
+func scanWebhook(row interface{ Scan(...interface{}) error }) (*models.Webhook, error) {
+	var w models.Webhook
+	var events string
+	if err := row.Scan(&w.ID, &w.UserID, &w.URL, &events, &w.Secret, &w.Active, &w.CreatedAt, &w.UpdatedAt); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to scan webhook: %w", err)
+	}
+	w.Events = strings.Split(events, ",")
+	return &w, nil
+}
+
+func scanWebhookDelivery(row interface{ Scan(...interface{}) error }) (*models.WebhookDelivery, error) {
+	var d models.WebhookDelivery
+	var payload string
+	var retryOf sql.NullInt64
+	if err := row.Scan(&d.ID, &d.WebhookID, &d.EventID, &d.EventType, &payload, &d.StatusCode, &d.Success, &d.LatencyMs,
+		&d.ResponseExcerpt, &d.Error, &d.Test, &retryOf, &d.CreatedAt); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to scan webhook delivery: %w", err)
+	}
+	d.Payload = json.RawMessage(payload)
+	if retryOf.Valid {
+		d.RetryOf = &retryOf.Int64
+	}
+	return &d, nil
+}
@@ -0,0 +1,162 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"lio-ai/internal/models"
+)
+
+// WidgetRepository stores widget tokens and their daily usage
+type WidgetRepository struct {
+	db *sql.DB
+}
+
+// NewWidgetRepository creates a new widget repository
+func NewWidgetRepository(db *sql.DB) *WidgetRepository {
+	return &WidgetRepository{db: db}
+}
+
+const widgetTokenColumns = `id, user_id, name, token_prefix, persona_id, COALESCE(model, ''), allowed_origins,
+	requests_per_minute, daily_requests, daily_tokens, max_tokens, max_cost_usd, created_at, last_used_at, revoked_at`
+
+// Create stores a widget token under the hash of its secret
+func (r *WidgetRepository) Create(t *models.WidgetToken, tokenHash string) error {
+	now := time.Now()
+	result, err := r.db.Exec(`
+		INSERT INTO widget_tokens (user_id, name, token_hash, token_prefix, persona_id, model, allowed_origins,
+			requests_per_minute, daily_requests, daily_tokens, max_tokens, max_cost_usd, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, t.UserID, t.Name, tokenHash, t.TokenPrefix, t.PersonaID, t.Model, strings.Join(t.AllowedOrigins, " "),
+		t.Limits.RequestsPerMinute, t.Limits.DailyRequests, t.Limits.DailyTokens, t.Limits.MaxTokens, t.Limits.MaxCostUSD, now)
+	if err != nil {
+		return fmt.Errorf("failed to create widget token: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to get last insert id: %w", err)
+	}
+	t.ID = id
+	t.CreatedAt = now
+	return nil
+}
+
+// GetActiveByHash retrieves an unrevoked widget token by the hash of its
+// secret, or nil
+func (r *WidgetRepository) GetActiveByHash(tokenHash string) (*models.WidgetToken, error) {
+	t, err := scanWidgetToken(r.db.QueryRow(
+		"SELECT "+widgetTokenColumns+" FROM widget_tokens WHERE token_hash = ? AND revoked_at IS NULL", tokenHash,
+	))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get widget token: %w", err)
+	}
+	return t, nil
+}
+
+// ListByUser retrieves a user's widget tokens, newest first
+func (r *WidgetRepository) ListByUser(userID string) ([]models.WidgetToken, error) {
+	rows, err := r.db.Query("SELECT "+widgetTokenColumns+" FROM widget_tokens WHERE user_id = ? ORDER BY id DESC", userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list widget tokens: %w", err)
+	}
+	defer rows.Close()
+
+	tokens := make([]models.WidgetToken, 0)
+	for rows.Next() {
+		t, err := scanWidgetToken(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan widget token: %w", err)
+		}
+		tokens = append(tokens, *t)
+	}
+	return tokens, rows.Err()
+}
+
+// Revoke revokes one of a user's widget tokens, reporting whether it was
+// active
+func (r *WidgetRepository) Revoke(id int64, userID string) (bool, error) {
+	result, err := r.db.Exec(
+		"UPDATE widget_tokens SET revoked_at = ? WHERE id = ? AND user_id = ? AND revoked_at IS NULL",
+		time.Now(), id, userID,
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to revoke widget token: %w", err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to revoke widget token: %w", err)
+	}
+	return n > 0, nil
+}
+
+// Reserve claims one completion for a token if it is under both daily
+// limits. The check and increment happen in one statement so concurrent
+// requests cannot overshoot the request limit.
+func (r *WidgetRepository) Reserve(tokenID int64, day string, requestLimit, tokenLimit int) (bool, error) {
+	if requestLimit <= 0 || tokenLimit <= 0 {
+		return false, nil
+	}
+	result, err := r.db.Exec(`
+		INSERT INTO widget_usage (token_id, day, requests) VALUES (?, ?, 1)
+		ON CONFLICT(token_id, day) DO UPDATE SET requests = requests + 1
+		WHERE requests < ? AND tokens < ?
+	`, tokenID, day, requestLimit, tokenLimit)
+	if err != nil {
+		return false, fmt.Errorf("failed to reserve widget completion: %w", err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to reserve widget completion: %w", err)
+	}
+	return n > 0, nil
+}
+
+// Release gives back a reserved completion, e.g. when the AI call failed
+func (r *WidgetRepository) Release(tokenID int64, day string) error {
+	_, err := r.db.Exec(`
+		UPDATE widget_usage SET requests = requests - 1
+		WHERE token_id = ? AND day = ? AND requests > 0
+	`, tokenID, day)
+	if err != nil {
+		return fmt.Errorf("failed to release widget completion: %w", err)
+	}
+	return nil
+}
+
+// RecordUse adds the tokens of a completion to the token's daily usage
+func (r *WidgetRepository) RecordUse(tokenID int64, day string, tokens int) error {
+	if _, err := r.db.Exec(
+		"UPDATE widget_usage SET tokens = tokens + ? WHERE token_id = ? AND day = ?", tokens, tokenID, day,
+	); err != nil {
+		return fmt.Errorf("failed to record widget tokens: %w", err)
+	}
+	if _, err := r.db.Exec("UPDATE widget_tokens SET last_used_at = ? WHERE id = ?", time.Now(), tokenID); err != nil {
+		return fmt.Errorf("failed to record widget token use: %w", err)
+	}
+	return nil
+}
+
+func scanWidgetToken(row interface{ Scan(...interface{}) error }) (*models.WidgetToken, error) {
+	var t models.WidgetToken
+	var origins string
+	var lastUsed, revoked sql.NullTime
+	if err := row.Scan(&t.ID, &t.UserID, &t.Name, &t.TokenPrefix, &t.PersonaID, &t.Model, &origins,
+		&t.Limits.RequestsPerMinute, &t.Limits.DailyRequests, &t.Limits.DailyTokens, &t.Limits.MaxTokens,
+		&t.Limits.MaxCostUSD, &t.CreatedAt, &lastUsed, &revoked); err != nil {
+		return nil, err
+	}
+	t.AllowedOrigins = strings.Fields(origins)
+	if lastUsed.Valid {
+		t.LastUsedAt = &lastUsed.Time
+	}
+	if revoked.Valid {
+		t.RevokedAt = &revoked.Time
+	}
+	return &t, nil
+}
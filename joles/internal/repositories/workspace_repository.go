@@ -0,0 +1,602 @@
+package repositories
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"lio-ai/internal/models"
+)
+
+// workspaceTable is a table captured in workspace snapshots, with the
+// condition selecting a user's rows
+type workspaceTable struct {
+	name  string
+	where string
+}
+
+// sqliteTimeLayout is the layout the driver writes times in
+const sqliteTimeLayout = "2006-01-02 15:04:05.999999999-07:00"
+
+// Tables in workspace snapshots, parents before children
+var workspaceTables = []workspaceTable{
+	{"collections", "user_id = ?"},
+	{"documents", "user_id = ?"},
+	{"document_tags", "document_id IN (SELECT id FROM documents WHERE user_id = ?)"},
+	{"chats", "user_id = ?"},
+	{"messages", "chat_id IN (SELECT id FROM chats WHERE user_id = ?)"},
+	{"user_preferences", "user_id = ?"},
+	{"response_plugins", "user_id = ?"},
+}
+
+// WorkspaceReplacement reports what replacing a workspace changed, for the
+// caller to update storage accounting, stored files and search indexes
+type WorkspaceReplacement struct {
+	Restored           map[string]int64 // Rows inserted, by table
+	RemovedDocumentIDs []int64
+	RemovedFileKeys    []string // Files no restored document references
+	RestoredDocuments  []int64
+}
+
+// WorkspaceRepository handles workspace snapshots, restores and snapshot
+// schedules, and reads and replaces the workspace rows they cover
+type WorkspaceRepository struct {
+	db *sql.DB
+}
+
+// NewWorkspaceRepository creates a new workspace repository
+func NewWorkspaceRepository(db *sql.DB) *WorkspaceRepository {
+	return &WorkspaceRepository{db: db}
+}
+
+const snapshotColumns = `id, user_id, source, status, COALESCE(storage_backend, ''), COALESCE(storage_key, ''),
+	size_bytes, COALESCE(sha256, ''), counts, COALESCE(error, ''), created_at, completed_at`
+
+// CreateSnapshot stores a queued snapshot
+func (r *WorkspaceRepository) CreateSnapshot(s *models.WorkspaceSnapshot) error {
+	s.Status = models.WorkspaceJobQueued
+	s.CreatedAt = time.Now()
+	result, err := r.db.Exec(`
+		INSERT INTO workspace_snapshots (user_id, source, status, created_at) VALUES (?, ?, ?, ?)
+	`, s.UserID, s.Trigger, s.Status, s.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create snapshot: %w", err)
+	}
+	if s.ID, err = result.LastInsertId(); err != nil {
+		return fmt.Errorf("failed to get last insert id: %w", err)
+	}
+	if s.Counts == nil {
+		s.Counts = map[string]int64{}
+	}
+	return nil
+}
+
+// UpdateSnapshot saves a snapshot's status and the file it was written to
+func (r *WorkspaceRepository) UpdateSnapshot(s *models.WorkspaceSnapshot) error {
+	counts, err := json.Marshal(s.Counts)
+	if err != nil {
+		return fmt.Errorf("failed to encode snapshot counts: %w", err)
+	}
+	_, err = r.db.Exec(`
+		UPDATE workspace_snapshots
+		SET status = ?, storage_backend = NULLIF(?, ''), storage_key = NULLIF(?, ''), size_bytes = ?,
+			sha256 = NULLIF(?, ''), counts = ?, error = NULLIF(?, ''), completed_at = ?
+		WHERE id = ?
+	`, s.Status, s.Backend, s.Key, s.SizeBytes, s.SHA256, string(counts), s.Error, s.CompletedAt, s.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update snapshot: %w", err)
+	}
+	return nil
+}
+
+// GetSnapshot retrieves a snapshot, or nil if it does not exist
+func (r *WorkspaceRepository) GetSnapshot(id int64) (*models.WorkspaceSnapshot, error) {
+	s, err := scanSnapshot(r.db.QueryRow(`SELECT `+snapshotColumns+` FROM workspace_snapshots WHERE id = ?`, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get snapshot: %w", err)
+	}
+	return s, nil
+}
+
+// ListSnapshots retrieves a user's snapshots, newest first; with trigger
+// set, only those it started
+func (r *WorkspaceRepository) ListSnapshots(userID, trigger string) ([]models.WorkspaceSnapshot, error) {
+	query := `SELECT ` + snapshotColumns + ` FROM workspace_snapshots WHERE user_id = ?`
+	args := []interface{}{userID}
+	if trigger != "" {
+		query += " AND source = ?"
+		args = append(args, trigger)
+	}
+	rows, err := r.db.Query(query+" ORDER BY created_at DESC, id DESC", args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list snapshots: %w", err)
+	}
+	defer rows.Close()
+
+	snapshots := make([]models.WorkspaceSnapshot, 0)
+	for rows.Next() {
+		s, err := scanSnapshot(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan snapshot: %w", err)
+		}
+		snapshots = append(snapshots, *s)
+	}
+	return snapshots, rows.Err()
+}
+
+// LatestSnapshotAt retrieves a user's newest completed snapshot taken at or
+// before at, or nil if there is none
+func (r *WorkspaceRepository) LatestSnapshotAt(userID string, at time.Time) (*models.WorkspaceSnapshot, error) {
+	s, err := scanSnapshot(r.db.QueryRow(`
+		SELECT `+snapshotColumns+` FROM workspace_snapshots
+		WHERE user_id = ? AND status = ? AND created_at <= ?
+		ORDER BY created_at DESC, id DESC LIMIT 1
+	`, userID, models.WorkspaceJobCompleted, at))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get snapshot: %w", err)
+	}
+	return s, nil
+}
+
+// DeleteSnapshot removes a snapshot record
+func (r *WorkspaceRepository) DeleteSnapshot(id int64) error {
+	if _, err := r.db.Exec("DELETE FROM workspace_snapshots WHERE id = ?", id); err != nil {
+		return fmt.Errorf("failed to delete snapshot: %w", err)
+	}
+	return nil
+}
+
+func scanSnapshot(row interface{ Scan(...interface{}) error }) (*models.WorkspaceSnapshot, error) {
+	var s models.WorkspaceSnapshot
+	var counts sql.NullString
+	var completedAt sql.NullTime
+	if err := row.Scan(&s.ID, &s.UserID, &s.Trigger, &s.Status, &s.Backend, &s.Key,
+		&s.SizeBytes, &s.SHA256, &counts, &s.Error, &s.CreatedAt, &completedAt); err != nil {
+		return nil, err
+	}
+	s.Counts = map[string]int64{}
+	if counts.Valid && counts.String != "" {
+		if err := json.Unmarshal([]byte(counts.String), &s.Counts); err != nil {
+			return nil, fmt.Errorf("failed to decode snapshot counts: %w", err)
+		}
+	}
+	if completedAt.Valid {
+		s.CompletedAt = &completedAt.Time
+	}
+	return &s, nil
+}
+
+const restoreColumns = `id, user_id, snapshot_id, safety_snapshot_id, status, restored, COALESCE(error, ''), created_at, completed_at`
+
+// CreateRestore stores a queued restore job
+func (r *WorkspaceRepository) CreateRestore(job *models.WorkspaceRestore) error {
+	job.Status = models.WorkspaceJobQueued
+	job.CreatedAt = time.Now()
+	result, err := r.db.Exec(`
+		INSERT INTO workspace_restores (user_id, snapshot_id, status, created_at) VALUES (?, ?, ?, ?)
+	`, job.UserID, job.SnapshotID, job.Status, job.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create restore: %w", err)
+	}
+	if job.ID, err = result.LastInsertId(); err != nil {
+		return fmt.Errorf("failed to get last insert id: %w", err)
+	}
+	if job.Restored == nil {
+		job.Restored = map[string]int64{}
+	}
+	return nil
+}
+
+// UpdateRestore saves a restore job's status and results
+func (r *WorkspaceRepository) UpdateRestore(job *models.WorkspaceRestore) error {
+	restored, err := json.Marshal(job.Restored)
+	if err != nil {
+		return fmt.Errorf("failed to encode restore counts: %w", err)
+	}
+	_, err = r.db.Exec(`
+		UPDATE workspace_restores
+		SET status = ?, safety_snapshot_id = ?, restored = ?, error = NULLIF(?, ''), completed_at = ?
+		WHERE id = ?
+	`, job.Status, job.SafetySnapshotID, string(restored), job.Error, job.CompletedAt, job.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update restore: %w", err)
+	}
+	return nil
+}
+
+// GetRestore retrieves a restore job, or nil if it does not exist
+func (r *WorkspaceRepository) GetRestore(id int64) (*models.WorkspaceRestore, error) {
+	var job models.WorkspaceRestore
+	var safetyID sql.NullInt64
+	var restored sql.NullString
+	var completedAt sql.NullTime
+	err := r.db.QueryRow(`SELECT `+restoreColumns+` FROM workspace_restores WHERE id = ?`, id).Scan(
+		&job.ID, &job.UserID, &job.SnapshotID, &safetyID, &job.Status, &restored, &job.Error, &job.CreatedAt, &completedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get restore: %w", err)
+	}
+
+	if safetyID.Valid {
+		job.SafetySnapshotID = &safetyID.Int64
+	}
+	job.Restored = map[string]int64{}
+	if restored.Valid && restored.String != "" {
+		if err := json.Unmarshal([]byte(restored.String), &job.Restored); err != nil {
+			return nil, fmt.Errorf("failed to decode restore counts: %w", err)
+		}
+	}
+	if completedAt.Valid {
+		job.CompletedAt = &completedAt.Time
+	}
+	return &job, nil
+}
+
+// HasActiveJob reports whether a user has a snapshot or restore queued or
+// running
+func (r *WorkspaceRepository) HasActiveJob(userID string) (bool, error) {
+	var n int
+	err := r.db.QueryRow(`
+		SELECT (SELECT COUNT(*) FROM workspace_snapshots WHERE user_id = ? AND status IN (?, ?))
+			+ (SELECT COUNT(*) FROM workspace_restores WHERE user_id = ? AND status IN (?, ?))
+	`, userID, models.WorkspaceJobQueued, models.WorkspaceJobRunning,
+		userID, models.WorkspaceJobQueued, models.WorkspaceJobRunning).Scan(&n)
+	if err != nil {
+		return false, fmt.Errorf("failed to check workspace jobs: %w", err)
+	}
+	return n > 0, nil
+}
+
+// FailInterrupted completes snapshots and restores left running by a
+// previous process
+func (r *WorkspaceRepository) FailInterrupted() error {
+	now := time.Now()
+	for _, table := range []string{"workspace_snapshots", "workspace_restores"} {
+		_, err := r.db.Exec(`UPDATE `+table+` SET status = ?, completed_at = ? WHERE status IN (?, ?)`,
+			models.WorkspaceJobInterrupted, now, models.WorkspaceJobQueued, models.WorkspaceJobRunning)
+		if err != nil {
+			return fmt.Errorf("failed to update interrupted workspace jobs: %w", err)
+		}
+	}
+	return nil
+}
+
+// GetSchedule retrieves a user's snapshot schedule, or nil if none is set
+func (r *WorkspaceRepository) GetSchedule(userID string) (*models.SnapshotSchedule, error) {
+	var s models.SnapshotSchedule
+	var nextRun sql.NullTime
+	err := r.db.QueryRow(`
+		SELECT user_id, interval_hours, keep, next_run_at, updated_at FROM workspace_snapshot_schedules WHERE user_id = ?
+	`, userID).Scan(&s.UserID, &s.IntervalHours, &s.Keep, &nextRun, &s.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get snapshot schedule: %w", err)
+	}
+	if nextRun.Valid {
+		s.NextRunAt = &nextRun.Time
+	}
+	return &s, nil
+}
+
+// SetSchedule replaces a user's snapshot schedule
+func (r *WorkspaceRepository) SetSchedule(s *models.SnapshotSchedule) error {
+	s.UpdatedAt = time.Now()
+	_, err := r.db.Exec(`
+		INSERT INTO workspace_snapshot_schedules (user_id, interval_hours, keep, next_run_at, updated_at)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(user_id) DO UPDATE SET interval_hours = excluded.interval_hours, keep = excluded.keep,
+			next_run_at = excluded.next_run_at, updated_at = excluded.updated_at
+	`, s.UserID, s.IntervalHours, s.Keep, s.NextRunAt, s.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save snapshot schedule: %w", err)
+	}
+	return nil
+}
+
+// ListDueSchedules retrieves schedules whose next run is at or before now
+func (r *WorkspaceRepository) ListDueSchedules(now time.Time) ([]models.SnapshotSchedule, error) {
+	rows, err := r.db.Query(`
+		SELECT user_id, interval_hours, keep, next_run_at, updated_at FROM workspace_snapshot_schedules
+		WHERE interval_hours > 0 AND next_run_at <= ?
+		ORDER BY next_run_at
+	`, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list snapshot schedules: %w", err)
+	}
+	defer rows.Close()
+
+	schedules := make([]models.SnapshotSchedule, 0)
+	for rows.Next() {
+		var s models.SnapshotSchedule
+		var nextRun sql.NullTime
+		if err := rows.Scan(&s.UserID, &s.IntervalHours, &s.Keep, &nextRun, &s.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan snapshot schedule: %w", err)
+		}
+		if nextRun.Valid {
+			s.NextRunAt = &nextRun.Time
+		}
+		schedules = append(schedules, s)
+	}
+	return schedules, rows.Err()
+}
+
+// Dump reads a user's rows of each workspace table as column values
+func (r *WorkspaceRepository) Dump(userID string) (map[string][]map[string]interface{}, error) {
+	tables := make(map[string][]map[string]interface{}, len(workspaceTables))
+	for _, t := range workspaceTables {
+		rows, err := r.db.Query(`SELECT * FROM `+t.name+` WHERE `+t.where+` ORDER BY rowid`, userID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", t.name, err)
+		}
+		records, err := scanRecords(rows)
+		rows.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", t.name, err)
+		}
+		tables[t.name] = records
+	}
+	return tables, nil
+}
+
+// scanRecords reads rows as column-to-value maps. Text read back as bytes
+// is returned as a string so it survives JSON encoding as text, and times
+// in the layout the driver writes them in.
+func scanRecords(rows *sql.Rows) ([]map[string]interface{}, error) {
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	records := make([]map[string]interface{}, 0)
+	for rows.Next() {
+		values := make([]interface{}, len(columns))
+		ptrs := make([]interface{}, len(columns))
+		for i := range values {
+			ptrs[i] = &values[i]
+		}
+		if err := rows.Scan(ptrs...); err != nil {
+			return nil, err
+		}
+		record := make(map[string]interface{}, len(columns))
+		for i, column := range columns {
+			switch v := values[i].(type) {
+			case []byte:
+				record[column] = string(v)
+			case time.Time:
+				record[column] = v.Format(sqliteTimeLayout)
+			default:
+				record[column] = v
+			}
+		}
+		records = append(records, record)
+	}
+	return records, rows.Err()
+}
+
+// Replace swaps a user's workspace rows for those in tables, in one
+// transaction. Restored rows get new IDs, with references between them
+// remapped. Uploaded files are not part of snapshots, so a restored
+// document keeps its file only when a current document still has it.
+// Messages' attachments, feedback and scheduled sends go with the chats
+// they belong to.
+func (r *WorkspaceRepository) Replace(userID string, tables map[string][]map[string]interface{}) (*WorkspaceReplacement, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result := &WorkspaceReplacement{Restored: make(map[string]int64)}
+	files := make(map[string]bool)
+	rows, err := tx.Query("SELECT id, COALESCE(file_key, '') FROM documents WHERE user_id = ?", userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read documents: %w", err)
+	}
+	for rows.Next() {
+		var id int64
+		var key string
+		if err := rows.Scan(&id, &key); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to read documents: %w", err)
+		}
+		result.RemovedDocumentIDs = append(result.RemovedDocumentIDs, id)
+		if key != "" {
+			files[key] = true
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read documents: %w", err)
+	}
+
+	userChats := "(SELECT id FROM chats WHERE user_id = ?)"
+	for _, query := range []string{
+		"DELETE FROM message_feedback WHERE chat_id IN " + userChats,
+		"DELETE FROM scheduled_messages WHERE chat_id IN " + userChats,
+		"DELETE FROM messages WHERE chat_id IN " + userChats,
+		"DELETE FROM chats WHERE user_id = ?",
+		"DELETE FROM document_chunks_fts WHERE rowid IN (SELECT id FROM document_chunks WHERE user_id = ?)",
+		"DELETE FROM document_chunks WHERE user_id = ?",
+		"DELETE FROM document_tags WHERE document_id IN (SELECT id FROM documents WHERE user_id = ?)",
+		"DELETE FROM documents WHERE user_id = ?",
+		"DELETE FROM collections WHERE user_id = ?",
+		"DELETE FROM user_preferences WHERE user_id = ?",
+		"DELETE FROM response_plugins WHERE user_id = ?",
+	} {
+		if _, err := tx.Exec(query, userID); err != nil {
+			return nil, fmt.Errorf("failed to clear workspace: %w", err)
+		}
+	}
+
+	collectionIDs := make(map[int64]int64)
+	documentIDs := make(map[int64]int64)
+	chatIDs := make(map[int64]int64)
+	keptFiles := make(map[string]bool)
+
+	for _, t := range workspaceTables {
+		columns, err := tableColumns(tx, t.name)
+		if err != nil {
+			return nil, err
+		}
+		for _, record := range tables[t.name] {
+			row := make(map[string]interface{}, len(record))
+			for column, value := range record {
+				if columns[column] {
+					row[column] = recordValue(value)
+				}
+			}
+			if columns["user_id"] {
+				row["user_id"] = userID
+			}
+			oldID := recordID(record["id"])
+			delete(row, "id")
+
+			switch t.name {
+			case "collections":
+				// Parents are linked once every collection has its new ID
+				row["parent_id"] = nil
+			case "documents":
+				row["collection_id"] = remapID(row["collection_id"], collectionIDs)
+				if key, _ := row["file_key"].(string); key != "" {
+					if files[key] {
+						keptFiles[key] = true
+					} else {
+						for _, column := range []string{"file_name", "file_content_type", "file_size", "file_backend", "file_key"} {
+							row[column] = nil
+						}
+					}
+				}
+			case "document_tags":
+				id, ok := documentIDs[recordID(row["document_id"])]
+				if !ok {
+					continue
+				}
+				row["document_id"] = id
+			case "messages":
+				id, ok := chatIDs[recordID(row["chat_id"])]
+				if !ok {
+					continue
+				}
+				row["chat_id"] = id
+			}
+
+			newID, err := insertRecord(tx, t.name, row)
+			if err != nil {
+				return nil, err
+			}
+			result.Restored[t.name]++
+			switch t.name {
+			case "collections":
+				collectionIDs[oldID] = newID
+			case "documents":
+				documentIDs[oldID] = newID
+				result.RestoredDocuments = append(result.RestoredDocuments, newID)
+			case "chats":
+				chatIDs[oldID] = newID
+			}
+		}
+	}
+
+	for _, record := range tables["collections"] {
+		parent, ok := collectionIDs[recordID(record["parent_id"])]
+		if !ok {
+			continue
+		}
+		if _, err := tx.Exec("UPDATE collections SET parent_id = ? WHERE id = ?", parent, collectionIDs[recordID(record["id"])]); err != nil {
+			return nil, fmt.Errorf("failed to restore collections: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit restore: %w", err)
+	}
+	for key := range files {
+		if !keptFiles[key] {
+			result.RemovedFileKeys = append(result.RemovedFileKeys, key)
+		}
+	}
+	return result, nil
+}
+
+// tableColumns lists the columns a table has in this schema
+func tableColumns(tx *sql.Tx, table string) (map[string]bool, error) {
+	rows, err := tx.Query("SELECT name FROM pragma_table_info(?)", table)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s columns: %w", table, err)
+	}
+	defer rows.Close()
+
+	columns := make(map[string]bool)
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("failed to read %s columns: %w", table, err)
+		}
+		columns[name] = true
+	}
+	return columns, rows.Err()
+}
+
+func insertRecord(tx *sql.Tx, table string, row map[string]interface{}) (int64, error) {
+	columns := make([]string, 0, len(row))
+	args := make([]interface{}, 0, len(row))
+	for column, value := range row {
+		columns = append(columns, column)
+		args = append(args, value)
+	}
+	query := `INSERT INTO ` + table + ` (` + strings.Join(columns, ", ") + `) VALUES (` +
+		strings.TrimSuffix(strings.Repeat("?, ", len(columns)), ", ") + `)`
+	result, err := tx.Exec(query, args...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to restore %s: %w", table, err)
+	}
+	return result.LastInsertId()
+}
+
+// recordValue converts a column value decoded from JSON for insertion
+func recordValue(value interface{}) interface{} {
+	n, ok := value.(json.Number)
+	if !ok {
+		return value
+	}
+	if i, err := n.Int64(); err == nil {
+		return i
+	}
+	f, _ := n.Float64()
+	return f
+}
+
+// recordID reads an ID column decoded from JSON
+func recordID(value interface{}) int64 {
+	switch v := recordValue(value).(type) {
+	case int64:
+		return v
+	case float64:
+		return int64(v)
+	}
+	return 0
+}
+
+// remapID translates a reference to a restored row's new ID; references to
+// rows that were not restored are dropped
+func remapID(value interface{}, ids map[int64]int64) interface{} {
+	if value == nil {
+		return nil
+	}
+	if id, ok := ids[recordID(value)]; ok {
+		return id
+	}
+	return nil
+}
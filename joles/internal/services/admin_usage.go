@@ -0,0 +1,52 @@
+package services
+
+import (
+	"fmt"
+	"time"
+
+	"lio-ai/internal/models"
+)
+
+// maxTopUsers is the most users GetTopUsers ranks
+const maxTopUsers = 100
+
+// GetAdminUsageSummary sums the usage of all users created in [from, to)
+func (s *UsageService) GetAdminUsageSummary(from, to time.Time) (*models.AdminUsageSummary, error) {
+	if err := checkUsageRange(from, to); err != nil {
+		return nil, err
+	}
+	return s.usageRepo.GetAdminUsageSummary(from, to)
+}
+
+// GetTopUsers ranks the users with the most usage created in [from, to) by
+// cost, tokens or requests
+func (s *UsageService) GetTopUsers(from, to time.Time, rankBy string, limit int) ([]models.UserUsage, error) {
+	if err := checkUsageRange(from, to); err != nil {
+		return nil, err
+	}
+	switch rankBy {
+	case models.UsageRankCost, models.UsageRankTokens, models.UsageRankRequests:
+	default:
+		return nil, fmt.Errorf("%w: by must be 'cost', 'tokens' or 'requests'", ErrInvalidMessage)
+	}
+	if limit < 1 || limit > maxTopUsers {
+		return nil, fmt.Errorf("%w: limit must be between 1 and %d", ErrInvalidMessage, maxTopUsers)
+	}
+	return s.usageRepo.GetTopUsers(from, to, rankBy, limit)
+}
+
+// GetUsageByModel sums the usage of all users created in [from, to) per
+// model
+func (s *UsageService) GetUsageByModel(from, to time.Time) ([]models.ModelUsage, error) {
+	if err := checkUsageRange(from, to); err != nil {
+		return nil, err
+	}
+	return s.usageRepo.GetUsageByModel(from, to)
+}
+
+func checkUsageRange(from, to time.Time) error {
+	if !from.Before(to) {
+		return fmt.Errorf("%w: from must be before to", ErrInvalidMessage)
+	}
+	return nil
+}
@@ -0,0 +1,196 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"lio-ai/internal/models"
+	"lio-ai/internal/repositories"
+)
+
+// ErrAPIKeyInvalid is returned for unknown, revoked or expired API keys,
+// and keys of deactivated users
+var ErrAPIKeyInvalid = errors.New("invalid API key")
+
+// APIKeyPrefix marks personal API keys, so they are recognizable in logs
+// and secret scanners, and told apart from login tokens
+const APIKeyPrefix = "lio_sk_"
+
+// maxAPIKeysPerUser caps the unrevoked keys a user can hold
+const maxAPIKeysPerUser = 25
+
+// APIKeyService issues personal API keys, which authenticate as their
+// owner within their scopes
+type APIKeyService struct {
+	repo  *repositories.APIKeyRepository
+	users *repositories.UserRepository
+}
+
+// NewAPIKeyService creates an API key service
+func NewAPIKeyService(repo *repositories.APIKeyRepository, users *repositories.UserRepository) *APIKeyService {
+	return &APIKeyService{repo: repo, users: users}
+}
+
+// CreateKey issues an API key for userID. The key is only returned here;
+// just its hash is stored.
+func (s *APIKeyService) CreateKey(userID int64, req *models.CreateAPIKeyRequest) (*models.CreatedAPIKey, error) {
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		return nil, fmt.Errorf("%w: expires_at must be in the future", ErrInvalidMessage)
+	}
+	existing, err := s.repo.ListByUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	active := 0
+	for _, k := range existing {
+		if k.RevokedAt == nil {
+			active++
+		}
+	}
+	if active >= maxAPIKeysPerUser {
+		return nil, fmt.Errorf("%w: at most %d API keys; revoke one first", ErrInvalidMessage, maxAPIKeysPerUser)
+	}
+
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return nil, fmt.Errorf("failed to generate API key: %w", err)
+	}
+	secret := APIKeyPrefix + hex.EncodeToString(b)
+
+	scopes := []string{models.APIKeyScopeRead, models.APIKeyScopeWrite}
+	if len(req.Scopes) > 0 {
+		scopes = uniqueScopes(req.Scopes)
+	}
+	key := &models.APIKey{
+		UserID:      userID,
+		Name:        strings.TrimSpace(req.Name),
+		Description: strings.TrimSpace(req.Description),
+		KeyPrefix:   secret[:len(APIKeyPrefix)+8],
+		Scopes:      scopes,
+	}
+	if req.ExpiresAt != nil {
+		expires := req.ExpiresAt.UTC()
+		key.ExpiresAt = &expires
+	}
+	if key.Name == "" {
+		return nil, fmt.Errorf("%w: name is required", ErrInvalidMessage)
+	}
+	if err := s.repo.Create(key, hashAPIKey(secret)); err != nil {
+		return nil, err
+	}
+	return &models.CreatedAPIKey{APIKey: key, Key: secret}, nil
+}
+
+// ListKeys returns userID's API keys, revoked and expired ones included
+func (s *APIKeyService) ListKeys(userID int64) ([]models.APIKey, error) {
+	return s.repo.ListByUser(userID)
+}
+
+// GetKey returns one of userID's API keys
+func (s *APIKeyService) GetKey(id, userID int64) (*models.APIKey, error) {
+	key, err := s.repo.Get(id, userID)
+	if err != nil {
+		return nil, err
+	}
+	if key == nil {
+		return nil, fmt.Errorf("%w: API key %d", ErrNotFound, id)
+	}
+	return key, nil
+}
+
+// UpdateKey renames one of userID's API keys or changes its scopes
+func (s *APIKeyService) UpdateKey(id, userID int64, req *models.UpdateAPIKeyRequest) (*models.APIKey, error) {
+	key, err := s.GetKey(id, userID)
+	if err != nil {
+		return nil, err
+	}
+	if req.Name != nil {
+		if key.Name = strings.TrimSpace(*req.Name); key.Name == "" {
+			return nil, fmt.Errorf("%w: name cannot be empty", ErrInvalidMessage)
+		}
+	}
+	if req.Description != nil {
+		key.Description = strings.TrimSpace(*req.Description)
+	}
+	if len(req.Scopes) > 0 {
+		key.Scopes = uniqueScopes(req.Scopes)
+	}
+	if err := s.repo.Update(key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// RevokeKey revokes one of userID's API keys; requests with it fail
+// immediately
+func (s *APIKeyService) RevokeKey(id, userID int64) error {
+	revoked, err := s.repo.Revoke(id, userID)
+	if err != nil {
+		return err
+	}
+	if !revoked {
+		return fmt.Errorf("%w: API key %d", ErrNotFound, id)
+	}
+	return nil
+}
+
+// Authenticate resolves the API key a request presents and its owner,
+// recording that the key was used
+func (s *APIKeyService) Authenticate(secret string) (*models.APIKey, *models.User, error) {
+	if !strings.HasPrefix(secret, APIKeyPrefix) {
+		return nil, nil, ErrAPIKeyInvalid
+	}
+	key, err := s.repo.GetActiveByHash(hashAPIKey(secret))
+	if err != nil {
+		return nil, nil, err
+	}
+	if key == nil || !key.IsActive {
+		return nil, nil, ErrAPIKeyInvalid
+	}
+	user, err := s.users.GetByID(key.UserID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if user == nil {
+		return nil, nil, ErrAPIKeyInvalid
+	}
+
+	if err := s.repo.RecordUse(key.ID); err != nil {
+		log.Printf("⚠️  Failed to record use of API key %d: %v", key.ID, err)
+	}
+	return key, user, nil
+}
+
+// HasScope reports whether an API key was granted scope
+func HasScope(key *models.APIKey, scope string) bool {
+	for _, s := range key.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// uniqueScopes drops repeated scopes, keeping their order
+func uniqueScopes(scopes []string) []string {
+	seen := make(map[string]bool, len(scopes))
+	unique := make([]string, 0, len(scopes))
+	for _, scope := range scopes {
+		if !seen[scope] {
+			seen[scope] = true
+			unique = append(unique, scope)
+		}
+	}
+	return unique
+}
+
+func hashAPIKey(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,127 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"lio-ai/internal/models"
+	"lio-ai/internal/repositories"
+)
+
+func newTestAPIKeyService(t *testing.T) (*APIKeyService, *sql.DB, *models.User) {
+	t.Helper()
+	conn := newTestDB(t)
+	user := createTestUser(t, conn, "alice", "user")
+	return NewAPIKeyService(repositories.NewAPIKeyRepository(conn), repositories.NewUserRepository(conn)), conn, user
+}
+
+func TestAPIKeyAuthenticateLooksUpByHash(t *testing.T) {
+	svc, conn, user := newTestAPIKeyService(t)
+
+	created, err := svc.CreateKey(user.ID, &models.CreateAPIKeyRequest{Name: "ci"})
+	if err != nil {
+		t.Fatalf("CreateKey: %v", err)
+	}
+
+	var stored string
+	if err := conn.QueryRow(`SELECT key_hash FROM api_keys WHERE id = ?`, created.ID).Scan(&stored); err != nil {
+		t.Fatalf("read key_hash: %v", err)
+	}
+	if stored == created.Key || stored != hashAPIKey(created.Key) {
+		t.Fatalf("stored key_hash %q is not the SHA-256 of the key", stored)
+	}
+
+	key, owner, err := svc.Authenticate(created.Key)
+	if err != nil {
+		t.Fatalf("Authenticate: %v", err)
+	}
+	if key.ID != created.ID || owner.ID != user.ID {
+		t.Fatalf("Authenticate resolved key %d of user %d, want key %d of user %d", key.ID, owner.ID, created.ID, user.ID)
+	}
+	if key.LastUsedAt != nil {
+		t.Errorf("returned key already carries LastUsedAt")
+	}
+	if got, _ := svc.GetKey(created.ID, user.ID); got.LastUsedAt == nil {
+		t.Errorf("Authenticate did not record the key's use")
+	}
+
+	for _, secret := range []string{"", created.Key + "x", created.Key[len(APIKeyPrefix):], APIKeyPrefix + "unknown"} {
+		if _, _, err := svc.Authenticate(secret); !errors.Is(err, ErrAPIKeyInvalid) {
+			t.Errorf("Authenticate(%q) error = %v, want ErrAPIKeyInvalid", secret, err)
+		}
+	}
+}
+
+func TestAPIKeyAuthenticateRejectsRevokedAndExpiredKeys(t *testing.T) {
+	svc, conn, user := newTestAPIKeyService(t)
+
+	revoked, err := svc.CreateKey(user.ID, &models.CreateAPIKeyRequest{Name: "revoked"})
+	if err != nil {
+		t.Fatalf("CreateKey: %v", err)
+	}
+	if err := svc.RevokeKey(revoked.ID, user.ID); err != nil {
+		t.Fatalf("RevokeKey: %v", err)
+	}
+	if _, _, err := svc.Authenticate(revoked.Key); !errors.Is(err, ErrAPIKeyInvalid) {
+		t.Errorf("revoked key: error = %v, want ErrAPIKeyInvalid", err)
+	}
+
+	soon := time.Now().Add(time.Hour)
+	expired, err := svc.CreateKey(user.ID, &models.CreateAPIKeyRequest{Name: "expired", ExpiresAt: &soon})
+	if err != nil {
+		t.Fatalf("CreateKey: %v", err)
+	}
+	if _, err := conn.Exec(`UPDATE api_keys SET expires_at = ? WHERE id = ?`, time.Now().Add(-time.Minute).UTC(), expired.ID); err != nil {
+		t.Fatalf("expire key: %v", err)
+	}
+	if _, _, err := svc.Authenticate(expired.Key); !errors.Is(err, ErrAPIKeyInvalid) {
+		t.Errorf("expired key: error = %v, want ErrAPIKeyInvalid", err)
+	}
+
+	past := time.Now().Add(-time.Hour)
+	if _, err := svc.CreateKey(user.ID, &models.CreateAPIKeyRequest{Name: "past", ExpiresAt: &past}); !errors.Is(err, ErrInvalidMessage) {
+		t.Errorf("CreateKey with past expires_at: error = %v, want ErrInvalidMessage", err)
+	}
+}
+
+func TestAPIKeyRevokeOnlyOwnKeys(t *testing.T) {
+	svc, conn, user := newTestAPIKeyService(t)
+	other := createTestUser(t, conn, "bob", "user")
+
+	created, err := svc.CreateKey(user.ID, &models.CreateAPIKeyRequest{Name: "ci"})
+	if err != nil {
+		t.Fatalf("CreateKey: %v", err)
+	}
+	if err := svc.RevokeKey(created.ID, other.ID); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("RevokeKey by another user: error = %v, want ErrNotFound", err)
+	}
+	if _, _, err := svc.Authenticate(created.Key); err != nil {
+		t.Fatalf("key stopped working after another user's revoke: %v", err)
+	}
+}
+
+func TestAPIKeyScopesDefaultAndDeduplicate(t *testing.T) {
+	svc, _, user := newTestAPIKeyService(t)
+
+	created, err := svc.CreateKey(user.ID, &models.CreateAPIKeyRequest{Name: "default"})
+	if err != nil {
+		t.Fatalf("CreateKey: %v", err)
+	}
+	if !HasScope(created.APIKey, models.APIKeyScopeRead) || !HasScope(created.APIKey, models.APIKeyScopeWrite) || HasScope(created.APIKey, models.APIKeyScopeAdmin) {
+		t.Errorf("default scopes = %v, want read and write", created.Scopes)
+	}
+
+	created, err = svc.CreateKey(user.ID, &models.CreateAPIKeyRequest{Name: "read", Scopes: []string{"read", "read"}})
+	if err != nil {
+		t.Fatalf("CreateKey: %v", err)
+	}
+	key, _, err := svc.Authenticate(created.Key)
+	if err != nil {
+		t.Fatalf("Authenticate: %v", err)
+	}
+	if len(key.Scopes) != 1 || key.Scopes[0] != models.APIKeyScopeRead {
+		t.Errorf("stored scopes = %v, want [read]", key.Scopes)
+	}
+}
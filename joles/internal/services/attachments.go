@@ -0,0 +1,275 @@
+package services
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"github.com/google/uuid"
+	"lio-ai/internal/models"
+	"lio-ai/internal/storage"
+)
+
+// MaxAttachmentsPerMessage caps the number of files on one message
+const MaxAttachmentsPerMessage = 10
+
+var (
+	// ErrAttachmentsDisabled is returned when no attachment store is configured
+	ErrAttachmentsDisabled = errors.New("attachments are not enabled")
+	// ErrAttachmentTooLarge is returned when a file exceeds the size limit
+	ErrAttachmentTooLarge = errors.New("attachment too large")
+)
+
+// SetAttachmentStore enables message attachments backed by store
+func (s *ChatService) SetAttachmentStore(store storage.Store, maxBytes int64) {
+	s.attachments = store
+	s.maxAttachmentBytes = maxBytes
+}
+
+// SetStorageService counts attachments against users' storage limits
+func (s *ChatService) SetStorageService(storage *StorageService) {
+	s.storage = storage
+}
+
+// SendMessageWithAttachments stores uploaded files and a message referencing them
+func (s *ChatService) SendMessageWithAttachments(chatID int64, userID string, req *models.MessageRequest, files []*multipart.FileHeader) (*models.Message, error) {
+	if s.attachments == nil {
+		return nil, ErrAttachmentsDisabled
+	}
+	if len(files) > MaxAttachmentsPerMessage {
+		return nil, fmt.Errorf("%w: at most %d attachments per message", ErrInvalidMessage, MaxAttachmentsPerMessage)
+	}
+	var total int64
+	for _, f := range files {
+		if s.maxAttachmentBytes > 0 && f.Size > s.maxAttachmentBytes {
+			return nil, fmt.Errorf("%w: %s exceeds %d bytes", ErrAttachmentTooLarge, f.Filename, s.maxAttachmentBytes)
+		}
+		total += f.Size
+	}
+	if s.storage != nil {
+		if err := s.storage.Check(userID, total); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err := s.ownedChat(chatID, userID); err != nil {
+		return nil, err
+	}
+
+	// Upload first so a message never references a missing file
+	uploaded := make([]models.Attachment, 0, len(files))
+	for _, f := range files {
+		a, err := s.storeAttachment(chatID, userID, f)
+		if err != nil {
+			s.deleteStoredAttachments(uploaded)
+			return nil, err
+		}
+		uploaded = append(uploaded, *a)
+	}
+
+	message, err := s.addMessage(chatID, req, len(uploaded) > 0)
+	if err != nil {
+		s.deleteStoredAttachments(uploaded)
+		return nil, err
+	}
+
+	for i := range uploaded {
+		uploaded[i].MessageID = message.ID
+		if err := s.repo.CreateAttachment(&uploaded[i]); err != nil {
+			s.deleteStoredAttachments(uploaded[i:])
+			return nil, err
+		}
+		s.recordAttachmentStorage(uploaded[i])
+	}
+	message.Attachments = uploaded
+
+	return message, nil
+}
+
+// storeAttachment writes one uploaded file to the attachment store
+func (s *ChatService) storeAttachment(chatID int64, userID string, f *multipart.FileHeader) (*models.Attachment, error) {
+	file, err := f.Open()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read upload: %w", err)
+	}
+	defer file.Close()
+
+	contentType := f.Header.Get("Content-Type")
+	if contentType == "" || contentType == "application/octet-stream" {
+		// Sniff from the first bytes when the client didn't say
+		head := make([]byte, 512)
+		n, _ := io.ReadFull(file, head)
+		contentType = http.DetectContentType(head[:n])
+		if _, err := file.Seek(0, io.SeekStart); err != nil {
+			return nil, fmt.Errorf("failed to read upload: %w", err)
+		}
+	}
+
+	key := fmt.Sprintf("chats/%d/%s%s", chatID, uuid.New().String(), strings.ToLower(filepath.Ext(f.Filename)))
+	if err := s.attachments.Put(key, file, f.Size, contentType); err != nil {
+		return nil, fmt.Errorf("failed to store attachment: %w", err)
+	}
+
+	return &models.Attachment{
+		ChatID:         chatID,
+		UserID:         userID,
+		Filename:       filepath.Base(f.Filename),
+		ContentType:    contentType,
+		SizeBytes:      f.Size,
+		StorageBackend: s.attachments.Name(),
+		StorageKey:     key,
+	}, nil
+}
+
+// GetAttachment returns an attachment and its content for the chat owner
+func (s *ChatService) GetAttachment(chatID, attachmentID int64, userID string) (*models.Attachment, io.ReadCloser, error) {
+	if s.attachments == nil {
+		return nil, nil, ErrAttachmentsDisabled
+	}
+
+	chat, err := s.repo.GetChatByID(chatID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if chat.UserID != userID {
+		return nil, nil, ErrUnauthorized
+	}
+
+	a, err := s.repo.GetAttachmentByID(attachmentID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if a.ChatID != chatID {
+		return nil, nil, fmt.Errorf("attachment not found")
+	}
+
+	content, err := s.attachments.Get(a.StorageKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read attachment: %w", err)
+	}
+	return a, content, nil
+}
+
+// messagesWithAttachments loads a chat's messages with their attachments
+func (s *ChatService) messagesWithAttachments(chatID int64) ([]models.Message, error) {
+	messages, err := s.repo.GetMessagesByChatID(chatID)
+	if err != nil {
+		return nil, err
+	}
+
+	attachments, err := s.repo.GetAttachmentsByChatID(chatID)
+	if err != nil {
+		return nil, err
+	}
+	if len(attachments) == 0 {
+		return messages, nil
+	}
+
+	byMessage := make(map[int64][]models.Attachment)
+	for _, a := range attachments {
+		byMessage[a.MessageID] = append(byMessage[a.MessageID], a)
+	}
+	for i := range messages {
+		messages[i].Attachments = byMessage[messages[i].ID]
+	}
+	return messages, nil
+}
+
+// contentWithAttachments builds multi-part content for the AI service:
+// images are inlined as data URLs for vision models, other files are
+// described by name so the model knows they exist
+func (s *ChatService) contentWithAttachments(msg models.Message) []map[string]interface{} {
+	parts := make([]map[string]interface{}, 0, len(msg.Attachments)+1)
+	if msg.Content != "" {
+		parts = append(parts, map[string]interface{}{"type": "text", "text": msg.Content})
+	}
+
+	for _, a := range msg.Attachments {
+		if strings.HasPrefix(a.ContentType, "image/") && s.attachments != nil {
+			if data, err := s.readAttachment(a); err == nil {
+				parts = append(parts, map[string]interface{}{
+					"type": "image_url",
+					"image_url": map[string]interface{}{
+						"url": "data:" + a.ContentType + ";base64," + base64.StdEncoding.EncodeToString(data),
+					},
+				})
+				continue
+			} else {
+				log.Printf("Failed to load attachment %d for AI request: %v", a.ID, err)
+			}
+		}
+		parts = append(parts, map[string]interface{}{
+			"type": "text",
+			"text": fmt.Sprintf("[Attached file: %s (%s, %d bytes)]", a.Filename, a.ContentType, a.SizeBytes),
+		})
+	}
+	return parts
+}
+
+// readAttachment loads an attachment's content into memory
+func (s *ChatService) readAttachment(a models.Attachment) ([]byte, error) {
+	r, err := s.attachments.Get(a.StorageKey)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
+
+// recordAttachmentStorage charges a saved attachment to its uploader
+func (s *ChatService) recordAttachmentStorage(a models.Attachment) {
+	if s.storage == nil {
+		return
+	}
+	if err := s.storage.Record(a.UserID, models.StorageKindAttachment, a.ID, a.SizeBytes); err != nil {
+		log.Printf("Failed to record storage for attachment %d: %v", a.ID, err)
+	}
+}
+
+// releaseAttachmentStorage stops counting deleted attachments
+func (s *ChatService) releaseAttachmentStorage(attachments []models.Attachment) {
+	if s.storage == nil || len(attachments) == 0 {
+		return
+	}
+	ids := make([]int64, len(attachments))
+	for i, a := range attachments {
+		ids[i] = a.ID
+	}
+	if err := s.storage.Release(models.StorageKindAttachment, ids...); err != nil {
+		log.Printf("Failed to release attachment storage: %v", err)
+	}
+}
+
+// deleteStoredAttachments removes attachment content from the store
+func (s *ChatService) deleteStoredAttachments(attachments []models.Attachment) {
+	if s.attachments == nil {
+		return
+	}
+	for _, a := range attachments {
+		if err := s.attachments.Delete(a.StorageKey); err != nil {
+			log.Printf("Failed to delete attachment %s: %v", a.StorageKey, err)
+		}
+	}
+}
+
+// PurgeUserAttachments deletes every attachment a user uploaded, returning
+// how many were removed
+func (s *ChatService) PurgeUserAttachments(userID string) (int64, error) {
+	attachments, err := s.repo.GetAttachmentsByUserID(userID)
+	if err != nil {
+		return 0, err
+	}
+	removed, err := s.repo.DeleteAttachmentsByUserID(userID)
+	if err != nil {
+		return 0, err
+	}
+	s.deleteStoredAttachments(attachments)
+	s.releaseAttachmentStorage(attachments)
+	return removed, nil
+}
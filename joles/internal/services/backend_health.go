@@ -0,0 +1,145 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"lio-ai/internal/models"
+)
+
+// Backend health states
+const (
+	BackendStatusUnknown = "unknown"
+	BackendStatusUp      = "up"
+	BackendStatusDown    = "down"
+)
+
+// BackendHealth tracks reachability of the Python backend. The gateway
+// starts without waiting for the backend; requests are routed only while it
+// is (or may be) up, and the monitor notices when it comes back.
+type BackendHealth struct {
+	baseURL string
+	client  *http.Client
+
+	mu          sync.RWMutex
+	status      string
+	lastChecked time.Time
+	lastError   string
+	failures    int
+
+	// Optional record of outages
+	incidents *IncidentService
+}
+
+// BackendHealthStatus is a snapshot of the backend health state
+type BackendHealthStatus struct {
+	Status              string    `json:"status"`
+	LastChecked         time.Time `json:"last_checked"`
+	LastError           string    `json:"last_error,omitempty"`
+	ConsecutiveFailures int       `json:"consecutive_failures"`
+}
+
+// NewBackendHealth creates a backend health monitor
+func NewBackendHealth(baseURL string) *BackendHealth {
+	return &BackendHealth{
+		baseURL: baseURL,
+		client:  &http.Client{Timeout: 3 * time.Second},
+		status:  BackendStatusUnknown,
+	}
+}
+
+// SetIncidents records backend outages as incidents
+func (h *BackendHealth) SetIncidents(incidents *IncidentService) {
+	h.incidents = incidents
+}
+
+// Start probes the backend on an interval until the process exits
+func (h *BackendHealth) Start(interval time.Duration) {
+	go func() {
+		h.Check()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			h.Check()
+		}
+	}()
+}
+
+// Check probes the backend /health endpoint once and updates the state
+func (h *BackendHealth) Check() bool {
+	resp, err := h.client.Get(h.baseURL + "/health")
+	if err == nil {
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			err = fmt.Errorf("health check returned status %d", resp.StatusCode)
+		}
+	}
+
+	if err != nil {
+		h.MarkDown(err)
+		return false
+	}
+	h.markUp()
+	return true
+}
+
+// IsAvailable reports whether requests should be routed to the backend.
+// An unknown state counts as available so the first request is attempted.
+func (h *BackendHealth) IsAvailable() bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.status != BackendStatusDown
+}
+
+// MarkDown records a failed probe or request
+func (h *BackendHealth) MarkDown(err error) {
+	h.mu.Lock()
+	wasDown := h.status == BackendStatusDown
+	if !wasDown {
+		log.Printf("⚠️  Backend %s unreachable: %v", h.baseURL, err)
+	}
+	h.status = BackendStatusDown
+	h.lastChecked = time.Now()
+	h.lastError = err.Error()
+	h.failures++
+	h.mu.Unlock()
+
+	if !wasDown && h.incidents != nil {
+		h.incidents.Open(models.IncidentComponentBackend, models.IncidentKindOutage, err.Error())
+	}
+}
+
+func (h *BackendHealth) markUp() {
+	h.mu.Lock()
+	wasDown := h.status == BackendStatusDown
+	switch h.status {
+	case BackendStatusDown:
+		log.Printf("✓ Backend %s reconnected after %d failed checks", h.baseURL, h.failures)
+	case BackendStatusUnknown:
+		log.Printf("✓ Backend %s is up", h.baseURL)
+	}
+	h.status = BackendStatusUp
+	h.lastChecked = time.Now()
+	h.lastError = ""
+	h.failures = 0
+	h.mu.Unlock()
+
+	if wasDown && h.incidents != nil {
+		h.incidents.Resolve(models.IncidentComponentBackend, models.IncidentKindOutage)
+	}
+}
+
+// Status returns a snapshot of the current state
+func (h *BackendHealth) Status() BackendHealthStatus {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return BackendHealthStatus{
+		Status:              h.status,
+		LastChecked:         h.lastChecked,
+		LastError:           h.lastError,
+		ConsecutiveFailures: h.failures,
+	}
+}
@@ -0,0 +1,130 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"lio-ai/internal/models"
+	"lio-ai/internal/repositories"
+)
+
+// ErrBillingPeriodOpen is returned when closing a month that is not over,
+// or whose usage is not rolled up yet
+var ErrBillingPeriodOpen = errors.New("billing period is still open")
+
+// BillingService closes each UTC month into an immutable statement per
+// user, from the daily usage rollups, with the adjustments admins added
+type BillingService struct {
+	repo  *repositories.BillingRepository
+	users *repositories.UserRepository
+}
+
+// NewBillingService creates a billing service
+func NewBillingService(repo *repositories.BillingRepository, users *repositories.UserRepository) *BillingService {
+	return &BillingService{repo: repo, users: users}
+}
+
+// CloseMonth closes period (YYYY-MM) into statements for the users with
+// usage or adjustments in it who have none for it yet, returning how many
+// were closed. Closing a month again only bills users it missed.
+func (s *BillingService) CloseMonth(period string) (int, error) {
+	start, err := time.ParseInLocation(models.BillingPeriodFormat, strings.TrimSpace(period), time.UTC)
+	if err != nil {
+		return 0, fmt.Errorf("%w: period must be YYYY-MM", ErrInvalidMessage)
+	}
+	return s.closeMonth(start, time.Now())
+}
+
+func (s *BillingService) closeMonth(start, now time.Time) (int, error) {
+	end := start.AddDate(0, 1, 0)
+	period := start.Format(models.BillingPeriodFormat)
+	if end.After(now) {
+		return 0, fmt.Errorf("%w: %s is not over", ErrBillingPeriodOpen, period)
+	}
+	rolledUntil, err := s.repo.RolledUntil()
+	if err != nil {
+		return 0, err
+	}
+	if rolledUntil.Before(end) {
+		return 0, fmt.Errorf("%w: usage is only rolled up until %s", ErrBillingPeriodOpen, rolledUntil.Format(time.RFC3339))
+	}
+	return s.repo.CloseMonth(period, start, end)
+}
+
+// Start closes the previous month into statements once its usage is
+// rolled up, checking on an interval until the process exits. The first
+// check waits an interval, for the rollups to catch up on startup.
+func (s *BillingService) Start(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			now := time.Now().UTC()
+			start := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC).AddDate(0, -1, 0)
+			closed, err := s.closeMonth(start, now)
+			switch {
+			case errors.Is(err, ErrBillingPeriodOpen):
+				// The rollups have not caught up with the month's end yet
+			case err != nil:
+				log.Printf("⚠️  Failed to close billing statements: %v", err)
+			case closed > 0:
+				log.Printf("🧾 Billing: closed %d statements for %s", closed, start.Format(models.BillingPeriodFormat))
+			}
+		}
+	}()
+}
+
+// ListStatements retrieves a user's statements, latest month first
+func (s *BillingService) ListStatements(userID string) ([]models.BillingStatement, error) {
+	return s.repo.ListStatements(userID)
+}
+
+// GetStatement retrieves a statement with its lines and adjustments. Users
+// only see their own; others' are ErrNotFound unless the caller is an
+// admin.
+func (s *BillingService) GetStatement(id int64, userID string, isAdmin bool) (*models.BillingStatement, error) {
+	statement, err := s.repo.GetStatement(id)
+	if err != nil {
+		return nil, err
+	}
+	if statement == nil || (statement.UserID != userID && !isAdmin) {
+		return nil, fmt.Errorf("%w: statement %d", ErrNotFound, id)
+	}
+	return statement, nil
+}
+
+// ListPendingAdjustments retrieves a user's adjustments that are not yet
+// on a statement
+func (s *BillingService) ListPendingAdjustments(userID string) ([]models.BillingAdjustment, error) {
+	return s.repo.ListPendingAdjustments(userID)
+}
+
+// AddAdjustment adds a credit or charge to a user's next statement
+func (s *BillingService) AddAdjustment(userID int64, req *models.CreateBillingAdjustmentRequest, createdBy string) (*models.BillingAdjustment, error) {
+	user, err := s.users.FindByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, fmt.Errorf("%w: user %d", ErrNotFound, userID)
+	}
+	reason := strings.TrimSpace(req.Reason)
+	if reason == "" {
+		return nil, fmt.Errorf("%w: reason is required", ErrInvalidMessage)
+	}
+
+	adjustment := &models.BillingAdjustment{
+		UserID:    strconv.FormatInt(userID, 10),
+		AmountUSD: req.AmountUSD,
+		Reason:    reason,
+		CreatedBy: createdBy,
+	}
+	if err := s.repo.CreateAdjustment(adjustment); err != nil {
+		return nil, err
+	}
+	return adjustment, nil
+}
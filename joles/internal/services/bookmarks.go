@@ -0,0 +1,63 @@
+package services
+
+import (
+	"fmt"
+
+	"lio-ai/internal/models"
+)
+
+// SetChatPinned pins or unpins a chat owned by the user
+func (s *ChatService) SetChatPinned(chatID int64, userID string, pinned bool) (*models.Chat, error) {
+	chat, err := s.repo.GetChatByID(chatID)
+	if err != nil {
+		return nil, err
+	}
+	if chat.UserID != userID {
+		return nil, ErrUnauthorized
+	}
+
+	if err := s.repo.SetChatPinned(chatID, pinned); err != nil {
+		return nil, err
+	}
+	chat.IsPinned = pinned
+	return chat, nil
+}
+
+// SetMessageBookmarked bookmarks or un-bookmarks a message in a chat owned by the user
+func (s *ChatService) SetMessageBookmarked(chatID, messageID int64, userID string, bookmarked bool) (*models.Message, error) {
+	chat, err := s.repo.GetChatByID(chatID)
+	if err != nil {
+		return nil, err
+	}
+	if chat.UserID != userID {
+		return nil, ErrUnauthorized
+	}
+
+	message, err := s.repo.GetMessageByID(messageID)
+	if err != nil {
+		return nil, err
+	}
+	if message.ChatID != chatID {
+		return nil, fmt.Errorf("message not found")
+	}
+
+	if err := s.repo.SetMessageBookmarked(messageID, bookmarked); err != nil {
+		return nil, err
+	}
+	message.Bookmarked = bookmarked
+	return message, nil
+}
+
+// GetBookmarks lists the user's bookmarked messages across all chats
+func (s *ChatService) GetBookmarks(userID string, limit, offset int) ([]models.Bookmark, error) {
+	if limit <= 0 {
+		limit = 20
+	}
+	if limit > 100 {
+		limit = 100
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	return s.repo.GetBookmarksByUserID(userID, limit, offset)
+}
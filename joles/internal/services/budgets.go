@@ -0,0 +1,262 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"lio-ai/internal/models"
+	"lio-ai/internal/repositories"
+)
+
+// ErrBudgetExceeded is returned when a user with a "block" budget, or in
+// an organization with one, has already spent it this month
+var ErrBudgetExceeded = errors.New("budget exceeded")
+
+// BudgetExceededError is the ErrBudgetExceeded of CheckBudget, carrying
+// the spent budget so it can still be reported to the client
+type BudgetExceededError struct {
+	Status *models.BudgetStatus
+}
+
+func (e *BudgetExceededError) Error() string {
+	kind := "monthly budget"
+	if e.Status.Scope == models.BudgetScopeOrg {
+		kind = "monthly organization budget"
+	}
+	return fmt.Sprintf("%v: $%.6f spent of a $%.6f %s", ErrBudgetExceeded, e.Status.SpentUSD, e.Status.MonthlyUSD, kind)
+}
+
+// Unwrap lets errors.Is match ErrBudgetExceeded
+func (e *BudgetExceededError) Unwrap() error {
+	return ErrBudgetExceeded
+}
+
+// SetBudgets enables monthly spending budgets, kept in budgets
+func (s *UsageService) SetBudgets(budgets *repositories.BudgetRepository) {
+	s.budgets = budgets
+}
+
+// GetBudgetStatus returns userID's spending against their budget this
+// month, or nil when they have no budget. Spending is the cost of all their
+// usage recorded since the quota month began. When their organization has
+// a budget too, the one that binds is returned: a spent budget before an
+// unspent one, then "block" before "throttle" before "warn", otherwise the
+// one with less left.
+func (s *UsageService) GetBudgetStatus(userID string) (*models.BudgetStatus, error) {
+	if s.budgets == nil || userID == "" {
+		return nil, nil
+	}
+	now := time.Now()
+	var status *models.BudgetStatus
+	budget, err := s.budgets.Get(models.BudgetScopeUser, userID)
+	if err != nil {
+		return nil, err
+	}
+	if budget != nil {
+		if status, err = s.budgetStatus(budget, now); err != nil {
+			return nil, err
+		}
+	}
+
+	if s.orgs == nil {
+		return status, nil
+	}
+	org, err := s.orgs.GetByUser(userID)
+	if err != nil || org == nil {
+		return status, err
+	}
+	if budget, err = s.budgets.Get(models.BudgetScopeOrg, strconv.FormatInt(org.ID, 10)); err != nil || budget == nil {
+		return status, err
+	}
+	orgStatus, err := s.budgetStatus(budget, now)
+	if err != nil {
+		return nil, err
+	}
+	if status == nil || budgetBinds(orgStatus, status) {
+		return orgStatus, nil
+	}
+	return status, nil
+}
+
+// budgetSeverity orders what a budget does to the next request: nothing
+// while unspent, then a warning, a cheaper model, a refusal
+func budgetSeverity(status *models.BudgetStatus) int {
+	if !status.Exceeded {
+		return 0
+	}
+	switch status.Mode {
+	case models.BudgetModeBlock:
+		return 3
+	case models.BudgetModeThrottle:
+		return 2
+	default:
+		return 1
+	}
+}
+
+// budgetBinds reports whether a takes precedence over b
+func budgetBinds(a, b *models.BudgetStatus) bool {
+	if sa, sb := budgetSeverity(a), budgetSeverity(b); sa != sb {
+		return sa > sb
+	}
+	return a.RemainingUSD < b.RemainingUSD
+}
+
+// CheckBudget is run before billable requests and returns the user's
+// budget status, nil when they have none. A spent "block" budget rejects
+// the request; "warn" and "throttle" budgets let it through, leaving the
+// caller to downgrade the model of a throttled one. The request that
+// crosses the budget is allowed to finish, so spending can end up slightly
+// above it.
+func (s *UsageService) CheckBudget(userID string) (*models.BudgetStatus, error) {
+	status, err := s.GetBudgetStatus(userID)
+	if err != nil || status == nil {
+		return nil, err
+	}
+	if status.Exceeded && status.Mode == models.BudgetModeBlock {
+		return status, &BudgetExceededError{Status: status}
+	}
+	return status, nil
+}
+
+// ListBudgets retrieves every budget with its spending this month
+func (s *UsageService) ListBudgets() ([]models.BudgetStatus, error) {
+	if s.budgets == nil {
+		return []models.BudgetStatus{}, nil
+	}
+	budgets, err := s.budgets.List()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	statuses := make([]models.BudgetStatus, 0, len(budgets))
+	for i := range budgets {
+		status, err := s.budgetStatus(&budgets[i], now)
+		if err != nil {
+			return nil, err
+		}
+		statuses = append(statuses, *status)
+	}
+	return statuses, nil
+}
+
+// SetUserBudget creates or changes userID's monthly budget
+func (s *UsageService) SetUserBudget(userID string, req *models.SetBudgetRequest, updatedBy string) (*models.BudgetStatus, error) {
+	return s.setBudget(models.BudgetScopeUser, userID, req, updatedBy)
+}
+
+// DeleteUserBudget removes userID's budget
+func (s *UsageService) DeleteUserBudget(userID string) error {
+	return s.deleteBudget(models.BudgetScopeUser, userID, "user "+userID)
+}
+
+// SetOrgBudget creates or changes the monthly budget an organization's
+// members share
+func (s *UsageService) SetOrgBudget(orgID int64, req *models.SetBudgetRequest, updatedBy string) (*models.BudgetStatus, error) {
+	if s.orgs == nil {
+		return nil, fmt.Errorf("%w: organizations are not enabled", ErrInvalidMessage)
+	}
+	org, err := s.orgs.GetByID(orgID)
+	if err != nil {
+		return nil, err
+	}
+	if org == nil {
+		return nil, fmt.Errorf("%w: organization %d", ErrNotFound, orgID)
+	}
+	return s.setBudget(models.BudgetScopeOrg, strconv.FormatInt(orgID, 10), req, updatedBy)
+}
+
+// GetOrgBudgetStatus returns an organization's spending against its budget
+// this month, or nil when it has no budget
+func (s *UsageService) GetOrgBudgetStatus(orgID int64) (*models.BudgetStatus, error) {
+	if s.budgets == nil {
+		return nil, nil
+	}
+	budget, err := s.budgets.Get(models.BudgetScopeOrg, strconv.FormatInt(orgID, 10))
+	if err != nil || budget == nil {
+		return nil, err
+	}
+	return s.budgetStatus(budget, time.Now())
+}
+
+// DeleteOrgBudget removes an organization's budget
+func (s *UsageService) DeleteOrgBudget(orgID int64) error {
+	return s.deleteBudget(models.BudgetScopeOrg, strconv.FormatInt(orgID, 10), fmt.Sprintf("organization %d", orgID))
+}
+
+func (s *UsageService) setBudget(scope, scopeID string, req *models.SetBudgetRequest, updatedBy string) (*models.BudgetStatus, error) {
+	if s.budgets == nil {
+		return nil, fmt.Errorf("%w: budgets are not enabled", ErrInvalidMessage)
+	}
+	mode := req.Mode
+	if mode == "" {
+		mode = models.BudgetModeBlock
+	}
+	if mode != models.BudgetModeWarn && mode != models.BudgetModeThrottle && mode != models.BudgetModeBlock {
+		return nil, fmt.Errorf("%w: mode must be 'warn', 'throttle' or 'block'", ErrInvalidMessage)
+	}
+	if req.MonthlyUSD <= 0 {
+		return nil, fmt.Errorf("%w: monthly_usd must be positive", ErrInvalidMessage)
+	}
+
+	budget := &models.Budget{
+		Scope:      scope,
+		ScopeID:    scopeID,
+		MonthlyUSD: req.MonthlyUSD,
+		Mode:       mode,
+		UpdatedBy:  updatedBy,
+	}
+	if err := s.budgets.Upsert(budget); err != nil {
+		return nil, err
+	}
+	return s.budgetStatus(budget, time.Now())
+}
+
+func (s *UsageService) deleteBudget(scope, scopeID, owner string) error {
+	found := false
+	if s.budgets != nil {
+		var err error
+		if found, err = s.budgets.Delete(scope, scopeID); err != nil {
+			return err
+		}
+	}
+	if !found {
+		return fmt.Errorf("%w: %s has no budget", ErrNotFound, owner)
+	}
+	return nil
+}
+
+// budgetStatus adds the spending of budget's month containing now; an
+// organization's is what its current members spent
+func (s *UsageService) budgetStatus(budget *models.Budget, now time.Time) (*models.BudgetStatus, error) {
+	_, monthStart := s.quotaPeriodStarts(now)
+	_, nextMonth := s.nextQuotaResets(now)
+	var spent float64
+	var err error
+	if budget.Scope == models.BudgetScopeOrg {
+		var orgID int64
+		if orgID, err = strconv.ParseInt(budget.ScopeID, 10, 64); err == nil && s.orgs != nil {
+			spent, err = s.orgs.GetCostSince(orgID, monthStart)
+		}
+	} else {
+		spent, err = s.usageRepo.GetCostSince(budget.ScopeID, monthStart)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	status := &models.BudgetStatus{
+		Budget:      *budget,
+		SpentUSD:    spent,
+		PeriodStart: monthStart,
+		ResetsAt:    nextMonth,
+	}
+	if spent >= budget.MonthlyUSD {
+		status.Exceeded = true
+	} else {
+		status.RemainingUSD = budget.MonthlyUSD - spent
+	}
+	return status, nil
+}
@@ -0,0 +1,73 @@
+package services
+
+import (
+	"log"
+	"time"
+
+	"lio-ai/internal/models"
+	"lio-ai/internal/repositories"
+)
+
+// Change feed page sizes
+const (
+	DefaultChangeFeedLimit = 500
+	MaxChangeFeedLimit     = 5000
+)
+
+// ChangeFeedService serves the ordered change events the analytics service
+// consumes incrementally, and expires old ones
+type ChangeFeedService struct {
+	repo      *repositories.ChangeRepository
+	retention time.Duration // 0 keeps events indefinitely
+}
+
+// NewChangeFeedService creates a change feed service
+func NewChangeFeedService(repo *repositories.ChangeRepository, retention time.Duration) *ChangeFeedService {
+	return &ChangeFeedService{repo: repo, retention: retention}
+}
+
+// List returns up to limit events after the since cursor. Reading one
+// more than requested tells whether the consumer is caught up.
+func (s *ChangeFeedService) List(since int64, limit int) (*models.ChangeFeedPage, error) {
+	if limit <= 0 {
+		limit = DefaultChangeFeedLimit
+	}
+	if limit > MaxChangeFeedLimit {
+		limit = MaxChangeFeedLimit
+	}
+
+	events, err := s.repo.ListSince(since, limit+1)
+	if err != nil {
+		return nil, err
+	}
+	page := &models.ChangeFeedPage{Data: events, NextCursor: since}
+	if len(events) > limit {
+		page.Data = events[:limit]
+		page.HasMore = true
+	}
+	if n := len(page.Data); n > 0 {
+		page.NextCursor = page.Data[n-1].ID
+	}
+	return page, nil
+}
+
+// Start deletes events past the retention period on an interval until the
+// process exits. It does nothing when events are kept indefinitely.
+func (s *ChangeFeedService) Start(interval time.Duration) {
+	if s.retention <= 0 {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			deleted, err := s.repo.DeleteBefore(time.Now().Add(-s.retention))
+			if err != nil {
+				log.Printf("Failed to expire change events: %v", err)
+			} else if deleted > 0 {
+				log.Printf("🧹 Change feed: deleted %d expired events", deleted)
+			}
+			<-ticker.C
+		}
+	}()
+}
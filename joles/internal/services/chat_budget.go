@@ -0,0 +1,106 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"lio-ai/internal/models"
+)
+
+// ErrChatBudgetExceeded is returned when a chat with a "block" budget has
+// already spent it
+var ErrChatBudgetExceeded = errors.New("chat budget exceeded")
+
+// ErrInvalidBudgetAction is returned for a budget_action other than block or warn
+var ErrInvalidBudgetAction = errors.New("budget_action must be 'block' or 'warn'")
+
+// ValidBudgetAction reports whether action is a known chat budget action
+func ValidBudgetAction(action string) bool {
+	return action == models.BudgetActionBlock || action == models.BudgetActionWarn
+}
+
+// chatBudget returns the chat's spending against its budget, or nil when
+// the chat has no budget or usage is not tracked. Spending is the cost of
+// every completion recorded against the chat in usage_metrics.
+func (s *ChatService) chatBudget(chat *models.Chat) (*models.ChatBudgetStatus, error) {
+	if chat.BudgetUSD <= 0 || s.usageService == nil {
+		return nil, nil
+	}
+
+	usage, err := s.usageService.GetChatUsage(chat.UserID, chat.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &models.ChatBudgetStatus{
+		BudgetUSD: chat.BudgetUSD,
+		SpentUSD:  usage.TotalCostUSD,
+		Action:    chat.BudgetAction,
+	}
+	if status.Action == "" {
+		status.Action = models.BudgetActionBlock
+	}
+	if status.SpentUSD >= status.BudgetUSD {
+		status.Exceeded = true
+	} else {
+		status.RemainingUSD = status.BudgetUSD - status.SpentUSD
+	}
+	return status, nil
+}
+
+// checkChatBudget is run before a completion. A spent "block" budget
+// rejects the request; a spent "warn" budget lets it through. The
+// completion that crosses the budget is allowed to finish, so spending can
+// end up slightly above it.
+func (s *ChatService) checkChatBudget(chat *models.Chat) error {
+	status, err := s.chatBudget(chat)
+	if err != nil || status == nil {
+		return err
+	}
+	if status.Exceeded && status.Action == models.BudgetActionBlock {
+		return fmt.Errorf("%w: $%.6f spent of a $%.6f budget", ErrChatBudgetExceeded, status.SpentUSD, status.BudgetUSD)
+	}
+	return nil
+}
+
+// budgetWarning describes a spent "warn" budget for the completion response
+func budgetWarning(status *models.ChatBudgetStatus) string {
+	if status == nil || !status.Exceeded || status.Action != models.BudgetActionWarn {
+		return ""
+	}
+	return fmt.Sprintf("chat budget exceeded: $%.6f spent of a $%.6f budget", status.SpentUSD, status.BudgetUSD)
+}
+
+// checkSpendingBudget is checkChatBudget for the user's monthly budget,
+// returning its status for the response. Exempt traffic and untracked
+// usage have no budget.
+func (s *ChatService) checkSpendingBudget(ctx context.Context, userID string) (*models.BudgetStatus, error) {
+	if s.usageService == nil || userID == "" || usageExempt(ctx) {
+		return nil, nil
+	}
+	return s.usageService.CheckBudget(userID)
+}
+
+// throttleModel sends the requests of a user whose "throttle" budget is
+// spent to the cheapest active chat model, returning the model to use and
+// the one requested when it changed. Without a cheaper model the request
+// goes through unchanged.
+func (s *ChatService) throttleModel(status *models.BudgetStatus, model string) (string, string) {
+	if status == nil || !status.Exceeded || status.Mode != models.BudgetModeThrottle {
+		return model, ""
+	}
+	cheapest, err := s.usageService.CheapestChatModel()
+	if err != nil {
+		log.Printf("⚠️  Failed to find a model to throttle %s to: %v", status.ScopeID, err)
+		return model, ""
+	}
+	if cheapest == model {
+		return model, ""
+	}
+	if model == "" {
+		return cheapest, "default"
+	}
+	return cheapest, model
+}
@@ -0,0 +1,248 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"lio-ai/internal/models"
+	"lio-ai/internal/repositories"
+)
+
+const (
+	// Documents a chat can have attached
+	maxChatDocuments = 20
+	// Passages of attached documents added to each prompt, and the most
+	// runes they take together
+	contextChunkLimit = 5
+	contextMaxRunes   = 6000
+)
+
+// contextPassage is a passage of an attached document picked for a prompt
+type contextPassage struct {
+	chunk   models.ContextChunk
+	content string
+}
+
+// SetChatDocuments lets documents be attached to chats, their passages
+// relevant to each prompt being added to the completion's context. Reads
+// of confidential documents are recorded in accessLog.
+func (s *ChatService) SetChatDocuments(chatDocs *repositories.ChatDocumentRepository, docs *repositories.DocumentRepository, accessLog *repositories.DocumentAccessRepository) {
+	s.chatDocs = chatDocs
+	s.docs = docs
+	s.docAccessLog = accessLog
+}
+
+// SetContextSearch finds the passages of attached documents relevant to a
+// prompt by semantic search. Without it, or when it finds none, passages
+// are ranked by the share of the prompt's terms they contain.
+func (s *ChatService) SetContextSearch(search *SemanticSearchService) {
+	s.contextSearch = search
+}
+
+// AttachDocuments attaches documents owned by userID to one of their
+// chats and returns the chat's documents
+func (s *ChatService) AttachDocuments(chatID int64, userID string, documentIDs []uint) ([]models.ChatDocument, error) {
+	if s.chatDocs == nil {
+		return nil, fmt.Errorf("%w: documents can't be attached to chats", ErrInvalidMessage)
+	}
+	if _, err := s.ownedChat(chatID, userID); err != nil {
+		return nil, err
+	}
+	attached, err := s.chatDocs.List(chatID)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[uint]bool, len(attached)+len(documentIDs))
+	for _, doc := range attached {
+		seen[doc.DocumentID] = true
+	}
+	count := len(attached)
+	for _, id := range documentIDs {
+		doc, err := s.docs.GetByID(id)
+		if err != nil {
+			return nil, err
+		}
+		if doc == nil {
+			return nil, fmt.Errorf("%w: document %d", ErrNotFound, id)
+		}
+		if doc.UserID != userID {
+			return nil, ErrUnauthorized
+		}
+		if !seen[id] {
+			seen[id] = true
+			count++
+		}
+	}
+	if count > maxChatDocuments {
+		return nil, fmt.Errorf("%w: a chat can have at most %d documents attached", ErrInvalidMessage, maxChatDocuments)
+	}
+
+	if err := s.chatDocs.Attach(chatID, documentIDs); err != nil {
+		return nil, err
+	}
+	return s.chatDocs.List(chatID)
+}
+
+// ListChatDocuments retrieves the documents attached to one of userID's
+// chats
+func (s *ChatService) ListChatDocuments(chatID int64, userID string) ([]models.ChatDocument, error) {
+	if _, err := s.ownedChat(chatID, userID); err != nil {
+		return nil, err
+	}
+	if s.chatDocs == nil {
+		return []models.ChatDocument{}, nil
+	}
+	return s.chatDocs.List(chatID)
+}
+
+// DetachDocument removes a document from one of userID's chats
+func (s *ChatService) DetachDocument(chatID int64, userID string, documentID uint) error {
+	if _, err := s.ownedChat(chatID, userID); err != nil {
+		return err
+	}
+	found := false
+	if s.chatDocs != nil {
+		var err error
+		if found, err = s.chatDocs.Detach(chatID, documentID); err != nil {
+			return err
+		}
+	}
+	if !found {
+		return fmt.Errorf("%w: document %d is not attached to chat %d", ErrNotFound, documentID, chatID)
+	}
+	return nil
+}
+
+// documentContext picks the passages of a chat's attached documents most
+// relevant to prompt and returns them with the system message carrying
+// them. Failures are logged and leave the prompt without context.
+func (s *ChatService) documentContext(ctx context.Context, chatID int64, userID, prompt string) ([]models.ContextChunk, map[string]interface{}) {
+	if s.chatDocs == nil || chatID == 0 || strings.TrimSpace(prompt) == "" {
+		return nil, nil
+	}
+	attached, err := s.chatDocs.List(chatID)
+	if err != nil {
+		log.Printf("⚠️  Failed to load documents of chat %d: %v", chatID, err)
+		return nil, nil
+	}
+	if len(attached) == 0 {
+		return nil, nil
+	}
+	ids := make([]uint, len(attached))
+	for i, doc := range attached {
+		ids[i] = doc.DocumentID
+	}
+
+	reader := models.DocumentReader{UserID: userID, Endpoint: "POST /api/v1/chat/completions"}
+	var passages []contextPassage
+	if s.contextSearch != nil {
+		if passages, err = s.searchContext(ctx, userID, prompt, ids, reader); err != nil {
+			log.Printf("⚠️  Semantic search of chat %d documents failed, matching terms instead: %v", chatID, err)
+		}
+	}
+	if len(passages) == 0 {
+		if passages, err = s.matchContext(userID, prompt, ids, reader); err != nil {
+			log.Printf("⚠️  Failed to match documents of chat %d: %v", chatID, err)
+			return nil, nil
+		}
+	}
+	if len(passages) == 0 {
+		return nil, nil
+	}
+
+	var b strings.Builder
+	b.WriteString("Passages from documents the user attached to this chat follow. " +
+		"Use them where they help answer, naming the document drawn on, and ignore those that are not relevant.")
+	chunks := make([]models.ContextChunk, 0, len(passages))
+	used := 0
+	for _, p := range passages {
+		content := p.content
+		if n := utf8.RuneCountInString(content); used+n > contextMaxRunes {
+			if len(chunks) > 0 {
+				break
+			}
+			content = string([]rune(content)[:contextMaxRunes])
+		}
+		used += utf8.RuneCountInString(content)
+		chunks = append(chunks, p.chunk)
+		fmt.Fprintf(&b, "\n\n[%d] %s (passage %d)\n%s", len(chunks), p.chunk.Title, p.chunk.Seq+1, content)
+	}
+	return chunks, map[string]interface{}{"role": "system", "content": b.String()}
+}
+
+// searchContext finds the indexed passages of documents closest to prompt
+func (s *ChatService) searchContext(ctx context.Context, userID, prompt string, ids []uint, reader models.DocumentReader) ([]contextPassage, error) {
+	resp, err := s.contextSearch.Search(ctx, models.SemanticSearchQuery{
+		UserID:      userID,
+		Query:       prompt,
+		Limit:       contextChunkLimit,
+		Hybrid:      true,
+		TextWeight:  DefaultSearchTextWeight,
+		DocumentIDs: ids,
+		Reader:      reader,
+	})
+	if err != nil {
+		return nil, err
+	}
+	passages := make([]contextPassage, 0, len(resp.Data))
+	for _, r := range resp.Data {
+		passages = append(passages, contextPassage{
+			chunk: models.ContextChunk{
+				DocumentID: r.Document.ID,
+				Title:      r.Document.Title,
+				ChunkID:    r.ChunkID,
+				Seq:        r.Seq,
+				Score:      r.Score,
+			},
+			content: r.Content,
+		})
+	}
+	return passages, nil
+}
+
+// matchContext splits documents owned by userID into passages and ranks
+// them by the share of prompt's terms each contains
+func (s *ChatService) matchContext(userID, prompt string, ids []uint, reader models.DocumentReader) ([]contextPassage, error) {
+	terms := searchTerms(prompt)
+	if len(terms) == 0 {
+		return nil, nil
+	}
+	var passages []contextPassage
+	confidential := make(map[uint]bool)
+	for _, id := range ids {
+		doc, err := s.docs.GetByID(id)
+		if err != nil {
+			return nil, err
+		}
+		if doc == nil || doc.UserID != userID {
+			continue
+		}
+		confidential[doc.ID] = doc.Confidential
+		for seq, content := range splitDocument(doc) {
+			if score := termCoverage(terms, content); score > 0 {
+				passages = append(passages, contextPassage{
+					chunk:   models.ContextChunk{DocumentID: doc.ID, Title: doc.Title, Seq: seq, Score: score},
+					content: content,
+				})
+			}
+		}
+	}
+
+	sort.SliceStable(passages, func(i, j int) bool { return passages[i].chunk.Score > passages[j].chunk.Score })
+	if len(passages) > contextChunkLimit {
+		passages = passages[:contextChunkLimit]
+	}
+	read := make(map[uint]bool)
+	for _, p := range passages {
+		if confidential[p.chunk.DocumentID] && !read[p.chunk.DocumentID] {
+			read[p.chunk.DocumentID] = true
+			recordDocumentRead(s.docAccessLog, reader, p.chunk.DocumentID)
+		}
+	}
+	return passages, nil
+}
@@ -0,0 +1,34 @@
+package services
+
+import (
+	"context"
+
+	"lio-ai/internal/models"
+)
+
+// SetModerationService enables moderation of user messages before completion
+func (s *ChatService) SetModerationService(moderation *ModerationService) {
+	s.moderation = moderation
+}
+
+// SetSecurityService enables jailbreak detection on user messages, and
+// strict moderation for flagged users when the service has it
+func (s *ChatService) SetSecurityService(security *SecurityService) {
+	s.security = security
+}
+
+// moderateMessage screens a user message before it is saved and sent:
+// jailbreak attempts are recorded, then the message is moderated, strictly
+// for flagged users. It returns nil, nil when moderation is not enabled.
+func (s *ChatService) moderateMessage(ctx context.Context, userID string, chatID int64, content string) (*models.ModerationResult, error) {
+	if s.security != nil {
+		s.security.Inspect(userID, chatID, content)
+		if strict := s.security.strictModeration(userID); strict != nil {
+			return strict.CheckStrict(ctx, userID, content)
+		}
+	}
+	if s.moderation == nil {
+		return nil, nil
+	}
+	return s.moderation.Check(ctx, userID, content)
+}
@@ -0,0 +1,54 @@
+package services
+
+import (
+	"fmt"
+	"log"
+
+	"lio-ai/internal/models"
+)
+
+// setChatPersona points a chat at one of ownerID's personas, normally the
+// chat's owner; 0 clears it
+func (s *ChatService) setChatPersona(chat *models.Chat, personaID int64, ownerID string) error {
+	if personaID == 0 {
+		chat.PersonaID = nil
+		return nil
+	}
+	if s.personas == nil {
+		return fmt.Errorf("%w: personas are not enabled", ErrPersonaNotFound)
+	}
+	if _, err := s.personas.chatPersona(personaID, ownerID); err != nil {
+		return err
+	}
+	chat.PersonaID = &personaID
+	return nil
+}
+
+// chatPersona loads the persona a chat uses, or nil if it has none. A
+// persona that can't be loaded is logged and the chat answers without it.
+func (s *ChatService) chatPersona(chat *models.Chat) *models.Persona {
+	if chat == nil || chat.PersonaID == nil || s.personas == nil {
+		return nil
+	}
+	persona, err := s.personas.repo.GetByID(*chat.PersonaID)
+	if err != nil {
+		log.Printf("⚠️  Failed to load persona %d for chat %d: %v", *chat.PersonaID, chat.ID, err)
+		return nil
+	}
+	return persona
+}
+
+// withPersonaPrompt puts the persona's system prompt ahead of the history.
+// It is sent with every completion rather than stored as a message, so
+// switching persona takes effect from the next reply.
+func withPersonaPrompt(persona *models.Persona, messages []map[string]interface{}) []map[string]interface{} {
+	if persona == nil || persona.SystemPrompt == "" {
+		return messages
+	}
+	prompted := make([]map[string]interface{}, 0, len(messages)+1)
+	prompted = append(prompted, map[string]interface{}{
+		"role":    "system",
+		"content": persona.SystemPrompt,
+	})
+	return append(prompted, messages...)
+}
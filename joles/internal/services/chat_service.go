@@ -2,39 +2,134 @@ package services
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"encoding/json"
 	"fmt"
 	"io"
+	"log"
 	"net/http"
-	"os"
+	"strconv"
+	"sync"
+	"time"
 
 	"lio-ai/internal/models"
 	"lio-ai/internal/repositories"
+	"lio-ai/internal/storage"
+	"lio-ai/internal/tokenizer"
 )
 
 // ChatService handles business logic for chats
 type ChatService struct {
-	repo *repositories.ChatRepository
+	repo         *repositories.ChatRepository
+	usageService *UsageService
+	// AI service completions and embeddings are sent to; defaultAIServiceURL
+	// when unset
+	aiServiceURL string
+
+	// Optional attachment storage; uploads are rejected when unset
+	attachments        storage.Store
+	templates          *TemplateService
+	personas           *PersonaService
+	moderation         *ModerationService
+	security           *SecurityService
+	aliases            *ModelAliasService
+	maxAttachmentBytes int64
+
+	// Optional fallback chains by model, tried when a completion fails
+	fallbacks      map[string][]string
+	attemptTimeout time.Duration
+
+	// Optional latency routing of logical models across providers
+	health       *ModelHealth
+	routes       map[string][]string
+	hysteresis   float64
+	routeCurrent map[string]string
+	routeMu      sync.Mutex
+
+	// Optional storage accounting for attachments
+	storage *StorageService
+
+	// Optional cache of completions for identical requests
+	cache *ResponseCache
+
+	// Optional post-processing of assistant output
+	responsePlugins *ResponsePluginService
+
+	// Optional deprecation warnings and sunset replacement of models
+	deprecations *ModelDeprecationService
+
+	// Optional per-user provider order for routed models
+	preferences *PreferencesService
+
+	// Optional documents attached to chats, searched for context
+	chatDocs      *repositories.ChatDocumentRepository
+	docs          *repositories.DocumentRepository
+	docAccessLog  *repositories.DocumentAccessRepository
+	contextSearch *SemanticSearchService
+
+	// Optional platform-managed keys by provider, for failover
+	platformKeys map[string]string
+
+	// In-flight completions by chat ID, so they can be stopped
+	inflight   map[int64]*inflightGeneration
+	inflightMu sync.Mutex
 }
 
+// inflightGeneration tracks a running completion
+type inflightGeneration struct {
+	cancel  context.CancelFunc
+	stopped bool
+}
+
+// defaultAIServiceURL is the AI service of a local development setup
+const defaultAIServiceURL = "http://localhost:8000"
+
+var (
+	// ErrNoGeneration is returned when stopping a chat with no running completion
+	ErrNoGeneration = errors.New("no generation in progress")
+	// ErrInvalidMessage is returned when a message fails validation
+	ErrInvalidMessage = errors.New("invalid message")
+	// ErrInvalidContextStrategy is returned for unknown context strategies
+	ErrInvalidContextStrategy = errors.New("context_strategy must be 'truncate' or 'summarize'")
+)
+
 // NewChatService creates a new chat service
-func NewChatService(repo *repositories.ChatRepository) *ChatService {
-	return &ChatService{repo: repo}
+func NewChatService(repo *repositories.ChatRepository, usageService *UsageService) *ChatService {
+	return &ChatService{
+		repo:         repo,
+		usageService: usageService,
+		inflight:     make(map[int64]*inflightGeneration),
+	}
+}
+
+// CreateChat creates a new chat, optionally using one of the user's personas
+func (s *ChatService) CreateChat(userID, title, contextStrategy string, personaID int64) (*models.Chat, error) {
+	return s.createChat(userID, title, contextStrategy, personaID, userID)
 }
 
-// CreateChat creates a new chat
-func (s *ChatService) CreateChat(userID, title string) (*models.Chat, error) {
+// createChat creates a new chat using one of personaOwnerID's personas
+func (s *ChatService) createChat(userID, title, contextStrategy string, personaID int64, personaOwnerID string) (*models.Chat, error) {
 	if userID == "" {
 		return nil, fmt.Errorf("user_id is required")
 	}
 	if title == "" {
 		title = "New Chat"
 	}
+	if contextStrategy == "" {
+		contextStrategy = models.ContextStrategyTruncate
+	}
+	if !ValidContextStrategy(contextStrategy) {
+		return nil, ErrInvalidContextStrategy
+	}
 
 	chat := &models.Chat{
-		UserID: userID,
-		Title:  title,
+		UserID:          userID,
+		Title:           title,
+		ContextStrategy: contextStrategy,
+	}
+	if err := s.setChatPersona(chat, personaID, personaOwnerID); err != nil {
+		return nil, err
 	}
 
 	if err := s.repo.CreateChat(chat); err != nil {
@@ -44,37 +139,115 @@ func (s *ChatService) CreateChat(userID, title string) (*models.Chat, error) {
 	return chat, nil
 }
 
-// GetChat retrieves a chat by ID with its messages (with ownership check)
-func (s *ChatService) GetChat(id int64, userID string) (*models.ChatWithMessages, error) {
+// SetAIServiceURL sets the AI service completions and embeddings are sent to
+func (s *ChatService) SetAIServiceURL(url string) {
+	s.aiServiceURL = url
+}
+
+// aiService returns the base URL of the AI service
+func (s *ChatService) aiService() string {
+	if s.aiServiceURL == "" {
+		return defaultAIServiceURL
+	}
+	return s.aiServiceURL
+}
+
+// SetTemplateService enables template_id on chat completion requests
+func (s *ChatService) SetTemplateService(templates *TemplateService) {
+	s.templates = templates
+}
+
+// SetPersonaService enables persona_id on chats
+func (s *ChatService) SetPersonaService(personas *PersonaService) {
+	s.personas = personas
+}
+
+// SetModelAliases enables model aliases in completion requests
+func (s *ChatService) SetModelAliases(aliases *ModelAliasService) {
+	s.aliases = aliases
+}
+
+// resolveModel maps an alias to its concrete model, returning the alias
+// used (if any). Messages and usage record the concrete model.
+func (s *ChatService) resolveModel(model string) (string, string) {
+	model, alias, _ := s.resolveModelNotice(model)
+	return model, alias
+}
+
+// resolveModelNotice is resolveModel that also applies model deprecations:
+// a deprecated model is replaced once it is past its sunset, and the notice
+// to return with the response is reported
+func (s *ChatService) resolveModelNotice(model string) (string, string, *models.ModelDeprecationNotice) {
+	alias := ""
+	if s.aliases != nil {
+		model, alias = s.aliases.Resolve(model)
+	}
+	if s.deprecations == nil {
+		return model, alias, nil
+	}
+	model, notice := s.deprecations.Check(model)
+	return model, alias, notice
+}
+
+// ownedChat loads a chat, returning ErrNotFound when there is none and
+// ErrUnauthorized when it belongs to another user
+func (s *ChatService) ownedChat(id int64, userID string) (*models.Chat, error) {
 	chat, err := s.repo.GetChatByID(id)
+	return checkChatOwner(chat, strconv.FormatInt(id, 10), userID, err)
+}
+
+// ownedChatByUUID is ownedChat for a chat identified by UUID
+func (s *ChatService) ownedChatByUUID(uuid, userID string) (*models.Chat, error) {
+	chat, err := s.repo.GetChatByUUID(uuid)
+	return checkChatOwner(chat, uuid, userID, err)
+}
+
+func checkChatOwner(chat *models.Chat, ref, userID string, err error) (*models.Chat, error) {
+	if errors.Is(err, repositories.ErrChatNotFound) {
+		return nil, fmt.Errorf("%w: chat %s", ErrNotFound, ref)
+	}
 	if err != nil {
 		return nil, err
 	}
-
-	// CRITICAL: Verify ownership
 	if chat.UserID != userID {
 		return nil, ErrUnauthorized
 	}
+	return chat, nil
+}
+
+// GetChat retrieves a chat by ID with its messages (with ownership check)
+func (s *ChatService) GetChat(id int64, userID string) (*models.ChatWithMessages, error) {
+	// CRITICAL: Verify ownership
+	chat, err := s.ownedChat(id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	messages, err := s.messagesWithAttachments(id)
+	if err != nil {
+		return nil, err
+	}
 
-	messages, err := s.repo.GetMessagesByChatID(id)
+	budget, err := s.chatBudget(chat)
 	if err != nil {
 		return nil, err
 	}
 
 	return &models.ChatWithMessages{
 		Chat:     *chat,
+		Budget:   budget,
 		Messages: messages,
 	}, nil
 }
 
-// GetChatByUUID retrieves a chat by UUID with its messages
-func (s *ChatService) GetChatByUUID(uuid string) (*models.ChatWithMessages, error) {
-	chat, err := s.repo.GetChatByUUID(uuid)
+// GetChatByUUID retrieves a user's chat by UUID with its messages
+func (s *ChatService) GetChatByUUID(uuid, userID string) (*models.ChatWithMessages, error) {
+	chat, err := s.ownedChatByUUID(uuid, userID)
 	if err != nil {
 		return nil, err
 	}
 
-	messages, err := s.repo.GetMessagesByChatID(chat.ID)
+	messages, err := s.messagesWithAttachments(chat.ID)
 	if err != nil {
 		return nil, err
 	}
@@ -107,15 +280,41 @@ func (s *ChatService) GetUserChats(userID string, limit, offset int) ([]models.C
 	return chats, total, nil
 }
 
-// UpdateChat updates a chat's title
-func (s *ChatService) UpdateChat(id int64, title string) (*models.Chat, error) {
-	chat, err := s.repo.GetChatByID(id)
+// UpdateChat updates the title, context strategy, response caps, budget and persona of a user's chat
+func (s *ChatService) UpdateChat(id int64, userID string, req *models.ChatRequest) (*models.Chat, error) {
+	chat, err := s.ownedChat(id, userID)
 	if err != nil {
 		return nil, err
 	}
 
-	if title != "" {
-		chat.Title = title
+	if req.Title != "" {
+		chat.Title = req.Title
+	}
+	if req.ContextStrategy != "" {
+		if !ValidContextStrategy(req.ContextStrategy) {
+			return nil, ErrInvalidContextStrategy
+		}
+		chat.ContextStrategy = req.ContextStrategy
+	}
+	if req.MaxOutputTokens != nil {
+		chat.MaxOutputTokens = *req.MaxOutputTokens
+	}
+	if req.MaxCostUSD != nil {
+		chat.MaxCostUSD = *req.MaxCostUSD
+	}
+	if req.BudgetUSD != nil {
+		chat.BudgetUSD = *req.BudgetUSD
+	}
+	if req.BudgetAction != "" {
+		if !ValidBudgetAction(req.BudgetAction) {
+			return nil, ErrInvalidBudgetAction
+		}
+		chat.BudgetAction = req.BudgetAction
+	}
+	if req.PersonaID != nil {
+		if err := s.setChatPersona(chat, *req.PersonaID, chat.UserID); err != nil {
+			return nil, err
+		}
 	}
 
 	if err := s.repo.UpdateChat(chat); err != nil {
@@ -125,36 +324,99 @@ func (s *ChatService) UpdateChat(id int64, title string) (*models.Chat, error) {
 	return chat, nil
 }
 
-// DeleteChat deletes a chat
-func (s *ChatService) DeleteChat(id int64) error {
-	return s.repo.DeleteChat(id)
+// DeleteChat deletes a user's chat and its stored attachments
+func (s *ChatService) DeleteChat(id int64, userID string) error {
+	if _, err := s.ownedChat(id, userID); err != nil {
+		return err
+	}
+	attachments, err := s.repo.GetAttachmentsByChatID(id)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.DeleteChat(id, userID); err != nil {
+		return err
+	}
+	s.deleteStoredAttachments(attachments)
+	s.releaseAttachmentStorage(attachments)
+	return nil
+}
+
+// SendMessage sends a message in a user's chat
+func (s *ChatService) SendMessage(chatID int64, userID string, req *models.MessageRequest) (*models.Message, error) {
+	if _, err := s.ownedChat(chatID, userID); err != nil {
+		return nil, err
+	}
+
+	return s.addMessage(chatID, req, false)
 }
 
-// SendMessage sends a message in a chat
-func (s *ChatService) SendMessage(chatID int64, role, content, model string) (*models.Message, error) {
-	// Validate chat exists
-	_, err := s.repo.GetChatByID(chatID)
+// SendMessageByUUID sends a message in a user's chat identified by UUID
+func (s *ChatService) SendMessageByUUID(uuid, userID string, req *models.MessageRequest) (*models.Message, error) {
+	chat, err := s.ownedChatByUUID(uuid, userID)
 	if err != nil {
 		return nil, err
 	}
 
+	return s.addMessage(chat.ID, req, false)
+}
+
+// addMessage validates and stores a message, including tool-calling fields.
+// Content may be empty when the message carries attachments.
+func (s *ChatService) addMessage(chatID int64, req *models.MessageRequest, hasAttachments bool) (*models.Message, error) {
+	role := req.Role
 	if role == "" {
 		role = "user"
 	}
-	if content == "" {
-		return nil, fmt.Errorf("content is required")
+
+	switch role {
+	case "user", "system":
+		if len(req.ToolCalls) > 0 || req.ToolCallID != "" {
+			return nil, fmt.Errorf("%w: tool_calls and tool_call_id are only valid on assistant and tool messages", ErrInvalidMessage)
+		}
+	case "assistant":
+		if req.ToolCallID != "" {
+			return nil, fmt.Errorf("%w: tool_call_id is only valid on tool messages", ErrInvalidMessage)
+		}
+	case "tool":
+		if req.ToolCallID == "" {
+			return nil, fmt.Errorf("%w: tool_call_id is required for tool messages", ErrInvalidMessage)
+		}
+		if len(req.ToolCalls) > 0 {
+			return nil, fmt.Errorf("%w: tool_calls are only valid on assistant messages", ErrInvalidMessage)
+		}
+	default:
+		return nil, fmt.Errorf("%w: invalid role: %s", ErrInvalidMessage, role)
 	}
 
-	var modelPtr *string
-	if model != "" {
-		modelPtr = &model
+	if len(req.ToolCalls) > 0 {
+		var calls []json.RawMessage
+		if err := json.Unmarshal(req.ToolCalls, &calls); err != nil {
+			return nil, fmt.Errorf("%w: tool_calls must be a JSON array", ErrInvalidMessage)
+		}
+	}
+
+	// Assistant messages that only call tools may have no text
+	if req.Content == "" && len(req.ToolCalls) == 0 && !hasAttachments {
+		return nil, fmt.Errorf("%w: content is required", ErrInvalidMessage)
 	}
 
 	message := &models.Message{
-		ChatID:  chatID,
-		Role:    role,
-		Content: content,
-		Model:   modelPtr,
+		ChatID:     chatID,
+		Role:       role,
+		Content:    req.Content,
+		Tokens:     tokenizer.Count(req.Model, req.Content) + tokenizer.Count(req.Model, string(req.ToolCalls)),
+		ToolCalls:  req.ToolCalls,
+		Truncated:  req.Truncated,
+		Moderation: req.Moderation,
+	}
+	message.ContextChunks = req.ContextChunks
+	if req.Model != "" {
+		model := req.Model
+		message.Model = &model
+	}
+	if req.ToolCallID != "" {
+		toolCallID := req.ToolCallID
+		message.ToolCallID = &toolCallID
 	}
 
 	if err := s.repo.CreateMessage(message); err != nil {
@@ -164,116 +426,309 @@ func (s *ChatService) SendMessage(chatID int64, role, content, model string) (*m
 	return message, nil
 }
 
-// SendMessageByUUID sends a message in a chat identified by UUID
-func (s *ChatService) SendMessageByUUID(uuid, role, content, model string) (*models.Message, error) {
-	// Validate chat exists and get ID
-	chat, err := s.repo.GetChatByUUID(uuid)
-	if err != nil {
+// GetChatMessages retrieves all messages for a user's chat
+func (s *ChatService) GetChatMessages(chatID int64, userID string) ([]models.Message, error) {
+	if _, err := s.ownedChat(chatID, userID); err != nil {
 		return nil, err
 	}
 
-	if role == "" {
-		role = "user"
-	}
-	if content == "" {
-		return nil, fmt.Errorf("content is required")
+	return s.messagesWithAttachments(chatID)
+}
+
+// GetChatMessagesByUUID retrieves all messages for a user's chat identified by UUID
+func (s *ChatService) GetChatMessagesByUUID(uuid, userID string) ([]models.Message, error) {
+	chat, err := s.ownedChatByUUID(uuid, userID)
+	if err != nil {
+		return nil, err
 	}
 
-	var modelPtr *string
-	if model != "" {
-		modelPtr = &model
+	return s.messagesWithAttachments(chat.ID)
+}
+
+// GetChatStats returns message and usage statistics for a chat owned by the user
+func (s *ChatService) GetChatStats(chatID int64, userID string) (*models.ChatStats, error) {
+	chat, err := s.repo.GetChatByID(chatID)
+	if err != nil {
+		return nil, err
+	}
+	if chat.UserID != userID {
+		return nil, ErrUnauthorized
 	}
 
-	message := &models.Message{
-		ChatID:  chat.ID,
-		Role:    role,
-		Content: content,
-		Model:   modelPtr,
+	stats := &models.ChatStats{ChatID: chatID, Models: make([]models.ModelUsageStat, 0)}
+	if s.usageService != nil {
+		if stats, err = s.usageService.GetChatUsage(userID, chatID); err != nil {
+			return nil, err
+		}
 	}
 
-	if err := s.repo.CreateMessage(message); err != nil {
+	if stats.TotalMessages, err = s.repo.CountMessagesByChatID(chatID); err != nil {
 		return nil, err
 	}
-
-	return message, nil
+	return stats, nil
 }
 
-// GetChatMessages retrieves all messages for a chat
-func (s *ChatService) GetChatMessages(chatID int64) ([]models.Message, error) {
-	// Validate chat exists
-	_, err := s.repo.GetChatByID(chatID)
+// StopGeneration cancels the running completion for a chat owned by the user
+func (s *ChatService) StopGeneration(chatID int64, userID string) error {
+	chat, err := s.repo.GetChatByID(chatID)
 	if err != nil {
-		return nil, err
+		return err
+	}
+	if chat.UserID != userID {
+		return ErrUnauthorized
 	}
 
-	return s.repo.GetMessagesByChatID(chatID)
-}
+	s.inflightMu.Lock()
+	defer s.inflightMu.Unlock()
 
-// GetChatMessagesByUUID retrieves all messages for a chat identified by UUID
-func (s *ChatService) GetChatMessagesByUUID(uuid string) ([]models.Message, error) {
-	// Validate chat exists and get ID
-	chat, err := s.repo.GetChatByUUID(uuid)
-	if err != nil {
-		return nil, err
+	gen, ok := s.inflight[chatID]
+	if !ok {
+		return ErrNoGeneration
 	}
+	gen.stopped = true
+	gen.cancel()
+	return nil
+}
+
+// beginGeneration registers a cancellable completion for a chat
+func (s *ChatService) beginGeneration(ctx context.Context, chatID int64) (context.Context, *inflightGeneration) {
+	ctx, cancel := context.WithCancel(ctx)
+	gen := &inflightGeneration{cancel: cancel}
+
+	s.inflightMu.Lock()
+	s.inflight[chatID] = gen
+	s.inflightMu.Unlock()
+
+	return ctx, gen
+}
+
+// endGeneration unregisters a completion and reports whether it was stopped
+func (s *ChatService) endGeneration(chatID int64, gen *inflightGeneration) bool {
+	s.inflightMu.Lock()
+	defer s.inflightMu.Unlock()
 
-	return s.repo.GetMessagesByChatID(chat.ID)
+	if s.inflight[chatID] == gen {
+		delete(s.inflight, chatID)
+	}
+	gen.cancel()
+	return gen.stopped
 }
 
 // CreateChatCompletion creates a new chat or adds to existing one and gets AI response
-func (s *ChatService) CreateChatCompletion(req *models.ChatCompletionRequest) (*models.ChatCompletionResponse, error) {
+func (s *ChatService) CreateChatCompletion(ctx context.Context, req *models.ChatCompletionRequest) (*models.ChatCompletionResponse, error) {
 	var chatID int64
 	var err error
+	var chat *models.Chat
+	contextStrategy := models.ContextStrategyTruncate
+
+	if req.TemplateID != 0 {
+		if err := s.applyTemplate(req); err != nil {
+			return nil, err
+		}
+	}
+	if req.Message == "" {
+		return nil, fmt.Errorf("%w: message is required", ErrInvalidMessage)
+	}
+
+	// Continuing a chat requires owning it
+	if req.ChatID != 0 {
+		if chat, err = s.ownedChat(req.ChatID, req.UserID); err != nil {
+			return nil, err
+		}
+	}
+
+	// User messages are screened before a chat is created or anything saved
+	var moderation *models.ModerationResult
+	if req.ToolCallID == "" {
+		if moderation, err = s.moderateMessage(ctx, req.UserID, req.ChatID, req.Message); err != nil {
+			return nil, err
+		}
+	}
+
+	// A spent "block" budget refuses the request before a chat is created
+	spending, err := s.checkSpendingBudget(ctx, req.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	var modelAlias string
+	var deprecation *models.ModelDeprecationNotice
+	req.Model, modelAlias, deprecation = s.resolveModelNotice(req.Model)
 
 	// Create new chat if chatID not provided
 	if req.ChatID == 0 {
 		userID := req.UserID
 		if userID == "" {
-			userID = "anonymous"
+			return nil, fmt.Errorf("%w: user_id is required", ErrInvalidMessage)
 		}
 		title := req.Title
 		if title == "" {
 			title = truncateText(req.Message, 50)
 		}
 
-		chat, err := s.CreateChat(userID, title)
+		personaOwnerID := req.PersonaOwnerID
+		if personaOwnerID == "" {
+			personaOwnerID = userID
+		}
+		chat, err = s.createChat(userID, title, "", req.PersonaID, personaOwnerID)
 		if err != nil {
+			if errors.Is(err, ErrPersonaNotFound) {
+				return nil, err
+			}
 			return nil, fmt.Errorf("failed to create chat: %w", err)
 		}
 		chatID = chat.ID
 	} else {
-		chatID = req.ChatID
+		chatID = chat.ID
+		if chat.ContextStrategy != "" {
+			contextStrategy = chat.ContextStrategy
+		}
+	}
+	if err := s.checkChatBudget(chat); err != nil {
+		return nil, err
+	}
+
+	// The chat's persona supplies the model when the request names none
+	persona := s.chatPersona(chat)
+	if persona != nil && req.Model == "" && persona.DefaultModel != "" {
+		req.Model, modelAlias, deprecation = s.resolveModelNotice(persona.DefaultModel)
+	}
+
+	// A spent "throttle" budget sends the request to the cheapest model,
+	// whichever provider it was pinned to
+	var throttledFrom string
+	if req.Model, throttledFrom = s.throttleModel(spending, req.Model); throttledFrom != "" {
+		req.Provider = ""
+	}
+
+	// A routed logical model goes to the user's preferred provider, or
+	// else its fastest healthy one
+	var route string
+	providerOrder := s.providerOrder(req.UserID)
+	req.Model, route, err = s.routeModel(req.Model, req.Provider, providerOrder)
+	if err != nil {
+		return nil, err
+	}
+
+	// Passages of the chat's attached documents relevant to the message go
+	// into the prompt, and so count towards its cost
+	var contextChunks []models.ContextChunk
+	var contextMessage map[string]interface{}
+	prompt := req.Message
+	if req.ToolCallID == "" && req.ChatID != 0 {
+		contextChunks, contextMessage = s.documentContext(ctx, chatID, req.UserID, req.Message)
+		if contextMessage != nil {
+			prompt = contextMessage["content"].(string) + "\n\n" + req.Message
+		}
 	}
 
-	// Save user message
-	_, err = s.SendMessage(chatID, "user", req.Message, req.Model)
+	// Turn the token and cost caps into max_tokens before anything is saved
+	// or sent, so a prompt that is already over budget is rejected cleanly
+	caps := resolveResponseCaps(req, chat)
+	var history []models.Message
+	if caps.MaxCostUSD > 0 && req.ChatID != 0 {
+		history, err = s.repo.GetMessagesByChatID(chatID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get chat history: %w", err)
+		}
+	}
+	outputLimit, err := s.outputTokenLimit(caps, req.Model, history, prompt)
+	if err != nil {
+		return nil, err
+	}
+
+	// Save user message (or a tool result answering an earlier tool call)
+	inbound := &models.MessageRequest{Role: "user", Content: req.Message, Model: req.Model}
+	if req.ToolCallID != "" {
+		inbound.Role = "tool"
+		inbound.ToolCallID = req.ToolCallID
+	} else {
+		inbound.Moderation = moderation
+	}
+	_, err = s.addMessage(chatID, inbound, false)
 	if err != nil {
 		return nil, fmt.Errorf("failed to save user message: %w", err)
 	}
 
 	// Get chat history for context
-	messages, err := s.repo.GetMessagesByChatID(chatID)
+	messages, err := s.messagesWithAttachments(chatID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get chat history: %w", err)
 	}
 
 	// Build messages array for AI service
-	aiMessages := make([]map[string]interface{}, 0, len(messages))
-	for _, msg := range messages {
-		aiMessages = append(aiMessages, map[string]interface{}{
-			"role":    msg.Role,
-			"content": msg.Content,
-		})
+	aiMessages := withPersonaPrompt(persona, s.buildAIMessages(messages))
+	if contextMessage != nil {
+		last := len(aiMessages) - 1
+		aiMessages = append(aiMessages[:last], contextMessage, aiMessages[last])
+	}
+
+	// Tool definitions are passed through to the provider
+	extra := make(map[string]interface{})
+	if len(req.Tools) > 0 {
+		extra["tools"] = req.Tools
+		if len(req.ToolChoice) > 0 {
+			extra["tool_choice"] = req.ToolChoice
+		}
+	}
+	if outputLimit > 0 {
+		extra["max_tokens"] = outputLimit
+	}
+	if persona != nil && persona.Temperature != nil {
+		extra["temperature"] = *persona.Temperature
+	}
+
+	// Register the generation so POST /chats/:id/stop can cancel it
+	genCtx, gen := s.beginGeneration(ctx, chatID)
+
+	// Fit history into the model's context window
+	// and bill any summary of the dropped turns like the completion itself
+	summaryTarget := completionTarget{UserID: req.UserID, ChatID: chatID, Model: req.Model, Endpoint: "/api/v1/chat/completions"}
+	aiMessages = s.fitContextWindow(genCtx, summaryTarget, contextStrategy, aiMessages, messages)
+
+	// Identical requests are answered from the cache without billing
+	var cacheKey string
+	var aiResponse *AIServiceResponse
+	cached := false
+	if s.cache != nil {
+		cacheKey = s.cache.Key(req.Model, aiMessages, extra)
+		aiResponse, cached = s.cache.Get(cacheKey)
 	}
 
-	// Call Python AI service for completion
-	aiResponse, err := s.callAIService(req.Model, aiMessages, req.UserID)
+	target := completionTarget{UserID: req.UserID, ChatID: chatID, Model: req.Model, Endpoint: "/api/v1/chat/completions", Route: route, ProviderOrder: providerOrder}
+	if !cached {
+		aiResponse, err = s.completeWithFallback(genCtx, target, aiMessages, extra)
+	}
+	stopped := s.endGeneration(chatID, gen)
+	if stopped {
+		if aiResponse != nil {
+			aiResponse.Content = s.postProcess(ctx, chat, persona, aiResponse.Content)
+		}
+		return s.saveStoppedCompletion(chatID, req.Model, aiResponse)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to get AI response: %w", err)
 	}
-
-	// Save AI response
-	aiMessage, err := s.SendMessage(chatID, "assistant", aiResponse.Content, req.Model)
+	if s.cache != nil && !cached {
+		s.cache.Put(cacheKey, aiResponse)
+	}
+	truncated := enforceOutputLimit(aiResponse, outputLimit)
+	content := s.postProcess(ctx, chat, persona, aiResponse.Content)
+
+	// Save AI response under the model that actually answered
+	usedModel := req.Model
+	var fallbackFrom string
+	if aiResponse.Model != req.Model {
+		usedModel, fallbackFrom = aiResponse.Model, req.Model
+	}
+	aiMessage, err := s.addMessage(chatID, &models.MessageRequest{
+		Role:          "assistant",
+		Content:       content,
+		Model:         usedModel,
+		ToolCalls:     aiResponse.ToolCalls,
+		Truncated:     truncated,
+		ContextChunks: contextChunks,
+	}, false)
 	if err != nil {
 		return nil, fmt.Errorf("failed to save AI message: %w", err)
 	}
@@ -283,24 +738,248 @@ func (s *ChatService) CreateChatCompletion(req *models.ChatCompletionRequest) (*
 		aiMessage.Tokens = aiResponse.Tokens
 	}
 
+	budget, err := s.chatBudget(chat)
+	if err != nil {
+		log.Printf("⚠️  Failed to get budget for chat %d: %v", chatID, err)
+	}
+	if spending != nil {
+		if spending, err = s.usageService.GetBudgetStatus(req.UserID); err != nil {
+			log.Printf("⚠️  Failed to get budget of %s: %v", req.UserID, err)
+		}
+	}
+
+	resp := &models.ChatCompletionResponse{
+		ChatID:        chatID,
+		MessageID:     aiMessage.ID,
+		Role:          aiMessage.Role,
+		Content:       aiMessage.Content,
+		Model:         aiMessage.Model,
+		ModelAlias:    modelAlias,
+		Route:         route,
+		Provider:      routedProvider(route, usedModel),
+		FallbackFrom:  fallbackFrom,
+		Tokens:        aiMessage.Tokens,
+		Truncated:     truncated,
+		Cached:        cached,
+		Deprecation:   deprecation,
+		Budget:        budget,
+		BudgetWarning: budgetWarning(budget),
+		ToolCalls:     aiMessage.ToolCalls,
+		ContextChunks: aiMessage.ContextChunks,
+		CreatedAt:     aiMessage.CreatedAt,
+	}
+	resp.SpendingBudget = spending
+	resp.ThrottledFrom = throttledFrom
+	return resp, nil
+}
+
+// applyTemplate renders req.TemplateID into req.Message
+func (s *ChatService) applyTemplate(req *models.ChatCompletionRequest) error {
+	if s.templates == nil {
+		return fmt.Errorf("%w: prompt templates are not enabled", ErrInvalidMessage)
+	}
+	rendered, err := s.templates.Render(req.TemplateID, req.UserID, req.Variables)
+	if err != nil {
+		return err
+	}
+	if req.Message != "" {
+		rendered += "\n\n" + req.Message
+	}
+	req.Message = rendered
+	return nil
+}
+
+// saveStoppedCompletion answers a completion the user stopped. Provider
+// calls aren't streamed, so there is no partial output: a stop before the
+// response arrived saves nothing and returns an empty stopped response
+// without a message ID. A response that arrived as it was stopped is kept,
+// flagged as stopped.
+func (s *ChatService) saveStoppedCompletion(chatID int64, model string, resp *AIServiceResponse) (*models.ChatCompletionResponse, error) {
+	if resp != nil && resp.Model != "" {
+		model = resp.Model
+	}
+	message := &models.Message{
+		ChatID:  chatID,
+		Role:    "assistant",
+		Stopped: true,
+	}
+	if model != "" {
+		message.Model = &model
+	}
+	if resp == nil || resp.Content == "" {
+		return &models.ChatCompletionResponse{
+			ChatID:    chatID,
+			Role:      message.Role,
+			Model:     message.Model,
+			Stopped:   true,
+			CreatedAt: time.Now(),
+		}, nil
+	}
+	message.Content = resp.Content
+	message.Tokens = resp.Tokens
+
+	if err := s.repo.CreateMessage(message); err != nil {
+		return nil, fmt.Errorf("failed to save stopped message: %w", err)
+	}
+
 	return &models.ChatCompletionResponse{
 		ChatID:    chatID,
-		MessageID: aiMessage.ID,
-		Role:      aiMessage.Role,
-		Content:   aiMessage.Content,
-		Model:     aiMessage.Model,
-		Tokens:    aiMessage.Tokens,
-		CreatedAt: aiMessage.CreatedAt,
+		MessageID: message.ID,
+		Role:      message.Role,
+		Content:   message.Content,
+		Model:     message.Model,
+		Tokens:    message.Tokens,
+		Stopped:   true,
+		CreatedAt: message.CreatedAt,
 	}, nil
 }
 
-// callAIService calls the Python AI service for chat completion
-func (s *ChatService) callAIService(model string, messages []map[string]interface{}, userID string) (*AIServiceResponse, error) {
-	// Get AI service URL from environment
-	aiServiceURL := os.Getenv("AI_SERVICE_URL")
-	if aiServiceURL == "" {
-		aiServiceURL = "http://localhost:8000"
+// buildAIMessages converts stored chat history into the AI service format
+func (s *ChatService) buildAIMessages(messages []models.Message) []map[string]interface{} {
+	aiMessages := make([]map[string]interface{}, 0, len(messages))
+	for _, msg := range messages {
+		aiMessage := map[string]interface{}{
+			"role":    msg.Role,
+			"content": msg.Content,
+		}
+		if len(msg.ToolCalls) > 0 {
+			aiMessage["tool_calls"] = msg.ToolCalls
+		}
+		if msg.ToolCallID != nil {
+			aiMessage["tool_call_id"] = *msg.ToolCallID
+		}
+		if len(msg.Attachments) > 0 {
+			aiMessage["content"] = s.contentWithAttachments(msg)
+		}
+		aiMessages = append(aiMessages, aiMessage)
+	}
+	return aiMessages
+}
+
+// completionTarget identifies who and what a completion is billed to
+type completionTarget struct {
+	UserID   string
+	ChatID   int64
+	Model    string
+	Endpoint string
+	Route    string // Routed logical model Model was chosen for, if any
+	// Provider preference for the route's other candidates
+	ProviderOrder []string
+}
+
+// reservedOutputTokens is the response length reserved against the quota
+// for requests without max_tokens
+const reservedOutputTokens = 1024
+
+// completeWithFailover calls the AI service, failing over to the
+// platform-managed key when the user's own key is rejected. Quota for the
+// estimated usage is reserved first, and every attempt is tracked against
+// the user's quota.
+func (s *ChatService) completeWithFailover(ctx context.Context, target completionTarget, messages []map[string]interface{}, extra map[string]interface{}) (*AIServiceResponse, error) {
+	reservation, err := s.reserveCompletion(ctx, target, messages, extra)
+	if err != nil {
+		return nil, err
+	}
+
+	provider := ProviderForModel(target.Model)
+	keySource := KeySourceUser
+	start := time.Now()
+	resp, err := s.callAIService(ctx, target.Model, messages, target.UserID, "", extra)
+	if err != nil && shouldFailover(err) {
+		if platformKey := s.platformKey(provider); platformKey != "" {
+			s.trackCompletion(ctx, target, provider, keySource, nil, time.Since(start), err, 0)
+			log.Printf("⚠️  User key for %s failed, retrying with platform key (user=%s)", provider, target.UserID)
+
+			keySource = KeySourcePlatform
+			start = time.Now()
+			resp, err = s.callAIService(ctx, target.Model, messages, target.UserID, platformKey, extra)
+		}
+	}
+	duration := time.Since(start)
+	s.trackCompletion(ctx, target, provider, keySource, resp, duration, err, reservation)
+	s.observeModel(ctx, target.Model, duration, err)
+
+	if resp != nil {
+		resp.KeySource = keySource
+		resp.Duration = duration
+	}
+	return resp, err
+}
+
+// reserveCompletion reserves the user's quota for a completion's prompt
+// and its max_tokens, so concurrent requests cannot together overrun the
+// limits. It returns 0 when usage is not tracked.
+func (s *ChatService) reserveCompletion(ctx context.Context, target completionTarget, messages []map[string]interface{}, extra map[string]interface{}) (int64, error) {
+	if s.usageService == nil || target.UserID == "" || usageExempt(ctx) {
+		return 0, nil
+	}
+	if _, err := s.usageService.CheckBudget(target.UserID); err != nil {
+		return 0, err
+	}
+
+	outputTokens := reservedOutputTokens
+	if limit, ok := extra["max_tokens"].(int); ok && limit > 0 {
+		outputTokens = limit
+	}
+	promptTokens := 0
+	for _, msg := range messages {
+		promptTokens += messageTokens(target.Model, msg)
+	}
+
+	costModel := target.Model
+	if costModel == "" {
+		costModel = "default"
+	}
+	cost, err := s.usageService.CalculateCost(promptTokens, outputTokens, costModel)
+	if err != nil {
+		return 0, err
+	}
+	return s.usageService.ReserveQuota(target.UserID, promptTokens+outputTokens, cost)
+}
+
+// trackCompletion records a completion attempt against the user's quota,
+// settling or releasing reservation when it is set. Exempt traffic is not
+// recorded.
+func (s *ChatService) trackCompletion(ctx context.Context, target completionTarget, provider, keySource string, resp *AIServiceResponse, duration time.Duration, callErr error, reservation int64) {
+	if s.usageService == nil || target.UserID == "" || usageExempt(ctx) {
+		return
+	}
+
+	usageReq := &models.UsageRequest{
+		UserID:      target.UserID,
+		RequestType: "chat",
+		ResourceID:  target.ChatID,
+		ModelUsed:   target.Model,
+		Endpoint:    target.Endpoint,
+		Provider:    provider,
+		KeySource:   keySource,
+		DurationMs:  duration.Milliseconds(),
+		Success:     callErr == nil,
+
+		ReservationID: reservation,
+	}
+	if usageReq.ModelUsed == "" {
+		usageReq.ModelUsed = "default"
+	}
+	if resp != nil {
+		usageReq.TokensInput = resp.PromptTokens
+		usageReq.TokensOutput = resp.CompletionTokens
+	}
+	if callErr != nil {
+		usageReq.ErrorMessage = callErr.Error()
+	}
+
+	if err := s.usageService.TrackUsage(usageReq); err != nil {
+		log.Printf("Failed to track completion usage: %v", err)
+		s.usageService.RetryUsage(usageReq, err)
 	}
+}
+
+// callAIService calls the Python AI service for chat completion.
+// When apiKey is set, the backend uses it instead of the user's synced key.
+// Entries in extra (e.g. tools) are added to the request payload.
+func (s *ChatService) callAIService(ctx context.Context, model string, messages []map[string]interface{}, userID, apiKey string, extra map[string]interface{}) (*AIServiceResponse, error) {
+	aiServiceURL := s.aiService()
 
 	// Prepare request payload
 	payload := map[string]interface{}{
@@ -308,18 +987,28 @@ func (s *ChatService) callAIService(model string, messages []map[string]interfac
 		"messages": messages,
 		"user_id":  userID,
 	}
+	for k, v := range extra {
+		payload[k] = v
+	}
+	if apiKey != "" {
+		payload["api_key"] = apiKey
+		payload["key_source"] = KeySourcePlatform
+	}
 
 	payloadBytes, err := json.Marshal(payload)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal payload: %w", err)
 	}
 
-	// Make HTTP request to AI service
-	resp, err := http.Post(
-		aiServiceURL+"/api/v1/chat/completions",
-		"application/json",
-		bytes.NewBuffer(payloadBytes),
-	)
+	// Make HTTP request to AI service (cancelled along with ctx)
+	MarkUpstream(ctx, aiServiceURL+"/api/v1/chat/completions", model)
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, aiServiceURL+"/api/v1/chat/completions", bytes.NewBuffer(payloadBytes))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create AI request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(httpReq)
 	if err != nil {
 		return nil, fmt.Errorf("failed to call AI service: %w", err)
 	}
@@ -335,12 +1024,16 @@ func (s *ChatService) callAIService(model string, messages []map[string]interfac
 	var result struct {
 		Choices []struct {
 			Message struct {
-				Role    string `json:"role"`
-				Content string `json:"content"`
+				Role      string          `json:"role"`
+				Content   string          `json:"content"`
+				ToolCalls json.RawMessage `json:"tool_calls"`
 			} `json:"message"`
+			FinishReason string `json:"finish_reason"`
 		} `json:"choices"`
 		Usage struct {
-			TotalTokens int `json:"total_tokens"`
+			PromptTokens     int `json:"prompt_tokens"`
+			CompletionTokens int `json:"completion_tokens"`
+			TotalTokens      int `json:"total_tokens"`
 		} `json:"usage"`
 	}
 
@@ -354,15 +1047,34 @@ func (s *ChatService) callAIService(model string, messages []map[string]interfac
 	}
 
 	return &AIServiceResponse{
-		Content: result.Choices[0].Message.Content,
-		Tokens:  result.Usage.TotalTokens,
+		Content:          result.Choices[0].Message.Content,
+		ToolCalls:        nonNullJSON(result.Choices[0].Message.ToolCalls),
+		FinishReason:     result.Choices[0].FinishReason,
+		Tokens:           result.Usage.TotalTokens,
+		PromptTokens:     result.Usage.PromptTokens,
+		CompletionTokens: result.Usage.CompletionTokens,
 	}, nil
 }
 
 // AIServiceResponse represents the response from AI service
 type AIServiceResponse struct {
-	Content string
-	Tokens  int
+	Content          string
+	ToolCalls        json.RawMessage
+	FinishReason     string
+	Tokens           int
+	PromptTokens     int
+	CompletionTokens int
+	Model            string        // Set by completeWithFallback
+	KeySource        string        // Set by completeWithFailover
+	Duration         time.Duration // Set by completeWithFailover
+}
+
+// nonNullJSON drops an explicit JSON null so it isn't stored as tool calls
+func nonNullJSON(raw json.RawMessage) json.RawMessage {
+	if string(raw) == "null" {
+		return nil
+	}
+	return raw
 }
 
 // AIServiceError captures non-200 responses from the Python AI service.
@@ -0,0 +1,69 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"lio-ai/internal/models"
+	"lio-ai/internal/repositories"
+)
+
+func TestStoppedCompletionWithoutOutputSavesNoMessage(t *testing.T) {
+	// The AI service never answers
+	hang := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-hang:
+		}
+	}))
+	t.Cleanup(srv.Close)
+	t.Cleanup(func() { close(hang) })
+
+	conn := newTestDB(t)
+	user := createTestUser(t, conn, "stopper", "user")
+	userID := fmt.Sprint(user.ID)
+	s := NewChatService(repositories.NewChatRepository(conn), nil)
+	s.SetAIServiceURL(srv.URL)
+	chat, err := s.CreateChat(userID, "stopped chat", "", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan *models.ChatCompletionResponse, 1)
+	go func() {
+		resp, err := s.CreateChatCompletion(context.Background(), &models.ChatCompletionRequest{ChatID: chat.ID, UserID: userID, Message: "hello", Model: "gpt-4"})
+		if err != nil {
+			t.Errorf("CreateChatCompletion: %v", err)
+		}
+		done <- resp
+	}()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		err := s.StopGeneration(chat.ID, userID)
+		if err == nil {
+			break
+		}
+		if !errors.Is(err, ErrNoGeneration) || time.Now().After(deadline) {
+			t.Fatalf("StopGeneration: %v", err)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	resp := <-done
+	if resp == nil || !resp.Stopped || resp.MessageID != 0 || resp.Content != "" {
+		t.Fatalf("response = %+v, want an empty stopped response without a message", resp)
+	}
+	var assistant int
+	if err := conn.QueryRow("SELECT COUNT(*) FROM messages WHERE chat_id = ? AND role = 'assistant'", chat.ID).Scan(&assistant); err != nil {
+		t.Fatal(err)
+	}
+	if assistant != 0 {
+		t.Errorf("%d assistant messages saved for the stopped completion, want none", assistant)
+	}
+}
@@ -0,0 +1,173 @@
+package services
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"lio-ai/internal/models"
+	"lio-ai/internal/repositories"
+	"lio-ai/internal/storage"
+)
+
+// CleanupService periodically deletes what has outlived its use: expired
+// invitation tokens, passkey challenges and quota holds, the quotas of
+// expired guest sessions, past trial counters, finished jobs, and stored
+// objects no row refers to any more
+type CleanupService struct {
+	repo  *repositories.CleanupRepository
+	store storage.Store // Optional; orphaned objects are not looked for when unset
+
+	// Finished jobs are kept this long; 0 keeps them
+	jobRetention time.Duration
+	// Guest IDs start with guestIDPrefix and their session cookies are
+	// valid for guestSessionTTL; 0 leaves guest quotas alone
+	guestIDPrefix   string
+	guestSessionTTL time.Duration
+	// Objects younger than this are never orphans: their row may not be
+	// written yet
+	orphanGrace time.Duration
+
+	run   sync.Mutex // One pass at a time
+	mu    sync.Mutex
+	stats models.CleanupStats
+}
+
+// NewCleanupService creates a new cleanup service
+func NewCleanupService(repo *repositories.CleanupRepository, jobRetention, orphanGrace time.Duration) *CleanupService {
+	return &CleanupService{repo: repo, jobRetention: jobRetention, orphanGrace: orphanGrace}
+}
+
+// SetGuestSessions deletes the quotas of guests, whose IDs start with
+// prefix, once their sessions of ttl have expired
+func (s *CleanupService) SetGuestSessions(prefix string, ttl time.Duration) {
+	s.guestIDPrefix, s.guestSessionTTL = prefix, ttl
+}
+
+// SetStore looks for orphaned objects in store, where uploads,
+// attachments and snapshots are kept
+func (s *CleanupService) SetStore(store storage.Store) {
+	s.store = store
+}
+
+// Start runs a pass on an interval until the process exits; a zero
+// interval disables periodic cleanup
+func (s *CleanupService) Start(interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			report, err := s.Run()
+			if err != nil {
+				log.Printf("Failed to clean up expired data: %v", err)
+			} else if report.RowsDeleted() > 0 || report.BlobsDeleted > 0 {
+				log.Printf("🧹 Cleanup: deleted %d rows and %d stored objects (%d bytes)",
+					report.RowsDeleted(), report.BlobsDeleted, report.BytesReclaimed)
+			}
+			<-ticker.C
+		}
+	}()
+}
+
+// Run does one cleanup pass now and adds it to the stats
+func (s *CleanupService) Run() (*models.CleanupReport, error) {
+	s.run.Lock()
+	defer s.run.Unlock()
+
+	report, err := s.clean()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stats.Runs++
+	s.stats.RowsDeleted += report.RowsDeleted()
+	s.stats.BlobsDeleted += report.BlobsDeleted
+	s.stats.BytesReclaimed += report.BytesReclaimed
+	s.stats.LastRun = report
+	s.stats.LastError = ""
+	if err != nil {
+		s.stats.LastError = err.Error()
+		return nil, err
+	}
+	return report, nil
+}
+
+// Stats sums the passes since the server started
+func (s *CleanupService) Stats() models.CleanupStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.stats
+}
+
+// clean deletes each kind of garbage in turn. The report counts what was
+// deleted before any failure.
+func (s *CleanupService) clean() (*models.CleanupReport, error) {
+	now := time.Now()
+	report := &models.CleanupReport{StartedAt: now.UTC()}
+	defer func() { report.CompletedAt = time.Now().UTC() }()
+
+	var err error
+	if report.InvitationsDeleted, err = s.repo.DeleteExpiredInvitations(now); err != nil {
+		return report, err
+	}
+	if report.ChallengesDeleted, err = s.repo.DeleteExpiredChallenges(now); err != nil {
+		return report, err
+	}
+	if report.RevokedTokensDeleted, err = s.repo.DeleteExpiredRevokedTokens(now); err != nil {
+		return report, err
+	}
+	if report.SessionsDeleted, err = s.repo.DeleteExpiredSessions(now); err != nil {
+		return report, err
+	}
+	if report.ReservationsDeleted, err = s.repo.DeleteExpiredReservations(now); err != nil {
+		return report, err
+	}
+	if s.guestIDPrefix != "" && s.guestSessionTTL > 0 {
+		if report.GuestSessionsDeleted, err = s.repo.DeleteGuestQuotasBefore(s.guestIDPrefix, now.Add(-s.guestSessionTTL)); err != nil {
+			return report, err
+		}
+	}
+	// Trial limits are per UTC day; yesterday's row is kept for support
+	if report.TrialUsageDeleted, err = s.repo.DeleteTrialUsageBefore(now.UTC().AddDate(0, 0, -1).Format("2006-01-02")); err != nil {
+		return report, err
+	}
+	if s.jobRetention > 0 {
+		if report.JobsDeleted, err = s.repo.DeleteFinishedJobsBefore(now.Add(-s.jobRetention)); err != nil {
+			return report, err
+		}
+	}
+	err = s.deleteOrphans(report, now)
+	return report, err
+}
+
+// deleteOrphans deletes stored objects older than the grace period that
+// no document, attachment or snapshot refers to
+func (s *CleanupService) deleteOrphans(report *models.CleanupReport, now time.Time) error {
+	if s.store == nil {
+		return nil
+	}
+	lister, ok := s.store.(storage.Lister)
+	if !ok {
+		report.BlobScanSkipped = true
+		return nil
+	}
+
+	keys, err := s.repo.StorageKeys()
+	if err != nil {
+		return err
+	}
+	cutoff := now.Add(-s.orphanGrace)
+	return lister.List(func(key string, size int64, modified time.Time) error {
+		if keys[key] || modified.After(cutoff) {
+			return nil
+		}
+		if err := s.store.Delete(key); err != nil {
+			log.Printf("⚠️  Failed to delete orphaned object %s: %v", key, err)
+			return nil
+		}
+		report.BlobsDeleted++
+		report.BytesReclaimed += size
+		return nil
+	})
+}
@@ -0,0 +1,106 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+
+	"lio-ai/internal/models"
+	"lio-ai/internal/repositories"
+)
+
+var (
+	// ErrCollectionDepth is returned when nesting a collection inside one
+	// that is itself nested
+	ErrCollectionDepth = errors.New("collections can only be nested one level deep")
+	// ErrCollectionNotEmpty is returned when deleting a collection that
+	// still has collections nested in it
+	ErrCollectionNotEmpty = errors.New("collection has nested collections")
+)
+
+// CollectionService handles document collection business logic
+type CollectionService struct {
+	repo *repositories.CollectionRepository
+}
+
+// NewCollectionService creates a new collection service
+func NewCollectionService(repo *repositories.CollectionRepository) *CollectionService {
+	return &CollectionService{repo: repo}
+}
+
+// CreateCollection creates a collection for userID, inside parent_id when
+// set
+func (s *CollectionService) CreateCollection(userID string, req *models.CreateCollectionRequest) (*models.Collection, error) {
+	if req.ParentID != nil {
+		parent, err := ownedCollection(s.repo, *req.ParentID, userID)
+		if err != nil {
+			return nil, err
+		}
+		if parent.ParentID != nil {
+			return nil, ErrCollectionDepth
+		}
+	}
+
+	collection := &models.Collection{UserID: userID, Name: req.Name, ParentID: req.ParentID}
+	if err := s.repo.Create(collection); err != nil {
+		return nil, fmt.Errorf("service error: %w", err)
+	}
+	return collection, nil
+}
+
+// ListCollections returns userID's collections
+func (s *CollectionService) ListCollections(userID string) ([]models.Collection, error) {
+	return s.repo.ListByUser(userID)
+}
+
+// GetCollection retrieves a collection owned by userID
+func (s *CollectionService) GetCollection(id int64, userID string) (*models.Collection, error) {
+	return ownedCollection(s.repo, id, userID)
+}
+
+// RenameCollection renames a collection owned by userID
+func (s *CollectionService) RenameCollection(id int64, userID, name string) (*models.Collection, error) {
+	if _, err := ownedCollection(s.repo, id, userID); err != nil {
+		return nil, err
+	}
+	if err := s.repo.Rename(id, userID, name); err != nil {
+		return nil, fmt.Errorf("service error: %w", err)
+	}
+	return ownedCollection(s.repo, id, userID)
+}
+
+// DeleteCollection deletes a collection owned by userID. Its documents move
+// up into the parent collection, or to the top level; a collection with
+// nested collections must be emptied first.
+func (s *CollectionService) DeleteCollection(id int64, userID string) error {
+	collection, err := ownedCollection(s.repo, id, userID)
+	if err != nil {
+		return err
+	}
+	children, err := s.repo.CountChildren(id)
+	if err != nil {
+		return fmt.Errorf("service error: %w", err)
+	}
+	if children > 0 {
+		return ErrCollectionNotEmpty
+	}
+	if err := s.repo.Delete(id, userID, collection.ParentID); err != nil {
+		return fmt.Errorf("service error: %w", err)
+	}
+	return nil
+}
+
+// ownedCollection loads a collection, returning ErrNotFound when there is
+// none and ErrUnauthorized when it belongs to another user
+func ownedCollection(repo *repositories.CollectionRepository, id int64, userID string) (*models.Collection, error) {
+	collection, err := repo.GetByID(id)
+	if err != nil {
+		return nil, fmt.Errorf("service error: %w", err)
+	}
+	if collection == nil {
+		return nil, fmt.Errorf("%w: collection %d", ErrNotFound, id)
+	}
+	if collection.UserID != userID {
+		return nil, ErrUnauthorized
+	}
+	return collection, nil
+}
@@ -0,0 +1,120 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+	"lio-ai/internal/models"
+)
+
+// CompareModels sends the same prompt to 2–4 models concurrently and stores
+// each answer as a separate assistant message tagged with its model
+func (s *ChatService) CompareModels(ctx context.Context, chatID int64, userID string, req *models.CompareRequest) (*models.CompareResponse, error) {
+	if len(req.Models) < 2 || len(req.Models) > 4 {
+		return nil, fmt.Errorf("%w: compare requires 2 to 4 models", ErrInvalidMessage)
+	}
+	// Aliases are resolved first so two names for one model are rejected
+	seen := make(map[string]bool, len(req.Models))
+	for i, model := range req.Models {
+		model, _ = s.resolveModel(model)
+		req.Models[i] = model
+		if model == "" || seen[model] {
+			return nil, fmt.Errorf("%w: models must be distinct and non-empty", ErrInvalidMessage)
+		}
+		seen[model] = true
+	}
+
+	chat, err := s.repo.GetChatByID(chatID)
+	if err != nil {
+		return nil, err
+	}
+	if chat.UserID != userID {
+		return nil, ErrUnauthorized
+	}
+
+	moderation, err := s.moderateMessage(ctx, userID, chatID, req.Message)
+	if err != nil {
+		return nil, err
+	}
+
+	userMessage, err := s.addMessage(chatID, &models.MessageRequest{Role: "user", Content: req.Message, Moderation: moderation}, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save user message: %w", err)
+	}
+
+	history, err := s.messagesWithAttachments(chatID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get chat history: %w", err)
+	}
+	persona := s.chatPersona(chat)
+	aiMessages := withPersonaPrompt(persona, s.buildAIMessages(history))
+
+	// One model failing must not cancel the others, so errors are recorded
+	// per result instead of being returned to the group
+	results := make([]models.ModelComparison, len(req.Models))
+	var g errgroup.Group
+	for i, model := range req.Models {
+		g.Go(func() error {
+			results[i] = s.compareOne(ctx, chat, model, aiMessages, history)
+			return nil
+		})
+	}
+	_ = g.Wait()
+
+	// Store answers in request order so history reads predictably
+	for i := range results {
+		if results[i].Error != "" {
+			continue
+		}
+		results[i].Content = s.postProcess(ctx, chat, persona, results[i].Content)
+		msg, err := s.addMessage(chatID, &models.MessageRequest{
+			Role:    "assistant",
+			Content: results[i].Content,
+			Model:   results[i].Model,
+		}, false)
+		if err != nil {
+			results[i].Error = fmt.Sprintf("failed to save response: %v", err)
+			continue
+		}
+		results[i].MessageID = msg.ID
+	}
+
+	return &models.CompareResponse{
+		ChatID:        chatID,
+		UserMessageID: userMessage.ID,
+		Results:       results,
+	}, nil
+}
+
+// compareOne runs a single model of a comparison over aiMessages, the
+// prompt built from history
+func (s *ChatService) compareOne(ctx context.Context, chat *models.Chat, model string, aiMessages []map[string]interface{}, history []models.Message) models.ModelComparison {
+	result := models.ModelComparison{Model: model}
+
+	target := completionTarget{UserID: chat.UserID, ChatID: chat.ID, Model: model, Endpoint: "/api/v1/chats/:id/compare"}
+	messages := s.fitContextWindow(ctx, target, chat.ContextStrategy, aiMessages, history)
+
+	start := time.Now()
+	resp, err := s.completeWithFailover(ctx, target, messages, nil)
+	result.LatencyMs = time.Since(start).Milliseconds()
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	result.Content = resp.Content
+	result.LatencyMs = resp.Duration.Milliseconds()
+	result.TokensInput = resp.PromptTokens
+	result.TokensOutput = resp.CompletionTokens
+	if s.usageService != nil {
+		if cost, err := s.usageService.CalculateCost(resp.PromptTokens, resp.CompletionTokens, model); err == nil {
+			if resp.KeySource == KeySourcePlatform {
+				cost *= s.usageService.platformKeyMarkup()
+			}
+			result.CostUSD = cost
+		}
+	}
+	return result
+}
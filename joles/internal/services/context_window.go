@@ -0,0 +1,269 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"unicode/utf8"
+
+	"lio-ai/internal/models"
+	"lio-ai/internal/tokenizer"
+)
+
+// defaultContextLimit is used for models not listed in modelContextLimits
+const defaultContextLimit = 8192
+
+// modelContextLimits maps model name prefixes to context window sizes (tokens).
+// Longer prefixes are matched first, so "gpt-4o" wins over "gpt-4".
+var modelContextLimits = []struct {
+	prefix string
+	limit  int
+}{
+	{"gpt-4o", 128000},
+	{"gpt-4-turbo", 128000},
+	{"gpt-4-32k", 32768},
+	{"gpt-4", 8192},
+	{"gpt-3.5-turbo", 16385},
+	{"o1", 128000},
+	{"claude", 200000},
+	{"gemini-1.5", 1000000},
+	{"gemini", 32768},
+	{"command-r", 128000},
+	{"command", 4096},
+}
+
+// ModelContextLimit returns the context window size for a model
+func ModelContextLimit(model string) int {
+	name := strings.ToLower(model)
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		name = name[i+1:]
+	}
+	for _, m := range modelContextLimits {
+		if strings.HasPrefix(name, m.prefix) {
+			return m.limit
+		}
+	}
+	return defaultContextLimit
+}
+
+// messageTokens counts the tokens a single chat message adds to a prompt
+// for model
+func messageTokens(model string, msg map[string]interface{}) int {
+	role, _ := msg["role"].(string)
+	content, _ := msg["content"].(string)
+	tokens := tokenizer.CountMessage(model, tokenizer.Message{Role: role, Content: content})
+	if calls, ok := msg["tool_calls"].(json.RawMessage); ok {
+		tokens += tokenizer.Count(model, string(calls))
+	}
+	return tokens
+}
+
+// contextBudget returns the prompt token budget for a model, leaving room
+// for the completion
+func contextBudget(model string) int {
+	limit := ModelContextLimit(model)
+	reserve := limit / 4
+	if reserve > 4096 {
+		reserve = 4096
+	}
+	return limit - reserve
+}
+
+// ValidContextStrategy reports whether strategy is a supported context strategy
+func ValidContextStrategy(strategy string) bool {
+	return strategy == models.ContextStrategyTruncate || strategy == models.ContextStrategySummarize
+}
+
+// fitContextWindow trims chat history to the model's context budget.
+// System messages and the latest message are always kept; the oldest turns
+// are dropped, or folded into a synthetic system message when the strategy
+// is "summarize". history is the chat's messages messages were built from;
+// summaries of its dropped turns are cached on the chat by the seq of the
+// last message they cover, so they are billed to target once rather than
+// on every completion.
+func (s *ChatService) fitContextWindow(ctx context.Context, target completionTarget, strategy string, messages []map[string]interface{}, history []models.Message) []map[string]interface{} {
+	model := target.Model
+	budget := contextBudget(model)
+	if strategy == models.ContextStrategySummarize {
+		// Leave room for the summary itself
+		budget -= budget / 8
+	}
+
+	var system, turns []map[string]interface{}
+	used := 0
+	for _, msg := range messages {
+		if msg["role"] == "system" {
+			system = append(system, msg)
+			used += messageTokens(model, msg)
+		} else {
+			turns = append(turns, msg)
+		}
+	}
+
+	keepFrom := keepTurnsFrom(model, turns, budget-used)
+	if keepFrom == 0 {
+		return messages
+	}
+
+	var summary string
+	if strategy == models.ContextStrategySummarize {
+		var err error
+		summary, keepFrom, err = s.summarizeDropped(ctx, target, turns, turnSeqs(history, len(turns)), keepFrom, budget-used)
+		if err != nil {
+			log.Printf("⚠️  Context summarization failed, truncating instead: %v", err)
+		}
+	}
+	dropped := turns[:keepFrom]
+
+	fitted := make([]map[string]interface{}, 0, len(system)+len(turns)-keepFrom+1)
+	fitted = append(fitted, system...)
+	if summary != "" {
+		fitted = append(fitted, map[string]interface{}{
+			"role":    "system",
+			"content": "Summary of the earlier conversation: " + summary,
+		})
+	}
+	fitted = append(fitted, turns[keepFrom:]...)
+
+	log.Printf("✓ Context window: dropped %d of %d turns for %s (strategy=%s)", len(dropped), len(turns), model, strategy)
+	return fitted
+}
+
+// keepTurnsFrom returns the index of the oldest turn kept when the newest
+// turns are fitted into budget tokens. The latest turn is always kept.
+func keepTurnsFrom(model string, turns []map[string]interface{}, budget int) int {
+	// Walk back from the newest turn until the budget is spent
+	keepFrom := len(turns)
+	used := 0
+	for i := len(turns) - 1; i >= 0; i-- {
+		cost := messageTokens(model, turns[i])
+		if used+cost > budget && i < len(turns)-1 {
+			break
+		}
+		used += cost
+		keepFrom = i
+	}
+
+	// Tool results can't be sent without the assistant message that called them
+	for keepFrom < len(turns)-1 && turns[keepFrom]["role"] == "tool" {
+		keepFrom++
+	}
+	return keepFrom
+}
+
+// turnSeqs lists the seq of each of the n non-system messages of history,
+// which are the turns of the prompt built from it, or nil when they don't
+// line up
+func turnSeqs(history []models.Message, n int) []int64 {
+	seqs := make([]int64, 0, n)
+	for _, msg := range history {
+		if msg.Role != "system" {
+			seqs = append(seqs, msg.Seq)
+		}
+	}
+	if len(seqs) != n {
+		return nil
+	}
+	return seqs
+}
+
+// summarizeDropped returns the summary standing in for turns before
+// keepFrom and the index of the first turn kept after it. A cached summary
+// reaching at least as far is reused, and the turns it covers dropped.
+// Otherwise the turns past the cached summary are folded into it, dropping
+// enough to leave half of budget free so the next completions can reuse
+// the result. Without seqs the summary is not cached.
+func (s *ChatService) summarizeDropped(ctx context.Context, target completionTarget, turns []map[string]interface{}, seqs []int64, keepFrom, budget int) (string, int, error) {
+	if seqs == nil || target.ChatID == 0 {
+		summary, err := s.summarizeTurns(ctx, target, turns[:keepFrom])
+		return summary, keepFrom, err
+	}
+
+	cached, throughSeq, err := s.repo.GetContextSummary(target.ChatID)
+	if err != nil {
+		return "", keepFrom, err
+	}
+	covered := 0 // Turns the cached summary covers
+	if cached != "" {
+		for i, seq := range seqs {
+			if seq == throughSeq {
+				covered = i + 1
+				break
+			}
+		}
+	}
+	if covered >= keepFrom && covered < len(turns) && turns[covered]["role"] != "tool" {
+		return cached, covered, nil
+	}
+
+	cut := keepTurnsFrom(target.Model, turns, budget/2)
+	if cut < keepFrom {
+		cut = keepFrom
+	}
+	since := turns[covered:cut]
+	if covered > 0 {
+		since = append([]map[string]interface{}{{
+			"role":    "system",
+			"content": "Summary of the earlier conversation: " + cached,
+		}}, since...)
+	}
+	summary, err := s.summarizeTurns(ctx, target, since)
+	if err != nil {
+		return "", keepFrom, err
+	}
+	if err := s.repo.SaveContextSummary(target.ChatID, summary, seqs[cut-1]); err != nil {
+		log.Printf("⚠️  Failed to cache context summary of chat %d: %v", target.ChatID, err)
+	}
+	return summary, cut, nil
+}
+
+// summarizeTurns asks the AI service to condense dropped turns into a short
+// summary, billed to target like any other completion
+func (s *ChatService) summarizeTurns(ctx context.Context, target completionTarget, turns []map[string]interface{}) (string, error) {
+	resp, err := s.completeWithFailover(ctx, target, summaryPrompt(target.Model, turns), nil)
+	if err != nil {
+		return "", err
+	}
+	if strings.TrimSpace(resp.Content) == "" {
+		return "", fmt.Errorf("empty summary")
+	}
+	return resp.Content, nil
+}
+
+// summaryPrompt builds the request asking model to summarize turns
+func summaryPrompt(model string, turns []map[string]interface{}) []map[string]interface{} {
+	return transcriptPrompt(model, "Summarize the following conversation in a few sentences. Preserve names, facts, decisions and open questions.", turns)
+}
+
+// transcriptPrompt builds a request applying instruction to a transcript
+// of turns, trimmed from the start to fit model's context window
+func transcriptPrompt(model, instruction string, turns []map[string]interface{}) []map[string]interface{} {
+	var transcript strings.Builder
+	for _, msg := range turns {
+		content, _ := msg["content"].(string)
+		fmt.Fprintf(&transcript, "%s: %s\n", msg["role"], content)
+	}
+
+	// Keep the summarization prompt itself within budget
+	text := transcript.String()
+	if maxChars := contextBudget(model) * 3; len(text) > maxChars {
+		cut := len(text) - maxChars
+		for cut < len(text) && !utf8.RuneStart(text[cut]) {
+			cut++
+		}
+		text = text[cut:]
+	}
+
+	return []map[string]interface{}{
+		{
+			"role":    "system",
+			"content": instruction,
+		},
+		{
+			"role":    "user",
+			"content": text,
+		},
+	}
+}
@@ -0,0 +1,119 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"unicode/utf8"
+
+	"lio-ai/internal/models"
+	"lio-ai/internal/repositories"
+)
+
+// newFakeAIService points s at an AI service that answers every completion
+// with reply, and counts the calls
+func newFakeAIService(t *testing.T, s *ChatService, reply string) *atomic.Int32 {
+	t.Helper()
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"choices": []map[string]interface{}{{"message": map[string]string{"role": "assistant", "content": reply}, "finish_reason": "stop"}},
+			"usage":   map[string]int{"prompt_tokens": 100, "completion_tokens": 20, "total_tokens": 120},
+		})
+	}))
+	t.Cleanup(srv.Close)
+	s.SetAIServiceURL(srv.URL)
+	return &calls
+}
+
+// fitSummarizedChat fits the history of chatID into the window of the
+// "command" model, whose 4k context the test turns overflow
+func fitSummarizedChat(t *testing.T, s *ChatService, chat *models.Chat) []map[string]interface{} {
+	t.Helper()
+	history, err := s.messagesWithAttachments(chat.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	target := completionTarget{UserID: chat.UserID, ChatID: chat.ID, Model: "command", Endpoint: "/api/v1/chat/completions"}
+	return s.fitContextWindow(context.Background(), target, chat.ContextStrategy, s.buildAIMessages(history), history)
+}
+
+func addTestTurns(t *testing.T, s *ChatService, chatID int64, from, to int) {
+	t.Helper()
+	for i := from; i < to; i++ {
+		role := "user"
+		if i%2 == 1 {
+			role = "assistant"
+		}
+		content := fmt.Sprintf("turn %d: %s", i, strings.Repeat("hello ", 400))
+		if _, err := s.addMessage(chatID, &models.MessageRequest{Role: role, Content: content}, false); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestSummarizedContextIsBilledAndCached(t *testing.T) {
+	conn := newTestDB(t)
+	user := createTestUser(t, conn, "summarizer", "user")
+	userID := fmt.Sprint(user.ID)
+	s := NewChatService(repositories.NewChatRepository(conn), NewUsageService(repositories.NewUsageRepository(conn)))
+	calls := newFakeAIService(t, s, "They said hello a lot.")
+
+	chat, err := s.CreateChat(userID, "long chat", models.ContextStrategySummarize, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	addTestTurns(t, s, chat.ID, 0, 12)
+
+	fitted := fitSummarizedChat(t, s, chat)
+	if calls.Load() != 1 {
+		t.Fatalf("summarizing made %d AI calls, want 1", calls.Load())
+	}
+	if content, _ := fitted[0]["content"].(string); fitted[0]["role"] != "system" || !strings.HasSuffix(content, "They said hello a lot.") {
+		t.Fatalf("first message = %v, want the summary", fitted[0])
+	}
+
+	var tracked int
+	if err := conn.QueryRow("SELECT COUNT(*) FROM usage_metrics WHERE user_id = ? AND endpoint = '/api/v1/chat/completions'", userID).Scan(&tracked); err != nil {
+		t.Fatal(err)
+	}
+	if tracked != 1 {
+		t.Errorf("%d summary completions tracked, want 1", tracked)
+	}
+
+	// The next completions reuse the summary until the window moves past it
+	if again := fitSummarizedChat(t, s, chat); calls.Load() != 1 || len(again) != len(fitted) {
+		t.Errorf("refitting made %d AI calls and kept %d messages, want 1 call and %d", calls.Load(), len(again), len(fitted))
+	}
+	addTestTurns(t, s, chat.ID, 12, 14)
+	fitSummarizedChat(t, s, chat)
+	if calls.Load() != 1 {
+		t.Errorf("refitting after two more turns made %d AI calls, want the cached summary reused", calls.Load())
+	}
+
+	addTestTurns(t, s, chat.ID, 14, 24)
+	fitSummarizedChat(t, s, chat)
+	if calls.Load() != 2 {
+		t.Errorf("refitting past the cached summary made %d AI calls, want 2", calls.Load())
+	}
+}
+
+func TestTranscriptPromptTrimsOnARuneBoundary(t *testing.T) {
+	turns := []map[string]interface{}{{"role": "user", "content": strings.Repeat("é", 20000)}}
+	for _, model := range []string{"command", "gpt-4"} {
+		prompt := transcriptPrompt(model, "Summarize.", turns)
+		text, _ := prompt[1]["content"].(string)
+		if !utf8.ValidString(text) {
+			t.Errorf("%s transcript is not valid UTF-8 after trimming", model)
+		}
+		if max := contextBudget(model) * 3; len(text) > max {
+			t.Errorf("%s transcript is %d bytes, want at most %d", model, len(text), max)
+		}
+	}
+}
@@ -0,0 +1,202 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"lio-ai/internal/models"
+	"lio-ai/internal/repositories"
+)
+
+var (
+	// ErrInvalidCostConfig is returned when a price or operation type fails
+	// validation
+	ErrInvalidCostConfig = errors.New("invalid cost config")
+	// ErrCostConfigExists is returned when adding a model that already has a
+	// cost config
+	ErrCostConfigExists = errors.New("cost config already exists")
+)
+
+// defaultCostModel prices models without an active cost config of their
+// own, so it can't be deactivated
+const defaultCostModel = "default"
+
+// CostConfigService lets admins manage model prices without SQL access.
+// Prices are read from the database whenever usage is charged, so changes
+// apply to the next request.
+type CostConfigService struct {
+	repo    *repositories.CostConfigRepository
+	usage   *UsageService
+	aliases *ModelAliasService // Optional; resolves aliases in price lookups
+
+	// Optional price feed synced by SyncPrices
+	feed       string
+	feedClient *http.Client
+	syncing    sync.Mutex // One sync at a time
+}
+
+// NewCostConfigService creates a new cost config service
+func NewCostConfigService(repo *repositories.CostConfigRepository, usage *UsageService) *CostConfigService {
+	return &CostConfigService{repo: repo, usage: usage}
+}
+
+// SetModelAliases resolves model aliases when looking up effective prices
+func (s *CostConfigService) SetModelAliases(aliases *ModelAliasService) {
+	s.aliases = aliases
+}
+
+// List retrieves every cost config, active or not
+func (s *CostConfigService) List() ([]models.CostConfig, error) {
+	return s.repo.List()
+}
+
+// Create prices a model that has no cost config yet
+func (s *CostConfigService) Create(req *models.CreateCostConfigRequest, adminID string) (*models.CostConfig, error) {
+	name := strings.TrimSpace(req.ModelName)
+	if name == "" {
+		return nil, fmt.Errorf("%w: model_name is required", ErrInvalidCostConfig)
+	}
+	operation := req.OperationType
+	if operation == "" {
+		operation = "chat"
+	}
+	c := &models.CostConfig{
+		ModelName:          name,
+		CostPerInputToken:  *req.CostPerInputToken,
+		CostPerOutputToken: *req.CostPerOutputToken,
+		OperationType:      operation,
+		IsActive:           true,
+	}
+	if err := validateCostConfig(c); err != nil {
+		return nil, err
+	}
+
+	existing, err := s.repo.Get(name)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return nil, fmt.Errorf("%w: %s", ErrCostConfigExists, name)
+	}
+	if err := s.repo.Create(c, models.CostConfigSourceAdmin, adminID); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Update changes a model's prices or operation type, or (re)activates it.
+// Changes are recorded in the price history.
+func (s *CostConfigService) Update(modelName string, req *models.UpdateCostConfigRequest, adminID string) (*models.CostConfig, error) {
+	c, err := s.get(modelName)
+	if err != nil {
+		return nil, err
+	}
+	previous := *c
+	if req.CostPerInputToken != nil {
+		c.CostPerInputToken = *req.CostPerInputToken
+	}
+	if req.CostPerOutputToken != nil {
+		c.CostPerOutputToken = *req.CostPerOutputToken
+	}
+	if req.OperationType != nil {
+		c.OperationType = *req.OperationType
+	}
+	if req.IsActive != nil {
+		c.IsActive = *req.IsActive
+	}
+	if err := validateCostConfig(c); err != nil {
+		return nil, err
+	}
+	if *c == previous {
+		return c, nil
+	}
+	if err := s.repo.Update(c, &previous, models.CostConfigSourceAdmin, adminID); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Deactivate stops charging a model's own prices; its usage is then
+// charged at the default's. The row is kept for the usage it priced.
+func (s *CostConfigService) Deactivate(modelName, adminID string) (*models.CostConfig, error) {
+	active := false
+	return s.Update(modelName, &models.UpdateCostConfigRequest{IsActive: &active}, adminID)
+}
+
+// History retrieves price changes, newest first, of one model or of every
+// model when modelName is empty, and how many there are in all
+func (s *CostConfigService) History(modelName string, limit, offset int) ([]models.CostConfigChange, int, error) {
+	return s.repo.ListChanges(strings.TrimSpace(modelName), limit, offset)
+}
+
+// EffectivePrice returns what usage of model is charged, through an alias
+// and with the platform key markup when keySource is KeySourcePlatform
+func (s *CostConfigService) EffectivePrice(model, keySource string) (*models.EffectivePrice, error) {
+	model = strings.TrimSpace(model)
+	if model == "" {
+		return nil, fmt.Errorf("%w: model is required", ErrInvalidCostConfig)
+	}
+	if keySource == "" {
+		keySource = KeySourceUser
+	}
+	if keySource != KeySourceUser && keySource != KeySourcePlatform {
+		return nil, fmt.Errorf("%w: key_source must be %q or %q", ErrInvalidCostConfig, KeySourceUser, KeySourcePlatform)
+	}
+
+	price := &models.EffectivePrice{Model: model, KeySource: keySource, Markup: 1}
+	if s.aliases != nil {
+		price.Model, price.Alias = s.aliases.Resolve(model)
+	}
+	if keySource == KeySourcePlatform {
+		price.Markup = s.usage.platformKeyMarkup()
+	}
+
+	config, err := s.usage.usageRepo.GetCostConfig(price.Model)
+	if err != nil {
+		return nil, err
+	}
+	price.PricedAs = config.ModelName
+	price.IsFallback = config.ModelName != price.Model
+	price.CostPerInputToken = config.CostPerInputToken
+	price.CostPerOutputToken = config.CostPerOutputToken
+
+	// Charged the way TrackUsage charges, so the two can't disagree
+	if price.InputCostPer1K, err = s.usage.CalculateCost(1000, 0, price.Model); err != nil {
+		return nil, err
+	}
+	if price.OutputCostPer1K, err = s.usage.CalculateCost(0, 1000, price.Model); err != nil {
+		return nil, err
+	}
+	price.InputCostPer1K *= price.Markup
+	price.OutputCostPer1K *= price.Markup
+	return price, nil
+}
+
+func (s *CostConfigService) get(modelName string) (*models.CostConfig, error) {
+	c, err := s.repo.Get(strings.TrimSpace(modelName))
+	if err != nil {
+		return nil, err
+	}
+	if c == nil {
+		return nil, fmt.Errorf("%w: no cost config for %s", ErrNotFound, modelName)
+	}
+	return c, nil
+}
+
+// validateCostConfig checks prices and operation type, and that the
+// default price stays active
+func validateCostConfig(c *models.CostConfig) error {
+	if c.CostPerInputToken < 0 || c.CostPerOutputToken < 0 {
+		return fmt.Errorf("%w: costs cannot be negative", ErrInvalidCostConfig)
+	}
+	if !costOperationTypes[c.OperationType] {
+		return fmt.Errorf("%w: operation_type must be chat, code_generation or embedding", ErrInvalidCostConfig)
+	}
+	if c.ModelName == defaultCostModel && !c.IsActive {
+		return fmt.Errorf("%w: the %s price applies to models without one and can't be deactivated", ErrInvalidCostConfig, defaultCostModel)
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package services
+
+import (
+	"log"
+
+	"lio-ai/internal/models"
+	"lio-ai/internal/repositories"
+)
+
+// SetAccessLog records reads of confidential documents in accessLog
+func (s *DocumentService) SetAccessLog(accessLog *repositories.DocumentAccessRepository) {
+	s.accessLog = accessLog
+}
+
+// RecordRead adds a read to the access log of each confidential document
+// in docs. Failures are logged rather than failing the read.
+func (s *DocumentService) RecordRead(reader models.DocumentReader, docs ...*models.DocumentResponse) {
+	for _, doc := range docs {
+		if doc.Confidential {
+			recordDocumentRead(s.accessLog, reader, doc.ID)
+		}
+	}
+}
+
+// GetAccessLog retrieves the access log of a document owned by userID,
+// newest first, with the total count
+func (s *DocumentService) GetAccessLog(id uint, userID string, limit, offset int) ([]models.DocumentAccess, int64, error) {
+	if _, err := s.ownedDocument(id, userID); err != nil {
+		return nil, 0, err
+	}
+	if s.accessLog == nil {
+		return []models.DocumentAccess{}, 0, nil
+	}
+	return s.accessLog.ListByDocument(id, limit, offset)
+}
+
+func recordDocumentRead(accessLog *repositories.DocumentAccessRepository, reader models.DocumentReader, documentID uint) {
+	if accessLog == nil {
+		return
+	}
+	access := &models.DocumentAccess{
+		DocumentID: documentID,
+		UserID:     reader.UserID,
+		Endpoint:   reader.Endpoint,
+		IPAddress:  reader.IPAddress,
+	}
+	if err := accessLog.Record(access); err != nil {
+		log.Printf("⚠️  Failed to record read of confidential document %d: %v", documentID, err)
+	}
+}
@@ -0,0 +1,160 @@
+package services
+
+import (
+	"fmt"
+	"log"
+
+	"lio-ai/internal/models"
+	"lio-ai/internal/repositories"
+)
+
+// Longest range of content, in characters, a comment can be anchored to
+const maxCommentRange = 10000
+
+// SetComments lets documents be commented on, with comments kept in
+// comments
+func (s *DocumentService) SetComments(comments *repositories.DocumentCommentRepository) {
+	s.comments = comments
+}
+
+// ListComments retrieves the comments on a document owned by userID in the
+// order of their ranges
+func (s *DocumentService) ListComments(id uint, userID string) ([]models.DocumentComment, error) {
+	doc, err := s.ownedDocument(id, userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.documentComments(doc.ID, doc.Content)
+}
+
+// AttachComments sets the comments of a document already retrieved for its
+// owner
+func (s *DocumentService) AttachComments(doc *models.DocumentResponse) error {
+	comments, err := s.documentComments(doc.ID, doc.Content)
+	if err != nil {
+		return err
+	}
+	doc.Comments = &comments
+	return nil
+}
+
+// AddComment comments on a range of the content of a document owned by
+// userID, keeping the text the range covers as the comment's quote
+func (s *DocumentService) AddComment(id uint, userID string, req *models.CreateDocumentCommentRequest) (*models.DocumentComment, error) {
+	if s.comments == nil {
+		return nil, fmt.Errorf("%w: document comments are not enabled", ErrInvalidMessage)
+	}
+	doc, err := s.ownedDocument(id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	start, end := *req.StartOffset, *req.EndOffset
+	content := []rune(doc.Content)
+	switch {
+	case start > end:
+		return nil, fmt.Errorf("%w: start_offset must not be after end_offset", ErrInvalidMessage)
+	case end > len(content):
+		return nil, fmt.Errorf("%w: end_offset is past the end of the content (%d characters)", ErrInvalidMessage, len(content))
+	case end-start > maxCommentRange:
+		return nil, fmt.Errorf("%w: comments can cover at most %d characters", ErrInvalidMessage, maxCommentRange)
+	}
+
+	comment := &models.DocumentComment{
+		DocumentID:  doc.ID,
+		UserID:      userID,
+		Body:        req.Body,
+		StartOffset: start,
+		EndOffset:   end,
+		Quote:       string(content[start:end]),
+	}
+	if err := s.comments.Create(comment); err != nil {
+		return nil, err
+	}
+	return comment, nil
+}
+
+// UpdateComment edits the body of, or resolves, a comment on a document
+// owned by userID
+func (s *DocumentService) UpdateComment(id uint, userID string, commentID int64, req *models.UpdateDocumentCommentRequest) (*models.DocumentComment, error) {
+	doc, comment, err := s.ownedComment(id, userID, commentID)
+	if err != nil {
+		return nil, err
+	}
+	if req.Body != nil {
+		comment.Body = *req.Body
+	}
+	if req.Resolved != nil {
+		comment.Resolved = *req.Resolved
+	}
+	if err := s.comments.Update(comment); err != nil {
+		return nil, err
+	}
+	comment.Outdated = commentOutdated(comment, []rune(doc.Content))
+	return comment, nil
+}
+
+// DeleteComment deletes a comment on a document owned by userID
+func (s *DocumentService) DeleteComment(id uint, userID string, commentID int64) error {
+	if _, _, err := s.ownedComment(id, userID, commentID); err != nil {
+		return err
+	}
+	if _, err := s.comments.Delete(commentID, id); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (s *DocumentService) ownedComment(id uint, userID string, commentID int64) (*models.Document, *models.DocumentComment, error) {
+	doc, err := s.ownedDocument(id, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	var comment *models.DocumentComment
+	if s.comments != nil {
+		if comment, err = s.comments.Get(commentID, doc.ID); err != nil {
+			return nil, nil, err
+		}
+	}
+	if comment == nil {
+		return nil, nil, fmt.Errorf("%w: comment %d on document %d", ErrNotFound, commentID, id)
+	}
+	return doc, comment, nil
+}
+
+// documentComments lists a document's comments, flagging those whose
+// range no longer covers their quote
+func (s *DocumentService) documentComments(id uint, content string) ([]models.DocumentComment, error) {
+	if s.comments == nil {
+		return []models.DocumentComment{}, nil
+	}
+	comments, err := s.comments.ListByDocument(id)
+	if err != nil {
+		return nil, err
+	}
+	runes := []rune(content)
+	for i := range comments {
+		comments[i].Outdated = commentOutdated(&comments[i], runes)
+	}
+	return comments, nil
+}
+
+// deleteComments removes the comments of a deleted document. Failures are
+// logged rather than failing the delete.
+func (s *DocumentService) deleteComments(id uint) {
+	if s.comments == nil {
+		return
+	}
+	if err := s.comments.DeleteByDocument(id); err != nil {
+		log.Printf("Failed to delete comments on document %d: %v", id, err)
+	}
+}
+
+// commentOutdated reports whether content has changed under a comment's
+// range since it was made
+func commentOutdated(comment *models.DocumentComment, content []rune) bool {
+	if comment.EndOffset > len(content) {
+		return true
+	}
+	return string(content[comment.StartOffset:comment.EndOffset]) != comment.Quote
+}
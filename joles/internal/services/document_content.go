@@ -0,0 +1,168 @@
+package services
+
+import (
+	"fmt"
+	"html"
+	"mime"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"lio-ai/internal/models"
+)
+
+// contentMediaTypes maps the media types accepted as content types to the
+// content type and, for code, the language they stand for
+var contentMediaTypes = map[string][2]string{
+	"text/plain":             {models.DocumentContentPlaintext},
+	"text/markdown":          {models.DocumentContentMarkdown},
+	"text/x-markdown":        {models.DocumentContentMarkdown},
+	"text/html":              {models.DocumentContentHTML},
+	"application/xhtml+xml":  {models.DocumentContentHTML},
+	"text/x-go":              {models.DocumentContentCode, "go"},
+	"text/x-python":          {models.DocumentContentCode, "python"},
+	"text/x-java":            {models.DocumentContentCode, "java"},
+	"text/x-c":               {models.DocumentContentCode, "c"},
+	"text/x-c++":             {models.DocumentContentCode, "cpp"},
+	"text/x-rust":            {models.DocumentContentCode, "rust"},
+	"text/x-ruby":            {models.DocumentContentCode, "ruby"},
+	"text/x-sh":              {models.DocumentContentCode, "shell"},
+	"application/x-sh":       {models.DocumentContentCode, "shell"},
+	"text/javascript":        {models.DocumentContentCode, "javascript"},
+	"application/javascript": {models.DocumentContentCode, "javascript"},
+	"application/typescript": {models.DocumentContentCode, "typescript"},
+	"application/json":       {models.DocumentContentCode, "json"},
+	"application/sql":        {models.DocumentContentCode, "sql"},
+	"application/yaml":       {models.DocumentContentCode, "yaml"},
+}
+
+// codeLanguageName matches a lowercased programming language name, e.g.
+// "go", "c++", "c#" or "objective-c"
+var codeLanguageName = regexp.MustCompile(`^[a-z0-9][a-z0-9+#._-]{0,31}$`)
+
+// codeExtensions are the file extensions code documents are exported with
+var codeExtensions = map[string]string{
+	"go": "go", "python": "py", "java": "java", "c": "c", "cpp": "cpp", "c++": "cpp",
+	"csharp": "cs", "c#": "cs", "rust": "rs", "ruby": "rb", "php": "php", "shell": "sh",
+	"bash": "sh", "javascript": "js", "typescript": "ts", "kotlin": "kt", "swift": "swift",
+	"json": "json", "sql": "sql", "yaml": "yaml",
+}
+
+// normalizeContentType resolves a requested content type and code language
+// to the stored pair. Empty means plaintext; media types are accepted for
+// the content types they correspond to, and code needs a language.
+func normalizeContentType(contentType, codeLanguage string) (string, string, error) {
+	contentType = strings.ToLower(strings.TrimSpace(contentType))
+	codeLanguage = strings.ToLower(strings.TrimSpace(codeLanguage))
+
+	switch contentType {
+	case "":
+		contentType = models.DocumentContentPlaintext
+	case models.DocumentContentPlaintext, models.DocumentContentMarkdown, models.DocumentContentHTML, models.DocumentContentCode:
+	default:
+		mediaType, _, err := mime.ParseMediaType(contentType)
+		known, ok := contentMediaTypes[mediaType]
+		if err != nil || !ok {
+			return "", "", fmt.Errorf("%w: content_type must be markdown, plaintext, html or code", ErrInvalidMessage)
+		}
+		contentType = known[0]
+		if codeLanguage == "" {
+			codeLanguage = known[1]
+		}
+	}
+
+	if contentType != models.DocumentContentCode {
+		if codeLanguage != "" {
+			return "", "", fmt.Errorf("%w: code_language is only used by code documents", ErrInvalidMessage)
+		}
+		return contentType, "", nil
+	}
+	if codeLanguage == "" {
+		return "", "", fmt.Errorf("%w: code documents need a code_language", ErrInvalidMessage)
+	}
+	if !codeLanguageName.MatchString(codeLanguage) {
+		return "", "", fmt.Errorf("%w: code_language %q is not a language name", ErrInvalidMessage, codeLanguage)
+	}
+	return contentType, codeLanguage, nil
+}
+
+// contentTypeFilter resolves the content type and code language a document
+// list is filtered by, accepting media types like normalizeContentType
+func contentTypeFilter(contentType, codeLanguage string) (string, string) {
+	contentType = strings.ToLower(strings.TrimSpace(contentType))
+	codeLanguage = strings.ToLower(strings.TrimSpace(codeLanguage))
+	if mediaType, _, err := mime.ParseMediaType(contentType); err == nil {
+		if known, ok := contentMediaTypes[mediaType]; ok {
+			contentType = known[0]
+			if codeLanguage == "" {
+				codeLanguage = known[1]
+			}
+		}
+	}
+	return contentType, codeLanguage
+}
+
+// DocumentExport is a document's content as a file of its content type
+type DocumentExport struct {
+	Filename  string
+	MediaType string
+	Content   string
+}
+
+// ExportDocument returns the content of a document owned by userID as a
+// file: Markdown and HTML keep their media types, code gets its language's
+// extension and everything else is plain text
+func (s *DocumentService) ExportDocument(id uint, userID string) (*models.DocumentResponse, *DocumentExport, error) {
+	doc, err := s.ownedDocument(id, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	export := &DocumentExport{Content: doc.Content}
+	ext := "txt"
+	switch doc.ContentType {
+	case models.DocumentContentMarkdown:
+		export.MediaType, ext = "text/markdown; charset=utf-8", "md"
+	case models.DocumentContentHTML:
+		export.MediaType, ext = "text/html; charset=utf-8", "html"
+	case models.DocumentContentCode:
+		export.MediaType = "text/plain; charset=utf-8"
+		if e, ok := codeExtensions[doc.CodeLanguage]; ok {
+			ext = e
+		}
+	default:
+		export.MediaType = "text/plain; charset=utf-8"
+	}
+	export.Filename = exportName(doc.Title) + "." + ext
+	return doc.ToResponse(), export, nil
+}
+
+// exportName turns a title into a file name, replacing path separators and
+// other characters that are awkward in file names
+func exportName(title string) string {
+	name := strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || strings.ContainsRune(" -_", r) {
+			return r
+		}
+		return '_'
+	}, strings.TrimSpace(title))
+	if name == "" {
+		return "document"
+	}
+	return name
+}
+
+var (
+	htmlHidden   = regexp.MustCompile(`(?is)<(script|style|head)\b.*?</(script|style|head)\s*>`)
+	htmlBlockTag = regexp.MustCompile(`(?i)</?(p|div|br|hr|h[1-6]|li|ul|ol|tr|table|section|article|pre|blockquote)\b[^>]*>`)
+	htmlTag      = regexp.MustCompile(`<[^>]*>`)
+)
+
+// htmlText is the readable text of an HTML document, with block elements
+// as paragraph breaks, so it splits like prose
+func htmlText(content string) string {
+	text := htmlHidden.ReplaceAllString(content, "")
+	text = htmlBlockTag.ReplaceAllString(text, "\n\n")
+	text = htmlTag.ReplaceAllString(text, "")
+	return html.UnescapeString(text)
+}
@@ -0,0 +1,269 @@
+package services
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"path"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"lio-ai/internal/extract"
+	"lio-ai/internal/models"
+	"lio-ai/internal/repositories"
+)
+
+const (
+	// MaxDocumentImportEntries caps the documents in one import
+	MaxDocumentImportEntries = 1000
+	// Longest JSONL line read, and largest ZIP entry when uploads have no
+	// per-file limit
+	maxImportEntryBytes = 20 << 20
+)
+
+// DocumentImportService creates documents in bulk from ZIP archives and
+// JSONL streams, in background jobs that can be polled
+type DocumentImportService struct {
+	jobs *repositories.DocumentImportRepository
+	docs *DocumentService
+}
+
+// NewDocumentImportService creates a new document import service
+func NewDocumentImportService(jobs *repositories.DocumentImportRepository, docs *DocumentService) *DocumentImportService {
+	return &DocumentImportService{jobs: jobs, docs: docs}
+}
+
+// ParseDocumentZIP reads each text and Markdown file of a ZIP archive as a
+// document titled after the file. Other files become failed entries;
+// directories, hidden files and macOS metadata are skipped.
+func (s *DocumentImportService) ParseDocumentZIP(data []byte) ([]models.DocumentImportEntry, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("%w: not a ZIP archive", ErrInvalidImport)
+	}
+	limit := int64(maxImportEntryBytes)
+	if s.docs.maxFileBytes > 0 {
+		limit = s.docs.maxFileBytes
+	}
+
+	var entries []models.DocumentImportEntry
+	for _, f := range zr.File {
+		if f.FileInfo().IsDir() || skippedArchiveFile(f.Name) {
+			continue
+		}
+		if len(entries) == MaxDocumentImportEntries {
+			return nil, fmt.Errorf("%w: at most %d files per import", ErrInvalidImport, MaxDocumentImportEntries)
+		}
+		entry := models.DocumentImportEntry{Entry: len(entries) + 1, Name: f.Name}
+		if err := readArchiveDocument(f, limit, &entry.Request); err != nil {
+			entry.Error = err.Error()
+		}
+		entries = append(entries, entry)
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("%w: the archive has no files", ErrInvalidImport)
+	}
+	return entries, nil
+}
+
+func skippedArchiveFile(name string) bool {
+	if strings.HasPrefix(name, "__MACOSX/") {
+		return true
+	}
+	return strings.HasPrefix(path.Base(name), ".")
+}
+
+func readArchiveDocument(f *zip.File, limit int64, req *models.CreateDocumentRequest) error {
+	format, err := extract.Detect(f.Name, "")
+	if err != nil || (format != extract.FormatText && format != extract.FormatMarkdown) {
+		return errors.New("unsupported file type: only text and Markdown files are imported")
+	}
+	if f.UncompressedSize64 > uint64(limit) {
+		return fmt.Errorf("%v: file exceeds %d bytes", ErrDocumentFileTooLarge, limit)
+	}
+
+	rc, err := f.Open()
+	if err != nil {
+		return fmt.Errorf("failed to read file: %v", err)
+	}
+	defer rc.Close()
+	data, err := io.ReadAll(io.LimitReader(rc, limit+1))
+	if err != nil {
+		return fmt.Errorf("failed to read file: %v", err)
+	}
+	if int64(len(data)) > limit {
+		return fmt.Errorf("%v: file exceeds %d bytes", ErrDocumentFileTooLarge, limit)
+	}
+
+	text, err := extract.Text(format, data)
+	if err != nil {
+		return err
+	}
+	req.Title = documentTitle(f.Name)
+	req.Content = text
+	if format == extract.FormatMarkdown {
+		req.ContentType = models.DocumentContentMarkdown
+	}
+	return nil
+}
+
+// ParseDocumentJSONL reads one document per line, each an object with the
+// fields of a document create request. Blank lines are skipped; lines
+// that are not valid JSON become failed entries.
+func (s *DocumentImportService) ParseDocumentJSONL(r io.Reader) ([]models.DocumentImportEntry, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64<<10), maxImportEntryBytes)
+
+	var entries []models.DocumentImportEntry
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := bytes.TrimSpace(scanner.Bytes())
+		if len(text) == 0 {
+			continue
+		}
+		if len(entries) == MaxDocumentImportEntries {
+			return nil, fmt.Errorf("%w: at most %d documents per import", ErrInvalidImport, MaxDocumentImportEntries)
+		}
+		entry := models.DocumentImportEntry{Entry: line}
+		if err := json.Unmarshal(text, &entry.Request); err != nil {
+			entry.Error = "invalid JSON: " + err.Error()
+		}
+		entry.Name = entry.Request.Title
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		if errors.Is(err, bufio.ErrTooLong) {
+			return nil, fmt.Errorf("%w: line %d exceeds %d bytes", ErrInvalidImport, line+1, maxImportEntryBytes)
+		}
+		return nil, fmt.Errorf("%w: %v", ErrInvalidImport, err)
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("%w: no documents found", ErrInvalidImport)
+	}
+	return entries, nil
+}
+
+// StartImport queues an import of entries for userID. Tags in defaults
+// are added to every entry, and its collection is used by entries that
+// name none.
+func (s *DocumentImportService) StartImport(userID, source string, entries []models.DocumentImportEntry, defaults *models.ImportDocumentsRequest) (*models.DocumentImportJob, error) {
+	if defaults.CollectionID != nil {
+		if _, err := ownedCollection(s.docs.collections, *defaults.CollectionID, userID); err != nil {
+			return nil, err
+		}
+	}
+	for i := range entries {
+		req := &entries[i].Request
+		req.Tags = append(req.Tags, defaults.Tags...)
+		if req.CollectionID == nil {
+			req.CollectionID = defaults.CollectionID
+		}
+	}
+
+	job := &models.DocumentImportJob{
+		UserID:       userID,
+		Source:       source,
+		TotalEntries: len(entries),
+	}
+	if err := s.jobs.Create(job); err != nil {
+		return nil, err
+	}
+
+	go s.runImport(*job, entries)
+	return job, nil
+}
+
+// runImport creates the documents one by one, saving progress as it goes
+// so the job can be polled
+func (s *DocumentImportService) runImport(job models.DocumentImportJob, entries []models.DocumentImportEntry) {
+	job.Status = models.ImportStatusRunning
+	s.saveImportProgress(&job)
+
+	for i, entry := range entries {
+		result := models.DocumentImportResult{Entry: entry.Entry, Name: entry.Name}
+		err := errors.New(entry.Error)
+		if entry.Error == "" {
+			err = validateImportedDocument(&entry.Request)
+		}
+		if err == nil {
+			var doc *models.DocumentResponse
+			if doc, err = s.docs.CreateDocument(&entry.Request, job.UserID); err == nil {
+				result.DocumentID = doc.ID
+			}
+		}
+
+		job.Processed++
+		if err != nil {
+			job.Failed++
+			result.Error = err.Error()
+		} else {
+			job.Created++
+		}
+		job.Results = append(job.Results, result)
+		if (i+1)%50 == 0 {
+			s.saveImportProgress(&job)
+		}
+	}
+
+	now := time.Now()
+	job.Status = models.ImportStatusCompleted
+	job.CompletedAt = &now
+	s.saveImportProgress(&job)
+	log.Printf("✅ Document import %d finished: %d created, %d failed", job.ID, job.Created, job.Failed)
+}
+
+// validateImportedDocument applies the checks a create request gets when
+// it is bound from a request body
+func validateImportedDocument(req *models.CreateDocumentRequest) error {
+	req.Title = strings.TrimSpace(req.Title)
+	switch {
+	case req.Title == "":
+		return errors.New("title is required")
+	case utf8.RuneCountInString(req.Title) > 255:
+		return errors.New("title must be at most 255 characters")
+	case strings.TrimSpace(req.Content) == "":
+		return errors.New("content is required")
+	case req.CollectionID != nil && *req.CollectionID <= 0:
+		return errors.New("invalid collection_id")
+	}
+	req.Tags = normalizeTags(req.Tags)
+	if len(req.Tags) > 20 {
+		return errors.New("at most 20 tags")
+	}
+	for _, tag := range req.Tags {
+		if utf8.RuneCountInString(tag) > 50 {
+			return fmt.Errorf("tag %q is longer than 50 characters", tag)
+		}
+	}
+	return nil
+}
+
+func (s *DocumentImportService) saveImportProgress(job *models.DocumentImportJob) {
+	if err := s.jobs.UpdateProgress(job); err != nil {
+		log.Printf("Failed to save document import %d progress: %v", job.ID, err)
+	}
+}
+
+// GetImportJob retrieves an import job of userID
+func (s *DocumentImportService) GetImportJob(id int64, userID string) (*models.DocumentImportJob, error) {
+	job, err := s.jobs.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if job == nil || job.UserID != userID {
+		return nil, fmt.Errorf("%w: import %d", ErrNotFound, id)
+	}
+	return job, nil
+}
+
+// FailInterruptedImports marks imports cut short by a restart
+func (s *DocumentImportService) FailInterruptedImports() error {
+	return s.jobs.FailInterrupted()
+}
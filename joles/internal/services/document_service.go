@@ -1,53 +1,152 @@
 package services
 
 import (
+	"errors"
 	"fmt"
+	"log"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
 
 	"lio-ai/internal/models"
 	"lio-ai/internal/repositories"
+	"lio-ai/internal/storage"
 )
 
 // DocumentService handles document business logic
 type DocumentService struct {
-	repo *repositories.DocumentRepository
+	repo        *repositories.DocumentRepository
+	collections *repositories.CollectionRepository
+	// Optional storage accounting; limits are not enforced when unset
+	storage *StorageService
+	// Optional store for uploaded originals; uploads are rejected when unset
+	files        storage.Store
+	maxFileBytes int64
+	// Optional semantic search index kept up to date with document writes
+	index *SemanticSearchService
+	// Optional access log of confidential documents
+	accessLog *repositories.DocumentAccessRepository
+	// Optional revision history of titles and contents
+	versions *repositories.DocumentVersionRepository
+	// Optional comments anchored to ranges of documents' content
+	comments *repositories.DocumentCommentRepository
+	// Optional webhooks notified of created, updated and deleted documents
+	webhooks *WebhookService
 }
 
 // NewDocumentService creates a new document service
-func NewDocumentService(repo *repositories.DocumentRepository) *DocumentService {
-	return &DocumentService{repo: repo}
+func NewDocumentService(repo *repositories.DocumentRepository, collections *repositories.CollectionRepository) *DocumentService {
+	return &DocumentService{repo: repo, collections: collections}
 }
 
-// CreateDocument creates a new document
-func (s *DocumentService) CreateDocument(req *models.CreateDocumentRequest) (*models.DocumentResponse, error) {
+// SetStorageService counts document sizes against users' storage limits
+func (s *DocumentService) SetStorageService(storage *StorageService) {
+	s.storage = storage
+}
+
+// SetWebhooks sends document events to the owners' webhooks
+func (s *DocumentService) SetWebhooks(webhooks *WebhookService) {
+	s.webhooks = webhooks
+}
+
+// documentEvent sends a document event to its owner's webhooks
+func (s *DocumentService) documentEvent(eventType string, doc *models.Document) {
+	if s.webhooks != nil {
+		s.webhooks.Dispatch(doc.UserID, eventType, documentEventData(doc))
+	}
+}
+
+// MaxContentBytes is the largest document content any plan allows, or 0
+// when unlimited
+func (s *DocumentService) MaxContentBytes() int64 {
+	if s.storage == nil {
+		return 0
+	}
+	return s.storage.MaxDocumentBytes()
+}
+
+// CreateDocument creates a new document, charged to userID's storage
+func (s *DocumentService) CreateDocument(req *models.CreateDocumentRequest, userID string) (*models.DocumentResponse, error) {
 	doc := &models.Document{
-		Title:   req.Title,
-		Content: req.Content,
+		UserID:       userID,
+		Title:        req.Title,
+		Content:      req.Content,
+		Tags:         normalizeTags(req.Tags),
+		CollectionID: req.CollectionID,
+		Confidential: req.Confidential,
+		WordCount:    wordCount(req.Content),
+	}
+	if err := setDocumentMetadata(doc, req.SourceURL, req.Language, req.ContentType, req.CodeLanguage); err != nil {
+		return nil, err
+	}
+
+	if req.CollectionID != nil {
+		if _, err := ownedCollection(s.collections, *req.CollectionID, userID); err != nil {
+			return nil, err
+		}
+	}
+
+	if s.storage != nil {
+		if err := s.storage.CheckDocument(userID, doc.Content); err != nil {
+			return nil, err
+		}
+		if err := s.storage.Check(userID, documentSize(doc)); err != nil {
+			return nil, err
+		}
 	}
 
 	if err := s.repo.Create(doc); err != nil {
 		return nil, fmt.Errorf("service error: %w", err)
 	}
 
+	if s.storage != nil {
+		if err := s.storage.Record(userID, models.StorageKindDocument, int64(doc.ID), documentSize(doc)); err != nil {
+			log.Printf("Failed to record storage for document %d: %v", doc.ID, err)
+		}
+	}
+	if s.index != nil {
+		s.index.Enqueue(doc.ID)
+	}
+	s.recordVersion(doc)
+	s.documentEvent(models.WebhookEventDocumentCreated, doc)
+
 	return doc.ToResponse(), nil
 }
 
-// GetDocument retrieves a document by ID
-func (s *DocumentService) GetDocument(id uint) (*models.DocumentResponse, error) {
+// ownedDocument loads a document, returning ErrNotFound when there is none
+// and ErrUnauthorized when it belongs to another user
+func (s *DocumentService) ownedDocument(id uint, userID string) (*models.Document, error) {
 	doc, err := s.repo.GetByID(id)
 	if err != nil {
 		return nil, fmt.Errorf("service error: %w", err)
 	}
-
 	if doc == nil {
-		return nil, fmt.Errorf("document not found")
+		return nil, fmt.Errorf("%w: document %d", ErrNotFound, id)
+	}
+	if doc.UserID != userID {
+		return nil, ErrUnauthorized
+	}
+	return doc, nil
+}
+
+// GetDocument retrieves a document by ID (with ownership check)
+func (s *DocumentService) GetDocument(id uint, userID string) (*models.DocumentResponse, error) {
+	doc, err := s.ownedDocument(id, userID)
+	if err != nil {
+		return nil, err
 	}
 
 	return doc.ToResponse(), nil
 }
 
-// GetDocuments retrieves all documents with pagination
-func (s *DocumentService) GetDocuments(skip, limit int) ([]*models.DocumentResponse, int64, error) {
-	docs, total, err := s.repo.GetAll(skip, limit)
+// GetDocuments retrieves a user's documents matching the filter with
+// pagination
+func (s *DocumentService) GetDocuments(filter models.DocumentFilter) ([]*models.DocumentResponse, int64, error) {
+	filter.Tags = normalizeTags(filter.Tags)
+	filter.Language = strings.ToLower(strings.TrimSpace(filter.Language))
+	filter.ContentType, filter.CodeLanguage = contentTypeFilter(filter.ContentType, filter.CodeLanguage)
+	docs, total, err := s.repo.GetAll(filter)
 	if err != nil {
 		return nil, 0, fmt.Errorf("service error: %w", err)
 	}
@@ -60,32 +159,253 @@ func (s *DocumentService) GetDocuments(skip, limit int) ([]*models.DocumentRespo
 	return responses, total, nil
 }
 
-// UpdateDocument updates an existing document
-func (s *DocumentService) UpdateDocument(id uint, req *models.UpdateDocumentRequest) (*models.DocumentResponse, error) {
+// UpdateDocument updates a document owned by userID, charging growth to
+// their storage
+func (s *DocumentService) UpdateDocument(id uint, userID string, req *models.UpdateDocumentRequest) (*models.DocumentResponse, error) {
 	updates := &models.Document{}
 	if req.Title != nil {
 		updates.Title = *req.Title
 	}
 	if req.Content != nil {
 		updates.Content = *req.Content
+		updates.WordCount = wordCount(*req.Content)
+	}
+	if req.Tags != nil {
+		updates.Tags = normalizeTags(*req.Tags)
+	}
+
+	existing, err := s.ownedDocument(id, userID)
+	if err != nil {
+		return nil, err
+	}
+	expectedVersion := 0
+	if req.Version != nil {
+		expectedVersion = *req.Version
+		if existing.Version != expectedVersion {
+			return nil, fmt.Errorf("%w: document %d is at version %d", repositories.ErrVersionConflict, id, existing.Version)
+		}
+	}
+	metadataChanged := req.SourceURL != nil || req.Language != nil || req.ContentType != nil || req.CodeLanguage != nil
+	metadata := *existing
+	if metadataChanged {
+		sourceURL, language, contentType, codeLanguage := existing.SourceURL, existing.Language, existing.ContentType, existing.CodeLanguage
+		if req.SourceURL != nil {
+			sourceURL = *req.SourceURL
+		}
+		if req.Language != nil {
+			language = *req.Language
+		}
+		if req.ContentType != nil {
+			contentType = *req.ContentType
+			// A new content type drops the old language unless one is given
+			if req.CodeLanguage == nil {
+				codeLanguage = ""
+			}
+		}
+		if req.CodeLanguage != nil {
+			codeLanguage = *req.CodeLanguage
+		}
+		if err := setDocumentMetadata(&metadata, sourceURL, language, contentType, codeLanguage); err != nil {
+			return nil, err
+		}
+	}
+
+	if s.storage != nil {
+		_, recorded, err := s.storage.Owner(models.StorageKindDocument, int64(id))
+		if err != nil {
+			return nil, err
+		}
+
+		updated := *existing
+		if req.Title != nil {
+			updated.Title = *req.Title
+		}
+		if req.Content != nil {
+			updated.Content = *req.Content
+			if err := s.storage.CheckDocument(userID, updated.Content); err != nil {
+				return nil, err
+			}
+		}
+		if err := s.storage.Check(userID, documentSize(&updated)-recorded); err != nil {
+			return nil, err
+		}
 	}
 
-	doc, err := s.repo.Update(id, updates)
+	doc, err := s.repo.Update(id, userID, updates, expectedVersion)
+	if errors.Is(err, repositories.ErrVersionConflict) {
+		return nil, err
+	}
 	if err != nil {
 		return nil, fmt.Errorf("service error: %w", err)
 	}
 
 	if doc == nil {
-		return nil, fmt.Errorf("document not found")
+		return nil, fmt.Errorf("%w: document %d", ErrNotFound, id)
+	}
+	if req.Confidential != nil {
+		if err := s.repo.SetConfidential(id, userID, *req.Confidential); err != nil {
+			return nil, fmt.Errorf("service error: %w", err)
+		}
+		doc.Confidential = *req.Confidential
+	}
+	if metadataChanged {
+		if err := s.repo.SetMetadata(id, userID, metadata.SourceURL, metadata.Language, metadata.ContentType, metadata.CodeLanguage); err != nil {
+			return nil, fmt.Errorf("service error: %w", err)
+		}
+		doc.SourceURL, doc.Language = metadata.SourceURL, metadata.Language
+		doc.ContentType, doc.CodeLanguage = metadata.ContentType, metadata.CodeLanguage
 	}
 
+	if s.storage != nil {
+		if err := s.storage.Record(userID, models.StorageKindDocument, int64(doc.ID), documentSize(doc)); err != nil {
+			log.Printf("Failed to record storage for document %d: %v", doc.ID, err)
+		}
+	}
+	// The content type picks how the content is split for search
+	if s.index != nil && (req.Content != nil || doc.ContentType != existing.ContentType) {
+		s.index.Enqueue(doc.ID)
+	}
+	if doc.Title != existing.Title || doc.Content != existing.Content {
+		s.recordVersion(doc)
+	}
+	s.documentEvent(models.WebhookEventDocumentUpdated, doc)
+
 	return doc.ToResponse(), nil
 }
 
-// DeleteDocument deletes a document
-func (s *DocumentService) DeleteDocument(id uint) error {
-	if err := s.repo.Delete(id); err != nil {
+// DeleteDocument deletes a document owned by userID, along with its
+// uploaded file
+func (s *DocumentService) DeleteDocument(id uint, userID string) error {
+	doc, err := s.ownedDocument(id, userID)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.Delete(id, userID); err != nil {
+		return fmt.Errorf("%w: document %d", ErrNotFound, id)
+	}
+	s.deleteFile(doc.File)
+	if s.storage != nil {
+		if err := s.storage.Release(models.StorageKindDocument, int64(id)); err != nil {
+			log.Printf("Failed to release storage for document %d: %v", id, err)
+		}
+	}
+	if s.index != nil {
+		s.index.Remove(id)
+	}
+	if s.versions != nil {
+		if err := s.versions.DeleteByDocument(id); err != nil {
+			log.Printf("Failed to delete versions of document %d: %v", id, err)
+		}
+	}
+	s.deleteComments(id)
+	s.documentEvent(models.WebhookEventDocumentDeleted, doc)
+	return nil
+}
+
+// MoveDocument files a document owned by userID in one of their
+// collections, or at the top level when collectionID is nil
+func (s *DocumentService) MoveDocument(id uint, userID string, collectionID *int64) (*models.DocumentResponse, error) {
+	if _, err := s.ownedDocument(id, userID); err != nil {
+		return nil, err
+	}
+	if collectionID != nil {
+		if _, err := ownedCollection(s.collections, *collectionID, userID); err != nil {
+			return nil, err
+		}
+	}
+
+	moved, err := s.repo.SetCollection(id, userID, collectionID)
+	if err != nil {
+		return nil, fmt.Errorf("service error: %w", err)
+	}
+	if !moved {
+		return nil, fmt.Errorf("%w: document %d", ErrNotFound, id)
+	}
+	return s.GetDocument(id, userID)
+}
+
+// SetDocumentTags replaces the tags of a document owned by userID
+func (s *DocumentService) SetDocumentTags(id uint, userID string, tags []string) error {
+	if _, err := s.ownedDocument(id, userID); err != nil {
+		return err
+	}
+	if err := s.repo.SetTags(id, normalizeTags(tags)); err != nil {
 		return fmt.Errorf("service error: %w", err)
 	}
 	return nil
 }
+
+// SetFavorite marks a document owned by userID as one of their favorites,
+// or not; a nil favorite toggles it
+func (s *DocumentService) SetFavorite(id uint, userID string, favorite *bool) (*models.DocumentResponse, error) {
+	doc, err := s.ownedDocument(id, userID)
+	if err != nil {
+		return nil, err
+	}
+	doc.IsFavorite = !doc.IsFavorite
+	if favorite != nil {
+		doc.IsFavorite = *favorite
+	}
+	if err := s.repo.SetFavorite(id, userID, doc.IsFavorite); err != nil {
+		return nil, fmt.Errorf("service error: %w", err)
+	}
+	return doc.ToResponse(), nil
+}
+
+// normalizeTags trims and lowercases tags, dropping empty and repeated
+// ones, so "Work" and " work" are the same tag
+func normalizeTags(tags []string) []string {
+	normalized := make([]string, 0, len(tags))
+	seen := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		normalized = append(normalized, tag)
+	}
+	sort.Strings(normalized)
+	return normalized
+}
+
+// languageTag matches the shape of a lowercased BCP 47 language tag, e.g.
+// "en", "pt-br" or "zh-hant-tw"
+var languageTag = regexp.MustCompile(`^[a-z]{2,3}(-[a-z0-9]{1,8})*$`)
+
+// setDocumentMetadata checks and sets a document's source URL, language,
+// content type and code language. Languages are lowercased so filters
+// match them exactly.
+func setDocumentMetadata(doc *models.Document, sourceURL, language, contentType, codeLanguage string) error {
+	doc.SourceURL = strings.TrimSpace(sourceURL)
+	if doc.SourceURL != "" {
+		u, err := url.Parse(doc.SourceURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("%w: source_url must be an http or https URL", ErrInvalidMessage)
+		}
+	}
+
+	doc.Language = strings.ToLower(strings.TrimSpace(language))
+	if doc.Language != "" && !languageTag.MatchString(doc.Language) {
+		return fmt.Errorf("%w: language %q is not a BCP 47 language tag", ErrInvalidMessage, language)
+	}
+
+	var err error
+	doc.ContentType, doc.CodeLanguage, err = normalizeContentType(contentType, codeLanguage)
+	return err
+}
+
+// wordCount is the number of whitespace-separated words in text
+func wordCount(text string) int {
+	return len(strings.Fields(text))
+}
+
+// documentSize is the number of bytes a document counts against storage,
+// its uploaded file included
+func documentSize(doc *models.Document) int64 {
+	size := int64(len(doc.Title) + len(doc.Content))
+	if doc.File != nil {
+		size += doc.File.Size
+	}
+	return size
+}
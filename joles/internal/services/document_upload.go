@@ -0,0 +1,174 @@
+package services
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime/multipart"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"lio-ai/internal/extract"
+	"lio-ai/internal/models"
+	"lio-ai/internal/storage"
+)
+
+var (
+	// ErrDocumentUploadsDisabled is returned when no file store is configured
+	ErrDocumentUploadsDisabled = errors.New("document uploads are not enabled")
+	// ErrDocumentFileTooLarge is returned when an uploaded file exceeds the
+	// size limit
+	ErrDocumentFileTooLarge = errors.New("document file too large")
+)
+
+// SetFileStore enables document uploads, keeping the original files in
+// store
+func (s *DocumentService) SetFileStore(store storage.Store, maxBytes int64) {
+	s.files = store
+	s.maxFileBytes = maxBytes
+}
+
+// UploadDocument creates a document from an uploaded PDF, DOCX, text or
+// Markdown file. The text is extracted into the document's content and the
+// original is kept in the file store; both count against userID's storage.
+func (s *DocumentService) UploadDocument(userID string, req *models.UploadDocumentRequest, f *multipart.FileHeader) (*models.DocumentResponse, error) {
+	if s.files == nil {
+		return nil, ErrDocumentUploadsDisabled
+	}
+	if s.maxFileBytes > 0 && f.Size > s.maxFileBytes {
+		return nil, fmt.Errorf("%w: %s exceeds %d bytes", ErrDocumentFileTooLarge, f.Filename, s.maxFileBytes)
+	}
+	format, err := extract.Detect(f.Filename, f.Header.Get("Content-Type"))
+	if err != nil {
+		return nil, err
+	}
+	if req.CollectionID != nil {
+		if _, err := ownedCollection(s.collections, *req.CollectionID, userID); err != nil {
+			return nil, err
+		}
+	}
+
+	file, err := f.Open()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read upload: %w", err)
+	}
+	defer file.Close()
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read upload: %w", err)
+	}
+
+	text, err := extract.Text(format, data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", f.Filename, err)
+	}
+
+	title := strings.TrimSpace(req.Title)
+	if title == "" {
+		title = documentTitle(f.Filename)
+	}
+	doc := &models.Document{
+		UserID:       userID,
+		Title:        title,
+		Content:      text,
+		Tags:         normalizeTags(req.Tags),
+		CollectionID: req.CollectionID,
+		Confidential: req.Confidential,
+		WordCount:    wordCount(text),
+		File: &models.DocumentFile{
+			Name:        filepath.Base(f.Filename),
+			ContentType: format.ContentType(),
+			Size:        int64(len(data)),
+			Backend:     s.files.Name(),
+			Key:         fmt.Sprintf("documents/%s.%s", uuid.New().String(), format),
+		},
+	}
+	// The content is the extracted text, whatever the file's format
+	contentType := models.DocumentContentPlaintext
+	if format == extract.FormatMarkdown {
+		contentType = models.DocumentContentMarkdown
+	}
+	if err := setDocumentMetadata(doc, req.SourceURL, req.Language, contentType, ""); err != nil {
+		return nil, err
+	}
+
+	if s.storage != nil {
+		if err := s.storage.CheckDocument(userID, doc.Content); err != nil {
+			return nil, err
+		}
+		if err := s.storage.Check(userID, documentSize(doc)); err != nil {
+			return nil, err
+		}
+	}
+
+	// Store the file first so a document never references a missing one
+	if err := s.files.Put(doc.File.Key, bytes.NewReader(data), doc.File.Size, doc.File.ContentType); err != nil {
+		return nil, fmt.Errorf("failed to store document file: %w", err)
+	}
+	if err := s.repo.Create(doc); err != nil {
+		s.deleteFile(doc.File)
+		return nil, fmt.Errorf("service error: %w", err)
+	}
+
+	if s.storage != nil {
+		if err := s.storage.Record(userID, models.StorageKindDocument, int64(doc.ID), documentSize(doc)); err != nil {
+			log.Printf("Failed to record storage for document %d: %v", doc.ID, err)
+		}
+	}
+	if s.index != nil {
+		s.index.Enqueue(doc.ID)
+	}
+	s.recordVersion(doc)
+	s.documentEvent(models.WebhookEventDocumentCreated, doc)
+
+	return doc.ToResponse(), nil
+}
+
+// GetDocumentFile opens the original file of an uploaded document owned by
+// userID, returned with the document; the caller must close it
+func (s *DocumentService) GetDocumentFile(id uint, userID string) (*models.DocumentResponse, io.ReadCloser, error) {
+	doc, err := s.ownedDocument(id, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if doc.File == nil {
+		return nil, nil, fmt.Errorf("%w: document %d was not uploaded as a file", ErrNotFound, id)
+	}
+	if s.files == nil {
+		return nil, nil, ErrDocumentUploadsDisabled
+	}
+
+	content, err := s.files.Get(doc.File.Key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read document file: %w", err)
+	}
+	return doc.ToResponse(), content, nil
+}
+
+// deleteFile removes the stored original of a document, logging failures
+func (s *DocumentService) deleteFile(file *models.DocumentFile) {
+	if file == nil || s.files == nil {
+		return
+	}
+	if err := s.files.Delete(file.Key); err != nil {
+		log.Printf("Failed to delete document file %s: %v", file.Key, err)
+	}
+}
+
+// documentTitle derives a title from an uploaded file's name
+func documentTitle(filename string) string {
+	name := filepath.Base(filename)
+	title := strings.TrimSpace(strings.TrimSuffix(name, filepath.Ext(name)))
+	if title == "" {
+		title = name
+	}
+	for len(title) > 255 {
+		_, size := utf8.DecodeLastRuneInString(title)
+		title = title[:len(title)-size]
+	}
+	return title
+}
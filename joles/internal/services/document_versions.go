@@ -0,0 +1,265 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"lio-ai/internal/models"
+	"lio-ai/internal/repositories"
+)
+
+// ErrDiffTooLarge is returned when two versions differ in too many lines
+// to diff
+var ErrDiffTooLarge = errors.New("versions too different to diff")
+
+const (
+	// Versions kept per document; older ones are dropped as edits come in
+	maxDocumentVersions = 100
+	// Lines of unchanged context around each change in a unified diff
+	diffContextLines = 3
+	// Bound on the lines compared after the common start and end are
+	// trimmed, the product of both sides' counts
+	maxDiffCells = 16 << 20
+)
+
+// SetVersionHistory keeps a revision of each document's title and content
+// in versions on every write
+func (s *DocumentService) SetVersionHistory(versions *repositories.DocumentVersionRepository) {
+	s.versions = versions
+}
+
+// recordVersion stores doc as its next version. Failures are logged
+// rather than failing the write.
+func (s *DocumentService) recordVersion(doc *models.Document) {
+	if s.versions == nil {
+		return
+	}
+	if _, err := s.versions.Record(doc, maxDocumentVersions); err != nil {
+		log.Printf("⚠️  Failed to record version of document %d: %v", doc.ID, err)
+	}
+}
+
+// ListVersions retrieves the versions of a document owned by userID,
+// newest first and without their content
+func (s *DocumentService) ListVersions(id uint, userID string) ([]models.DocumentVersion, error) {
+	if _, err := s.ownedDocument(id, userID); err != nil {
+		return nil, err
+	}
+	if s.versions == nil {
+		return []models.DocumentVersion{}, nil
+	}
+	return s.versions.List(id)
+}
+
+// DiffVersions diffs two versions of a document owned by userID line by
+// line. to defaults to the newest version and from to the one before it.
+func (s *DocumentService) DiffVersions(id uint, userID string, from, to int) (*models.DocumentDiff, error) {
+	if _, err := s.ownedDocument(id, userID); err != nil {
+		return nil, err
+	}
+	if s.versions == nil {
+		return nil, fmt.Errorf("%w: document %d has no versions", ErrNotFound, id)
+	}
+
+	if to == 0 {
+		latest, err := s.versions.Latest(id)
+		if err != nil {
+			return nil, err
+		}
+		to = latest
+	}
+	if from == 0 {
+		from = to - 1
+		if from < 1 {
+			from = to
+		}
+	}
+
+	older, err := s.documentVersion(id, from)
+	if err != nil {
+		return nil, err
+	}
+	newer, err := s.documentVersion(id, to)
+	if err != nil {
+		return nil, err
+	}
+
+	ops, err := diffLines(splitLines(older.Content), splitLines(newer.Content))
+	if err != nil {
+		return nil, err
+	}
+	diff := &models.DocumentDiff{DocumentID: id, From: from, To: to}
+	for _, op := range ops {
+		switch op.kind {
+		case '+':
+			diff.Additions++
+		case '-':
+			diff.Deletions++
+		}
+	}
+	if diff.Additions > 0 || diff.Deletions > 0 || older.Title != newer.Title {
+		diff.Unified = fmt.Sprintf("--- %s (v%d)\n+++ %s (v%d)\n", older.Title, older.Version, newer.Title, newer.Version) +
+			unifiedHunks(ops, diffContextLines)
+	}
+	return diff, nil
+}
+
+func (s *DocumentService) documentVersion(id uint, version int) (*models.DocumentVersion, error) {
+	v, err := s.versions.Get(id, version)
+	if err != nil {
+		return nil, err
+	}
+	if v == nil {
+		return nil, fmt.Errorf("%w: version %d of document %d", ErrNotFound, version, id)
+	}
+	return v, nil
+}
+
+// diffOp is one line of a diff: kept (' '), removed ('-') or added ('+')
+type diffOp struct {
+	kind byte
+	line string
+}
+
+// splitLines splits text into lines, without an empty last line for a
+// trailing newline
+func splitLines(text string) []string {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	if text == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
+
+// diffLines computes a minimal line diff from a to b by longest common
+// subsequence, after setting aside the lines they start and end with
+func diffLines(a, b []string) ([]diffOp, error) {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	midA, midB := a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+	n, m := len(midA), len(midB)
+	if (n+1)*(m+1) > maxDiffCells {
+		return nil, fmt.Errorf("%w: %d and %d changed lines", ErrDiffTooLarge, n, m)
+	}
+
+	ops := make([]diffOp, 0, len(a)+len(b)-prefix-suffix)
+	for _, line := range a[:prefix] {
+		ops = append(ops, diffOp{' ', line})
+	}
+
+	// lcs[i*(m+1)+j] is the longest common subsequence of midA[i:] and midB[j:]
+	lcs := make([]int32, (n+1)*(m+1))
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			switch {
+			case midA[i] == midB[j]:
+				lcs[i*(m+1)+j] = lcs[(i+1)*(m+1)+j+1] + 1
+			case lcs[(i+1)*(m+1)+j] >= lcs[i*(m+1)+j+1]:
+				lcs[i*(m+1)+j] = lcs[(i+1)*(m+1)+j]
+			default:
+				lcs[i*(m+1)+j] = lcs[i*(m+1)+j+1]
+			}
+		}
+	}
+	i, j := 0, 0
+	for i < n || j < m {
+		switch {
+		case i < n && j < m && midA[i] == midB[j]:
+			ops = append(ops, diffOp{' ', midA[i]})
+			i++
+			j++
+		case j == m || (i < n && lcs[(i+1)*(m+1)+j] >= lcs[i*(m+1)+j+1]):
+			ops = append(ops, diffOp{'-', midA[i]})
+			i++
+		default:
+			ops = append(ops, diffOp{'+', midB[j]})
+			j++
+		}
+	}
+
+	for _, line := range a[len(a)-suffix:] {
+		ops = append(ops, diffOp{' ', line})
+	}
+	return ops, nil
+}
+
+// unifiedHunks formats a diff as unified diff hunks with context lines of
+// unchanged text around each change
+func unifiedHunks(ops []diffOp, context int) string {
+	// Lines of each side before each op, for the hunk headers
+	lineA, lineB := make([]int, len(ops)+1), make([]int, len(ops)+1)
+	for i, op := range ops {
+		lineA[i+1], lineB[i+1] = lineA[i], lineB[i]
+		if op.kind != '+' {
+			lineA[i+1]++
+		}
+		if op.kind != '-' {
+			lineB[i+1]++
+		}
+	}
+
+	var out strings.Builder
+	for next := 0; next < len(ops); {
+		first := next
+		for first < len(ops) && ops[first].kind == ' ' {
+			first++
+		}
+		if first == len(ops) {
+			break
+		}
+
+		// Changes closer than twice the context share a hunk
+		end := first
+		for i := first; i < len(ops); {
+			if ops[i].kind != ' ' {
+				i++
+				end = i
+				continue
+			}
+			run := i
+			for run < len(ops) && ops[run].kind == ' ' {
+				run++
+			}
+			if run == len(ops) || run-i > 2*context {
+				break
+			}
+			i = run
+		}
+
+		lo, hi := first-context, end+context
+		if lo < next {
+			lo = next
+		}
+		if hi > len(ops) {
+			hi = len(ops)
+		}
+		fmt.Fprintf(&out, "@@ -%s +%s @@\n", hunkRange(lineA[lo], lineA[hi]-lineA[lo]), hunkRange(lineB[lo], lineB[hi]-lineB[lo]))
+		for _, op := range ops[lo:hi] {
+			out.WriteByte(op.kind)
+			out.WriteString(op.line)
+			out.WriteByte('\n')
+		}
+		next = hi
+	}
+	return out.String()
+}
+
+// hunkRange formats the start and length of one side of a hunk; an empty
+// side is numbered by the line it follows
+func hunkRange(before, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", before)
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", before+1)
+	}
+	return fmt.Sprintf("%d,%d", before+1, count)
+}
@@ -0,0 +1,129 @@
+package services
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/google/uuid"
+	"lio-ai/internal/models"
+)
+
+// DuplicateChat copies a chat owned by the user into a new chat with a new
+// UUID, including its settings, messages and attachments. With
+// UpToMessageID set, messages after that one are left out. Pins,
+// bookmarks, feedback and scheduled messages are not copied.
+func (s *ChatService) DuplicateChat(chatID int64, userID string, req *models.DuplicateChatRequest) (*models.ChatWithMessages, error) {
+	source, err := s.repo.GetChatByID(chatID)
+	if err != nil {
+		return nil, err
+	}
+	if source.UserID != userID {
+		return nil, ErrUnauthorized
+	}
+
+	messages, err := s.messagesWithAttachments(chatID)
+	if err != nil {
+		return nil, err
+	}
+	if req.UpToMessageID != 0 {
+		end := -1
+		for i, m := range messages {
+			if m.ID == req.UpToMessageID {
+				end = i
+				break
+			}
+		}
+		if end < 0 {
+			return nil, fmt.Errorf("message not found")
+		}
+		messages = messages[:end+1]
+	}
+
+	title := strings.TrimSpace(req.Title)
+	if title == "" {
+		title = source.Title + " (copy)"
+	}
+	chat := &models.Chat{
+		UserID:          userID,
+		Title:           title,
+		ContextStrategy: source.ContextStrategy,
+		MaxOutputTokens: source.MaxOutputTokens,
+		MaxCostUSD:      source.MaxCostUSD,
+		BudgetUSD:       source.BudgetUSD,
+		BudgetAction:    source.BudgetAction,
+		PersonaID:       source.PersonaID,
+	}
+	if s.storage != nil {
+		var total int64
+		for _, m := range messages {
+			for _, a := range m.Attachments {
+				total += a.SizeBytes
+			}
+		}
+		if err := s.storage.Check(userID, total); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.repo.CopyChat(chat, messages); err != nil {
+		return nil, err
+	}
+
+	if err := s.copyAttachments(chat.ID, userID, messages); err != nil {
+		if delErr := s.DeleteChat(chat.ID, userID); delErr != nil {
+			return nil, fmt.Errorf("%v (and failed to remove the partial copy: %v)", err, delErr)
+		}
+		return nil, err
+	}
+
+	if messages == nil {
+		messages = []models.Message{}
+	}
+	return &models.ChatWithMessages{
+		Chat:     *chat,
+		Messages: messages,
+	}, nil
+}
+
+// copyAttachments stores a copy of each message's attachments under the
+// new chat for userID, so the copies outlive the original chat
+func (s *ChatService) copyAttachments(chatID int64, userID string, messages []models.Message) error {
+	for i := range messages {
+		if len(messages[i].Attachments) == 0 {
+			continue
+		}
+		if s.attachments == nil {
+			return ErrAttachmentsDisabled
+		}
+
+		copies := make([]models.Attachment, 0, len(messages[i].Attachments))
+		for _, a := range messages[i].Attachments {
+			content, err := s.attachments.Get(a.StorageKey)
+			if err != nil {
+				return fmt.Errorf("failed to read attachment: %w", err)
+			}
+			key := fmt.Sprintf("chats/%d/%s%s", chatID, uuid.New().String(), strings.ToLower(filepath.Ext(a.Filename)))
+			err = s.attachments.Put(key, content, a.SizeBytes, a.ContentType)
+			content.Close()
+			if err != nil {
+				return fmt.Errorf("failed to store attachment: %w", err)
+			}
+
+			a.ID = 0
+			a.MessageID = messages[i].ID
+			a.ChatID = chatID
+			a.UserID = userID
+			a.StorageBackend = s.attachments.Name()
+			a.StorageKey = key
+			if err := s.repo.CreateAttachment(&a); err != nil {
+				s.deleteStoredAttachments([]models.Attachment{a})
+				return err
+			}
+			s.recordAttachmentStorage(a)
+			copies = append(copies, a)
+		}
+		messages[i].Attachments = copies
+	}
+	return nil
+}
@@ -0,0 +1,178 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"lio-ai/internal/models"
+	"lio-ai/internal/tokenizer"
+)
+
+// CreateEmbeddings returns vectors for req.Input from the model's provider.
+// Like completions, the user's synced key is used first and a platform key
+// is tried when the provider rejects it. The user's budget is checked and
+// quota for the input reserved first, and each attempt is tracked as an "embedding" request.
+func (s *ChatService) CreateEmbeddings(ctx context.Context, userID string, req *models.EmbeddingRequest) (*models.EmbeddingResponse, error) {
+	if userID == "" {
+		return nil, fmt.Errorf("%w: user_id is required", ErrInvalidMessage)
+	}
+	if len(req.Input) == 0 {
+		return nil, fmt.Errorf("%w: input is required", ErrInvalidMessage)
+	}
+	for _, text := range req.Input {
+		if strings.TrimSpace(text) == "" {
+			return nil, fmt.Errorf("%w: input must not contain empty strings", ErrInvalidMessage)
+		}
+	}
+
+	model, _ := s.resolveModel(req.Model)
+	reservation, err := s.reserveEmbedding(ctx, userID, model, req.Input)
+	if err != nil {
+		return nil, err
+	}
+
+	provider := ProviderForModel(model)
+	keySource := KeySourceUser
+	start := time.Now()
+	resp, err := s.callEmbeddingService(ctx, model, req.Input, userID, "")
+	if err != nil && shouldFailover(err) {
+		if platformKey := s.platformKey(provider); platformKey != "" {
+			s.trackEmbedding(ctx, userID, model, provider, keySource, nil, time.Since(start), err, 0)
+			log.Printf("⚠️  User key for %s failed, retrying embeddings with platform key (user=%s)", provider, userID)
+
+			keySource = KeySourcePlatform
+			start = time.Now()
+			resp, err = s.callEmbeddingService(ctx, model, req.Input, userID, platformKey)
+		}
+	}
+	s.trackEmbedding(ctx, userID, model, provider, keySource, resp, time.Since(start), err, reservation)
+	if err != nil {
+		return nil, err
+	}
+
+	resp.Model = model
+	resp.KeySource = keySource
+	return resp, nil
+}
+
+// reserveEmbedding reserves the user's quota for embedding input, so
+// concurrent requests cannot together overrun the limits. It returns 0
+// when usage is not tracked.
+func (s *ChatService) reserveEmbedding(ctx context.Context, userID, model string, input []string) (int64, error) {
+	if s.usageService == nil || usageExempt(ctx) {
+		return 0, nil
+	}
+	// Embeddings have no cheaper model to throttle to, so only a spent
+	// "block" budget applies
+	if _, err := s.usageService.CheckBudget(userID); err != nil {
+		return 0, err
+	}
+	tokens := 0
+	for _, text := range input {
+		tokens += tokenizer.Count(model, text)
+	}
+	cost, err := s.usageService.CalculateCost(tokens, 0, model)
+	if err != nil {
+		return 0, err
+	}
+	return s.usageService.ReserveQuota(userID, tokens, cost)
+}
+
+// trackEmbedding records an embeddings attempt against the user's quota,
+// settling or releasing reservation when it is set. Embeddings only bill
+// input tokens; exempt traffic is not recorded.
+func (s *ChatService) trackEmbedding(ctx context.Context, userID, model, provider, keySource string, resp *models.EmbeddingResponse, duration time.Duration, callErr error, reservation int64) {
+	if s.usageService == nil || usageExempt(ctx) {
+		return
+	}
+
+	usageReq := &models.UsageRequest{
+		UserID:      userID,
+		RequestType: "embedding",
+		ModelUsed:   model,
+		Endpoint:    "/api/v1/embeddings",
+		Provider:    provider,
+		KeySource:   keySource,
+		DurationMs:  duration.Milliseconds(),
+		Success:     callErr == nil,
+
+		ReservationID: reservation,
+	}
+	if resp != nil {
+		usageReq.TokensInput = resp.Tokens
+	}
+	if callErr != nil {
+		usageReq.ErrorMessage = callErr.Error()
+	}
+
+	if err := s.usageService.TrackUsage(usageReq); err != nil {
+		log.Printf("Failed to track embedding usage: %v", err)
+		s.usageService.RetryUsage(usageReq, err)
+	}
+}
+
+// callEmbeddingService calls the Python AI service for embeddings. When
+// apiKey is set, the backend uses it instead of the user's synced key.
+func (s *ChatService) callEmbeddingService(ctx context.Context, model string, input []string, userID, apiKey string) (*models.EmbeddingResponse, error) {
+	aiServiceURL := s.aiService()
+
+	payload := map[string]interface{}{
+		"model":   model,
+		"input":   input,
+		"user_id": userID,
+	}
+	if apiKey != "" {
+		payload["api_key"] = apiKey
+		payload["key_source"] = KeySourcePlatform
+	}
+
+	payloadBytes, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal payload: %w", err)
+	}
+
+	MarkUpstream(ctx, aiServiceURL+"/api/v1/embeddings", model)
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, aiServiceURL+"/api/v1/embeddings", bytes.NewBuffer(payloadBytes))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create AI request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call AI service: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return nil, &AIServiceError{StatusCode: resp.StatusCode, Body: string(bodyBytes)}
+	}
+
+	var result struct {
+		Data  []models.Embedding `json:"data"`
+		Usage struct {
+			PromptTokens int `json:"prompt_tokens"`
+			TotalTokens  int `json:"total_tokens"`
+		} `json:"usage"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode AI response: %w", err)
+	}
+	if len(result.Data) != len(input) {
+		return nil, fmt.Errorf("AI service returned %d embeddings for %d inputs", len(result.Data), len(input))
+	}
+
+	tokens := result.Usage.PromptTokens
+	if tokens == 0 {
+		tokens = result.Usage.TotalTokens
+	}
+	return &models.EmbeddingResponse{Data: result.Data, Tokens: tokens}, nil
+}
@@ -0,0 +1,192 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+
+	"lio-ai/internal/models"
+	"lio-ai/internal/repositories"
+)
+
+// exemptionTokenPrefix marks exemption tokens, so they are recognizable in
+// logs and secret scanners
+const exemptionTokenPrefix = "lxt_"
+
+// ExemptionService manages the admin list of traffic exempt from rate
+// limiting and usage tracking. The list is kept in memory and reloaded
+// whenever it changes, so matching a request costs no database query.
+type ExemptionService struct {
+	repo *repositories.ExemptionRepository
+
+	mu       sync.RWMutex
+	tokens   []models.RateLimitExemption
+	networks []exemptNetwork
+}
+
+type exemptNetwork struct {
+	network   *net.IPNet
+	exemption models.RateLimitExemption
+}
+
+// NewExemptionService creates a new exemption service; call Load before
+// matching requests
+func NewExemptionService(repo *repositories.ExemptionRepository) *ExemptionService {
+	return &ExemptionService{repo: repo}
+}
+
+// Load reads the exemptions from the database
+func (s *ExemptionService) Load() error {
+	exemptions, err := s.repo.List()
+	if err != nil {
+		return err
+	}
+
+	var tokens []models.RateLimitExemption
+	var networks []exemptNetwork
+	for _, e := range exemptions {
+		switch e.Kind {
+		case models.ExemptionKindToken:
+			tokens = append(tokens, e)
+		case models.ExemptionKindCIDR:
+			if _, network, err := net.ParseCIDR(e.CIDR); err == nil {
+				networks = append(networks, exemptNetwork{network: network, exemption: e})
+			}
+		}
+	}
+
+	s.mu.Lock()
+	s.tokens, s.networks = tokens, networks
+	s.mu.Unlock()
+	return nil
+}
+
+// Create adds an exemption. For token exemptions the token is generated
+// and only returned here; just its hash is stored.
+func (s *ExemptionService) Create(createdBy string, req *models.CreateExemptionRequest) (*models.CreatedExemption, error) {
+	e := &models.RateLimitExemption{Name: req.Name, Kind: req.Kind, CreatedBy: createdBy}
+	var token string
+	switch req.Kind {
+	case models.ExemptionKindCIDR:
+		cidr, err := normalizeCIDR(req.CIDR)
+		if err != nil {
+			return nil, err
+		}
+		e.CIDR = cidr
+	case models.ExemptionKindToken:
+		if req.CIDR != "" {
+			return nil, fmt.Errorf("%w: cidr is only used by cidr exemptions", ErrInvalidMessage)
+		}
+		b := make([]byte, 24)
+		if _, err := rand.Read(b); err != nil {
+			return nil, fmt.Errorf("failed to generate token: %w", err)
+		}
+		token = exemptionTokenPrefix + hex.EncodeToString(b)
+		e.TokenHash = hashExemptionToken(token)
+		e.TokenPrefix = token[:len(exemptionTokenPrefix)+8]
+	default:
+		return nil, fmt.Errorf("%w: kind must be token or cidr", ErrInvalidMessage)
+	}
+
+	if err := s.repo.Create(e); err != nil {
+		return nil, err
+	}
+	if err := s.Load(); err != nil {
+		return nil, err
+	}
+	return &models.CreatedExemption{RateLimitExemption: e, Token: token}, nil
+}
+
+// List returns every exemption
+func (s *ExemptionService) List() ([]models.RateLimitExemption, error) {
+	return s.repo.List()
+}
+
+// Delete removes an exemption; requests it matched are limited and tracked
+// again immediately
+func (s *ExemptionService) Delete(id int64) error {
+	deleted, err := s.repo.Delete(id)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return fmt.Errorf("%w: exemption %d", ErrNotFound, id)
+	}
+	return s.Load()
+}
+
+// Match returns the exemption covering a request that presented token
+// (possibly empty) from ip, or nil
+func (s *ExemptionService) Match(token, ip string) *models.RateLimitExemption {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if strings.HasPrefix(token, exemptionTokenPrefix) {
+		hash := hashExemptionToken(token)
+		for i := range s.tokens {
+			if subtle.ConstantTimeCompare([]byte(hash), []byte(s.tokens[i].TokenHash)) == 1 {
+				e := s.tokens[i]
+				return &e
+			}
+		}
+	}
+	if addr := net.ParseIP(ip); addr != nil {
+		for _, n := range s.networks {
+			if n.network.Contains(addr) {
+				e := n.exemption
+				return &e
+			}
+		}
+	}
+	return nil
+}
+
+// normalizeCIDR parses a CIDR range, or a single address as the range
+// holding just it
+func normalizeCIDR(cidr string) (string, error) {
+	cidr = strings.TrimSpace(cidr)
+	if cidr == "" {
+		return "", fmt.Errorf("%w: cidr is required for cidr exemptions", ErrInvalidMessage)
+	}
+	if !strings.Contains(cidr, "/") {
+		ip := net.ParseIP(cidr)
+		if ip == nil {
+			return "", fmt.Errorf("%w: %q is not an IP address or CIDR range", ErrInvalidMessage, cidr)
+		}
+		if ip.To4() != nil {
+			return ip.String() + "/32", nil
+		}
+		return ip.String() + "/128", nil
+	}
+	_, network, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return "", fmt.Errorf("%w: %q is not an IP address or CIDR range", ErrInvalidMessage, cidr)
+	}
+	return network.String(), nil
+}
+
+func hashExemptionToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+type usageExemptionKey struct{}
+
+// WithUsageExemption marks ctx as carrying exempt traffic, whose
+// completions and embeddings are neither reserved against quotas nor
+// recorded in usage metrics
+func WithUsageExemption(ctx context.Context) context.Context {
+	return context.WithValue(ctx, usageExemptionKey{}, true)
+}
+
+// usageExempt reports whether ctx carries exempt traffic
+func usageExempt(ctx context.Context) bool {
+	exempt, _ := ctx.Value(usageExemptionKey{}).(bool)
+	return exempt
+}
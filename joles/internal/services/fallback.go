@@ -0,0 +1,92 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+)
+
+// SetModelFallbacks enables fallback routing: when a model fails or times
+// out, the models in its chain (or the "*" chain) are tried in order.
+// attemptTimeout bounds each attempt that still has a fallback after it.
+func (s *ChatService) SetModelFallbacks(chains map[string][]string, attemptTimeout time.Duration) {
+	s.fallbacks = chains
+	s.attemptTimeout = attemptTimeout
+}
+
+// fallbackChain lists the models to try for model, starting with model
+// itself. Fallback entries may be aliases.
+func (s *ChatService) fallbackChain(model string) []string {
+	chain := s.fallbacks[model]
+	if chain == nil {
+		chain = s.fallbacks["*"]
+	}
+
+	models := []string{model}
+	seen := map[string]bool{model: true}
+	for _, fallback := range chain {
+		fallback, _ = s.resolveModel(fallback)
+		if !seen[fallback] {
+			seen[fallback] = true
+			models = append(models, fallback)
+		}
+	}
+	return models
+}
+
+// completeWithFallback runs completeWithFailover against each model in the
+// fallback chain, after the other candidates of a routed model, until one
+// succeeds. The model that answered is set on the
+// response; each attempt is tracked under its own model.
+func (s *ChatService) completeWithFallback(ctx context.Context, target completionTarget, messages []map[string]interface{}, extra map[string]interface{}) (*AIServiceResponse, error) {
+	chain := s.fallbackChain(target.Model)
+	if target.Route != "" {
+		chain = s.withRouteCandidates(target.Route, chain, target.ProviderOrder)
+	}
+
+	var resp *AIServiceResponse
+	var err error
+	for i, model := range chain {
+		attempt := target
+		attempt.Model = model
+
+		attemptCtx, cancel := ctx, context.CancelFunc(func() {})
+		last := i == len(chain)-1
+		if !last && s.attemptTimeout > 0 {
+			attemptCtx, cancel = context.WithTimeout(ctx, s.attemptTimeout)
+		}
+		resp, err = s.completeWithFailover(attemptCtx, attempt, messages, extra)
+		cancel()
+
+		if resp != nil {
+			resp.Model = model
+		}
+		if err == nil || last || !shouldFallback(ctx, err) {
+			return resp, err
+		}
+		log.Printf("⚠️  Model %s failed (%v), falling back to %s (chat=%d)", model, err, chain[i+1], target.ChatID)
+	}
+	return resp, err
+}
+
+// shouldFallback reports whether a failed completion is worth retrying on
+// another model: timeouts, transport errors, and provider-side failures.
+// Requests the provider rejected as invalid, and requests cancelled by the
+// caller (e.g. a stopped generation), are not retried.
+func shouldFallback(ctx context.Context, err error) bool {
+	if ctx.Err() != nil || errors.Is(err, ErrQuotaExceeded) {
+		return false
+	}
+	aiErr, ok := IsAIServiceError(err)
+	if !ok {
+		return true
+	}
+	switch aiErr.StatusCode {
+	case http.StatusBadRequest, http.StatusUnprocessableEntity, http.StatusRequestEntityTooLarge:
+		return false
+	default:
+		return aiErr.StatusCode >= 400
+	}
+}
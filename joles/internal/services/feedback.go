@@ -0,0 +1,94 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"lio-ai/internal/models"
+)
+
+// maxFeedbackCommentLength caps the optional comment on a rating
+const maxFeedbackCommentLength = 2000
+
+// ErrInvalidFeedback is returned when a rating fails validation
+var ErrInvalidFeedback = errors.New("invalid feedback")
+
+// SubmitFeedback rates an assistant message in a chat owned by the user.
+// Rating the same message again replaces the earlier rating.
+func (s *ChatService) SubmitFeedback(chatID, messageID int64, userID string, req *models.FeedbackRequest) (*models.MessageFeedback, error) {
+	rating := strings.ToLower(strings.TrimSpace(req.Rating))
+	if rating != models.FeedbackUp && rating != models.FeedbackDown {
+		return nil, fmt.Errorf("%w: rating must be 'up' or 'down'", ErrInvalidFeedback)
+	}
+	comment := strings.TrimSpace(req.Comment)
+	if utf8.RuneCountInString(comment) > maxFeedbackCommentLength {
+		return nil, fmt.Errorf("%w: comment must be at most %d characters", ErrInvalidFeedback, maxFeedbackCommentLength)
+	}
+
+	message, err := s.ownedMessage(chatID, messageID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if message.Role != "assistant" {
+		return nil, fmt.Errorf("%w: only assistant messages can be rated", ErrInvalidFeedback)
+	}
+
+	feedback := &models.MessageFeedback{
+		MessageID: messageID,
+		ChatID:    chatID,
+		UserID:    userID,
+		Rating:    rating,
+		Comment:   comment,
+	}
+	if message.Model != nil {
+		feedback.Model = *message.Model
+	}
+	if err := s.repo.UpsertFeedback(feedback); err != nil {
+		return nil, err
+	}
+	return feedback, nil
+}
+
+// DeleteFeedback withdraws the user's rating of a message
+func (s *ChatService) DeleteFeedback(chatID, messageID int64, userID string) error {
+	if _, err := s.ownedMessage(chatID, messageID, userID); err != nil {
+		return err
+	}
+	deleted, err := s.repo.DeleteFeedback(messageID, userID)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return fmt.Errorf("feedback not found")
+	}
+	return nil
+}
+
+// GetFeedbackStats aggregates ratings in [since, until) per model, and per
+// day when byDay is set
+func (s *ChatService) GetFeedbackStats(since, until time.Time, model string, byDay bool) ([]models.FeedbackStats, error) {
+	return s.repo.GetFeedbackStats(since, until, model, byDay)
+}
+
+// ownedMessage loads a message, checking it belongs to a chat owned by the user
+func (s *ChatService) ownedMessage(chatID, messageID int64, userID string) (*models.Message, error) {
+	chat, err := s.repo.GetChatByID(chatID)
+	if err != nil {
+		return nil, err
+	}
+	if chat.UserID != userID {
+		return nil, ErrUnauthorized
+	}
+
+	message, err := s.repo.GetMessageByID(messageID)
+	if err != nil {
+		return nil, err
+	}
+	if message.ChatID != chatID {
+		return nil, fmt.Errorf("message not found")
+	}
+	return message, nil
+}
@@ -0,0 +1,256 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"lio-ai/internal/models"
+	"lio-ai/internal/repositories"
+)
+
+// ErrInvalidConfig is returned when an imported configuration fails validation
+var ErrInvalidConfig = errors.New("invalid configuration")
+
+// costOperationTypes are the operation types a cost config may price
+var costOperationTypes = map[string]bool{"chat": true, "code_generation": true, "embedding": true}
+
+// GatewayConfigService exports the gateway configuration and imports it
+// into another environment
+type GatewayConfigService struct {
+	repo    *repositories.GatewayConfigRepository
+	aliases *repositories.ModelAliasRepository
+	// Sections set from environment variables: plans, features, routing, policies
+	environment *models.GatewayConfig
+}
+
+// NewGatewayConfigService creates a new gateway config service.
+// environment holds the settings read from environment variables.
+func NewGatewayConfigService(repo *repositories.GatewayConfigRepository, aliases *repositories.ModelAliasRepository, environment *models.GatewayConfig) *GatewayConfigService {
+	return &GatewayConfigService{repo: repo, aliases: aliases, environment: environment}
+}
+
+// Export returns the running configuration
+func (s *GatewayConfigService) Export() (*models.GatewayConfig, error) {
+	costs, err := s.repo.ListCostConfigs()
+	if err != nil {
+		return nil, err
+	}
+	stored, err := s.aliases.List()
+	if err != nil {
+		return nil, err
+	}
+	aliases := make([]models.ModelAliasSetting, len(stored))
+	for i, a := range stored {
+		aliases[i] = models.ModelAliasSetting{Alias: a.Alias, Model: a.Model, Description: a.Description}
+	}
+
+	return &models.GatewayConfig{
+		Format:       models.GatewayConfigFormat,
+		Environment:  s.environment.Environment,
+		ExportedAt:   time.Now().UTC(),
+		CostConfigs:  costs,
+		ModelAliases: aliases,
+		Plans:        s.environment.Plans,
+		Features:     s.environment.Features,
+		Routing:      s.environment.Routing,
+		Policies:     s.environment.Policies,
+	}, nil
+}
+
+// Import validates doc and applies its cost configs and model aliases,
+// returning every difference from the running configuration. The document
+// is authoritative for each section it includes: aliases it leaves out are
+// deleted and cost configs it leaves out are deactivated. Omitted (null)
+// sections are left alone. With dryRun nothing is written.
+func (s *GatewayConfigService) Import(doc *models.GatewayConfig, adminID string, dryRun bool) (*models.ConfigImportResult, error) {
+	if err := validateGatewayConfig(doc); err != nil {
+		return nil, err
+	}
+	current, err := s.Export()
+	if err != nil {
+		return nil, err
+	}
+
+	result := &models.ConfigImportResult{DryRun: dryRun, Changes: []models.ConfigChange{}}
+
+	var costs []models.CostConfigSetting
+	var deactivate []string
+	if doc.CostConfigs != nil {
+		existing := make(map[string]models.CostConfigSetting)
+		for _, c := range current.CostConfigs {
+			existing[c.ModelName] = c
+		}
+		seen := make(map[string]bool)
+		for _, c := range doc.CostConfigs {
+			seen[c.ModelName] = true
+			before, ok := existing[c.ModelName]
+			switch {
+			case !ok:
+				result.Changes = append(result.Changes, models.ConfigChange{Section: "cost_configs", Key: c.ModelName, Action: models.ConfigActionCreate, After: c})
+				costs = append(costs, c)
+			case before != c:
+				result.Changes = append(result.Changes, models.ConfigChange{Section: "cost_configs", Key: c.ModelName, Action: models.ConfigActionUpdate, Before: before, After: c})
+				costs = append(costs, c)
+			}
+		}
+		for _, c := range current.CostConfigs {
+			if !seen[c.ModelName] && c.IsActive {
+				result.Changes = append(result.Changes, models.ConfigChange{Section: "cost_configs", Key: c.ModelName, Action: models.ConfigActionDeactivate, Before: c})
+				deactivate = append(deactivate, c.ModelName)
+			}
+		}
+	}
+
+	var aliases []models.ModelAliasSetting
+	var deleteAliases []string
+	if doc.ModelAliases != nil {
+		existing := make(map[string]models.ModelAliasSetting)
+		for _, a := range current.ModelAliases {
+			existing[a.Alias] = a
+		}
+		seen := make(map[string]bool)
+		for _, a := range doc.ModelAliases {
+			seen[a.Alias] = true
+			before, ok := existing[a.Alias]
+			switch {
+			case !ok:
+				result.Changes = append(result.Changes, models.ConfigChange{Section: "model_aliases", Key: a.Alias, Action: models.ConfigActionCreate, After: a})
+				aliases = append(aliases, a)
+			case before != a:
+				result.Changes = append(result.Changes, models.ConfigChange{Section: "model_aliases", Key: a.Alias, Action: models.ConfigActionUpdate, Before: before, After: a})
+				aliases = append(aliases, a)
+			}
+		}
+		for _, a := range current.ModelAliases {
+			if !seen[a.Alias] {
+				result.Changes = append(result.Changes, models.ConfigChange{Section: "model_aliases", Key: a.Alias, Action: models.ConfigActionDelete, Before: a})
+				deleteAliases = append(deleteAliases, a.Alias)
+			}
+		}
+	}
+
+	if doc.Plans != nil {
+		result.Changes = append(result.Changes, diffEnvSection("plans", current.Plans, doc.Plans)...)
+	}
+	if doc.Features != nil {
+		result.Changes = append(result.Changes, diffEnvSection("features", current.Features, doc.Features)...)
+	}
+	if doc.Policies != nil {
+		result.Changes = append(result.Changes, diffEnvSection("policies", current.Policies, doc.Policies)...)
+	}
+	if doc.Routing != nil {
+		result.Changes = append(result.Changes, diffEnvSection("routing.fallbacks", current.Routing.Fallbacks, doc.Routing.Fallbacks)...)
+		result.Changes = append(result.Changes, diffEnvSection("routing.routes", current.Routing.Routes, doc.Routing.Routes)...)
+		if doc.Routing.AttemptTimeoutSeconds != current.Routing.AttemptTimeoutSeconds {
+			result.Changes = append(result.Changes, models.ConfigChange{
+				Section: "routing", Key: "attempt_timeout_seconds", Action: models.ConfigActionUpdate,
+				Before: current.Routing.AttemptTimeoutSeconds, After: doc.Routing.AttemptTimeoutSeconds, RequiresEnv: true,
+			})
+		}
+	}
+
+	applied := len(costs) + len(deactivate) + len(aliases) + len(deleteAliases)
+	if dryRun || applied == 0 {
+		return result, nil
+	}
+	if err := s.repo.Apply(costs, deactivate, aliases, deleteAliases, adminID); err != nil {
+		return nil, err
+	}
+	result.Applied = applied
+	return result, nil
+}
+
+// diffEnvSection compares a section set from environment variables; its
+// changes are reported for the operator to make, never applied
+func diffEnvSection[V any](section string, current, next map[string]V) []models.ConfigChange {
+	keys := make([]string, 0, len(current)+len(next))
+	for k := range current {
+		keys = append(keys, k)
+	}
+	for k := range next {
+		if _, ok := current[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	var changes []models.ConfigChange
+	for _, k := range keys {
+		before, had := current[k]
+		after, has := next[k]
+		change := models.ConfigChange{Section: section, Key: k, RequiresEnv: true}
+		switch {
+		case !had:
+			change.Action, change.After = models.ConfigActionCreate, after
+		case !has:
+			change.Action, change.Before = models.ConfigActionDelete, before
+		case !reflect.DeepEqual(before, after):
+			change.Action, change.Before, change.After = models.ConfigActionUpdate, before, after
+		default:
+			continue
+		}
+		changes = append(changes, change)
+	}
+	return changes
+}
+
+// validateGatewayConfig checks an imported document before anything is
+// compared or written, normalizing alias names to lower case
+func validateGatewayConfig(doc *models.GatewayConfig) error {
+	if doc.Format != models.GatewayConfigFormat {
+		return fmt.Errorf("%w: unsupported format %d (expected %d)", ErrInvalidConfig, doc.Format, models.GatewayConfigFormat)
+	}
+
+	seen := make(map[string]bool)
+	for i, c := range doc.CostConfigs {
+		name := strings.TrimSpace(c.ModelName)
+		if name == "" {
+			return fmt.Errorf("%w: cost_configs[%d]: model_name is required", ErrInvalidConfig, i)
+		}
+		if seen[name] {
+			return fmt.Errorf("%w: cost_configs: duplicate model %q", ErrInvalidConfig, name)
+		}
+		seen[name] = true
+		if c.CostPerInputToken < 0 || c.CostPerOutputToken < 0 {
+			return fmt.Errorf("%w: cost_configs[%d]: costs cannot be negative", ErrInvalidConfig, i)
+		}
+		if !costOperationTypes[c.OperationType] {
+			return fmt.Errorf("%w: cost_configs[%d]: operation_type must be chat, code_generation or embedding", ErrInvalidConfig, i)
+		}
+		doc.CostConfigs[i].ModelName = name
+	}
+
+	aliases := make(map[string]bool)
+	for i := range doc.ModelAliases {
+		a := &doc.ModelAliases[i]
+		a.Alias = strings.ToLower(strings.TrimSpace(a.Alias))
+		a.Model = strings.TrimSpace(a.Model)
+		if !aliasNamePattern.MatchString(a.Alias) {
+			return fmt.Errorf("%w: model_aliases[%d]: alias must be 1-64 lowercase letters, digits, '.', '_' or '-'", ErrInvalidConfig, i)
+		}
+		if a.Model == "" {
+			return fmt.Errorf("%w: model_aliases[%d]: model is required", ErrInvalidConfig, i)
+		}
+		if aliases[a.Alias] {
+			return fmt.Errorf("%w: model_aliases: duplicate alias %q", ErrInvalidConfig, a.Alias)
+		}
+		aliases[a.Alias] = true
+	}
+	// Aliases resolve in a single step, so none may point at another
+	for i, a := range doc.ModelAliases {
+		if aliases[strings.ToLower(a.Model)] {
+			return fmt.Errorf("%w: model_aliases[%d]: %q points at another alias", ErrInvalidConfig, i, a.Alias)
+		}
+	}
+
+	for name, plan := range doc.Plans {
+		if plan.StorageLimitBytes < 0 {
+			return fmt.Errorf("%w: plans.%s: storage_limit_bytes cannot be negative", ErrInvalidConfig, name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package services
+
+import (
+	"errors"
+
+	"lio-ai/internal/models"
+	"lio-ai/internal/tokenizer"
+)
+
+// ErrGuestQuotaExceeded is returned once a guest session has used its quota
+var ErrGuestQuotaExceeded = errors.New("guest quota exceeded")
+
+// GuestService applies the reduced quota guest sessions run under. Guests
+// are tracked like users, under their guest ID, so usage and chats work
+// unchanged; only the limits differ.
+type GuestService struct {
+	usage             *UsageService
+	dailyTokenLimit   int
+	dailyCostLimitUSD float64
+}
+
+// NewGuestService creates a guest service with the per-session daily limits
+func NewGuestService(usage *UsageService, dailyTokenLimit int, dailyCostLimitUSD float64) *GuestService {
+	return &GuestService{
+		usage:             usage,
+		dailyTokenLimit:   dailyTokenLimit,
+		dailyCostLimitUSD: dailyCostLimitUSD,
+	}
+}
+
+// Admit checks that the guest can afford a completion of message on
+// model. A guest's quota is created by the first completion it sends, and
+// lowered to the guest limits here on the next; until then the message is
+// checked against the guest limits alone, so requests that are admitted
+// but never sent leave no quota behind.
+func (s *GuestService) Admit(guestID, model, message string) error {
+	if model == "" {
+		model = "default"
+	}
+	tokens := tokenizer.Count(model, message)
+
+	exists, err := s.usage.HasQuota(guestID)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		cost, err := s.usage.CalculateCost(tokens/2, tokens/2, model)
+		if err != nil {
+			return err
+		}
+		if tokens > s.dailyTokenLimit || cost > s.dailyCostLimitUSD {
+			return ErrGuestQuotaExceeded
+		}
+		return nil
+	}
+
+	status, err := s.usage.GetQuotaStatus(guestID)
+	if err != nil {
+		return err
+	}
+	if status.DailyTokenLimit != s.dailyTokenLimit || status.DailyCostLimitUSD != s.dailyCostLimitUSD {
+		if err := s.usage.UpdateQuota(guestID, &models.QuotaUpdateRequest{
+			DailyTokenLimit:   &s.dailyTokenLimit,
+			DailyCostLimitUSD: &s.dailyCostLimitUSD,
+		}); err != nil {
+			return err
+		}
+	}
+
+	ok, err := s.usage.CheckQuota(guestID, tokens, model)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrGuestQuotaExceeded
+	}
+	return nil
+}
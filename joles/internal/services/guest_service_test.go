@@ -0,0 +1,67 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"lio-ai/internal/repositories"
+)
+
+func TestGuestAdmitCreatesNoQuotaUntilAMessageIsSent(t *testing.T) {
+	conn := newTestDB(t)
+	usage := NewUsageService(repositories.NewUsageRepository(conn))
+	guests := NewGuestService(usage, 50, 1)
+
+	for i := 0; i < 3; i++ {
+		if err := guests.Admit("guest_a", "gpt-4", "hello there"); err != nil {
+			t.Fatalf("Admit: %v", err)
+		}
+	}
+	var quotas int
+	if err := conn.QueryRow("SELECT COUNT(*) FROM user_quotas").Scan(&quotas); err != nil {
+		t.Fatalf("count quotas: %v", err)
+	}
+	if quotas != 0 {
+		t.Errorf("Admit created %d quotas before any message was sent, want 0", quotas)
+	}
+
+	// A message over the guest limits is refused even without a quota
+	if err := guests.Admit("guest_a", "gpt-4", strings.Repeat("word ", 100)); !errors.Is(err, ErrGuestQuotaExceeded) {
+		t.Errorf("Admit of a message over the limit: error = %v, want ErrGuestQuotaExceeded", err)
+	}
+}
+
+func TestGuestAdmitAppliesGuestLimitsOnceSent(t *testing.T) {
+	conn := newTestDB(t)
+	usage := NewUsageService(repositories.NewUsageRepository(conn))
+	guests := NewGuestService(usage, 50, 1)
+
+	// Sending the first message reserves quota, creating the guest's with
+	// the default limits
+	id, err := usage.ReserveQuota("guest_a", 10, 0)
+	if err != nil {
+		t.Fatalf("ReserveQuota: %v", err)
+	}
+	if err := usage.ReleaseReservation(id); err != nil {
+		t.Fatalf("ReleaseReservation: %v", err)
+	}
+
+	if err := guests.Admit("guest_a", "gpt-4", "hello there"); err != nil {
+		t.Fatalf("Admit: %v", err)
+	}
+	status, err := usage.GetQuotaStatus("guest_a")
+	if err != nil {
+		t.Fatalf("GetQuotaStatus: %v", err)
+	}
+	if status.DailyTokenLimit != 50 || status.DailyCostLimitUSD != 1 {
+		t.Errorf("guest limits = %d tokens, $%.2f, want 50 tokens, $1.00", status.DailyTokenLimit, status.DailyCostLimitUSD)
+	}
+
+	if err := usage.usageRepo.UpdateQuotaUsage("guest_a", 45, 0); err != nil {
+		t.Fatalf("UpdateQuotaUsage: %v", err)
+	}
+	if err := guests.Admit("guest_a", "gpt-4", "hello there, how are you doing today?"); !errors.Is(err, ErrGuestQuotaExceeded) {
+		t.Errorf("Admit past the guest's daily tokens: error = %v, want ErrGuestQuotaExceeded", err)
+	}
+}
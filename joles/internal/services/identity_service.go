@@ -0,0 +1,77 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+
+	"lio-ai/internal/models"
+	"lio-ai/internal/repositories"
+)
+
+// ErrSameAccount is returned when merging an account into itself
+var ErrSameAccount = errors.New("cannot merge an account into itself")
+
+// IdentityService merges duplicate accounts of one person into the
+// account they keep using
+type IdentityService struct {
+	repo  *repositories.IdentityRepository
+	users *repositories.UserRepository
+}
+
+// NewIdentityService creates an identity service
+func NewIdentityService(repo *repositories.IdentityRepository, users *repositories.UserRepository) *IdentityService {
+	return &IdentityService{repo: repo, users: users}
+}
+
+// MergeUsers merges sourceID into targetID on an admin's behalf. The
+// source account is deactivated and everything it owned moves to the
+// target.
+func (s *IdentityService) MergeUsers(sourceID, targetID int64, adminID string) (*models.MergeReport, error) {
+	if sourceID == targetID {
+		return nil, ErrSameAccount
+	}
+	source, err := s.users.FindByID(sourceID)
+	if err != nil {
+		return nil, err
+	}
+	target, err := s.users.FindByID(targetID)
+	if err != nil {
+		return nil, err
+	}
+	if source == nil || target == nil {
+		return nil, ErrUserNotFound
+	}
+	return s.merge(source, targetID, adminID, models.MergeMethodAdmin)
+}
+
+// LinkAccount merges the account with email into targetID, the logged-in
+// user, once they prove they own it with its password
+func (s *IdentityService) LinkAccount(targetID int64, email, password string) (*models.MergeReport, error) {
+	source, err := s.users.FindByEmail(email)
+	if err != nil {
+		return nil, err
+	}
+	if source == nil || s.users.VerifyPassword(source, password) != nil {
+		return nil, ErrInvalidCredentials
+	}
+	if source.ID == targetID {
+		return nil, ErrSameAccount
+	}
+	return s.merge(source, targetID, fmt.Sprint(targetID), models.MergeMethodLink)
+}
+
+// ListMerges returns the merge audit trail, newest first
+func (s *IdentityService) ListMerges(limit, offset int) ([]models.IdentityMerge, int, error) {
+	return s.repo.List(limit, offset)
+}
+
+func (s *IdentityService) merge(source *models.User, targetID int64, mergedBy, method string) (*models.MergeReport, error) {
+	report, err := s.repo.Merge(source, targetID, mergedBy, method)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("[AUDIT] Account %d (%s) merged into %d by %s: %d chats, %d documents, %d provider keys, %d usage records",
+		source.ID, source.Email, targetID, mergedBy, report.Chats, report.Documents, report.ProviderKeys, report.UsageRecords)
+	return report, nil
+}
@@ -0,0 +1,217 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"lio-ai/internal/models"
+	"lio-ai/internal/repositories"
+)
+
+const (
+	// Days of history the status page covers
+	statusWindowDays = 30
+	// Incidents listed on the status page
+	statusRecentIncidents = 10
+)
+
+// IncidentService records gateway restarts, backend outages and degraded
+// models as incidents, and summarizes them for the status page
+type IncidentService struct {
+	repo *repositories.IncidentRepository
+}
+
+// NewIncidentService creates an incident service
+func NewIncidentService(repo *repositories.IncidentRepository) *IncidentService {
+	return &IncidentService{repo: repo}
+}
+
+// Start records this start of the gateway, as a restart when it ran
+// before, and updates the heartbeat on an interval until the process
+// exits. The downtime of a restart runs from the last heartbeat, so it is
+// accurate to about one interval.
+func (s *IncidentService) Start(interval time.Duration) {
+	if err := s.recordStart(); err != nil {
+		log.Printf("Failed to record gateway start: %v", err)
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			if err := s.repo.Heartbeat(time.Now()); err != nil {
+				log.Printf("%v", err)
+			}
+		}
+	}()
+}
+
+func (s *IncidentService) recordStart() error {
+	now := time.Now()
+	lastSeen, err := s.repo.LastHeartbeat()
+	if err != nil {
+		return err
+	}
+	if lastSeen != nil {
+		// Whatever was ongoing when the previous process stopped is
+		// recorded as ending then; probes reopen what is still down
+		if _, err := s.repo.ResolveAllOpen(*lastSeen); err != nil {
+			return err
+		}
+		restart := &models.Incident{
+			Component:  models.IncidentComponentGateway,
+			Kind:       models.IncidentKindRestart,
+			Detail:     fmt.Sprintf("gateway restarted after %s down", now.Sub(*lastSeen).Round(time.Second)),
+			StartedAt:  *lastSeen,
+			ResolvedAt: &now,
+		}
+		if err := s.repo.Create(restart); err != nil {
+			return err
+		}
+	}
+	return s.repo.StartHeartbeat(now)
+}
+
+// Open records the start of an incident on a component. Repeated reports
+// while it is ongoing are ignored; failures are logged.
+func (s *IncidentService) Open(component, kind, detail string) {
+	opened, err := s.repo.Open(component, kind, detail, time.Now())
+	if err != nil {
+		log.Printf("Failed to record incident on %s: %v", component, err)
+		return
+	}
+	if opened {
+		log.Printf("🚨 Incident opened: %s %s (%s)", component, kind, detail)
+	}
+}
+
+// Resolve records the end of a component's ongoing incident of kind
+func (s *IncidentService) Resolve(component, kind string) {
+	resolved, err := s.repo.Resolve(component, kind, time.Now())
+	if err != nil {
+		log.Printf("Failed to resolve incident on %s: %v", component, err)
+		return
+	}
+	if resolved {
+		log.Printf("✓ Incident resolved: %s %s", component, kind)
+	}
+}
+
+// ListIncidents returns incident history, newest first
+func (s *IncidentService) ListIncidents(filter models.IncidentFilter) ([]models.Incident, int, error) {
+	return s.repo.List(filter)
+}
+
+// Status summarizes the last statusWindowDays for the status page:
+// each component's current state, uptime and mean time to recovery, and
+// the most recent incidents. Incident details are left out.
+func (s *IncidentService) Status() (*models.StatusPage, error) {
+	now := time.Now()
+	since := now.AddDate(0, 0, -statusWindowDays)
+	incidents, _, err := s.repo.List(models.IncidentFilter{Since: since})
+	if err != nil {
+		return nil, err
+	}
+
+	type tally struct {
+		status    string
+		downtime  time.Duration
+		recovery  time.Duration
+		resolved  int
+		incidents int
+	}
+	tallies := map[string]*tally{
+		models.IncidentComponentGateway: {status: models.ComponentOperational},
+		models.IncidentComponentBackend: {status: models.ComponentOperational},
+	}
+	for _, i := range incidents {
+		t := tallies[i.Component]
+		if t == nil {
+			t = &tally{status: models.ComponentOperational}
+			tallies[i.Component] = t
+		}
+		t.incidents++
+
+		end := now
+		if i.ResolvedAt != nil {
+			end = *i.ResolvedAt
+			t.recovery += end.Sub(i.StartedAt)
+			t.resolved++
+		} else {
+			t.status = worseStatus(t.status, componentStatusFor(i.Kind))
+		}
+		if i.Kind != models.IncidentKindDegraded {
+			start := i.StartedAt
+			if start.Before(since) {
+				start = since
+			}
+			t.downtime += end.Sub(start)
+		}
+	}
+
+	window := now.Sub(since)
+	page := &models.StatusPage{
+		Status:      models.ComponentOperational,
+		WindowDays:  statusWindowDays,
+		Components:  make([]models.ComponentStatus, 0, len(tallies)),
+		GeneratedAt: now.UTC(),
+	}
+	for name, t := range tallies {
+		component := models.ComponentStatus{
+			Name:          name,
+			Status:        t.status,
+			UptimePercent: 100 * (1 - t.downtime.Seconds()/window.Seconds()),
+			Incidents:     t.incidents,
+		}
+		if component.UptimePercent < 0 {
+			component.UptimePercent = 0
+		}
+		if t.resolved > 0 {
+			component.MTTRSeconds = t.recovery.Seconds() / float64(t.resolved)
+		}
+		page.Components = append(page.Components, component)
+		page.Status = worseStatus(page.Status, t.status)
+	}
+	// The gateway and backend first, then models by name
+	sort.Slice(page.Components, func(i, j int) bool {
+		mi := strings.HasPrefix(page.Components[i].Name, models.IncidentComponentModelPrefix)
+		mj := strings.HasPrefix(page.Components[j].Name, models.IncidentComponentModelPrefix)
+		if mi != mj {
+			return mj
+		}
+		return page.Components[i].Name < page.Components[j].Name
+	})
+
+	if len(incidents) > statusRecentIncidents {
+		incidents = incidents[:statusRecentIncidents]
+	}
+	for i := range incidents {
+		incidents[i].Detail = ""
+	}
+	page.Incidents = incidents
+	return page, nil
+}
+
+// componentStatusFor is the state an ongoing incident of kind puts its
+// component in
+func componentStatusFor(kind string) string {
+	if kind == models.IncidentKindDegraded {
+		return models.ComponentDegraded
+	}
+	return models.ComponentOutage
+}
+
+// worseStatus returns the more severe of two component states
+func worseStatus(a, b string) string {
+	rank := map[string]int{
+		models.ComponentOperational: 0,
+		models.ComponentDegraded:    1,
+		models.ComponentOutage:      2,
+	}
+	if rank[b] > rank[a] {
+		return b
+	}
+	return a
+}
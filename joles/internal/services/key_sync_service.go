@@ -0,0 +1,165 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"lio-ai/internal/models"
+	"lio-ai/internal/repositories"
+)
+
+// Retry backoff bounds for queued key syncs
+const (
+	keySyncBaseBackoff = 5 * time.Second
+	keySyncMaxBackoff  = 5 * time.Minute
+)
+
+// KeySyncService pushes users' provider keys to the Python backend and
+// queues syncs that fail while the backend is unreachable
+type KeySyncService struct {
+	keyRepo    *repositories.ProviderKeyRepository
+	queueRepo  *repositories.SyncQueueRepository
+	backendURL string
+	client     *http.Client
+}
+
+// NewKeySyncService creates a new key sync service
+func NewKeySyncService(keyRepo *repositories.ProviderKeyRepository, queueRepo *repositories.SyncQueueRepository, backendURL string) *KeySyncService {
+	return &KeySyncService{
+		keyRepo:    keyRepo,
+		queueRepo:  queueRepo,
+		backendURL: backendURL,
+		client:     &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Sync triggers a sync for a user in the background, queueing it on failure
+func (s *KeySyncService) Sync(userID string) {
+	go func() {
+		if err := s.syncUser(userID); err != nil {
+			log.Printf("Failed to sync API keys to backend, queued for retry (user=%s): %v", userID, err)
+			if qerr := s.queueRepo.Enqueue(userID, err.Error()); qerr != nil {
+				log.Printf("Failed to queue key sync: %v", qerr)
+			}
+			return
+		}
+		log.Printf("✓ API keys synced to Python backend for user %s", userID)
+	}()
+}
+
+// Pending returns the syncs currently waiting for the backend
+func (s *KeySyncService) Pending() ([]models.PendingKeySync, error) {
+	return s.queueRepo.GetAll()
+}
+
+// Start runs the retry worker until the process exits
+func (s *KeySyncService) Start(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			s.processQueue()
+		}
+	}()
+}
+
+// processQueue retries due syncs once the backend reports healthy
+func (s *KeySyncService) processQueue() {
+	due, err := s.queueRepo.GetDue(50)
+	if err != nil {
+		log.Printf("Failed to read key sync queue: %v", err)
+		return
+	}
+	if len(due) == 0 || !s.backendHealthy() {
+		return
+	}
+
+	for _, pending := range due {
+		if err := s.syncUser(pending.UserID); err != nil {
+			next := time.Now().Add(keySyncBackoff(pending.Attempts))
+			if merr := s.queueRepo.MarkFailed(pending.ID, err.Error(), next); merr != nil {
+				log.Printf("Failed to update key sync queue: %v", merr)
+			}
+			continue
+		}
+		if err := s.queueRepo.Delete(pending.ID); err != nil {
+			log.Printf("Failed to remove delivered key sync: %v", err)
+		}
+		log.Printf("✓ Queued API key sync delivered for user %s", pending.UserID)
+	}
+}
+
+// backendHealthy probes the backend health endpoint
+func (s *KeySyncService) backendHealthy() bool {
+	resp, err := s.client.Get(s.backendURL + "/health")
+	if err != nil {
+		return false
+	}
+	defer resp.Body.Close()
+	return resp.StatusCode == http.StatusOK
+}
+
+// syncUser sends all of a user's active API keys to the Python backend
+func (s *KeySyncService) syncUser(userID string) error {
+	keyResponses, err := s.keyRepo.GetAllByUser(userID)
+	if err != nil {
+		return fmt.Errorf("failed to fetch API keys: %w", err)
+	}
+
+	// Build API keys map - need to fetch decrypted keys
+	apiKeys := make(map[string]string)
+	for _, keyResp := range keyResponses {
+		if !keyResp.IsActive {
+			continue
+		}
+		fullKey, err := s.keyRepo.GetByUserAndProvider(userID, keyResp.Provider)
+		if err != nil {
+			log.Printf("Failed to fetch key for %s: %v", keyResp.Provider, err)
+			continue
+		}
+		if fullKey != nil {
+			apiKeys[fullKey.Provider] = fullKey.APIKey
+		}
+	}
+
+	payload := map[string]interface{}{
+		"user_id":  userID,
+		"api_keys": apiKeys,
+	}
+
+	jsonData, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal payload: %w", err)
+	}
+
+	resp, err := s.client.Post(
+		s.backendURL+"/api/v1/models/sync-keys",
+		"application/json",
+		bytes.NewBuffer(jsonData),
+	)
+	if err != nil {
+		return fmt.Errorf("backend unreachable: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("backend returned HTTP %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// keySyncBackoff returns the exponential retry delay after a number of attempts
+func keySyncBackoff(attempts int) time.Duration {
+	delay := keySyncBaseBackoff
+	for i := 1; i < attempts && delay < keySyncMaxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > keySyncMaxBackoff {
+		delay = keySyncMaxBackoff
+	}
+	return delay
+}
@@ -0,0 +1,206 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ProviderHeader pins a completion to one provider of a routed model
+const ProviderHeader = "X-Provider"
+
+// ErrProviderNotRouted is returned when a pinned provider cannot serve the model
+var ErrProviderNotRouted = errors.New("provider cannot serve the requested model")
+
+// RouteStatus is the candidate a logical model is currently routed to
+type RouteStatus struct {
+	Route      string   `json:"route"`
+	Current    string   `json:"current,omitempty"` // Empty until the route has been used
+	Candidates []string `json:"candidates"`
+}
+
+// SetLatencyRouting enables model health tracking and, for the logical
+// models in routes, routing to the fastest healthy candidate. A candidate
+// must be faster than the current one by the hysteresis fraction before
+// traffic switches to it.
+func (s *ChatService) SetLatencyRouting(health *ModelHealth, routes map[string][]string, hysteresis float64) {
+	s.health = health
+	s.routes = routes
+	s.hysteresis = hysteresis
+	s.routeCurrent = make(map[string]string)
+}
+
+// ModelHealth returns the health tracker, or nil when it is not enabled
+func (s *ChatService) ModelHealth() *ModelHealth {
+	return s.health
+}
+
+// RouteStatuses lists each routed model and the candidate it uses now
+func (s *ChatService) RouteStatuses() []RouteStatus {
+	s.routeMu.Lock()
+	defer s.routeMu.Unlock()
+
+	statuses := make([]RouteStatus, 0, len(s.routes))
+	for route, candidates := range s.routes {
+		statuses = append(statuses, RouteStatus{Route: route, Current: s.routeCurrent[route], Candidates: candidates})
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Route < statuses[j].Route })
+	return statuses
+}
+
+// routeModel picks the concrete model for a routed logical model and
+// returns the route it came from; other models are returned unchanged.
+// With provider set, the candidate from that provider is used regardless
+// of latency. Otherwise the healthy candidate from the provider earliest
+// in order wins, and latency decides when none of them is healthy.
+func (s *ChatService) routeModel(model, provider string, order []string) (string, string, error) {
+	provider = strings.ToLower(strings.TrimSpace(provider))
+	candidates := s.routes[model]
+	if candidates == nil {
+		if provider != "" && ProviderForModel(model) != provider {
+			return "", "", fmt.Errorf("%w: %s is not served by %s", ErrProviderNotRouted, model, provider)
+		}
+		return model, "", nil
+	}
+
+	if provider != "" {
+		for _, candidate := range candidates {
+			if ProviderForModel(candidate) == provider {
+				return candidate, model, nil
+			}
+		}
+		return "", "", fmt.Errorf("%w: %s has no %s candidate", ErrProviderNotRouted, model, provider)
+	}
+	if preferred := s.preferredCandidate(candidates, order); preferred != "" {
+		return preferred, model, nil
+	}
+
+	s.routeMu.Lock()
+	defer s.routeMu.Unlock()
+
+	current := s.routeCurrent[model]
+	next := s.fastestCandidate(current, candidates)
+	if next != current {
+		if current != "" {
+			log.Printf("🔀 Routing %s to %s (was %s)", model, next, current)
+		}
+		s.routeCurrent[model] = next
+	}
+	return next, model, nil
+}
+
+// preferredCandidate returns the first healthy candidate in provider
+// order, or "" when no preferred provider has one
+func (s *ChatService) preferredCandidate(candidates, order []string) string {
+	for _, provider := range order {
+		for _, candidate := range candidates {
+			if ProviderForModel(candidate) == provider && s.health.Healthy(candidate) {
+				return candidate
+			}
+		}
+	}
+	return ""
+}
+
+// providerRank is the position of model's provider in order, with
+// unlisted providers after all listed ones
+func providerRank(model string, order []string) int {
+	provider := ProviderForModel(model)
+	for i, p := range order {
+		if p == provider {
+			return i
+		}
+	}
+	return len(order)
+}
+
+// routedProvider is the provider that served model for a route, or ""
+// when the request was not routed
+func routedProvider(route, model string) string {
+	if route == "" {
+		return ""
+	}
+	return ProviderForModel(model)
+}
+
+// fastestCandidate chooses among the healthy candidates, falling back to
+// all of them when none is healthy. Candidates without a latency sample
+// are tried first so every candidate gets measured. The current candidate
+// is kept unless another is faster by more than the hysteresis.
+func (s *ChatService) fastestCandidate(current string, candidates []string) string {
+	healthy := make([]string, 0, len(candidates))
+	for _, candidate := range candidates {
+		if s.health.Healthy(candidate) {
+			healthy = append(healthy, candidate)
+		}
+	}
+	if len(healthy) == 0 {
+		healthy = candidates
+	}
+
+	best, bestLatency := "", 0.0
+	for _, candidate := range healthy {
+		latency, ok := s.health.Latency(candidate)
+		if !ok {
+			return candidate
+		}
+		if best == "" || latency < bestLatency {
+			best, bestLatency = candidate, latency
+		}
+	}
+
+	if current != "" && current != best && s.health.Healthy(current) {
+		if latency, ok := s.health.Latency(current); ok && bestLatency >= latency*(1-s.hysteresis) {
+			return current
+		}
+	}
+	return best
+}
+
+// withRouteCandidates adds the route's other candidates, healthy ones
+// first, then by provider order and latency, after the chosen model in a
+// fallback chain
+func (s *ChatService) withRouteCandidates(route string, chain, order []string) []string {
+	var others []string
+	for _, candidate := range s.routes[route] {
+		if candidate != chain[0] {
+			others = append(others, candidate)
+		}
+	}
+	sort.SliceStable(others, func(i, j int) bool {
+		hi, hj := s.health.Healthy(others[i]), s.health.Healthy(others[j])
+		if hi != hj {
+			return hi
+		}
+		if ri, rj := providerRank(others[i], order), providerRank(others[j], order); ri != rj {
+			return ri < rj
+		}
+		li, oki := s.health.Latency(others[i])
+		lj, okj := s.health.Latency(others[j])
+		return oki && (!okj || li < lj)
+	})
+
+	seen := map[string]bool{chain[0]: true}
+	result := []string{chain[0]}
+	for _, model := range append(others, chain[1:]...) {
+		if !seen[model] {
+			seen[model] = true
+			result = append(result, model)
+		}
+	}
+	return result
+}
+
+// observeModel feeds a completion's outcome into model health. Requests
+// cancelled by the caller or rejected as invalid say nothing about the
+// model's health and are skipped.
+func (s *ChatService) observeModel(ctx context.Context, model string, duration time.Duration, err error) {
+	if s.health == nil || (err != nil && !shouldFallback(ctx, err)) {
+		return
+	}
+	s.health.Observe(model, duration, err)
+}
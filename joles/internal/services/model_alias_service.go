@@ -0,0 +1,114 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+
+	"lio-ai/internal/models"
+	"lio-ai/internal/repositories"
+)
+
+// ErrInvalidModelAlias is returned when an alias or its target fails validation
+var ErrInvalidModelAlias = errors.New("invalid model alias")
+
+// aliasNamePattern keeps alias names distinguishable from provider/model names
+var aliasNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]{0,63}$`)
+
+// ModelAliasService resolves and manages model aliases. Aliases are read
+// from the database on every request, so repointing one shifts traffic on
+// all instances at once.
+type ModelAliasService struct {
+	repo *repositories.ModelAliasRepository
+}
+
+// NewModelAliasService creates a new model alias service
+func NewModelAliasService(repo *repositories.ModelAliasRepository) *ModelAliasService {
+	return &ModelAliasService{repo: repo}
+}
+
+// Resolve returns the concrete model for model and the alias it was
+// reached through, if any. Unknown names are returned unchanged; lookup
+// errors are logged and also fall back to the name as given.
+func (s *ModelAliasService) Resolve(model string) (concrete, alias string) {
+	name := strings.ToLower(strings.TrimSpace(model))
+	if name == "" {
+		return model, ""
+	}
+	a, err := s.repo.Get(name)
+	if err != nil {
+		log.Printf("Failed to resolve model alias %q: %v", name, err)
+		return model, ""
+	}
+	if a == nil {
+		return model, ""
+	}
+	return a.Model, a.Alias
+}
+
+// SetAlias creates an alias or repoints an existing one. Aliases cannot
+// point at other aliases, so resolution is always a single step.
+func (s *ModelAliasService) SetAlias(alias, adminID string, req *models.ModelAliasRequest) (*models.ModelAlias, error) {
+	alias = strings.ToLower(strings.TrimSpace(alias))
+	target := strings.TrimSpace(req.Model)
+	if !aliasNamePattern.MatchString(alias) {
+		return nil, fmt.Errorf("%w: alias must be 1-64 lowercase letters, digits, '.', '_' or '-'", ErrInvalidModelAlias)
+	}
+	if target == "" {
+		return nil, fmt.Errorf("%w: model is required", ErrInvalidModelAlias)
+	}
+	if strings.EqualFold(target, alias) {
+		return nil, fmt.Errorf("%w: an alias cannot point at itself", ErrInvalidModelAlias)
+	}
+
+	targetAlias, err := s.repo.Get(strings.ToLower(target))
+	if err != nil {
+		return nil, err
+	}
+	if targetAlias != nil {
+		return nil, fmt.Errorf("%w: %q is itself an alias (for %s)", ErrInvalidModelAlias, target, targetAlias.Model)
+	}
+	if existing, err := s.repo.Get(alias); err != nil {
+		return nil, err
+	} else if existing == nil {
+		// A new alias must not shadow a model that other aliases point at
+		pointed, err := s.repo.IsTarget(alias)
+		if err != nil {
+			return nil, err
+		}
+		if pointed {
+			return nil, fmt.Errorf("%w: %q is the target of another alias", ErrInvalidModelAlias, alias)
+		}
+	}
+
+	a := &models.ModelAlias{
+		Alias:       alias,
+		Model:       target,
+		Description: req.Description,
+		UpdatedBy:   adminID,
+	}
+	if err := s.repo.Upsert(a); err != nil {
+		return nil, err
+	}
+	log.Printf("🔀 Model alias %s now points at %s (by user %s)", a.Alias, a.Model, adminID)
+	return a, nil
+}
+
+// ListAliases retrieves all aliases
+func (s *ModelAliasService) ListAliases() ([]models.ModelAlias, error) {
+	return s.repo.List()
+}
+
+// DeleteAlias removes an alias
+func (s *ModelAliasService) DeleteAlias(alias string) error {
+	deleted, err := s.repo.Delete(strings.ToLower(alias))
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrNotFound
+	}
+	return nil
+}
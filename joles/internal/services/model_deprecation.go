@@ -0,0 +1,178 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"lio-ai/internal/models"
+	"lio-ai/internal/repositories"
+)
+
+// ErrInvalidDeprecation is returned when a deprecation's replacement is
+// not usable
+var ErrInvalidDeprecation = errors.New("invalid model deprecation")
+
+// deprecationNoticeWindow is how far back a user's requests are looked at
+// to decide that they use a model being deprecated
+const deprecationNoticeWindow = 30 * 24 * time.Hour
+
+// ModelDeprecationService manages the deprecation of catalog models and
+// applies it to requests. Like aliases it is read from the database on
+// every request, so a sunset takes effect on all instances at once.
+type ModelDeprecationService struct {
+	repo          *repositories.ModelCatalogRepository
+	notifications *NotificationService
+}
+
+// NewModelDeprecationService creates a model deprecation service
+func NewModelDeprecationService(repo *repositories.ModelCatalogRepository, notifications *NotificationService) *ModelDeprecationService {
+	return &ModelDeprecationService{repo: repo, notifications: notifications}
+}
+
+// Check returns the model that serves requests naming model and, when
+// model is deprecated, the notice to return with the response. After the
+// sunset the replacement, if any, serves them; replacements are not
+// themselves checked, so mapping is a single step.
+func (s *ModelDeprecationService) Check(model string) (string, *models.ModelDeprecationNotice) {
+	if model == "" {
+		return model, nil
+	}
+	d, err := s.repo.GetDeprecation(model)
+	if err != nil {
+		log.Printf("Failed to check deprecation of model %q: %v", model, err)
+		return model, nil
+	}
+	if d == nil {
+		return model, nil
+	}
+
+	notice := deprecationNotice(d)
+	if d.ReplacementModel != "" && d.Sunset(time.Now()) {
+		notice.Replaced = true
+		return d.ReplacementModel, notice
+	}
+	return model, notice
+}
+
+// Deprecate marks a catalog model deprecated, or changes the sunset or
+// replacement of one that already is. Users who used the model recently
+// are notified when it is first deprecated and whenever the sunset date or
+// replacement changes.
+func (s *ModelDeprecationService) Deprecate(model, adminID string, req *models.DeprecateModelRequest) (*models.ModelDeprecation, error) {
+	model = strings.TrimSpace(model)
+	replacement := strings.TrimSpace(req.ReplacementModel)
+	if replacement != "" {
+		if replacement == model {
+			return nil, fmt.Errorf("%w: a model cannot replace itself", ErrInvalidDeprecation)
+		}
+		exists, err := s.repo.Exists(replacement)
+		if err != nil {
+			return nil, err
+		}
+		if !exists {
+			return nil, fmt.Errorf("%w: replacement %q is not in the model catalog", ErrInvalidDeprecation, replacement)
+		}
+		deprecated, err := s.repo.GetDeprecation(replacement)
+		if err != nil {
+			return nil, err
+		}
+		if deprecated != nil {
+			return nil, fmt.Errorf("%w: replacement %q is itself deprecated", ErrInvalidDeprecation, replacement)
+		}
+	}
+
+	previous, err := s.repo.GetDeprecation(model)
+	if err != nil {
+		return nil, err
+	}
+	d := &models.ModelDeprecation{
+		Model:            model,
+		SunsetAt:         req.SunsetAt.UTC(),
+		ReplacementModel: replacement,
+		Notice:           strings.TrimSpace(req.Notice),
+		DeprecatedBy:     adminID,
+	}
+	found, err := s.repo.SetDeprecation(d)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, fmt.Errorf("%w: model %s is not in the catalog", ErrNotFound, model)
+	}
+	log.Printf("⏳ Model %s deprecated with sunset %s (by user %s)", d.Model, d.SunsetAt.Format(time.RFC3339), adminID)
+
+	if previous == nil || !previous.SunsetAt.Equal(d.SunsetAt) || previous.ReplacementModel != d.ReplacementModel {
+		s.notifyRecentUsers(d)
+	}
+	return d, nil
+}
+
+// ListDeprecations retrieves every deprecated model
+func (s *ModelDeprecationService) ListDeprecations() ([]models.ModelDeprecation, error) {
+	return s.repo.ListDeprecations()
+}
+
+// Undeprecate returns a model to normal service
+func (s *ModelDeprecationService) Undeprecate(model string) error {
+	cleared, err := s.repo.ClearDeprecation(strings.TrimSpace(model))
+	if err != nil {
+		return err
+	}
+	if !cleared {
+		return fmt.Errorf("%w: model %s is not deprecated", ErrNotFound, model)
+	}
+	return nil
+}
+
+// notifyRecentUsers tells the users who used d.Model recently about its
+// deprecation. Failures are logged; the deprecation itself stands.
+func (s *ModelDeprecationService) notifyRecentUsers(d *models.ModelDeprecation) {
+	users, err := s.repo.UsersOfModelSince(d.Model, time.Now().Add(-deprecationNoticeWindow))
+	if err != nil {
+		log.Printf("Failed to find users of deprecated model %s: %v", d.Model, err)
+		return
+	}
+
+	sunset := d.SunsetAt.Format("January 2, 2006")
+	var body string
+	switch {
+	case d.Sunset(time.Now()) && d.ReplacementModel != "":
+		body = fmt.Sprintf("%s was retired on %s. Requests for it are now answered by %s.", d.Model, sunset, d.ReplacementModel)
+	case d.Sunset(time.Now()):
+		body = fmt.Sprintf("%s was retired on %s. Switch to another model.", d.Model, sunset)
+	case d.ReplacementModel != "":
+		body = fmt.Sprintf("%s will be retired on %s. After that, requests for it will be answered by %s.", d.Model, sunset, d.ReplacementModel)
+	default:
+		body = fmt.Sprintf("%s will be retired on %s. Switch to another model before then.", d.Model, sunset)
+	}
+	if d.Notice != "" {
+		body += " " + d.Notice
+	}
+
+	title := fmt.Sprintf("Model %s is deprecated", d.Model)
+	if err := s.notifications.Notify(users, models.NotificationKindModelDeprecation, title, body, deprecationNotice(d)); err != nil {
+		log.Printf("Failed to notify users of deprecated model %s: %v", d.Model, err)
+		return
+	}
+	if len(users) > 0 {
+		log.Printf("📣 Notified %d users of the deprecation of %s", len(users), d.Model)
+	}
+}
+
+func deprecationNotice(d *models.ModelDeprecation) *models.ModelDeprecationNotice {
+	return &models.ModelDeprecationNotice{
+		Model:            d.Model,
+		DeprecatedAt:     d.DeprecatedAt,
+		SunsetAt:         d.SunsetAt,
+		ReplacementModel: d.ReplacementModel,
+		Notice:           d.Notice,
+	}
+}
+
+// SetModelDeprecations applies model deprecations to completion requests
+func (s *ChatService) SetModelDeprecations(deprecations *ModelDeprecationService) {
+	s.deprecations = deprecations
+}
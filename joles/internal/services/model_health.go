@@ -0,0 +1,158 @@
+package services
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"lio-ai/internal/models"
+)
+
+const (
+	// Weight of the newest sample in a model's rolling latency
+	modelLatencyWeight = 0.2
+	// Consecutive failures after which a model is considered unhealthy
+	modelFailureThreshold = 3
+	// How long an unhealthy model is avoided before it is tried again
+	modelHealthCooldown = 30 * time.Second
+)
+
+// ModelHealth keeps a rolling latency and failure count for each model
+// from the completions that reach it. It is in-memory, so each instance
+// learns from its own traffic.
+type ModelHealth struct {
+	mu     sync.RWMutex
+	models map[string]*modelHealthEntry
+
+	// Optional record of the periods models were unhealthy
+	incidents *IncidentService
+}
+
+type modelHealthEntry struct {
+	latencyMs   float64
+	samples     int
+	failures    int
+	lastFailure time.Time
+	lastError   string
+}
+
+// ModelHealthStatus is a snapshot of one model's health
+type ModelHealthStatus struct {
+	Model               string     `json:"model"`
+	Healthy             bool       `json:"healthy"`
+	LatencyMs           float64    `json:"latency_ms"` // Rolling average of successful completions
+	Samples             int        `json:"samples"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	LastError           string     `json:"last_error,omitempty"`
+	LastFailure         *time.Time `json:"last_failure,omitempty"`
+}
+
+// NewModelHealth creates an empty model health tracker
+func NewModelHealth() *ModelHealth {
+	return &ModelHealth{models: make(map[string]*modelHealthEntry)}
+}
+
+// SetIncidents records models becoming unhealthy as degraded incidents
+func (h *ModelHealth) SetIncidents(incidents *IncidentService) {
+	h.incidents = incidents
+}
+
+// Observe records a completion's outcome. Successes update the rolling
+// latency and reset the failure count.
+func (h *ModelHealth) Observe(model string, duration time.Duration, err error) {
+	if model == "" {
+		return
+	}
+	failures := h.observe(model, duration, err)
+	if h.incidents == nil {
+		return
+	}
+	// Failing reaches the threshold once; a success after it ends the incident
+	component := models.IncidentComponentModelPrefix + model
+	if err != nil && failures == modelFailureThreshold {
+		h.incidents.Open(component, models.IncidentKindDegraded, err.Error())
+	} else if err == nil && failures >= modelFailureThreshold {
+		h.incidents.Resolve(component, models.IncidentKindDegraded)
+	}
+}
+
+// observe updates the model's entry, returning its consecutive failures:
+// including this one on error, or the ones this success ended
+func (h *ModelHealth) observe(model string, duration time.Duration, err error) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	entry := h.models[model]
+	if entry == nil {
+		entry = &modelHealthEntry{}
+		h.models[model] = entry
+	}
+	if err != nil {
+		entry.failures++
+		entry.lastFailure = time.Now()
+		entry.lastError = err.Error()
+		return entry.failures
+	}
+	failures := entry.failures
+
+	ms := float64(duration.Microseconds()) / 1000
+	if entry.samples == 0 {
+		entry.latencyMs = ms
+	} else {
+		entry.latencyMs += modelLatencyWeight * (ms - entry.latencyMs)
+	}
+	entry.samples++
+	entry.failures = 0
+	entry.lastError = ""
+	return failures
+}
+
+// Healthy reports whether completions should be routed to model. An
+// unhealthy model is tried again once the cooldown has passed; unknown
+// models are healthy.
+func (h *ModelHealth) Healthy(model string) bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.models[model].healthy()
+}
+
+// Latency returns the model's rolling latency, or false before its
+// first successful completion
+func (h *ModelHealth) Latency(model string) (float64, bool) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	entry := h.models[model]
+	if entry == nil || entry.samples == 0 {
+		return 0, false
+	}
+	return entry.latencyMs, true
+}
+
+// Status returns a snapshot of every model seen, sorted by name
+func (h *ModelHealth) Status() []ModelHealthStatus {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	statuses := make([]ModelHealthStatus, 0, len(h.models))
+	for model, entry := range h.models {
+		status := ModelHealthStatus{
+			Model:               model,
+			Healthy:             entry.healthy(),
+			LatencyMs:           entry.latencyMs,
+			Samples:             entry.samples,
+			ConsecutiveFailures: entry.failures,
+			LastError:           entry.lastError,
+		}
+		if !entry.lastFailure.IsZero() {
+			lastFailure := entry.lastFailure
+			status.LastFailure = &lastFailure
+		}
+		statuses = append(statuses, status)
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Model < statuses[j].Model })
+	return statuses
+}
+
+func (e *modelHealthEntry) healthy() bool {
+	return e == nil || e.failures < modelFailureThreshold || time.Since(e.lastFailure) >= modelHealthCooldown
+}
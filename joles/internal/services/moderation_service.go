@@ -0,0 +1,170 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"lio-ai/internal/models"
+)
+
+var (
+	// ErrContentBlocked is returned when moderation flags a user message
+	ErrContentBlocked = errors.New("message blocked by content moderation")
+	// ErrModerationUnavailable is returned when the moderation check fails
+	// and the gateway is not configured to fail open
+	ErrModerationUnavailable = errors.New("content moderation is unavailable")
+)
+
+// Category score at which strict moderation blocks a message
+const strictModerationScore = 0.2
+
+// ContentBlockedError carries the moderation outcome of a blocked message
+type ContentBlockedError struct {
+	Result *models.ModerationResult
+}
+
+func (e *ContentBlockedError) Error() string {
+	return fmt.Sprintf("%v: %s", ErrContentBlocked, strings.Join(e.Result.Categories, ", "))
+}
+
+// Unwrap lets errors.Is match ErrContentBlocked
+func (e *ContentBlockedError) Unwrap() error {
+	return ErrContentBlocked
+}
+
+// ModerationService checks user content with the moderation endpoint of
+// the AI service before it is sent for completion
+type ModerationService struct {
+	aiServiceURL string
+	model        string
+	failOpen     bool
+	client       *http.Client
+}
+
+// NewModerationService creates a moderation service using model through
+// the AI service at aiServiceURL. With failOpen, messages are let through
+// unmoderated when the check fails.
+func NewModerationService(aiServiceURL, model string, failOpen bool) *ModerationService {
+	if aiServiceURL == "" {
+		aiServiceURL = defaultAIServiceURL
+	}
+	return &ModerationService{
+		aiServiceURL: aiServiceURL,
+		model:        model,
+		failOpen:     failOpen,
+		client:       &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Check moderates text for userID. A flagged message returns a
+// *ContentBlockedError; otherwise the outcome is returned to be stored
+// with the message. It is nil when the check failed open.
+func (s *ModerationService) Check(ctx context.Context, userID, text string) (*models.ModerationResult, error) {
+	result, _, err := s.moderate(ctx, userID, text)
+	if err != nil {
+		if s.failOpen {
+			log.Printf("⚠️  Moderation check failed, letting message through (user=%s): %v", userID, err)
+			return nil, nil
+		}
+		return nil, fmt.Errorf("%w: %v", ErrModerationUnavailable, err)
+	}
+	if result.Flagged {
+		return nil, &ContentBlockedError{Result: result}
+	}
+	return result, nil
+}
+
+// CheckStrict is Check for users flagged for jailbreak attempts: it fails
+// closed, and also blocks categories the provider scored at or above
+// strictModerationScore without flagging them.
+func (s *ModerationService) CheckStrict(ctx context.Context, userID, text string) (*models.ModerationResult, error) {
+	result, scores, err := s.moderate(ctx, userID, text)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrModerationUnavailable, err)
+	}
+	result.Strict = true
+	for category, score := range scores {
+		if score >= strictModerationScore && !slices.Contains(result.Categories, category) {
+			result.Categories = append(result.Categories, category)
+		}
+	}
+	sort.Strings(result.Categories)
+	if result.Flagged || len(result.Categories) > 0 {
+		result.Flagged = true
+		return nil, &ContentBlockedError{Result: result}
+	}
+	return result, nil
+}
+
+// moderate calls the moderation endpoint, returning the outcome and the
+// highest score the provider gave each category
+func (s *ModerationService) moderate(ctx context.Context, userID, text string) (*models.ModerationResult, map[string]float64, error) {
+	payloadBytes, err := json.Marshal(map[string]interface{}{
+		"model":   s.model,
+		"input":   text,
+		"user_id": userID,
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to marshal payload: %w", err)
+	}
+
+	MarkUpstream(ctx, s.aiServiceURL+"/api/v1/moderations", s.model)
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, s.aiServiceURL+"/api/v1/moderations", bytes.NewBuffer(payloadBytes))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create moderation request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.client.Do(httpReq)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to call AI service: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return nil, nil, &AIServiceError{StatusCode: resp.StatusCode, Body: string(bodyBytes)}
+	}
+
+	var body struct {
+		Results []struct {
+			Flagged        bool               `json:"flagged"`
+			Categories     map[string]bool    `json:"categories"`
+			CategoryScores map[string]float64 `json:"category_scores"`
+		} `json:"results"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, nil, fmt.Errorf("failed to decode moderation response: %w", err)
+	}
+	if len(body.Results) == 0 {
+		return nil, nil, fmt.Errorf("no moderation result from AI service")
+	}
+
+	result := &models.ModerationResult{Model: s.model, CheckedAt: time.Now().UTC()}
+	scores := make(map[string]float64)
+	for _, r := range body.Results {
+		result.Flagged = result.Flagged || r.Flagged
+		for category, flagged := range r.Categories {
+			if flagged && !slices.Contains(result.Categories, category) {
+				result.Categories = append(result.Categories, category)
+			}
+		}
+		for category, score := range r.CategoryScores {
+			if score > scores[category] {
+				scores[category] = score
+			}
+		}
+	}
+	sort.Strings(result.Categories)
+	return result, scores, nil
+}
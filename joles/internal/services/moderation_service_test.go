@@ -0,0 +1,30 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestModerationCallsTheConfiguredAIService(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/moderations" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(`{"results":[{"flagged":true,"categories":{"violence":true},"category_scores":{"violence":0.9}}]}`))
+	}))
+	t.Cleanup(srv.Close)
+
+	s := NewModerationService(srv.URL, "omni-moderation-latest", false)
+	_, err := s.Check(context.Background(), "1", "a violent message")
+	var blocked *ContentBlockedError
+	if !errors.As(err, &blocked) {
+		t.Fatalf("err = %v, want the message blocked by the configured service", err)
+	}
+	if len(blocked.Result.Categories) != 1 || blocked.Result.Categories[0] != "violence" {
+		t.Errorf("categories = %v, want [violence]", blocked.Result.Categories)
+	}
+}
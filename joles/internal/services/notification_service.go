@@ -0,0 +1,67 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"lio-ai/internal/models"
+	"lio-ai/internal/repositories"
+)
+
+// NotificationService delivers messages to users' notification centers
+type NotificationService struct {
+	repo *repositories.NotificationRepository
+}
+
+// NewNotificationService creates a notification service
+func NewNotificationService(repo *repositories.NotificationRepository) *NotificationService {
+	return &NotificationService{repo: repo}
+}
+
+// Notify sends the same notification to each user; data, if not nil, is
+// attached as JSON
+func (s *NotificationService) Notify(userIDs []string, kind, title, body string, data interface{}) error {
+	if len(userIDs) == 0 {
+		return nil
+	}
+	n := &models.Notification{Kind: kind, Title: title, Body: body}
+	if data != nil {
+		b, err := json.Marshal(data)
+		if err != nil {
+			return fmt.Errorf("failed to encode notification: %w", err)
+		}
+		n.Data = b
+	}
+	return s.repo.CreateMany(userIDs, n)
+}
+
+// List returns a page of userID's notifications and their unread count
+func (s *NotificationService) List(userID string, unreadOnly bool, limit, offset int) ([]models.Notification, int, error) {
+	notifications, err := s.repo.ListByUser(userID, unreadOnly, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	unread, err := s.repo.CountUnread(userID)
+	if err != nil {
+		return nil, 0, err
+	}
+	return notifications, unread, nil
+}
+
+// MarkRead marks one of userID's notifications read
+func (s *NotificationService) MarkRead(id int64, userID string) error {
+	found, err := s.repo.MarkRead(id, userID)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return fmt.Errorf("%w: notification %d", ErrNotFound, id)
+	}
+	return nil
+}
+
+// MarkAllRead marks all of userID's notifications read, returning how many
+// were unread
+func (s *NotificationService) MarkAllRead(userID string) (int64, error) {
+	return s.repo.MarkAllRead(userID)
+}
@@ -0,0 +1,152 @@
+package services
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"lio-ai/internal/models"
+	"lio-ai/internal/repositories"
+)
+
+//go:embed onboarding_samples.json
+var onboardingSamplesJSON []byte
+
+// onboardingSamples is the sample content new users start with. Text is a
+// text/template rendered with the user's Name and Username.
+type onboardingSamples struct {
+	Assistant struct {
+		Name         string   `json:"name"`
+		SystemPrompt string   `json:"system_prompt"`
+		Temperature  *float64 `json:"temperature"`
+	} `json:"assistant"`
+	Documents []struct {
+		Title       string   `json:"title"`
+		Content     string   `json:"content"`
+		ContentType string   `json:"content_type"`
+		Tags        []string `json:"tags"`
+	} `json:"documents"`
+	Chat struct {
+		Title           string `json:"title"`
+		AttachDocuments bool   `json:"attach_documents"`
+		Messages        []struct {
+			Role    string `json:"role"`
+			Content string `json:"content"`
+		} `json:"messages"`
+	} `json:"chat"`
+}
+
+// OnboardingService fills the workspace of users logging in for the first
+// time with sample content: a starter assistant persona, example documents
+// and a tutorial chat
+type OnboardingService struct {
+	users    *repositories.UserRepository
+	chats    *ChatService
+	docs     *DocumentService
+	personas *PersonaService
+	samples  onboardingSamples
+}
+
+// NewOnboardingService creates an onboarding service provisioning the
+// embedded samples
+func NewOnboardingService(users *repositories.UserRepository, chats *ChatService, docs *DocumentService, personas *PersonaService) (*OnboardingService, error) {
+	s := &OnboardingService{users: users, chats: chats, docs: docs, personas: personas}
+	if err := json.Unmarshal(onboardingSamplesJSON, &s.samples); err != nil {
+		return nil, fmt.Errorf("invalid onboarding samples: %w", err)
+	}
+	return s, nil
+}
+
+// ProvisionFirstLogin creates the sample content for user unless it was
+// already provisioned, or the user logged in before onboarding existed.
+// Content created before a failure is kept; it is not provisioned again.
+func (s *OnboardingService) ProvisionFirstLogin(user *models.User) error {
+	claimed, err := s.users.ClaimOnboarding(user.ID)
+	if err != nil || !claimed {
+		return err
+	}
+
+	name := user.FullName
+	if name == "" {
+		name = user.Username
+	}
+	r := &sampleRenderer{data: map[string]string{"Name": name, "Username": user.Username}}
+	userID := strconv.FormatInt(user.ID, 10)
+
+	var personaID int64
+	if s.personas != nil && s.samples.Assistant.Name != "" {
+		persona, err := s.personas.CreatePersona(userID, &models.PersonaRequest{
+			Name:         r.render(s.samples.Assistant.Name),
+			SystemPrompt: r.render(s.samples.Assistant.SystemPrompt),
+			Temperature:  s.samples.Assistant.Temperature,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create sample assistant: %w", err)
+		}
+		personaID = persona.ID
+	}
+
+	docIDs := make([]uint, 0, len(s.samples.Documents))
+	for _, sample := range s.samples.Documents {
+		doc, err := s.docs.CreateDocument(&models.CreateDocumentRequest{
+			Title:       r.render(sample.Title),
+			Content:     r.render(sample.Content),
+			ContentType: sample.ContentType,
+			Tags:        sample.Tags,
+		}, userID)
+		if err != nil {
+			return fmt.Errorf("failed to create sample document %q: %w", sample.Title, err)
+		}
+		docIDs = append(docIDs, doc.ID)
+	}
+
+	if s.samples.Chat.Title == "" {
+		return r.err
+	}
+	chat, err := s.chats.CreateChat(userID, r.render(s.samples.Chat.Title), "", personaID)
+	if err != nil {
+		return fmt.Errorf("failed to create sample chat: %w", err)
+	}
+	for _, message := range s.samples.Chat.Messages {
+		if _, err := s.chats.SendMessage(chat.ID, userID, &models.MessageRequest{
+			Role:    message.Role,
+			Content: r.render(message.Content),
+		}); err != nil {
+			return fmt.Errorf("failed to add sample chat message: %w", err)
+		}
+	}
+	if s.samples.Chat.AttachDocuments && len(docIDs) > 0 {
+		if _, err := s.chats.AttachDocuments(chat.ID, userID, docIDs); err != nil {
+			log.Printf("⚠️  Failed to attach sample documents to chat %d: %v", chat.ID, err)
+		}
+	}
+	return r.err
+}
+
+// sampleRenderer renders sample text, keeping the first error and falling
+// back to the unrendered text
+type sampleRenderer struct {
+	data map[string]string
+	err  error
+}
+
+func (r *sampleRenderer) render(text string) string {
+	if !strings.Contains(text, "{{") {
+		return text
+	}
+	tmpl, err := template.New("sample").Option("missingkey=zero").Parse(text)
+	if err == nil {
+		var b strings.Builder
+		if err = tmpl.Execute(&b, r.data); err == nil {
+			return b.String()
+		}
+	}
+	if r.err == nil {
+		r.err = fmt.Errorf("invalid onboarding sample template: %w", err)
+	}
+	return text
+}
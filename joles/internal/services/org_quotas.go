@@ -0,0 +1,96 @@
+package services
+
+import (
+	"fmt"
+
+	"lio-ai/internal/models"
+	"lio-ai/internal/repositories"
+)
+
+// SetOrganizations enables quotas and budgets shared by the members of an
+// organization, on top of each member's own
+func (s *UsageService) SetOrganizations(orgs *repositories.OrganizationRepository) {
+	s.orgs = orgs
+}
+
+// GetOrgQuotaStatus retrieves an organization's quota with its members'
+// usage, or nil when it has no quota
+func (s *UsageService) GetOrgQuotaStatus(orgID int64) (*models.OrgQuotaStatus, error) {
+	if s.orgs == nil {
+		return nil, nil
+	}
+	return s.orgs.GetQuotaStatus(orgID)
+}
+
+// SetOrgQuota creates or changes an organization's quota limits; limits
+// left out of req keep their value, or no limit for a new quota
+func (s *UsageService) SetOrgQuota(orgID int64, req *models.QuotaUpdateRequest) (*models.OrgQuotaStatus, error) {
+	if s.orgs == nil {
+		return nil, fmt.Errorf("%w: organizations are not enabled", ErrInvalidMessage)
+	}
+	org, err := s.orgs.GetByID(orgID)
+	if err != nil {
+		return nil, err
+	}
+	if org == nil {
+		return nil, fmt.Errorf("%w: organization %d", ErrNotFound, orgID)
+	}
+	if (req.DailyTokenLimit != nil && *req.DailyTokenLimit < 0) || (req.MonthlyTokenLimit != nil && *req.MonthlyTokenLimit < 0) ||
+		(req.DailyCostLimitUSD != nil && *req.DailyCostLimitUSD < 0) || (req.MonthlyCostLimitUSD != nil && *req.MonthlyCostLimitUSD < 0) {
+		return nil, fmt.Errorf("%w: limits must not be negative", ErrInvalidMessage)
+	}
+
+	quota := &models.OrgQuota{OrgID: orgID}
+	current, err := s.orgs.GetQuotaStatus(orgID)
+	if err != nil {
+		return nil, err
+	}
+	if current != nil {
+		*quota = current.OrgQuota
+	}
+	if req.DailyTokenLimit != nil {
+		quota.DailyTokenLimit = *req.DailyTokenLimit
+	}
+	if req.MonthlyTokenLimit != nil {
+		quota.MonthlyTokenLimit = *req.MonthlyTokenLimit
+	}
+	if req.DailyCostLimitUSD != nil {
+		quota.DailyCostLimitUSD = *req.DailyCostLimitUSD
+	}
+	if req.MonthlyCostLimitUSD != nil {
+		quota.MonthlyCostLimitUSD = *req.MonthlyCostLimitUSD
+	}
+	if err := s.orgs.SetQuota(quota); err != nil {
+		return nil, err
+	}
+	return s.orgs.GetQuotaStatus(orgID)
+}
+
+// DeleteOrgQuota removes an organization's quota, leaving only its members'
+// own
+func (s *UsageService) DeleteOrgQuota(orgID int64) error {
+	found := false
+	if s.orgs != nil {
+		var err error
+		if found, err = s.orgs.DeleteQuota(orgID); err != nil {
+			return err
+		}
+	}
+	if !found {
+		return fmt.Errorf("%w: organization %d has no quota", ErrNotFound, orgID)
+	}
+	return nil
+}
+
+// userOrgQuota retrieves the quota of userID's organization, or nil when
+// they belong to none or it has no quota
+func (s *UsageService) userOrgQuota(userID string) (*models.OrgQuotaStatus, error) {
+	if s.orgs == nil {
+		return nil, nil
+	}
+	org, err := s.orgs.GetByUser(userID)
+	if err != nil || org == nil {
+		return nil, err
+	}
+	return s.orgs.GetQuotaStatus(org.ID)
+}
@@ -0,0 +1,203 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"lio-ai/internal/models"
+	"lio-ai/internal/repositories"
+)
+
+// ErrAlreadyInOrganization is returned when adding a user who already
+// belongs to an organization to another
+var ErrAlreadyInOrganization = errors.New("user already belongs to an organization")
+
+// OrganizationService manages organizations and their members. The quotas
+// and budgets members share are kept by UsageService.
+type OrganizationService struct {
+	repo  *repositories.OrganizationRepository
+	users *repositories.UserRepository
+	usage *UsageService
+}
+
+// NewOrganizationService creates an organization service
+func NewOrganizationService(repo *repositories.OrganizationRepository, users *repositories.UserRepository, usage *UsageService) *OrganizationService {
+	return &OrganizationService{repo: repo, users: users, usage: usage}
+}
+
+// Create creates an organization with userID as its owner
+func (s *OrganizationService) Create(userID string, req *models.CreateOrganizationRequest) (*models.Organization, error) {
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return nil, fmt.Errorf("%w: name is required", ErrInvalidMessage)
+	}
+	current, err := s.repo.GetByUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	if current != nil {
+		return nil, ErrAlreadyInOrganization
+	}
+
+	org := &models.Organization{Name: name, CreatedBy: userID}
+	if err := s.repo.Create(org, userID); err != nil {
+		return nil, err
+	}
+	return s.Get(org.ID, userID, false)
+}
+
+// GetForUser retrieves the organization userID belongs to, or nil
+func (s *OrganizationService) GetForUser(userID string) (*models.Organization, error) {
+	org, err := s.repo.GetByUser(userID)
+	if err != nil || org == nil {
+		return nil, err
+	}
+	return s.Get(org.ID, userID, false)
+}
+
+// Get retrieves an organization with its members, quota and budget. Only
+// members and admins can see it.
+func (s *OrganizationService) Get(orgID int64, userID string, isAdmin bool) (*models.Organization, error) {
+	org, err := s.repo.GetByID(orgID)
+	if err != nil {
+		return nil, err
+	}
+	if org == nil {
+		return nil, fmt.Errorf("%w: organization %d", ErrNotFound, orgID)
+	}
+	if !isAdmin {
+		member, err := s.repo.GetMember(orgID, userID)
+		if err != nil {
+			return nil, err
+		}
+		if member == nil {
+			return nil, fmt.Errorf("%w: organization %d", ErrNotFound, orgID)
+		}
+	}
+
+	if org.Members, err = s.repo.ListMembers(orgID); err != nil {
+		return nil, err
+	}
+	if org.Quota, err = s.usage.GetOrgQuotaStatus(orgID); err != nil {
+		return nil, err
+	}
+	if org.Budget, err = s.usage.GetOrgBudgetStatus(orgID); err != nil {
+		return nil, err
+	}
+	return org, nil
+}
+
+// Delete removes an organization with its quota and budget; its owners
+// and admins can
+func (s *OrganizationService) Delete(orgID int64, userID string, isAdmin bool) error {
+	if err := s.requireOwner(orgID, userID, isAdmin); err != nil {
+		return err
+	}
+	found, err := s.repo.Delete(orgID)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return fmt.Errorf("%w: organization %d", ErrNotFound, orgID)
+	}
+	return nil
+}
+
+// AddMember adds a user to an organization on behalf of userID, one of its
+// owners or an admin
+func (s *OrganizationService) AddMember(orgID int64, userID string, isAdmin bool, req *models.AddOrganizationMemberRequest) (*models.OrganizationMember, error) {
+	if err := s.requireOwner(orgID, userID, isAdmin); err != nil {
+		return nil, err
+	}
+	role := req.Role
+	if role == "" {
+		role = models.OrgRoleMember
+	}
+	if role != models.OrgRoleOwner && role != models.OrgRoleMember {
+		return nil, fmt.Errorf("%w: role must be 'owner' or 'member'", ErrInvalidMessage)
+	}
+	id, err := strconv.ParseInt(req.UserID, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("%w: user %s", ErrNotFound, req.UserID)
+	}
+	user, err := s.users.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, fmt.Errorf("%w: user %s", ErrNotFound, req.UserID)
+	}
+	current, err := s.repo.GetByUser(req.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if current != nil {
+		return nil, ErrAlreadyInOrganization
+	}
+
+	member := &models.OrganizationMember{OrgID: orgID, UserID: req.UserID, Role: role}
+	if err := s.repo.AddMember(member); err != nil {
+		return nil, err
+	}
+	return member, nil
+}
+
+// RemoveMember removes memberID from an organization on behalf of userID:
+// an owner, an admin, or the member leaving. The last owner cannot leave;
+// the organization is deleted instead.
+func (s *OrganizationService) RemoveMember(orgID int64, userID string, isAdmin bool, memberID string) error {
+	if memberID != userID {
+		if err := s.requireOwner(orgID, userID, isAdmin); err != nil {
+			return err
+		}
+	}
+	member, err := s.repo.GetMember(orgID, memberID)
+	if err != nil {
+		return err
+	}
+	if member == nil {
+		return fmt.Errorf("%w: user %s is not a member of organization %d", ErrNotFound, memberID, orgID)
+	}
+	if member.Role == models.OrgRoleOwner {
+		owners, err := s.repo.CountOwners(orgID)
+		if err != nil {
+			return err
+		}
+		if owners <= 1 {
+			return fmt.Errorf("%w: the last owner cannot be removed; delete the organization instead", ErrInvalidMessage)
+		}
+	}
+	if _, err := s.repo.RemoveMember(orgID, memberID); err != nil {
+		return err
+	}
+	return nil
+}
+
+// requireOwner checks that userID may manage an organization: admins can
+// manage any, owners their own. Others get ErrNotFound when they are not a
+// member, ErrUnauthorized when they are.
+func (s *OrganizationService) requireOwner(orgID int64, userID string, isAdmin bool) error {
+	org, err := s.repo.GetByID(orgID)
+	if err != nil {
+		return err
+	}
+	if org == nil {
+		return fmt.Errorf("%w: organization %d", ErrNotFound, orgID)
+	}
+	if isAdmin {
+		return nil
+	}
+	member, err := s.repo.GetMember(orgID, userID)
+	if err != nil {
+		return err
+	}
+	if member == nil {
+		return fmt.Errorf("%w: organization %d", ErrNotFound, orgID)
+	}
+	if member.Role != models.OrgRoleOwner {
+		return fmt.Errorf("%w: only owners can manage the organization", ErrUnauthorized)
+	}
+	return nil
+}
@@ -0,0 +1,284 @@
+package services
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"lio-ai/internal/auth"
+	"lio-ai/internal/models"
+	"lio-ai/internal/repositories"
+)
+
+// ErrPasskeyChallenge is returned when a ceremony is completed with an
+// unknown, expired or already used challenge
+var ErrPasskeyChallenge = errors.New("passkey challenge is unknown or expired")
+
+// PasskeyService registers WebAuthn passkeys on accounts and logs users in
+// with them. Password login keeps working alongside.
+type PasskeyService struct {
+	repo         *repositories.PasskeyRepository
+	users        *repositories.UserRepository
+	rp           *auth.RelyingParty
+	challengeTTL time.Duration
+}
+
+// NewPasskeyService creates a passkey service for relying party rp whose
+// ceremonies must complete within challengeTTL
+func NewPasskeyService(repo *repositories.PasskeyRepository, users *repositories.UserRepository, rp *auth.RelyingParty, challengeTTL time.Duration) *PasskeyService {
+	return &PasskeyService{repo: repo, users: users, rp: rp, challengeTTL: challengeTTL}
+}
+
+// BeginRegistration starts adding a passkey to userID's account. Passkeys
+// the user already has are excluded, so an authenticator is not
+// registered twice.
+func (s *PasskeyService) BeginRegistration(userID int64) (*models.PasskeyRegistrationOptions, error) {
+	user, err := s.users.GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+	existing, err := s.repo.ListByUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	challenge, err := s.newChallenge(models.PasskeyCeremonyRegistration, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	options := &models.PasskeyRegistrationOptions{ChallengeID: challenge.ID, ExpiresAt: challenge.ExpiresAt}
+	params := &options.PublicKey
+	params.Challenge = base64.RawURLEncoding.EncodeToString(challenge.Challenge)
+	params.RP.ID = s.rp.ID
+	params.RP.Name = s.rp.Name
+	params.User.ID = userHandle(userID)
+	params.User.Name = user.Email
+	params.User.DisplayName = user.FullName
+	if params.User.DisplayName == "" {
+		params.User.DisplayName = user.Username
+	}
+	for _, alg := range auth.PasskeyAlgorithms {
+		params.PubKeyCredParams = append(params.PubKeyCredParams, models.PasskeyAlgorithm{Type: "public-key", Alg: alg})
+	}
+	params.Timeout = s.challengeTTL.Milliseconds()
+	params.Attestation = "none"
+	// Discoverable, so the user can log in without typing their email
+	params.Selection.ResidentKey = "required"
+	params.Selection.RequireResident = true
+	params.Selection.UserVerification = "required"
+	params.ExcludeCredentials = descriptors(existing)
+	return options, nil
+}
+
+// FinishRegistration verifies the browser's response to a registration
+// ceremony userID started and stores the new passkey
+func (s *PasskeyService) FinishRegistration(userID int64, req *models.PasskeyRegistrationRequest) (*models.Passkey, error) {
+	challenge, err := s.repo.ConsumeChallenge(req.ChallengeID, models.PasskeyCeremonyRegistration)
+	if err != nil {
+		return nil, err
+	}
+	if challenge == nil || challenge.UserID != userID {
+		return nil, ErrPasskeyChallenge
+	}
+
+	clientData, err := decodeBase64URL(req.Credential.Response.ClientDataJSON)
+	if err != nil {
+		return nil, fmt.Errorf("%w: clientDataJSON: %v", auth.ErrPasskeyInvalid, err)
+	}
+	attestation, err := decodeBase64URL(req.Credential.Response.AttestationObject)
+	if err != nil {
+		return nil, fmt.Errorf("%w: attestationObject: %v", auth.ErrPasskeyInvalid, err)
+	}
+	credential, err := s.rp.VerifyRegistration(challenge.Challenge, clientData, attestation)
+	if err != nil {
+		return nil, err
+	}
+	credentialID := base64.RawURLEncoding.EncodeToString(credential.ID)
+	if req.Credential.ID != credentialID {
+		return nil, fmt.Errorf("%w: credential ID does not match the authenticator data", auth.ErrPasskeyInvalid)
+	}
+
+	name := req.Name
+	if name == "" {
+		name = "Passkey"
+	}
+	passkey := &models.Passkey{
+		UserID:         userID,
+		CredentialID:   credentialID,
+		PublicKey:      credential.PublicKey,
+		SignCount:      credential.SignCount,
+		Transports:     req.Credential.Response.Transports,
+		Name:           name,
+		BackupEligible: credential.BackupEligible,
+	}
+	if err := s.repo.Create(passkey); err != nil {
+		return nil, err
+	}
+	return passkey, nil
+}
+
+// BeginLogin starts a passkey login. With an email, the account's passkeys
+// are listed for the browser; an unknown email gets the same response as
+// one without passkeys, so accounts cannot be probed.
+func (s *PasskeyService) BeginLogin(email string) (*models.PasskeyLoginOptions, error) {
+	var allowed []models.Passkey
+	if email != "" {
+		user, err := s.users.GetByEmail(email)
+		if err != nil {
+			return nil, err
+		}
+		if user != nil {
+			if allowed, err = s.repo.ListByUser(user.ID); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	challenge, err := s.newChallenge(models.PasskeyCeremonyLogin, 0)
+	if err != nil {
+		return nil, err
+	}
+	return &models.PasskeyLoginOptions{
+		ChallengeID: challenge.ID,
+		ExpiresAt:   challenge.ExpiresAt,
+		PublicKey: models.PasskeyRequestParameters{
+			Challenge:        base64.RawURLEncoding.EncodeToString(challenge.Challenge),
+			RPID:             s.rp.ID,
+			Timeout:          s.challengeTTL.Milliseconds(),
+			UserVerification: "required",
+			AllowCredentials: descriptors(allowed),
+		},
+	}, nil
+}
+
+// FinishLogin verifies the browser's assertion for a login ceremony and
+// returns the user it authenticates
+func (s *PasskeyService) FinishLogin(req *models.PasskeyLoginRequest) (*models.User, error) {
+	challenge, err := s.repo.ConsumeChallenge(req.ChallengeID, models.PasskeyCeremonyLogin)
+	if err != nil {
+		return nil, err
+	}
+	if challenge == nil {
+		return nil, ErrPasskeyChallenge
+	}
+
+	passkey, err := s.repo.GetByCredentialID(req.Credential.ID)
+	if err != nil {
+		return nil, err
+	}
+	if passkey == nil {
+		return nil, fmt.Errorf("%w: unknown credential", auth.ErrPasskeyInvalid)
+	}
+	response := req.Credential.Response
+	if handle := strings.TrimRight(response.UserHandle, "="); handle != "" && handle != userHandle(passkey.UserID) {
+		return nil, fmt.Errorf("%w: credential belongs to another user", auth.ErrPasskeyInvalid)
+	}
+
+	var clientData, authData, signature []byte
+	for _, field := range []struct {
+		name  string
+		value string
+		dst   *[]byte
+	}{
+		{"clientDataJSON", response.ClientDataJSON, &clientData},
+		{"authenticatorData", response.AuthenticatorData, &authData},
+		{"signature", response.Signature, &signature},
+	} {
+		if *field.dst, err = decodeBase64URL(field.value); err != nil {
+			return nil, fmt.Errorf("%w: %s: %v", auth.ErrPasskeyInvalid, field.name, err)
+		}
+	}
+	signCount, err := s.rp.VerifyLogin(challenge.Challenge, passkey.PublicKey, clientData, authData, signature)
+	if err != nil {
+		return nil, err
+	}
+
+	// Authenticators that keep a counter must increase it on every use; a
+	// counter going backwards means the credential was cloned. Synced
+	// passkeys report 0 throughout.
+	if (signCount != 0 || passkey.SignCount != 0) && signCount <= passkey.SignCount {
+		return nil, fmt.Errorf("%w: signature counter did not increase", auth.ErrPasskeyInvalid)
+	}
+	recorded, err := s.repo.RecordUse(passkey.ID, passkey.SignCount, signCount)
+	if err != nil {
+		return nil, err
+	}
+	if !recorded {
+		return nil, fmt.Errorf("%w: passkey was used concurrently", auth.ErrPasskeyInvalid)
+	}
+
+	user, err := s.users.GetByID(passkey.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrUserInactive
+	}
+	_ = s.users.UpdateLastLogin(user.ID)
+	return user, nil
+}
+
+// ListPasskeys returns the passkeys on userID's account
+func (s *PasskeyService) ListPasskeys(userID int64) ([]models.Passkey, error) {
+	return s.repo.ListByUser(userID)
+}
+
+// DeletePasskey removes one of userID's passkeys
+func (s *PasskeyService) DeletePasskey(userID, id int64) error {
+	deleted, err := s.repo.Delete(id, userID)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return fmt.Errorf("%w: passkey %d", ErrNotFound, id)
+	}
+	return nil
+}
+
+// newChallenge stores a fresh challenge for a ceremony
+func (s *PasskeyService) newChallenge(ceremony string, userID int64) (*models.PasskeyChallenge, error) {
+	value, err := auth.NewPasskeyChallenge()
+	if err != nil {
+		return nil, err
+	}
+	challenge := &models.PasskeyChallenge{
+		ID:        uuid.New().String(),
+		Ceremony:  ceremony,
+		UserID:    userID,
+		Challenge: value,
+		ExpiresAt: time.Now().Add(s.challengeTTL),
+	}
+	if err := s.repo.CreateChallenge(challenge); err != nil {
+		return nil, err
+	}
+	return challenge, nil
+}
+
+// userHandle is the WebAuthn user.id of an account: its ID, which is
+// opaque to the browser and carries no personal information
+func userHandle(userID int64) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(userID, 10)))
+}
+
+// descriptors lists passkeys for the browser
+func descriptors(passkeys []models.Passkey) []models.PasskeyDescriptor {
+	list := make([]models.PasskeyDescriptor, 0, len(passkeys))
+	for _, p := range passkeys {
+		list = append(list, models.PasskeyDescriptor{Type: "public-key", ID: p.CredentialID, Transports: p.Transports})
+	}
+	return list
+}
+
+// decodeBase64URL decodes base64url, with or without padding, as browsers
+// and libraries differ
+func decodeBase64URL(value string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(value, "="))
+}
@@ -0,0 +1,155 @@
+package services
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"lio-ai/internal/auth"
+	"lio-ai/internal/models"
+	"lio-ai/internal/repositories"
+)
+
+// testPasskey is an ES256 credential registered on a user's account, able
+// to answer login ceremonies for example.com
+type testPasskey struct {
+	key          *ecdsa.PrivateKey
+	credentialID string
+}
+
+func registerTestPasskey(t *testing.T, repo *repositories.PasskeyRepository, userID int64, signCount uint32) *testPasskey {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	// COSE_Key {1: 2, 3: -7, -1: 1, -2: x, -3: y}
+	cose := []byte{0xa5, 0x01, 0x02, 0x03, 0x26, 0x20, 0x01, 0x21, 0x58, 0x20}
+	cose = append(cose, key.X.FillBytes(make([]byte, 32))...)
+	cose = append(cose, 0x22, 0x58, 0x20)
+	cose = append(cose, key.Y.FillBytes(make([]byte, 32))...)
+
+	p := &testPasskey{key: key, credentialID: base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf("credential-%d", userID)))}
+	if err := repo.Create(&models.Passkey{UserID: userID, CredentialID: p.credentialID, PublicKey: cose, SignCount: signCount, Name: "test"}); err != nil {
+		t.Fatalf("create passkey: %v", err)
+	}
+	return p
+}
+
+// assert answers the login ceremony options started with signCount
+func (p *testPasskey) assert(t *testing.T, options *models.PasskeyLoginOptions, signCount uint32) *models.PasskeyLoginRequest {
+	t.Helper()
+	rpIDHash := sha256.Sum256([]byte("example.com"))
+	authData := binary.BigEndian.AppendUint32(append(rpIDHash[:], 0x05), signCount)
+	clientData := []byte(fmt.Sprintf(`{"type":"webauthn.get","challenge":%q,"origin":"https://example.com"}`, options.PublicKey.Challenge))
+	clientDataHash := sha256.Sum256(clientData)
+	digest := sha256.Sum256(append(bytes.Clone(authData), clientDataHash[:]...))
+	signature, err := ecdsa.SignASN1(rand.Reader, p.key, digest[:])
+	if err != nil {
+		t.Fatalf("SignASN1: %v", err)
+	}
+
+	req := &models.PasskeyLoginRequest{ChallengeID: options.ChallengeID}
+	req.Credential.ID = p.credentialID
+	req.Credential.Type = "public-key"
+	req.Credential.Response.ClientDataJSON = base64.RawURLEncoding.EncodeToString(clientData)
+	req.Credential.Response.AuthenticatorData = base64.RawURLEncoding.EncodeToString(authData)
+	req.Credential.Response.Signature = base64.RawURLEncoding.EncodeToString(signature)
+	return req
+}
+
+func newTestPasskeyService(t *testing.T, signCount uint32) (*PasskeyService, *models.User, *testPasskey) {
+	t.Helper()
+	conn := newTestDB(t)
+	user := createTestUser(t, conn, "alice", "user")
+	repo := repositories.NewPasskeyRepository(conn)
+	rp := &auth.RelyingParty{ID: "example.com", Name: "Example", Origins: []string{"https://example.com"}}
+	return NewPasskeyService(repo, repositories.NewUserRepository(conn), rp, time.Minute), user, registerTestPasskey(t, repo, user.ID, signCount)
+}
+
+func TestFinishLoginRejectsReplayedChallenge(t *testing.T) {
+	svc, user, passkey := newTestPasskeyService(t, 0)
+
+	options, err := svc.BeginLogin("")
+	if err != nil {
+		t.Fatalf("BeginLogin: %v", err)
+	}
+	req := passkey.assert(t, options, 0)
+	got, err := svc.FinishLogin(req)
+	if err != nil {
+		t.Fatalf("FinishLogin: %v", err)
+	}
+	if got.ID != user.ID {
+		t.Fatalf("FinishLogin authenticated user %d, want %d", got.ID, user.ID)
+	}
+
+	if _, err := svc.FinishLogin(req); !errors.Is(err, ErrPasskeyChallenge) {
+		t.Errorf("replayed assertion: error = %v, want ErrPasskeyChallenge", err)
+	}
+
+	registration, err := svc.BeginRegistration(user.ID)
+	if err != nil {
+		t.Fatalf("BeginRegistration: %v", err)
+	}
+	req = passkey.assert(t, &models.PasskeyLoginOptions{ChallengeID: registration.ChallengeID, PublicKey: models.PasskeyRequestParameters{Challenge: registration.PublicKey.Challenge}}, 0)
+	if _, err := svc.FinishLogin(req); !errors.Is(err, ErrPasskeyChallenge) {
+		t.Errorf("registration challenge used to log in: error = %v, want ErrPasskeyChallenge", err)
+	}
+}
+
+func TestFinishLoginChecksSignCount(t *testing.T) {
+	tests := []struct {
+		name     string
+		stored   uint32
+		asserted uint32
+		wantErr  bool
+	}{
+		{"counter increases", 5, 6, false},
+		{"counter jumps", 5, 100, false},
+		{"counter repeats", 5, 5, true},
+		{"counter goes back", 5, 3, true},
+		{"counter reset to zero", 5, 0, true},
+		{"synced passkey without counter", 0, 0, false},
+		{"counter starts", 0, 1, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc, _, passkey := newTestPasskeyService(t, tt.stored)
+			options, err := svc.BeginLogin("")
+			if err != nil {
+				t.Fatalf("BeginLogin: %v", err)
+			}
+
+			_, err = svc.FinishLogin(passkey.assert(t, options, tt.asserted))
+			if tt.wantErr {
+				if !errors.Is(err, auth.ErrPasskeyInvalid) {
+					t.Fatalf("FinishLogin error = %v, want ErrPasskeyInvalid", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("FinishLogin: %v", err)
+			}
+			if tt.asserted == 0 {
+				return
+			}
+
+			// The new count is recorded, so it cannot be used again
+			options, err = svc.BeginLogin("")
+			if err != nil {
+				t.Fatalf("BeginLogin: %v", err)
+			}
+			if _, err := svc.FinishLogin(passkey.assert(t, options, tt.asserted)); !errors.Is(err, auth.ErrPasskeyInvalid) {
+				t.Errorf("reusing sign count %d: error = %v, want ErrPasskeyInvalid", tt.asserted, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,105 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+
+	"lio-ai/internal/models"
+	"lio-ai/internal/repositories"
+)
+
+// ErrPersonaNotFound is returned for unknown persona IDs
+var ErrPersonaNotFound = errors.New("persona not found")
+
+// PersonaService handles assistant persona business logic
+type PersonaService struct {
+	repo *repositories.PersonaRepository
+}
+
+// NewPersonaService creates a new persona service
+func NewPersonaService(repo *repositories.PersonaRepository) *PersonaService {
+	return &PersonaService{repo: repo}
+}
+
+// CreatePersona creates a persona owned by the user
+func (s *PersonaService) CreatePersona(userID string, req *models.PersonaRequest) (*models.Persona, error) {
+	p := &models.Persona{
+		UserID:       userID,
+		Name:         req.Name,
+		SystemPrompt: req.SystemPrompt,
+		DefaultModel: req.DefaultModel,
+		Temperature:  req.Temperature,
+	}
+	if err := s.repo.Create(p); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// GetPersona retrieves a persona owned by the user
+func (s *PersonaService) GetPersona(id int64, userID string) (*models.Persona, error) {
+	p, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if p == nil {
+		return nil, ErrPersonaNotFound
+	}
+	if p.UserID != userID {
+		return nil, ErrUnauthorized
+	}
+	return p, nil
+}
+
+// ListPersonas retrieves the user's personas
+func (s *PersonaService) ListPersonas(userID string) ([]models.Persona, error) {
+	return s.repo.ListByUser(userID)
+}
+
+// UpdatePersona updates a persona owned by the user
+func (s *PersonaService) UpdatePersona(id int64, userID string, req *models.UpdatePersonaRequest) (*models.Persona, error) {
+	p, err := s.GetPersona(id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Name != nil {
+		p.Name = *req.Name
+	}
+	if req.SystemPrompt != nil {
+		p.SystemPrompt = *req.SystemPrompt
+	}
+	if req.DefaultModel != nil {
+		p.DefaultModel = *req.DefaultModel
+	}
+	if req.Temperature != nil {
+		p.Temperature = req.Temperature
+	}
+	if req.ClearTemperature {
+		p.Temperature = nil
+	}
+
+	if err := s.repo.Update(p); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// DeletePersona deletes a persona owned by the user; chats using it go
+// back to having no persona
+func (s *PersonaService) DeletePersona(id int64, userID string) error {
+	if _, err := s.GetPersona(id, userID); err != nil {
+		return err
+	}
+	return s.repo.Delete(id)
+}
+
+// chatPersona checks that a persona can be attached to a chat owned by
+// userID. Personas belonging to someone else are reported as not found.
+func (s *PersonaService) chatPersona(id int64, userID string) (*models.Persona, error) {
+	p, err := s.GetPersona(id, userID)
+	if errors.Is(err, ErrPersonaNotFound) || errors.Is(err, ErrUnauthorized) {
+		return nil, fmt.Errorf("%w: %d", ErrPersonaNotFound, id)
+	}
+	return p, err
+}
@@ -0,0 +1,111 @@
+package services
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"lio-ai/internal/models"
+	"lio-ai/internal/repositories"
+)
+
+// PlanService manages plan tiers and moves users between them. A user's
+// quota starts with their plan's limits; changing plan resets the limits
+// to the new plan's.
+type PlanService struct {
+	repo  *repositories.PlanRepository
+	users *repositories.UserRepository
+	usage *UsageService
+}
+
+// NewPlanService creates a plan service
+func NewPlanService(repo *repositories.PlanRepository, users *repositories.UserRepository, usage *UsageService) *PlanService {
+	return &PlanService{repo: repo, users: users, usage: usage}
+}
+
+// List retrieves every plan
+func (s *PlanService) List() ([]models.Plan, error) {
+	return s.repo.List()
+}
+
+// Set creates a plan or changes its limits. With ApplyToUsers the quota
+// limits of the plan's users are reset to the new ones, and how many were
+// is returned; otherwise only users whose quota is created or who are
+// moved to the plan from now on get them.
+func (s *PlanService) Set(name string, req *models.SetPlanRequest) (*models.Plan, int64, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if !planPattern.MatchString(name) {
+		return nil, 0, fmt.Errorf("%w: invalid plan name %q", ErrInvalidMessage, name)
+	}
+	for _, v := range []*int{req.DailyTokenLimit, req.MonthlyTokenLimit, req.RequestsPerMinute} {
+		if v != nil && *v < 0 {
+			return nil, 0, fmt.Errorf("%w: limits must not be negative", ErrInvalidMessage)
+		}
+	}
+	for _, v := range []*float64{req.DailyCostLimitUSD, req.MonthlyCostLimitUSD} {
+		if v != nil && *v < 0 {
+			return nil, 0, fmt.Errorf("%w: limits must not be negative", ErrInvalidMessage)
+		}
+	}
+
+	plan, err := s.repo.Get(name)
+	if err != nil {
+		return nil, 0, err
+	}
+	if plan == nil {
+		if req.DailyTokenLimit == nil || req.MonthlyTokenLimit == nil || req.DailyCostLimitUSD == nil || req.MonthlyCostLimitUSD == nil {
+			return nil, 0, fmt.Errorf("%w: a new plan needs daily_token_limit, monthly_token_limit, daily_cost_limit_usd and monthly_cost_limit_usd", ErrInvalidMessage)
+		}
+		plan = &models.Plan{Name: name}
+	}
+	if req.DailyTokenLimit != nil {
+		plan.DailyTokenLimit = *req.DailyTokenLimit
+	}
+	if req.MonthlyTokenLimit != nil {
+		plan.MonthlyTokenLimit = *req.MonthlyTokenLimit
+	}
+	if req.DailyCostLimitUSD != nil {
+		plan.DailyCostLimitUSD = *req.DailyCostLimitUSD
+	}
+	if req.MonthlyCostLimitUSD != nil {
+		plan.MonthlyCostLimitUSD = *req.MonthlyCostLimitUSD
+	}
+	if req.RequestsPerMinute != nil {
+		plan.RequestsPerMinute = *req.RequestsPerMinute
+	}
+	if err := s.repo.Save(plan); err != nil {
+		return nil, 0, err
+	}
+
+	var updated int64
+	if req.ApplyToUsers {
+		if updated, err = s.repo.ApplyToUsers(name); err != nil {
+			return nil, 0, err
+		}
+	}
+	return plan, updated, nil
+}
+
+// AssignUser moves a user to a plan, resetting their quota limits to the
+// plan's, and returns their quota status. Usage so far is kept.
+func (s *PlanService) AssignUser(userID int64, planName string) (*models.QuotaStatus, error) {
+	user, err := s.users.FindByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, fmt.Errorf("%w: user %d", ErrNotFound, userID)
+	}
+	plan, err := s.repo.Get(strings.ToLower(strings.TrimSpace(planName)))
+	if err != nil {
+		return nil, err
+	}
+	if plan == nil {
+		return nil, fmt.Errorf("%w: unknown plan %q", ErrInvalidMessage, planName)
+	}
+
+	if err := s.repo.AssignUser(userID, plan.Name); err != nil {
+		return nil, err
+	}
+	return s.usage.GetQuotaStatus(strconv.FormatInt(userID, 10))
+}
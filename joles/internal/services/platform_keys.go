@@ -0,0 +1,54 @@
+package services
+
+import (
+	"net/http"
+)
+
+// Key sources recorded on usage rows
+const (
+	KeySourceUser     = "user"
+	KeySourcePlatform = "platform"
+)
+
+// SetPlatformKeys sets the platform-managed fallback keys by lowercase
+// provider, retried when a user's key is rejected or exhausted
+func (s *ChatService) SetPlatformKeys(keys map[string]string) {
+	s.platformKeys = keys
+}
+
+// platformKey returns the platform-managed fallback key for a provider, or
+// "" when it has none
+func (s *ChatService) platformKey(provider string) string {
+	return s.platformKeys[provider]
+}
+
+// SetPlatformKeyMarkup sets the multiplier applied to costs billed through
+// a platform key
+func (s *UsageService) SetPlatformKeyMarkup(markup float64) {
+	s.platformMarkup = markup
+}
+
+// platformKeyMarkup returns the multiplier applied to costs billed through
+// a platform key, 1 when unset
+func (s *UsageService) platformKeyMarkup() float64 {
+	if s.platformMarkup <= 0 {
+		return 1.0
+	}
+	return s.platformMarkup
+}
+
+// shouldFailover reports whether an AI service error indicates the user's key
+// was rejected or exhausted by the provider
+func shouldFailover(err error) bool {
+	aiErr, ok := IsAIServiceError(err)
+	if !ok {
+		return false
+	}
+
+	switch aiErr.StatusCode {
+	case http.StatusUnauthorized, http.StatusPaymentRequired, http.StatusForbidden, http.StatusTooManyRequests:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,61 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"lio-ai/internal/repositories"
+)
+
+func TestRejectedUserKeyFailsOverToPlatformKeyAtMarkup(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&payload)
+		if payload["api_key"] != "sk-platform" {
+			w.WriteHeader(http.StatusUnauthorized)
+			json.NewEncoder(w).Encode(map[string]string{"detail": "invalid api key"})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"choices": []map[string]interface{}{{"message": map[string]string{"role": "assistant", "content": "hi"}, "finish_reason": "stop"}},
+			"usage":   map[string]int{"prompt_tokens": 1000, "completion_tokens": 1000, "total_tokens": 2000},
+		})
+	}))
+	t.Cleanup(srv.Close)
+
+	conn := newTestDB(t)
+	user := createTestUser(t, conn, "failover", "user")
+	userID := fmt.Sprint(user.ID)
+	usageService := NewUsageService(repositories.NewUsageRepository(conn))
+	usageService.SetPlatformKeyMarkup(1.5)
+	s := NewChatService(repositories.NewChatRepository(conn), usageService)
+	s.SetAIServiceURL(srv.URL)
+	s.SetPlatformKeys(map[string]string{"openai": "sk-platform"})
+
+	target := completionTarget{UserID: userID, Model: "gpt-4", Endpoint: "/api/v1/chat/completions"}
+	resp, err := s.completeWithFailover(context.Background(), target, []map[string]interface{}{{"role": "user", "content": "hello"}}, nil)
+	if err != nil {
+		t.Fatalf("completion failed after failover: %v", err)
+	}
+	if resp.Content != "hi" {
+		t.Errorf("content = %q, want the platform key's reply", resp.Content)
+	}
+
+	var platformCost float64
+	err = conn.QueryRow("SELECT cost_usd FROM usage_metrics WHERE user_id = ? AND key_source = ? AND success = 1", userID, KeySourcePlatform).Scan(&platformCost)
+	if err != nil {
+		t.Fatalf("no successful platform key usage tracked: %v", err)
+	}
+	base, err := usageService.CalculateCost(1000, 1000, "gpt-4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(platformCost-base*1.5) > 1e-9 {
+		t.Errorf("platform key usage cost %v, want %v at the 1.5 markup", platformCost, base*1.5)
+	}
+}
@@ -0,0 +1,101 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+
+	"lio-ai/internal/models"
+	"lio-ai/internal/repositories"
+)
+
+// ErrInvalidPreferences is returned when a preference value is not valid
+var ErrInvalidPreferences = errors.New("invalid preferences")
+
+var providerNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// PreferencesService handles users' account-wide settings
+type PreferencesService struct {
+	repo *repositories.PreferencesRepository
+}
+
+// NewPreferencesService creates a new preferences service
+func NewPreferencesService(repo *repositories.PreferencesRepository) *PreferencesService {
+	return &PreferencesService{repo: repo}
+}
+
+// Get returns userID's preferences, with defaults when none are set
+func (s *PreferencesService) Get(userID string) (*models.UserPreferences, error) {
+	p, err := s.repo.Get(userID)
+	if err != nil {
+		return nil, err
+	}
+	if p == nil {
+		p = &models.UserPreferences{UserID: userID}
+	}
+	if p.ProviderOrder == nil {
+		p.ProviderOrder = []string{}
+	}
+	return p, nil
+}
+
+// Update changes the preferences set in req. Provider names are matched
+// case-insensitively and listed once, in the order first given.
+func (s *PreferencesService) Update(userID string, req *models.UpdatePreferencesRequest) (*models.UserPreferences, error) {
+	p, err := s.Get(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.ProviderOrder != nil {
+		order := make([]string, 0, len(*req.ProviderOrder))
+		seen := make(map[string]bool)
+		for _, provider := range *req.ProviderOrder {
+			provider = strings.ToLower(strings.TrimSpace(provider))
+			if !providerNamePattern.MatchString(provider) {
+				return nil, fmt.Errorf("%w: invalid provider name %q", ErrInvalidPreferences, provider)
+			}
+			if !seen[provider] {
+				seen[provider] = true
+				order = append(order, provider)
+			}
+		}
+		p.ProviderOrder = order
+	}
+
+	if err := s.repo.Set(p); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// ProviderOrder returns userID's provider preference, or nil when they
+// have none
+func (s *PreferencesService) ProviderOrder(userID string) ([]string, error) {
+	p, err := s.repo.Get(userID)
+	if err != nil || p == nil {
+		return nil, err
+	}
+	return p.ProviderOrder, nil
+}
+
+// SetPreferences routes logical models by each user's provider order
+func (s *ChatService) SetPreferences(preferences *PreferencesService) {
+	s.preferences = preferences
+}
+
+// providerOrder returns the user's provider preference for routing;
+// failures to load it fall back to latency routing
+func (s *ChatService) providerOrder(userID string) []string {
+	if s.preferences == nil || userID == "" {
+		return nil
+	}
+	order, err := s.preferences.ProviderOrder(userID)
+	if err != nil {
+		log.Printf("⚠️  Failed to load provider order for user %s: %v", userID, err)
+		return nil
+	}
+	return order
+}
@@ -0,0 +1,165 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"lio-ai/internal/models"
+)
+
+var (
+	// ErrPriceFeedNotConfigured is returned by SyncPrices without a feed
+	ErrPriceFeedNotConfigured = errors.New("no price feed is configured")
+	// ErrPriceFeedUnavailable is returned when the feed can't be read
+	ErrPriceFeedUnavailable = errors.New("price feed unavailable")
+)
+
+// maxPriceFeedBytes bounds the price feed document
+const maxPriceFeedBytes = 5 << 20
+
+// SetPriceFeed syncs prices from the JSON document (a models.PriceFeed) at
+// source: an http(s) URL, such as a curated feed or a provider's price
+// list converted to that shape, or a file path
+func (s *CostConfigService) SetPriceFeed(source string, timeout time.Duration) {
+	s.feed = source
+	s.feedClient = &http.Client{Timeout: timeout}
+}
+
+// StartPriceSync syncs prices from the feed on an interval until the
+// process exits; a zero interval or no feed disables periodic syncs
+func (s *CostConfigService) StartPriceSync(interval time.Duration) {
+	if interval <= 0 || s.feed == "" {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			report, err := s.SyncPrices(context.Background(), "")
+			if err != nil {
+				log.Printf("⚠️  Failed to sync prices: %v", err)
+			} else if len(report.Created) > 0 || len(report.Updated) > 0 || len(report.Skipped) > 0 {
+				log.Printf("💲 Price sync: %d created, %d updated, %d skipped",
+					len(report.Created), len(report.Updated), len(report.Skipped))
+			}
+			<-ticker.C
+		}
+	}()
+}
+
+// SyncPrices brings cost configs in line with the price feed: models it
+// lists are priced or repriced, recording each change in the price
+// history, and models it leaves out are left alone. Repriced models keep
+// whether they are active. changedBy is the admin who asked for the sync,
+// empty for scheduled ones.
+func (s *CostConfigService) SyncPrices(ctx context.Context, changedBy string) (*models.PriceSyncReport, error) {
+	if s.feed == "" {
+		return nil, ErrPriceFeedNotConfigured
+	}
+	s.syncing.Lock()
+	defer s.syncing.Unlock()
+
+	feed, err := s.readPriceFeed(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &models.PriceSyncReport{
+		Source:    s.feed,
+		StartedAt: time.Now().UTC(),
+		Created:   []string{},
+		Updated:   []string{},
+		Skipped:   []string{},
+	}
+	defer func() { report.CompletedAt = time.Now().UTC() }()
+
+	seen := make(map[string]bool, len(feed.Prices))
+	for i, entry := range feed.Prices {
+		name := strings.TrimSpace(entry.ModelName)
+		switch {
+		case name == "":
+			report.Skipped = append(report.Skipped, fmt.Sprintf("prices[%d]: model_name is required", i))
+			continue
+		case seen[name]:
+			report.Skipped = append(report.Skipped, fmt.Sprintf("%s: listed more than once", name))
+			continue
+		}
+		seen[name] = true
+
+		existing, err := s.repo.Get(name)
+		if err != nil {
+			return report, err
+		}
+		c := &models.CostConfig{ModelName: name, OperationType: "chat", IsActive: true}
+		if existing != nil {
+			*c = *existing
+		}
+		c.CostPerInputToken = entry.CostPerInputToken
+		c.CostPerOutputToken = entry.CostPerOutputToken
+		if entry.OperationType != "" {
+			c.OperationType = entry.OperationType
+		}
+		if err := validateCostConfig(c); err != nil {
+			report.Skipped = append(report.Skipped, fmt.Sprintf("%s: %v", name, err))
+			continue
+		}
+
+		switch {
+		case existing == nil:
+			if err := s.repo.Create(c, models.CostConfigSourceSync, changedBy); err != nil {
+				return report, err
+			}
+			report.Created = append(report.Created, name)
+		case *c == *existing:
+			report.Unchanged++
+		default:
+			if err := s.repo.Update(c, existing, models.CostConfigSourceSync, changedBy); err != nil {
+				return report, err
+			}
+			report.Updated = append(report.Updated, name)
+		}
+	}
+	return report, nil
+}
+
+// readPriceFeed fetches and decodes the price feed
+func (s *CostConfigService) readPriceFeed(ctx context.Context) (*models.PriceFeed, error) {
+	var body io.ReadCloser
+	if strings.HasPrefix(s.feed, "http://") || strings.HasPrefix(s.feed, "https://") {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.feed, nil)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrPriceFeedUnavailable, err)
+		}
+		req.Header.Set("Accept", "application/json")
+		resp, err := s.feedClient.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrPriceFeedUnavailable, err)
+		}
+		if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+			return nil, fmt.Errorf("%w: %s returned %d", ErrPriceFeedUnavailable, s.feed, resp.StatusCode)
+		}
+		body = resp.Body
+	} else {
+		f, err := os.Open(s.feed)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrPriceFeedUnavailable, err)
+		}
+		body = f
+	}
+	defer body.Close()
+
+	var feed models.PriceFeed
+	if err := json.NewDecoder(io.LimitReader(body, maxPriceFeedBytes)).Decode(&feed); err != nil {
+		return nil, fmt.Errorf("%w: invalid price feed: %v", ErrPriceFeedUnavailable, err)
+	}
+	return &feed, nil
+}
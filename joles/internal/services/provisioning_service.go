@@ -0,0 +1,394 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	netmail "net/mail"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"lio-ai/internal/auth"
+	"lio-ai/internal/mail"
+	"lio-ai/internal/models"
+	"lio-ai/internal/repositories"
+)
+
+const (
+	// MaxImportRows caps the rows accepted in one bulk import
+	MaxImportRows = 10000
+	// invitationTTL is how long an invitation link can be redeemed
+	invitationTTL = 7 * 24 * time.Hour
+	// uninvitedPasswordHash is stored until an invitation is accepted; it is
+	// not a valid bcrypt hash, so no password can match it
+	uninvitedPasswordHash = "!invited"
+)
+
+var (
+	// ErrInvalidImport is returned for an unreadable or empty import file
+	ErrInvalidImport = errors.New("invalid import file")
+	// ErrInvalidInvitation is returned for unknown, used or expired invitation tokens
+	ErrInvalidInvitation = errors.New("invitation is invalid or has expired")
+	// ErrUserExists is returned when provisioning an email that is already registered
+	ErrUserExists = errors.New("email already registered")
+)
+
+var (
+	provisionRoles   = map[string]bool{"user": true, "developer": true, "admin": true}
+	planPattern      = regexp.MustCompile(`^[a-z0-9_-]{1,50}$`)
+	usernameDisallow = regexp.MustCompile(`[^a-z0-9_.-]+`)
+)
+
+// ProvisioningService creates invited accounts for bulk imports and SCIM
+type ProvisioningService struct {
+	users       *repositories.UserRepository
+	invitations *repositories.InvitationRepository
+	jobs        *repositories.UserImportRepository
+	mailer      mail.Mailer
+	inviteURL   string
+}
+
+// NewProvisioningService creates a new provisioning service
+func NewProvisioningService(users *repositories.UserRepository, invitations *repositories.InvitationRepository, jobs *repositories.UserImportRepository, mailer mail.Mailer, inviteURL string) *ProvisioningService {
+	return &ProvisioningService{
+		users:       users,
+		invitations: invitations,
+		jobs:        jobs,
+		mailer:      mailer,
+		inviteURL:   inviteURL,
+	}
+}
+
+// ParseUserCSV reads import rows from CSV with a header row naming the
+// columns email (required), name, role and plan, in any order
+func ParseUserCSV(r io.Reader) ([]models.UserImportRow, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, fmt.Errorf("%w: file is empty", ErrInvalidImport)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidImport, err)
+	}
+
+	columns := make(map[string]int)
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))] = i
+	}
+	if _, ok := columns["email"]; !ok {
+		return nil, fmt.Errorf("%w: header must include an email column", ErrInvalidImport)
+	}
+	field := func(record []string, name string) string {
+		if i, ok := columns[name]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	var rows []models.UserImportRow
+	for line := 1; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: row %d: %v", ErrInvalidImport, line, err)
+		}
+		if len(rows) == MaxImportRows {
+			return nil, fmt.Errorf("%w: more than %d rows", ErrInvalidImport, MaxImportRows)
+		}
+		rows = append(rows, models.UserImportRow{
+			Row:      line,
+			Email:    field(record, "email"),
+			FullName: field(record, "name"),
+			Role:     field(record, "role"),
+			Plan:     field(record, "plan"),
+		})
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("%w: no rows after the header", ErrInvalidImport)
+	}
+	return rows, nil
+}
+
+// StartImport queues an import job and provisions its rows in the background
+func (s *ProvisioningService) StartImport(adminID, source string, rows []models.UserImportRow) (*models.UserImportJob, error) {
+	job := &models.UserImportJob{
+		CreatedBy: adminID,
+		Source:    source,
+		TotalRows: len(rows),
+	}
+	if err := s.jobs.Create(job); err != nil {
+		return nil, err
+	}
+
+	go s.runImport(*job, rows)
+	return job, nil
+}
+
+// runImport provisions rows one by one, saving progress as it goes so the
+// job can be polled
+func (s *ProvisioningService) runImport(job models.UserImportJob, rows []models.UserImportRow) {
+	job.Status = models.ImportStatusRunning
+	s.saveImportProgress(&job)
+
+	seen := make(map[string]bool)
+	for i, row := range rows {
+		email := strings.ToLower(row.Email)
+		var err error
+		if seen[email] {
+			err = fmt.Errorf("duplicate email in file")
+		} else {
+			seen[email] = true
+			_, err = s.ProvisionUser(row)
+		}
+
+		job.Processed++
+		if err != nil {
+			job.Failed++
+			job.Errors = append(job.Errors, models.UserImportError{Row: row.Row, Email: row.Email, Error: err.Error()})
+		} else {
+			job.Created++
+		}
+		if (i+1)%50 == 0 {
+			s.saveImportProgress(&job)
+		}
+	}
+
+	now := time.Now()
+	job.Status = models.ImportStatusCompleted
+	job.CompletedAt = &now
+	s.saveImportProgress(&job)
+	log.Printf("✅ User import %d finished: %d created, %d failed", job.ID, job.Created, job.Failed)
+}
+
+func (s *ProvisioningService) saveImportProgress(job *models.UserImportJob) {
+	if err := s.jobs.UpdateProgress(job); err != nil {
+		log.Printf("Failed to save user import %d progress: %v", job.ID, err)
+	}
+}
+
+// GetImportJob retrieves an import job
+func (s *ProvisioningService) GetImportJob(id int64) (*models.UserImportJob, error) {
+	job, err := s.jobs.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if job == nil {
+		return nil, ErrNotFound
+	}
+	return job, nil
+}
+
+// FailInterruptedImports marks imports cut short by a restart
+func (s *ProvisioningService) FailInterruptedImports() error {
+	return s.jobs.FailInterrupted()
+}
+
+// ProvisionUser creates an inactive account and emails an invitation to
+// set its password. Role defaults to "user" and plan to "free".
+func (s *ProvisioningService) ProvisionUser(row models.UserImportRow) (*models.User, error) {
+	addr, err := netmail.ParseAddress(row.Email)
+	if err != nil || addr.Address != row.Email {
+		return nil, fmt.Errorf("invalid email address")
+	}
+	email := strings.ToLower(addr.Address)
+
+	role := strings.ToLower(row.Role)
+	if role == "" {
+		role = "user"
+	}
+	if !provisionRoles[role] {
+		return nil, fmt.Errorf("invalid role %q (must be user, developer or admin)", row.Role)
+	}
+	plan := strings.ToLower(row.Plan)
+	if plan == "" {
+		plan = "free"
+	}
+	if !planPattern.MatchString(plan) {
+		return nil, fmt.Errorf("invalid plan %q", row.Plan)
+	}
+
+	if existing, err := s.users.FindByEmail(email); err != nil {
+		return nil, err
+	} else if existing != nil {
+		return nil, ErrUserExists
+	}
+
+	username, err := s.availableUsername(email)
+	if err != nil {
+		return nil, err
+	}
+
+	user := &models.User{
+		Username:     username,
+		Email:        email,
+		PasswordHash: uninvitedPasswordHash,
+		FullName:     row.FullName,
+		Role:         role,
+		Plan:         plan,
+		IsActive:     false,
+	}
+	if err := s.users.Create(user); err != nil {
+		return nil, err
+	}
+
+	if err := s.sendInvitation(user); err != nil {
+		// The account is kept; a failed delivery is only logged
+		log.Printf("⚠️  Failed to send invitation to %s: %v", user.Email, err)
+	}
+	return user, nil
+}
+
+// availableUsername derives a unique username from an email's local part
+func (s *ProvisioningService) availableUsername(email string) (string, error) {
+	base := usernameDisallow.ReplaceAllString(strings.SplitN(email, "@", 2)[0], "")
+	if len(base) > 40 {
+		base = base[:40]
+	}
+	for len(base) < 3 {
+		base += "_"
+	}
+
+	for i := 1; i <= 100; i++ {
+		candidate := base
+		if i > 1 {
+			candidate = fmt.Sprintf("%s%d", base, i)
+		}
+		taken, err := s.users.UsernameExists(candidate)
+		if err != nil {
+			return "", err
+		}
+		if !taken {
+			return candidate, nil
+		}
+	}
+
+	token, err := newInvitationToken()
+	if err != nil {
+		return "", err
+	}
+	return base + "_" + token[:8], nil
+}
+
+// sendInvitation creates an invitation token and emails its link
+func (s *ProvisioningService) sendInvitation(user *models.User) error {
+	token, err := newInvitationToken()
+	if err != nil {
+		return err
+	}
+	if err := s.invitations.Create(user.ID, hashInvitationToken(token), time.Now().Add(invitationTTL)); err != nil {
+		return err
+	}
+
+	link := s.inviteURL + "?token=" + url.QueryEscape(token)
+	body := fmt.Sprintf(
+		"Hello %s,\n\nAn account has been created for you on Lio AI.\n\nSet your password within 7 days:\n%s\n",
+		displayName(user), link,
+	)
+	return s.mailer.Send(user.Email, "You're invited to Lio AI", body)
+}
+
+// AcceptInvitation sets the password for an invited account and activates it
+func (s *ProvisioningService) AcceptInvitation(token, password string) (*models.User, error) {
+	if err := auth.ValidatePassword(password); err != nil {
+		return nil, err
+	}
+
+	userID, err := s.invitations.Accept(hashInvitationToken(token))
+	if err != nil {
+		return nil, err
+	}
+	if userID == 0 {
+		return nil, ErrInvalidInvitation
+	}
+
+	hash, err := auth.HashPassword(password)
+	if err != nil {
+		return nil, errors.New("failed to process password")
+	}
+	if err := s.users.ActivateWithPassword(userID, hash); err != nil {
+		return nil, fmt.Errorf("failed to activate account: %w", err)
+	}
+	return s.users.FindByID(userID)
+}
+
+// ListUsers retrieves a page of users, including invited and deactivated ones
+func (s *ProvisioningService) ListUsers(limit, offset int) ([]models.User, int, error) {
+	users, err := s.users.List(limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	total, err := s.users.Count()
+	if err != nil {
+		return nil, 0, err
+	}
+	return users, total, nil
+}
+
+// FindUserByEmail retrieves a user by email, including invited and deactivated ones
+func (s *ProvisioningService) FindUserByEmail(email string) (*models.User, error) {
+	return s.users.FindByEmail(email)
+}
+
+// GetUser retrieves a user by ID, including invited and deactivated ones
+func (s *ProvisioningService) GetUser(id int64) (*models.User, error) {
+	user, err := s.users.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrNotFound
+	}
+	return user, nil
+}
+
+// UpdateUser changes a provisioned user's name and active flag. Accounts
+// that never accepted their invitation stay unable to log in.
+func (s *ProvisioningService) UpdateUser(id int64, fullName *string, active *bool) (*models.User, error) {
+	user, err := s.GetUser(id)
+	if err != nil {
+		return nil, err
+	}
+	if fullName != nil {
+		user.FullName = *fullName
+	}
+	if active != nil {
+		user.IsActive = *active
+	}
+	if err := s.users.UpdateProvisioned(user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+func displayName(user *models.User) string {
+	if user.FullName != "" {
+		return user.FullName
+	}
+	return user.Username
+}
+
+// newInvitationToken returns a random URL-safe token
+func newInvitationToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate token: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func hashInvitationToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
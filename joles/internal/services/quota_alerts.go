@@ -0,0 +1,180 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"time"
+
+	"lio-ai/internal/mail"
+	"lio-ai/internal/models"
+	"lio-ai/internal/repositories"
+)
+
+// SetQuotaAlerts alerts users as their usage crosses thresholds of their
+// quota limits, by webhook (when webhooks is set) and email (when mailer
+// is), recording each alert in alerts
+func (s *UsageService) SetQuotaAlerts(alerts *repositories.QuotaAlertRepository, webhooks *WebhookService, mailer mail.Mailer, users *repositories.UserRepository) {
+	s.alerts = alerts
+	s.alertWebhooks = webhooks
+	s.alertMailer = mailer
+	s.alertUsers = users
+}
+
+// GetQuotaAlertSettings retrieves when and how userID is alerted
+func (s *UsageService) GetQuotaAlertSettings(userID string) (*models.QuotaAlertSettings, error) {
+	if s.alerts == nil {
+		return nil, fmt.Errorf("%w: quota alerts are not enabled", ErrInvalidMessage)
+	}
+	return s.alerts.GetSettings(userID)
+}
+
+// UpdateQuotaAlertSettings changes when and how userID is alerted
+func (s *UsageService) UpdateQuotaAlertSettings(userID string, req *models.UpdateQuotaAlertSettingsRequest) (*models.QuotaAlertSettings, error) {
+	settings, err := s.GetQuotaAlertSettings(userID)
+	if err != nil {
+		return nil, err
+	}
+	if req.Thresholds != nil {
+		seen := make(map[int]bool, len(req.Thresholds))
+		thresholds := make([]int, 0, len(req.Thresholds))
+		for _, t := range req.Thresholds {
+			if !seen[t] {
+				seen[t] = true
+				thresholds = append(thresholds, t)
+			}
+		}
+		sort.Ints(thresholds)
+		settings.Thresholds = thresholds
+	}
+	if req.Webhook != nil {
+		settings.Webhook = *req.Webhook
+	}
+	if req.Email != nil {
+		settings.Email = *req.Email
+	}
+	if err := s.alerts.SaveSettings(userID, settings); err != nil {
+		return nil, err
+	}
+	return settings, nil
+}
+
+// ListQuotaAlerts retrieves the alerts raised for userID, newest first,
+// and how many there are in all
+func (s *UsageService) ListQuotaAlerts(userID string, limit, offset int) ([]models.QuotaAlert, int, error) {
+	if s.alerts == nil {
+		return []models.QuotaAlert{}, 0, nil
+	}
+	alerts, err := s.alerts.ListByUser(userID, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	total, err := s.alerts.CountByUser(userID)
+	if err != nil {
+		return nil, 0, err
+	}
+	return alerts, total, nil
+}
+
+// checkQuotaAlerts raises an alert for each of userID's thresholds their
+// usage has reached this day or month and wasn't alerted on yet. Failures
+// are logged rather than failing the usage being tracked.
+func (s *UsageService) checkQuotaAlerts(userID string) {
+	if s.alerts == nil {
+		return
+	}
+	settings, err := s.alerts.GetSettings(userID)
+	if err != nil {
+		log.Printf("⚠️  Failed to get quota alert settings for %s: %v", userID, err)
+		return
+	}
+	if len(settings.Thresholds) == 0 {
+		return
+	}
+	quota, err := s.usageRepo.GetUserQuota(userID)
+	if err != nil {
+		log.Printf("⚠️  Failed to get quota of %s for alerts: %v", userID, err)
+		return
+	}
+
+	var channels []string
+	if settings.Webhook && s.alertWebhooks != nil {
+		channels = append(channels, "webhook")
+	}
+	if settings.Email && s.alertMailer != nil {
+		channels = append(channels, "email")
+	}
+
+	metrics := []struct {
+		name        string
+		used, limit float64
+		periodStart time.Time
+	}{
+		{models.QuotaMetricDailyTokens, float64(quota.DailyTokensUsed), float64(quota.DailyTokenLimit), quota.LastResetDaily},
+		{models.QuotaMetricMonthlyTokens, float64(quota.MonthlyTokensUsed), float64(quota.MonthlyTokenLimit), quota.LastResetMonthly},
+		{models.QuotaMetricDailyCost, quota.DailyCostUsedUSD, quota.DailyCostLimitUSD, quota.LastResetDaily},
+		{models.QuotaMetricMonthlyCost, quota.MonthlyCostUsedUSD, quota.MonthlyCostLimitUSD, quota.LastResetMonthly},
+	}
+	for _, m := range metrics {
+		if m.limit <= 0 {
+			continue
+		}
+		for _, threshold := range settings.Thresholds {
+			if m.used*100 < float64(threshold)*m.limit {
+				break
+			}
+			alert := &models.QuotaAlert{
+				UserID: userID, Metric: m.name, Threshold: threshold,
+				Used: m.used, Limit: m.limit, PeriodStart: m.periodStart, Channels: channels,
+			}
+			recorded, err := s.alerts.Record(alert)
+			if err != nil {
+				log.Printf("⚠️  Failed to record quota alert for %s: %v", userID, err)
+				return
+			}
+			if recorded {
+				s.sendQuotaAlert(alert)
+			}
+		}
+	}
+}
+
+// sendQuotaAlert tells the user about an alert on its channels
+func (s *UsageService) sendQuotaAlert(alert *models.QuotaAlert) {
+	for _, channel := range alert.Channels {
+		switch channel {
+		case "webhook":
+			s.alertWebhooks.Dispatch(alert.UserID, models.WebhookEventQuotaThreshold, quotaAlertEventData(alert))
+		case "email":
+			go s.emailQuotaAlert(alert)
+		}
+	}
+}
+
+func (s *UsageService) emailQuotaAlert(alert *models.QuotaAlert) {
+	id, err := strconv.ParseInt(alert.UserID, 10, 64)
+	if err != nil || s.alertUsers == nil {
+		return
+	}
+	user, err := s.alertUsers.FindByID(id)
+	if err != nil || user == nil || user.Email == "" {
+		log.Printf("⚠️  No email address for quota alert %d: %v", alert.ID, err)
+		return
+	}
+
+	period, limit := "daily", fmt.Sprintf("%.0f tokens", alert.Limit)
+	if alert.Metric == models.QuotaMetricMonthlyTokens || alert.Metric == models.QuotaMetricMonthlyCost {
+		period = "monthly"
+	}
+	if alert.Metric == models.QuotaMetricDailyCost || alert.Metric == models.QuotaMetricMonthlyCost {
+		limit = fmt.Sprintf("$%.2f", alert.Limit)
+	}
+	subject := fmt.Sprintf("You've used %d%% of your %s Lio AI quota", alert.Threshold, period)
+	body := fmt.Sprintf("Your usage has reached %d%% of your %s limit of %s.\n\n"+
+		"Requests that would go past the limit are refused until the quota resets. "+
+		"You can change when you get these alerts under usage alert settings.\n", alert.Threshold, period, limit)
+	if err := s.alertMailer.Send(user.Email, subject, body); err != nil {
+		log.Printf("⚠️  Failed to email quota alert %d: %v", alert.ID, err)
+	}
+}
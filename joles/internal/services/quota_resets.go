@@ -0,0 +1,68 @@
+package services
+
+import (
+	"log"
+	"time"
+)
+
+// SetQuotaResetLocation starts quota days at midnight and months on the 1st
+// in loc rather than in UTC
+func (s *UsageService) SetQuotaResetLocation(loc *time.Location) {
+	s.resetLocation = loc
+}
+
+// quotaPeriodStarts returns when the quota day and month containing now
+// started
+func (s *UsageService) quotaPeriodStarts(now time.Time) (time.Time, time.Time) {
+	loc := s.resetLocation
+	if loc == nil {
+		loc = time.UTC
+	}
+	now = now.In(loc)
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+	month := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, loc)
+	return day, month
+}
+
+// nextQuotaResets returns when the quota day and month containing now end
+func (s *UsageService) nextQuotaResets(now time.Time) (time.Time, time.Time) {
+	day, month := s.quotaPeriodStarts(now)
+	return day.AddDate(0, 0, 1), month.AddDate(0, 1, 0)
+}
+
+// ResetDueQuotas resets the daily usage of quotas last reset before today
+// began, and the monthly usage of those last reset before this month,
+// returning how many of each were reset
+func (s *UsageService) ResetDueQuotas(now time.Time) (int64, int64, error) {
+	day, month := s.quotaPeriodStarts(now)
+	daily, err := s.usageRepo.ResetDailyQuotas(day)
+	if err != nil {
+		return 0, 0, err
+	}
+	monthly, err := s.usageRepo.ResetMonthlyQuotas(month)
+	if err != nil {
+		return daily, 0, err
+	}
+	return daily, monthly, nil
+}
+
+// StartQuotaResets resets quotas as days and months begin, checking on an
+// interval (a minute when not positive) until the process exits
+func (s *UsageService) StartQuotaResets(interval time.Duration) {
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			daily, monthly, err := s.ResetDueQuotas(time.Now())
+			if err != nil {
+				log.Printf("⚠️  Failed to reset quotas: %v", err)
+			} else if daily > 0 || monthly > 0 {
+				log.Printf("🔄 Quota reset: %d daily, %d monthly", daily, monthly)
+			}
+			<-ticker.C
+		}
+	}()
+}
@@ -0,0 +1,113 @@
+package services
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"lio-ai/internal/models"
+)
+
+// RequestRegistry tracks the requests being served, so admins can see
+// what is running and cancel a request stuck on a hung provider
+type RequestRegistry struct {
+	mu       sync.Mutex
+	requests map[string]*trackedRequest
+}
+
+// trackedRequest is a registered request and the cancel func of its context
+type trackedRequest struct {
+	info   models.InflightRequest
+	cancel context.CancelFunc
+}
+
+type trackedRequestKey struct{}
+
+// NewRequestRegistry creates an empty request registry
+func NewRequestRegistry() *RequestRegistry {
+	return &RequestRegistry{requests: make(map[string]*trackedRequest)}
+}
+
+// Begin registers a request and returns its ID with the context to serve
+// it under, cancelled by Cancel. The returned func unregisters it and
+// must be called once the request is done.
+func (r *RequestRegistry) Begin(ctx context.Context, method, route, path, userID string) (string, context.Context, func()) {
+	ctx, cancel := context.WithCancel(ctx)
+	req := &trackedRequest{
+		info: models.InflightRequest{
+			ID:        uuid.New().String(),
+			Method:    method,
+			Route:     route,
+			Path:      path,
+			UserID:    userID,
+			StartedAt: time.Now(),
+		},
+		cancel: cancel,
+	}
+
+	r.mu.Lock()
+	r.requests[req.info.ID] = req
+	r.mu.Unlock()
+
+	ctx = context.WithValue(ctx, trackedRequestKey{}, &registeredRequest{registry: r, request: req})
+	return req.info.ID, ctx, func() {
+		r.mu.Lock()
+		delete(r.requests, req.info.ID)
+		r.mu.Unlock()
+		cancel()
+	}
+}
+
+// List returns the requests being served, longest running first
+func (r *RequestRegistry) List() []models.InflightRequest {
+	now := time.Now()
+	r.mu.Lock()
+	requests := make([]models.InflightRequest, 0, len(r.requests))
+	for _, req := range r.requests {
+		info := req.info
+		info.ElapsedMS = now.Sub(info.StartedAt).Milliseconds()
+		requests = append(requests, info)
+	}
+	r.mu.Unlock()
+
+	sort.Slice(requests, func(i, j int) bool { return requests[i].StartedAt.Before(requests[j].StartedAt) })
+	return requests
+}
+
+// Cancel cancels the context of a request being served, reporting whether
+// there was one with that ID
+func (r *RequestRegistry) Cancel(id string) bool {
+	r.mu.Lock()
+	req, ok := r.requests[id]
+	if ok {
+		req.info.Cancelled = true
+	}
+	r.mu.Unlock()
+
+	if ok {
+		req.cancel()
+	}
+	return ok
+}
+
+// registeredRequest ties a request to the registry it is tracked in
+type registeredRequest struct {
+	registry *RequestRegistry
+	request  *trackedRequest
+}
+
+// MarkUpstream records the service, and optionally the model, the request
+// served under ctx is now waiting on. It does nothing for requests that
+// aren't tracked.
+func MarkUpstream(ctx context.Context, target, model string) {
+	reg, ok := ctx.Value(trackedRequestKey{}).(*registeredRequest)
+	if !ok {
+		return
+	}
+	reg.registry.mu.Lock()
+	reg.request.info.Upstream = target
+	reg.request.info.UpstreamModel = model
+	reg.registry.mu.Unlock()
+}
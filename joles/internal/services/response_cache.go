@@ -0,0 +1,143 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"lio-ai/internal/models"
+)
+
+// ResponseCache keeps completions for identical requests, keyed on the
+// model and a hash of the normalized message history, so repeated prompts
+// are answered without calling (or billing) the provider again
+type ResponseCache struct {
+	ttl        time.Duration
+	maxEntries int
+
+	mu      sync.Mutex
+	entries map[string]cachedResponse
+
+	hits   atomic.Int64
+	misses atomic.Int64
+}
+
+// cachedResponse is a stored completion and when it stops being served
+type cachedResponse struct {
+	response  AIServiceResponse
+	expiresAt time.Time
+}
+
+// SetResponseCache answers repeated identical completion requests from cache
+func (s *ChatService) SetResponseCache(cache *ResponseCache) {
+	s.cache = cache
+}
+
+// NewResponseCache creates a response cache holding up to maxEntries
+// completions for ttl each
+func NewResponseCache(ttl time.Duration, maxEntries int) *ResponseCache {
+	return &ResponseCache{
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		entries:    make(map[string]cachedResponse),
+	}
+}
+
+// Key hashes the model, the messages sent to it and any request options
+// (tools, max_tokens). Role names and surrounding whitespace in text
+// content are normalized so trivially different histories share a key.
+func (c *ResponseCache) Key(model string, messages []map[string]interface{}, extra map[string]interface{}) string {
+	normalized := make([]map[string]interface{}, len(messages))
+	for i, m := range messages {
+		n := make(map[string]interface{}, len(m))
+		for k, v := range m {
+			if text, ok := v.(string); ok {
+				switch k {
+				case "role":
+					v = strings.ToLower(strings.TrimSpace(text))
+				case "content":
+					v = strings.TrimSpace(text)
+				}
+			}
+			n[k] = v
+		}
+		normalized[i] = n
+	}
+
+	// encoding/json sorts map keys, so equal requests encode identically
+	payload, _ := json.Marshal(map[string]interface{}{
+		"model":    strings.ToLower(strings.TrimSpace(model)),
+		"messages": normalized,
+		"extra":    extra,
+	})
+	sum := sha256.Sum256(payload)
+	return hex.EncodeToString(sum[:])
+}
+
+// Get returns a copy of the cached completion for key, counting the lookup
+// as a hit or a miss
+func (c *ResponseCache) Get(key string) (*AIServiceResponse, bool) {
+	c.mu.Lock()
+	entry, ok := c.entries[key]
+	if ok && time.Now().After(entry.expiresAt) {
+		delete(c.entries, key)
+		ok = false
+	}
+	c.mu.Unlock()
+
+	if !ok {
+		c.misses.Add(1)
+		return nil, false
+	}
+	c.hits.Add(1)
+	resp := entry.response
+	return &resp, true
+}
+
+// Put stores a completion under key. When the cache is full, expired
+// entries are dropped first, then the entry closest to expiry.
+func (c *ResponseCache) Put(key string, resp *AIServiceResponse) {
+	now := time.Now()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, exists := c.entries[key]; !exists && c.maxEntries > 0 && len(c.entries) >= c.maxEntries {
+		var oldestKey string
+		var oldest time.Time
+		for k, e := range c.entries {
+			if now.After(e.expiresAt) {
+				delete(c.entries, k)
+				continue
+			}
+			if oldestKey == "" || e.expiresAt.Before(oldest) {
+				oldestKey, oldest = k, e.expiresAt
+			}
+		}
+		if len(c.entries) >= c.maxEntries {
+			delete(c.entries, oldestKey)
+		}
+	}
+	c.entries[key] = cachedResponse{response: *resp, expiresAt: now.Add(c.ttl)}
+}
+
+// Stats returns the hit and miss counters
+func (c *ResponseCache) Stats() models.ResponseCacheStats {
+	c.mu.Lock()
+	entries := len(c.entries)
+	c.mu.Unlock()
+
+	stats := models.ResponseCacheStats{
+		Hits:    c.hits.Load(),
+		Misses:  c.misses.Load(),
+		Entries: entries,
+		TTL:     c.ttl.String(),
+	}
+	if total := stats.Hits + stats.Misses; total > 0 {
+		stats.HitRate = float64(stats.Hits) / float64(total)
+	}
+	return stats
+}
@@ -0,0 +1,115 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"unicode/utf8"
+
+	"lio-ai/internal/models"
+	"lio-ai/internal/tokenizer"
+)
+
+// ErrCostCapExceeded is returned when the prompt alone would exceed max_cost_usd
+var ErrCostCapExceeded = errors.New("max_cost_usd exceeded")
+
+// responseCaps are the output token and cost limits for one response.
+// Zero means no limit.
+type responseCaps struct {
+	MaxTokens  int
+	MaxCostUSD float64
+}
+
+// resolveResponseCaps combines request and chat caps; the lower non-zero wins
+func resolveResponseCaps(req *models.ChatCompletionRequest, chat *models.Chat) responseCaps {
+	caps := responseCaps{MaxTokens: req.MaxTokens, MaxCostUSD: req.MaxCostUSD}
+	if chat != nil {
+		caps.MaxTokens = lowerCap(caps.MaxTokens, chat.MaxOutputTokens)
+		caps.MaxCostUSD = lowerCostCap(caps.MaxCostUSD, chat.MaxCostUSD)
+	}
+	return caps
+}
+
+// lowerCap returns the lower of two caps, ignoring unset (zero) ones
+func lowerCap(a, b int) int {
+	if a == 0 || (b != 0 && b < a) {
+		return b
+	}
+	return a
+}
+
+func lowerCostCap(a, b float64) float64 {
+	if a == 0 || (b != 0 && b < a) {
+		return b
+	}
+	return a
+}
+
+// outputTokenLimit returns the max_tokens to send to the provider, or 0 for
+// none. A cost cap is converted to tokens using the model's prices and an
+// estimate of the prompt; when the prompt alone is over the cap the
+// request is rejected before anything is sent.
+func (s *ChatService) outputTokenLimit(caps responseCaps, model string, history []models.Message, message string) (int, error) {
+	if caps.MaxCostUSD == 0 || s.usageService == nil {
+		return caps.MaxTokens, nil
+	}
+
+	promptTokens := tokenizer.CountMessages(model, []tokenizer.Message{{Role: "user", Content: message}})
+	for _, msg := range s.buildAIMessages(history) {
+		promptTokens += messageTokens(model, msg)
+	}
+	if budget := contextBudget(model); promptTokens > budget {
+		promptTokens = budget
+	}
+
+	costModel := model
+	if costModel == "" {
+		costModel = "default"
+	}
+	inputCost, err := s.usageService.CalculateCost(promptTokens, 0, costModel)
+	if err != nil {
+		return 0, err
+	}
+	if inputCost >= caps.MaxCostUSD {
+		return 0, fmt.Errorf("%w: prompt is estimated at $%.6f, above the $%.6f cap", ErrCostCapExceeded, inputCost, caps.MaxCostUSD)
+	}
+
+	// Cost of 1000 output tokens, the unit prices are quoted in
+	outputCost, err := s.usageService.CalculateCost(0, 1000, costModel)
+	if err != nil {
+		return 0, err
+	}
+	if outputCost <= 0 {
+		return caps.MaxTokens, nil
+	}
+
+	affordable := int((caps.MaxCostUSD - inputCost) / outputCost * 1000)
+	if affordable < 1 {
+		return 0, fmt.Errorf("%w: no room left for a response within $%.6f", ErrCostCapExceeded, caps.MaxCostUSD)
+	}
+	return lowerCap(caps.MaxTokens, affordable), nil
+}
+
+// enforceOutputLimit flags a response that hit the token limit and trims
+// output from providers that ignored max_tokens. Usage has already been
+// recorded with the provider's actual token counts.
+func enforceOutputLimit(resp *AIServiceResponse, limit int) bool {
+	if limit == 0 || resp == nil {
+		return false
+	}
+	if resp.CompletionTokens > limit {
+		resp.Content = truncateRunes(resp.Content, limit*4)
+		return true
+	}
+	return resp.FinishReason == "length" || resp.CompletionTokens == limit
+}
+
+// truncateRunes cuts text to at most maxBytes without splitting a character
+func truncateRunes(text string, maxBytes int) string {
+	if len(text) <= maxBytes {
+		return text
+	}
+	for maxBytes > 0 && !utf8.RuneStart(text[maxBytes]) {
+		maxBytes--
+	}
+	return text[:maxBytes]
+}
@@ -0,0 +1,246 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"lio-ai/internal/models"
+	"lio-ai/internal/repositories"
+)
+
+var (
+	// ErrUnknownResponsePlugin is returned when configuring a plugin that is
+	// not registered
+	ErrUnknownResponsePlugin = errors.New("unknown response plugin")
+	// ErrInvalidPluginOptions is returned when a plugin's options are not
+	// valid for it
+	ErrInvalidPluginOptions = errors.New("invalid response plugin options")
+)
+
+// ResponsePlugin post-processes assistant output before it is saved and
+// returned. Plugins must be safe for concurrent use; options come from
+// the user's or persona's configuration.
+type ResponsePlugin interface {
+	// Name identifies the plugin in configurations
+	Name() string
+	// Describe documents the plugin and its options for clients
+	Describe() models.ResponsePluginInfo
+	// Process returns the transformed content
+	Process(ctx context.Context, content string, options map[string]string) (string, error)
+}
+
+// responsePluginValidator is implemented by plugins whose option values
+// need checking beyond their names
+type responsePluginValidator interface {
+	Validate(options map[string]string) error
+}
+
+// ResponsePluginService runs the configured plugin chain over assistant
+// output and keeps per-plugin timing metrics. A failing plugin is skipped
+// so post-processing never loses a response.
+type ResponsePluginService struct {
+	repo    *repositories.ResponsePluginRepository
+	plugins map[string]ResponsePlugin
+
+	mu    sync.Mutex
+	stats map[string]*models.ResponsePluginStats
+}
+
+// NewResponsePluginService creates a plugin service with the built-in
+// plugins registered
+func NewResponsePluginService(repo *repositories.ResponsePluginRepository) *ResponsePluginService {
+	s := &ResponsePluginService{
+		repo:    repo,
+		plugins: make(map[string]ResponsePlugin),
+		stats:   make(map[string]*models.ResponsePluginStats),
+	}
+	for _, p := range builtinResponsePlugins() {
+		s.Register(p)
+	}
+	return s
+}
+
+// Register adds a plugin, replacing any registered under the same name.
+// It must be called before the service is used.
+func (s *ResponsePluginService) Register(p ResponsePlugin) {
+	s.plugins[p.Name()] = p
+}
+
+// Available describes the registered plugins, by name
+func (s *ResponsePluginService) Available() []models.ResponsePluginInfo {
+	infos := make([]models.ResponsePluginInfo, 0, len(s.plugins))
+	for _, p := range s.plugins {
+		infos = append(infos, p.Describe())
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name < infos[j].Name })
+	return infos
+}
+
+// ListSettings returns userID's plugin chains, the default first
+func (s *ResponsePluginService) ListSettings(userID string) ([]models.ResponsePluginSettings, error) {
+	return s.repo.ListByUser(userID)
+}
+
+// SetSettings replaces userID's plugin chain for a persona, or their
+// default with personaID 0. Persona ownership is checked by the caller.
+func (s *ResponsePluginService) SetSettings(userID string, personaID int64, plugins []models.ResponsePluginConfig) (*models.ResponsePluginSettings, error) {
+	for _, cfg := range plugins {
+		if err := s.validate(cfg); err != nil {
+			return nil, err
+		}
+	}
+	if plugins == nil {
+		plugins = []models.ResponsePluginConfig{}
+	}
+
+	settings := &models.ResponsePluginSettings{UserID: userID, PersonaID: personaID, Plugins: plugins}
+	if err := s.repo.Set(settings); err != nil {
+		return nil, err
+	}
+	return settings, nil
+}
+
+// DeleteSettings removes userID's plugin chain for a persona, so its chats
+// fall back to the default
+func (s *ResponsePluginService) DeleteSettings(userID string, personaID int64) error {
+	deleted, err := s.repo.Delete(userID, personaID)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return fmt.Errorf("%w: no response plugins set for persona %d", ErrNotFound, personaID)
+	}
+	return nil
+}
+
+func (s *ResponsePluginService) validate(cfg models.ResponsePluginConfig) error {
+	p, ok := s.plugins[cfg.Name]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownResponsePlugin, cfg.Name)
+	}
+	known := p.Describe().Options
+	for option := range cfg.Options {
+		if _, ok := known[option]; !ok {
+			return fmt.Errorf("%w: %s has no option %q", ErrInvalidPluginOptions, cfg.Name, option)
+		}
+	}
+	if v, ok := p.(responsePluginValidator); ok {
+		if err := v.Validate(cfg.Options); err != nil {
+			return fmt.Errorf("%w: %s: %v", ErrInvalidPluginOptions, cfg.Name, err)
+		}
+	}
+	return nil
+}
+
+// Apply runs the plugin chain configured for ownerID's persona, or their
+// default, over content
+func (s *ResponsePluginService) Apply(ctx context.Context, ownerID string, personaID int64, content string) string {
+	if content == "" {
+		return content
+	}
+	settings, err := s.settingsFor(ownerID, personaID)
+	if err != nil {
+		log.Printf("⚠️  Failed to load response plugins for user %s: %v", ownerID, err)
+		return content
+	}
+	if settings == nil {
+		return content
+	}
+
+	for _, cfg := range settings.Plugins {
+		p, ok := s.plugins[cfg.Name]
+		if !ok {
+			// Unregistered since it was configured
+			continue
+		}
+		start := time.Now()
+		out, err := p.Process(ctx, content, cfg.Options)
+		s.observe(cfg.Name, time.Since(start), err, err == nil && out != content)
+		if err != nil {
+			log.Printf("⚠️  Response plugin %s failed: %v", cfg.Name, err)
+			continue
+		}
+		content = out
+	}
+	return content
+}
+
+// settingsFor resolves the chain for a persona, falling back to the
+// owner's default
+func (s *ResponsePluginService) settingsFor(ownerID string, personaID int64) (*models.ResponsePluginSettings, error) {
+	if ownerID == "" {
+		return nil, nil
+	}
+	if personaID != 0 {
+		settings, err := s.repo.Get(ownerID, personaID)
+		if err != nil || settings != nil {
+			return settings, err
+		}
+	}
+	return s.repo.Get(ownerID, 0)
+}
+
+func (s *ResponsePluginService) observe(name string, d time.Duration, err error, changed bool) {
+	ms := float64(d.Microseconds()) / 1000
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	st, ok := s.stats[name]
+	if !ok {
+		st = &models.ResponsePluginStats{Name: name}
+		s.stats[name] = st
+	}
+	st.Runs++
+	if err != nil {
+		st.Errors++
+	}
+	if changed {
+		st.Changed++
+	}
+	st.TotalMs += ms
+	if ms > st.MaxMs {
+		st.MaxMs = ms
+	}
+}
+
+// Stats returns the timing metrics of each plugin that has run on this
+// instance, by name
+func (s *ResponsePluginService) Stats() []models.ResponsePluginStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stats := make([]models.ResponsePluginStats, 0, len(s.stats))
+	for _, st := range s.stats {
+		out := *st
+		if out.Runs > 0 {
+			out.AvgMs = out.TotalMs / float64(out.Runs)
+		}
+		stats = append(stats, out)
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Name < stats[j].Name })
+	return stats
+}
+
+// SetResponsePlugins post-processes assistant output with the plugin
+// chains users configure
+func (s *ChatService) SetResponsePlugins(plugins *ResponsePluginService) {
+	s.responsePlugins = plugins
+}
+
+// postProcess runs the chat owner's response plugins over assistant
+// output. Chats with a persona use the persona owner's settings, so a
+// widget's persona is post-processed the way its owner configured.
+func (s *ChatService) postProcess(ctx context.Context, chat *models.Chat, persona *models.Persona, content string) string {
+	if s.responsePlugins == nil {
+		return content
+	}
+	if persona != nil {
+		return s.responsePlugins.Apply(ctx, persona.UserID, persona.ID, content)
+	}
+	return s.responsePlugins.Apply(ctx, chat.UserID, 0, content)
+}
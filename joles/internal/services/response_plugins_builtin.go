@@ -0,0 +1,410 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"lio-ai/internal/models"
+)
+
+// Built-in response plugins
+const (
+	PluginMarkdownSanitizer   = "markdown_sanitizer"
+	PluginLinkRewriter        = "link_rewriter"
+	PluginProfanityMasker     = "profanity_masker"
+	PluginCodeFenceNormalizer = "code_fence_normalizer"
+)
+
+func builtinResponsePlugins() []ResponsePlugin {
+	return []ResponsePlugin{
+		markdownSanitizer{},
+		linkRewriter{},
+		newProfanityMasker(),
+		codeFenceNormalizer{},
+	}
+}
+
+// markdownSanitizer strips raw HTML and script links from markdown so it
+// is safe to render. Code blocks and spans are left alone.
+type markdownSanitizer struct{}
+
+var (
+	sanitizerBlockTags = func() []*regexp.Regexp {
+		var res []*regexp.Regexp
+		for _, tag := range []string{"script", "style", "iframe", "object", "embed", "textarea"} {
+			res = append(res, regexp.MustCompile(`(?is)<`+tag+`\b.*?(?:</`+tag+`\s*>|\z)`))
+		}
+		return res
+	}()
+	sanitizerComment    = regexp.MustCompile(`(?s)<!--.*?(?:-->|\z)`)
+	sanitizerTag        = regexp.MustCompile(`</?([a-zA-Z][a-zA-Z0-9+.-]*)(?:[:\s/][^>]*)?>`)
+	sanitizerAutolink   = regexp.MustCompile(`^<([a-zA-Z][a-zA-Z0-9+.-]*):[^\s<>]*>$`)
+	sanitizerScriptLink = regexp.MustCompile(`(?i)\]\(\s*<?\s*(?:javascript|vbscript|data):[^()]*(?:\([^()]*\)[^()]*)*\)`)
+)
+
+func (markdownSanitizer) Name() string { return PluginMarkdownSanitizer }
+
+func (markdownSanitizer) Describe() models.ResponsePluginInfo {
+	return models.ResponsePluginInfo{
+		Name:        PluginMarkdownSanitizer,
+		Description: "Removes raw HTML, HTML comments and javascript:, vbscript: and data: links outside code",
+		Options: map[string]string{
+			"allow_tags": "Comma-separated HTML tags to keep, without attributes (e.g. \"b,i,br\")",
+		},
+	}
+}
+
+func (markdownSanitizer) Process(_ context.Context, content string, options map[string]string) (string, error) {
+	allowed := make(map[string]bool)
+	for _, tag := range strings.Split(options["allow_tags"], ",") {
+		if tag = strings.ToLower(strings.TrimSpace(tag)); tag != "" {
+			allowed[tag] = true
+		}
+	}
+
+	return mapProse(content, func(text string) string {
+		for _, re := range sanitizerBlockTags {
+			text = re.ReplaceAllString(text, "")
+		}
+		text = sanitizerComment.ReplaceAllString(text, "")
+		text = sanitizerScriptLink.ReplaceAllString(text, "](#)")
+		return sanitizerTag.ReplaceAllStringFunc(text, func(tag string) string {
+			if m := sanitizerAutolink.FindStringSubmatch(tag); m != nil {
+				if isScriptScheme(m[1]) {
+					return ""
+				}
+				return tag
+			}
+			name := strings.ToLower(sanitizerTag.FindStringSubmatch(tag)[1])
+			if !allowed[name] {
+				return ""
+			}
+			if strings.HasPrefix(tag, "</") {
+				return "</" + name + ">"
+			}
+			return "<" + name + ">"
+		})
+	}), nil
+}
+
+func isScriptScheme(scheme string) bool {
+	switch strings.ToLower(scheme) {
+	case "javascript", "vbscript", "data":
+		return true
+	}
+	return false
+}
+
+// linkRewriter rewrites the http(s) links in assistant output, adding
+// query parameters and routing them through a redirect. Links in code are
+// left alone.
+type linkRewriter struct{}
+
+var linkPattern = regexp.MustCompile("https?://[^\\s<>()\\[\\]\"'`]+")
+
+func (linkRewriter) Name() string { return PluginLinkRewriter }
+
+func (linkRewriter) Describe() models.ResponsePluginInfo {
+	return models.ResponsePluginInfo{
+		Name:        PluginLinkRewriter,
+		Description: "Rewrites links outside code: adds query parameters and routes them through a redirect URL",
+		Options: map[string]string{
+			"redirect":     "URL the escaped link is appended to (e.g. \"https://example.com/out?url=\")",
+			"append_query": "Query parameters added to links that don't set them (e.g. \"utm_source=lio\")",
+			"skip_domains": "Comma-separated domains, and their subdomains, whose links are kept as they are",
+		},
+	}
+}
+
+func (linkRewriter) Validate(options map[string]string) error {
+	if redirect := options["redirect"]; redirect != "" {
+		u, err := url.Parse(redirect)
+		if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+			return fmt.Errorf("redirect must be an http or https URL")
+		}
+	}
+	if _, err := url.ParseQuery(options["append_query"]); err != nil {
+		return fmt.Errorf("append_query is not a valid query string")
+	}
+	return nil
+}
+
+func (p linkRewriter) Process(_ context.Context, content string, options map[string]string) (string, error) {
+	if err := p.Validate(options); err != nil {
+		return "", err
+	}
+	redirect := options["redirect"]
+	extra, _ := url.ParseQuery(options["append_query"])
+	var skip []string
+	for _, d := range strings.Split(options["skip_domains"], ",") {
+		if d = strings.ToLower(strings.TrimSpace(d)); d != "" {
+			skip = append(skip, d)
+		}
+	}
+	if redirect == "" && len(extra) == 0 {
+		return content, nil
+	}
+
+	return mapProse(content, func(text string) string {
+		return linkPattern.ReplaceAllStringFunc(text, func(link string) string {
+			// Sentence punctuation after a bare link is not part of it
+			trimmed := strings.TrimRight(link, ".,;:!?")
+			suffix := link[len(trimmed):]
+			if redirect != "" && strings.HasPrefix(trimmed, redirect) {
+				return link
+			}
+
+			u, err := url.Parse(trimmed)
+			if err != nil {
+				return link
+			}
+			host := strings.ToLower(u.Hostname())
+			for _, d := range skip {
+				if host == d || strings.HasSuffix(host, "."+d) {
+					return link
+				}
+			}
+
+			if len(extra) > 0 {
+				existing := u.Query()
+				for key, values := range extra {
+					if _, set := existing[key]; set {
+						continue
+					}
+					for _, v := range values {
+						param := url.QueryEscape(key) + "=" + url.QueryEscape(v)
+						if u.RawQuery == "" {
+							u.RawQuery = param
+						} else {
+							u.RawQuery += "&" + param
+						}
+					}
+				}
+			}
+			rewritten := u.String()
+			if redirect != "" {
+				rewritten = redirect + url.QueryEscape(rewritten)
+			}
+			return rewritten + suffix
+		})
+	}), nil
+}
+
+// profanityMasker masks profane words outside code, keeping their length
+type profanityMasker struct {
+	words *regexp.Regexp
+}
+
+// profanityWords is the built-in list; common inflections are matched too
+var profanityWords = []string{
+	"fuck", "motherfucker", "shit", "bullshit", "bitch", "bastard", "asshole", "cunt",
+	"dick", "prick", "piss", "crap", "damn", "wanker", "bollocks", "twat", "slut", "whore",
+}
+
+func newProfanityMasker() profanityMasker {
+	return profanityMasker{words: profanityPattern(profanityWords)}
+}
+
+func profanityPattern(words []string) *regexp.Regexp {
+	quoted := make([]string, 0, len(words))
+	for _, w := range words {
+		if w = strings.TrimSpace(w); w != "" {
+			quoted = append(quoted, regexp.QuoteMeta(w))
+		}
+	}
+	if len(quoted) == 0 {
+		return nil
+	}
+	return regexp.MustCompile(`(?i)\b(?:` + strings.Join(quoted, "|") + `)(?:s|es|ed|er|ers|ing|y)?\b`)
+}
+
+func (profanityMasker) Name() string { return PluginProfanityMasker }
+
+func (profanityMasker) Describe() models.ResponsePluginInfo {
+	return models.ResponsePluginInfo{
+		Name:        PluginProfanityMasker,
+		Description: "Masks profanity outside code with a mask character, keeping word length",
+		Options: map[string]string{
+			"words":      "Comma-separated words masked in addition to the built-in list",
+			"mask":       "Mask character (default \"*\")",
+			"keep_first": "\"true\" keeps the first letter of masked words",
+		},
+	}
+}
+
+func (profanityMasker) Validate(options map[string]string) error {
+	if mask, ok := options["mask"]; ok && utf8.RuneCountInString(mask) != 1 {
+		return fmt.Errorf("mask must be a single character")
+	}
+	if keep, ok := options["keep_first"]; ok {
+		if _, err := strconv.ParseBool(keep); err != nil {
+			return fmt.Errorf("keep_first must be true or false")
+		}
+	}
+	return nil
+}
+
+func (p profanityMasker) Process(_ context.Context, content string, options map[string]string) (string, error) {
+	if err := p.Validate(options); err != nil {
+		return "", err
+	}
+	mask := "*"
+	if m := options["mask"]; m != "" {
+		mask = m
+	}
+	keepFirst, _ := strconv.ParseBool(options["keep_first"])
+	patterns := []*regexp.Regexp{p.words}
+	if extra := profanityPattern(strings.Split(options["words"], ",")); extra != nil {
+		patterns = append(patterns, extra)
+	}
+
+	return mapProse(content, func(text string) string {
+		for _, re := range patterns {
+			text = re.ReplaceAllStringFunc(text, func(word string) string {
+				n := utf8.RuneCountInString(word)
+				if keepFirst {
+					first, _ := utf8.DecodeRuneInString(word)
+					return string(first) + strings.Repeat(mask, n-1)
+				}
+				return strings.Repeat(mask, n)
+			})
+		}
+		return text
+	}), nil
+}
+
+// codeFenceNormalizer rewrites fenced code blocks as ``` fences with a
+// lowercase language, closes unterminated ones and puts a blank line
+// before each, so output renders the same everywhere
+type codeFenceNormalizer struct{}
+
+func (codeFenceNormalizer) Name() string { return PluginCodeFenceNormalizer }
+
+func (codeFenceNormalizer) Describe() models.ResponsePluginInfo {
+	return models.ResponsePluginInfo{
+		Name:        PluginCodeFenceNormalizer,
+		Description: "Normalizes code fences to ``` with a lowercase language and closes unterminated blocks",
+		Options: map[string]string{
+			"default_language": "Language set on fences that name none (e.g. \"text\")",
+		},
+	}
+}
+
+func (codeFenceNormalizer) Process(_ context.Context, content string, options map[string]string) (string, error) {
+	lines := strings.Split(content, "\n")
+	out := make([]string, 0, len(lines)+2)
+	for i := 0; i < len(lines); {
+		line := lines[i]
+		trimmed := strings.TrimLeft(line, " ")
+		marker := fenceMarker(trimmed)
+		if marker == "" || len(line)-len(trimmed) > 3 {
+			out = append(out, line)
+			i++
+			continue
+		}
+
+		lang := ""
+		if fields := strings.Fields(trimmed[len(marker):]); len(fields) > 0 {
+			lang = strings.ToLower(fields[0])
+		}
+		if lang == "" {
+			lang = options["default_language"]
+		}
+
+		// The block runs to its closing fence, or the end of the output
+		var body []string
+		j := i + 1
+		for ; j < len(lines); j++ {
+			if closesFence(strings.TrimLeft(lines[j], " "), marker) {
+				j++
+				break
+			}
+			body = append(body, lines[j])
+		}
+
+		// A longer fence keeps ``` lines inside the block literal
+		width := 3
+		for _, b := range body {
+			t := strings.TrimLeft(b, " ")
+			if run := len(t) - len(strings.TrimLeft(t, "`")); run >= width {
+				width = run + 1
+			}
+		}
+		fence := strings.Repeat("`", width)
+
+		if len(out) > 0 && strings.TrimSpace(out[len(out)-1]) != "" {
+			out = append(out, "")
+		}
+		out = append(out, fence+lang)
+		out = append(out, body...)
+		out = append(out, fence)
+		i = j
+	}
+	return strings.Join(out, "\n"), nil
+}
+
+// fenceMarker returns the ``` or ~~~ run opening a code fence, or ""
+func fenceMarker(line string) string {
+	for _, c := range []string{"`", "~"} {
+		n := len(line) - len(strings.TrimLeft(line, c))
+		if n >= 3 {
+			// Backtick fences can't have backticks in their info string
+			if c == "`" && strings.Contains(line[n:], "`") {
+				return ""
+			}
+			return line[:n]
+		}
+	}
+	return ""
+}
+
+// closesFence reports whether line closes a fence opened with marker
+func closesFence(line, marker string) bool {
+	line = strings.TrimRight(line, " \t\r")
+	return len(line) >= len(marker) && strings.Trim(line, marker[:1]) == ""
+}
+
+var inlineCode = regexp.MustCompile("`[^`\n]+`")
+
+// mapProse applies fn to the parts of markdown outside fenced code blocks
+// and inline code spans
+func mapProse(content string, fn func(string) string) string {
+	var b, prose strings.Builder
+	flush := func() {
+		text := prose.String()
+		last := 0
+		for _, span := range inlineCode.FindAllStringIndex(text, -1) {
+			b.WriteString(fn(text[last:span[0]]))
+			b.WriteString(text[span[0]:span[1]])
+			last = span[1]
+		}
+		b.WriteString(fn(text[last:]))
+		prose.Reset()
+	}
+
+	fence := ""
+	for _, line := range strings.SplitAfter(content, "\n") {
+		trimmed := strings.TrimLeft(line, " ")
+		if fence == "" {
+			if marker := fenceMarker(trimmed); marker != "" && len(line)-len(trimmed) <= 3 {
+				flush()
+				fence = marker
+				b.WriteString(line)
+				continue
+			}
+			prose.WriteString(line)
+			continue
+		}
+		b.WriteString(line)
+		if closesFence(strings.TrimRight(trimmed, "\n"), fence) {
+			fence = ""
+		}
+	}
+	flush()
+	return b.String()
+}
@@ -0,0 +1,123 @@
+package services
+
+import (
+	"errors"
+	"log"
+	"strconv"
+	"time"
+
+	"lio-ai/internal/models"
+	"lio-ai/internal/repositories"
+)
+
+// ErrUserNotFound is returned when purging a user that doesn't exist
+var ErrUserNotFound = errors.New("user not found")
+
+// RetentionService expires request logs by data category and erases a
+// user's content on request
+type RetentionService struct {
+	repo  *repositories.RetentionRepository
+	users *repositories.UserRepository
+	chats *ChatService
+
+	// How long usage log rows, and their error messages, are kept; 0 keeps
+	// them indefinitely. Error messages can quote prompts and provider
+	// responses, so they usually get the shorter period.
+	metadataTTL  time.Duration
+	errorBodyTTL time.Duration
+	// How long reads of confidential documents are kept; 0 keeps them
+	// indefinitely
+	documentAccessTTL time.Duration
+}
+
+// NewRetentionService creates a retention service
+func NewRetentionService(repo *repositories.RetentionRepository, users *repositories.UserRepository, chats *ChatService, metadataTTL, errorBodyTTL time.Duration) *RetentionService {
+	return &RetentionService{
+		repo:         repo,
+		users:        users,
+		chats:        chats,
+		metadataTTL:  metadataTTL,
+		errorBodyTTL: errorBodyTTL,
+	}
+}
+
+// SetDocumentAccessTTL expires confidential document access log entries
+// after ttl
+func (s *RetentionService) SetDocumentAccessTTL(ttl time.Duration) {
+	s.documentAccessTTL = ttl
+}
+
+// Start enforces retention on an interval until the process exits. It
+// does nothing when no retention period is configured.
+func (s *RetentionService) Start(interval time.Duration) {
+	if s.metadataTTL <= 0 && s.errorBodyTTL <= 0 && s.documentAccessTTL <= 0 {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			report, err := s.Enforce()
+			if err != nil {
+				log.Printf("Failed to enforce log retention: %v", err)
+			} else if report.MetadataDeleted > 0 || report.ErrorBodiesCleared > 0 || report.DocumentAccessDeleted > 0 {
+				log.Printf("🧹 Log retention: deleted %d usage rows, cleared %d error messages, deleted %d document reads",
+					report.MetadataDeleted, report.ErrorBodiesCleared, report.DocumentAccessDeleted)
+			}
+			<-ticker.C
+		}
+	}()
+}
+
+// Enforce applies the retention periods once
+func (s *RetentionService) Enforce() (*models.RetentionReport, error) {
+	report := &models.RetentionReport{}
+	now := time.Now()
+	var err error
+	if s.errorBodyTTL > 0 {
+		if report.ErrorBodiesCleared, err = s.repo.ClearErrorBodiesBefore(now.Add(-s.errorBodyTTL)); err != nil {
+			return nil, err
+		}
+	}
+	if s.metadataTTL > 0 {
+		if report.MetadataDeleted, err = s.repo.DeleteUsageMetricsBefore(now.Add(-s.metadataTTL)); err != nil {
+			return nil, err
+		}
+	}
+	if s.documentAccessTTL > 0 {
+		if report.DocumentAccessDeleted, err = s.repo.DeleteDocumentAccessBefore(now.Add(-s.documentAccessTTL)); err != nil {
+			return nil, err
+		}
+	}
+	return report, nil
+}
+
+// PurgeUser erases a user's message contents and logged request bodies
+// for a GDPR erasure request, and reports what was removed. The account
+// itself and usage metadata are left alone.
+func (s *RetentionService) PurgeUser(userID string) (*models.PurgeReport, error) {
+	id, err := strconv.ParseInt(userID, 10, 64)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+	user, err := s.users.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+
+	report, err := s.repo.PurgeUserContent(userID)
+	if err != nil {
+		return nil, err
+	}
+	if s.chats != nil {
+		if report.AttachmentsRemoved, err = s.chats.PurgeUserAttachments(userID); err != nil {
+			return nil, err
+		}
+	}
+	report.CompletedAt = time.Now().UTC()
+	log.Printf("🧹 Purged content of user %s: %d messages, %d chats, %d attachments", userID, report.MessagesScrubbed, report.ChatsScrubbed, report.AttachmentsRemoved)
+	return report, nil
+}
@@ -0,0 +1,170 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"lio-ai/internal/models"
+	"lio-ai/internal/repositories"
+)
+
+const (
+	// MaxScheduleAhead limits how far in the future a message can be scheduled
+	MaxScheduleAhead = 365 * 24 * time.Hour
+	// scheduledRunTimeout bounds a single scheduled completion
+	scheduledRunTimeout = 5 * time.Minute
+	// scheduledBatchSize caps the messages run per scheduler tick
+	scheduledBatchSize = 20
+)
+
+var (
+	// ErrInvalidSchedule is returned for scheduled_at values in the past or too far ahead
+	ErrInvalidSchedule = errors.New("invalid scheduled_at")
+	// ErrScheduleNotPending is returned when cancelling a message that already ran
+	ErrScheduleNotPending = errors.New("scheduled message is no longer pending")
+)
+
+// MessageScheduler queues prompts for later and runs them in the background
+type MessageScheduler struct {
+	repo        *repositories.ScheduledMessageRepository
+	chatService *ChatService
+}
+
+// NewMessageScheduler creates a new message scheduler
+func NewMessageScheduler(repo *repositories.ScheduledMessageRepository, chatService *ChatService) *MessageScheduler {
+	return &MessageScheduler{repo: repo, chatService: chatService}
+}
+
+// Schedule queues a user message to be sent, and answered, at req.ScheduledAt
+func (s *MessageScheduler) Schedule(chatID int64, userID string, req *models.MessageRequest) (*models.ScheduledMessage, error) {
+	if err := s.checkOwner(chatID, userID); err != nil {
+		return nil, err
+	}
+	if req.Role != "user" {
+		return nil, fmt.Errorf("%w: only user messages can be scheduled", ErrInvalidMessage)
+	}
+	if req.Content == "" {
+		return nil, fmt.Errorf("%w: content is required", ErrInvalidMessage)
+	}
+
+	now := time.Now()
+	at := *req.ScheduledAt
+	if !at.After(now) {
+		return nil, fmt.Errorf("%w: must be in the future", ErrInvalidSchedule)
+	}
+	if at.Sub(now) > MaxScheduleAhead {
+		return nil, fmt.Errorf("%w: must be within %d days", ErrInvalidSchedule, int(MaxScheduleAhead.Hours()/24))
+	}
+
+	m := &models.ScheduledMessage{
+		ChatID:      chatID,
+		UserID:      userID,
+		Content:     req.Content,
+		Model:       req.Model,
+		ScheduledAt: at.UTC(),
+	}
+	if err := s.repo.Create(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// List returns a chat's scheduled messages
+func (s *MessageScheduler) List(chatID int64, userID string) ([]models.ScheduledMessage, error) {
+	if err := s.checkOwner(chatID, userID); err != nil {
+		return nil, err
+	}
+	return s.repo.GetByChatID(chatID)
+}
+
+// Cancel cancels a pending scheduled message
+func (s *MessageScheduler) Cancel(chatID, scheduleID int64, userID string) error {
+	if err := s.checkOwner(chatID, userID); err != nil {
+		return err
+	}
+
+	m, err := s.repo.GetByID(scheduleID)
+	if err != nil {
+		return err
+	}
+	if m.ChatID != chatID {
+		return fmt.Errorf("scheduled message not found")
+	}
+
+	cancelled, err := s.repo.Cancel(scheduleID)
+	if err != nil {
+		return err
+	}
+	if !cancelled {
+		return ErrScheduleNotPending
+	}
+	return nil
+}
+
+func (s *MessageScheduler) checkOwner(chatID int64, userID string) error {
+	chat, err := s.chatService.repo.GetChatByID(chatID)
+	if err != nil {
+		return err
+	}
+	if chat.UserID != userID {
+		return ErrUnauthorized
+	}
+	return nil
+}
+
+// Start runs due messages every interval in the background
+func (s *MessageScheduler) Start(interval time.Duration) {
+	if n, err := s.repo.FailInterrupted(); err != nil {
+		log.Printf("Failed to clean up interrupted scheduled messages: %v", err)
+	} else if n > 0 {
+		log.Printf("⚠️  Marked %d interrupted scheduled messages as failed", n)
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			s.runDue()
+		}
+	}()
+}
+
+// runDue claims and runs messages whose time has come
+func (s *MessageScheduler) runDue() {
+	due, err := s.repo.ClaimDue(time.Now(), scheduledBatchSize)
+	if err != nil {
+		log.Printf("Failed to read scheduled messages: %v", err)
+	}
+
+	for _, m := range due {
+		s.run(m)
+	}
+}
+
+// run sends one scheduled message through the normal completion path, so
+// the prompt and reply are appended to the chat and usage is tracked
+func (s *MessageScheduler) run(m models.ScheduledMessage) {
+	ctx, cancel := context.WithTimeout(context.Background(), scheduledRunTimeout)
+	defer cancel()
+
+	resp, err := s.chatService.CreateChatCompletion(ctx, &models.ChatCompletionRequest{
+		ChatID:  m.ChatID,
+		UserID:  m.UserID,
+		Message: m.Content,
+		Model:   m.Model,
+	})
+	if err != nil {
+		log.Printf("Scheduled message %d failed: %v", m.ID, err)
+		if merr := s.repo.MarkFailed(m.ID, err.Error()); merr != nil {
+			log.Printf("Failed to update scheduled message %d: %v", m.ID, merr)
+		}
+		return
+	}
+
+	if err := s.repo.MarkCompleted(m.ID, resp.MessageID); err != nil {
+		log.Printf("Failed to update scheduled message %d: %v", m.ID, err)
+	}
+}
@@ -0,0 +1,125 @@
+package services
+
+import (
+	"log"
+	"regexp"
+	"time"
+	"unicode/utf8"
+
+	"lio-ai/internal/models"
+	"lio-ai/internal/repositories"
+)
+
+// jailbreakRule is a known prompt-injection or jailbreak pattern
+type jailbreakRule struct {
+	name     string
+	severity string
+	pattern  *regexp.Regexp
+}
+
+// jailbreakRules are checked against every moderated user message. They
+// catch common phrasings only; the point is a review trail, not a filter.
+var jailbreakRules = []jailbreakRule{
+	{"ignore_instructions", models.SeverityHigh, regexp.MustCompile(`(?i)\b(ignore|disregard|forget)\b.{0,30}\b(previous|prior|above|earlier|all|your)\b.{0,20}\b(instructions|rules|prompts?|directions)\b`)},
+	{"jailbreak_persona", models.SeverityHigh, regexp.MustCompile(`(?i)\b(do anything now|DAN mode|jailbreak mode|developer mode enabled)\b`)},
+	{"bypass_safety", models.SeverityMedium, regexp.MustCompile(`(?i)\b(bypass|disable|turn off|override)\b.{0,20}\b(safety|content filters?|moderation|guardrails)\b`)},
+	{"system_prompt_extraction", models.SeverityMedium, regexp.MustCompile(`(?i)\b(reveal|show|print|repeat|output)\b.{0,30}\b(system prompt|hidden instructions|initial instructions)\b`)},
+	{"unrestricted_roleplay", models.SeverityMedium, regexp.MustCompile(`(?i)\b(pretend|act as if|imagine)\b.{0,40}\b(no|without)\b.{0,20}\b(restrictions|filters|guidelines|limits|rules)\b`)},
+	{"prompt_delimiter", models.SeverityLow, regexp.MustCompile(`(?i)(<\|im_start\|>|<\|system\|>|\[/?INST\]|###\s*system\b)`)},
+}
+
+// Characters of context kept on each side of a match in the excerpt
+const securityExcerptContext = 40
+
+// SecurityService detects jailbreak attempts in user messages, records
+// them as security events, and decides which users need strict moderation
+type SecurityService struct {
+	repo       *repositories.SecurityEventRepository
+	flagWindow time.Duration
+
+	// Optional moderation applied to flagged users, failing closed
+	strict *ModerationService
+}
+
+// NewSecurityService creates a security service. A user with a medium or
+// high severity event within flagWindow counts as flagged.
+func NewSecurityService(repo *repositories.SecurityEventRepository, flagWindow time.Duration) *SecurityService {
+	return &SecurityService{repo: repo, flagWindow: flagWindow}
+}
+
+// SetStrictModeration requires moderation for flagged users' messages
+func (s *SecurityService) SetStrictModeration(moderation *ModerationService) {
+	s.strict = moderation
+}
+
+// Inspect checks a user message against the jailbreak rules and records
+// an event for each rule that matches. Messages are never blocked here;
+// recording failures are logged.
+func (s *SecurityService) Inspect(userID string, chatID int64, text string) []models.SecurityEvent {
+	var events []models.SecurityEvent
+	for _, rule := range jailbreakRules {
+		loc := rule.pattern.FindStringIndex(text)
+		if loc == nil {
+			continue
+		}
+
+		event := models.SecurityEvent{
+			UserID:    userID,
+			EventType: models.SecurityEventJailbreak,
+			Severity:  rule.severity,
+			Pattern:   rule.name,
+			Excerpt:   excerpt(text, loc[0], loc[1]),
+		}
+		if chatID != 0 {
+			event.ChatID = &chatID
+		}
+		if err := s.repo.Create(&event); err != nil {
+			log.Printf("Failed to record security event: %v", err)
+			continue
+		}
+		log.Printf("🛡️  Possible jailbreak attempt (user=%s, rule=%s, severity=%s)", userID, rule.name, rule.severity)
+		events = append(events, event)
+	}
+	return events
+}
+
+// ListEvents returns security events for review, newest first
+func (s *SecurityService) ListEvents(filter models.SecurityEventFilter) ([]models.SecurityEvent, int, error) {
+	return s.repo.List(filter)
+}
+
+// strictModeration returns the moderation to apply to a flagged user's
+// messages, or nil when the user is not flagged or strict moderation is off
+func (s *SecurityService) strictModeration(userID string) *ModerationService {
+	if s.strict == nil {
+		return nil
+	}
+	flagged, err := s.repo.HasRecent(userID, models.SeverityMedium, time.Now().Add(-s.flagWindow))
+	if err != nil {
+		log.Printf("Failed to check security events for user %s: %v", userID, err)
+		return s.strict
+	}
+	if !flagged {
+		return nil
+	}
+	return s.strict
+}
+
+// excerpt returns the matched text with some context on each side
+func excerpt(text string, start, end int) string {
+	from, to := start-securityExcerptContext, end+securityExcerptContext
+	if from < 0 {
+		from = 0
+	}
+	if to > len(text) {
+		to = len(text)
+	}
+	// Keep the cut on rune boundaries
+	for from > 0 && !utf8.RuneStart(text[from]) {
+		from--
+	}
+	for to < len(text) && !utf8.RuneStart(text[to]) {
+		to++
+	}
+	return text[from:to]
+}
@@ -0,0 +1,498 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"lio-ai/internal/models"
+	"lio-ai/internal/repositories"
+)
+
+const (
+	// Passages are cut at paragraph breaks once they reach this many runes
+	documentChunkRunes = 1000
+	// Text past this many chunks is not indexed
+	maxDocumentChunks = 256
+	// Chunks embedded per embeddings request
+	embeddingBatchSize = 32
+	// Documents waiting to be indexed; reindex picks up any dropped
+	indexQueueSize = 256
+	indexTimeout   = 2 * time.Minute
+	// Full-text matches considered per hybrid search
+	maxTextMatches = 1000
+
+	DefaultSemanticSearchLimit = 10
+	MaxSemanticSearchLimit     = 50
+	DefaultSearchTextWeight    = 0.3
+)
+
+// SemanticSearchService embeds users' documents in chunks and searches
+// them by similarity to a query. Documents are indexed in the background
+// as they are written; the embeddings are billed to the document's owner
+// like any other embeddings request.
+type SemanticSearchService struct {
+	chunks   *repositories.DocumentChunkRepository
+	docs     *repositories.DocumentRepository
+	embedder *ChatService
+	model    string
+	queue    chan uint
+	// Optional access log of confidential documents
+	accessLog *repositories.DocumentAccessRepository
+}
+
+// NewSemanticSearchService creates a semantic search service embedding
+// with model
+func NewSemanticSearchService(chunks *repositories.DocumentChunkRepository, docs *repositories.DocumentRepository, embedder *ChatService, model string) *SemanticSearchService {
+	return &SemanticSearchService{
+		chunks:   chunks,
+		docs:     docs,
+		embedder: embedder,
+		model:    model,
+		queue:    make(chan uint, indexQueueSize),
+	}
+}
+
+// SetAccessLog records confidential documents returned by searches as
+// read
+func (s *SemanticSearchService) SetAccessLog(accessLog *repositories.DocumentAccessRepository) {
+	s.accessLog = accessLog
+}
+
+// Model is the embedding model documents and queries are embedded with
+func (s *SemanticSearchService) Model() string {
+	return s.model
+}
+
+// Start indexes queued documents in the background until the process exits
+func (s *SemanticSearchService) Start() {
+	go func() {
+		for id := range s.queue {
+			if err := s.index(id); err != nil {
+				log.Printf("⚠️  Failed to index document %d for semantic search: %v", id, err)
+			}
+		}
+	}()
+}
+
+// Enqueue schedules a document to be (re)indexed. It never blocks; when
+// the queue is full the document is left for a reindex.
+func (s *SemanticSearchService) Enqueue(documentID uint) {
+	select {
+	case s.queue <- documentID:
+	default:
+		log.Printf("⚠️  Semantic index queue full, skipping document %d", documentID)
+	}
+}
+
+// Remove drops a deleted document's chunks
+func (s *SemanticSearchService) Remove(documentID uint) {
+	if err := s.chunks.DeleteByDocument(documentID); err != nil {
+		log.Printf("Failed to remove document %d from semantic index: %v", documentID, err)
+	}
+}
+
+// Reindex queues userID's documents that have no chunks for the current
+// model, returning how many were queued
+func (s *SemanticSearchService) Reindex(userID string) (int, error) {
+	ids, err := s.chunks.UnindexedDocumentIDs(userID, s.model)
+	if err != nil {
+		return 0, err
+	}
+	for _, id := range ids {
+		s.Enqueue(id)
+	}
+	return len(ids), nil
+}
+
+// index embeds a document's current content and replaces its chunks. A
+// document deleted since it was queued has its chunks removed.
+func (s *SemanticSearchService) index(documentID uint) error {
+	doc, err := s.docs.GetByID(documentID)
+	if err != nil {
+		return err
+	}
+	if doc == nil || doc.UserID == "" {
+		return s.chunks.DeleteByDocument(documentID)
+	}
+
+	passages := splitDocument(doc)
+	ctx, cancel := context.WithTimeout(context.Background(), indexTimeout)
+	defer cancel()
+
+	chunks := make([]models.DocumentChunk, 0, len(passages))
+	for start := 0; start < len(passages); start += embeddingBatchSize {
+		end := start + embeddingBatchSize
+		if end > len(passages) {
+			end = len(passages)
+		}
+		vectors, err := s.embed(ctx, doc.UserID, passages[start:end])
+		if err != nil {
+			return err
+		}
+		for i, v := range vectors {
+			chunks = append(chunks, models.DocumentChunk{
+				UserID:    doc.UserID,
+				Seq:       start + i,
+				Content:   passages[start+i],
+				Embedding: v,
+				Model:     s.model,
+			})
+		}
+	}
+	return s.chunks.Replace(documentID, chunks)
+}
+
+// embed returns one vector per input, in input order
+func (s *SemanticSearchService) embed(ctx context.Context, userID string, input []string) ([][]float32, error) {
+	resp, err := s.embedder.CreateEmbeddings(ctx, userID, &models.EmbeddingRequest{Model: s.model, Input: input})
+	if err != nil {
+		return nil, err
+	}
+	vectors := make([][]float32, len(input))
+	for _, e := range resp.Data {
+		if e.Index < 0 || e.Index >= len(input) {
+			continue
+		}
+		v := make([]float32, len(e.Embedding))
+		for i, f := range e.Embedding {
+			v[i] = float32(f)
+		}
+		vectors[e.Index] = v
+	}
+	for i, v := range vectors {
+		if len(v) == 0 {
+			return nil, fmt.Errorf("no embedding returned for input %d", i)
+		}
+	}
+	return vectors, nil
+}
+
+// Search returns the chunks of the user's documents closest to the query.
+// Vector scores are the cosine similarity of the embeddings; hybrid
+// searches blend in the share of query terms each chunk contains.
+func (s *SemanticSearchService) Search(ctx context.Context, q models.SemanticSearchQuery) (*models.SemanticSearchResponse, error) {
+	if q.Limit <= 0 {
+		q.Limit = DefaultSemanticSearchLimit
+	}
+	if q.Limit > MaxSemanticSearchLimit {
+		q.Limit = MaxSemanticSearchLimit
+	}
+
+	vectors, err := s.embed(ctx, q.UserID, []string{q.Query})
+	if err != nil {
+		return nil, err
+	}
+	query := vectors[0]
+
+	contentType, _ := contentTypeFilter(q.ContentType, "")
+	candidates, err := s.chunks.ListVectors(s.model, models.DocumentFilter{
+		UserID:       q.UserID,
+		CollectionID: q.CollectionID,
+		Language:     strings.ToLower(q.Language),
+		ContentType:  contentType,
+		IDs:          q.DocumentIDs,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var terms []string
+	var matches map[int64]string
+	if q.Hybrid {
+		terms = searchTerms(q.Query)
+		if len(terms) > 0 {
+			matches, err = s.chunks.MatchText(q.UserID, s.model, ftsQuery(terms), maxTextMatches)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	results := make([]models.SemanticSearchResult, 0, len(candidates))
+	for _, chunk := range candidates {
+		if len(chunk.Embedding) != len(query) {
+			// Embedded before the model's dimensions changed
+			continue
+		}
+		r := models.SemanticSearchResult{
+			ChunkID:     chunk.ID,
+			Seq:         chunk.Seq,
+			VectorScore: cosineSimilarity(query, chunk.Embedding),
+			Document:    models.SearchResultDocument{ID: chunk.DocumentID},
+		}
+		r.Score = r.VectorScore
+		if q.Hybrid {
+			var text float64
+			if content, ok := matches[chunk.ID]; ok {
+				text = termCoverage(terms, content)
+			}
+			r.TextScore = &text
+			r.Score = (1-q.TextWeight)*r.VectorScore + q.TextWeight*text
+		}
+		if r.Score < q.MinScore {
+			continue
+		}
+		results = append(results, r)
+	}
+
+	sort.SliceStable(results, func(i, j int) bool { return results[i].Score > results[j].Score })
+	if len(results) > q.Limit {
+		results = results[:q.Limit]
+	}
+	if err := s.fillResults(q.UserID, q.Reader, results); err != nil {
+		return nil, err
+	}
+
+	return &models.SemanticSearchResponse{
+		Query:  q.Query,
+		Model:  s.model,
+		Hybrid: q.Hybrid,
+		Data:   results,
+	}, nil
+}
+
+// fillResults loads the content and source document of each result,
+// recording one read per confidential document
+func (s *SemanticSearchService) fillResults(userID string, reader models.DocumentReader, results []models.SemanticSearchResult) error {
+	ids := make([]int64, len(results))
+	for i, r := range results {
+		ids[i] = r.ChunkID
+	}
+	contents, err := s.chunks.GetContents(ids)
+	if err != nil {
+		return err
+	}
+
+	docs := make(map[uint]*models.Document)
+	for i := range results {
+		r := &results[i]
+		r.Content = contents[r.ChunkID]
+
+		doc, ok := docs[r.Document.ID]
+		if !ok {
+			if doc, err = s.docs.GetByID(r.Document.ID); err != nil {
+				return err
+			}
+			docs[r.Document.ID] = doc
+			if doc != nil && doc.UserID == userID && doc.Confidential {
+				recordDocumentRead(s.accessLog, reader, doc.ID)
+			}
+		}
+		if doc == nil || doc.UserID != userID {
+			continue
+		}
+		r.Document = models.SearchResultDocument{
+			ID:           doc.ID,
+			Title:        doc.Title,
+			Tags:         doc.Tags,
+			CollectionID: doc.CollectionID,
+			File:         doc.File,
+			Confidential: doc.Confidential,
+			UpdatedAt:    doc.UpdatedAt,
+		}
+	}
+	return nil
+}
+
+// splitDocument splits a document's content into passages with the
+// splitter for its content type: code keeps its lines and top-level blocks
+// together, HTML is reduced to its text, and the rest splits as prose
+func splitDocument(doc *models.Document) []string {
+	switch doc.ContentType {
+	case models.DocumentContentCode:
+		return chunkCode(doc.Content, documentChunkRunes, maxDocumentChunks)
+	case models.DocumentContentHTML:
+		return chunkText(htmlText(doc.Content), documentChunkRunes, maxDocumentChunks)
+	default:
+		return chunkText(doc.Content, documentChunkRunes, maxDocumentChunks)
+	}
+}
+
+// chunkText splits text into passages of about size runes, breaking at
+// paragraphs where it can and at whitespace otherwise
+func chunkText(text string, size, max int) []string {
+	var chunks []string
+	var current strings.Builder
+	flush := func() {
+		if s := strings.TrimSpace(current.String()); s != "" && len(chunks) < max {
+			chunks = append(chunks, s)
+		}
+		current.Reset()
+	}
+
+	for _, para := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n\n") {
+		para = strings.TrimSpace(para)
+		if para == "" {
+			continue
+		}
+		if current.Len() > 0 && utf8.RuneCountInString(current.String())+utf8.RuneCountInString(para) > size {
+			flush()
+		}
+		for utf8.RuneCountInString(para) > size {
+			cut := splitPoint(para, size)
+			current.WriteString(para[:cut])
+			flush()
+			para = strings.TrimSpace(para[cut:])
+		}
+		if current.Len() > 0 {
+			current.WriteString("\n\n")
+		}
+		current.WriteString(para)
+		if len(chunks) >= max {
+			break
+		}
+	}
+	flush()
+	return chunks
+}
+
+// chunkCode splits source code into passages of about size runes. It packs
+// whole top-level blocks where it can and otherwise breaks between lines,
+// so indentation and line structure survive in every passage.
+func chunkCode(text string, size, max int) []string {
+	var chunks []string
+	var current strings.Builder
+	runes := 0
+	flush := func() {
+		s := strings.TrimRight(strings.TrimLeft(current.String(), "\n"), " \t\n")
+		if strings.TrimSpace(s) != "" && len(chunks) < max {
+			chunks = append(chunks, s)
+		}
+		current.Reset()
+		runes = 0
+	}
+	add := func(s string) {
+		if runes > 0 && runes+utf8.RuneCountInString(s) > size {
+			flush()
+		}
+		current.WriteString(s)
+		runes += utf8.RuneCountInString(s)
+	}
+
+	for _, block := range codeBlocks(strings.ReplaceAll(text, "\r\n", "\n")) {
+		if utf8.RuneCountInString(block) <= size {
+			add(block)
+		} else {
+			// A block too long for one passage starts its own
+			flush()
+			for _, line := range strings.SplitAfter(block, "\n") {
+				for utf8.RuneCountInString(line) > size {
+					cut := splitPoint(line, size)
+					add(line[:cut])
+					flush()
+					line = line[cut:]
+				}
+				add(line)
+			}
+		}
+		if len(chunks) >= max {
+			break
+		}
+	}
+	flush()
+	return chunks
+}
+
+// codeBlocks splits source at blank lines followed by an unindented line,
+// where top-level declarations usually start
+func codeBlocks(text string) []string {
+	lines := strings.SplitAfter(text, "\n")
+	var blocks []string
+	start := 0
+	for i := 1; i < len(lines); i++ {
+		line := lines[i]
+		if strings.TrimSpace(lines[i-1]) == "" && strings.TrimSpace(line) != "" && line[0] != ' ' && line[0] != '\t' {
+			blocks = append(blocks, strings.Join(lines[start:i], ""))
+			start = i
+		}
+	}
+	return append(blocks, strings.Join(lines[start:], ""))
+}
+
+// splitPoint is the byte offset of the last whitespace within the first
+// size runes of s, or of the size'th rune when there is none
+func splitPoint(s string, size int) int {
+	end, n := len(s), 0
+	for i := range s {
+		if n == size {
+			end = i
+			break
+		}
+		n++
+	}
+	if i := strings.LastIndexFunc(s[:end], unicode.IsSpace); i > end/2 {
+		return i
+	}
+	return end
+}
+
+// searchTerms are the distinct lower-cased words of a query
+func searchTerms(query string) []string {
+	seen := make(map[string]bool)
+	var terms []string
+	for _, word := range strings.FieldsFunc(strings.ToLower(query), notWordRune) {
+		if !seen[word] {
+			seen[word] = true
+			terms = append(terms, word)
+		}
+	}
+	return terms
+}
+
+// ftsQuery matches chunks containing any of the terms. Terms are letters
+// and digits only, so quoting them is enough to keep FTS syntax out.
+func ftsQuery(terms []string) string {
+	quoted := make([]string, len(terms))
+	for i, t := range terms {
+		quoted[i] = `"` + t + `"`
+	}
+	return strings.Join(quoted, " OR ")
+}
+
+// termCoverage is the share of terms that appear as words in content
+func termCoverage(terms []string, content string) float64 {
+	if len(terms) == 0 {
+		return 0
+	}
+	words := make(map[string]bool)
+	for _, word := range strings.FieldsFunc(strings.ToLower(content), notWordRune) {
+		words[word] = true
+	}
+	found := 0
+	for _, t := range terms {
+		if words[t] {
+			found++
+		}
+	}
+	return float64(found) / float64(len(terms))
+}
+
+func notWordRune(r rune) bool {
+	return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+}
+
+func cosineSimilarity(a, b []float32) float64 {
+	var dot, na, nb float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		na += float64(a[i]) * float64(a[i])
+		nb += float64(b[i]) * float64(b[i])
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(na) * math.Sqrt(nb))
+}
+
+// SetSemanticIndex keeps documents embedded for semantic search as they
+// are written
+func (s *DocumentService) SetSemanticIndex(index *SemanticSearchService) {
+	s.index = index
+}
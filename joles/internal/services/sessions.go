@@ -0,0 +1,46 @@
+package services
+
+import (
+	"fmt"
+	"time"
+
+	"lio-ai/internal/models"
+	"lio-ai/internal/repositories"
+)
+
+// SetSessions records a session for each login token issued, so users can
+// list and end them
+func (s *UserService) SetSessions(sessions *repositories.SessionRepository) {
+	s.sessions = sessions
+}
+
+// ListSessions retrieves a user's active sessions, latest first, marking
+// the one of the token currentTokenID
+func (s *UserService) ListSessions(userID int64, currentTokenID string) ([]models.Session, error) {
+	sessions, err := s.sessions.ListActive(userID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	for i := range sessions {
+		sessions[i].Current = currentTokenID != "" && sessions[i].TokenID == currentTokenID
+	}
+	return sessions, nil
+}
+
+// TerminateSession ends one of a user's sessions, revoking its token
+func (s *UserService) TerminateSession(userID, id int64) error {
+	found, err := s.sessions.Terminate(userID, id)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return fmt.Errorf("%w: session %d", ErrNotFound, id)
+	}
+	return nil
+}
+
+// TerminateOtherSessions ends all of a user's sessions but the one of the
+// token currentTokenID, returning how many were ended
+func (s *UserService) TerminateOtherSessions(userID int64, currentTokenID string) (int64, error) {
+	return s.sessions.TerminateOthers(userID, currentTokenID)
+}
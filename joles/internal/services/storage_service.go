@@ -0,0 +1,175 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"lio-ai/internal/models"
+	"lio-ai/internal/repositories"
+)
+
+// ErrStorageLimitExceeded is returned when an upload would take a user past
+// their plan's storage limit
+var ErrStorageLimitExceeded = errors.New("storage limit exceeded")
+
+// ErrDocumentTooLarge is returned when a document's content is longer than
+// its owner's plan allows for a single document
+var ErrDocumentTooLarge = errors.New("document content too large")
+
+// defaultStoragePlan is used for users without a plan, or whose plan has no
+// configured limit
+const defaultStoragePlan = "free"
+
+// StorageService accounts for the bytes users store in documents and
+// attachments and enforces per-plan limits. Exports are streamed to the
+// client and never stored, so they do not count.
+type StorageService struct {
+	repo   *repositories.StorageRepository
+	users  *repositories.UserRepository
+	limits map[string]int64 // Bytes per plan; 0 means unlimited
+
+	// Largest document content in bytes per plan; 0 means unlimited
+	documentLimits map[string]int64
+}
+
+// NewStorageService creates a new storage service
+func NewStorageService(repo *repositories.StorageRepository, users *repositories.UserRepository, limits map[string]int64) *StorageService {
+	return &StorageService{repo: repo, users: users, limits: limits}
+}
+
+// SetDocumentLimits caps the content length of a single document per plan
+func (s *StorageService) SetDocumentLimits(limits map[string]int64) {
+	s.documentLimits = limits
+}
+
+// MaxDocumentBytes is the largest document content any plan allows, or 0
+// when some plan is unlimited
+func (s *StorageService) MaxDocumentBytes() int64 {
+	var max int64
+	for _, limit := range s.documentLimits {
+		if limit <= 0 {
+			return 0
+		}
+		if limit > max {
+			max = limit
+		}
+	}
+	if _, ok := s.documentLimits[defaultStoragePlan]; !ok {
+		return 0
+	}
+	return max
+}
+
+// CheckDocument returns ErrDocumentTooLarge if content is longer than the
+// user's plan allows for one document
+func (s *StorageService) CheckDocument(userID, content string) error {
+	if len(s.documentLimits) == 0 {
+		return nil
+	}
+	plan, _ := s.planLimit(userID)
+	limit := s.documentLimit(plan)
+	if limit > 0 && int64(len(content)) > limit {
+		return fmt.Errorf("%w: content is %d bytes, the %s plan allows at most %d per document",
+			ErrDocumentTooLarge, len(content), plan, limit)
+	}
+	return nil
+}
+
+// Usage returns the user's stored bytes against their plan's limit
+func (s *StorageService) Usage(userID string) (*models.StorageUsage, error) {
+	usage, err := s.repo.GetUsage(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	usage.Plan, usage.BytesLimit = s.planLimit(userID)
+	usage.DocumentMaxBytes = s.documentLimit(usage.Plan)
+	if usage.BytesLimit > 0 {
+		if remaining := usage.BytesLimit - usage.BytesUsed; remaining > 0 {
+			usage.BytesRemaining = remaining
+		}
+		usage.PercentUsed = float64(usage.BytesUsed) / float64(usage.BytesLimit) * 100
+	}
+	return usage, nil
+}
+
+// Check returns ErrStorageLimitExceeded if storing additional more bytes
+// would take the user past their limit
+func (s *StorageService) Check(userID string, additional int64) error {
+	if additional <= 0 {
+		return nil
+	}
+	usage, err := s.Usage(userID)
+	if err != nil {
+		return err
+	}
+	if usage.BytesLimit > 0 && usage.BytesUsed+additional > usage.BytesLimit {
+		return fmt.Errorf("%w: %d of %d bytes used on the %s plan, %d more requested",
+			ErrStorageLimitExceeded, usage.BytesUsed, usage.BytesLimit, usage.Plan, additional)
+	}
+	return nil
+}
+
+// Record sets the size of a stored object
+func (s *StorageService) Record(userID, kind string, objectID, bytes int64) error {
+	return s.repo.Record(userID, kind, objectID, bytes)
+}
+
+// Owner returns the user an object is charged to and its recorded size
+func (s *StorageService) Owner(kind string, objectID int64) (string, int64, error) {
+	return s.repo.Owner(kind, objectID)
+}
+
+// Release stops counting deleted objects
+func (s *StorageService) Release(kind string, objectIDs ...int64) error {
+	return s.repo.Remove(kind, objectIDs...)
+}
+
+// TopConsumers lists the users storing the most bytes, with their limits
+func (s *StorageService) TopConsumers(limit int) ([]models.StorageUsage, error) {
+	consumers, err := s.repo.TopConsumers(limit)
+	if err != nil {
+		return nil, err
+	}
+	for i := range consumers {
+		c := &consumers[i]
+		c.Plan, c.BytesLimit = s.planFor(c.Plan)
+		c.DocumentMaxBytes = s.documentLimit(c.Plan)
+		if c.BytesLimit > 0 {
+			if remaining := c.BytesLimit - c.BytesUsed; remaining > 0 {
+				c.BytesRemaining = remaining
+			}
+			c.PercentUsed = float64(c.BytesUsed) / float64(c.BytesLimit) * 100
+		}
+	}
+	return consumers, nil
+}
+
+// planLimit looks up the user's plan and its storage limit
+func (s *StorageService) planLimit(userID string) (string, int64) {
+	plan := ""
+	if id, err := strconv.ParseInt(userID, 10, 64); err == nil && s.users != nil {
+		if user, err := s.users.FindByID(id); err == nil && user != nil {
+			plan = user.Plan
+		}
+	}
+	return s.planFor(plan)
+}
+
+// planFor maps a plan name to the plan whose limit applies
+func (s *StorageService) planFor(plan string) (string, int64) {
+	if limit, ok := s.limits[plan]; ok {
+		return plan, limit
+	}
+	return defaultStoragePlan, s.limits[defaultStoragePlan]
+}
+
+// documentLimit is the document content limit of a plan, as resolved by
+// planFor; plans without an entry use the default plan's
+func (s *StorageService) documentLimit(plan string) int64 {
+	if limit, ok := s.documentLimits[plan]; ok {
+		return limit
+	}
+	return s.documentLimits[defaultStoragePlan]
+}
@@ -0,0 +1,150 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"time"
+
+	"lio-ai/internal/models"
+)
+
+// ErrSummaryUnavailable is returned when the AI service fails to summarize a chat
+var ErrSummaryUnavailable = errors.New("summary could not be generated")
+
+// chatSummaryInstruction asks for a summary followed by action items in a
+// layout parseChatSummary can split
+const chatSummaryInstruction = `Summarize the following conversation in a few sentences. Preserve names, facts, decisions and open questions.
+Then list the action items (follow-ups, tasks, commitments) it contains.
+Answer in exactly this format:
+Summary: <summary>
+Action items:
+- <item>
+Write "- None" if there are no action items.`
+
+// actionItemsHeading matches the line separating the summary from its action items
+var actionItemsHeading = regexp.MustCompile(`(?im)^\s*\**action items\**\s*:?\s*\**\s*$`)
+
+// listMarker matches a bullet or number at the start of an action item
+var listMarker = regexp.MustCompile(`^\s*(?:[-*•]|\d+[.)])\s*`)
+
+// GetChatSummary returns a short summary and the action items of a chat
+// owned by the user. The summary is cached on the chat and regenerated
+// only once new messages have made it stale, or when refresh is set. model picks the summarizing
+// model; by default the chat's most recent assistant model is used.
+func (s *ChatService) GetChatSummary(ctx context.Context, chatID int64, userID, model string, refresh bool) (*models.ChatSummary, error) {
+	chat, err := s.repo.GetChatByID(chatID)
+	if err != nil {
+		return nil, err
+	}
+	if chat.UserID != userID {
+		return nil, ErrUnauthorized
+	}
+
+	cached, err := s.repo.GetChatSummary(chatID)
+	if err != nil {
+		return nil, err
+	}
+	if cached != nil && !cached.Stale && !refresh {
+		return cached, nil
+	}
+
+	messages, err := s.repo.GetMessagesByChatID(chatID)
+	if err != nil {
+		return nil, err
+	}
+	if len(messages) == 0 {
+		return nil, fmt.Errorf("%w: chat has no messages to summarize", ErrInvalidMessage)
+	}
+
+	if strings.TrimSpace(model) == "" {
+		for i := len(messages) - 1; i >= 0; i-- {
+			if messages[i].Role == "assistant" && messages[i].Model != nil {
+				model = *messages[i].Model
+				break
+			}
+		}
+	}
+	model, _ = s.resolveModel(model)
+
+	turns := make([]map[string]interface{}, 0, len(messages))
+	for _, m := range messages {
+		if m.Role == "user" || m.Role == "assistant" {
+			turns = append(turns, map[string]interface{}{"role": m.Role, "content": m.Content})
+		}
+	}
+
+	target := completionTarget{UserID: userID, ChatID: chatID, Model: model, Endpoint: "/api/v1/chats/:id/summary"}
+	resp, err := s.completeWithFailover(ctx, target, transcriptPrompt(model, chatSummaryInstruction, turns), nil)
+	if err == nil && strings.TrimSpace(resp.Content) == "" {
+		err = fmt.Errorf("empty summary")
+	}
+	if err != nil {
+		if cached != nil {
+			// Better an outdated summary than none
+			log.Printf("⚠️  Failed to refresh summary for chat %d, serving the stale one: %v", chatID, err)
+			return cached, nil
+		}
+		return nil, fmt.Errorf("%w: %v", ErrSummaryUnavailable, err)
+	}
+
+	text, actionItems := parseChatSummary(resp.Content)
+	summary := &models.ChatSummary{
+		ChatID:      chatID,
+		Summary:     text,
+		Model:       model,
+		ActionItems: actionItems,
+		GeneratedAt: time.Now(),
+	}
+	if err := s.repo.SaveChatSummary(summary, messages[len(messages)-1].Seq); err != nil {
+		return nil, err
+	}
+	return summary, nil
+}
+
+// AttachChatSummaries sets the cached summary, if any, on each chat. No
+// summaries are generated.
+func (s *ChatService) AttachChatSummaries(chats []models.Chat) error {
+	ids := make([]int64, len(chats))
+	for i := range chats {
+		ids[i] = chats[i].ID
+	}
+	summaries, err := s.repo.GetChatSummaries(ids)
+	if err != nil {
+		return err
+	}
+	for i := range chats {
+		chats[i].Summary = summaries[chats[i].ID]
+	}
+	return nil
+}
+
+// parseChatSummary splits a reply to chatSummaryInstruction into the
+// summary and its action items. A reply that ignored the format is kept
+// whole as the summary.
+func parseChatSummary(content string) (string, []string) {
+	content = strings.TrimSpace(content)
+	items := make([]string, 0)
+
+	loc := actionItemsHeading.FindStringIndex(content)
+	if loc == nil {
+		return strings.TrimSpace(strings.TrimPrefix(content, "Summary:")), items
+	}
+
+	summary := strings.TrimSpace(content[:loc[0]])
+	summary = strings.TrimSpace(strings.TrimPrefix(summary, "Summary:"))
+	for _, line := range strings.Split(content[loc[1]:], "\n") {
+		item := strings.TrimSpace(listMarker.ReplaceAllString(line, ""))
+		if item == "" || strings.EqualFold(strings.TrimSuffix(item, "."), "none") {
+			continue
+		}
+		items = append(items, item)
+	}
+	if summary == "" {
+		summary = content
+	}
+	return summary, items
+}
@@ -0,0 +1,166 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"lio-ai/internal/models"
+	"lio-ai/internal/repositories"
+)
+
+var (
+	// ErrTemplateNotFound is returned for unknown template IDs
+	ErrTemplateNotFound = errors.New("template not found")
+	// ErrMissingTemplateVariable is returned when a render omits a placeholder's value
+	ErrMissingTemplateVariable = errors.New("missing template variables")
+)
+
+// templateVarPattern matches {{name}} placeholders, allowing inner spaces
+var templateVarPattern = regexp.MustCompile(`\{\{\s*([A-Za-z_][A-Za-z0-9_]*)\s*\}\}`)
+
+// TemplateService handles prompt template business logic
+type TemplateService struct {
+	repo *repositories.TemplateRepository
+}
+
+// NewTemplateService creates a new template service
+func NewTemplateService(repo *repositories.TemplateRepository) *TemplateService {
+	return &TemplateService{repo: repo}
+}
+
+// TemplateVariables lists the distinct placeholder names in content, in order
+func TemplateVariables(content string) []string {
+	vars := make([]string, 0)
+	seen := make(map[string]bool)
+	for _, m := range templateVarPattern.FindAllStringSubmatch(content, -1) {
+		if !seen[m[1]] {
+			seen[m[1]] = true
+			vars = append(vars, m[1])
+		}
+	}
+	return vars
+}
+
+// RenderTemplate substitutes variables into content. Every placeholder must
+// have a value; extra values are ignored.
+func RenderTemplate(content string, variables map[string]string) (string, error) {
+	var missing []string
+	for _, name := range TemplateVariables(content) {
+		if _, ok := variables[name]; !ok {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		return "", fmt.Errorf("%w: %s", ErrMissingTemplateVariable, strings.Join(missing, ", "))
+	}
+
+	return templateVarPattern.ReplaceAllStringFunc(content, func(match string) string {
+		return variables[templateVarPattern.FindStringSubmatch(match)[1]]
+	}), nil
+}
+
+// CreateTemplate creates a prompt template owned by the user
+func (s *TemplateService) CreateTemplate(userID string, req *models.PromptTemplateRequest) (*models.PromptTemplate, error) {
+	t := &models.PromptTemplate{
+		UserID:      userID,
+		Name:        req.Name,
+		Description: req.Description,
+		Content:     req.Content,
+		IsShared:    req.IsShared,
+	}
+	if err := s.repo.Create(t); err != nil {
+		return nil, err
+	}
+	t.Variables = TemplateVariables(t.Content)
+	return t, nil
+}
+
+// getTemplate loads a template, mapping a missing row to ErrTemplateNotFound
+func (s *TemplateService) getTemplate(id int64) (*models.PromptTemplate, error) {
+	t, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if t == nil {
+		return nil, ErrTemplateNotFound
+	}
+	return t, nil
+}
+
+// GetTemplate retrieves a template the user owns or that is shared
+func (s *TemplateService) GetTemplate(id int64, userID string) (*models.PromptTemplate, error) {
+	t, err := s.getTemplate(id)
+	if err != nil {
+		return nil, err
+	}
+	if t.UserID != userID && !t.IsShared {
+		return nil, ErrUnauthorized
+	}
+	t.Variables = TemplateVariables(t.Content)
+	return t, nil
+}
+
+// ListTemplates retrieves the templates visible to the user
+func (s *TemplateService) ListTemplates(userID string) ([]models.PromptTemplate, error) {
+	templates, err := s.repo.ListVisible(userID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range templates {
+		templates[i].Variables = TemplateVariables(templates[i].Content)
+	}
+	return templates, nil
+}
+
+// UpdateTemplate updates a template owned by the user
+func (s *TemplateService) UpdateTemplate(id int64, userID string, req *models.UpdatePromptTemplateRequest) (*models.PromptTemplate, error) {
+	t, err := s.getTemplate(id)
+	if err != nil {
+		return nil, err
+	}
+	if t.UserID != userID {
+		return nil, ErrUnauthorized
+	}
+
+	if req.Name != nil {
+		t.Name = *req.Name
+	}
+	if req.Description != nil {
+		t.Description = *req.Description
+	}
+	if req.Content != nil {
+		t.Content = *req.Content
+	}
+	if req.IsShared != nil {
+		t.IsShared = *req.IsShared
+	}
+
+	if err := s.repo.Update(t); err != nil {
+		return nil, err
+	}
+	t.Variables = TemplateVariables(t.Content)
+	return t, nil
+}
+
+// DeleteTemplate deletes a template owned by the user
+func (s *TemplateService) DeleteTemplate(id int64, userID string) error {
+	t, err := s.getTemplate(id)
+	if err != nil {
+		return err
+	}
+	if t.UserID != userID {
+		return ErrUnauthorized
+	}
+	return s.repo.Delete(id)
+}
+
+// Render renders a template visible to the user with the given variables
+func (s *TemplateService) Render(id int64, userID string, variables map[string]string) (string, error) {
+	t, err := s.GetTemplate(id, userID)
+	if err != nil {
+		return "", err
+	}
+	return RenderTemplate(t.Content, variables)
+}
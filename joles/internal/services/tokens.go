@@ -0,0 +1,56 @@
+package services
+
+import (
+	"fmt"
+
+	"lio-ai/internal/models"
+	"lio-ai/internal/tokenizer"
+)
+
+// CountTokens counts the tokens of text, or of a chat request's messages
+// including their framing, for the model a name or alias resolves to
+func (s *ChatService) CountTokens(req *models.TokenCountRequest) (*models.TokenCountResponse, error) {
+	if (req.Text == "") == (len(req.Messages) == 0) {
+		return nil, fmt.Errorf("%w: exactly one of text and messages is required", ErrInvalidMessage)
+	}
+
+	model, alias := s.resolveModel(req.Model)
+	response := &models.TokenCountResponse{
+		Model:      model,
+		ModelAlias: alias,
+		Encoding:   tokenizer.EncodingForModel(model),
+		Estimated:  !tokenizer.Exact(model),
+	}
+	if req.Text != "" {
+		response.Tokens = tokenizer.Count(model, req.Text)
+		return response, nil
+	}
+
+	messages := make([]tokenizer.Message, len(req.Messages))
+	for i, m := range req.Messages {
+		messages[i] = tokenizer.Message{Role: m.Role, Name: m.Name, Content: m.Content}
+	}
+	response.Tokens = tokenizer.CountMessages(model, messages)
+	return response, nil
+}
+
+// EstimateCost counts the tokens of a chat request's messages for the
+// model its name or alias resolves to and prices the request for userID
+// before it is sent
+func (s *ChatService) EstimateCost(userID string, req *models.CostEstimateRequest) (*models.CostEstimate, error) {
+	if s.usageService == nil {
+		return nil, fmt.Errorf("usage tracking is not configured")
+	}
+	model, alias := s.resolveModel(req.Model)
+	messages := make([]tokenizer.Message, len(req.Messages))
+	for i, m := range req.Messages {
+		messages[i] = tokenizer.Message{Role: m.Role, Name: m.Name, Content: m.Content}
+	}
+
+	estimate, err := s.usageService.EstimateCost(userID, model, tokenizer.CountMessages(model, messages), req.MaxTokens)
+	if err != nil {
+		return nil, err
+	}
+	estimate.ModelAlias = alias
+	return estimate, nil
+}
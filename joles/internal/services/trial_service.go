@@ -0,0 +1,133 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"log"
+	"time"
+
+	"lio-ai/internal/models"
+	"lio-ai/internal/repositories"
+)
+
+// ErrTrialExhausted is returned once a client has used its daily trial completions
+var ErrTrialExhausted = errors.New("trial limit reached")
+
+// TrialService serves a small daily allowance of completions to
+// unauthenticated clients so the product can be demoed without signing up
+type TrialService struct {
+	repo         *repositories.TrialRepository
+	chatService  *ChatService
+	usageService *UsageService
+	limit        int
+	ipLimit      int
+	model        string
+}
+
+// NewTrialService creates a trial service allowing limit completions per
+// client and ipLimit per IP a day. An empty model selects the cheapest
+// active chat model at request time; model may also be an alias.
+func NewTrialService(repo *repositories.TrialRepository, chatService *ChatService, usageService *UsageService, limit, ipLimit int, model string) *TrialService {
+	return &TrialService{
+		repo:         repo,
+		chatService:  chatService,
+		usageService: usageService,
+		limit:        limit,
+		ipLimit:      ipLimit,
+		model:        model,
+	}
+}
+
+// TrialClientKey scopes trial usage to a device fingerprint and IP. Hashing
+// keeps raw fingerprints out of the database. Clients choose their
+// fingerprint, so the IP's own daily limit bounds how many keys it can use.
+func TrialClientKey(ip, fingerprint string) string {
+	sum := sha256.Sum256([]byte(ip + "|" + fingerprint))
+	return hex.EncodeToString(sum[:])
+}
+
+// trialDay is the UTC day trial usage is counted against
+func trialDay(now time.Time) string {
+	return now.UTC().Format("2006-01-02")
+}
+
+// trialModel resolves the model trial completions run on
+func (s *TrialService) trialModel() (string, error) {
+	if s.model != "" {
+		model, _ := s.chatService.resolveModel(s.model)
+		return model, nil
+	}
+	return s.usageService.CheapestChatModel()
+}
+
+// Complete runs a single-turn completion against the client's trial quota.
+// Trial requests have no user, so they are always served with the
+// platform key for the trial model's provider.
+func (s *TrialService) Complete(ctx context.Context, clientKey, ip, message string) (*models.TrialCompletionResponse, error) {
+	model, err := s.trialModel()
+	if err != nil {
+		return nil, err
+	}
+
+	day := trialDay(time.Now())
+	ok, err := s.repo.Reserve(clientKey, day, ip, s.limit, s.ipLimit)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrTrialExhausted
+	}
+
+	messages := []map[string]interface{}{{"role": "user", "content": message}}
+	apiKey := s.chatService.platformKey(ProviderForModel(model))
+	resp, err := s.chatService.callAIService(ctx, model, messages, "", apiKey, nil)
+	if err != nil {
+		// Failed calls don't count against the trial
+		if relErr := s.repo.Release(clientKey, day); relErr != nil {
+			log.Printf("Failed to release trial completion: %v", relErr)
+		}
+		return nil, err
+	}
+	if resp.Tokens > 0 {
+		if err := s.repo.AddTokens(clientKey, day, resp.Tokens); err != nil {
+			log.Printf("Failed to record trial tokens: %v", err)
+		}
+	}
+
+	used, err := s.repo.GetUsed(clientKey, day)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.TrialCompletionResponse{
+		Content:   resp.Content,
+		Model:     model,
+		Tokens:    resp.Tokens,
+		Limit:     s.limit,
+		Remaining: max(s.limit-used, 0),
+	}, nil
+}
+
+// GetQuota reports the client's trial allowance for today
+func (s *TrialService) GetQuota(clientKey string) (*models.TrialQuota, error) {
+	model, err := s.trialModel()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	used, err := s.repo.GetUsed(clientKey, trialDay(now))
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.TrialQuota{
+		Limit:     s.limit,
+		Used:      used,
+		Remaining: max(s.limit-used, 0),
+		Model:     model,
+		ResetsAt:  time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, time.UTC),
+	}, nil
+}
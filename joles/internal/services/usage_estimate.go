@@ -0,0 +1,67 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"lio-ai/internal/models"
+)
+
+// estimateHistoryDays is how far back a user's usage is read for the
+// usual length of their responses from a model
+const estimateHistoryDays = 30
+
+// EstimateCost prices a chat request to model with inputTokens of prompt
+// and a response of up to maxOutputTokens (reservedOutputTokens when 0)
+// at the model's cost_config prices. The expected response length is
+// userID's average for the model over the last 30 days, or the maximum
+// when they have not used it. Requests on platform keys are billed at
+// the platform key markup on top.
+func (s *UsageService) EstimateCost(userID, model string, inputTokens, maxOutputTokens int) (*models.CostEstimate, error) {
+	if maxOutputTokens <= 0 {
+		maxOutputTokens = reservedOutputTokens
+	}
+	config, err := s.usageRepo.GetCostConfig(model)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cost config: %w", err)
+	}
+
+	expected := maxOutputTokens
+	profile, err := s.usageRepo.GetUsageProfile(userID, time.Now().AddDate(0, 0, -estimateHistoryDays), "")
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range profile {
+		if p.Model == model && p.Requests > 0 {
+			if avg := int(math.Round(float64(p.TokensOutput) / float64(p.Requests))); avg < expected {
+				expected = avg
+			}
+			break
+		}
+	}
+
+	inputCost, err := s.CalculateCost(inputTokens, 0, model)
+	if err != nil {
+		return nil, err
+	}
+	expectedCost, err := s.CalculateCost(0, expected, model)
+	if err != nil {
+		return nil, err
+	}
+	maxCost, err := s.CalculateCost(0, maxOutputTokens, model)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.CostEstimate{
+		Model:                model,
+		Priced:               config.ModelName == model,
+		InputTokens:          inputTokens,
+		OutputTokensExpected: expected,
+		OutputTokensMax:      maxOutputTokens,
+		InputCostUSD:         inputCost,
+		OutputCostUSD:        models.CostRange{Min: 0, Expected: expectedCost, Max: maxCost},
+		TotalCostUSD:         models.CostRange{Min: inputCost, Expected: inputCost + expectedCost, Max: inputCost + maxCost},
+	}, nil
+}
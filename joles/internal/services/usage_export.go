@@ -0,0 +1,16 @@
+package services
+
+import (
+	"fmt"
+
+	"lio-ai/internal/models"
+)
+
+// ExportUsage calls fn with each raw usage row matching filter, oldest
+// first, without holding the export in memory
+func (s *UsageService) ExportUsage(filter models.UsageExportFilter, fn func(*models.UsageMetric) error) error {
+	if !filter.From.Before(filter.To) {
+		return fmt.Errorf("%w: from must be before to", ErrInvalidMessage)
+	}
+	return s.usageRepo.ExportUsage(filter, fn)
+}
@@ -0,0 +1,221 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sync/atomic"
+	"time"
+
+	"lio-ai/internal/models"
+	"lio-ai/internal/repositories"
+)
+
+// Attempts at tracking a queued usage event, and the wait before the first
+// retry, doubling after each
+const (
+	usageQueueAttempts = 5
+	usageQueueBackoff  = 200 * time.Millisecond
+)
+
+// maxUsageDeadLetters caps the dead letters listed or replayed at once
+const maxUsageDeadLetters = 500
+
+// usageJob is a usage event waiting to be tracked and the attempts at it
+// so far
+type usageJob struct {
+	req      *models.UsageRequest
+	attempts int
+}
+
+// UsageQueue tracks usage events off the request path, retrying the ones
+// that failed before they were recorded. Events that still fail, or that
+// don't fit in the queue, are kept in the usage_dead_letters table to be
+// replayed.
+type UsageQueue struct {
+	usage *UsageService
+	repo  *repositories.UsageRepository
+	jobs  chan usageJob
+
+	tracked      atomic.Int64
+	retries      atomic.Int64
+	deadLettered atomic.Int64
+	dropped      atomic.Int64
+}
+
+// NewUsageQueue creates a queue holding up to size events
+func NewUsageQueue(usage *UsageService, size int) *UsageQueue {
+	return &UsageQueue{
+		usage: usage,
+		repo:  usage.usageRepo,
+		jobs:  make(chan usageJob, size),
+	}
+}
+
+// Start starts workers tracking queued events until the process exits
+func (q *UsageQueue) Start(workers int) {
+	for i := 0; i < workers; i++ {
+		go func() {
+			for job := range q.jobs {
+				q.track(job)
+			}
+		}()
+	}
+}
+
+// Enqueue queues a usage event to be tracked, without blocking. An event
+// that doesn't fit is dead-lettered straight away.
+func (q *UsageQueue) Enqueue(req *models.UsageRequest) {
+	q.enqueue(usageJob{req: req})
+}
+
+// Retry queues a usage event a TrackUsage call already failed on
+func (q *UsageQueue) Retry(req *models.UsageRequest) {
+	q.retries.Add(1)
+	q.enqueue(usageJob{req: req, attempts: 1})
+}
+
+func (q *UsageQueue) enqueue(job usageJob) {
+	select {
+	case q.jobs <- job:
+	default:
+		q.deadLetter(job, errors.New("usage queue full"))
+	}
+}
+
+// track tracks a queued event, retrying with backoff while it fails before
+// being recorded
+func (q *UsageQueue) track(job usageJob) {
+	backoff := usageQueueBackoff << max(job.attempts-1, 0)
+	for {
+		if job.attempts > 0 {
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+		err := q.usage.TrackUsage(job.req)
+		job.attempts++
+		switch {
+		case err == nil:
+			q.tracked.Add(1)
+			return
+		case !errors.Is(err, ErrUsageNotRecorded):
+			// Recorded, only the quota or later steps failed; retrying
+			// would count it twice
+			q.tracked.Add(1)
+			log.Printf("⚠️  Usage for user %s tracked with errors: %v", job.req.UserID, err)
+			return
+		case job.attempts >= usageQueueAttempts:
+			q.deadLetter(job, err)
+			return
+		}
+		q.retries.Add(1)
+	}
+}
+
+// deadLetter keeps an event that could not be tracked. If even that fails
+// the event is dropped, logging it so it can be recovered by hand.
+func (q *UsageQueue) deadLetter(job usageJob, trackErr error) {
+	if err := q.repo.AddUsageDeadLetter(job.req, trackErr.Error(), job.attempts); err != nil {
+		q.dropped.Add(1)
+		payload, _ := json.Marshal(job.req)
+		log.Printf("⚠️  Dropped usage event (%v, then %v): %s", trackErr, err, payload)
+		return
+	}
+	q.deadLettered.Add(1)
+	log.Printf("⚠️  Usage for user %s dead-lettered after %d attempts: %v", job.req.UserID, job.attempts, trackErr)
+}
+
+// Stats reports the queue's backlog and what became of its events
+func (q *UsageQueue) Stats() models.UsageQueueStats {
+	return models.UsageQueueStats{
+		Pending:      len(q.jobs),
+		Capacity:     cap(q.jobs),
+		Tracked:      q.tracked.Load(),
+		Retries:      q.retries.Load(),
+		DeadLettered: q.deadLettered.Load(),
+		Dropped:      q.dropped.Load(),
+	}
+}
+
+// SetUsageQueue enables retrying failed usage tracking through q
+func (s *UsageService) SetUsageQueue(q *UsageQueue) {
+	s.queue = q
+}
+
+// TrackUsageAsync tracks a usage event without blocking the caller,
+// through the queue when one is set
+func (s *UsageService) TrackUsageAsync(req *models.UsageRequest) {
+	if s.queue != nil {
+		s.queue.Enqueue(req)
+		return
+	}
+	go func() {
+		if err := s.TrackUsage(req); err != nil {
+			log.Printf("⚠️  Failed to track usage: %v", err)
+		}
+	}()
+}
+
+// RetryUsage hands an event TrackUsage failed with err to the queue, when
+// one is set and the usage was not recorded
+func (s *UsageService) RetryUsage(req *models.UsageRequest, err error) {
+	if s.queue != nil && errors.Is(err, ErrUsageNotRecorded) {
+		s.queue.Retry(req)
+	}
+}
+
+// ListUsageDeadLetters retrieves up to limit dead-lettered usage events,
+// oldest first, and how many there are in all
+func (s *UsageService) ListUsageDeadLetters(limit int) ([]models.UsageDeadLetter, int, error) {
+	if err := checkUsageDeadLetterLimit(limit); err != nil {
+		return nil, 0, err
+	}
+	letters, err := s.usageRepo.ListUsageDeadLetters(limit)
+	if err != nil {
+		return nil, 0, err
+	}
+	total, err := s.usageRepo.CountUsageDeadLetters()
+	if err != nil {
+		return nil, 0, err
+	}
+	return letters, total, nil
+}
+
+// ReplayUsageDeadLetters tracks up to limit dead-lettered events, oldest
+// first, removing the ones that get recorded. Replayed usage is recorded
+// at the time of the replay. It returns how many were replayed and how
+// many failed again.
+func (s *UsageService) ReplayUsageDeadLetters(limit int) (replayed, failed int, err error) {
+	if err := checkUsageDeadLetterLimit(limit); err != nil {
+		return 0, 0, err
+	}
+	letters, err := s.usageRepo.ListUsageDeadLetters(limit)
+	if err != nil {
+		return 0, 0, err
+	}
+	for _, l := range letters {
+		req := l.Request
+		if trackErr := s.TrackUsage(&req); trackErr != nil && errors.Is(trackErr, ErrUsageNotRecorded) {
+			failed++
+			if err := s.usageRepo.FailUsageDeadLetter(l.ID, trackErr.Error()); err != nil {
+				return replayed, failed, err
+			}
+			continue
+		} else if trackErr != nil {
+			log.Printf("⚠️  Replayed usage dead letter %d tracked with errors: %v", l.ID, trackErr)
+		}
+		replayed++
+		if err := s.usageRepo.DeleteUsageDeadLetter(l.ID); err != nil {
+			return replayed, failed, fmt.Errorf("usage dead letter %d replayed but not removed: %w", l.ID, err)
+		}
+	}
+	return replayed, failed, nil
+}
+
+func checkUsageDeadLetterLimit(limit int) error {
+	if limit < 1 || limit > maxUsageDeadLetters {
+		return fmt.Errorf("%w: limit must be between 1 and %d", ErrInvalidMessage, maxUsageDeadLetters)
+	}
+	return nil
+}
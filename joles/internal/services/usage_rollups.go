@@ -0,0 +1,34 @@
+package services
+
+import (
+	"log"
+	"time"
+)
+
+// usageRollupDelay is how long after an hour ends it is rolled up, so
+// usage still being tracked for it is not missed
+const usageRollupDelay = 5 * time.Minute
+
+// RollupUsage rolls up the usage of every hour that ended at least
+// usageRollupDelay before now, returning how many hours were rolled up
+func (s *UsageService) RollupUsage(now time.Time) (int, error) {
+	return s.usageRepo.RollupUsage(now.Add(-usageRollupDelay))
+}
+
+// StartUsageRollups keeps the hourly and daily usage rollups that usage
+// summaries and metrics read from current, checking on an interval until
+// the process exits
+func (s *UsageService) StartUsageRollups(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			if hours, err := s.RollupUsage(time.Now()); err != nil {
+				log.Printf("⚠️  Failed to roll up usage: %v", err)
+			} else if hours > 0 {
+				log.Printf("📊 Usage rollup: %d hours", hours)
+			}
+			<-ticker.C
+		}
+	}()
+}
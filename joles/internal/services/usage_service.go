@@ -1,25 +1,87 @@
 package services
 
 import (
+	"errors"
 	"fmt"
+	"strings"
 	"time"
 
+	"lio-ai/internal/mail"
 	"lio-ai/internal/models"
 	"lio-ai/internal/repositories"
 )
 
+// quotaReservationTTL is how long a reservation holds quota when the
+// request never settles it, e.g. because the process exited mid-request
+const quotaReservationTTL = 10 * time.Minute
+
+// ErrQuotaExceeded is returned when a request would take the user past a
+// daily or monthly token or cost limit
+var ErrQuotaExceeded = errors.New("quota exceeded")
+
+// ErrUsageNotRecorded wraps TrackUsage errors from before the usage was
+// written, which are safe to retry
+var ErrUsageNotRecorded = errors.New("usage not recorded")
+
 // UsageService handles business logic for usage tracking
 type UsageService struct {
 	usageRepo *repositories.UsageRepository
+	// Optional; adds storage usage to the quota status
+	storage *StorageService
+
+	// Optional alerts as usage crosses thresholds of quota limits
+	alerts        *repositories.QuotaAlertRepository
+	alertWebhooks *WebhookService
+	alertMailer   mail.Mailer
+	alertUsers    *repositories.UserRepository
+
+	// Days start at midnight and months on the 1st here; UTC when unset
+	resetLocation *time.Location
+
+	// Optional monthly spending budgets
+	budgets *repositories.BudgetRepository
+
+	// Optional organizations whose members share quota and budget limits
+	orgs *repositories.OrganizationRepository
+
+	// Optional plan tiers; adds the user's plan and rate limit to the quota
+	// status
+	plans *repositories.PlanRepository
+
+	// Cost multiplier for usage billed through a platform key
+	platformMarkup float64
+
+	// Live usage events of each user, published as usage is tracked
+	stream *UsageStream
+
+	// Optional queue TrackUsageAsync retries failed tracking through
+	queue *UsageQueue
 }
 
 // NewUsageService creates a new usage service
 func NewUsageService(usageRepo *repositories.UsageRepository) *UsageService {
 	return &UsageService{
 		usageRepo: usageRepo,
+		stream:    NewUsageStream(),
 	}
 }
 
+// SubscribeUsage returns a channel receiving userID's usage as it is
+// tracked, and a func that unsubscribes it
+func (s *UsageService) SubscribeUsage(userID string) (<-chan *models.UsageMetric, func()) {
+	return s.stream.Subscribe(userID)
+}
+
+// SetStorageService includes storage usage in quota status
+func (s *UsageService) SetStorageService(storage *StorageService) {
+	s.storage = storage
+}
+
+// SetPlans includes the user's plan and its rate limit in quota status
+func (s *UsageService) SetPlans(plans *repositories.PlanRepository) {
+	s.plans = plans
+}
+
 // CalculateCost calculates the cost based on token usage and model
 func (s *UsageService) CalculateCost(tokensInput, tokensOutput int, modelName string) (float64, error) {
 	config, err := s.usageRepo.GetCostConfig(modelName)
@@ -35,12 +97,24 @@ func (s *UsageService) CalculateCost(tokensInput, tokensOutput int, modelName st
 	return totalCost, nil
 }
 
-// TrackUsage tracks a usage event
+// TrackUsage tracks a usage event. Errors from before the usage was
+// written wrap ErrUsageNotRecorded.
 func (s *UsageService) TrackUsage(req *models.UsageRequest) error {
 	// Calculate cost
 	cost, err := s.CalculateCost(req.TokensInput, req.TokensOutput, req.ModelUsed)
 	if err != nil {
-		return err
+		return fmt.Errorf("%w: %w", ErrUsageNotRecorded, err)
+	}
+
+	// Platform fallback keys are billed at the configured markup
+	if req.KeySource == KeySourcePlatform {
+		cost *= s.platformKeyMarkup()
+	}
+
+	// Infer the provider from the model when the caller did not supply one
+	provider := req.Provider
+	if provider == "" {
+		provider = ProviderForModel(req.ModelUsed)
 	}
 
 	// Create usage metric
@@ -55,50 +129,100 @@ func (s *UsageService) TrackUsage(req *models.UsageRequest) error {
 		CostUSD:      cost,
 		DurationMs:   req.DurationMs,
 		Endpoint:     req.Endpoint,
+		Provider:     provider,
+		KeySource:    req.KeySource,
 		Success:      req.Success,
 		ErrorMessage: req.ErrorMessage,
 	}
 
 	// Track the usage
 	if err := s.usageRepo.TrackUsage(metric); err != nil {
-		return fmt.Errorf("failed to track usage: %w", err)
+		if metric.ID == 0 {
+			return fmt.Errorf("%w: %w", ErrUsageNotRecorded, err)
+		}
+		return err
 	}
 
-	// Update quota if successful
-	if req.Success {
+	// Settle the request's reservation with its actual usage, or update
+	// quota if successful
+	switch {
+	case req.ReservationID != 0 && req.Success:
+		if err := s.usageRepo.SettleReservation(req.ReservationID, req.UserID, metric.TokensTotal, cost); err != nil {
+			return fmt.Errorf("failed to update quota: %w", err)
+		}
+	case req.ReservationID != 0:
+		if err := s.usageRepo.ReleaseReservation(req.ReservationID); err != nil {
+			return fmt.Errorf("failed to update quota: %w", err)
+		}
+	case req.Success:
 		if err := s.usageRepo.UpdateQuotaUsage(req.UserID, metric.TokensTotal, cost); err != nil {
 			return fmt.Errorf("failed to update quota: %w", err)
 		}
 	}
+	s.stream.Publish(metric)
+	if req.Success {
+		s.checkQuotaAlerts(req.UserID)
+	}
 
 	return nil
 }
 
-// CheckQuota checks if user has enough quota
-func (s *UsageService) CheckQuota(userID string, tokensNeeded int, modelName string) (bool, error) {
-	// Get or create user quota
+// HasQuota reports whether userID has a quota yet; unlike the other quota
+// methods it does not create one
+func (s *UsageService) HasQuota(userID string) (bool, error) {
+	return s.usageRepo.QuotaExists(userID)
+}
+
+// currentQuota retrieves a user's quota, creating it with defaults. Days
+// and months are started by StartQuotaResets.
+func (s *UsageService) currentQuota(userID string) (*models.UserQuota, error) {
 	quota, err := s.usageRepo.GetUserQuota(userID)
 	if err != nil {
-		return false, fmt.Errorf("failed to get user quota: %w", err)
+		return nil, fmt.Errorf("failed to get user quota: %w", err)
 	}
+	return quota, nil
+}
 
-	// Check if daily/monthly reset is needed
-	now := time.Now()
-	if now.Sub(quota.LastResetDaily) >= 24*time.Hour {
-		if err := s.usageRepo.ResetDailyQuota(userID); err != nil {
-			return false, fmt.Errorf("failed to reset daily quota: %w", err)
-		}
-		quota.DailyTokensUsed = 0
-		quota.DailyCostUsedUSD = 0.0
+// ReserveQuota holds tokens and their estimated cost against userID's
+// quota while a request runs. Concurrent reservations are checked
+// atomically against the limits, so together they cannot overspend. The
+// reservation is settled with the actual usage by TrackUsage when the
+// request's UsageRequest carries its ID, or dropped by ReleaseReservation.
+func (s *UsageService) ReserveQuota(userID string, tokens int, costUSD float64) (int64, error) {
+	if _, err := s.currentQuota(userID); err != nil {
+		return 0, err
 	}
+	id, err := s.usageRepo.ReserveQuota(userID, tokens, costUSD, time.Now().Add(quotaReservationTTL))
+	if err != nil {
+		return 0, err
+	}
+	if id == 0 {
+		return 0, fmt.Errorf("%w: %d tokens ($%.6f) would exceed the daily or monthly limit of the user or their organization", ErrQuotaExceeded, tokens, costUSD)
+	}
+	return id, nil
+}
 
-	if now.Sub(quota.LastResetMonthly) >= 30*24*time.Hour {
-		if err := s.usageRepo.ResetMonthlyQuota(userID); err != nil {
-			return false, fmt.Errorf("failed to reset monthly quota: %w", err)
-		}
-		quota.MonthlyTokensUsed = 0
-		quota.MonthlyCostUsedUSD = 0.0
+// ReleaseReservation drops a reservation whose request was never sent
+func (s *UsageService) ReleaseReservation(id int64) error {
+	return s.usageRepo.ReleaseReservation(id)
+}
+
+// CheckQuota checks if user has enough quota, counting what requests in
+// flight have reserved. When the user belongs to an organization with a
+// quota, its members' combined usage must also leave room for the request.
+func (s *UsageService) CheckQuota(userID string, tokensNeeded int, modelName string) (bool, error) {
+	quota, err := s.currentQuota(userID)
+	if err != nil {
+		return false, err
 	}
+	reservedTokens, reservedCost, err := s.usageRepo.GetReservedQuota(userID)
+	if err != nil {
+		return false, err
+	}
+	quota.DailyTokensUsed += reservedTokens
+	quota.MonthlyTokensUsed += reservedTokens
+	quota.DailyCostUsedUSD += reservedCost
+	quota.MonthlyCostUsedUSD += reservedCost
 
 	// Check token limits
 	if quota.DailyTokensUsed+tokensNeeded > quota.DailyTokenLimit {
@@ -121,32 +245,22 @@ func (s *UsageService) CheckQuota(userID string, tokensNeeded int, modelName str
 		return false, nil
 	}
 
+	org, err := s.userOrgQuota(userID)
+	if err != nil {
+		return false, err
+	}
+	if org != nil && !org.Allows(tokensNeeded, estimatedCost) {
+		return false, nil
+	}
+
 	return true, nil
 }
 
 // GetQuotaStatus retrieves the current quota status for a user
 func (s *UsageService) GetQuotaStatus(userID string) (*models.QuotaStatus, error) {
-	quota, err := s.usageRepo.GetUserQuota(userID)
+	quota, err := s.currentQuota(userID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get user quota: %w", err)
-	}
-
-	// Check if reset is needed
-	now := time.Now()
-	if now.Sub(quota.LastResetDaily) >= 24*time.Hour {
-		if err := s.usageRepo.ResetDailyQuota(userID); err != nil {
-			return nil, fmt.Errorf("failed to reset daily quota: %w", err)
-		}
-		quota.DailyTokensUsed = 0
-		quota.DailyCostUsedUSD = 0.0
-	}
-
-	if now.Sub(quota.LastResetMonthly) >= 30*24*time.Hour {
-		if err := s.usageRepo.ResetMonthlyQuota(userID); err != nil {
-			return nil, fmt.Errorf("failed to reset monthly quota: %w", err)
-		}
-		quota.MonthlyTokensUsed = 0
-		quota.MonthlyCostUsedUSD = 0.0
+		return nil, err
 	}
 
 	status := &models.QuotaStatus{
@@ -170,27 +284,87 @@ func (s *UsageService) GetQuotaStatus(userID string) (*models.QuotaStatus, error
 		LastResetDaily:           quota.LastResetDaily,
 		LastResetMonthly:         quota.LastResetMonthly,
 	}
+	status.DailyResetsAt, status.MonthlyResetsAt = s.nextQuotaResets(time.Now())
+
+	if s.storage != nil {
+		storage, err := s.storage.Usage(userID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get storage usage: %w", err)
+		}
+		status.Storage = storage
+	}
+	if status.ReservedTokens, status.ReservedCostUSD, err = s.usageRepo.GetReservedQuota(userID); err != nil {
+		return nil, err
+	}
+	if status.Organization, err = s.userOrgQuota(userID); err != nil {
+		return nil, err
+	}
+	if s.plans != nil {
+		plan, err := s.plans.GetForUser(userID)
+		if err != nil {
+			return nil, err
+		}
+		if plan != nil {
+			status.Plan, status.RequestsPerMinute = plan.Name, plan.RequestsPerMinute
+		}
+	}
 
 	return status, nil
 }
 
-// GetUsageSummary retrieves aggregated usage for a user
-func (s *UsageService) GetUsageSummary(userID, period string) (*models.UsageSummary, error) {
-	summary, err := s.usageRepo.GetUsageSummary(userID, period)
+// GetUsageSummary retrieves aggregated usage for a user over period, the
+// day or month up to now or all time, of the usage filter matches when it
+// is not nil. A non-zero from or to replaces that end of the period's
+// range.
+func (s *UsageService) GetUsageSummary(userID, period string, from, to time.Time, filter *models.UsageFilter) (*models.UsageSummary, error) {
+	since := usagePeriodStart(period, time.Now())
+	if !from.IsZero() {
+		since = from
+	}
+	if !since.IsZero() && !to.IsZero() {
+		if err := checkUsageRange(since, to); err != nil {
+			return nil, err
+		}
+	}
+
+	summary, err := s.usageRepo.GetUsageSummary(userID, since, to, filter)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get usage summary: %w", err)
 	}
 
 	// Get breakdown by endpoint
-	endpoints, err := s.usageRepo.GetUsageByEndpoint(userID, period)
+	endpoints, err := s.usageRepo.GetUsageByEndpoint(userID, since, to, filter)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get usage by endpoint: %w", err)
 	}
 
+	summary.Period = period
 	summary.EndpointBreakdown = endpoints
+	if !since.IsZero() {
+		since = since.UTC()
+		summary.From = &since
+	}
+	if !to.IsZero() {
+		to = to.UTC()
+		summary.To = &to
+	}
 	return summary, nil
 }
 
+// usagePeriodStart returns when a usage summary period ending at now
+// starts: a day or a month back for "daily" and "monthly", and the zero
+// time (all usage) otherwise
+func usagePeriodStart(period string, now time.Time) time.Time {
+	switch period {
+	case "daily":
+		return now.AddDate(0, 0, -1)
+	case "monthly":
+		return now.AddDate(0, -1, 0)
+	default:
+		return time.Time{}
+	}
+}
+
 // UpdateQuota updates the quota limits for a user
 func (s *UsageService) UpdateQuota(userID string, req *models.QuotaUpdateRequest) error {
 	updates := make(map[string]interface{})
@@ -214,3 +388,60 @@ func (s *UsageService) UpdateQuota(userID string, req *models.QuotaUpdateRequest
 
 	return s.usageRepo.UpdateUserQuota(userID, updates)
 }
+
+// GetProviderKeyUsage retrieves usage analytics for one of the user's provider keys
+func (s *UsageService) GetProviderKeyUsage(userID, provider string) (*models.ProviderKeyUsage, error) {
+	usage, err := s.usageRepo.GetProviderKeyUsage(userID, provider)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get provider key usage: %w", err)
+	}
+	return usage, nil
+}
+
+// CheapestChatModel returns the lowest-priced active chat model
+func (s *UsageService) CheapestChatModel() (string, error) {
+	return s.usageRepo.GetCheapestModel("chat")
+}
+
+// GetChatUsage retrieves completion usage recorded against one of the user's chats
+func (s *UsageService) GetChatUsage(userID string, chatID int64) (*models.ChatStats, error) {
+	stats, err := s.usageRepo.GetChatUsage(userID, chatID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get chat usage: %w", err)
+	}
+	return stats, nil
+}
+
+// ProviderForModel infers the provider that serves a model from its name.
+// Returns an empty string for local or unknown models.
+func ProviderForModel(model string) string {
+	name := strings.ToLower(model)
+	if i := strings.Index(name, "/"); i > 0 {
+		return name[:i]
+	}
+
+	switch {
+	case strings.HasPrefix(name, "gpt-"), strings.HasPrefix(name, "o1"), strings.HasPrefix(name, "text-embedding"):
+		return "openai"
+	case strings.HasPrefix(name, "claude"):
+		return "anthropic"
+	case strings.HasPrefix(name, "gemini"):
+		return "google"
+	case strings.HasPrefix(name, "command"), strings.HasPrefix(name, "embed-"):
+		return "cohere"
+	default:
+		return ""
+	}
+}
+
+// GetUsageByResource sums userID's usage created in [from, to) per chat or
+// document, most expensive first, and returns how many resources there are
+func (s *UsageService) GetUsageByResource(userID, resourceType string, from, to time.Time, limit, offset int) ([]models.UsageByResource, int, error) {
+	if resourceType != models.UsageResourceChat && resourceType != models.UsageResourceDocument {
+		return nil, 0, fmt.Errorf("%w: type must be 'chat' or 'document'", ErrInvalidMessage)
+	}
+	if err := checkUsageRange(from, to); err != nil {
+		return nil, 0, err
+	}
+	return s.usageRepo.GetUsageByResource(userID, resourceType, from, to, limit, offset)
+}
@@ -0,0 +1,105 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"lio-ai/internal/models"
+)
+
+// ErrUnknownModel is returned when simulating a model that is not in the
+// pricing catalog
+var ErrUnknownModel = errors.New("unknown model")
+
+// Days the usage profile covers when the request names none, and the
+// length of the month projections are scaled to
+const (
+	defaultSimulationDays = 30
+	simulationMonthDays   = 30
+)
+
+// SimulateUsage replays userID's recent usage profile against other
+// models' prices and projects the monthly cost of each, so users can see
+// what switching their default model would save or cost
+func (s *UsageService) SimulateUsage(userID string, req *models.SimulateUsageRequest) (*models.UsageSimulation, error) {
+	days := req.Days
+	if days == 0 {
+		days = defaultSimulationDays
+	}
+	since := time.Now().AddDate(0, 0, -days)
+	scale := float64(simulationMonthDays) / float64(days)
+
+	profile, err := s.usageRepo.GetUsageProfile(userID, since, req.RequestType)
+	if err != nil {
+		return nil, err
+	}
+
+	sim := &models.UsageSimulation{
+		Days:        days,
+		Since:       since.UTC(),
+		RequestType: req.RequestType,
+		Profile:     profile,
+		Projections: make([]models.ModelCostProjection, 0, len(req.Models)),
+	}
+	for i := range profile {
+		p := &profile[i]
+		cost, err := s.CalculateCost(int(p.TokensInput), int(p.TokensOutput), p.Model)
+		if err != nil {
+			return nil, err
+		}
+		p.MonthlyCostUSD = roundUSD(cost * scale)
+		sim.CurrentMonthlyCostUSD += cost * scale
+	}
+
+	seen := make(map[string]bool, len(req.Models))
+	for _, model := range req.Models {
+		model = strings.TrimSpace(model)
+		if seen[model] {
+			continue
+		}
+		seen[model] = true
+
+		// Unknown models are priced as "default", which would mislead here
+		config, err := s.usageRepo.GetCostConfig(model)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get cost config: %w", err)
+		}
+		if config.ModelName != model {
+			return nil, fmt.Errorf("%w: %s has no pricing", ErrUnknownModel, model)
+		}
+
+		var monthly float64
+		for _, p := range profile {
+			cost, err := s.CalculateCost(int(p.TokensInput), int(p.TokensOutput), model)
+			if err != nil {
+				return nil, err
+			}
+			monthly += cost * scale
+		}
+		projection := models.ModelCostProjection{
+			Model:          model,
+			MonthlyCostUSD: roundUSD(monthly),
+			DeltaUSD:       roundUSD(monthly - sim.CurrentMonthlyCostUSD),
+		}
+		if sim.CurrentMonthlyCostUSD > 0 {
+			projection.DeltaPercent = math.Round((monthly-sim.CurrentMonthlyCostUSD)/sim.CurrentMonthlyCostUSD*1000) / 10
+		}
+		sim.Projections = append(sim.Projections, projection)
+	}
+	sort.SliceStable(sim.Projections, func(i, j int) bool {
+		return sim.Projections[i].MonthlyCostUSD < sim.Projections[j].MonthlyCostUSD
+	})
+	sim.CurrentMonthlyCostUSD = roundUSD(sim.CurrentMonthlyCostUSD)
+
+	return sim, nil
+}
+
+// roundUSD rounds to a millionth of a dollar, the precision per-token
+// prices are given in. Adding zero turns a rounded -0 into 0.
+func roundUSD(v float64) float64 {
+	return math.Round(v*1e6)/1e6 + 0
+}
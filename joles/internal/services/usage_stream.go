@@ -0,0 +1,57 @@
+package services
+
+import (
+	"sync"
+
+	"lio-ai/internal/models"
+)
+
+// usageStreamBuffer is how many events a subscriber can fall behind by
+// before further events are dropped for it
+const usageStreamBuffer = 64
+
+// UsageStream fans usage events out to the subscribers of each user as
+// TrackUsage records them. Events are only delivered within this process.
+type UsageStream struct {
+	mu   sync.Mutex
+	subs map[string]map[chan *models.UsageMetric]struct{}
+}
+
+// NewUsageStream creates a usage stream without subscribers
+func NewUsageStream() *UsageStream {
+	return &UsageStream{subs: make(map[string]map[chan *models.UsageMetric]struct{})}
+}
+
+// Subscribe returns a channel receiving userID's usage events and a func
+// that unsubscribes it, which must be called once the subscriber is done
+func (s *UsageStream) Subscribe(userID string) (<-chan *models.UsageMetric, func()) {
+	ch := make(chan *models.UsageMetric, usageStreamBuffer)
+	s.mu.Lock()
+	if s.subs[userID] == nil {
+		s.subs[userID] = make(map[chan *models.UsageMetric]struct{})
+	}
+	s.subs[userID][ch] = struct{}{}
+	s.mu.Unlock()
+
+	return ch, func() {
+		s.mu.Lock()
+		delete(s.subs[userID], ch)
+		if len(s.subs[userID]) == 0 {
+			delete(s.subs, userID)
+		}
+		s.mu.Unlock()
+	}
+}
+
+// Publish sends a usage event to its user's subscribers without waiting;
+// a subscriber whose buffer is full misses it
+func (s *UsageStream) Publish(metric *models.UsageMetric) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for ch := range s.subs[metric.UserID] {
+		select {
+		case ch <- metric:
+		default:
+		}
+	}
+}
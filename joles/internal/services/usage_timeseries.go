@@ -0,0 +1,65 @@
+package services
+
+import (
+	"fmt"
+	"time"
+
+	"lio-ai/internal/models"
+)
+
+// Most buckets a usage time series can have: a month of hours, or a year
+// of days
+const (
+	maxHourlyBuckets = 31 * 24
+	maxDailyBuckets  = 366
+)
+
+// GetUsageTimeseries sums a metric of userID's usage into hourly or daily
+// UTC buckets over [from, to). from is rounded down and to up to whole
+// buckets; buckets without usage have a value of 0.
+func (s *UsageService) GetUsageTimeseries(userID, granularity, metric string, from, to time.Time) (*models.UsageTimeseries, error) {
+	step, maxBuckets := 24*time.Hour, maxDailyBuckets
+	switch granularity {
+	case models.UsageGranularityDay:
+	case models.UsageGranularityHour:
+		step, maxBuckets = time.Hour, maxHourlyBuckets
+	default:
+		return nil, fmt.Errorf("%w: granularity must be 'hour' or 'day'", ErrInvalidMessage)
+	}
+	switch metric {
+	case models.UsageMetricTokens, models.UsageMetricCost, models.UsageMetricRequests:
+	default:
+		return nil, fmt.Errorf("%w: metric must be 'tokens', 'cost' or 'requests'", ErrInvalidMessage)
+	}
+
+	from = from.UTC().Truncate(step)
+	if rounded := to.UTC().Truncate(step); rounded.Before(to) {
+		to = rounded.Add(step)
+	} else {
+		to = rounded
+	}
+	if !from.Before(to) {
+		return nil, fmt.Errorf("%w: from must be before to", ErrInvalidMessage)
+	}
+	if n := int(to.Sub(from) / step); n > maxBuckets {
+		return nil, fmt.Errorf("%w: at most %d %s buckets can be requested, not %d", ErrInvalidMessage, maxBuckets, granularity, n)
+	}
+
+	values, err := s.usageRepo.GetUsageTimeseries(userID, metric, granularity == models.UsageGranularityHour, from, to)
+	if err != nil {
+		return nil, err
+	}
+	series := &models.UsageTimeseries{
+		Granularity: granularity,
+		Metric:      metric,
+		From:        from,
+		To:          to,
+		Data:        make([]models.UsageTimeseriesPoint, 0, int(to.Sub(from)/step)),
+	}
+	for t := from; t.Before(to); t = t.Add(step) {
+		v := values[t]
+		series.Data = append(series.Data, models.UsageTimeseriesPoint{BucketStart: t, Value: v})
+		series.Total += v
+	}
+	return series, nil
+}
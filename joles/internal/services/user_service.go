@@ -7,6 +7,7 @@ import (
 	"lio-ai/internal/auth"
 	"lio-ai/internal/models"
 	"lio-ai/internal/repositories"
+	"strconv"
 	"time"
 )
 
@@ -19,8 +20,10 @@ var (
 
 // UserService handles user-related business logic
 type UserService struct {
-	repo       *repositories.UserRepository
-	jwtManager *auth.JWTManager
+	repo        *repositories.UserRepository
+	jwtManager  *auth.JWTManager
+	revocations *repositories.TokenRevocationRepository
+	sessions    *repositories.SessionRepository
 }
 
 // NewUserService creates a new user service
@@ -31,6 +34,28 @@ func NewUserService(repo *repositories.UserRepository, jwtManager *auth.JWTManag
 	}
 }
 
+// SetTokenRevocations enables revoking login tokens on logout and
+// password change
+func (s *UserService) SetTokenRevocations(revocations *repositories.TokenRevocationRepository) {
+	s.revocations = revocations
+}
+
+// RevokeToken revokes a login token by its jti until it expires, ending
+// its session. Tokens issued without a jti cannot be revoked and are left
+// to expire.
+func (s *UserService) RevokeToken(tokenID, userID string, expiresAt time.Time) error {
+	if s.revocations == nil || tokenID == "" {
+		return nil
+	}
+	if err := s.revocations.RevokeToken(tokenID, userID, expiresAt); err != nil {
+		return err
+	}
+	if s.sessions != nil {
+		return s.sessions.DeleteByTokenID(tokenID)
+	}
+	return nil
+}
+
 // Register creates a new user account
 func (s *UserService) Register(username, email, password, fullName string) (*models.User, error) {
 	// Validate password
@@ -61,8 +86,9 @@ func (s *UserService) Register(username, email, password, fullName string) (*mod
 	return user, nil
 }
 
-// Login authenticates a user and returns JWT token
-func (s *UserService) Login(email, password string) (string, *models.User, error) {
+// Login authenticates a user and returns JWT token, starting a session on
+// client
+func (s *UserService) Login(email, password string, client models.SessionClient) (string, *models.User, error) {
 	log.Printf("🔍 Login attempt for: %s", email)
 	
 	// Find user by email
@@ -97,16 +123,9 @@ func (s *UserService) Login(email, password string) (string, *models.User, error
 	// Update last login
 	_ = s.repo.UpdateLastLogin(user.ID)
 
-	// Generate JWT token (24-hour expiration)
-	// Use string conversion of user.ID as the subject
-	token, err := s.jwtManager.GenerateToken(
-		fmt.Sprintf("%d", user.ID),
-		user.Email,
-		[]string{user.Role},
-		24*time.Hour,
-	)
+	token, err := s.issueToken(user, client)
 	if err != nil {
-		return "", nil, errors.New("failed to generate token")
+		return "", nil, err
 	}
 
 	return token, user, nil
@@ -145,19 +164,38 @@ func (s *UserService) ChangePassword(userID int64, oldPassword, newPassword stri
 		return errors.New("failed to process password")
 	}
 
+	// Revoke every token issued with the old password, before it changes
+	// so a failure leaves it unchanged. The cutoff has the one-second
+	// resolution of iat, so tokens with a session are revoked by jti too.
+	if s.revocations != nil {
+		if err := s.revocations.RevokeUserTokens(strconv.FormatInt(userID, 10), time.Now()); err != nil {
+			return err
+		}
+	}
+	if s.sessions != nil {
+		if err := s.sessions.TerminateAll(userID); err != nil {
+			return err
+		}
+	}
+
 	// Update password in database
 	return s.repo.UpdatePassword(userID, hash)
 }
 
-// GenerateTokenForUser generates a JWT token for a user
-func (s *UserService) GenerateTokenForUser(user *models.User) (string, error) {
+// GenerateTokenForUser generates a JWT token for a user, starting a
+// session on client
+func (s *UserService) GenerateTokenForUser(user *models.User, client models.SessionClient) (string, error) {
 	if user == nil {
 		return "", errors.New("user cannot be nil")
 	}
-	
+	return s.issueToken(user, client)
+}
+
+// issueToken generates a JWT token for a user and records its session
+func (s *UserService) issueToken(user *models.User, client models.SessionClient) (string, error) {
 	// Generate JWT token with 24-hour expiration
 	// Use string conversion of user.ID as the subject
-	token, err := s.jwtManager.GenerateToken(
+	token, claims, err := s.jwtManager.GenerateToken(
 		fmt.Sprintf("%d", user.ID),
 		user.Email,
 		[]string{user.Role},
@@ -166,6 +204,20 @@ func (s *UserService) GenerateTokenForUser(user *models.User) (string, error) {
 	if err != nil {
 		return "", errors.New("failed to generate token")
 	}
-	
+
+	if s.sessions != nil {
+		session := &models.Session{
+			UserID:    user.ID,
+			TokenID:   claims.ID,
+			IPAddress: client.IPAddress,
+			UserAgent: client.UserAgent,
+			ExpiresAt: claims.ExpiresAt.Time,
+		}
+		if err := s.sessions.Create(session); err != nil {
+			log.Printf("❌ Failed to record session for user %d: %v", user.ID, err)
+			return "", errors.New("failed to start session")
+		}
+	}
+
 	return token, nil
 }
@@ -0,0 +1,363 @@
+package services
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/google/uuid"
+	"lio-ai/internal/models"
+	"lio-ai/internal/repositories"
+)
+
+var (
+	// ErrWebhookLimit is returned when a user already has the most webhooks allowed
+	ErrWebhookLimit = errors.New("webhook limit reached")
+	// ErrWebhookAddress is returned when a webhook URL resolves to an
+	// address deliveries may not be sent to
+	ErrWebhookAddress = errors.New("webhook address not allowed")
+)
+
+const (
+	maxWebhooksPerUser = 10
+	// Deliveries kept per webhook for the delivery log
+	maxWebhookDeliveries = 100
+	// Bytes of the receiver's response kept with each delivery
+	webhookExcerptBytes = 1024
+)
+
+// WebhookService sends signed event payloads to users' webhooks and logs
+// each delivery. Every request carries X-Lio-Event, X-Lio-Delivery (the
+// event ID, the same on retries), X-Lio-Timestamp and X-Lio-Signature:
+// "sha256=" and the hex HMAC-SHA256, keyed by the webhook's secret, of the
+// timestamp, a dot and the body.
+type WebhookService struct {
+	repo   *repositories.WebhookRepository
+	client *http.Client
+	// Deliveries to loopback, private and link-local addresses are
+	// refused unless allowed, so webhooks cannot reach internal services
+	allowPrivate bool
+}
+
+// NewWebhookService creates a webhook service whose deliveries time out
+// after timeout
+func NewWebhookService(repo *repositories.WebhookRepository, timeout time.Duration, allowPrivate bool) *WebhookService {
+	s := &WebhookService{repo: repo, allowPrivate: allowPrivate}
+	dialer := &net.Dialer{Timeout: timeout, Control: s.checkDial}
+	s.client = &http.Client{
+		Timeout:   timeout,
+		Transport: &http.Transport{DialContext: dialer.DialContext, Proxy: nil},
+		// A redirect's response is the delivery's result
+		CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+	}
+	return s
+}
+
+// webhookEvent is the payload delivered for an event
+type webhookEvent struct {
+	ID        string      `json:"id"`
+	Type      string      `json:"type"`
+	CreatedAt time.Time   `json:"created_at"`
+	Test      bool        `json:"test,omitempty"`
+	Data      interface{} `json:"data"`
+}
+
+// Create registers a webhook for userID, subscribed to events or, when
+// there are none, to every event. The returned webhook carries its secret.
+func (s *WebhookService) Create(userID string, req *models.CreateWebhookRequest) (*models.Webhook, error) {
+	if err := s.checkURL(req.URL); err != nil {
+		return nil, err
+	}
+	events := models.WebhookEvents
+	if len(req.Events) > 0 {
+		events = make([]string, 0, len(req.Events))
+		for _, e := range req.Events {
+			if !knownWebhookEvent(e) {
+				return nil, fmt.Errorf("%w: unknown event type %q", ErrInvalidMessage, e)
+			}
+			if !slices.Contains(events, e) {
+				events = append(events, e)
+			}
+		}
+	}
+
+	count, err := s.repo.CountByUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	if count >= maxWebhooksPerUser {
+		return nil, fmt.Errorf("%w: at most %d webhooks", ErrWebhookLimit, maxWebhooksPerUser)
+	}
+
+	secret := make([]byte, 24)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, fmt.Errorf("failed to generate webhook secret: %w", err)
+	}
+	w := &models.Webhook{
+		UserID: userID,
+		URL:    req.URL,
+		Events: events,
+		Secret: "whsec_" + hex.EncodeToString(secret),
+		Active: true,
+	}
+	if err := s.repo.Create(w); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// List retrieves userID's webhooks without their secrets
+func (s *WebhookService) List(userID string) ([]models.Webhook, error) {
+	webhooks, err := s.repo.ListByUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range webhooks {
+		webhooks[i].Secret = ""
+	}
+	return webhooks, nil
+}
+
+// Delete removes one of userID's webhooks with its delivery log
+func (s *WebhookService) Delete(id int64, userID string) error {
+	found, err := s.repo.Delete(id, userID)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return fmt.Errorf("%w: webhook %d", ErrNotFound, id)
+	}
+	return nil
+}
+
+// Test sends a sample payload of eventType, or the webhook's first event
+// type, to one of userID's webhooks and returns the delivery's result
+func (s *WebhookService) Test(id int64, userID, eventType string) (*models.WebhookDelivery, error) {
+	w, err := s.ownedWebhook(id, userID)
+	if err != nil {
+		return nil, err
+	}
+	if eventType == "" {
+		eventType = w.Events[0]
+	}
+	if !knownWebhookEvent(eventType) {
+		return nil, fmt.Errorf("%w: unknown event type %q", ErrInvalidMessage, eventType)
+	}
+
+	now := time.Now().UTC()
+	var data map[string]interface{}
+	if eventType == models.WebhookEventQuotaThreshold {
+		data = quotaAlertEventData(&models.QuotaAlert{
+			ID: 1, Metric: models.QuotaMetricDailyTokens, Threshold: 80, Used: 80000, Limit: 100000,
+			PeriodStart: now.Truncate(24 * time.Hour), CreatedAt: now,
+		})
+	} else {
+		data = documentEventData(&models.Document{
+			ID: 1, Title: "Example document", ContentType: models.DocumentContentMarkdown,
+			Tags: []string{"example"}, WordCount: 42, CreatedAt: now, UpdatedAt: now,
+		})
+	}
+	eventID := uuid.NewString()
+	payload, err := json.Marshal(webhookEvent{
+		ID: eventID, Type: eventType, CreatedAt: now, Test: true, Data: data,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode webhook event: %w", err)
+	}
+	return s.deliver(w, &models.WebhookDelivery{
+		WebhookID: w.ID, EventID: eventID, EventType: eventType, Payload: payload, Test: true,
+	})
+}
+
+// Deliveries retrieves the most recent deliveries to one of userID's
+// webhooks, newest first
+func (s *WebhookService) Deliveries(id int64, userID string, limit int) ([]models.WebhookDelivery, error) {
+	if _, err := s.ownedWebhook(id, userID); err != nil {
+		return nil, err
+	}
+	return s.repo.ListDeliveries(id, limit)
+}
+
+// Retry sends a delivery's payload to one of userID's webhooks again,
+// signed anew, and returns the new delivery
+func (s *WebhookService) Retry(id int64, userID string, deliveryID int64) (*models.WebhookDelivery, error) {
+	w, err := s.ownedWebhook(id, userID)
+	if err != nil {
+		return nil, err
+	}
+	previous, err := s.repo.GetDelivery(deliveryID, id)
+	if err != nil {
+		return nil, err
+	}
+	if previous == nil {
+		return nil, fmt.Errorf("%w: delivery %d of webhook %d", ErrNotFound, deliveryID, id)
+	}
+	return s.deliver(w, &models.WebhookDelivery{
+		WebhookID: w.ID, EventID: previous.EventID, EventType: previous.EventType,
+		Payload: previous.Payload, Test: previous.Test, RetryOf: &previous.ID,
+	})
+}
+
+// Dispatch sends an event to userID's active webhooks subscribed to it, in
+// the background
+func (s *WebhookService) Dispatch(userID, eventType string, data interface{}) {
+	go func() {
+		webhooks, err := s.repo.ListForEvent(userID, eventType)
+		if err != nil {
+			log.Printf("⚠️  Failed to load webhooks for %s: %v", eventType, err)
+			return
+		}
+		if len(webhooks) == 0 {
+			return
+		}
+		eventID := uuid.NewString()
+		payload, err := json.Marshal(webhookEvent{ID: eventID, Type: eventType, CreatedAt: time.Now().UTC(), Data: data})
+		if err != nil {
+			log.Printf("⚠️  Failed to encode webhook event %s: %v", eventType, err)
+			return
+		}
+		for i := range webhooks {
+			d := &models.WebhookDelivery{WebhookID: webhooks[i].ID, EventID: eventID, EventType: eventType, Payload: payload}
+			if _, err := s.deliver(&webhooks[i], d); err != nil {
+				log.Printf("⚠️  Failed to record delivery to webhook %d: %v", webhooks[i].ID, err)
+			}
+		}
+	}()
+}
+
+// deliver POSTs d's payload to w and records the outcome on d
+func (s *WebhookService) deliver(w *models.Webhook, d *models.WebhookDelivery) (*models.WebhookDelivery, error) {
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(w.Secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(d.Payload)
+
+	started := time.Now()
+	req, err := http.NewRequest(http.MethodPost, w.URL, bytes.NewReader(d.Payload))
+	if err == nil {
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("User-Agent", "Lio-Webhooks/1.0")
+		req.Header.Set("X-Lio-Event", d.EventType)
+		req.Header.Set("X-Lio-Delivery", d.EventID)
+		req.Header.Set("X-Lio-Timestamp", timestamp)
+		req.Header.Set("X-Lio-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+
+		var resp *http.Response
+		if resp, err = s.client.Do(req); err == nil {
+			excerpt, _ := io.ReadAll(io.LimitReader(resp.Body, webhookExcerptBytes))
+			resp.Body.Close()
+			d.StatusCode = resp.StatusCode
+			d.Success = resp.StatusCode >= 200 && resp.StatusCode < 300
+			d.ResponseExcerpt = strings.ToValidUTF8(string(excerpt), "")
+		}
+	}
+	d.LatencyMs = time.Since(started).Milliseconds()
+	if err != nil {
+		d.Error = err.Error()
+	}
+
+	if err := s.repo.RecordDelivery(d, maxWebhookDeliveries); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+func (s *WebhookService) ownedWebhook(id int64, userID string) (*models.Webhook, error) {
+	w, err := s.repo.Get(id, userID)
+	if err != nil {
+		return nil, err
+	}
+	if w == nil {
+		return nil, fmt.Errorf("%w: webhook %d", ErrNotFound, id)
+	}
+	return w, nil
+}
+
+// checkURL validates a webhook URL, refusing hosts that are internal
+// addresses on their face; names resolving to them are refused on dial
+func (s *WebhookService) checkURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return fmt.Errorf("%w: url must be an absolute http or https URL", ErrInvalidMessage)
+	}
+	if s.allowPrivate {
+		return nil
+	}
+	host := u.Hostname()
+	if ip := net.ParseIP(host); (ip != nil && internalAddress(ip)) || strings.EqualFold(host, "localhost") {
+		return fmt.Errorf("%w: %s is an internal address", ErrWebhookAddress, host)
+	}
+	return nil
+}
+
+// checkDial refuses connections to internal addresses once a delivery's
+// host is resolved
+func (s *WebhookService) checkDial(network, address string, _ syscall.RawConn) error {
+	if s.allowPrivate {
+		return nil
+	}
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if ip := net.ParseIP(host); ip == nil || internalAddress(ip) {
+		return fmt.Errorf("%w: %s", ErrWebhookAddress, host)
+	}
+	return nil
+}
+
+func internalAddress(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsUnspecified() || ip.IsMulticast()
+}
+
+func knownWebhookEvent(event string) bool {
+	return slices.Contains(models.WebhookEvents, event)
+}
+
+// documentEventData is the data of a document event: the document without
+// its content
+func documentEventData(doc *models.Document) map[string]interface{} {
+	data := map[string]interface{}{
+		"id":            doc.ID,
+		"title":         doc.Title,
+		"content_type":  doc.ContentType,
+		"tags":          doc.Tags,
+		"collection_id": doc.CollectionID,
+		"word_count":    doc.WordCount,
+		"created_at":    doc.CreatedAt,
+		"updated_at":    doc.UpdatedAt,
+	}
+	if doc.CodeLanguage != "" {
+		data["code_language"] = doc.CodeLanguage
+	}
+	return data
+}
+
+// quotaAlertEventData is the data of a quota.threshold_crossed event
+func quotaAlertEventData(alert *models.QuotaAlert) map[string]interface{} {
+	return map[string]interface{}{
+		"id":           alert.ID,
+		"metric":       alert.Metric,
+		"threshold":    alert.Threshold,
+		"used":         alert.Used,
+		"limit":        alert.Limit,
+		"period_start": alert.PeriodStart,
+		"created_at":   alert.CreatedAt,
+	}
+}
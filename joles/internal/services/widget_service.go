@@ -0,0 +1,213 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+
+	"lio-ai/internal/models"
+	"lio-ai/internal/repositories"
+)
+
+var (
+	// ErrWidgetTokenInvalid is returned for unknown or revoked widget tokens
+	ErrWidgetTokenInvalid = errors.New("invalid widget token")
+	// ErrWidgetOrigin is returned when a widget is used from a site its
+	// token does not allow
+	ErrWidgetOrigin = errors.New("origin not allowed for this widget")
+	// ErrInvalidWidgetOrigin is returned when a token is created for an
+	// origin that is not an http(s) origin
+	ErrInvalidWidgetOrigin = errors.New("allowed origins must be http or https origins")
+	// ErrWidgetQuotaExceeded is returned once a widget token has used its
+	// daily allowance
+	ErrWidgetQuotaExceeded = errors.New("widget quota exceeded")
+)
+
+// widgetTokenPrefix marks widget tokens, so they are recognizable in logs
+// and secret scanners
+const widgetTokenPrefix = "lwt_"
+
+// WidgetService issues public tokens for embeddable chat widgets and serves
+// their completions. A token only reaches one persona of its owner, and
+// every visitor chats under their own ID, so visitors never see each
+// other's conversations.
+type WidgetService struct {
+	repo     *repositories.WidgetRepository
+	chats    *ChatService
+	personas *PersonaService
+	// Instance maximums; tokens default to these and can only lower them
+	limits models.WidgetLimits
+}
+
+// NewWidgetService creates a widget service whose tokens stay within limits
+func NewWidgetService(repo *repositories.WidgetRepository, chats *ChatService, personas *PersonaService, limits models.WidgetLimits) *WidgetService {
+	return &WidgetService{repo: repo, chats: chats, personas: personas, limits: limits}
+}
+
+// CreateToken issues a widget token for one of userID's personas. The
+// token is only returned here; just its hash is stored.
+func (s *WidgetService) CreateToken(userID string, req *models.CreateWidgetTokenRequest) (*models.CreatedWidgetToken, error) {
+	if _, err := s.personas.GetPersona(req.PersonaID, userID); err != nil {
+		return nil, err
+	}
+	origins := make([]string, 0, len(req.AllowedOrigins))
+	for _, origin := range req.AllowedOrigins {
+		normalized, err := normalizeOrigin(origin)
+		if err != nil {
+			return nil, err
+		}
+		origins = append(origins, normalized)
+	}
+
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return nil, fmt.Errorf("failed to generate token: %w", err)
+	}
+	secret := widgetTokenPrefix + hex.EncodeToString(b)
+
+	token := &models.WidgetToken{
+		UserID:         userID,
+		Name:           req.Name,
+		TokenPrefix:    secret[:len(widgetTokenPrefix)+8],
+		PersonaID:      req.PersonaID,
+		Model:          req.Model,
+		AllowedOrigins: origins,
+		Limits: models.WidgetLimits{
+			RequestsPerMinute: capLimit(req.RequestsPerMinute, s.limits.RequestsPerMinute),
+			DailyRequests:     capLimit(req.DailyRequests, s.limits.DailyRequests),
+			DailyTokens:       capLimit(req.DailyTokens, s.limits.DailyTokens),
+			MaxTokens:         capLimit(req.MaxTokens, s.limits.MaxTokens),
+			MaxCostUSD:        s.limits.MaxCostUSD,
+		},
+	}
+	if req.MaxCostUSD > 0 && req.MaxCostUSD < s.limits.MaxCostUSD {
+		token.Limits.MaxCostUSD = req.MaxCostUSD
+	}
+	if err := s.repo.Create(token, hashWidgetToken(secret)); err != nil {
+		return nil, err
+	}
+	return &models.CreatedWidgetToken{WidgetToken: token, Token: secret}, nil
+}
+
+// ListTokens returns userID's widget tokens, revoked ones included
+func (s *WidgetService) ListTokens(userID string) ([]models.WidgetToken, error) {
+	return s.repo.ListByUser(userID)
+}
+
+// RevokeToken revokes one of userID's widget tokens; widgets using it stop
+// working immediately
+func (s *WidgetService) RevokeToken(id int64, userID string) error {
+	revoked, err := s.repo.Revoke(id, userID)
+	if err != nil {
+		return err
+	}
+	if !revoked {
+		return fmt.Errorf("%w: widget token %d", ErrNotFound, id)
+	}
+	return nil
+}
+
+// Authenticate resolves the widget token a request presents, checking it
+// is used from one of its allowed origins
+func (s *WidgetService) Authenticate(secret, origin string) (*models.WidgetToken, error) {
+	if !strings.HasPrefix(secret, widgetTokenPrefix) {
+		return nil, ErrWidgetTokenInvalid
+	}
+	token, err := s.repo.GetActiveByHash(hashWidgetToken(secret))
+	if err != nil {
+		return nil, err
+	}
+	if token == nil {
+		return nil, ErrWidgetTokenInvalid
+	}
+
+	normalized, err := normalizeOrigin(origin)
+	if err != nil {
+		return nil, ErrWidgetOrigin
+	}
+	for _, allowed := range token.AllowedOrigins {
+		if allowed == normalized {
+			return token, nil
+		}
+	}
+	return nil, ErrWidgetOrigin
+}
+
+// Complete answers a widget visitor's message with the token's persona,
+// within the token's daily allowance and per-response caps
+func (s *WidgetService) Complete(ctx context.Context, token *models.WidgetToken, sessionID string, req *models.WidgetCompletionRequest) (*models.WidgetCompletionResponse, error) {
+	day := time.Now().UTC().Format("2006-01-02")
+	ok, err := s.repo.Reserve(token.ID, day, token.Limits.DailyRequests, token.Limits.DailyTokens)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrWidgetQuotaExceeded
+	}
+
+	resp, err := s.chats.CreateChatCompletion(ctx, &models.ChatCompletionRequest{
+		ChatID:         req.ChatID,
+		Message:        req.Message,
+		Model:          token.Model,
+		UserID:         WidgetVisitorID(token.ID, sessionID),
+		PersonaID:      token.PersonaID,
+		PersonaOwnerID: token.UserID,
+		MaxTokens:      token.Limits.MaxTokens,
+		MaxCostUSD:     token.Limits.MaxCostUSD,
+	})
+	if err != nil {
+		// Failed completions don't count against the widget
+		if relErr := s.repo.Release(token.ID, day); relErr != nil {
+			log.Printf("Failed to release widget completion: %v", relErr)
+		}
+		return nil, err
+	}
+	if err := s.repo.RecordUse(token.ID, day, resp.Tokens); err != nil {
+		log.Printf("Failed to record widget usage: %v", err)
+	}
+
+	return &models.WidgetCompletionResponse{
+		ChatID:    resp.ChatID,
+		MessageID: resp.MessageID,
+		Content:   resp.Content,
+		Tokens:    resp.Tokens,
+		Truncated: resp.Truncated,
+		SessionID: sessionID,
+	}, nil
+}
+
+// WidgetVisitorID is the user ID a widget visitor's chats and usage are
+// recorded under
+func WidgetVisitorID(tokenID int64, sessionID string) string {
+	return fmt.Sprintf("widget_%d_%s", tokenID, sessionID)
+}
+
+func hashWidgetToken(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// normalizeOrigin reduces an origin or URL to scheme://host[:port] as
+// browsers send it in the Origin header
+func normalizeOrigin(origin string) (string, error) {
+	u, err := url.Parse(strings.TrimSpace(origin))
+	if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+		return "", fmt.Errorf("%w: %q", ErrInvalidWidgetOrigin, origin)
+	}
+	return strings.ToLower(u.Scheme + "://" + u.Host), nil
+}
+
+// capLimit applies a requested limit, defaulting to and capped by max
+func capLimit(requested, max int) int {
+	if requested <= 0 || requested > max {
+		return max
+	}
+	return requested
+}
@@ -0,0 +1,519 @@
+package services
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"lio-ai/internal/models"
+	"lio-ai/internal/repositories"
+	"lio-ai/internal/storage"
+)
+
+// DefaultSnapshotKeep is how many scheduled snapshots are kept when a
+// schedule does not say
+const DefaultSnapshotKeep = 7
+
+var (
+	// ErrWorkspaceSnapshotsDisabled is returned when no storage backend is
+	// configured
+	ErrWorkspaceSnapshotsDisabled = errors.New("workspace snapshots are not enabled")
+	// ErrWorkspaceBusy is returned when a snapshot or restore of the
+	// workspace is already queued or running
+	ErrWorkspaceBusy = errors.New("a workspace snapshot or restore is already in progress")
+	// ErrSnapshotUnusable is returned when a snapshot did not complete or
+	// fails its integrity checks
+	ErrSnapshotUnusable = errors.New("snapshot cannot be restored")
+)
+
+// WorkspaceService snapshots users' chats, documents and settings to the
+// storage backend and restores them. Snapshots and restores run as
+// background jobs, one at a time per user.
+type WorkspaceService struct {
+	repo  *repositories.WorkspaceRepository
+	store storage.Store
+	docs  *DocumentService
+	chats *ChatService
+
+	mu    sync.Mutex
+	locks map[string]*sync.Mutex
+}
+
+// NewWorkspaceService creates a workspace service writing snapshots to
+// store; snapshots are disabled when store is nil
+func NewWorkspaceService(repo *repositories.WorkspaceRepository, store storage.Store, docs *DocumentService, chats *ChatService) *WorkspaceService {
+	return &WorkspaceService{
+		repo:  repo,
+		store: store,
+		docs:  docs,
+		chats: chats,
+		locks: make(map[string]*sync.Mutex),
+	}
+}
+
+// lock serializes workspace jobs of a user
+func (s *WorkspaceService) lock(userID string) func() {
+	s.mu.Lock()
+	l, ok := s.locks[userID]
+	if !ok {
+		l = &sync.Mutex{}
+		s.locks[userID] = l
+	}
+	s.mu.Unlock()
+	l.Lock()
+	return l.Unlock
+}
+
+// CreateSnapshot queues a snapshot of userID's workspace
+func (s *WorkspaceService) CreateSnapshot(userID, trigger string) (*models.WorkspaceSnapshot, error) {
+	if s.store == nil {
+		return nil, ErrWorkspaceSnapshotsDisabled
+	}
+	if err := s.checkIdle(userID); err != nil {
+		return nil, err
+	}
+	snapshot := &models.WorkspaceSnapshot{UserID: userID, Trigger: trigger}
+	if err := s.repo.CreateSnapshot(snapshot); err != nil {
+		return nil, err
+	}
+
+	go func(snapshot models.WorkspaceSnapshot) {
+		defer s.lock(snapshot.UserID)()
+		s.runSnapshot(&snapshot)
+	}(*snapshot)
+	return snapshot, nil
+}
+
+func (s *WorkspaceService) checkIdle(userID string) error {
+	busy, err := s.repo.HasActiveJob(userID)
+	if err != nil {
+		return err
+	}
+	if busy {
+		return ErrWorkspaceBusy
+	}
+	return nil
+}
+
+// runSnapshot writes a snapshot, saving its outcome. The caller holds the
+// user's lock.
+func (s *WorkspaceService) runSnapshot(snapshot *models.WorkspaceSnapshot) error {
+	snapshot.Status = models.WorkspaceJobRunning
+	s.saveSnapshot(snapshot)
+
+	err := s.writeSnapshot(snapshot)
+	now := time.Now()
+	snapshot.CompletedAt = &now
+	if err != nil {
+		snapshot.Status = models.WorkspaceJobFailed
+		snapshot.Error = err.Error()
+		log.Printf("⚠️  Workspace snapshot %d for %s failed: %v", snapshot.ID, snapshot.UserID, err)
+	} else {
+		snapshot.Status = models.WorkspaceJobCompleted
+		log.Printf("📦 Workspace snapshot %d for %s stored (%d bytes)", snapshot.ID, snapshot.UserID, snapshot.SizeBytes)
+	}
+	s.saveSnapshot(snapshot)
+	return err
+}
+
+// writeSnapshot dumps the workspace to a gzipped JSON file and reads the
+// stored copy back to check it before the snapshot counts as completed
+func (s *WorkspaceService) writeSnapshot(snapshot *models.WorkspaceSnapshot) error {
+	tables, err := s.repo.Dump(snapshot.UserID)
+	if err != nil {
+		return err
+	}
+	data := models.WorkspaceSnapshotData{
+		Format:    models.WorkspaceSnapshotFormat,
+		UserID:    snapshot.UserID,
+		CreatedAt: snapshot.CreatedAt,
+		Tables:    tables,
+	}
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if err := json.NewEncoder(zw).Encode(data); err != nil {
+		return fmt.Errorf("failed to encode snapshot: %w", err)
+	}
+	if err := zw.Close(); err != nil {
+		return fmt.Errorf("failed to compress snapshot: %w", err)
+	}
+	sum := sha256.Sum256(buf.Bytes())
+
+	key := fmt.Sprintf("snapshots/%s/%s.json.gz", snapshot.UserID, uuid.New().String())
+	size := int64(buf.Len())
+	if err := s.store.Put(key, bytes.NewReader(buf.Bytes()), size, "application/gzip"); err != nil {
+		return fmt.Errorf("failed to store snapshot: %w", err)
+	}
+	snapshot.Backend = s.store.Name()
+	snapshot.Key = key
+	snapshot.SizeBytes = size
+	snapshot.SHA256 = hex.EncodeToString(sum[:])
+
+	if _, err := s.readSnapshot(snapshot); err != nil {
+		s.deleteObject(key)
+		snapshot.Key = ""
+		return err
+	}
+	snapshot.Counts = make(map[string]int64, len(tables))
+	for table, rows := range tables {
+		snapshot.Counts[table] = int64(len(rows))
+	}
+	return nil
+}
+
+// readSnapshot downloads a snapshot file, checking its size and digest,
+// and decodes it
+func (s *WorkspaceService) readSnapshot(snapshot *models.WorkspaceSnapshot) (*models.WorkspaceSnapshotData, error) {
+	r, err := s.store.Get(snapshot.Key)
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to read snapshot file: %v", ErrSnapshotUnusable, err)
+	}
+	defer r.Close()
+	body, err := io.ReadAll(io.LimitReader(r, snapshot.SizeBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to read snapshot file: %v", ErrSnapshotUnusable, err)
+	}
+	if int64(len(body)) != snapshot.SizeBytes {
+		return nil, fmt.Errorf("%w: snapshot file is %d bytes, expected %d", ErrSnapshotUnusable, len(body), snapshot.SizeBytes)
+	}
+	sum := sha256.Sum256(body)
+	if hex.EncodeToString(sum[:]) != snapshot.SHA256 {
+		return nil, fmt.Errorf("%w: snapshot file checksum mismatch", ErrSnapshotUnusable)
+	}
+
+	zr, err := gzip.NewReader(bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrSnapshotUnusable, err)
+	}
+	dec := json.NewDecoder(zr)
+	dec.UseNumber()
+	var data models.WorkspaceSnapshotData
+	if err := dec.Decode(&data); err != nil {
+		return nil, fmt.Errorf("%w: failed to decode snapshot: %v", ErrSnapshotUnusable, err)
+	}
+	if data.Format != models.WorkspaceSnapshotFormat {
+		return nil, fmt.Errorf("%w: unsupported snapshot format %d", ErrSnapshotUnusable, data.Format)
+	}
+	if data.UserID != snapshot.UserID {
+		return nil, fmt.Errorf("%w: snapshot belongs to another user", ErrSnapshotUnusable)
+	}
+	return &data, nil
+}
+
+func (s *WorkspaceService) saveSnapshot(snapshot *models.WorkspaceSnapshot) {
+	if err := s.repo.UpdateSnapshot(snapshot); err != nil {
+		log.Printf("Failed to save workspace snapshot %d: %v", snapshot.ID, err)
+	}
+}
+
+func (s *WorkspaceService) deleteObject(key string) {
+	if err := s.store.Delete(key); err != nil {
+		log.Printf("Failed to delete snapshot file %s: %v", key, err)
+	}
+}
+
+// ListSnapshots retrieves a user's snapshots, newest first
+func (s *WorkspaceService) ListSnapshots(userID string) ([]models.WorkspaceSnapshot, error) {
+	return s.repo.ListSnapshots(userID, "")
+}
+
+// GetSnapshot retrieves a snapshot owned by userID
+func (s *WorkspaceService) GetSnapshot(id int64, userID string) (*models.WorkspaceSnapshot, error) {
+	snapshot, err := s.repo.GetSnapshot(id)
+	if err != nil {
+		return nil, err
+	}
+	if snapshot == nil || snapshot.UserID != userID {
+		return nil, fmt.Errorf("%w: snapshot %d", ErrNotFound, id)
+	}
+	return snapshot, nil
+}
+
+// DeleteSnapshot deletes a finished snapshot owned by userID and its file
+func (s *WorkspaceService) DeleteSnapshot(id int64, userID string) error {
+	defer s.lock(userID)()
+	snapshot, err := s.GetSnapshot(id, userID)
+	if err != nil {
+		return err
+	}
+	if snapshot.Status == models.WorkspaceJobQueued || snapshot.Status == models.WorkspaceJobRunning {
+		return ErrWorkspaceBusy
+	}
+	return s.deleteSnapshot(snapshot)
+}
+
+func (s *WorkspaceService) deleteSnapshot(snapshot *models.WorkspaceSnapshot) error {
+	if snapshot.Key != "" && s.store != nil {
+		s.deleteObject(snapshot.Key)
+	}
+	return s.repo.DeleteSnapshot(snapshot.ID)
+}
+
+// Restore queues a restore of userID's workspace from the snapshot named
+// in req, or from the latest completed snapshot taken at or before req.At
+func (s *WorkspaceService) Restore(userID string, req *models.RestoreWorkspaceRequest) (*models.WorkspaceRestore, error) {
+	if s.store == nil {
+		return nil, ErrWorkspaceSnapshotsDisabled
+	}
+	var snapshot *models.WorkspaceSnapshot
+	var err error
+	switch {
+	case req.SnapshotID > 0:
+		if snapshot, err = s.GetSnapshot(req.SnapshotID, userID); err != nil {
+			return nil, err
+		}
+	case req.At != nil:
+		if snapshot, err = s.repo.LatestSnapshotAt(userID, *req.At); err != nil {
+			return nil, err
+		}
+		if snapshot == nil {
+			return nil, fmt.Errorf("%w: no snapshot taken at or before %s", ErrNotFound, req.At.Format(time.RFC3339))
+		}
+	default:
+		return nil, fmt.Errorf("%w: snapshot_id or at is required", ErrInvalidMessage)
+	}
+	if snapshot.Status != models.WorkspaceJobCompleted {
+		return nil, fmt.Errorf("%w: snapshot %d is %s", ErrSnapshotUnusable, snapshot.ID, snapshot.Status)
+	}
+	if err := s.checkIdle(userID); err != nil {
+		return nil, err
+	}
+
+	job := &models.WorkspaceRestore{UserID: userID, SnapshotID: snapshot.ID}
+	if err := s.repo.CreateRestore(job); err != nil {
+		return nil, err
+	}
+
+	go s.runRestore(*job)
+	return job, nil
+}
+
+// runRestore takes a safety snapshot of the current workspace, checks the
+// snapshot being restored and replaces the workspace with it
+func (s *WorkspaceService) runRestore(job models.WorkspaceRestore) {
+	defer s.lock(job.UserID)()
+	job.Status = models.WorkspaceJobRunning
+	s.saveRestore(&job)
+
+	err := s.restore(&job)
+	now := time.Now()
+	job.CompletedAt = &now
+	if err != nil {
+		job.Status = models.WorkspaceJobFailed
+		job.Error = err.Error()
+		log.Printf("⚠️  Workspace restore %d for %s failed: %v", job.ID, job.UserID, err)
+	} else {
+		job.Status = models.WorkspaceJobCompleted
+		log.Printf("♻️  Workspace of %s restored from snapshot %d", job.UserID, job.SnapshotID)
+	}
+	s.saveRestore(&job)
+}
+
+func (s *WorkspaceService) restore(job *models.WorkspaceRestore) error {
+	snapshot, err := s.repo.GetSnapshot(job.SnapshotID)
+	if err != nil {
+		return err
+	}
+	if snapshot == nil || snapshot.Status != models.WorkspaceJobCompleted {
+		return fmt.Errorf("%w: snapshot %d is no longer available", ErrSnapshotUnusable, job.SnapshotID)
+	}
+	data, err := s.readSnapshot(snapshot)
+	if err != nil {
+		return err
+	}
+	for table, want := range snapshot.Counts {
+		if got := int64(len(data.Tables[table])); got != want {
+			return fmt.Errorf("%w: snapshot has %d %s rows, expected %d", ErrSnapshotUnusable, got, table, want)
+		}
+	}
+
+	safety := &models.WorkspaceSnapshot{UserID: job.UserID, Trigger: models.SnapshotTriggerPreRestore}
+	if err := s.repo.CreateSnapshot(safety); err != nil {
+		return err
+	}
+	job.SafetySnapshotID = &safety.ID
+	s.saveRestore(job)
+	if err := s.runSnapshot(safety); err != nil {
+		return fmt.Errorf("failed to snapshot the current workspace: %w", err)
+	}
+
+	replaced, err := s.repo.Replace(job.UserID, data.Tables)
+	if err != nil {
+		return err
+	}
+	job.Restored = replaced.Restored
+
+	// Attachments belonged to the replaced messages
+	if s.chats != nil {
+		if _, err := s.chats.PurgeUserAttachments(job.UserID); err != nil {
+			log.Printf("Failed to remove attachments of %s after restore: %v", job.UserID, err)
+		}
+	}
+	if s.docs != nil {
+		s.docs.restoredWorkspace(job.UserID, replaced)
+	}
+	return nil
+}
+
+func (s *WorkspaceService) saveRestore(job *models.WorkspaceRestore) {
+	if err := s.repo.UpdateRestore(job); err != nil {
+		log.Printf("Failed to save workspace restore %d: %v", job.ID, err)
+	}
+}
+
+// GetRestore retrieves a restore job of userID
+func (s *WorkspaceService) GetRestore(id int64, userID string) (*models.WorkspaceRestore, error) {
+	job, err := s.repo.GetRestore(id)
+	if err != nil {
+		return nil, err
+	}
+	if job == nil || job.UserID != userID {
+		return nil, fmt.Errorf("%w: restore %d", ErrNotFound, id)
+	}
+	return job, nil
+}
+
+// FailInterrupted marks snapshots and restores cut short by a restart
+func (s *WorkspaceService) FailInterrupted() error {
+	return s.repo.FailInterrupted()
+}
+
+// GetSchedule retrieves a user's snapshot schedule; users without one have
+// scheduled snapshots off
+func (s *WorkspaceService) GetSchedule(userID string) (*models.SnapshotSchedule, error) {
+	schedule, err := s.repo.GetSchedule(userID)
+	if err != nil {
+		return nil, err
+	}
+	if schedule == nil {
+		schedule = &models.SnapshotSchedule{UserID: userID, Keep: DefaultSnapshotKeep}
+	}
+	return schedule, nil
+}
+
+// SetSchedule replaces a user's snapshot schedule. The first scheduled
+// snapshot is taken one interval from now.
+func (s *WorkspaceService) SetSchedule(userID string, req *models.SetSnapshotScheduleRequest) (*models.SnapshotSchedule, error) {
+	if s.store == nil {
+		return nil, ErrWorkspaceSnapshotsDisabled
+	}
+	schedule := &models.SnapshotSchedule{UserID: userID, IntervalHours: req.IntervalHours, Keep: req.Keep}
+	if schedule.Keep == 0 {
+		schedule.Keep = DefaultSnapshotKeep
+	}
+	if schedule.IntervalHours > 0 {
+		next := time.Now().Add(time.Duration(schedule.IntervalHours) * time.Hour)
+		schedule.NextRunAt = &next
+	}
+	if err := s.repo.SetSchedule(schedule); err != nil {
+		return nil, err
+	}
+	return schedule, nil
+}
+
+// Start takes due scheduled snapshots every interval until the process
+// exits
+func (s *WorkspaceService) Start(interval time.Duration) {
+	if s.store == nil {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			s.runSchedules()
+			<-ticker.C
+		}
+	}()
+}
+
+// runSchedules snapshots each workspace whose schedule is due, then drops
+// its scheduled snapshots beyond the schedule's keep. A workspace busy
+// with another job waits for the next interval.
+func (s *WorkspaceService) runSchedules() {
+	now := time.Now()
+	schedules, err := s.repo.ListDueSchedules(now)
+	if err != nil {
+		log.Printf("Failed to list snapshot schedules: %v", err)
+		return
+	}
+	for i := range schedules {
+		schedule := &schedules[i]
+		next := now.Add(time.Duration(schedule.IntervalHours) * time.Hour)
+		schedule.NextRunAt = &next
+		if err := s.repo.SetSchedule(schedule); err != nil {
+			log.Printf("Failed to save snapshot schedule of %s: %v", schedule.UserID, err)
+			continue
+		}
+		if err := s.checkIdle(schedule.UserID); err != nil {
+			continue
+		}
+		s.runScheduled(schedule)
+	}
+}
+
+func (s *WorkspaceService) runScheduled(schedule *models.SnapshotSchedule) {
+	defer s.lock(schedule.UserID)()
+	snapshot := &models.WorkspaceSnapshot{UserID: schedule.UserID, Trigger: models.SnapshotTriggerScheduled}
+	if err := s.repo.CreateSnapshot(snapshot); err != nil {
+		log.Printf("Failed to create scheduled snapshot of %s: %v", schedule.UserID, err)
+		return
+	}
+	if err := s.runSnapshot(snapshot); err != nil {
+		return
+	}
+
+	scheduled, err := s.repo.ListSnapshots(schedule.UserID, models.SnapshotTriggerScheduled)
+	if err != nil {
+		log.Printf("Failed to list scheduled snapshots of %s: %v", schedule.UserID, err)
+		return
+	}
+	for i := schedule.Keep; i < len(scheduled); i++ {
+		if err := s.deleteSnapshot(&scheduled[i]); err != nil {
+			log.Printf("Failed to prune workspace snapshot %d: %v", scheduled[i].ID, err)
+		}
+	}
+}
+
+// restoredWorkspace brings stored files, storage accounting and the search
+// index in line with a restored workspace
+func (s *DocumentService) restoredWorkspace(userID string, replaced *repositories.WorkspaceReplacement) {
+	for _, key := range replaced.RemovedFileKeys {
+		s.deleteFile(&models.DocumentFile{Key: key})
+	}
+	for _, id := range replaced.RemovedDocumentIDs {
+		if s.storage != nil {
+			if err := s.storage.Release(models.StorageKindDocument, id); err != nil {
+				log.Printf("Failed to release storage for document %d: %v", id, err)
+			}
+		}
+		if s.index != nil {
+			s.index.Remove(uint(id))
+		}
+	}
+	for _, id := range replaced.RestoredDocuments {
+		if s.storage != nil {
+			doc, err := s.repo.GetByID(uint(id))
+			if err != nil || doc == nil {
+				log.Printf("Failed to load restored document %d: %v", id, err)
+				continue
+			}
+			if err := s.storage.Record(userID, models.StorageKindDocument, id, documentSize(doc)); err != nil {
+				log.Printf("Failed to record storage for document %d: %v", id, err)
+			}
+		}
+		if s.index != nil {
+			s.index.Enqueue(uint(id))
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package storage
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// DiskStore keeps objects as files under a base directory
+type DiskStore struct {
+	baseDir string
+}
+
+// NewDiskStore creates a disk store, creating the directory if needed
+func NewDiskStore(baseDir string) (*DiskStore, error) {
+	if err := os.MkdirAll(baseDir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create storage directory '%s': %w", baseDir, err)
+	}
+	return &DiskStore{baseDir: baseDir}, nil
+}
+
+// path resolves a key inside the base directory, rejecting traversal
+func (s *DiskStore) path(key string) (string, error) {
+	clean := filepath.Clean("/" + key)
+	if strings.Contains(key, "..") || clean == "/" {
+		return "", fmt.Errorf("invalid storage key: %s", key)
+	}
+	return filepath.Join(s.baseDir, clean), nil
+}
+
+// Put writes an object to disk
+func (s *DiskStore) Put(key string, r io.Reader, size int64, contentType string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	// Write to a temp file first so readers never see a partial object
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return fmt.Errorf("failed to create file: %w", err)
+	}
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Get opens an object from disk
+func (s *DiskStore) Get(key string) (io.ReadCloser, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(path)
+}
+
+// Delete removes an object from disk
+func (s *DiskStore) Delete(key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// List walks the files under the base directory, left-over temp files of
+// interrupted writes included
+func (s *DiskStore) List(fn func(key string, size int64, modified time.Time) error) error {
+	return filepath.WalkDir(s.baseDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		key, err := filepath.Rel(s.baseDir, path)
+		if err != nil {
+			return err
+		}
+		return fn(filepath.ToSlash(key), info.Size(), info.ModTime())
+	})
+}
+
+// Name identifies the backend
+func (s *DiskStore) Name() string {
+	return "disk"
+}
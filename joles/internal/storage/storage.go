@@ -0,0 +1,46 @@
+package storage
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"lio-ai/internal/config"
+)
+
+// Store persists binary objects such as chat attachments
+type Store interface {
+	// Put writes an object under key
+	Put(key string, r io.Reader, size int64, contentType string) error
+	// Get opens an object for reading; the caller must close it
+	Get(key string) (io.ReadCloser, error)
+	// Delete removes an object; missing objects are not an error
+	Delete(key string) error
+	// Name identifies the backend ("disk" or "s3")
+	Name() string
+}
+
+// Lister is implemented by stores that can enumerate their objects, which
+// finding orphaned objects needs
+type Lister interface {
+	// List calls fn with the key, size and modification time of each object
+	List(fn func(key string, size int64, modified time.Time) error) error
+}
+
+// NewStoreFromConfig builds the store cfg selects ("disk" or "s3")
+func NewStoreFromConfig(cfg config.AttachmentStoreConfig) (Store, error) {
+	switch cfg.Backend {
+	case "disk":
+		return NewDiskStore(cfg.Dir)
+	case "s3":
+		return NewS3Store(S3Config{
+			Bucket:    cfg.S3Bucket,
+			Region:    cfg.S3Region,
+			Endpoint:  cfg.S3Endpoint,
+			AccessKey: cfg.S3AccessKey,
+			SecretKey: cfg.S3SecretKey,
+		})
+	default:
+		return nil, fmt.Errorf("unknown ATTACHMENT_STORAGE backend: %s", cfg.Backend)
+	}
+}
@@ -0,0 +1,56 @@
+package storage
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"lio-ai/internal/config"
+)
+
+func TestNewStoreFromConfigDisk(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "attachments")
+	store, err := NewStoreFromConfig(config.AttachmentStoreConfig{Backend: "disk", Dir: dir})
+	if err != nil {
+		t.Fatalf("NewStoreFromConfig failed: %v", err)
+	}
+	if store.Name() != "disk" {
+		t.Fatalf("store is %q, want disk", store.Name())
+	}
+
+	if err := store.Put("chats/1/a.txt", strings.NewReader("hello"), 5, "text/plain"); err != nil {
+		t.Fatal(err)
+	}
+	r, err := store.Get("chats/1/a.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if data, _ := io.ReadAll(r); string(data) != "hello" {
+		t.Errorf("read %q back, want hello", data)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "chats", "1", "a.txt")); err != nil {
+		t.Errorf("object not kept in the configured directory: %v", err)
+	}
+}
+
+func TestNewStoreFromConfigErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  config.AttachmentStoreConfig
+		want string
+	}{
+		{"unknown backend", config.AttachmentStoreConfig{Backend: "ftp"}, "unknown ATTACHMENT_STORAGE backend: ftp"},
+		{"s3 without bucket", config.AttachmentStoreConfig{Backend: "s3", S3Region: "us-east-1"}, "S3_BUCKET is required"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewStoreFromConfig(tt.cfg)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("err = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
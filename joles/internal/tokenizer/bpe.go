@@ -0,0 +1,44 @@
+package tokenizer
+
+import (
+	"log"
+	"sync"
+
+	"github.com/pkoukk/tiktoken-go"
+	tiktoken_loader "github.com/pkoukk/tiktoken-go-loader"
+)
+
+func init() {
+	// Read the vocabularies bundled into the binary rather than
+	// downloading them on first use
+	tiktoken.SetBpeLoader(tiktoken_loader.NewOfflineLoader())
+}
+
+// encoders holds the byte pair encoder of each encoding, built on first use
+var encoders = map[string]*lazyEncoder{
+	EncodingCL100k: {},
+	EncodingO200k:  {},
+}
+
+type lazyEncoder struct {
+	once sync.Once
+	enc  *tiktoken.Tiktoken
+}
+
+// encoder returns the byte pair encoder of encoding, or nil when there is
+// none or it failed to load
+func encoder(encoding string) *tiktoken.Tiktoken {
+	lazy, ok := encoders[encoding]
+	if !ok {
+		return nil
+	}
+	lazy.once.Do(func() {
+		enc, err := tiktoken.GetEncoding(encoding)
+		if err != nil {
+			log.Printf("⚠️  Failed to load the %s encoder, estimating token counts instead: %v", encoding, err)
+			return
+		}
+		lazy.enc = enc
+	})
+	return lazy.enc
+}
@@ -0,0 +1,265 @@
+// Package tokenizer counts tokens the way OpenAI's tiktoken encodings
+// produce them. Models with a known encoding are counted exactly with its
+// byte pair encoder. Other models are estimated: text is pre-tokenized into
+// the same pieces as cl100k_base and the byte pair merges within each piece
+// are guessed, which is within about 10% of tiktoken for prose and further
+// off for rare words and code.
+package tokenizer
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Encodings, named as in tiktoken
+const (
+	EncodingO200k  = "o200k_base"
+	EncodingCL100k = "cl100k_base"
+)
+
+// Chat framing, as counted by OpenAI for gpt-3.5-turbo and later: each
+// message is wrapped in a few tokens, a name costs one more, and the reply
+// is primed with three
+const (
+	tokensPerMessage = 3
+	tokensPerName    = 1
+	tokensPerReply   = 3
+)
+
+// Message is a chat message to count
+type Message struct {
+	Role    string `json:"role"`
+	Name    string `json:"name,omitempty"`
+	Content string `json:"content"`
+}
+
+// encodingPrefixes maps the name prefixes of models whose encoding is
+// known to it. Longer prefixes come first, so "gpt-4o" wins over "gpt-4".
+var encodingPrefixes = []struct {
+	prefix   string
+	encoding string
+}{
+	{"gpt-4o", EncodingO200k},
+	{"gpt-4.1", EncodingO200k},
+	{"gpt-4.5", EncodingO200k},
+	{"o1", EncodingO200k},
+	{"o3", EncodingO200k},
+	{"o4", EncodingO200k},
+	{"gpt-4", EncodingCL100k},
+	{"gpt-3.5", EncodingCL100k},
+	{"text-embedding-3", EncodingCL100k},
+	{"text-embedding-ada-002", EncodingCL100k},
+}
+
+// knownEncoding returns model's encoding, or "" when it isn't known
+func knownEncoding(model string) string {
+	name := strings.ToLower(model)
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		name = name[i+1:]
+	}
+	for _, e := range encodingPrefixes {
+		if strings.HasPrefix(name, e.prefix) {
+			return e.encoding
+		}
+	}
+	return ""
+}
+
+// EncodingForModel returns the encoding used to count tokens for model.
+// Models of other providers are estimated as cl100k_base, which is close
+// for the English-heavy text most prompts are.
+func EncodingForModel(model string) string {
+	if encoding := knownEncoding(model); encoding != "" {
+		return encoding
+	}
+	return EncodingCL100k
+}
+
+// Exact reports whether counts for model come from its byte pair encoder
+// rather than an estimate
+func Exact(model string) bool {
+	return encoder(knownEncoding(model)) != nil
+}
+
+// Count returns the number of tokens text encodes to for model, estimated
+// when model's encoding isn't known
+func Count(model, text string) int {
+	if enc := encoder(knownEncoding(model)); enc != nil {
+		return len(enc.EncodeOrdinary(text))
+	}
+	return estimate(text, EncodingForModel(model) == EncodingO200k)
+}
+
+// estimate guesses the tokens of text from its pre-tokenizer pieces
+func estimate(text string, o200k bool) int {
+	tokens := 0
+	for text != "" {
+		piece, kind := nextPiece(text)
+		tokens += pieceTokens(piece, kind, o200k)
+		text = text[len(piece):]
+	}
+	return tokens
+}
+
+// CountMessages counts the prompt tokens of a chat request for model,
+// including the per-message framing
+func CountMessages(model string, messages []Message) int {
+	tokens := tokensPerReply
+	for _, m := range messages {
+		tokens += CountMessage(model, m)
+	}
+	return tokens
+}
+
+// CountMessage counts the tokens one message adds to a chat request
+func CountMessage(model string, m Message) int {
+	tokens := tokensPerMessage + Count(model, m.Role) + Count(model, m.Content)
+	if m.Name != "" {
+		tokens += tokensPerName + Count(model, m.Name)
+	}
+	return tokens
+}
+
+// Kinds of pre-tokenizer pieces
+const (
+	pieceContraction = iota
+	pieceWord
+	pieceNumber
+	piecePunct
+	pieceSpace
+)
+
+// nextPiece returns the first piece of text following the cl100k_base
+// pre-tokenizer pattern:
+//
+//	'(?i:[sdmt]|ll|ve|re)|[^\r\n\p{L}\p{N}]?\p{L}+|\p{N}{1,3}|
+//	 ?[^\s\p{L}\p{N}]+[\r\n]*|\s*[\r\n]+|\s+(?!\S)|\s+
+func nextPiece(text string) (string, int) {
+	r, size := utf8.DecodeRuneInString(text)
+
+	// Contractions
+	if r == '\'' {
+		lower := strings.ToLower(text[size:min(len(text), size+2)])
+		if strings.HasPrefix(lower, "ll") || strings.HasPrefix(lower, "ve") || strings.HasPrefix(lower, "re") {
+			return text[:size+2], pieceContraction
+		}
+		if lower != "" && strings.ContainsRune("sdmt", rune(lower[0])) {
+			return text[:size+1], pieceContraction
+		}
+	}
+
+	// A word, optionally led by one character that is not a letter,
+	// digit or newline (usually the space before it)
+	start := 0
+	if !unicode.IsLetter(r) && !unicode.IsNumber(r) && r != '\r' && r != '\n' {
+		start = size
+	}
+	if end := scan(text, start, unicode.IsLetter); end > start {
+		return text[:end], pieceWord
+	}
+
+	// Up to three digits
+	if unicode.IsNumber(r) {
+		end := size
+		for n := 1; n < 3 && end < len(text); n++ {
+			next, nextSize := utf8.DecodeRuneInString(text[end:])
+			if !unicode.IsNumber(next) {
+				break
+			}
+			end += nextSize
+		}
+		return text[:end], pieceNumber
+	}
+
+	// Punctuation and symbols, optionally led by a space and followed by
+	// newlines
+	start = 0
+	if r == ' ' {
+		start = size
+	}
+	if end := scan(text, start, isPunct); end > start {
+		return text[:scan(text, end, isNewline)], piecePunct
+	}
+
+	// Whitespace up to and including the last newline in the run
+	end := scan(text, 0, unicode.IsSpace)
+	if last := strings.LastIndexAny(text[:end], "\r\n"); last >= 0 {
+		return text[:last+1], pieceSpace
+	}
+	// Otherwise whitespace, leaving the last space to lead the next word
+	if end < len(text) && end > size {
+		_, lastSize := utf8.DecodeLastRuneInString(text[:end])
+		end -= lastSize
+	}
+	return text[:end], pieceSpace
+}
+
+// scan returns the offset after the run of runes matching fn from start
+func scan(text string, start int, fn func(rune) bool) int {
+	end := start
+	for end < len(text) {
+		r, size := utf8.DecodeRuneInString(text[end:])
+		if !fn(r) {
+			break
+		}
+		end += size
+	}
+	return end
+}
+
+func isPunct(r rune) bool {
+	return !unicode.IsSpace(r) && !unicode.IsLetter(r) && !unicode.IsNumber(r)
+}
+
+func isNewline(r rune) bool {
+	return r == '\r' || r == '\n'
+}
+
+// pieceTokens estimates how many tokens byte pair merging leaves of a piece
+func pieceTokens(piece string, kind int, o200k bool) int {
+	switch kind {
+	case pieceContraction, pieceNumber, pieceSpace:
+		return 1
+	case piecePunct:
+		// Common runs ("...", "**", "==") are single tokens; longer ones break up
+		return (utf8.RuneCountInString(strings.TrimLeft(piece, " \r\n")) + 2) / 3
+	}
+
+	// Words: ASCII words up to about eight letters are in the vocabulary,
+	// longer ones split into a few subwords, and very long ones, rarely
+	// seen whole, into about one per four letters. Ideographic and syllabic
+	// scripts take about a token per character, fewer with o200k_base's
+	// larger vocabulary; other scripts pair up.
+	ascii, ideographic, other := 0, 0, 0
+	for _, r := range piece {
+		switch {
+		case r < utf8.RuneSelf:
+			if unicode.IsLetter(r) {
+				ascii++
+			}
+		case unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul, unicode.Thai):
+			ideographic++
+		default:
+			if unicode.IsLetter(r) {
+				other++
+			}
+		}
+	}
+
+	tokens := 0
+	if ascii > 0 {
+		tokens += max(1+(ascii-1)/8, (ascii-3)/4)
+	}
+	if o200k {
+		tokens += (ideographic*2 + 2) / 3
+		tokens += (other + 2) / 3
+	} else {
+		tokens += ideographic
+		tokens += (other + 1) / 2
+	}
+	if tokens == 0 {
+		tokens = 1
+	}
+	return tokens
+}
@@ -0,0 +1,136 @@
+package tokenizer
+
+import (
+	"reflect"
+	"testing"
+)
+
+// withinTolerance reports whether an estimate is within 10% of tiktoken's
+// count, or 2 tokens for short texts
+func withinTolerance(got, want int) bool {
+	diff := got - want
+	if diff < 0 {
+		diff = -diff
+	}
+	return diff <= max(2, want/10)
+}
+
+// Counts by tiktoken, as published in OpenAI's cookbook "How to count
+// tokens with tiktoken"
+var tiktokenCounts = []struct {
+	model string
+	text  string
+	want  int
+}{
+	{"gpt-4", "hello world", 2},
+	{"gpt-4o", "hello world", 2},
+	{"gpt-4", "tiktoken is great!", 6},
+	{"gpt-4", "antidisestablishmentarianism", 6},
+	{"gpt-4", "2 + 2 = 4", 7},
+	{"gpt-4", "お誕生日おめでとう", 9},
+}
+
+func TestCountMatchesTiktoken(t *testing.T) {
+	for _, tt := range tiktokenCounts {
+		t.Run(tt.model+" "+tt.text, func(t *testing.T) {
+			if got := Count(tt.model, tt.text); got != tt.want {
+				t.Errorf("Count = %d, tiktoken counts %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEstimateIsCloseToTiktoken(t *testing.T) {
+	for _, tt := range tiktokenCounts {
+		t.Run(tt.model+" "+tt.text, func(t *testing.T) {
+			if got := estimate(tt.text, EncodingForModel(tt.model) == EncodingO200k); !withinTolerance(got, tt.want) {
+				t.Errorf("estimate = %d, tiktoken counts %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnknownModelsAreEstimated(t *testing.T) {
+	if Exact("claude-3-5-sonnet") {
+		t.Error("claude-3-5-sonnet is counted exactly, want an estimate")
+	}
+	if !Exact("gpt-4o-mini") || !Exact("openai/gpt-3.5-turbo") {
+		t.Error("OpenAI models are estimated, want exact counts")
+	}
+	text := "tiktoken is great!"
+	if got, want := Count("claude-3-5-sonnet", text), estimate(text, false); got != want {
+		t.Errorf("Count for an unknown model = %d, want the estimate %d", got, want)
+	}
+}
+
+// The cookbook's example chat, counted by the API as 129 prompt tokens for
+// gpt-4 and gpt-3.5-turbo, and 124 for gpt-4o
+func TestCountMessagesMatchesTiktoken(t *testing.T) {
+	messages := []Message{
+		{Role: "system", Content: "You are a helpful, pattern-following assistant that translates corporate jargon into plain English."},
+		{Role: "system", Name: "example_user", Content: "New synergies will help drive top-line growth."},
+		{Role: "system", Name: "example_assistant", Content: "Things working well together will increase revenue."},
+		{Role: "system", Name: "example_user", Content: "Let's circle back when we have more bandwidth to touch base on opportunities for increased leverage."},
+		{Role: "system", Name: "example_assistant", Content: "Let's talk later when we're less busy about how to do better."},
+		{Role: "user", Content: "This late pivot means we don't have time to boil the ocean for the client deliverable."},
+	}
+	tests := []struct {
+		model string
+		want  int
+	}{
+		{"gpt-4", 129},
+		{"gpt-3.5-turbo", 129},
+		{"gpt-4o", 124},
+	}
+	for _, tt := range tests {
+		if got := CountMessages(tt.model, messages); got != tt.want {
+			t.Errorf("CountMessages(%s) = %d, tiktoken counts %d", tt.model, got, tt.want)
+		}
+	}
+}
+
+func TestPreTokenizerPieces(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"Hello, world!", []string{"Hello", ",", " world", "!"}},
+		{"2 + 2 = 4", []string{"2", " +", " ", "2", " =", " ", "4"}},
+		{"12345", []string{"123", "45"}},
+		{"I'm sure they'll", []string{"I", "'m", " sure", " they", "'ll"}},
+		{"a\n\nb", []string{"a", "\n\n", "b"}},
+		{"x   y", []string{"x", "  ", " y"}},
+		{"end.\n", []string{"end", ".\n"}},
+		{"お誕生日", []string{"お誕生日"}},
+	}
+	for _, tt := range tests {
+		var got []string
+		for text := tt.text; text != ""; {
+			piece, _ := nextPiece(text)
+			got = append(got, piece)
+			text = text[len(piece):]
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("pieces of %q = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestEncodingForModel(t *testing.T) {
+	tests := []struct {
+		model string
+		want  string
+	}{
+		{"gpt-4o-mini", EncodingO200k},
+		{"openai/gpt-4.1", EncodingO200k},
+		{"o3-mini", EncodingO200k},
+		{"gpt-4-turbo", EncodingCL100k},
+		{"gpt-3.5-turbo", EncodingCL100k},
+		{"claude-3-5-sonnet", EncodingCL100k},
+	}
+	for _, tt := range tests {
+		if got := EncodingForModel(tt.model); got != tt.want {
+			t.Errorf("EncodingForModel(%s) = %s, want %s", tt.model, got, tt.want)
+		}
+	}
+}
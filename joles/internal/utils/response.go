@@ -1,15 +1,51 @@
 package utils
 
 import (
+	"fmt"
 	"net/http"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 	"lio-ai/internal/models"
 )
 
+// respond sends resp in the response schema version the client asked for
+func respond(c *gin.Context, statusCode int, resp models.APIResponse) {
+	version := ResponseVersion(c)
+	c.Header("Vary", "Accept")
+	if vendorVersionRequested(c) {
+		c.Header("Content-Type", fmt.Sprintf("application/vnd.lio.v%d+json; charset=utf-8", version))
+	}
+
+	if version == ResponseV1 {
+		c.JSON(statusCode, resp)
+		return
+	}
+
+	if resp.Error != nil {
+		body := gin.H{"error": resp.Error.Message, "code": resp.Error.Code}
+		if resp.Error.Details != "" {
+			body["details"] = resp.Error.Details
+		}
+		c.JSON(statusCode, body)
+		return
+	}
+	if resp.Meta != nil {
+		for header, value := range map[string]int{
+			"X-Total-Count": resp.Meta.TotalCount,
+			"X-Page":        resp.Meta.Page,
+			"X-Page-Size":   resp.Meta.PageSize,
+			"X-Total-Pages": resp.Meta.TotalPages,
+		} {
+			c.Header(header, strconv.Itoa(value))
+		}
+	}
+	c.JSON(statusCode, resp.Data)
+}
+
 // SuccessResponse sends a successful API response
 func SuccessResponse(c *gin.Context, data interface{}) {
-	c.JSON(http.StatusOK, models.APIResponse{
+	respond(c, http.StatusOK, models.APIResponse{
 		Success: true,
 		Data:    data,
 	})
@@ -17,7 +53,7 @@ func SuccessResponse(c *gin.Context, data interface{}) {
 
 // SuccessResponseWithMeta sends a successful API response with metadata
 func SuccessResponseWithMeta(c *gin.Context, data interface{}, meta *models.Meta) {
-	c.JSON(http.StatusOK, models.APIResponse{
+	respond(c, http.StatusOK, models.APIResponse{
 		Success: true,
 		Data:    data,
 		Meta:    meta,
@@ -26,7 +62,7 @@ func SuccessResponseWithMeta(c *gin.Context, data interface{}, meta *models.Meta
 
 // CreatedResponse sends a 201 Created response
 func CreatedResponse(c *gin.Context, data interface{}) {
-	c.JSON(http.StatusCreated, models.APIResponse{
+	respond(c, http.StatusCreated, models.APIResponse{
 		Success: true,
 		Data:    data,
 	})
@@ -34,7 +70,7 @@ func CreatedResponse(c *gin.Context, data interface{}) {
 
 // ErrorResponse sends an error API response
 func ErrorResponse(c *gin.Context, statusCode int, code, message string) {
-	c.JSON(statusCode, models.APIResponse{
+	respond(c, statusCode, models.APIResponse{
 		Success: false,
 		Error: &models.APIError{
 			Code:    code,
@@ -45,7 +81,7 @@ func ErrorResponse(c *gin.Context, statusCode int, code, message string) {
 
 // ErrorResponseWithDetails sends an error API response with details
 func ErrorResponseWithDetails(c *gin.Context, statusCode int, code, message, details string) {
-	c.JSON(statusCode, models.APIResponse{
+	respond(c, statusCode, models.APIResponse{
 		Success: false,
 		Error: &models.APIError{
 			Code:    code,
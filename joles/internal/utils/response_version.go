@@ -0,0 +1,94 @@
+package utils
+
+import (
+	"mime"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Response schema versions clients can ask for with
+// Accept: application/vnd.lio.v<N>+json
+const (
+	// ResponseV1 wraps payloads in {"success", "data", "meta", "error"}
+	ResponseV1 = 1
+	// ResponseV2 sends payloads bare, pagination in X-Total-Count,
+	// X-Page, X-Page-Size and X-Total-Pages, and errors as
+	// {"error", "code", "details"} like the rest of the API
+	ResponseV2 = 2
+
+	LatestResponseVersion = ResponseV2
+)
+
+// responseVersionKey caches the negotiated version in the gin context
+const responseVersionKey = "response_version"
+
+var vendorMediaType = regexp.MustCompile(`^application/vnd\.lio\.v([0-9]+)\+json$`)
+
+// NegotiateResponseVersion picks the response schema version from an
+// Accept header: the supported vendor version with the highest quality,
+// or v1 when none is named. ok is false when vendor versions are named
+// but none is supported and nothing else is acceptable.
+func NegotiateResponseVersion(accept string) (version int, ok bool) {
+	best, bestQ := 0, 0.0
+	named, other := false, false
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(v, 64); err != nil {
+				continue
+			}
+		}
+		if q <= 0 {
+			continue
+		}
+
+		m := vendorMediaType.FindStringSubmatch(mediaType)
+		if m == nil {
+			other = true
+			continue
+		}
+		named = true
+		n, err := strconv.Atoi(m[1])
+		if err != nil || n < ResponseV1 || n > LatestResponseVersion {
+			continue
+		}
+		if q > bestQ {
+			best, bestQ = n, q
+		}
+	}
+
+	switch {
+	case best != 0:
+		return best, true
+	case named && !other:
+		return 0, false
+	default:
+		return ResponseV1, true
+	}
+}
+
+// ResponseVersion is the response schema version the request asked for
+func ResponseVersion(c *gin.Context) int {
+	if v := c.GetInt(responseVersionKey); v != 0 {
+		return v
+	}
+	v, ok := NegotiateResponseVersion(c.GetHeader("Accept"))
+	if !ok {
+		v = ResponseV1
+	}
+	c.Set(responseVersionKey, v)
+	return v
+}
+
+// vendorVersionRequested reports whether the request named a vendor media
+// type, so the response should carry one
+func vendorVersionRequested(c *gin.Context) bool {
+	return strings.Contains(c.GetHeader("Accept"), "application/vnd.lio.")
+}
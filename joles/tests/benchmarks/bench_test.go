@@ -0,0 +1,187 @@
+package benchmarks
+
+import (
+	"database/sql"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"lio-ai/internal/config"
+	"lio-ai/internal/db"
+	"lio-ai/internal/handlers"
+	"lio-ai/internal/models"
+	"lio-ai/internal/repositories"
+	"lio-ai/internal/services"
+)
+
+// benchDriver names the driver the benchmarks run against, from
+// BENCH_DB_DRIVER (default "sqlite3")
+func benchDriver() string {
+	if driver := os.Getenv("BENCH_DB_DRIVER"); driver != "" {
+		return driver
+	}
+	return "sqlite3"
+}
+
+// openBenchDB opens a migrated database for the driver named by
+// BENCH_DB_DRIVER (default "sqlite3").
+func openBenchDB(b *testing.B) *sql.DB {
+	b.Helper()
+
+	switch driver := benchDriver(); driver {
+	case "sqlite3":
+		cfg := &config.Config{}
+		cfg.Database.DSN = filepath.Join(b.TempDir(), "bench.db")
+		cfg.Resilience.DBConnectRetries = 1
+		database, err := db.NewDatabase(cfg)
+		if err != nil {
+			b.Fatalf("failed to open database: %v", err)
+		}
+		b.Cleanup(func() { database.Close() })
+		return database.GetConnection()
+	case "postgres":
+		return openPostgresBenchDB(b)
+	default:
+		b.Fatalf("driver %q is not supported, use sqlite3 or postgres", driver)
+		return nil
+	}
+}
+
+func init() {
+	gin.SetMode(gin.TestMode)
+	// Repository logging would interleave with benchmark output
+	log.SetOutput(io.Discard)
+}
+
+func BenchmarkCreateMessage(b *testing.B) {
+	conn := openBenchDB(b)
+	repo := repositories.NewChatRepository(conn)
+
+	chat := &models.Chat{UserID: "bench", Title: "bench", ContextStrategy: models.ContextStrategyTruncate}
+	if err := repo.CreateChat(chat); err != nil {
+		b.Fatal(err)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		msg := &models.Message{ChatID: chat.ID, Role: "user", Content: "benchmark message"}
+		if err := repo.CreateMessage(msg); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkTrackUsage(b *testing.B) {
+	conn := openBenchDB(b)
+	usageService := services.NewUsageService(repositories.NewUsageRepository(conn))
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		err := usageService.TrackUsage(&models.UsageRequest{
+			UserID:       "bench",
+			RequestType:  "chat",
+			ModelUsed:    "gpt-4",
+			Endpoint:     "/api/v1/chat/completions",
+			TokensInput:  100,
+			TokensOutput: 50,
+			DurationMs:   120,
+			Success:      true,
+		})
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkProxyRoundtrip(b *testing.B) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"models":[]}`))
+	}))
+	defer backend.Close()
+
+	proxy := handlers.NewProxyHandler(backend.URL, nil)
+	router := gin.New()
+	router.NoRoute(proxy.ProxyRequest)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/models", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			b.Fatalf("unexpected status %d", w.Code)
+		}
+	}
+}
+
+// BenchmarkSemanticSearch runs GET /api/v1/search/semantic against 500
+// indexed chunks, with the AI service stubbed to return a fixed vector.
+func BenchmarkSemanticSearch(b *testing.B) {
+	if benchDriver() == "postgres" {
+		b.Skip("hybrid search matches chunks with SQLite FTS4")
+	}
+	embeddings := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"data":[{"index":0,"embedding":[0.1,0.2,0.3,0.4,0.5,0.6,0.7,0.8]}]}`))
+	}))
+	defer embeddings.Close()
+
+	const model = "text-embedding-3-small"
+	conn := openBenchDB(b)
+	docRepo := repositories.NewDocumentRepository(conn)
+	chunkRepo := repositories.NewDocumentChunkRepository(conn)
+	for i := 0; i < 50; i++ {
+		doc := &models.Document{UserID: "bench", Title: fmt.Sprintf("notes %d", i), Content: "notes"}
+		if err := docRepo.Create(doc); err != nil {
+			b.Fatal(err)
+		}
+		chunks := make([]models.DocumentChunk, 10)
+		for seq := range chunks {
+			embedding := make([]float32, 8)
+			for d := range embedding {
+				embedding[d] = float32((i+seq+d)%7) / 7
+			}
+			chunks[seq] = models.DocumentChunk{
+				UserID:    "bench",
+				Seq:       seq,
+				Content:   fmt.Sprintf("passage %d about topic %d", seq, i),
+				Embedding: embedding,
+				Model:     model,
+			}
+		}
+		if err := chunkRepo.Replace(doc.ID, chunks); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	usageService := services.NewUsageService(repositories.NewUsageRepository(conn))
+	chatService := services.NewChatService(repositories.NewChatRepository(conn), usageService)
+	chatService.SetAIServiceURL(embeddings.URL)
+	search := handlers.NewSemanticSearchHandler(services.NewSemanticSearchService(chunkRepo, docRepo, chatService, model))
+	router := gin.New()
+	router.GET("/api/v1/search/semantic", func(c *gin.Context) {
+		c.Set("user_id", "bench")
+		c.Next()
+	}, search.Search)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/search/semantic?q=topic&hybrid=true", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			b.Fatalf("unexpected status %d: %s", w.Code, w.Body.String())
+		}
+	}
+}
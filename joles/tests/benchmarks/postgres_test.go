@@ -0,0 +1,127 @@
+package benchmarks
+
+import (
+	"database/sql"
+	"os"
+	"testing"
+
+	"lio-ai/internal/db"
+)
+
+// postgresBenchSchema holds the tables the benchmarks write, in the
+// Postgres types OpenPostgres binds: SERIAL keys and INTEGER flags
+const postgresBenchSchema = `
+	DROP TABLE IF EXISTS messages, chats, usage_metrics, user_quotas, cost_config CASCADE;
+
+	CREATE TABLE chats (
+		id BIGSERIAL PRIMARY KEY,
+		user_id VARCHAR(255) NOT NULL,
+		title VARCHAR(255) NOT NULL,
+		chat_uuid VARCHAR(255) UNIQUE,
+		context_strategy VARCHAR(20) DEFAULT 'truncate',
+		is_pinned INTEGER DEFAULT 0,
+		max_output_tokens INTEGER DEFAULT 0,
+		max_cost_usd DOUBLE PRECISION DEFAULT 0,
+		budget_usd DOUBLE PRECISION DEFAULT 0,
+		budget_action VARCHAR(10) DEFAULT 'block',
+		persona_id BIGINT,
+		summary TEXT,
+		summary_model VARCHAR(100),
+		summary_at TIMESTAMP,
+		summary_action_items TEXT,
+		summary_stale INTEGER DEFAULT 1,
+		context_summary TEXT,
+		context_summary_seq BIGINT,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+	CREATE INDEX idx_chats_user_id ON chats(user_id);
+
+	CREATE TABLE messages (
+		id BIGSERIAL PRIMARY KEY,
+		chat_id BIGINT NOT NULL REFERENCES chats(id) ON DELETE CASCADE,
+		seq BIGINT,
+		role VARCHAR(50) NOT NULL,
+		content TEXT NOT NULL,
+		model VARCHAR(100),
+		tokens INTEGER DEFAULT 0,
+		stopped INTEGER DEFAULT 0,
+		tool_calls TEXT,
+		tool_call_id VARCHAR(255),
+		bookmarked INTEGER DEFAULT 0,
+		truncated INTEGER DEFAULT 0,
+		moderation TEXT,
+		context_chunks TEXT,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+	CREATE UNIQUE INDEX idx_messages_chat_seq ON messages(chat_id, seq);
+
+	CREATE TABLE usage_metrics (
+		id BIGSERIAL PRIMARY KEY,
+		user_id VARCHAR(255) NOT NULL,
+		request_type VARCHAR(50) NOT NULL,
+		resource_id BIGINT,
+		tokens_input INTEGER DEFAULT 0,
+		tokens_output INTEGER DEFAULT 0,
+		tokens_total INTEGER DEFAULT 0,
+		model_used VARCHAR(100),
+		cost_usd DOUBLE PRECISION DEFAULT 0.0,
+		duration_ms INTEGER DEFAULT 0,
+		endpoint VARCHAR(255),
+		provider VARCHAR(50),
+		key_source VARCHAR(20) DEFAULT 'user',
+		success INTEGER DEFAULT 1,
+		error_message TEXT,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+	CREATE INDEX idx_usage_metrics_user_created ON usage_metrics(user_id, created_at);
+
+	CREATE TABLE user_quotas (
+		id BIGSERIAL PRIMARY KEY,
+		user_id VARCHAR(255) NOT NULL UNIQUE,
+		daily_token_limit INTEGER DEFAULT 100000,
+		monthly_token_limit INTEGER DEFAULT 3000000,
+		daily_tokens_used INTEGER DEFAULT 0,
+		monthly_tokens_used INTEGER DEFAULT 0,
+		daily_cost_limit_usd DOUBLE PRECISION DEFAULT 10.0,
+		monthly_cost_limit_usd DOUBLE PRECISION DEFAULT 300.0,
+		daily_cost_used_usd DOUBLE PRECISION DEFAULT 0.0,
+		monthly_cost_used_usd DOUBLE PRECISION DEFAULT 0.0,
+		last_reset_daily TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		last_reset_monthly TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE TABLE cost_config (
+		id BIGSERIAL PRIMARY KEY,
+		model_name VARCHAR(100) NOT NULL UNIQUE,
+		cost_per_input_token DOUBLE PRECISION NOT NULL,
+		cost_per_output_token DOUBLE PRECISION NOT NULL,
+		operation_type VARCHAR(50) NOT NULL,
+		is_active INTEGER DEFAULT 1,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+	INSERT INTO cost_config (model_name, cost_per_input_token, cost_per_output_token, operation_type)
+	VALUES ('gpt-4', 0.00003, 0.00006, 'chat'), ('default', 0.000001, 0.000002, 'chat');
+`
+
+// openPostgresBenchDB recreates the benchmark tables in the scratch
+// database named by BENCH_POSTGRES_DSN
+func openPostgresBenchDB(b *testing.B) *sql.DB {
+	b.Helper()
+	dsn := os.Getenv("BENCH_POSTGRES_DSN")
+	if dsn == "" {
+		b.Fatal("BENCH_POSTGRES_DSN must name a scratch database for the postgres benchmarks")
+	}
+	conn, err := db.OpenPostgres(dsn)
+	if err != nil {
+		b.Fatalf("failed to open database: %v", err)
+	}
+	b.Cleanup(func() { conn.Close() })
+	if _, err := conn.Exec(postgresBenchSchema); err != nil {
+		b.Fatalf("failed to create the benchmark schema: %v", err)
+	}
+	return conn
+}